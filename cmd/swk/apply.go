@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/approval"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/owners"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/patch"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+	"gopkg.in/yaml.v3"
+)
+
+// applyManifest applies a manifest to the cluster, replaceable in tests
+var applyManifest = func(ctx context.Context, manifest []byte) error {
+	return cluster.New(cluster.Options{}).Apply(ctx, manifest)
+}
+
+// newApprover returns the approval client, replaceable in tests
+var newApprover = approval.New
+
+// applyEdited applies a file saved by --apply to the cluster. If the profile
+// of its namespace asks for approval, the keys that changed since before are
+// sent to the approval webhook first, and nothing is applied until the
+// change is approved.
+func applyEdited(path, jsonPath string, before []byte) error {
+	after, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	doc, err := parseSecret(after, jsonPath)
+	if err != nil || !doc.IsSecret() {
+		return fmt.Errorf("%s no longer holds a Secret", path)
+	}
+	meta := doc.Metadata()
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	name, profile, err := cfg.Profile(profileName, meta.Namespace)
+	if err != nil {
+		return err
+	}
+
+	manifest := after
+	if profile != nil && profile.Approval {
+		diff := keyDiff(manifestValues(before, jsonPath), manifestValues(after, jsonPath))
+		if len(diff) == 0 {
+			return fmt.Errorf(i18n.T("no keys changed in %s; nothing to approve"), path)
+		}
+		if manifest, err = approve(cfg, name, meta, diff, after); err != nil {
+			return err
+		}
+	}
+
+	if err := applyManifest(context.Background(), manifest); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, i18n.T("Applied %s\n"), path)
+	return nil
+}
+
+// approve asks the approval webhook to approve a change, waiting for the
+// answer, and returns the manifest stamped with the approval
+func approve(cfg *config.Config, profile string, meta secret.Metadata, diff []string, manifest []byte) ([]byte, error) {
+	user, err := owners.CurrentUser(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := cfg.ApprovalTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req := approval.Request{
+		ID:        approval.NewID(),
+		User:      user,
+		Namespace: meta.Namespace,
+		Name:      meta.Name,
+		Diff:      diff,
+		Expires:   now().Add(timeout).UTC().Truncate(time.Second),
+	}
+	fmt.Fprintf(stderr, i18n.T("Profile %s: waiting up to %s for approval of %s (request %s)\n"), profile, timeout, meta.Name, req.ID)
+	decision, err := newApprover(cfg.Approval.Webhook).Request(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(stderr, i18n.T("Approved by %s\n"), decision.Approver)
+
+	record, err := json.Marshal(struct {
+		ID       string `json:"id"`
+		Approver string `json:"approver"`
+		Token    string `json:"token"`
+	}{req.ID, decision.Approver, decision.Token})
+	if err != nil {
+		return nil, err
+	}
+	patchData, err := yaml.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": map[string]any{secret.ApprovalAnnotation: string(record)}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build patch: %w", err)
+	}
+	return patchManifest(manifest, patchData, patch.TypeMerge)
+}
+
+// manifestValues returns the data and stringData values of the Secret at
+// jsonPath as written, stringData winning as it does on the server
+func manifestValues(data []byte, jsonPath string) map[string]string {
+	values := map[string]string{}
+	doc, err := parseSecret(data, jsonPath)
+	if err != nil || !doc.IsSecret() {
+		return values
+	}
+	for _, e := range append(doc.Data(), doc.StringData()...) {
+		values[e.Key] = e.Value
+	}
+	return values
+}
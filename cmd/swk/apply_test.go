@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/approval"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/owners"
+)
+
+func TestRunApply(t *testing.T) {
+	var posted approval.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			_ = json.NewDecoder(r.Body).Decode(&posted)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"approved","token":"tok","approver":"bob@example.com"}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	cfg := "approval:\n  webhook: " + ts.URL + "\nprofiles:\n  prod:\n    namespaces: [prod]\n    approval: true\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvPath, cfgPath)
+	t.Setenv(owners.EnvUser, "alice@example.com")
+
+	var applied []string
+	origApply, origApprover := applyManifest, newApprover
+	applyManifest = func(_ context.Context, manifest []byte) error {
+		applied = append(applied, string(manifest))
+		return nil
+	}
+	newApprover = func(webhook string) *approval.Client {
+		c := approval.New(webhook)
+		c.Interval = time.Millisecond
+		return c
+	}
+	t.Cleanup(func() { applyManifest, newApprover = origApply, origApprover })
+	stderr = &strings.Builder{}
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	dir := t.TempDir()
+	editor := filepath.Join(dir, "editor.sh")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\nsed -i 's/admin/root/' \"$1\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	prod, dev := filepath.Join(dir, "prod.yaml"), filepath.Join(dir, "dev.yaml")
+	if err := os.WriteFile(prod, []byte(strings.Replace(setTestSecret, "name: test-secret", "name: db\n  namespace: prod", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dev, []byte(setTestSecret), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"--apply", "-e", editor, dev}); err != nil {
+		t.Fatalf("--apply outside any profile failed: %v", err)
+	}
+	if len(applied) != 1 || strings.Contains(applied[0], "swk.dev/approval") || posted.ID != "" {
+		t.Errorf("applied %q without a profile, want no approval", applied)
+	}
+
+	if err := run([]string{"--apply", "-e", editor, prod}); err != nil {
+		t.Fatalf("--apply in prod failed: %v", err)
+	}
+	if posted.User != "alice@example.com" || posted.Namespace != "prod" || len(posted.Diff) != 1 || posted.Diff[0] != "~ username" {
+		t.Errorf("approval request = %+v, want the changed key", posted)
+	}
+	if len(applied) != 2 || !strings.Contains(applied[1], "swk.dev/approval") || !strings.Contains(applied[1], "bob@example.com") {
+		t.Errorf("applied %q, want the approval annotation", applied)
+	}
+
+	if err := run([]string{"--apply", "-e", "true", prod}); err == nil || !strings.Contains(err.Error(), "nothing to approve") {
+		t.Errorf("--apply without changes error = %v, want nothing to approve", err)
+	}
+}
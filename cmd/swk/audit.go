@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/audit"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/check"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/progress"
+)
+
+// runAudit handles `swk audit [PATH...] [--state FILE] [--weak-list FILE]
+// [--baseline FILE [--update-baseline]] [--report [FORMAT=]DEST]...
+// [--fail-fast]`, checking every Secret manifest under the paths like swk
+// check. With --state, files that haven't changed since the last run reuse
+// their findings instead of being checked again.
+func runAudit(args []string) error {
+	fs := flag.NewFlagSet("swk audit", flag.ContinueOnError)
+	statePath := fs.String("state", "", "Keep file hashes and findings here, e.g. "+audit.DefaultStatePath+", to only check changed files")
+	noProgress := fs.Bool("no-progress", false, "Don't report progress")
+	failFast := fs.Bool("fail-fast", false, "Stop at the first error and report only that, on stderr")
+	weakList := fs.String("weak-list", "", "Flag values whose SHA-1 or SHA-256 hashes are listed in this file")
+	baselinePath := fs.String("baseline", "", "Only fail on findings not recorded in this baseline file")
+	updateBaseline := fs.Bool("update-baseline", false, "Record the current findings in the --baseline file instead of failing on them")
+	var reports stringList
+	fs.Var(&reports, "report", "Send findings to [FORMAT=]DEST, where DEST is - for stdout, a file, an http(s) URL, or s3://BUCKET/KEY (repeatable)")
+
+	roots, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+
+	opts, err := checkOptions(*weakList)
+	if err != nil {
+		return err
+	}
+	sinks, err := reportSinks(reports)
+	if err != nil {
+		return err
+	}
+	baseline, err := loadBaseline(*baselinePath, *updateBaseline, *failFast)
+	if err != nil {
+		return err
+	}
+	state := audit.NewState(opts)
+	if *statePath != "" {
+		if state, err = audit.LoadState(*statePath, opts); err != nil {
+			return err
+		}
+	}
+
+	files, err := audit.Files(roots)
+	if err != nil {
+		return err
+	}
+	findings, cached, err := auditFiles(files, state, baseline, newProgress("audit", len(files), *noProgress), *failFast)
+	if err != nil {
+		return err
+	}
+
+	if *statePath != "" {
+		state.Prune()
+		if err := state.Save(*statePath); err != nil {
+			return err
+		}
+	}
+
+	if *updateBaseline {
+		return saveBaseline(*baselinePath, findings)
+	}
+	reportBaseline(baseline)
+	err = printFindings("audit", findings, sinks)
+	fmt.Fprintf(stderr, i18n.T("Audited %d files, %d of them unchanged\n"), len(files), cached)
+	if check.HasErrors(findings) {
+		return errors.Join(err, fmt.Errorf(i18n.T("%d problem(s) found"), len(findings)))
+	}
+	return err
+}
+
+// auditFiles checks each file against the state, returning the findings the
+// baseline doesn't know and how many files were unchanged. With failFast it
+// stops at the first file with an error.
+func auditFiles(files []string, state *audit.State, baseline *check.Baseline, bar *progress.Reporter, failFast bool) ([]check.Finding, int, error) {
+	defer bar.Done()
+
+	var findings []check.Finding
+	cached := 0
+	for _, file := range files {
+		found, hit, err := state.Check(file)
+		if err != nil {
+			return nil, 0, err
+		}
+		found = baseline.Filter(found)
+		if err := stopEarly(found, failFast); err != nil {
+			return nil, 0, err
+		}
+		if hit {
+			cached++
+		}
+		findings = append(findings, found...)
+		bar.Increment()
+	}
+	return findings, cached, nil
+}
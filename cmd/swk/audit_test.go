@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunAudit(t *testing.T) {
+	out := captureStdout(t)
+	stderr = io.Discard
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ok.yaml"), []byte(setTestSecret), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state := filepath.Join(dir, ".swk", "state.json")
+	if err := run([]string{"audit", "--state", state, "--no-progress", dir}); err != nil {
+		t.Fatalf("audit of a clean tree failed: %v", err)
+	}
+	if _, err := os.Stat(state); err != nil {
+		t.Errorf("no state written: %v", err)
+	}
+
+	bad := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nstringData:\n  password: <CHANGEME>\n"
+	if err := os.WriteFile(filepath.Join(dir, "bad.yaml"), []byte(bad), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"audit", "--state", state, "--no-progress", dir}); err == nil {
+		t.Error("audit succeeded with a placeholder left")
+	}
+	if !strings.Contains(out.String(), "[placeholder]") {
+		t.Errorf("output = %q, want the placeholder finding", out.String())
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// keyBlame is the commit that last changed the value of a key
+type keyBlame struct {
+	Key     string    `json:"key"`
+	Commit  string    `json:"commit,omitempty"`
+	Author  string    `json:"author,omitempty"`
+	Date    time.Time `json:"date,omitzero"`
+	Subject string    `json:"subject,omitempty"`
+	// Uncommitted is set for values changed in the working tree
+	Uncommitted bool `json:"uncommitted,omitempty"`
+}
+
+// gitCommit is a commit that touched a file, with the file's path in it
+type gitCommit struct {
+	hash, author, subject, path string
+	date                        time.Time
+}
+
+// runBlame handles `swk blame FILE [--json]`, showing for each key the last
+// commit that changed its value, e.g. to tell when a credential was last
+// rotated. The file's git history is replayed through the decoder, so a
+// commit that only re-encodes a value, e.g. with other base64 padding,
+// doesn't count as a change. Renames are followed.
+func runBlame(args []string) error {
+	fs := flag.NewFlagSet("swk blame", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print the result as JSON")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: swk blame FILE [--json]")
+	}
+	file := positional[0]
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	doc, err := parseSecret(data, "")
+	if err != nil || !doc.IsSecret() {
+		return fmt.Errorf("%s is not a Secret", file)
+	}
+
+	blames, err := blameKeys(file, data)
+	if err != nil {
+		return err
+	}
+	// Keys are listed in the order of the file
+	var keys []string
+	for _, e := range append(doc.Data(), doc.StringData()...) {
+		keys = append(keys, e.Key)
+	}
+	var result []keyBlame
+	for _, key := range keys {
+		b := blames[key]
+		b.Key = key
+		result = append(result, b)
+	}
+
+	if *asJSON {
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, string(out))
+		return nil
+	}
+	return paged(func() error {
+		w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tCOMMIT\tAUTHOR\tDATE\tSUBJECT")
+		for _, b := range result {
+			switch {
+			case b.Uncommitted:
+				fmt.Fprintf(w, "%s\t-\t-\t-\tnot committed yet\n", b.Key)
+			case b.Commit == "":
+				fmt.Fprintf(w, "%s\t-\t-\t-\tnot in history\n", b.Key)
+			default:
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", b.Key, b.Commit[:min(8, len(b.Commit))], b.Author, b.Date.Format(time.DateOnly), b.Subject)
+			}
+		}
+		return w.Flush()
+	})
+}
+
+// blameKeys replays the history of file, oldest commit first, recording
+// for each key the commit that last changed its decoded value. Values that
+// differ in the working tree are marked uncommitted.
+func blameKeys(file string, current []byte) (map[string]keyBlame, error) {
+	commits, err := fileHistory(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("%s has no git history", file)
+	}
+
+	dir := filepath.Dir(file)
+	blames := map[string]keyBlame{}
+	var previous map[string]string
+	for _, c := range slices.Backward(commits) {
+		manifest, err := git(dir, "show", c.hash+":"+c.path)
+		if err != nil {
+			// Deleted in this commit
+			previous = nil
+			continue
+		}
+		values, ok := blameValues(manifest)
+		if !ok {
+			// A version that can't be decoded changes nothing that can be told
+			continue
+		}
+		for key, value := range values {
+			if old, ok := previous[key]; !ok || old != value {
+				blames[key] = keyBlame{Commit: c.hash, Author: c.author, Date: c.date, Subject: c.subject}
+			}
+		}
+		previous = values
+	}
+
+	values, _ := blameValues(current)
+	for key, value := range values {
+		if old, ok := previous[key]; !ok || old != value {
+			blames[key] = keyBlame{Uncommitted: true}
+		}
+	}
+	return blames, nil
+}
+
+// blameValues returns the decoded values of a version of a Secret, with
+// base64 normalized first, so that re-encoding a value isn't a change
+func blameValues(manifest []byte) (map[string]string, bool) {
+	doc, err := parseSecret(manifest, "")
+	if err != nil || !doc.IsSecret() {
+		return nil, false
+	}
+	if _, err := doc.NormalizeBase64(); err != nil {
+		return nil, false
+	}
+	if err := doc.DecodeText(); err != nil {
+		return nil, false
+	}
+	values := map[string]string{}
+	for _, e := range append(doc.Data(), doc.StringData()...) {
+		values[e.Key] = e.Value
+	}
+	return values, true
+}
+
+// fileHistory lists the commits that touched file, newest first, with the
+// path the file had in each
+func fileHistory(file string) ([]gitCommit, error) {
+	out, err := git(filepath.Dir(file), "log", "--follow", "--name-only", "--format=%x1e%H%x1f%an%x1f%aI%x1f%s", "--", filepath.Base(file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the git history of %s: %w", file, err)
+	}
+
+	var commits []gitCommit
+	for _, record := range strings.Split(string(out), "\x1e") {
+		header, paths, _ := strings.Cut(record, "\n")
+		fields := strings.Split(header, "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse git log: %w", err)
+		}
+		c := gitCommit{hash: fields[0], author: fields[1], date: date, subject: fields[3]}
+		if path := strings.TrimSpace(paths); path != "" {
+			c.path = strings.Split(path, "\n")[0]
+		} else if len(commits) > 0 {
+			// Merges list no paths; the file kept the one it has later
+			c.path = commits[len(commits)-1].path
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
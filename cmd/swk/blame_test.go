@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunBlame(t *testing.T) {
+	dir := t.TempDir()
+	gitRun := func(author string, args ...string) {
+		t.Helper()
+		args = append([]string{"-C", dir, "-c", "user.name=" + author, "-c", "user.email=" + author + "@example.com"}, args...)
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	commit := func(author, file, content, message string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gitRun(author, "add", file)
+		gitRun(author, "commit", "-qm", message)
+	}
+
+	gitRun("alice", "init", "-q")
+	commit("alice", "old.yaml", setTestSecret, "Add db credentials")
+	commit("bob", "old.yaml", setTestSecret+"  password: aHVudGVyMg==\n  token: dA==\n", "Rotate password")
+	// Renamed, with username encoded differently but decoding the same
+	gitRun("carol", "mv", "old.yaml", "db.yaml")
+	commit("carol", "db.yaml", strings.Replace(setTestSecret, "YWRtaW4=", "YWRtaW4", 1)+"  password: aHVudGVyMg==\n  token: dA==\n", "Move and reformat")
+	file := filepath.Join(dir, "db.yaml")
+	if err := os.WriteFile(file, []byte(setTestSecret+"  password: aHVudGVyMg==\n  token: dTI=\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := captureStdout(t)
+	if err := run([]string{"blame", "--json", file}); err != nil {
+		t.Fatalf("blame failed: %v", err)
+	}
+	var blames []keyBlame
+	if err := json.Unmarshal(out.Bytes(), &blames); err != nil {
+		t.Fatalf("blame output %q: %v", out, err)
+	}
+	want := []struct {
+		key, author, subject string
+		uncommitted          bool
+	}{
+		{"username", "alice", "Add db credentials", false},
+		{"password", "bob", "Rotate password", false},
+		{"token", "", "", true},
+	}
+	if len(blames) != len(want) {
+		t.Fatalf("blame = %+v, want %d keys", blames, len(want))
+	}
+	for i, w := range want {
+		b := blames[i]
+		if b.Key != w.key || b.Author != w.author || b.Subject != w.subject || b.Uncommitted != w.uncommitted {
+			t.Errorf("blame[%d] = %+v, want %+v", i, b, w)
+		}
+	}
+
+	out.Reset()
+	if err := run([]string{"blame", file}); err != nil {
+		t.Fatalf("blame failed: %v", err)
+	}
+	for _, line := range []string{"KEY", "username", "bob", "not committed yet"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output = %q, want %q", out, line)
+		}
+	}
+}
+
+func TestRunBlameNoHistory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "db.yaml")
+	if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"blame", file}); err == nil {
+		t.Error("blame outside a repository succeeded")
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/owners"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/patch"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+	"gopkg.in/yaml.v3"
+)
+
+// maxBreakGlass caps how long a break-glass session may bypass policies
+const maxBreakGlass = 4 * time.Hour
+
+// breakGlass is the running break-glass session, if any. While it is set,
+// saveFile skips key locks and owner checks and records every write.
+var breakGlass *breakGlassSession
+
+// now is the clock, replaceable in tests
+var now = time.Now
+
+// webhookClient sends break-glass notifications, replaceable in tests
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// breakGlassSession is an emergency edit and the files it wrote
+type breakGlassSession struct {
+	User     string           `json:"user"`
+	Reason   string           `json:"reason"`
+	Ticket   string           `json:"ticket"`
+	Started  time.Time        `json:"started"`
+	Deadline time.Time        `json:"deadline"`
+	Writes   []breakGlassFile `json:"writes"`
+}
+
+// breakGlassFile is a file written during a break-glass session
+type breakGlassFile struct {
+	File string   `json:"file"`
+	Keys []string `json:"keys"`
+}
+
+// breakglass runs other commands, so it registers itself once commands is
+// initialized
+func init() {
+	commands["breakglass"] = runBreakGlass
+}
+
+// runBreakGlass handles `swk breakglass COMMAND ARGS... --reason TEXT
+// --ticket ID [--duration D]`, running an edit or another swk command with
+// key locks and owner checks off, for at most the given duration
+func runBreakGlass(args []string) error {
+	flags, rest, err := breakGlassFlags(args)
+	if err != nil {
+		return err
+	}
+	reason, ticket := strings.TrimSpace(flags["reason"]), strings.TrimSpace(flags["ticket"])
+	if len(rest) == 0 || reason == "" || ticket == "" {
+		return fmt.Errorf("usage: swk breakglass edit|COMMAND ARGS... --reason TEXT --ticket ID [--duration 30m]")
+	}
+	if deterministic {
+		return fmt.Errorf("break-glass edits are stamped with who made them and when, so they can't be --deterministic")
+	}
+
+	duration := 30 * time.Minute
+	if d, ok := flags["duration"]; ok {
+		if duration, err = time.ParseDuration(d); err != nil || duration <= 0 || duration > maxBreakGlass {
+			return fmt.Errorf("invalid --duration %q: want a duration up to %s", d, maxBreakGlass)
+		}
+	}
+
+	var cmd func([]string) error
+	switch rest[0] {
+	case "edit":
+		cmd = runEdit
+	case "breakglass", "serve":
+	default:
+		cmd = commands[rest[0]]
+	}
+	if cmd == nil {
+		return fmt.Errorf("can't break glass for %q: want edit or a command that writes files", rest[0])
+	}
+
+	// Every emergency edit must be traceable to someone
+	user, err := owners.CurrentUser(context.Background())
+	if err != nil {
+		return err
+	}
+
+	started := now()
+	session := &breakGlassSession{User: user, Reason: reason, Ticket: ticket, Started: started, Deadline: started.Add(duration)}
+	breakGlass = session
+	defer func() { breakGlass = nil }()
+
+	fmt.Fprintf(stderr, "BREAK-GLASS (%s): key locks and owner checks are off until %s\n",
+		ticket, session.Deadline.Format(time.Kitchen))
+	cmdErr := cmd(rest[1:])
+
+	// Notify about whatever was written, even if the command failed later
+	if len(session.Writes) > 0 {
+		if err := session.notify(); err != nil {
+			fmt.Fprintf(stderr, "Warning: break-glass notification failed: %v\n", err)
+		}
+	}
+	return cmdErr
+}
+
+// breakGlassFlags pulls --reason, --ticket, and --duration out of args,
+// wherever they are, so the command after them never sees them
+func breakGlassFlags(args []string) (map[string]string, []string, error) {
+	flags := map[string]string{}
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || (name != "reason" && name != "ticket" && name != "duration") {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return nil, nil, fmt.Errorf("flag needs an argument: --%s", name)
+			}
+			i++
+			value = args[i]
+		}
+		flags[name] = value
+	}
+	return flags, rest, nil
+}
+
+// record stamps a file about to be written with the break-glass annotation
+// and remembers which keys it changes, refusing writes after the deadline
+func (s *breakGlassSession) record(path string, data []byte) ([]byte, error) {
+	if now().After(s.Deadline) {
+		return nil, fmt.Errorf("break-glass window for %s ended at %s; nothing was saved", s.Ticket, s.Deadline.Format(time.Kitchen))
+	}
+
+	after, err := parseSecret(data, "")
+	if err != nil || !after.IsSecret() {
+		s.Writes = append(s.Writes, breakGlassFile{File: path})
+		return data, nil
+	}
+	changed := appliedKeys(after)
+	if before := existingSecret(path); before != nil {
+		changed = secret.ChangedKeys(before, after)
+	}
+
+	annotation, err := json.Marshal(struct {
+		User   string    `json:"user"`
+		Reason string    `json:"reason"`
+		Ticket string    `json:"ticket"`
+		At     time.Time `json:"at"`
+		Keys   []string  `json:"keys"`
+	}{s.User, s.Reason, s.Ticket, now().UTC().Truncate(time.Second), changed})
+	if err != nil {
+		return nil, err
+	}
+	patchData, err := yaml.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": map[string]any{secret.BreakGlassAnnotation: string(annotation)}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build patch: %w", err)
+	}
+	stamped, err := patchManifest(data, patchData, patch.TypeMerge)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Writes = append(s.Writes, breakGlassFile{File: path, Keys: changed})
+	return stamped, nil
+}
+
+// notify posts the session to the configured webhook, if any
+func (s *breakGlassSession) notify() error {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	if cfg.BreakGlass.Webhook == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(struct {
+		Event string `json:"event"`
+		*breakGlassSession
+	}{"breakglass", s})
+	if err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(cfg.BreakGlass.Webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/owners"
+)
+
+func TestRunBreakGlass(t *testing.T) {
+	stderr = io.Discard
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	var notified []byte
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notified, _ = io.ReadAll(r.Body)
+	}))
+	defer hook.Close()
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("breakglass:\n  webhook: "+hook.URL+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvPath, cfgPath)
+	t.Setenv(owners.EnvUser, "alice@example.com")
+
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := run([]string{"lock", file, "--key", "username"}); err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+
+	if err := run([]string{"breakglass", "set", file, "username", "root"}); err == nil {
+		t.Error("breakglass without --reason and --ticket succeeded")
+	}
+
+	err := run([]string{"breakglass", "set", file, "username", "root", "--reason", "admin leaked", "--ticket", "INC-42"})
+	if err != nil {
+		t.Fatalf("breakglass set failed: %v", err)
+	}
+	if got := queryFile(t, file, ".data.username | @base64d"); got != "root" {
+		t.Errorf("username = %q, want root", got)
+	}
+	record := queryFile(t, file, `.metadata.annotations["swk.dev/break-glass"]`)
+	for _, want := range []string{`"user":"alice@example.com"`, `"ticket":"INC-42"`, `"keys":["username"]`} {
+		if !strings.Contains(record, want) {
+			t.Errorf("annotation %s missing %s", record, want)
+		}
+	}
+
+	var event struct {
+		Event  string
+		Ticket string
+		Writes []breakGlassFile
+	}
+	if err := json.Unmarshal(notified, &event); err != nil {
+		t.Fatalf("webhook body %q: %v", notified, err)
+	}
+	if event.Event != "breakglass" || event.Ticket != "INC-42" || len(event.Writes) != 1 {
+		t.Errorf("webhook event = %+v", event)
+	}
+
+	if breakGlass != nil {
+		t.Error("break-glass session outlived the command")
+	}
+	if err := run([]string{"set", file, "username", "admin"}); err == nil {
+		t.Error("set on a locked key succeeded after break-glass ended")
+	}
+}
+
+func TestBreakGlassDeadline(t *testing.T) {
+	s := &breakGlassSession{Ticket: "INC-1", Deadline: time.Now().Add(-time.Minute)}
+	_, err := s.record(filepath.Join(t.TempDir(), "secret.yaml"), []byte(setTestSecret))
+	if err == nil || !strings.Contains(err.Error(), "window for INC-1 ended") {
+		t.Errorf("record() error = %v, want a deadline error", err)
+	}
+	if len(s.Writes) != 0 {
+		t.Error("record() recorded a refused write")
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/bundle"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/safefile"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/schema"
+)
+
+// bundle and verify-bundle list the commands through the completion
+// scripts, so they register once commands is initialized
+func init() {
+	commands["bundle"] = runBundle
+	commands["verify-bundle"] = runVerifyBundle
+}
+
+// runBundle handles `swk bundle SUBCOMMAND`
+func runBundle(args []string) error {
+	if len(args) == 0 || args[0] != "offline" {
+		return fmt.Errorf("usage: swk bundle offline [-o FILE]")
+	}
+	return runBundleOffline(args[1:])
+}
+
+// runBundleOffline handles `swk bundle offline [-o FILE]`, writing a
+// tarball for machines without network access: this binary, the schemas
+// it validates against, the config schema with a sample policy, and the
+// completion scripts, with a SHA256SUMS to check them by
+func runBundleOffline(args []string) error {
+	fs := flag.NewFlagSet("swk bundle offline", flag.ContinueOnError)
+	out := fs.String("o", "", "Bundle file to write (default swk-offline-OS-ARCH.tar.gz)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("usage: swk bundle offline [-o FILE]")
+	}
+	if *out == "" {
+		*out = fmt.Sprintf("swk-offline-%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	}
+
+	files, err := offlineFiles()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := bundle.Write(&buf, "swk-offline", files); err != nil {
+		return err
+	}
+	if err := safefile.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	fmt.Fprintf(stderr, "Wrote %s with %d files; check it with swk verify-bundle %s\n", *out, len(files), *out)
+	return nil
+}
+
+// offlineFiles collects what goes into an offline bundle
+func offlineFiles() ([]bundle.File, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the swk binary: %w", err)
+	}
+	binary, err := os.ReadFile(exe)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the swk binary: %w", err)
+	}
+	schemas, err := schema.Document()
+	if err != nil {
+		return nil, err
+	}
+	configSchema, err := config.JSONSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	files := []bundle.File{
+		{Name: "bin/swk", Mode: 0755, Data: binary},
+		{Name: "schemas/v1.json", Mode: 0644, Data: schemas},
+		{Name: "config/swk-config.schema.json", Mode: 0644, Data: configSchema},
+		{Name: "config/policy.sample.yaml", Mode: 0644, Data: bundle.PolicySample},
+	}
+	for _, shell := range completionShells {
+		script, err := completionScript(shell)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, bundle.File{Name: "completion/swk." + shell, Mode: 0644, Data: []byte(script)})
+	}
+	return files, nil
+}
+
+// runVerifyBundle handles `swk verify-bundle FILE`, checking every file of
+// an offline bundle against its SHA256SUMS before it is installed
+func runVerifyBundle(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: swk verify-bundle FILE")
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	names, err := bundle.Verify(f)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		fmt.Fprintf(stdout, "%s: OK\n", name)
+	}
+	return nil
+}
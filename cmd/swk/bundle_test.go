@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/schema"
+)
+
+func TestRunBundleOffline(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	stderr = &strings.Builder{}
+	t.Cleanup(func() { stderr = os.Stderr })
+	path := filepath.Join(t.TempDir(), "offline.tar.gz")
+
+	if err := run([]string{"bundle", "offline", "-o", path}); err != nil {
+		t.Fatalf("run(bundle offline) failed: %v", err)
+	}
+	out := captureStdout(t)
+	if err := run([]string{"verify-bundle", path}); err != nil {
+		t.Fatalf("run(verify-bundle) failed: %v", err)
+	}
+	for _, name := range []string{"bin/swk", "schemas/v1.json", "config/swk-config.schema.json", "config/policy.sample.yaml", "completion/swk.bash", "completion/swk.zsh", "completion/swk.fish"} {
+		if !strings.Contains(out.String(), name+": OK") {
+			t.Errorf("verify-bundle output = %q, want %s checked", out, name)
+		}
+	}
+
+	// A damaged bundle fails
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data[:len(data)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"verify-bundle", path}); err == nil {
+		t.Error("run(verify-bundle) on a truncated bundle succeeded")
+	}
+}
+
+func TestRunCompletion(t *testing.T) {
+	for _, shell := range completionShells {
+		t.Run(shell, func(t *testing.T) {
+			out := captureStdout(t)
+			if err := run([]string{"completion", shell}); err != nil {
+				t.Fatalf("run() failed: %v", err)
+			}
+			wants := []string{"blame", "verify-bundle", "diff-tool"}
+			if _, ok := commands["ls"]; ok {
+				wants = append(wants, "swk ls --names")
+			}
+			for _, want := range wants {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("completion script lacks %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+	if err := run([]string{"completion", "tcsh"}); err == nil {
+		t.Error("run(completion tcsh) succeeded")
+	}
+}
+
+func TestRunSchemaUpdateFrom(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	doc, err := schema.Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "v1.json")
+	if err := os.WriteFile(path, doc, 0644); err != nil {
+		t.Fatal(err)
+	}
+	captureStdout(t)
+	if err := run([]string{"schema", "update", "--from", path}); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+	cached, err := schema.CachePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cached); err != nil {
+		t.Errorf("schema not cached: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"schema", "update", "--from", path}); err == nil {
+		t.Error("run() with an invalid schema succeeded")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"sort"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cloudsync"
+)
+
+// capabilitiesVersion is the version of the swk capabilities JSON. It only
+// changes when fields are removed or change meaning.
+const capabilitiesVersion = 1
+
+// capability is a feature that wrapper scripts and editor plugins can
+// detect with swk capabilities
+type capability struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Requires names a program the feature runs, looked up on PATH
+	Requires string `json:"requires,omitempty"`
+	// Available is false if the program it requires isn't installed
+	Available bool `json:"available"`
+	// Network is set for features that reach other hosts; the rest work
+	// without network access
+	Network bool `json:"network,omitempty"`
+}
+
+// capabilities lists the features compiled into this binary. Files that are
+// only built with a build tag add theirs in init.
+var capabilities = []capability{
+	{Name: "cluster", Description: "Reading and writing Secrets in a cluster", Requires: "kubectl", Network: true},
+	{Name: "sops", Description: "Editing and writing sops-encrypted manifests", Requires: "sops"},
+	{Name: "ssh", Description: "Editing files on other hosts with swk edit HOST:PATH", Requires: "ssh", Network: true},
+	{Name: "schema", Description: "Validating manifests against the Kubernetes OpenAPI schemas"},
+	{Name: "approval", Description: "Approval webhooks for --apply", Network: true},
+	{Name: "breakglass", Description: "Recorded emergency edits that bypass locks and owners"},
+}
+
+func init() {
+	commands["capabilities"] = runCapabilities
+}
+
+// capabilityList returns the capabilities, with a cloudsync.SCHEME entry
+// for every external store, sorted by name
+func capabilityList() []capability {
+	list := append([]capability(nil), capabilities...)
+	for _, scheme := range cloudsync.Schemes() {
+		list = append(list, capability{Name: "cloudsync." + scheme, Description: fmt.Sprintf("Syncing values with %s:// stores", scheme), Network: true})
+	}
+	for i := range list {
+		list[i].Available = true
+		if list[i].Requires != "" {
+			_, err := exec.LookPath(list[i].Requires)
+			list[i].Available = err == nil
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// runCapabilities handles `swk capabilities [--output text|json]`, listing
+// the commands and features of this binary so that scripts can detect them
+// instead of parsing version strings
+func runCapabilities(args []string) error {
+	fs := flag.NewFlagSet("swk capabilities", flag.ContinueOnError)
+	format := fs.String("output", "text", "Output format: text or json")
+	fs.StringVar(format, "o", "text", "Shorthand for --output")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 || (*format != "text" && *format != "json") {
+		return fmt.Errorf("usage: swk capabilities [--output text|json]")
+	}
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	features := capabilityList()
+
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Version  int          `json:"capabilitiesVersion"`
+			Swk      string       `json:"swkVersion"`
+			Platform string       `json:"platform"`
+			Commands []string     `json:"commands"`
+			Features []capability `json:"features"`
+		}{capabilitiesVersion, swkVersion(), runtime.GOOS + "/" + runtime.GOARCH, names, features})
+	}
+
+	fmt.Fprintf(stdout, "swk %s (%s/%s)\n\nFeatures:\n", swkVersion(), runtime.GOOS, runtime.GOARCH)
+	for _, c := range features {
+		state := "yes"
+		if !c.Available {
+			state = fmt.Sprintf("no (%s not found)", c.Requires)
+		}
+		description := c.Description
+		if c.Network {
+			description += " (needs network)"
+		}
+		fmt.Fprintf(stdout, "  %-18s %-24s %s\n", c.Name, state, description)
+	}
+	fmt.Fprintln(stdout, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(stdout, "  %s\n", name)
+	}
+	return nil
+}
+
+// swkVersion returns the module version swk was built from, or (devel)
+func swkVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
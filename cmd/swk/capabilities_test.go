@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "kubectl"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	out := captureStdout(t)
+	if err := run([]string{"capabilities", "--output", "json"}); err != nil {
+		t.Fatalf("swk capabilities error = %v", err)
+	}
+	var got struct {
+		Version  int      `json:"capabilitiesVersion"`
+		Commands []string `json:"commands"`
+		Features []struct {
+			Name      string `json:"name"`
+			Available bool   `json:"available"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out)
+	}
+	if got.Version != capabilitiesVersion || !slices.Contains(got.Commands, "capabilities") {
+		t.Errorf("version = %d, commands = %v", got.Version, got.Commands)
+	}
+
+	available := map[string]bool{}
+	for _, f := range got.Features {
+		available[f.Name] = f.Available
+	}
+	want := map[string]bool{"cluster": true, "sops": false, "schema": true}
+	for name, ok := range want {
+		if have, found := available[name]; !found || have != ok {
+			t.Errorf("feature %s: available = %v (listed %v), want %v", name, have, found, ok)
+		}
+	}
+	// Features left out by a build tag are left out of the list too
+	for command, feature := range map[string]string{"serve": "serve", "push": "cloudsync.vault", "snapshot": "snapshot"} {
+		_, built := commands[command]
+		if _, listed := available[feature]; listed != built {
+			t.Errorf("feature %s listed = %v, but command %s built = %v", feature, listed, command, built)
+		}
+	}
+
+	if err := run([]string{"capabilities", "--output", "yaml"}); err == nil {
+		t.Error("swk capabilities --output yaml succeeded, want a usage error")
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/check"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/progress"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+)
+
+// runCheck handles `swk check FILE... [--weak-list FILE] [--baseline FILE
+// [--update-baseline]] [--report [FORMAT=]DEST]...`, printing findings and
+// sending them to the report sinks, and failing if any of them is an error.
+// With --baseline, findings recorded in it are left out.
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("swk check", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: swk check FILE... [--since REF] [--weak-list FILE] [--baseline FILE [--update-baseline]] [--report [FORMAT=]DEST]... [--fail-fast]")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "\nRules:")
+		for _, rule := range check.Rules() {
+			fmt.Fprintf(fs.Output(), "  %-12s %s\n", rule.Name, rule.Description)
+		}
+		fmt.Fprintf(fs.Output(), "  %-12s %s\n", "owner", "Keys changed since --since REF that belong to another team")
+	}
+
+	noProgress := fs.Bool("no-progress", false, "Don't report progress while checking many files")
+	since := fs.String("since", "", "Check key owners for changes since this git ref")
+	failFast := fs.Bool("fail-fast", false, "Stop at the first error and report only that, on stderr")
+	weakList := fs.String("weak-list", "", "Flag values whose SHA-1 or SHA-256 hashes are listed in this file")
+	baselinePath := fs.String("baseline", "", "Only fail on findings not recorded in this baseline file")
+	updateBaseline := fs.Bool("update-baseline", false, "Record the current findings in the --baseline file instead of failing on them")
+	var reports stringList
+	fs.Var(&reports, "report", "Send findings to [FORMAT=]DEST, where DEST is - for stdout, a file, an http(s) URL, or s3://BUCKET/KEY (repeatable)")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("usage: swk check FILE... [--since REF] [--weak-list FILE] [--baseline FILE [--update-baseline]] [--report [FORMAT=]DEST]... [--fail-fast]")
+	}
+	opts, err := checkOptions(*weakList)
+	if err != nil {
+		return err
+	}
+	sinks, err := reportSinks(reports)
+	if err != nil {
+		return err
+	}
+	baseline, err := loadBaseline(*baselinePath, *updateBaseline, *failFast)
+	if err != nil {
+		return err
+	}
+
+	findings, err := checkFiles(files, opts, baseline, newProgress("check", len(files), *noProgress), *failFast)
+	if err != nil {
+		return err
+	}
+	if *since != "" {
+		found, err := checkOwnersSince(files, *since)
+		if err != nil {
+			return err
+		}
+		countFindings(found)
+		found = baseline.Filter(found)
+		if err := stopEarly(found, *failFast); err != nil {
+			return err
+		}
+		findings = append(findings, found...)
+	}
+	if *updateBaseline {
+		return saveBaseline(*baselinePath, findings)
+	}
+	reportBaseline(baseline)
+
+	err = printFindings("check", findings, sinks)
+	if check.HasErrors(findings) {
+		return errors.Join(err, fmt.Errorf(i18n.T("%d problem(s) found"), len(findings)))
+	}
+	return err
+}
+
+// checkOptions loads what the rules need besides the files
+func checkOptions(weakList string) (check.Options, error) {
+	var opts check.Options
+	if weakList != "" {
+		w, err := check.LoadWeakList(weakList)
+		if err != nil {
+			return opts, err
+		}
+		opts.WeakList = w
+	}
+	return opts, nil
+}
+
+// checkFiles checks each file in turn, reporting progress as it goes and
+// leaving out the findings the baseline knows. With failFast it stops at
+// the first file with an error.
+func checkFiles(files []string, opts check.Options, baseline *check.Baseline, bar *progress.Reporter, failFast bool) ([]check.Finding, error) {
+	defer bar.Done()
+
+	var findings []check.Finding
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		found, err := check.Manifest(file, data, opts)
+		if err != nil {
+			return nil, err
+		}
+		countFindings(found)
+		found = baseline.Filter(found)
+		if err := stopEarly(found, failFast); err != nil {
+			return nil, err
+		}
+		findings = append(findings, found...)
+		bar.Increment()
+	}
+	return findings, nil
+}
+
+// loadBaseline loads the --baseline file findings are compared with, or
+// returns nil without one or when --update-baseline records it anew
+func loadBaseline(path string, update, failFast bool) (*check.Baseline, error) {
+	if update && path == "" {
+		return nil, fmt.Errorf("--update-baseline needs --baseline FILE")
+	}
+	if update && failFast {
+		return nil, fmt.Errorf("--update-baseline records every finding, so it can't be used with --fail-fast")
+	}
+	if path == "" || update {
+		return nil, nil
+	}
+	baseline, err := check.LoadBaseline(path)
+	if err != nil {
+		return nil, err
+	}
+	if baseline.Ruleset != check.RulesetVersion {
+		warning := fmt.Sprintf(i18n.T("%s was recorded with ruleset %d, but the rules are now at %d; review the new findings and run --update-baseline"), path, baseline.Ruleset, check.RulesetVersion)
+		fmt.Fprintln(stderr, fmt.Sprintf(i18n.T("Warning: %s"), warning))
+	}
+	return baseline, nil
+}
+
+// saveBaseline records findings in a baseline file for --update-baseline
+func saveBaseline(path string, findings []check.Finding) error {
+	if err := check.NewBaseline(findings).Save(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, i18n.T("Recorded %d finding(s) in %s\n"), len(findings), path)
+	return nil
+}
+
+// reportBaseline tells how many findings a baseline left out, and how many
+// it records that are gone
+func reportBaseline(baseline *check.Baseline) {
+	if baseline == nil || baseline.Known() == 0 && baseline.Fixed() == 0 {
+		return
+	}
+	fmt.Fprintf(stderr, i18n.T("%d known finding(s) left out by the baseline, %d fixed since it was recorded\n"), baseline.Known(), baseline.Fixed())
+}
+
+// countFindings counts the rules that fired for swk stats
+func countFindings(findings []check.Finding) {
+	for _, f := range findings {
+		countCheck("check:" + f.Rule)
+	}
+}
+
+// stopEarly returns the first error among findings as an error with
+// --fail-fast, so that it ends the run and is reported on stderr
+func stopEarly(findings []check.Finding, failFast bool) error {
+	if !failFast {
+		return nil
+	}
+	for _, f := range findings {
+		if f.Severity == check.Error {
+			return errors.New(f.String())
+		}
+	}
+	return nil
+}
+
+// checkOwnersSince reports keys changed since a git ref that belong to a
+// team the user isn't in, as errors with --enforce-owners
+func checkOwnersSince(files []string, ref string) ([]check.Finding, error) {
+	if _, err := git(".", "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("unknown git ref %q", ref)
+	}
+
+	severity := check.Warning
+	if enforceOwners {
+		severity = check.Error
+	}
+
+	var findings []check.Finding
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		after, err := parseSecret(data, "")
+		if err != nil || !after.IsSecret() {
+			continue
+		}
+
+		changed := appliedKeys(after)
+		old, err := git(filepath.Dir(file), "show", ref+":./"+filepath.Base(file))
+		if before, perr := parseSecret(old, ""); err == nil && perr == nil && before.IsSecret() {
+			changed = secret.ChangedKeys(before, after)
+		}
+
+		violations, user, err := ownerViolations(changed)
+		if err != nil {
+			return nil, err
+		}
+		meta := after.Metadata()
+		id := meta.Name
+		if meta.Namespace != "" {
+			id = meta.Namespace + "/" + meta.Name
+		}
+		for _, v := range violations {
+			findings = append(findings, check.Finding{
+				File:     file,
+				Secret:   id,
+				Key:      v.Key,
+				Rule:     "owner",
+				Severity: severity,
+				Message:  fmt.Sprintf("changed since %s but owned by team %s, and %s is not a member", ref, v.Team, user),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// git runs a git command in dir and returns its output
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	return cmd.Output()
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+)
+
+func TestRunCheckFailFast(t *testing.T) {
+	out := captureStdout(t)
+
+	dir := t.TempDir()
+	bad := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nstringData:\n  password: <CHANGEME>\n"
+	var files []string
+	for _, name := range []string{"a.yaml", "b.yaml"} {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(bad), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+
+	for _, cmd := range []string{"check", "audit"} {
+		out.Reset()
+		err := run(append([]string{cmd, "--fail-fast", "--no-progress"}, files...))
+		if err == nil || !strings.Contains(err.Error(), "a.yaml:6: error [placeholder]") {
+			t.Errorf("%s --fail-fast error = %v, want the first finding", cmd, err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("%s --fail-fast printed a report: %q", cmd, out.String())
+		}
+	}
+}
+
+func TestRunCheckWeakList(t *testing.T) {
+	out := captureStdout(t)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "secret.yaml")
+	if err := os.WriteFile(file, []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nstringData:\n  password: password\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The SHA-1 of "password", as in the Pwned Passwords downloads
+	list := filepath.Join(dir, "weak.txt")
+	if err := os.WriteFile(list, []byte("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cmd := range []string{"check", "audit"} {
+		out.Reset()
+		if err := run([]string{cmd, "--no-progress", file}); err != nil {
+			t.Errorf("%s without --weak-list error = %v", cmd, err)
+		}
+		out.Reset()
+		err := run([]string{cmd, "--no-progress", "--weak-list", list, file})
+		if err == nil || !strings.Contains(out.String(), "error [weak] db password") {
+			t.Errorf("%s --weak-list error = %v, output = %q, want the weak value reported", cmd, err, out)
+		}
+	}
+
+	if err := run([]string{"check", "--weak-list", filepath.Join(dir, "missing.txt"), file}); err == nil {
+		t.Error("check with a missing weak list should fail")
+	}
+}
+
+func TestRunCheckBaseline(t *testing.T) {
+	out := captureStdout(t)
+	log := &strings.Builder{}
+	stderr = log
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "secret.yaml")
+	legacy := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nstringData:\n  password: <CHANGEME>\n"
+	if err := os.WriteFile(file, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	baseline := filepath.Join(dir, ".swk", "baseline.json")
+
+	for _, cmd := range []string{"check", "audit"} {
+		t.Run(cmd, func(t *testing.T) {
+			if err := os.WriteFile(file, []byte(legacy), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := run([]string{cmd, "--no-progress", "--update-baseline", file}); err == nil {
+				t.Error("--update-baseline without --baseline should fail")
+			}
+			log.Reset()
+			if err := run([]string{cmd, "--no-progress", "--baseline", baseline, "--update-baseline", file}); err != nil {
+				t.Fatalf("--update-baseline error = %v", err)
+			}
+			if !strings.Contains(log.String(), "Recorded 1 finding(s)") {
+				t.Errorf("stderr = %q, want the findings recorded", log)
+			}
+
+			out.Reset()
+			if err := run([]string{cmd, "--no-progress", "--baseline", baseline, file}); err != nil {
+				t.Errorf("error = %v, want known findings left out", err)
+			}
+			if out.Len() != 0 {
+				t.Errorf("stdout = %q, want no findings", out)
+			}
+
+			// A new finding fails the run, and only it is reported
+			if err := os.WriteFile(file, []byte(legacy+"  token: <CHANGEME>\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			out.Reset()
+			err := run([]string{cmd, "--no-progress", "--baseline", baseline, file})
+			if err == nil || !strings.Contains(out.String(), "db token") || strings.Contains(out.String(), "db password") {
+				t.Errorf("error = %v, stdout = %q, want only the new finding", err, out)
+			}
+		})
+	}
+
+	if err := run([]string{"check", "--baseline", filepath.Join(dir, "missing.json"), file}); err == nil {
+		t.Error("check with a missing baseline should fail")
+	}
+}
+
+func TestRunCheckReport(t *testing.T) {
+	out := captureStdout(t)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "secret.yaml")
+	if err := os.WriteFile(file, []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nstringData:\n  password: <CHANGEME>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sarif := filepath.Join(dir, "check.sarif")
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("reports:\n  - to: "+sarif+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvPath, cfgPath)
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantStdout string
+		wantSARIF  bool
+	}{
+		{"configured", nil, "error [placeholder] db password", true},
+		{"flags replace the config", []string{"--report", "json=-"}, `"rule": "placeholder"`, false},
+	}
+	for _, tt := range tests {
+		for _, cmd := range []string{"check", "audit"} {
+			t.Run(tt.name+"/"+cmd, func(t *testing.T) {
+				out.Reset()
+				_ = os.Remove(sarif)
+				err := run(append(append([]string{cmd, "--no-progress"}, tt.args...), file))
+				if err == nil || !strings.Contains(err.Error(), "problem(s) found") {
+					t.Errorf("error = %v, want the problem reported", err)
+				}
+				if !strings.Contains(out.String(), tt.wantStdout) {
+					t.Errorf("stdout = %q, want %q", out.String(), tt.wantStdout)
+				}
+				_, err = os.Stat(sarif)
+				if got := err == nil; got != tt.wantSARIF {
+					t.Errorf("SARIF written = %v, want %v", got, tt.wantSARIF)
+				}
+			})
+		}
+	}
+
+	if err := run([]string{"check", "--report", "xml=out.xml", file}); err == nil || !strings.Contains(err.Error(), "unknown report format") {
+		t.Errorf("check --report xml=out.xml error = %v, want an unknown format", err)
+	}
+}
@@ -0,0 +1,116 @@
+//go:build !nocloud
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+func init() {
+	commands["ci"] = runCI
+}
+
+// runCI handles `swk ci push FILE --github-repo ORG/REPO | --gitlab-project
+// ID`, writing the keys of a Secret manifest to CI secrets, so the cluster
+// and the pipelines get their values from the same source
+func runCI(args []string) error {
+	const usage = "usage: swk ci push FILE --github-repo ORG/REPO | --gitlab-project ID [--keys K1,K2] [--resume]"
+	if len(args) == 0 || args[0] != "push" {
+		return fmt.Errorf(usage)
+	}
+
+	fs := flag.NewFlagSet("swk ci push", flag.ContinueOnError)
+	githubRepo := fs.String("github-repo", "", "GitHub repository whose Actions secrets to write, e.g. org/repo")
+	gitlabProject := fs.String("gitlab-project", "", "GitLab project whose CI/CD variables to write, by ID or path")
+	keyList := fs.String("keys", "", "Comma-separated keys to push (default: all)")
+	sf := bindSyncFlags(fs)
+
+	positional, err := parseFlags(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || (*githubRepo == "") == (*gitlabProject == "") {
+		return fmt.Errorf(usage)
+	}
+	storeURL := "github://" + *githubRepo
+	if *gitlabProject != "" {
+		storeURL = "gitlab://" + *gitlabProject
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	values, err := secretValues(data)
+	if err != nil {
+		return err
+	}
+	if *keyList != "" {
+		selected := map[string]string{}
+		for _, key := range strings.Split(*keyList, ",") {
+			value, ok := values[key]
+			if !ok {
+				return fmt.Errorf("no key %q in %s", key, positional[0])
+			}
+			selected[key] = value
+		}
+		values = selected
+	}
+	variables, err := ciVariables(values)
+	if err != nil {
+		return err
+	}
+
+	s, err := sf.syncer("push", storeURL, positional[0], data)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := s.Push(context.Background(), names, variables)
+	return finishSync(s, results)
+}
+
+// ciVariables renames keys to names both GitHub and GitLab accept:
+// db-password becomes DB_PASSWORD
+func ciVariables(values map[string]string) (map[string]string, error) {
+	variables := make(map[string]string, len(values))
+	from := map[string]string{}
+	for key, value := range values {
+		name := ciVariableName(key)
+		if other, ok := from[name]; ok {
+			return nil, fmt.Errorf("keys %q and %q would both become CI variable %s", other, key, name)
+		}
+		from[name] = key
+		variables[name] = value
+	}
+	return variables, nil
+}
+
+// ciVariableName upper-cases key and replaces anything but letters, digits,
+// and underscores, prefixing a leading digit with an underscore
+func ciVariableName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
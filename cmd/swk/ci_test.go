@@ -0,0 +1,65 @@
+//go:build !nocloud
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCIVariableName(t *testing.T) {
+	tests := map[string]string{
+		"db-password": "DB_PASSWORD",
+		"API_KEY":     "API_KEY",
+		"tls.crt":     "TLS_CRT",
+		"1st":         "_1ST",
+	}
+	for key, want := range tests {
+		if got := ciVariableName(key); got != want {
+			t.Errorf("ciVariableName(%q) = %q, want %q", key, got, want)
+		}
+	}
+
+	if _, err := ciVariables(map[string]string{"a-b": "1", "a.b": "2"}); err == nil {
+		t.Error("ciVariables() accepted two keys with the same variable name")
+	}
+}
+
+func TestRunCIPush(t *testing.T) {
+	captureStdout(t)
+
+	got := map[string]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v struct{ Key, Value string }
+		_ = json.NewDecoder(r.Body).Decode(&v)
+		if r.Method == http.MethodPut {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		got[v.Key] = v.Value
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(ts.Close)
+	t.Setenv("CI_API_V4_URL", ts.URL)
+	t.Setenv("GITLAB_TOKEN", "token")
+
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"ci", "push", file, "--github-repo", "org/app", "--gitlab-project", "1"}); err == nil || !strings.Contains(err.Error(), "usage") {
+		t.Errorf("ci push with two targets = %v, want usage", err)
+	}
+	if err := run([]string{"ci", "push", file, "--gitlab-project", "1"}); err != nil {
+		t.Fatalf("ci push failed: %v", err)
+	}
+	if got["USERNAME"] != "admin" {
+		t.Errorf("variables = %v, want USERNAME=admin", got)
+	}
+}
@@ -0,0 +1,189 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/mirror"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/report"
+)
+
+// clusterSecret is a Secret fetched from a cluster, with decoded values
+type clusterSecret struct {
+	Namespace string
+	Name      string
+	Type      string
+	Values    map[string]string
+	Raw       json.RawMessage
+}
+
+func init() {
+	commands["sync"] = runSync
+}
+
+// runSync handles `swk sync --from CONTEXT --to CONTEXT -l SELECTOR
+// [--dry-run] [--report [FORMAT=]DEST]...`, bringing the matching Secrets
+// of a standby cluster in line with the active one. Only keys that are
+// missing or differ are written; keys only the target has are left alone.
+func runSync(args []string) error {
+	var clusterOpts cluster.Options
+	fs := flag.NewFlagSet("swk sync", flag.ContinueOnError)
+	clusterOpts.BindFlags(fs)
+	from := fs.String("from", "", "Kubeconfig context to copy from")
+	to := fs.String("to", "", "Kubeconfig context to copy to")
+	selector := fs.String("selector", "", "Label selector of the Secrets to sync, e.g. app=foo")
+	fs.StringVar(selector, "l", "", "Shorthand for -selector")
+	allNamespaces := fs.Bool("all-namespaces", false, "Sync Secrets in every namespace")
+	fs.BoolVar(allNamespaces, "A", false, "Shorthand for -all-namespaces")
+	dryRun := fs.Bool("dry-run", false, "Only report the keys that would be written")
+	var reports stringList
+	fs.Var(&reports, "report", "Send the keys written to [FORMAT=]DEST, where DEST is - for stdout, a file, an http(s) URL, or s3://BUCKET/KEY (repeatable)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 || *from == "" || *to == "" || *selector == "" {
+		return fmt.Errorf("usage: swk sync --from CONTEXT --to CONTEXT -l SELECTOR [-n NAMESPACE | -A] [--dry-run] [--report [FORMAT=]DEST]...")
+	}
+	if *from == *to {
+		return fmt.Errorf("--from and --to are both %s", *from)
+	}
+	sinks, err := reportSinks(reports)
+	if err != nil {
+		return err
+	}
+	listing := !reportsToStdout(sinks)
+
+	clusterOpts.Context = *from
+	source := cluster.New(clusterOpts)
+	clusterOpts.Context = *to
+	target := cluster.New(clusterOpts)
+
+	ctx := context.Background()
+	sources, err := fetchClusterSecrets(ctx, source, *selector, *allNamespaces)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *from, err)
+	}
+	targets, err := fetchClusterSecrets(ctx, target, *selector, *allNamespaces)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *to, err)
+	}
+
+	names := make([]string, 0, len(sources))
+	for id := range sources {
+		names = append(names, id)
+	}
+	sort.Strings(names)
+
+	changed := 0
+	var changes []report.Change
+	for _, id := range names {
+		src := sources[id]
+		dst, ok := targets[id]
+		if ok && dst.Type != src.Type {
+			return fmt.Errorf(i18n.T("%s has type %s in %s but %s in %s"), id, src.Type, *from, dst.Type, *to)
+		}
+
+		var lines []string
+		if !ok {
+			lines = keyDiff(nil, src.Values)
+		} else {
+			merged := copyValues(dst.Values)
+			for key, value := range src.Values {
+				merged[key] = value
+			}
+			lines = keyDiff(dst.Values, merged)
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		changed++
+		for _, line := range lines {
+			change := report.Change{Secret: id, Key: line[2:], Op: "changed"}
+			if line[0] == '+' {
+				change.Op = "added"
+			}
+			changes = append(changes, change)
+			if listing {
+				fmt.Fprintln(stdout, change)
+			}
+		}
+		if *dryRun {
+			continue
+		}
+
+		if !ok {
+			_, manifest, err := mirror.Clean(src.Raw)
+			if err != nil {
+				return err
+			}
+			if err := target.Apply(ctx, manifest); err != nil {
+				return err
+			}
+			continue
+		}
+		data := map[string]string{}
+		for _, line := range lines {
+			key := line[2:]
+			data[key] = base64.StdEncoding.EncodeToString([]byte(src.Values[key]))
+		}
+		patch, err := json.Marshal(map[string]any{"data": data})
+		if err != nil {
+			return err
+		}
+		if err := target.PatchSecret(ctx, dst.Namespace, dst.Name, patch); err != nil {
+			return err
+		}
+	}
+
+	if *dryRun {
+		fmt.Fprintf(stderr, i18n.T("%d of %d Secrets would change in %s\n"), changed, len(sources), *to)
+	} else {
+		fmt.Fprintf(stderr, i18n.T("Synced %d of %d Secrets to %s\n"), changed, len(sources), *to)
+	}
+	return writeReport(report.Report{Command: "sync", Changes: changes}, sinks)
+}
+
+// fetchClusterSecrets returns the matching Secrets of a cluster by
+// NAMESPACE/NAME
+func fetchClusterSecrets(ctx context.Context, client *cluster.Client, selector string, allNamespaces bool) (map[string]clusterSecret, error) {
+	items, err := client.GetSecrets(ctx, selector, allNamespaces)
+	if err != nil {
+		return nil, err
+	}
+	secrets := make(map[string]clusterSecret, len(items))
+	for _, item := range items {
+		var s struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Type string            `json:"type"`
+			Data map[string]string `json:"data"`
+		}
+		if err := json.Unmarshal(item, &s); err != nil {
+			return nil, fmt.Errorf("failed to parse secret: %w", err)
+		}
+		id := s.Metadata.Namespace + "/" + s.Metadata.Name
+		values := make(map[string]string, len(s.Data))
+		for key, encoded := range s.Data {
+			value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+			if err != nil {
+				return nil, fmt.Errorf("%s: key %q is not valid base64: %w", id, key, err)
+			}
+			values[key] = string(value)
+		}
+		secrets[id] = clusterSecret{Namespace: s.Metadata.Namespace, Name: s.Metadata.Name, Type: s.Type, Values: values, Raw: item}
+	}
+	return secrets, nil
+}
@@ -0,0 +1,80 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSync(t *testing.T) {
+	dir := t.TempDir()
+	// The fake kubectl lists DIR/CONTEXT.json and logs writes to DIR/writes
+	script := `#!/bin/sh
+while [ "${1#--}" != "$1" ]; do
+  [ "$1" = --context ] && ctx="$2"
+  shift 2
+done
+case "$1" in
+get) cat "` + dir + `/$ctx.json" ;;
+apply) echo "$ctx apply $(grep -c password)" >> "` + dir + `/writes" ;;
+patch) echo "$ctx $1 $2 $3 $(cat "$9")" >> "` + dir + `/writes" ;;
+*) echo "$ctx $*" >> "` + dir + `/writes" ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "kubectl"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake kubectl: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// "c2VjcmV0" and "c2VjcmV0\n" differ as base64 but not decoded
+	active := `{"items":[
+  {"metadata":{"name":"db","namespace":"prod"},"type":"Opaque","data":{"password":"bmV3","user":"YWRtaW4=","host":"ZGI="}},
+  {"metadata":{"name":"api","namespace":"prod"},"type":"Opaque","data":{"password":"c2VjcmV0"}},
+  {"metadata":{"name":"cache","namespace":"prod"},"type":"Opaque","data":{"password":"eA=="}}]}`
+	standby := `{"items":[
+  {"metadata":{"name":"db","namespace":"prod"},"type":"Opaque","data":{"password":"b2xk","user":"YWRtaW4=","local":"eA=="}},
+  {"metadata":{"name":"api","namespace":"prod"},"type":"Opaque","data":{"password":"c2VjcmV0\n"}}]}`
+	for name, list := range map[string]string{"active": active, "standby": standby} {
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), []byte(list), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stderr = &strings.Builder{}
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	out := captureStdout(t)
+	if err := run([]string{"sync", "--from", "active", "--to", "standby", "-l", "app=foo", "-A", "--dry-run"}); err != nil {
+		t.Fatalf("sync --dry-run failed: %v", err)
+	}
+	want := "prod/cache: + password\nprod/db: + host\nprod/db: ~ password\n"
+	if out.String() != want {
+		t.Errorf("sync --dry-run output = %q, want %q", out.String(), want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "writes")); !os.IsNotExist(err) {
+		t.Errorf("sync --dry-run wrote to the cluster: %v", err)
+	}
+
+	out.Reset()
+	reportFile := filepath.Join(dir, "sync.json")
+	if err := run([]string{"sync", "--from", "active", "--to", "standby", "-l", "app=foo", "-A", "--dry-run", "--report", reportFile, "--report", "text=-"}); err != nil {
+		t.Fatalf("sync --report failed: %v", err)
+	}
+	if out.String() != want {
+		t.Errorf("sync --report text=- output = %q, want %q", out.String(), want)
+	}
+	if data, err := os.ReadFile(reportFile); err != nil || !strings.Contains(string(data), `"op": "changed"`) {
+		t.Errorf("sync report = %s, %v, want the changed password", data, err)
+	}
+
+	if err := run([]string{"sync", "--from", "active", "--to", "standby", "-l", "app=foo", "-A"}); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	writes, _ := os.ReadFile(filepath.Join(dir, "writes"))
+	wantWrites := "standby apply 1\n" + `standby patch secret db {"data":{"host":"ZGI=","password":"bmV3"}}` + "\n"
+	if string(writes) != wantWrites {
+		t.Errorf("writes = %q, want %q", writes, wantWrites)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// completionShells are the shells swk completion writes scripts for
+var completionShells = []string{"bash", "zsh", "fish"}
+
+func init() {
+	commands["completion"] = runCompletion
+}
+
+// runCompletion handles `swk completion bash|zsh|fish`, printing a script
+// that completes the commands and global flags of this binary, e.g.
+// source <(swk completion bash)
+func runCompletion(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: swk completion %s", strings.Join(completionShells, "|"))
+	}
+	script, err := completionScript(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(stdout, script)
+	return nil
+}
+
+// completionScript returns the completion script for shell. Anything after
+// the command completes as a file name, or, in binaries with swk ls, as the
+// name of a Secret in the cluster once it starts with secret/. Those come
+// from swk ls --names, so they are cached like any listing.
+func completionScript(shell string) (string, error) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	flags := []string{"--lang"}
+	for _, name := range globalEnv {
+		flags = append(flags, "--"+name)
+	}
+	words := strings.Join(names, " ")
+	_, secrets := commands["ls"]
+
+	switch shell {
+	case "bash":
+		var b strings.Builder
+		fmt.Fprintf(&b, `# bash completion for swk
+_swk() {
+    local cur=${COMP_WORDS[COMP_CWORD]}
+    if [[ $COMP_CWORD -eq 1 ]]; then
+        COMPREPLY=($(compgen -W "%s %s" -- "$cur"))
+        [[ ${#COMPREPLY[@]} -gt 0 ]] && return
+    fi
+`, words, strings.Join(flags, " "))
+		if secrets {
+			b.WriteString(`    if [[ $cur == secret/* ]]; then
+        local i args=()
+        for ((i = 2; i < COMP_CWORD; i++)); do
+            case ${COMP_WORDS[i]} in
+            -n|--namespace|--context|--kubeconfig) args+=("${COMP_WORDS[i]}" "${COMP_WORDS[i+1]}") ;;
+            esac
+        done
+        COMPREPLY=($(compgen -W "$(swk ls --names "${args[@]}" 2>/dev/null)" -- "$cur"))
+        return
+    fi
+`)
+		}
+		b.WriteString(`    COMPREPLY=($(compgen -f -- "$cur"))
+}
+complete -o filenames -F _swk swk
+`)
+		return b.String(), nil
+	case "zsh":
+		files := "_files"
+		if secrets {
+			files = `if [[ $PREFIX == secret/* ]]; then
+        local i args=()
+        for ((i = 3; i < CURRENT; i++)); do
+            case $words[i] in
+            -n|--namespace|--context|--kubeconfig) args+=($words[i] $words[i+1]) ;;
+            esac
+        done
+        compadd -- $(swk ls --names $args 2>/dev/null)
+    else
+        _files
+    fi`
+		}
+		return fmt.Sprintf(`#compdef swk
+# zsh completion for swk
+_swk() {
+    if (( CURRENT == 2 )); then
+        _alternative 'commands:command:(%s)' 'flags:flag:(%s)' 'files:file:_files'
+    else
+        %s
+    fi
+}
+compdef _swk swk
+`, words, strings.Join(flags, " "), files), nil
+	case "fish":
+		var b strings.Builder
+		b.WriteString("# fish completion for swk\n")
+		fmt.Fprintf(&b, "complete -c swk -n __fish_use_subcommand -a '%s'\n", words)
+		for _, flag := range flags {
+			fmt.Fprintf(&b, "complete -c swk -n __fish_use_subcommand -l %s\n", strings.TrimPrefix(flag, "--"))
+		}
+		if secrets {
+			b.WriteString("complete -c swk -n 'not __fish_use_subcommand; and string match -q \"secret/*\" -- (commandline -ct)' -f -a '(swk ls --names 2>/dev/null)'\n")
+		}
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("unsupported shell %q; use %s", shell, strings.Join(completionShells, ", "))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/safefile"
+)
+
+// runConfig handles `swk config path|list|get KEY|set KEY VALUE|schema|env`,
+// inspecting and changing the config file by dotted keys such as
+// approval.webhook
+func runConfig(args []string) error {
+	const usage = "usage: swk config path | list | get KEY | set KEY VALUE | schema | env"
+	if len(args) == 0 {
+		return fmt.Errorf(usage)
+	}
+	path, err := config.Path()
+	if err != nil {
+		return fmt.Errorf("failed to find the config file: %w", err)
+	}
+
+	switch cmd, rest := args[0], args[1:]; {
+	case cmd == "path" && len(rest) == 0:
+		fmt.Fprintln(stdout, path)
+	case cmd == "schema" && len(rest) == 0:
+		schema, err := config.JSONSchema()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s\n", schema)
+	case cmd == "env" && len(rest) == 0:
+		// Settings first, then the global flags; set ones are marked
+		for _, v := range config.EnvVars() {
+			fmt.Fprintf(stdout, "%s%s\t%s (%s)\n", envMark(v.Name), v.Name, v.Key, v.Format)
+		}
+		for _, name := range globalEnv {
+			fmt.Fprintf(stdout, "%s%s\t--%s\n", envMark(globalEnvName(name)), globalEnvName(name), name)
+		}
+	case cmd == "list" && len(rest) == 0:
+		data, err := readConfig(path)
+		if err != nil {
+			return err
+		}
+		lines, err := config.List(data)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			fmt.Fprintln(stdout, line)
+		}
+	case cmd == "get" && len(rest) == 1:
+		data, err := readConfig(path)
+		if err != nil {
+			return err
+		}
+		value, err := config.Get(data, rest[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, value)
+	case cmd == "set" && len(rest) == 2:
+		data, err := readConfig(path)
+		if err != nil {
+			return err
+		}
+		updated, err := config.Set(data, rest[0], rest[1])
+		if err != nil {
+			return err
+		}
+		// Refuse a change that leaves the file invalid, e.g. a profile
+		// needing approval without a webhook
+		if _, err := config.Parse(updated, path); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return fmt.Errorf("failed to create config directory: %w", err)
+		}
+		if err := safefile.WriteFile(path, updated, 0600); err != nil {
+			return fmt.Errorf("failed to write config: %w", err)
+		}
+	default:
+		return fmt.Errorf(usage)
+	}
+	return nil
+}
+
+// envMark flags environment variables that are set
+func envMark(name string) string {
+	if _, ok := os.LookupEnv(name); ok {
+		return "* "
+	}
+	return "  "
+}
+
+// readConfig reads the config file, which may not exist yet
+func readConfig(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return data, nil
+}
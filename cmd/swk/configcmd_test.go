@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swk", "config.yaml")
+	t.Setenv("SWK_CONFIG", path)
+
+	steps := []struct {
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{[]string{"path"}, path + "\n", false},
+		{[]string{"list"}, "", false},
+		{[]string{"set", "approval.webhook", "https://example.com/hook"}, "", false},
+		{[]string{"set", "profiles.prod.approval", "true"}, "", false},
+		{[]string{"get", "profiles.prod.approval"}, "true\n", false},
+		{[]string{"list"}, "approval.webhook=https://example.com/hook\nprofiles.prod.approval=true\n", false},
+		{[]string{"set", "approval.webhook", ""}, "", true},
+		{[]string{"set", "approval.timeout", "soon"}, "", true},
+		{[]string{"get", "approval.timeout"}, "", true},
+		{[]string{"get", "approvals"}, "", true},
+		{[]string{"set", "watched"}, "", true},
+	}
+
+	out := captureStdout(t)
+	for _, step := range steps {
+		out.Reset()
+		err := run(append([]string{"config"}, step.args...))
+		if (err != nil) != step.wantErr {
+			t.Fatalf("config %v error = %v, wantErr %v", step.args, err, step.wantErr)
+		}
+		if got := out.String(); got != step.want {
+			t.Errorf("config %v output = %q, want %q", step.args, got, step.want)
+		}
+	}
+
+	// The refused changes left the file as it was
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "timeout") || !strings.Contains(string(data), "webhook: https://example.com/hook") {
+		t.Errorf("config =\n%s", data)
+	}
+}
+
+func TestRunConfigEnv(t *testing.T) {
+	t.Setenv("SWK_APPROVAL_WEBHOOK", "https://example.com/hook")
+	out := captureStdout(t)
+	if err := run([]string{"config", "env"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"* SWK_APPROVAL_WEBHOOK\tapproval.webhook (string)\n",
+		"  SWK_PROFILES\tprofiles (YAML)\n",
+		"  SWK_YES\t--yes\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output = %q, want it to contain %q", out, want)
+		}
+	}
+}
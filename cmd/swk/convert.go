@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+	"gopkg.in/yaml.v3"
+)
+
+// serverFields are metadata fields the API server sets, which don't belong
+// in a manifest for another object
+var serverFields = map[string]bool{
+	"uid": true, "resourceVersion": true, "creationTimestamp": true, "generation": true,
+	"managedFields": true, "selfLink": true, "ownerReferences": true,
+}
+
+// lastAppliedAnnotation is kubectl's copy of the last applied manifest
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// keyValue is a key and its raw value
+type keyValue struct {
+	Key, Value string
+}
+
+// runConvert handles `swk convert to-configmap|to-secret FILE [--keys
+// K1,K2] [--name NAME] [--move] [-o OUT]`, turning a Secret into a
+// ConfigMap or back, for moving values that aren't sensitive out of a
+// Secret or ones that are into it. Metadata is kept, apart from the fields
+// the API server sets; values are decoded or encoded as the target needs.
+// With --move, the converted keys are removed from FILE.
+func runConvert(args []string) error {
+	const usage = "usage: swk convert to-configmap|to-secret FILE [--keys K1,K2] [--name NAME] [--move] [-o OUT]"
+	if len(args) == 0 || (args[0] != "to-configmap" && args[0] != "to-secret") {
+		return fmt.Errorf(usage)
+	}
+	toSecret := args[0] == "to-secret"
+
+	fs := flag.NewFlagSet("swk convert "+args[0], flag.ContinueOnError)
+	keyList := fs.String("keys", "", "Comma-separated keys to convert (default: all)")
+	name := fs.String("name", "", "Name of the converted object (default: the name of FILE's)")
+	move := fs.Bool("move", false, "Remove the converted keys from FILE")
+	output := fs.String("o", "-", "Write the converted object to this file (- for stdout)")
+	positional, err := parseFlags(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *output == "" {
+		return fmt.Errorf(usage)
+	}
+	filePath := positional[0]
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("%s holds no manifest", filePath)
+	}
+	src := doc.Content[0]
+
+	sections, err := valueSections(src, toSecret)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	values, err := sourceValues(src, sections)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	if *keyList != "" {
+		if values, err = selectValues(values, strings.Split(*keyList, ",")); err != nil {
+			return fmt.Errorf("%s: %w", filePath, err)
+		}
+	}
+
+	converted, err := marshalNode(convertedObject(src, values, toSecret, *name))
+	if err != nil {
+		return err
+	}
+	if err := writeResult("", *output, converted); err != nil {
+		return err
+	}
+	if !*move {
+		return nil
+	}
+
+	moved := map[string]bool{}
+	for _, v := range values {
+		moved[v.Key] = true
+	}
+	for _, section := range sections {
+		removeKeys(findNode(src, section), moved)
+	}
+	remaining, err := marshalNode(src)
+	if err != nil {
+		return err
+	}
+	return writeResult(filePath, "", remaining)
+}
+
+// valueSections returns the fields holding values in the source object,
+// base64 first: data and stringData of a Secret, or binaryData and data of
+// a ConfigMap
+func valueSections(src *yaml.Node, toSecret bool) ([]string, error) {
+	kind := scalarField(src, "kind")
+	if toSecret {
+		if kind != "ConfigMap" {
+			return nil, fmt.Errorf("not a ConfigMap, but %q", kind)
+		}
+		return []string{"binaryData", "data"}, nil
+	}
+	v, ok := secret.ResolveVersion(scalarField(src, "apiVersion"), kind)
+	if !ok {
+		return nil, fmt.Errorf("not a Secret, but %q", kind)
+	}
+	return []string{v.DataField, v.StringDataField}, nil
+}
+
+// sourceValues returns the raw values in the source object's sections, in
+// document order, decoding the base64 ones. A key in both sections is one
+// value, and the later wins, as stringData does on the server.
+func sourceValues(src *yaml.Node, sections []string) ([]keyValue, error) {
+	var values []keyValue
+	index := map[string]int{}
+	for i, section := range sections {
+		node := findNode(src, section)
+		if node == nil || node.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			key, value := node.Content[j].Value, node.Content[j+1].Value
+			if i == 0 {
+				decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+				if err != nil {
+					return nil, fmt.Errorf("key %q is not valid base64: %w", key, err)
+				}
+				value = string(decoded)
+			}
+			if at, ok := index[key]; ok {
+				values[at].Value = value
+				continue
+			}
+			index[key] = len(values)
+			values = append(values, keyValue{key, value})
+		}
+	}
+	return values, nil
+}
+
+// selectValues picks keys from values, in the order given
+func selectValues(values []keyValue, keys []string) ([]keyValue, error) {
+	var selected []keyValue
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		found := false
+		for _, v := range values {
+			if v.Key == key {
+				selected, found = append(selected, v), true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no key %q", key)
+		}
+	}
+	return selected, nil
+}
+
+// convertedObject builds the ConfigMap, or Secret, holding values, with
+// src's metadata
+func convertedObject(src *yaml.Node, values []keyValue, toSecret bool, name string) *yaml.Node {
+	kind := "ConfigMap"
+	if toSecret {
+		kind = "Secret"
+	}
+	root := mapping("apiVersion", "v1", "kind", kind)
+	if metadata := cleanMetadata(findNode(src, "metadata"), name); metadata != nil {
+		root.Content = append(root.Content, scalarNode("metadata"), metadata)
+	}
+	if toSecret {
+		root.Content = append(root.Content, scalarNode("type"), scalarNode("Opaque"))
+	}
+	if immutable := findNode(src, "immutable"); immutable != nil {
+		root.Content = append(root.Content, scalarNode("immutable"), immutable)
+	}
+
+	data := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	binary := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, v := range values {
+		switch {
+		case toSecret:
+			data.Content = append(data.Content, scalarNode(v.Key), scalarNode(base64.StdEncoding.EncodeToString([]byte(v.Value))))
+		case utf8.ValidString(v.Value):
+			value := scalarNode(v.Value)
+			if strings.Contains(v.Value, "\n") {
+				value.Style = yaml.LiteralStyle
+			}
+			data.Content = append(data.Content, scalarNode(v.Key), value)
+		default:
+			binary.Content = append(binary.Content, scalarNode(v.Key), scalarNode(base64.StdEncoding.EncodeToString([]byte(v.Value))))
+		}
+	}
+	if len(data.Content) > 0 {
+		root.Content = append(root.Content, scalarNode("data"), data)
+	}
+	if len(binary.Content) > 0 {
+		root.Content = append(root.Content, scalarNode("binaryData"), binary)
+	}
+	return root
+}
+
+// cleanMetadata copies metadata without the fields the API server sets or
+// kubectl's last applied manifest, renaming it if name isn't empty
+func cleanMetadata(metadata *yaml.Node, name string) *yaml.Node {
+	clean := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if metadata != nil && metadata.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(metadata.Content); i += 2 {
+			k, v := metadata.Content[i], metadata.Content[i+1]
+			switch {
+			case serverFields[k.Value]:
+				continue
+			case k.Value == "name" && name != "":
+				v = scalarNode(name)
+			case k.Value == "annotations" && v.Kind == yaml.MappingNode:
+				annotations := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				for j := 0; j+1 < len(v.Content); j += 2 {
+					if v.Content[j].Value != lastAppliedAnnotation {
+						annotations.Content = append(annotations.Content, v.Content[j], v.Content[j+1])
+					}
+				}
+				if len(annotations.Content) == 0 {
+					continue
+				}
+				v = annotations
+			}
+			clean.Content = append(clean.Content, k, v)
+		}
+	}
+	if name != "" && findNode(clean, "name") == nil {
+		clean.Content = append([]*yaml.Node{scalarNode("name"), scalarNode(name)}, clean.Content...)
+	}
+	if len(clean.Content) == 0 {
+		return nil
+	}
+	return clean
+}
+
+// removeKeys removes keys from a mapping node
+func removeKeys(node *yaml.Node, keys map[string]bool) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	kept := node.Content[:0]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !keys[node.Content[i].Value] {
+			kept = append(kept, node.Content[i], node.Content[i+1])
+		}
+	}
+	node.Content = kept
+}
+
+// findNode returns the value of key in a mapping node, or nil
+func findNode(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalarField returns the scalar value of key in a mapping node, or ""
+func scalarField(node *yaml.Node, key string) string {
+	if v := findNode(node, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const convertTestSecret = `apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+  namespace: prod
+  labels:
+    app: web
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: '{}'
+    team: platform
+  uid: 0b1c
+  resourceVersion: "42"
+type: Opaque
+data:
+  LOG_LEVEL: ZGVidWc=
+  CA_BUNDLE: LS0tLS0KYWJjCg==
+  blob: AP8=
+  password: aHVudGVyMg==
+`
+
+func TestRunConvert(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := os.WriteFile(file, []byte(convertTestSecret), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := captureStdout(t)
+
+	if err := run([]string{"convert", "to-configmap", file, "--keys", "LOG_LEVEL,CA_BUNDLE,blob", "--name", "app-config"}); err != nil {
+		t.Fatalf("convert to-configmap failed: %v", err)
+	}
+	want := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  namespace: prod
+  labels:
+    app: web
+  annotations:
+    team: platform
+data:
+  LOG_LEVEL: debug
+  CA_BUNDLE: |
+    -----
+    abc
+binaryData:
+  blob: AP8=
+`
+	if out.String() != want {
+		t.Errorf("ConfigMap =\n%s\nwant\n%s", out, want)
+	}
+	if data, _ := os.ReadFile(file); string(data) != convertTestSecret {
+		t.Errorf("FILE changed without --move:\n%s", data)
+	}
+
+	configMap := filepath.Join(t.TempDir(), "configmap.yaml")
+	if err := os.WriteFile(configMap, out.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := run([]string{"convert", "to-secret", configMap}); err != nil {
+		t.Fatalf("convert to-secret failed: %v", err)
+	}
+	for _, want := range []string{"kind: Secret\n", "name: app-config\n", "type: Opaque\n", "LOG_LEVEL: ZGVidWc=\n", "CA_BUNDLE: LS0tLS0KYWJjCg==\n", "blob: AP8=\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Secret missing %q:\n%s", want, out)
+		}
+	}
+
+	out.Reset()
+	if err := run([]string{"convert", "to-configmap", file, "--keys", "LOG_LEVEL", "--move"}); err != nil {
+		t.Fatalf("convert --move failed: %v", err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "LOG_LEVEL") || !strings.Contains(string(data), "password: aHVudGVyMg==") {
+		t.Errorf("FILE after --move:\n%s\nwant only LOG_LEVEL removed", data)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"convert", "to-yaml", file}, "usage: swk convert"},
+		{[]string{"convert", "to-secret", file}, "not a ConfigMap"},
+		{[]string{"convert", "to-configmap", configMap}, "not a Secret"},
+		{[]string{"convert", "to-configmap", file, "--keys", "nope"}, `no key "nope"`},
+	}
+	for _, tt := range tests {
+		if err := run(tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("run(%q) error = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/recovery"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/usage"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+)
+
+// deleteAfterFormat formats DeleteAfterLabel values, which can't hold colons
+const deleteAfterFormat = "20060102T150405Z"
+
+func init() {
+	commands["delete"] = runDelete
+}
+
+// runDelete handles `swk delete secret/NAME -n NAMESPACE [--grace DURATION]
+// [--force]` and `swk delete --expired`. Secrets still referenced by a
+// workload are refused, and a deleted Secret is kept as an encrypted
+// recovery entry.
+func runDelete(args []string) error {
+	var clusterOpts cluster.Options
+	fs := flag.NewFlagSet("swk delete", flag.ContinueOnError)
+	clusterOpts.BindFlags(fs)
+	force := fs.Bool("force", false, "Delete even if the Secret is still referenced")
+	grace := fs.Duration("grace", 0, "Only label the Secret for deletion by swk delete --expired once this has passed, e.g. 24h")
+	expired := fs.Bool("expired", false, "Delete the Secrets whose grace period has passed")
+	allNamespaces := fs.Bool("all-namespaces", false, "With --expired, look in every namespace")
+	fs.BoolVar(allNamespaces, "A", false, "Shorthand for -all-namespaces")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	client := cluster.New(clusterOpts)
+	if *expired {
+		if len(positional) > 0 || *grace != 0 {
+			return fmt.Errorf("usage: swk delete --expired [-n NAMESPACE | -A] [--force]")
+		}
+		return deleteExpired(client, clusterOpts.Namespace, *allNamespaces, *force)
+	}
+
+	const usage = "usage: swk delete secret/NAME -n NAMESPACE [--grace DURATION] [--force]"
+	if len(positional) != 1 || clusterOpts.Namespace == "" {
+		return fmt.Errorf(usage)
+	}
+	name, ok := secretArg(positional[0])
+	if !ok {
+		return fmt.Errorf(usage)
+	}
+	if *grace < 0 {
+		return fmt.Errorf("--grace must not be negative")
+	}
+	namespace := clusterOpts.Namespace
+
+	if err := checkUnused(client, namespace, name, *force); err != nil {
+		return err
+	}
+	if *grace > 0 {
+		after := now().Add(*grace).UTC()
+		if err := client.LabelSecret(context.Background(), namespace, name, secret.DeleteAfterLabel+"="+after.Format(deleteAfterFormat)); err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, i18n.T("Marked %s/%s for deletion after %s\n"), namespace, name, after.Format(time.DateTime))
+		return nil
+	}
+
+	if !assumeYes {
+		if !isTerminal() {
+			return fmt.Errorf(i18n.T("pass --yes to delete %s/%s"), namespace, name)
+		}
+		ok, err := ask(bufio.NewReader(stdin), stderr, fmt.Sprintf(i18n.T("Delete %s/%s?"), namespace, name))
+		if err != nil || !ok {
+			return err
+		}
+	}
+	return deleteWithBackup(client, namespace, name)
+}
+
+// deleteExpired deletes the Secrets labelled for deletion whose time has
+// come. Secrets that gained a reference since are left alone.
+func deleteExpired(client *cluster.Client, namespace string, allNamespaces, force bool) error {
+	items, err := client.GetSecrets(context.Background(), secret.DeleteAfterLabel, allNamespaces)
+	if err != nil {
+		return err
+	}
+	var failed int
+	for _, item := range items {
+		var s struct {
+			Metadata struct {
+				Name      string            `json:"name"`
+				Namespace string            `json:"namespace"`
+				Labels    map[string]string `json:"labels"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal(item, &s); err != nil {
+			return fmt.Errorf("failed to parse secret: %w", err)
+		}
+		ns, name := s.Metadata.Namespace, s.Metadata.Name
+		if ns == "" {
+			ns = namespace
+		}
+		after, err := time.Parse(deleteAfterFormat, s.Metadata.Labels[secret.DeleteAfterLabel])
+		if err != nil {
+			fmt.Fprintln(stderr, fmt.Sprintf(i18n.T("Warning: %s"), fmt.Sprintf("%s/%s: invalid %s label", ns, name, secret.DeleteAfterLabel)))
+			continue
+		}
+		if now().Before(after) {
+			continue
+		}
+		if err := checkUnused(client, ns, name, force); err == nil {
+			err = deleteWithBackup(client, ns, name)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, fmt.Sprintf(i18n.T("Warning: %s"), err))
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d Secret(s) not deleted", failed)
+	}
+	return nil
+}
+
+// checkUnused fails if a workload or other object in namespace references
+// the Secret, unless force is set
+func checkUnused(client *cluster.Client, namespace, name string, force bool) error {
+	list, err := client.Run(context.Background(), nil, "get", usage.Kinds, "--namespace", namespace, "-o", "json")
+	if err != nil {
+		return fmt.Errorf("failed to look for references to %s/%s: %w", namespace, name, err)
+	}
+	refs, err := usage.Find(list, name)
+	if err != nil || len(refs) == 0 {
+		return err
+	}
+	lines := make([]string, len(refs))
+	for i, r := range refs {
+		lines[i] = "  " + r.String()
+	}
+	if force {
+		fmt.Fprintf(stderr, i18n.T("Deleting %s/%s although it is still used by:\n%s\n"), namespace, name, strings.Join(lines, "\n"))
+		return nil
+	}
+	return fmt.Errorf(i18n.T("%s/%s is still used by:\n%s\npass --force to delete it anyway"), namespace, name, strings.Join(lines, "\n"))
+}
+
+// deleteWithBackup saves a Secret as an encrypted recovery entry, decoded
+// like an unsaved edit, and then deletes it
+func deleteWithBackup(client *cluster.Client, namespace, name string) error {
+	ctx := context.Background()
+	manifest, err := client.GetSecretIn(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	if manifest, err = relocateManifest(manifest, namespace, name); err != nil {
+		return err
+	}
+	doc, err := parseSecret(manifest, "")
+	if err != nil {
+		return err
+	}
+	if err := doc.Decode(); err != nil {
+		return fmt.Errorf("failed to decode secret: %w", err)
+	}
+	decoded, err := doc.Bytes()
+	if err != nil {
+		return fmt.Errorf("failed to decode secret: %w", err)
+	}
+
+	store, err := recovery.DefaultStore()
+	if err != nil {
+		return fmt.Errorf("failed to back up %s/%s: %w", namespace, name, err)
+	}
+	e, err := store.Save(recovery.Entry{Source: namespace + "-" + name + ".yaml"}, decoded)
+	if err != nil {
+		return fmt.Errorf("failed to back up %s/%s: %w", namespace, name, err)
+	}
+
+	if err := client.DeleteSecret(ctx, namespace, name); err != nil {
+		_ = store.Delete(e.ID)
+		return err
+	}
+	fmt.Fprintf(stderr, i18n.T("Deleted %s/%s; restore it with: swk recover %s\n"), namespace, name, e.ID)
+	return nil
+}
@@ -0,0 +1,114 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/recovery"
+)
+
+func TestRunDelete(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Cleanup(func() { assumeYes = false })
+	var errOut strings.Builder
+	stderr = &errOut
+	t.Cleanup(func() { stderr = os.Stderr })
+	withStdin(t, "", false)
+	now = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = time.Now })
+
+	cluster := fakeCluster(t)
+	source := filepath.Join(cluster, "prod", "db")
+	if err := os.MkdirAll(filepath.Dir(source), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\n  namespace: prod\n  uid: \"1234\"\ndata:\n  password: c2VjcmV0\n"
+	if err := os.WriteFile(source, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	objects := `{"items":[{"kind":"Deployment","metadata":{"name":"web"},"spec":{"template":{"spec":{"volumes":[{"name":"creds","secret":{"secretName":"db"}}]}}}}]}`
+	if err := os.WriteFile(filepath.Join(cluster, ".objects"), []byte(objects), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := run([]string{"--yes", "delete", "secret/db", "-n", "prod"})
+	if err == nil || !strings.Contains(err.Error(), "Deployment/web: volume creds") {
+		t.Fatalf("delete of a used Secret error = %v, want its users", err)
+	}
+
+	assumeYes = false
+	if err := run([]string{"delete", "secret/db", "-n", "prod", "--force", "--grace", "24h"}); err != nil {
+		t.Fatalf("delete --grace failed: %v", err)
+	}
+	labels, _ := os.ReadFile(filepath.Join(cluster, ".labels"))
+	if string(labels) != "prod/db swk.dev/delete-after=20261017T120000Z\n" {
+		t.Errorf("labels = %q, want the deletion time", labels)
+	}
+
+	if err := run([]string{"delete", "secret/db", "-n", "prod", "--force"}); err == nil || !strings.Contains(err.Error(), "pass --yes") {
+		t.Errorf("delete without --yes error = %v, want a refusal", err)
+	}
+	if err := run([]string{"--yes", "delete", "secret/db", "-n", "prod", "--force"}); err != nil {
+		t.Fatalf("delete --force failed: %v", err)
+	}
+	if _, err := os.Stat(source); !os.IsNotExist(err) {
+		t.Errorf("Secret still exists: %v", err)
+	}
+
+	store, err := recovery.DefaultStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := store.List()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("recovery entries = %v, %v, want the deleted Secret", entries, err)
+	}
+	_, data, err := store.Load(entries[0].ID)
+	if err != nil || !strings.Contains(string(data), "password: secret") || strings.Contains(string(data), "uid") {
+		t.Errorf("backup = %q, %v, want the decoded Secret", data, err)
+	}
+	if !strings.Contains(errOut.String(), "swk recover "+entries[0].ID) {
+		t.Errorf("stderr = %q, want the recovery hint", errOut.String())
+	}
+}
+
+func TestRunDeleteExpired(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	stderr = &strings.Builder{}
+	t.Cleanup(func() { stderr = os.Stderr })
+	now = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = time.Now })
+
+	cluster := fakeCluster(t)
+	if err := os.MkdirAll(filepath.Join(cluster, "prod"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var items []string
+	for name, after := range map[string]string{"old": "20261015T000000Z", "new": "20261020T000000Z"} {
+		manifest := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: " + name + "\n  namespace: prod\ndata:\n  password: c2VjcmV0\n"
+		if err := os.WriteFile(filepath.Join(cluster, "prod", name), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, `{"metadata":{"name":"`+name+`","namespace":"prod","labels":{"swk.dev/delete-after":"`+after+`"}}}`)
+	}
+	if err := os.WriteFile(filepath.Join(cluster, ".secrets"), []byte(`{"items":[`+strings.Join(items, ",")+`]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"delete", "--expired", "-A"}); err != nil {
+		t.Fatalf("delete --expired failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cluster, "prod", "old")); !os.IsNotExist(err) {
+		t.Errorf("expired Secret still exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cluster, "prod", "new")); err != nil {
+		t.Errorf("Secret in its grace period was deleted: %v", err)
+	}
+}
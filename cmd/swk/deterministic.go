@@ -0,0 +1,23 @@
+package main
+
+import (
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+)
+
+// deterministic makes every manifest swk writes or prints canonical, so
+// that the same content always gives the same bytes, e.g. for pipelines
+// that hash rendered manifests to detect drift. Set by --deterministic.
+var deterministic bool
+
+// canonicalOutput returns manifests about to be written as they are, or
+// with --deterministic in canonical form. Encoded Secrets also get their
+// data values in canonical base64, which decoded ones don't have.
+func canonicalOutput(data []byte, encoded bool) ([]byte, error) {
+	switch {
+	case !deterministic:
+		return data, nil
+	case encoded:
+		return secret.CanonicalEncoded(data)
+	}
+	return secret.Canonical(data)
+}
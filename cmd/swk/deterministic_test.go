@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunDeterministic(t *testing.T) {
+	t.Cleanup(func() { deterministic = false })
+	inputs := []string{
+		"apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nstringData:\n  user: admin\n  password: \"s3cret\"\n",
+		"---\nkind: Secret\nstringData: {password: s3cret, user: 'admin'}\nmetadata:\n    name: db\napiVersion: v1\n",
+	}
+
+	var outputs []string
+	for _, input := range inputs {
+		out := captureStdout(t)
+		withStdin(t, input, false)
+		if err := run([]string{"--deterministic", "encode", "-"}); err != nil {
+			t.Fatalf("run() failed: %v", err)
+		}
+		outputs = append(outputs, out.String())
+	}
+	if outputs[0] != outputs[1] {
+		t.Errorf("outputs differ:\n%s\n---\n%s", outputs[0], outputs[1])
+	}
+	if want := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nstringData:\n  password: s3cret\n  user: admin\n"; outputs[0] != want {
+		t.Errorf("output =\n%s\nwant\n%s", outputs[0], want)
+	}
+}
+
+func TestRunDeterministicWrite(t *testing.T) {
+	t.Cleanup(func() { deterministic = false })
+	path := filepath.Join(t.TempDir(), "secret.yaml")
+	// Quoted values, a document marker, and keys out of order
+	if err := os.WriteFile(path, []byte("---\nkind: Secret\napiVersion: v1\nmetadata:\n    name: db\ndata:\n    username: \"YWRtaW4=\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"--deterministic", "set", path, "password", "s3cret"}); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	want := "apiVersion: v1\ndata:\n  password: czNjcmV0\n  username: YWRtaW4=\nkind: Secret\nmetadata:\n  name: db\n"
+	if string(data) != want {
+		t.Errorf("file =\n%s\nwant\n%s", data, want)
+	}
+
+	err := run([]string{"--deterministic", "breakglass", "set", path, "password", "x", "--reason", "outage", "--ticket", "INC-1"})
+	if err == nil || !strings.Contains(err.Error(), "can't be --deterministic") {
+		t.Errorf("run(breakglass) error = %v, want it refused", err)
+	}
+}
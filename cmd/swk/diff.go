@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/output"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/valuediff"
+)
+
+// diffTool shows how values changed where swk otherwise lists the changed
+// keys only: words or chars for a built-in diff within each value, or an
+// external command. Set by --diff-tool.
+var diffTool string
+
+// writeValueDiff shows the changes between two sets of decoded values with
+// tool, a built-in diff or an external command
+func writeValueDiff(w io.Writer, colors output.Terminal, before, after map[string]string, tool string) error {
+	if valuediff.Builtin(tool) {
+		valuediff.Write(w, before, after, tool, colors)
+		return nil
+	}
+	return valuediff.External(tool, before, after, w, stderr)
+}
+
+// confirmDiff shows how a write about to be confirmed changes the values
+// of the file at path, if --diff-tool asks for it
+func confirmDiff(path string, data []byte) error {
+	if diffTool == "" {
+		return nil
+	}
+	existing, _ := os.ReadFile(path)
+	return writeValueDiff(stderr, stderrTerminal(), decodedValues(existing, ""), decodedValues(data, ""), diffTool)
+}
+
+// showEditDiff shows how an edit changes a manifest: as a line diff of the
+// decoded documents, or with --diff-tool if given
+func showEditDiff(before, after []byte, jsonPath string) error {
+	colors := stderrTerminal()
+	if diffTool != "" {
+		return writeValueDiff(stderr, colors, decodedValues(before, jsonPath), decodedValues(after, jsonPath), diffTool)
+	}
+	valuediff.Lines(stderr, decodedText(before, jsonPath), decodedText(after, jsonPath), 3, colors)
+	return nil
+}
+
+// confirmSave asks question, after the diff of what is about to be saved,
+// returning errEditCanceled for no. --yes answers yes.
+func confirmSave(question string) error {
+	if assumeYes {
+		return nil
+	}
+	if !isTerminal() {
+		return fmt.Errorf("%s", i18n.T("--confirm needs a terminal to ask; pass --yes to save anyway"))
+	}
+	ok, err := ask(bufio.NewReader(stdin), stderr, question)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errEditCanceled
+	}
+	return nil
+}
+
+// decodedText returns a manifest with its Secret decoded, except for
+// binary values, or as it is if it isn't a Secret
+func decodedText(data []byte, jsonPath string) string {
+	doc, err := parseSecret(data, jsonPath)
+	if err != nil || !doc.IsSecret() || doc.DecodeText() != nil {
+		return string(data)
+	}
+	decoded, err := doc.Bytes()
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
+
+// decodedValues returns the data and stringData values of a manifest,
+// decoded except for binary values; none if it isn't a Secret
+func decodedValues(data []byte, jsonPath string) map[string]string {
+	values := map[string]string{}
+	doc, err := parseSecret(data, jsonPath)
+	if err != nil || !doc.IsSecret() || doc.DecodeText() != nil {
+		return values
+	}
+	for _, e := range append(doc.Data(), doc.StringData()...) {
+		values[e.Key] = e.Value
+	}
+	return values
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+)
+
+func TestConfirmDiff(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("profiles:\n  prod:\n    namespaces: [prod]\n    confirm: true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvPath, cfgPath)
+	file := filepath.Join(t.TempDir(), "db.yaml")
+	if err := os.WriteFile(file, []byte(strings.Replace(setTestSecret, "name: test-secret", "name: db\n  namespace: prod", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { diffTool, assumeYes = "", false })
+	assumeYes = false
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"keys only", []string{"set", file, "username", "root"}, "Profile prod: write"},
+		{"words", []string{"--diff-tool", "words", "set", file, "username", "administrator"}, "~ username\n    [-root-]{+administrator+}\n"},
+		{"chars", []string{"--diff-tool", "chars", "set", file, "username", "admin"}, "~ username\n    admin[-istrator-]\n"},
+		{"external", []string{"--diff-tool", "diff", "set", file, "username", "root"}, "> username: root"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withStdin(t, "y\n", true)
+			var out strings.Builder
+			stderr = &out
+			t.Cleanup(func() { stderr = os.Stderr })
+
+			if err := run(tt.args); err != nil {
+				t.Fatalf("run() failed: %v", err)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+			if tt.name == "keys only" && strings.Contains(out.String(), "username") {
+				t.Errorf("output = %q, want no values without --diff-tool", out.String())
+			}
+		})
+	}
+}
+
+func TestRunConfirm(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		input    string
+		terminal bool
+		want     string
+		wantErr  string
+		saved    bool
+	}{
+		{"yes", nil, "y\n", true, "-   username: admin\n+   username: root\n", "", true},
+		{"no", nil, "n\n", true, "Edit canceled", "", false},
+		{"no terminal", nil, "", false, "", "needs a terminal", false},
+		{"assume yes", []string{"--yes"}, "", false, "+   username: root", "", true},
+		{"diff tool", []string{"--diff-tool", "chars"}, "yes\n", true, "~ username\n    [-admin-]{+root+}\n", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "secret.yaml")
+			if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+				t.Fatal(err)
+			}
+			script := filepath.Join(t.TempDir(), "editor.sh")
+			if err := os.WriteFile(script, []byte("#!/bin/sh\nsed -i 's/admin/root/' \"$1\"\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			withStdin(t, tt.input, tt.terminal)
+			var out strings.Builder
+			stderr = &out
+			t.Cleanup(func() { stderr, diffTool, assumeYes = os.Stderr, "", false })
+
+			err := run(append(tt.args, "--confirm", "-e", script, file))
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("run() failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("run() error = %v, want %q", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+			want := "admin"
+			if tt.saved {
+				want = "root"
+			}
+			if got := queryFile(t, file, ".data.username | @base64d"); got != want {
+				t.Errorf("username = %q, want %q", got, want)
+			}
+		})
+	}
+}
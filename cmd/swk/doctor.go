@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/doctor"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/editor"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/tempdir"
+)
+
+// runDoctor handles `swk doctor [--editor EDITOR] [cluster flags]`,
+// checking the environment and printing a fix for every problem found. It
+// fails if a check swk can't work without fails.
+func runDoctor(args []string) error {
+	var clusterOpts cluster.Options
+	fs := flag.NewFlagSet("swk doctor", flag.ContinueOnError)
+	editorFlag := fs.String("editor", "", "Editor to check instead of $EDITOR or $VISUAL")
+	clusterOpts.BindFlags(fs)
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("usage: swk doctor [--editor EDITOR] [--kubeconfig FILE] [--context NAME]")
+	}
+	// A cluster that doesn't answer quickly is reported, not waited for
+	if clusterOpts.RequestTimeout > 5*time.Second {
+		clusterOpts.RequestTimeout = 5 * time.Second
+	}
+	clusterOpts.MaxRetries = 0
+
+	results := []doctor.Result{
+		doctor.Editor(editor.SelectEditor(*editorFlag)),
+		doctor.TempDir(tempdir.Parent()),
+		checkConfig(),
+	}
+	results = append(results, checkCluster(clusterOpts)...)
+	results = append(results,
+		doctor.Tool("sops", "--sops and SOPS-encrypted files", "install sops: https://github.com/getsops/sops/releases"),
+		doctor.Tool("age", "age keys used by sops", "install age: https://github.com/FiloSottile/age#installation"),
+		doctor.Tool("kubeseal", "sealing Secrets for the Sealed Secrets controller", "install kubeseal: https://github.com/bitnami-labs/sealed-secrets#kubeseal"),
+	)
+
+	failed := 0
+	for _, r := range results {
+		fmt.Fprintf(stdout, "[%s] %s: %s\n", r.Status, r.Name, r.Detail)
+		if r.Fix != "" {
+			fmt.Fprintf(stdout, "       fix: %s\n", r.Fix)
+		}
+		if r.Status == doctor.Fail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// checkConfig loads the config file, with the environment overrides
+func checkConfig() doctor.Result {
+	r := doctor.Result{Name: "config"}
+	path, err := config.Path()
+	if err != nil {
+		r.Status, r.Detail = doctor.OK, "no config directory; using defaults"
+		return r
+	}
+	if _, err := config.Load(path); err != nil {
+		r.Status, r.Detail = doctor.Fail, err.Error()
+		r.Fix = fmt.Sprintf("fix %s, e.g. with swk config set, or the SWK_* variable named above", path)
+		return r
+	}
+	if _, err := os.Stat(path); err != nil {
+		r.Status, r.Detail = doctor.OK, fmt.Sprintf("%s doesn't exist; using defaults", path)
+		return r
+	}
+	r.Status, r.Detail = doctor.OK, path
+	return r
+}
+
+// checkCluster checks for kubectl, a current context, and whether its API
+// server answers
+func checkCluster(opts cluster.Options) []doctor.Result {
+	tool := doctor.Tool("kubectl", "kubectl edit and every cluster command", "install kubectl: https://kubernetes.io/docs/tasks/tools/")
+	if tool.Status != doctor.OK {
+		tool.Status = doctor.Fail
+		return []doctor.Result{tool}
+	}
+
+	r := doctor.Result{Name: "cluster"}
+	client := cluster.New(opts)
+	ctx := context.Background()
+	name, err := client.CurrentContext(ctx)
+	if err != nil || name == "" {
+		r.Status, r.Detail = doctor.Warn, "no current kubeconfig context; only local files can be edited"
+		r.Fix = "kubectl config use-context NAME, or pass --kubeconfig and --context"
+		return []doctor.Result{tool, r}
+	}
+	if _, err := client.Run(ctx, nil, "version", "-o", "json"); err != nil {
+		r.Status, r.Detail = doctor.Warn, fmt.Sprintf("context %s: the API server didn't answer: %v", name, err)
+		r.Fix = "check the network or VPN, and that the credentials in the kubeconfig haven't expired"
+		return []doctor.Result{tool, r}
+	}
+	r.Status, r.Detail = doctor.OK, fmt.Sprintf("context %s is reachable", name)
+	return []doctor.Result{tool, r}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/tempdir"
+)
+
+func TestRunDoctor(t *testing.T) {
+	fakeCluster(t)
+	t.Cleanup(func() { tempdir.Override = "" })
+	tempdir.Override = t.TempDir()
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("SWK_CONFIG", config)
+
+	out := captureStdout(t)
+	if err := run([]string{"doctor", "--editor", "true"}); err != nil {
+		t.Fatalf("run() error = %v\n%s", err, out)
+	}
+	for _, want := range []string{"[ok] editor: true", "[ok] config:", "[ok] kubectl:", "[ok] cluster: context test-context is reachable"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output = %s, want it to contain %q", out, want)
+		}
+	}
+
+	// A broken config fails the run, with a fix
+	if err := os.WriteFile(config, []byte("approval:\n  webhok: x\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := run([]string{"doctor", "--editor", "true"}); err == nil {
+		t.Error("run() should fail with an invalid config")
+	}
+	if !strings.Contains(out.String(), "[fail] config:") || !strings.Contains(out.String(), "fix: fix "+config) {
+		t.Errorf("output = %s, want a config failure with a fix", out)
+	}
+}
+
+// fakeCluster puts a kubectl on PATH that keeps Secrets as files in
+// DIR/NAMESPACE/NAME, and returns DIR. Listings of Secrets and of objects
+// that could use them come from DIR/.secrets and DIR/.objects, and labels
+// set are logged to DIR/.labels.
+func fakeCluster(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	bin := t.TempDir()
+	script := `#!/bin/sh
+while [ "${1#--}" != "$1" ]; do shift 2; done
+case "$1 $2" in
+"get secrets")
+  cat "` + dir + `/.secrets" 2>/dev/null || echo '{"items":[]}' ;;
+"get secret")
+  f="` + dir + `/$5/$3"
+  if [ "$6" = --ignore-not-found ]; then
+    [ -f "$f" ] && echo "secret/$3"
+    exit 0
+  fi
+  cat "$f" ;;
+"get "*)
+  cat "` + dir + `/.objects" 2>/dev/null || echo '{"items":[]}' ;;
+"config current-context")
+  echo test-context ;;
+"label "*)
+  echo "$5/$3 $7" >> "` + dir + `/.labels" ;;
+"apply "*|"replace "*)
+  cat > "` + dir + `/.applied"
+  ns=$(sed -n 's/^  namespace: //p' "` + dir + `/.applied")
+  name=$(sed -n 's/^  name: //p' "` + dir + `/.applied")
+  mkdir -p "` + dir + `/$ns" && mv "` + dir + `/.applied" "` + dir + `/$ns/$name" ;;
+"delete "*)
+  rm "` + dir + `/$5/$3" ;;
+"create token")
+  echo "$@" >> "` + dir + `/.tokens"
+  echo '{"kind":"TokenRequest","status":{"token":"token-for-'"$5-$3"'","expirationTimestamp":"2026-10-16T13:00:00Z"}}' ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(bin, "kubectl"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake kubectl: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return dir
+}
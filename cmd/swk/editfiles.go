@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/editor"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/output"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/safefile"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/tempdir"
+)
+
+// editTarget is one of the files of a multi-file edit
+type editTarget struct {
+	path     string // resolved path of the file
+	data     []byte // contents before the edit
+	tmpPath  string // what the editor opens
+	cleanup  func() // removes tmpPath with its private directory
+	isSecret bool
+	opts     options
+}
+
+// editFiles wraps one editor session around several local files. Every
+// file is encoded and checked before any is written; the results are then
+// staged next to their files and renamed into place together, so an edit
+// that fails for one file, or is canceled in one, changes none of them.
+func editFiles(opts options) error {
+	if opts.compare {
+		return fmt.Errorf("--compare takes a single file")
+	}
+	if opts.cluster != (cluster.Options{}) {
+		return fmt.Errorf("--namespace, --context, and --kubeconfig need a Secret in the cluster, given as secret/NAME")
+	}
+
+	var targets []*editTarget
+	defer func() {
+		for _, t := range targets {
+			t.cleanup()
+		}
+	}()
+	seen := map[string]bool{}
+	for _, file := range opts.files {
+		if _, ok := secretInCluster(file); ok {
+			return fmt.Errorf("%s: Secrets in the cluster are edited one at a time", file)
+		}
+		if _, ok := parseRemote(file); ok {
+			return fmt.Errorf("%s: remote files are edited one at a time", file)
+		}
+		t, err := prepareTarget(file, opts)
+		if err != nil {
+			return err
+		}
+		if seen[t.path] {
+			t.cleanup()
+			return fmt.Errorf("%s is given more than once", file)
+		}
+		seen[t.path] = true
+		targets = append(targets, t)
+	}
+
+	editorCmd := editor.SelectEditor(opts.editor)
+	var tmpPaths []string
+	for _, t := range targets {
+		tmpPaths = append(tmpPaths, t.tmpPath)
+	}
+	if err := editor.LaunchEditor(editorCmd, tmpPaths...); err != nil {
+		// Don't throw away what may be a long edit because the editor crashed
+		return recoverTargets(targets, fmt.Errorf(i18n.T("editor failed: %w"), err))
+	}
+
+	// Encode and check every file before writing any. On a terminal, a file
+	// that can't be saved gets the reason above it and the editor is
+	// reopened, as for a single file.
+	var changed []*editTarget
+	results := map[*editTarget][]byte{}
+	previous := map[*editTarget][]byte{}
+encode:
+	for {
+		changed = nil
+		for _, t := range targets {
+			encoded, err := encodeTarget(t)
+			if errors.Is(err, errEditCanceled) {
+				fmt.Fprintf(stderr, "Edit canceled in %s; no file was changed\n", t.path)
+				return nil
+			}
+			if err != nil && (!t.isSecret || !isTerminal()) {
+				return fmt.Errorf("%s: %w", t.path, err)
+			}
+			if err != nil {
+				edit, markErr := markInvalid(t.tmpPath, err)
+				if markErr != nil {
+					return fmt.Errorf("%s: %w", t.path, errors.Join(err, markErr))
+				}
+				if prev, ok := previous[t]; ok && bytes.Equal(edit, prev) {
+					// Saved again without a change: stop, keeping the edits
+					return recoverTargets(targets, fmt.Errorf("%s: %w", t.path, err))
+				}
+				previous[t] = edit
+				if err := editor.LaunchEditor(editorCmd, tmpPaths...); err != nil {
+					return recoverTargets(targets, fmt.Errorf(i18n.T("editor failed: %w"), err))
+				}
+				continue encode
+			}
+			if encoded != nil {
+				changed = append(changed, t)
+				results[t] = encoded
+			}
+		}
+		break
+	}
+
+	if opts.confirm && len(changed) > 0 {
+		for _, t := range changed {
+			fmt.Fprintln(stderr, stderrTerminal().Paint(t.path, output.Bold))
+			if err := showEditDiff(t.data, results[t], t.opts.jsonPath); err != nil {
+				return err
+			}
+		}
+		err := confirmSave(fmt.Sprintf(i18n.T("Save %d files?"), len(changed)))
+		if errors.Is(err, errEditCanceled) {
+			fmt.Fprintln(stderr, "Edit canceled; no file was changed")
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	var staged []*safefile.Staged
+	for _, t := range changed {
+		s, err := stageTarget(t, results[t])
+		if err != nil {
+			for _, s := range staged {
+				s.Discard()
+			}
+			return fmt.Errorf("%s: %w; no file was changed", t.path, err)
+		}
+		staged = append(staged, s)
+	}
+	if err := safefile.CommitAll(staged); err != nil {
+		return err
+	}
+
+	if opts.apply {
+		for _, t := range targets {
+			if !t.isSecret {
+				continue
+			}
+			if err := applyEdited(t.path, t.opts.jsonPath, t.data); err != nil {
+				return fmt.Errorf("%s: %w", t.path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// recoverTargets saves the edits of every Secret of a multi-file edit for
+// recovery, returning cause with hints how to restore them
+func recoverTargets(targets []*editTarget, cause error) error {
+	var errs []error
+	for _, t := range targets {
+		if t.isSecret {
+			errs = append(errs, saveRecovery(t.tmpPath, t.path, t.opts.jsonPath, cause))
+		}
+	}
+	if len(errs) == 0 {
+		return cause
+	}
+	return errors.Join(errs...)
+}
+
+// prepareTarget resolves and reads a file of a multi-file edit, and writes
+// what the editor opens: the decoded Secret, or a copy of any other file
+func prepareTarget(file string, opts options) (*editTarget, error) {
+	path, err := resolveTarget(file)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("failed to read file: %w"), err)
+	}
+
+	doc, err := parseSecret(data, opts.jsonPath)
+	if duplicateKey(err) {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	t := &editTarget{path: path, data: data, isSecret: err == nil && doc.IsSecret(), opts: opts}
+	if opts.jsonPath != "" && !t.isSecret {
+		return nil, fmt.Errorf("%s: "+i18n.T("no Secret found at %s"), file, opts.jsonPath)
+	}
+
+	if !t.isSecret {
+		// Other files are edited as they are, on a copy like the Secrets
+		t.tmpPath, t.cleanup, err = tempdir.WriteFile("swk-*", filepath.Base(path), data)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+
+	// Production-like profiles make validation stricter by default
+	if _, profile, err := activeProfile(doc.Metadata().Namespace); err != nil {
+		return nil, err
+	} else if profile != nil {
+		if t.opts.validate == "" && t.opts.jsonPath == "" {
+			t.opts.validate = profile.Validate
+		}
+		t.opts.strict = t.opts.strict || profile.Strict
+	}
+
+	tmpPath, cleanup, err := writeDecoded(doc, !opts.noHeader)
+	colors := stderrTerminal()
+	for _, w := range doc.Warnings() {
+		fmt.Fprintln(stderr, colors.Paint(fmt.Sprintf(i18n.T("Warning: %s"), fmt.Sprintf("%s: %s", file, w)), output.Yellow))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: "+i18n.T("failed to process secret file: %w"), file, err)
+	}
+	t.tmpPath, t.cleanup = tmpPath, cleanup
+	return t, nil
+}
+
+// encodeTarget returns the edited file of a target as it is to be written,
+// or nil if the edit changed nothing
+func encodeTarget(t *editTarget) ([]byte, error) {
+	if t.isSecret {
+		return encodeEdited(t.path, t.tmpPath, t.opts)
+	}
+	edited, err := os.ReadFile(t.tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read edited file: %w", err)
+	}
+	if bytes.Equal(edited, t.data) {
+		return nil, nil
+	}
+	return edited, nil
+}
+
+// stageTarget checks and stages the result of a target; other files than
+// Secrets are staged as they are, as the editor would have saved them
+func stageTarget(t *editTarget, data []byte) (*safefile.Staged, error) {
+	if t.isSecret {
+		return stageFile(t.path, data)
+	}
+	return stageWrite(t.path, data)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunMultipleFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+		// wantChanged is whether the files are written
+		wantChanged bool
+	}{
+		{"all valid", `sed -i 's/admin/root/' "$1" "$2" "$3"`, "", true},
+		{"second invalid", `sed -i 's/admin/root/' "$1" "$2" "$3"; echo 'data: [' >> "$2"`, "b.yaml", false},
+		{"second canceled", `sed -i 's/admin/root/' "$1" "$3"; : > "$2"`, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{"a.yaml": setTestSecret, "b.yaml": setTestSecret, "notes.txt": "admin\n"}
+			for name, content := range files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			script := filepath.Join(t.TempDir(), "editor.sh")
+			if err := os.WriteFile(script, []byte("#!/bin/sh\n"+tt.script+"\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			stderr = &strings.Builder{}
+			t.Cleanup(func() { stderr = os.Stderr })
+			withStdin(t, "", false)
+
+			err := run([]string{"-e", script, filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml"), filepath.Join(dir, "notes.txt")})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("run() failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("run() error = %v, want %q", err, tt.wantErr)
+			}
+
+			for name, content := range files {
+				data, _ := os.ReadFile(filepath.Join(dir, name))
+				want := content
+				if tt.wantChanged {
+					// base64("admin") and base64("root")
+					want = strings.ReplaceAll(strings.ReplaceAll(content, "YWRtaW4=", "cm9vdA=="), "admin", "root")
+				}
+				if string(data) != want {
+					t.Errorf("%s = %q, want %q", name, data, want)
+				}
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != len(files) {
+				t.Errorf("run() left %d files behind, want %d", len(entries), len(files))
+			}
+		})
+	}
+}
+
+func TestRunMultipleFilesRefusesRemote(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.yaml")
+	if err := os.WriteFile(path, []byte(setTestSecret), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, other := range []string{"secret/db", "host:db.yaml"} {
+		if err := run([]string{"-e", "true", path, other}); err == nil || !strings.Contains(err.Error(), "one at a time") {
+			t.Errorf("run() with %s error = %v, want it refused", other, err)
+		}
+	}
+}
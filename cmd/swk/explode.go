@@ -0,0 +1,289 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/confformat"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+)
+
+// explodeIndexFile records, inside an exploded directory, where it came
+// from and how each key was written
+const explodeIndexFile = ".swk-explode.json"
+
+// explodeIndex is the contents of explodeIndexFile
+type explodeIndex struct {
+	// Source is the manifest, relative to the directory
+	Source string         `json:"source"`
+	Keys   []explodedFile `json:"keys"`
+}
+
+// explodedFile is one key written to a file
+type explodedFile struct {
+	Key    string `json:"key"`
+	File   string `json:"file"`
+	Format string `json:"format,omitempty"`
+	// SHA256 is the checksum of the file as written, to tell which keys
+	// were left alone
+	SHA256 string `json:"sha256"`
+	// Checksum names the sidecar checksum file written for binary keys
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// runExplode handles `swk explode FILE [--dir DIR] [--format FORMAT]`,
+// writing each key of a Secret to its own file for editing with the tools
+// made for it
+func runExplode(args []string) error {
+	fs := flag.NewFlagSet("swk explode", flag.ContinueOnError)
+	dir := fs.String("dir", "", "Directory to write the keys to (default FILE without its extension, plus .d)")
+	format := fs.String("format", "raw", "Format for values that look like config files: raw, auto, "+strings.Join(confformat.Names(), ", "))
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: swk explode FILE [--dir DIR] [--format raw|auto|%s]", strings.Join(confformat.Names(), "|"))
+	}
+	filePath := positional[0]
+	if *dir == "" {
+		*dir = strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ".d"
+	}
+
+	var want confformat.Format
+	switch *format {
+	case "raw", "auto":
+	default:
+		if want, err = confformat.Lookup(*format); err != nil {
+			return err
+		}
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	doc, err := parseSecret(data, "")
+	if err != nil || !doc.IsSecret() {
+		return fmt.Errorf("%s is not a Secret", filePath)
+	}
+	if err := doc.Decode(); err != nil {
+		return fmt.Errorf("failed to decode secret: %w", err)
+	}
+
+	// Never mix with an earlier explode, whose edits would be lost
+	if entries, err := os.ReadDir(*dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s already exists and is not empty; implode or remove it first", *dir)
+	}
+	if err := os.MkdirAll(*dir, 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	source, err := filepath.Rel(*dir, filePath)
+	if err != nil {
+		if source, err = filepath.Abs(filePath); err != nil {
+			return err
+		}
+	}
+	index := explodeIndex{Source: source}
+
+	for _, e := range doc.Data() {
+		if e.Key == "." || e.Key == ".." || strings.ContainsRune(e.Key, '/') {
+			return fmt.Errorf("key %q can't be used as a file name", e.Key)
+		}
+
+		value := e.Value
+		entry := explodedFile{Key: e.Key, File: e.Key}
+		if isBinary(value) {
+			// Binary keys are written as they are, with a checksum next to
+			// them that `sha256sum -c` understands
+			entry.Checksum = e.Key + ".sha256"
+		} else {
+			detected := confformat.Detect(value)
+			f := detected
+			if *format == "raw" || (want != nil && (detected == nil || detected.Name() != want.Name())) {
+				f = nil
+			}
+
+			if f != nil {
+				value = confformat.Expand(f, value)
+				if !strings.HasSuffix(entry.File, f.Ext()) {
+					entry.File += f.Ext()
+				}
+				entry.Format = f.Name()
+			} else if detected != nil && *format == "raw" {
+				fmt.Fprintf(stderr, "%s looks like a config file in %s format; explode with --format %s to edit it as one\n", e.Key, detected.Name(), detected.Name())
+			}
+		}
+
+		entry.SHA256 = checksum([]byte(value))
+		if err := os.WriteFile(filepath.Join(*dir, entry.File), []byte(value), 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", entry.File, err)
+		}
+		if entry.Checksum != "" {
+			sum := fmt.Sprintf("%s  %s\n", entry.SHA256, entry.File)
+			if err := os.WriteFile(filepath.Join(*dir, entry.Checksum), []byte(sum), 0600); err != nil {
+				return fmt.Errorf("failed to write %s: %w", entry.Checksum, err)
+			}
+		}
+		index.Keys = append(index.Keys, entry)
+	}
+
+	indexData, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(*dir, explodeIndexFile), append(indexData, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", explodeIndexFile, err)
+	}
+
+	fmt.Fprintf(stderr, "Exploded %d keys into %s; run swk implode %s when done\n", len(index.Keys), *dir, *dir)
+	return nil
+}
+
+// runImplode handles `swk implode DIR [-o OUT] [--keep]`, writing the
+// files of an exploded directory back into the Secret they came from.
+// Removed files delete their key and new files add one. Keys whose file
+// wasn't changed keep their encoded value from the manifest as it is, so
+// untouched binary blobs stay bit-identical.
+func runImplode(args []string) error {
+	fs := flag.NewFlagSet("swk implode", flag.ContinueOnError)
+	output := fs.String("o", "", "Write the result here instead of back to the source manifest (- for stdout)")
+	keep := fs.Bool("keep", false, "Keep the directory instead of removing it")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: swk implode DIR [-o OUT] [--keep]")
+	}
+	dir := positional[0]
+
+	indexData, err := os.ReadFile(filepath.Join(dir, explodeIndexFile))
+	if err != nil {
+		return fmt.Errorf("%s is not an exploded Secret: %w", dir, err)
+	}
+	var index explodeIndex
+	if err := json.Unmarshal(indexData, &index); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", explodeIndexFile, err)
+	}
+	source := index.Source
+	if !filepath.IsAbs(source) {
+		source = filepath.Join(dir, source)
+	}
+
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	doc, err := parseSecret(data, "")
+	if err != nil || !doc.IsSecret() {
+		return fmt.Errorf("%s is not a Secret", source)
+	}
+	encoded := doc.Values()
+	if err := doc.Decode(); err != nil {
+		return fmt.Errorf("failed to decode secret: %w", err)
+	}
+	original := doc.Values()
+
+	var entries []secret.Entry
+	untouched := map[string]bool{}
+	known := map[string]bool{explodeIndexFile: true}
+	for _, k := range index.Keys {
+		known[k.File] = true
+		if k.Checksum != "" {
+			known[k.Checksum] = true
+		}
+		content, err := os.ReadFile(filepath.Join(dir, k.File))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", k.File, err)
+		}
+
+		value := string(content)
+		if _, ok := encoded[k.Key]; ok && k.SHA256 == checksum(content) {
+			untouched[k.Key] = true
+		} else if k.Format != "" {
+			f, err := confformat.Lookup(k.Format)
+			if err != nil {
+				return err
+			}
+			value = confformat.Collapse(f, original[k.Key], value)
+			// Catch broken config before it reaches the cluster
+			if err := f.Validate(value); err != nil {
+				return fmt.Errorf("%s: %w; fix it and run swk implode again", k.File, err)
+			}
+		}
+		entries = append(entries, secret.Entry{Key: k.Key, Value: value})
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var added []string
+	for _, f := range files {
+		if !known[f.Name()] && f.Type().IsRegular() && !strings.HasPrefix(f.Name(), ".") {
+			added = append(added, f.Name())
+		}
+	}
+	sort.Strings(added)
+	for _, name := range added {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		entries = append(entries, secret.Entry{Key: name, Value: string(content)})
+	}
+
+	doc.SetData(entries)
+	if err := doc.Encode(); err != nil {
+		return fmt.Errorf("failed to encode secret: %w", err)
+	}
+	entries = doc.Data()
+	for i, e := range entries {
+		if untouched[e.Key] {
+			entries[i].Value = encoded[e.Key]
+		}
+	}
+	doc.SetData(entries)
+
+	result, err := doc.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := writeResult(source, *output, result); err != nil {
+		return err
+	}
+
+	if !*keep {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// isBinary reports whether a value can't be edited as text
+func isBinary(value string) bool {
+	return !utf8.ValidString(value) || strings.ContainsRune(value, 0)
+}
+
+// checksum returns the hex SHA-256 of data
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
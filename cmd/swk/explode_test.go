@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExplodeImplode(t *testing.T) {
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	props := "# app\r\nurl = jdbc:postgresql://db/app\r\nuser=caf\\u00e9\r\n"
+	manifest := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app\ndata:\n" +
+		"  app.conf: " + b64(props) + "\n" +
+		"  password: " + b64("hunter2") + "\n" +
+		"  token: " + b64("abc") + "\n"
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(file, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exploded := filepath.Join(dir, "app.d")
+
+	if err := run([]string{"explode", file, "--format", "properties"}); err != nil {
+		t.Fatalf("explode failed: %v", err)
+	}
+	conf, err := os.ReadFile(filepath.Join(exploded, "app.conf.properties"))
+	if err != nil {
+		t.Fatalf("missing exploded properties file: %v", err)
+	}
+	if string(conf) != "# app\nurl = jdbc:postgresql://db/app\nuser=café\n" {
+		t.Errorf("exploded properties = %q", conf)
+	}
+	if info, err := os.Stat(filepath.Join(exploded, "password")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("password file = %v, %v, want mode 0600", info, err)
+	}
+
+	// Exploding again would mix with the pending edit
+	if err := run([]string{"explode", file}); err == nil {
+		t.Error("explode into a non-empty directory should fail")
+	}
+
+	edited := strings.Replace(string(conf), "db/app", "db2/app", 1)
+	if err := os.WriteFile(filepath.Join(exploded, "app.conf.properties"), []byte(edited), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(exploded, "token")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(exploded, "api-key"), []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"implode", exploded}); err != nil {
+		t.Fatalf("implode failed: %v", err)
+	}
+	want := strings.Replace(props, "db/app", "db2/app", 1)
+	if got := queryFile(t, file, `.data["app.conf"] | @base64d`); got != want {
+		t.Errorf("app.conf = %q, want %q", got, want)
+	}
+	if got := queryFile(t, file, ".data.password | @base64d"); got != "hunter2" {
+		t.Errorf("password = %q, want hunter2", got)
+	}
+	if got := queryFile(t, file, ".data.token"); got != "" {
+		t.Errorf("token = %q, want it removed", got)
+	}
+	if got := queryFile(t, file, `.data["api-key"] | @base64d`); got != "new" {
+		t.Errorf("api-key = %q, want new", got)
+	}
+	if _, err := os.Stat(exploded); !os.IsNotExist(err) {
+		t.Error("implode left the decoded files behind")
+	}
+}
+
+func TestExplodeHint(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString([]byte("[server]\nhost = localhost\n"))
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	manifest := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app\ndata:\n  app.ini: " + b64 + "\n"
+	if err := os.WriteFile(file, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var errOut strings.Builder
+	stderr = &errOut
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	if err := run([]string{"explode", file}); err != nil {
+		t.Fatalf("explode failed: %v", err)
+	}
+	if !strings.Contains(errOut.String(), "app.ini looks like a config file in ini format; explode with --format ini") {
+		t.Errorf("stderr = %q, want a format hint", errOut.String())
+	}
+	// Without --format, the key keeps its name
+	if _, err := os.Stat(filepath.Join(filepath.Dir(file), "app.d", "app.ini")); err != nil {
+		t.Errorf("missing exploded file: %v", err)
+	}
+}
+
+func TestImplodeInvalid(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString([]byte(`<config><port>80</port></config>`))
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	manifest := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app\ndata:\n  app.xml: " + b64 + "\n"
+	if err := os.WriteFile(file, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exploded := filepath.Join(filepath.Dir(file), "app.d")
+
+	if err := run([]string{"explode", file, "--format", "xml"}); err != nil {
+		t.Fatalf("explode failed: %v", err)
+	}
+	edited := "<config>\n  <port>80</prot>\n</config>\n"
+	if err := os.WriteFile(filepath.Join(exploded, "app.xml"), []byte(edited), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := run([]string{"implode", exploded})
+	if err == nil || !strings.Contains(err.Error(), "app.xml: invalid XML") {
+		t.Fatalf("implode error = %v, want invalid XML", err)
+	}
+	// Nothing is written and the edit is kept for fixing
+	if got := queryFile(t, file, `.data["app.xml"]`); got != b64 {
+		t.Errorf("app.xml = %q, want it unchanged", got)
+	}
+	if _, err := os.Stat(exploded); err != nil {
+		t.Errorf("implode removed the directory after a failure: %v", err)
+	}
+}
+
+func TestExplodeBinary(t *testing.T) {
+	// Non-canonical base64: re-encoding would change these bytes
+	manifest := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app\ndata:\n" +
+		"  keystore.p12: /wB=\n" +
+		"  blob: |\n    /w==\n" +
+		"  password: aHVudGVyMg==\n"
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(file, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exploded := filepath.Join(dir, "app.d")
+
+	if err := run([]string{"explode", file}); err != nil {
+		t.Fatalf("explode failed: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(exploded, "keystore.p12"))
+	if err != nil || string(raw) != "\xff\x00" {
+		t.Fatalf("keystore.p12 = %q, %v, want the raw bytes", raw, err)
+	}
+	sum, err := os.ReadFile(filepath.Join(exploded, "keystore.p12.sha256"))
+	if err != nil || !strings.HasSuffix(string(sum), "  keystore.p12\n") {
+		t.Errorf("checksum sidecar = %q, %v", sum, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(exploded, "password"), []byte("swordfish"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"implode", exploded}); err != nil {
+		t.Fatalf("implode failed: %v", err)
+	}
+
+	if got := queryFile(t, file, `.data["keystore.p12"]`); got != "/wB=" {
+		t.Errorf("keystore.p12 = %q, want it bit-identical", got)
+	}
+	if got := queryFile(t, file, ".data.blob"); got != "/w==\n" {
+		t.Errorf("blob = %q, want it bit-identical", got)
+	}
+	if got := queryFile(t, file, ".data.password | @base64d"); got != "swordfish" {
+		t.Errorf("password = %q, want swordfish", got)
+	}
+	if got := queryFile(t, file, `.data["keystore.p12.sha256"]`); got != "" {
+		t.Error("implode turned the checksum sidecar into a key")
+	}
+}
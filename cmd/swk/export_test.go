@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunExport(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "db.yaml")
+	manifest := `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  namespace: prod
+type: kubernetes.io/basic-auth
+data:
+  username: YWRtaW4=
+  blob: /wA=
+stringData:
+  password: "hunter2, really"
+`
+	if err := os.WriteFile(file, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configMap := filepath.Join(dir, "cm.yaml")
+	if err := os.WriteFile(configMap, []byte("apiVersion: v1\nkind: ConfigMap\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "masked csv",
+			args: []string{file},
+			want: "file,namespace,name,type,key,size,value\n" +
+				file + ",prod,db,kubernetes.io/basic-auth,username,5,********\n" +
+				file + ",prod,db,kubernetes.io/basic-auth,blob,2,********\n" +
+				file + ",prod,db,kubernetes.io/basic-auth,password,15,********\n",
+		},
+		{
+			name: "tsv with values",
+			args: []string{file, "--format", "tsv", "--show-values"},
+			want: "file\tnamespace\tname\ttype\tkey\tsize\tvalue\n" +
+				file + "\tprod\tdb\tkubernetes.io/basic-auth\tusername\t5\tadmin\n" +
+				file + "\tprod\tdb\tkubernetes.io/basic-auth\tblob\t2\tbase64:/wA=\n" +
+				file + "\tprod\tdb\tkubernetes.io/basic-auth\tpassword\t15\thunter2, really\n",
+		},
+		{name: "not a secret", args: []string{configMap}, wantErr: true},
+		{name: "unknown format", args: []string{file, "--format", "xlsx"}, wantErr: true},
+		{name: "no files", args: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := captureStdout(t)
+			err := run(append([]string{"export"}, tt.args...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && out.String() != tt.want {
+				t.Errorf("output =\n%s\nwant\n%s", out, tt.want)
+			}
+		})
+	}
+}
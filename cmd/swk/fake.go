@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/fake"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/redact"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+	"gopkg.in/yaml.v3"
+)
+
+// runFake handles `swk fake FILE|- [-o OUT] [--from-schema]`, writing the
+// Secrets in FILE with every value replaced by a made-up one of the same
+// kind and about the same length: fresh keys and certificates for PEMs,
+// random tokens for tokens. Names, types, labels, and keys stay as they
+// are, so staging and tests can mirror production Secrets without real
+// credentials. With --from-schema, FILE is a manifest written by swk
+// redact, and the values are made up from its placeholders instead.
+func runFake(args []string) error {
+	fs := flag.NewFlagSet("swk fake", flag.ContinueOnError)
+	output := fs.String("o", "-", "Write the result to this file (- for stdout)")
+	fromSchema := fs.Bool("from-schema", false, "Read FILE as written by swk redact, making up values from its placeholders")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *output == "" {
+		return fmt.Errorf("usage: swk fake FILE|- [-o OUT] [--from-schema]")
+	}
+
+	source := positional[0]
+	var data []byte
+	if source == "-" {
+		source = "stdin"
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source, err)
+	}
+
+	result, found, err := rewriteSecrets(data, func(node *yaml.Node, v secret.Version) error {
+		return fakeSecret(node, v, *fromSchema)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fake %s: %w", source, err)
+	}
+	if found == 0 {
+		return fmt.Errorf("%s doesn't hold a Secret", source)
+	}
+	return writeResult("", *output, result)
+}
+
+// fakeSecret replaces the values of a Secret with made-up ones, and drops
+// the annotations that would hold the real values
+func fakeSecret(node *yaml.Node, v secret.Version, fromSchema bool) error {
+	if data := findNode(node, v.DataField); data != nil && data.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(data.Content); i += 2 {
+			value := data.Content[i+1]
+			raw, err := base64.StdEncoding.DecodeString(value.Value)
+			if err != nil || fromSchema && !isPlaceholder(string(raw)) {
+				// Not base64, or a placeholder written with --decoded
+				raw = []byte(value.Value)
+			}
+			made, err := fakeValue(data.Content[i].Value, raw, fromSchema)
+			if err != nil {
+				return err
+			}
+			value.Value, value.Style = base64.StdEncoding.EncodeToString(made), 0
+		}
+	}
+	if stringData := findNode(node, v.StringDataField); stringData != nil && stringData.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(stringData.Content); i += 2 {
+			value := stringData.Content[i+1]
+			made, err := fakeValue(stringData.Content[i].Value, []byte(value.Value), fromSchema)
+			if err != nil {
+				return err
+			}
+			if !utf8.Valid(made) {
+				return fmt.Errorf("key %q: binary values can't go in %s", stringData.Content[i].Value, v.StringDataField)
+			}
+			value.Value, value.Style = string(made), 0
+			if strings.Contains(value.Value, "\n") {
+				value.Style = yaml.LiteralStyle
+			}
+		}
+	}
+	removeKeys(findNode(findNode(node, "metadata"), "annotations"), redactedAnnotations)
+	return nil
+}
+
+// fakeValue makes up a value shaped like value, or like the placeholder
+// value holds with fromSchema
+func fakeValue(key string, value []byte, fromSchema bool) ([]byte, error) {
+	kind, length := redact.Kind(value), len(value)
+	if fromSchema {
+		var ok bool
+		if kind, length, ok = redact.ParsePlaceholder(string(value)); !ok {
+			return nil, fmt.Errorf("key %q holds %q, not a placeholder written by swk redact", key, value)
+		}
+	}
+	made, err := fake.Value(kind, length)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", key, err)
+	}
+	return made, nil
+}
+
+// isPlaceholder reports whether s is a placeholder written by swk redact
+func isPlaceholder(s string) bool {
+	_, _, ok := redact.ParsePlaceholder(s)
+	return ok
+}
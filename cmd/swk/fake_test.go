@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/redact"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRunFake(t *testing.T) {
+	const input = `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  labels:
+    app: db
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: '{"data":{"password":"aHVudGVyMg=="}}'
+type: Opaque
+data:
+  password: aHVudGVyMg==
+  token: ZGVhZGJlZWZkZWFkYmVlZmRlYWRiZWVm
+stringData:
+  url: postgres://app:hunter2@db/app
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret.yaml")
+	if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	shape := filepath.Join(dir, "shape.yaml")
+	if err := run([]string{"redact", path, "-o", shape}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"from real values", []string{"fake", path}},
+		{"from schema", []string{"fake", "--from-schema", shape}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := captureStdout(t)
+			if err := run(tt.args); err != nil {
+				t.Fatalf("run() failed: %v", err)
+			}
+			if strings.Contains(out.String(), "hunter2") || strings.Contains(out.String(), "aHVudGVyMg") || strings.Contains(out.String(), "last-applied") {
+				t.Errorf("output leaks a real value:\n%s", out)
+			}
+
+			var got struct {
+				Metadata struct {
+					Name   string
+					Labels map[string]string
+				}
+				Data       map[string]string
+				StringData map[string]string `yaml:"stringData"`
+			}
+			if err := yaml.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatalf("output isn't YAML: %v\n%s", err, out)
+			}
+			if got.Metadata.Name != "db" || got.Metadata.Labels["app"] != "db" {
+				t.Errorf("metadata = %+v, want it kept", got.Metadata)
+			}
+			for key, want := range map[string]string{"password": "string 7", "token": "hex 24"} {
+				value, err := base64.StdEncoding.DecodeString(got.Data[key])
+				if err != nil {
+					t.Fatalf("%s isn't base64: %v", key, err)
+				}
+				if placeholder := redact.Placeholder(value); placeholder != "<"+want+"B>" {
+					t.Errorf("%s = %q, a %s, want a <%sB>", key, value, placeholder, want)
+				}
+			}
+			if kind := redact.Kind([]byte(got.StringData["url"])); kind != "postgres-url" || len(got.StringData["url"]) != 29 {
+				t.Errorf("url = %q, want a 29-byte postgres URL", got.StringData["url"])
+			}
+		})
+	}
+}
+
+func TestRunFakeNotAPlaceholder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := os.WriteFile(path, []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nstringData:\n  password: hunter2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := run([]string{"fake", "--from-schema", path})
+	if err == nil || !strings.Contains(err.Error(), `key "password"`) {
+		t.Errorf("run() error = %v, want one naming the key that isn't a placeholder", err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantPos  []string
+		wantFlag string
+	}{
+		{"flags first", []string{"-o", "out", "a", "b"}, []string{"a", "b"}, "out"},
+		{"flags after positional", []string{"a", "-o", "out", "b"}, []string{"a", "b"}, "out"},
+		{"double dash", []string{"a", "--", "-o", "b"}, []string{"a", "-o", "b"}, ""},
+		{"no args", nil, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			out := fs.String("o", "", "")
+
+			got, err := parseFlags(fs, tt.args)
+			if err != nil {
+				t.Fatalf("parseFlags() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.wantPos) {
+				t.Errorf("parseFlags() positional = %v, want %v", got, tt.wantPos)
+			}
+			if *out != tt.wantFlag {
+				t.Errorf("parseFlags() -o = %q, want %q", *out, tt.wantFlag)
+			}
+		})
+	}
+}
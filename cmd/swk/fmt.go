@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+)
+
+// runFmt handles `swk fmt FILE... --normalize-base64 [--check]`, rewriting
+// data values in canonical base64 so tools encoding differently don't cause
+// spurious diffs
+func runFmt(args []string) error {
+	fs := flag.NewFlagSet("swk fmt", flag.ContinueOnError)
+	normalize := fs.Bool("normalize-base64", false, "Rewrite data values in canonical base64")
+	checkOnly := fs.Bool("check", false, "Only report the values that would change, failing if there are any")
+
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 || !*normalize {
+		return fmt.Errorf("usage: swk fmt FILE... --normalize-base64 [--check]")
+	}
+
+	found := 0
+	for _, file := range files {
+		n, err := formatFile(file, *checkOnly)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		found += n
+	}
+	if *checkOnly && found > 0 {
+		return fmt.Errorf(i18n.T("%d value(s) not in canonical base64"), found)
+	}
+	return nil
+}
+
+// formatFile normalizes the base64 in a file, or only reports it with
+// checkOnly, returning how many values were affected. Files that aren't
+// Secrets are left alone.
+func formatFile(file string, checkOnly bool) (int, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read file: %w", err)
+	}
+	doc, err := parseSecret(data, "")
+	if duplicateKey(err) {
+		return 0, err
+	}
+	if err != nil || !doc.IsSecret() {
+		return 0, nil
+	}
+
+	issues, err := doc.NormalizeBase64()
+	if err != nil {
+		return 0, err
+	}
+	for _, issue := range issues {
+		fmt.Fprintf(stdout, "%s: %s\n", file, issue)
+	}
+	if checkOnly || len(issues) == 0 {
+		return len(issues), nil
+	}
+
+	result, err := doc.Bytes()
+	if err != nil {
+		return 0, err
+	}
+	return len(issues), writeResult(file, "", result)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunFmt(t *testing.T) {
+	captureStdout(t)
+
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	content := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: test\ndata:\n  username: YWRtaW4\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	err := run([]string{"fmt", "--normalize-base64", "--check", file})
+	if err == nil || !strings.Contains(err.Error(), "1 value(s)") {
+		t.Fatalf("fmt --check error = %v, want one value reported", err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != content {
+		t.Errorf("fmt --check changed the file:\n%s", data)
+	}
+
+	if err := run([]string{"fmt", "--normalize-base64", file}); err != nil {
+		t.Fatalf("fmt failed: %v", err)
+	}
+	if got := queryFile(t, file, ".data.username"); got != "YWRtaW4=" {
+		t.Errorf("username = %q, want canonical base64", got)
+	}
+	if err := run([]string{"fmt", "--normalize-base64", "--check", file}); err != nil {
+		t.Errorf("fmt --check after fmt failed: %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/generate"
+)
+
+// runGen handles `swk gen tls|ca|ssh|bootstrap-token`
+func runGen(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: swk gen tls|ca|ssh|bootstrap-token [flags]")
+	}
+
+	switch args[0] {
+	case "tls":
+		return runGenCert(args[1:], false)
+	case "ca":
+		return runGenCert(args[1:], true)
+	case "ssh":
+		return runGenSSH(args[1:])
+	case "bootstrap-token":
+		return runGenBootstrapToken(args[1:])
+	default:
+		return fmt.Errorf("unknown gen command %q (available: tls, ca, ssh, bootstrap-token)", args[0])
+	}
+}
+
+// runGenCert writes a kubernetes.io/tls Secret holding a new certificate,
+// either self-signed or signed by a CA Secret
+func runGenCert(args []string, isCA bool) error {
+	name := "tls"
+	days := 365
+	if isCA {
+		name, days = "ca", 3650
+	}
+
+	fs := flag.NewFlagSet("swk gen "+name, flag.ContinueOnError)
+	cn := fs.String("cn", "", "Common name")
+	var sans stringList
+	fs.Var(&sans, "san", "Subject alternative name, DNS name or IP (repeatable, or comma-separated)")
+	fs.IntVar(&days, "days", days, "Validity in days")
+	keyType := fs.String("key-type", "ecdsa", "Key type: ecdsa, rsa, or ed25519")
+	var caFile string
+	if !isCA {
+		fs.StringVar(&caFile, "ca", "", "CA Secret manifest to sign with, as swk gen ca writes it; self-signed if empty")
+	}
+	secretName := fs.String("name", "", "Secret name (default derived from the common name)")
+	namespace := fs.String("namespace", "", "Namespace of the Secret")
+	fs.StringVar(namespace, "n", "", "Namespace of the Secret (shorthand)")
+	output := fs.String("o", "-", "Write the manifest to this file (- for stdout)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *cn == "" {
+		return fmt.Errorf("usage: swk gen %s --cn NAME [--san NAME]... [--days N] [-o FILE]", name)
+	}
+	if days <= 0 {
+		return fmt.Errorf("--days must be positive")
+	}
+
+	req := generate.CertRequest{CommonName: *cn, Days: days, KeyType: *keyType, IsCA: isCA}
+	for _, san := range sans {
+		req.SANs = append(req.SANs, strings.Split(san, ",")...)
+	}
+	if caFile != "" {
+		if req.CA, err = loadCA(caFile); err != nil {
+			return err
+		}
+	}
+
+	certPEM, keyPEM, err := generate.NewCertificate(req)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{"tls.crt", "tls.key"}
+	values := map[string]string{"tls.crt": string(certPEM), "tls.key": string(keyPEM)}
+	if req.CA != nil {
+		keys = append(keys, "ca.crt")
+		values["ca.crt"] = string(req.CA.CertPEM)
+	}
+
+	if *secretName == "" {
+		*secretName = secretNameFor(*cn, name)
+	}
+	result, err := newSecret(*secretName, *namespace, "kubernetes.io/tls", keys, values, nil)
+	if err != nil {
+		return err
+	}
+	return writeResult(*output, *output, result)
+}
+
+// runGenSSH writes a kubernetes.io/ssh-auth Secret holding a new SSH
+// keypair, optionally followed by a ConfigMap with the public key
+func runGenSSH(args []string) error {
+	fs := flag.NewFlagSet("swk gen ssh", flag.ContinueOnError)
+	keyType := fs.String("type", "ed25519", "Key type: ed25519, ecdsa, or rsa")
+	comment := fs.String("comment", "", "Public key comment, e.g. deploy@example.com")
+	secretName := fs.String("name", "ssh-key", "Secret name")
+	namespace := fs.String("namespace", "", "Namespace of the Secret")
+	fs.StringVar(namespace, "n", "", "Namespace of the Secret (shorthand)")
+	publicConfigMap := fs.Bool("public-configmap", false, "Also write a ConfigMap NAME-pub with the public key")
+	output := fs.String("o", "-", "Write the manifest to this file (- for stdout)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("usage: swk gen ssh [--type ed25519|ecdsa|rsa] [--name NAME] [--public-configmap] [-o FILE]")
+	}
+
+	key, err := generate.NewSSHKey(*keyType, *comment)
+	if err != nil {
+		return err
+	}
+
+	result, err := newSecret(*secretName, *namespace, "kubernetes.io/ssh-auth",
+		[]string{"ssh-privatekey", "ssh-publickey"},
+		map[string]string{"ssh-privatekey": string(key.PrivateKey), "ssh-publickey": string(key.PublicKey)},
+		nil)
+	if err != nil {
+		return err
+	}
+
+	if *publicConfigMap {
+		metadata := mapping("name", *secretName+"-pub")
+		if *namespace != "" {
+			metadata.Content = append(metadata.Content, mapping("namespace", *namespace).Content...)
+		}
+		root := mapping("apiVersion", "v1", "kind", "ConfigMap")
+		root.Content = append(root.Content, scalarNode("metadata"), metadata,
+			scalarNode("data"), mapping("ssh-publickey", string(key.PublicKey)))
+
+		configMap, err := marshalNode(root)
+		if err != nil {
+			return err
+		}
+		result = append(append(result, "---\n"...), configMap...)
+	}
+
+	fmt.Fprintf(stderr, "Fingerprint: %s\n", key.Fingerprint)
+	return writeResult(*output, *output, result)
+}
+
+// bootstrapGroupPattern is the form the API server accepts for the extra
+// groups of a bootstrap token
+var bootstrapGroupPattern = regexp.MustCompile(`^system:bootstrappers:[a-z0-9:-]{0,255}[a-z0-9]$`)
+
+// runGenBootstrapToken writes a bootstrap.kubernetes.io/token Secret in
+// kube-system, for joining nodes with kubeadm join --token
+func runGenBootstrapToken(args []string) error {
+	fs := flag.NewFlagSet("swk gen bootstrap-token", flag.ContinueOnError)
+	tokenFlag := fs.String("token", "", "Use this token, as ID.SECRET, instead of a random one")
+	ttl := fs.Duration("ttl", 24*time.Hour, "Time until the token expires, or 0 for never")
+	var usages, groups stringList
+	fs.Var(&usages, "usages", "What the token can be used for: authentication, signing, or both (repeatable, or comma-separated; default both)")
+	fs.Var(&groups, "groups", "Extra groups to authenticate as, each system:bootstrappers:... (repeatable, or comma-separated)")
+	description := fs.String("description", "", "What the token is for")
+	output := fs.String("o", "-", "Write the manifest to this file (- for stdout)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("usage: swk gen bootstrap-token [--token ID.SECRET] [--ttl 24h] [--usages authentication,signing] [--groups GROUP]... [-o FILE]")
+	}
+	if *ttl < 0 {
+		return fmt.Errorf("--ttl must not be negative")
+	}
+
+	token, err := generate.NewBootstrapToken()
+	if *tokenFlag != "" {
+		token, err = generate.ParseBootstrapToken(*tokenFlag)
+	}
+	if err != nil {
+		return err
+	}
+
+	keys := []string{"token-id", "token-secret"}
+	values := map[string]string{"token-id": token.ID, "token-secret": token.Secret}
+	add := func(key, value string) {
+		keys = append(keys, key)
+		values[key] = value
+	}
+	if *description != "" {
+		add("description", *description)
+	}
+	if *ttl > 0 {
+		add("expiration", now().Add(*ttl).UTC().Format(time.RFC3339))
+	}
+
+	if len(usages) == 0 {
+		usages = stringList{"authentication,signing"}
+	}
+	seen := map[string]bool{}
+	for _, list := range usages {
+		for _, usage := range strings.Split(list, ",") {
+			if usage != "authentication" && usage != "signing" {
+				return fmt.Errorf("unknown usage %q (supported: authentication, signing)", usage)
+			}
+			seen[usage] = true
+		}
+	}
+	for _, usage := range []string{"authentication", "signing"} {
+		if seen[usage] {
+			add("usage-bootstrap-"+usage, "true")
+		}
+	}
+
+	var extra []string
+	for _, list := range groups {
+		for _, group := range strings.Split(list, ",") {
+			if !bootstrapGroupPattern.MatchString(group) {
+				return fmt.Errorf("invalid group %q: bootstrap token groups must start with system:bootstrappers:", group)
+			}
+			extra = append(extra, group)
+		}
+	}
+	if len(extra) > 0 {
+		if !seen["authentication"] {
+			return fmt.Errorf("--groups needs the authentication usage")
+		}
+		add("auth-extra-groups", strings.Join(extra, ","))
+	}
+
+	result, err := newSecret("bootstrap-token-"+token.ID, "kube-system", "bootstrap.kubernetes.io/token", keys, values, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "Token: %s\n", token)
+	return writeResult(*output, *output, result)
+}
+
+// loadCA reads the CA certificate and key from a kubernetes.io/tls Secret
+func loadCA(path string) (*generate.CA, error) {
+	manifest, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA: %w", err)
+	}
+	data, err := secretValues(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA: %w", err)
+	}
+
+	ca, err := generate.ParseCA([]byte(data["tls.crt"]), []byte(data["tls.key"]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA in %s: %w", path, err)
+	}
+	return ca, nil
+}
+
+// secretValues returns the decoded data of a Secret manifest
+func secretValues(manifest []byte) (map[string]string, error) {
+	doc, err := parseSecret(manifest, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Decode(); err != nil {
+		return nil, err
+	}
+	return doc.Values(), nil
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// secretNameFor derives a valid Secret name from a common name, e.g.
+// "*.example.internal" becomes "example-internal-tls"
+func secretNameFor(cn, suffix string) string {
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(cn), "-")
+	return strings.Trim(name, "-") + "-" + suffix
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestRunGen(t *testing.T) {
+	tmpDir := t.TempDir()
+	caFile := filepath.Join(tmpDir, "ca.yaml")
+	tlsFile := filepath.Join(tmpDir, "tls.yaml")
+
+	if err := run([]string{"gen", "ca", "--cn", "Test CA", "-o", caFile}); err != nil {
+		t.Fatalf("gen ca: %v", err)
+	}
+	if err := run([]string{"gen", "tls", "--cn", "example.internal", "--san", "www.example.internal,10.0.0.1",
+		"--ca", caFile, "-n", "dev", "-o", tlsFile}); err != nil {
+		t.Fatalf("gen tls: %v", err)
+	}
+
+	if got := queryFile(t, tlsFile, ".type"); got != "kubernetes.io/tls" {
+		t.Errorf("type = %q, want kubernetes.io/tls", got)
+	}
+	if got := queryFile(t, tlsFile, ".metadata.name"); got != "example-internal-tls" {
+		t.Errorf("name = %q, want example-internal-tls", got)
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(queryFile(t, tlsFile, `.data["ca.crt"] | @base64d`))) {
+		t.Fatal("ca.crt holds no certificate")
+	}
+	block, _ := pem.Decode([]byte(queryFile(t, tlsFile, `.data["tls.crt"] | @base64d`)))
+	if block == nil {
+		t.Fatal("tls.crt holds no certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("invalid certificate: %v", err)
+	}
+	for _, host := range []string{"example.internal", "www.example.internal", "10.0.0.1"} {
+		if _, err := cert.Verify(x509.VerifyOptions{DNSName: host, Roots: roots}); err != nil {
+			t.Errorf("Verify(%s) error = %v", host, err)
+		}
+	}
+
+	// The certificate must be for tls.key, not for the CA's key
+	for _, file := range []string{caFile, tlsFile} {
+		crt := queryFile(t, file, `.data["tls.crt"] | @base64d`)
+		key := queryFile(t, file, `.data["tls.key"] | @base64d`)
+		if _, err := tls.X509KeyPair([]byte(crt), []byte(key)); err != nil {
+			t.Errorf("%s: tls.crt doesn't match tls.key: %v", filepath.Base(file), err)
+		}
+	}
+}
+
+func TestRunGenErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	notCA := filepath.Join(tmpDir, "self.yaml")
+	if err := run([]string{"gen", "tls", "--cn", "self", "-o", notCA}); err != nil {
+		t.Fatalf("gen tls: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no subcommand", []string{"gen"}},
+		{"unknown subcommand", []string{"gen", "pgp"}},
+		{"missing cn", []string{"gen", "tls"}},
+		{"invalid days", []string{"gen", "tls", "--cn", "a", "--days", "0"}},
+		{"CA is not a CA", []string{"gen", "tls", "--cn", "a", "--ca", notCA}},
+		{"missing CA", []string{"gen", "tls", "--cn", "a", "--ca", filepath.Join(tmpDir, "nope.yaml")}},
+		{"ca signed by ca", []string{"gen", "ca", "--cn", "a", "--ca", notCA}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureStdout(t)
+			if err := run(tt.args); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestRunGenSSH(t *testing.T) {
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+	defer func() { stderr = os.Stderr }()
+
+	file := filepath.Join(t.TempDir(), "ssh.yaml")
+	if err := run([]string{"gen", "ssh", "--type", "ed25519", "--name", "deploy", "--public-configmap", "-o", file}); err != nil {
+		t.Fatalf("gen ssh: %v", err)
+	}
+
+	if got := queryFile(t, file, ".type"); got != "kubernetes.io/ssh-auth" {
+		t.Errorf("type = %q, want kubernetes.io/ssh-auth", got)
+	}
+	signer, err := ssh.ParsePrivateKey([]byte(queryFile(t, file, `.data["ssh-privatekey"] | @base64d`)))
+	if err != nil {
+		t.Fatalf("invalid private key: %v", err)
+	}
+	if want := "Fingerprint: " + ssh.FingerprintSHA256(signer.PublicKey()); !strings.Contains(errBuf.String(), want) {
+		t.Errorf("stderr = %q, want %q", errBuf.String(), want)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	for _, want := range []string{"kind: ConfigMap", "name: deploy-pub", "ssh-ed25519 "} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("output missing %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestRunGenBootstrapToken(t *testing.T) {
+	var errBuf bytes.Buffer
+	stderr = &errBuf
+	defer func() { stderr = os.Stderr }()
+	old := now
+	now = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = old }()
+
+	file := filepath.Join(t.TempDir(), "token.yaml")
+	if err := run([]string{"gen", "bootstrap-token", "--token", "abcdef.0123456789abcdef", "--ttl", "2h",
+		"--usages", "authentication", "--groups", "system:bootstrappers:worker", "-o", file}); err != nil {
+		t.Fatalf("gen bootstrap-token: %v", err)
+	}
+	for expr, want := range map[string]string{
+		".metadata.name":                                     "bootstrap-token-abcdef",
+		".metadata.namespace":                                "kube-system",
+		".type":                                              "bootstrap.kubernetes.io/token",
+		`.data["token-id"] | @base64d`:                       "abcdef",
+		`.data["token-secret"] | @base64d`:                   "0123456789abcdef",
+		`.data.expiration | @base64d`:                        "2026-10-16T14:00:00Z",
+		`.data["usage-bootstrap-authentication"] | @base64d`: "true",
+		`.data["usage-bootstrap-signing"]`:                   "",
+		`.data["auth-extra-groups"] | @base64d`:              "system:bootstrappers:worker",
+	} {
+		if got := queryFile(t, file, expr); got != want {
+			t.Errorf("%s = %q, want %q", expr, got, want)
+		}
+	}
+	if !strings.Contains(errBuf.String(), "Token: abcdef.0123456789abcdef") {
+		t.Errorf("stderr = %q, want the token", errBuf.String())
+	}
+
+	if err := run([]string{"gen", "bootstrap-token", "--ttl", "0", "-o", file}); err != nil {
+		t.Fatalf("gen bootstrap-token --ttl 0: %v", err)
+	}
+	if got := queryFile(t, file, ".data.expiration"); got != "" {
+		t.Errorf("expiration = %q, want none with --ttl 0", got)
+	}
+	if got := queryFile(t, file, `.data["usage-bootstrap-signing"] | @base64d`); got != "true" {
+		t.Errorf("usage-bootstrap-signing = %q, want both usages by default", got)
+	}
+
+	for _, args := range [][]string{
+		{"--token", "ABCDEF.0123456789abcdef"},
+		{"--usages", "login"},
+		{"--groups", "system:masters"},
+		{"--usages", "signing", "--groups", "system:bootstrappers:worker"},
+		{"--ttl", "-1h"},
+	} {
+		if err := run(append([]string{"gen", "bootstrap-token", "-o", file}, args...)); err == nil {
+			t.Errorf("gen bootstrap-token %v should fail", args)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/owners"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+)
+
+// checkChanges refuses to replace the Secret at path with data if that
+// changes locked keys, and checks the changed keys against the owners in
+// the config. Files that don't hold a Secret have nothing to protect.
+func checkChanges(path string, data []byte) error {
+	after, err := parseSecret(data, "")
+	if err != nil || !after.IsSecret() {
+		return nil
+	}
+
+	before := existingSecret(path)
+	if before == nil {
+		// A new file: every key in it is a change
+		return checkOwners(appliedKeys(after))
+	}
+
+	if !unlockKeys {
+		locked, err := secret.LockViolations(before, after)
+		if err != nil {
+			return err
+		}
+		if len(locked) > 0 {
+			countCheck("locked-keys")
+			return fmt.Errorf(i18n.T("refusing to change locked keys %s; pass --unlock to allow it"), strings.Join(locked, ", "))
+		}
+	}
+	return checkOwners(secret.ChangedKeys(before, after))
+}
+
+// checkConstraints refuses to replace the Secret at path with data if a
+// changed value breaks the constraints in its annotations or the config.
+// Unlike locks and owners, constraints hold for break-glass edits too: a
+// value that breaks the application doesn't help in an emergency.
+func checkConstraints(path string, data []byte) error {
+	after, err := parseSecret(data, "")
+	if err != nil || !after.IsSecret() {
+		return nil
+	}
+	changed := appliedKeys(after)
+	if before := existingSecret(path); before != nil {
+		changed = secret.ChangedKeys(before, after)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	violations, err := secret.ConstraintViolations(after, cfg.Constraints, changed)
+	if err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	countCheck("constraints")
+	return fmt.Errorf(i18n.T("values break their constraints:\n  %s"), strings.Join(violations, "\n  "))
+}
+
+// checkOwners warns about changed keys owned by a team the user isn't in,
+// or refuses them with --enforce-owners
+func checkOwners(changed []string) error {
+	violations, user, err := ownerViolations(changed)
+	if err != nil {
+		if enforceOwners {
+			return err
+		}
+		fmt.Fprintf(stderr, i18n.T("Warning: can't check key owners: %v\n"), err)
+		return nil
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	countCheck("owners")
+
+	if enforceOwners {
+		keys := make([]string, len(violations))
+		for i, v := range violations {
+			keys[i] = fmt.Sprintf("%s (%s)", v.Key, v.Team)
+		}
+		return fmt.Errorf(i18n.T("%s may not change keys owned by other teams: %s"), user, strings.Join(keys, ", "))
+	}
+	for _, v := range violations {
+		fmt.Fprintf(stderr, i18n.T("Warning: %s, and %s is not a member\n"), v, user)
+	}
+	return nil
+}
+
+// ownerViolations checks changed keys against the owners in the config,
+// identifying the user only when some rule applies
+func ownerViolations(changed []string) ([]owners.Violation, string, error) {
+	if len(changed) == 0 {
+		return nil, "", nil
+	}
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return nil, "", err
+	}
+	rules := cfg.OwnerRules()
+	if len(rules.Owners) == 0 {
+		return nil, "", nil
+	}
+
+	user, err := owners.CurrentUser(context.Background())
+	if err != nil {
+		return nil, "", err
+	}
+	return rules.Check(user, changed), user, nil
+}
+
+// existingSecret returns the Secret currently at path, or nil if there is
+// none
+func existingSecret(path string) *secret.Document {
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	doc, err := parseSecret(existing, "")
+	if err != nil || !doc.IsSecret() {
+		return nil
+	}
+	return doc
+}
+
+// appliedKeys returns the data and stringData keys of a Secret, sorted
+func appliedKeys(doc *secret.Document) []string {
+	keys := map[string]bool{}
+	for _, e := range append(doc.Data(), doc.StringData()...) {
+		keys[e.Key] = true
+	}
+	return sortedKeys(keys)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/owners"
+)
+
+// withOwners configures payments as the owner of stripe-* keys and runs as
+// a user outside that team
+func withOwners(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	cfg := "teams:\n  payments: [bob@example.com]\nowners:\n  - keys: stripe-*\n    team: payments\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvPath, cfgPath)
+	t.Setenv(owners.EnvUser, "alice@example.com")
+
+	var buf bytes.Buffer
+	stderr = &buf
+	t.Cleanup(func() {
+		stderr = os.Stderr
+		enforceOwners = false
+	})
+	return &buf
+}
+
+func TestRunOwners(t *testing.T) {
+	warnings := withOwners(t)
+
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if err := run([]string{"set", file, "password", "x"}); err != nil {
+		t.Fatalf("set on an unowned key failed: %v", err)
+	}
+	if warnings.Len() != 0 {
+		t.Errorf("unexpected warning: %s", warnings)
+	}
+
+	if err := run([]string{"set", file, "stripe-key", "sk_1"}); err != nil {
+		t.Fatalf("set on an owned key failed without --enforce-owners: %v", err)
+	}
+	if !strings.Contains(warnings.String(), `key "stripe-key" is owned by team payments`) {
+		t.Errorf("warning = %q, want an owner warning", warnings)
+	}
+
+	err := run([]string{"--enforce-owners", "set", file, "stripe-key", "sk_2"})
+	if err == nil || !strings.Contains(err.Error(), "stripe-key (payments)") {
+		t.Fatalf("set with --enforce-owners error = %v, want an owner error", err)
+	}
+	if got := queryFile(t, file, ".data.stripe-key | @base64d"); got != "sk_1" {
+		t.Errorf("stripe-key = %q, want it unchanged", got)
+	}
+}
+
+func TestRunConstraints(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("constraints:\n  - keys: user*\n    charset: visible\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvPath, cfgPath)
+
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	err := run([]string{"set", file, "username", "ad min"})
+	if err == nil || !strings.Contains(err.Error(), `key "username" has a space at position 3`) {
+		t.Fatalf("set error = %v, want a constraint error", err)
+	}
+	if got := queryFile(t, file, ".data.username | @base64d"); got != "admin" {
+		t.Errorf("username = %q, want it unchanged", got)
+	}
+	if err := run([]string{"set", file, "username", "root"}); err != nil {
+		t.Errorf("set with a valid value failed: %v", err)
+	}
+}
+
+func TestRunCheckSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	withOwners(t)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "secret.yaml")
+	if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "secret.yaml"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-qm", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if err := run([]string{"set", file, "stripe-key", "sk_1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := captureStdout(t)
+	if err := run([]string{"check", "--since", "HEAD", file}); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "warning [owner] test-secret stripe-key: changed since HEAD") {
+		t.Errorf("output = %q, want an owner warning", out)
+	}
+
+	enforceOwners = true
+	if err := run([]string{"check", "--since", "HEAD", file}); err == nil {
+		t.Error("check with --enforce-owners succeeded, want an error")
+	}
+	if err := run([]string{"check", "--since", "no-such-ref", file}); err == nil {
+		t.Error("check with an unknown ref succeeded")
+	}
+}
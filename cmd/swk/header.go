@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+)
+
+// headerPrefix starts every line of the help header above a decoded
+// Secret, so that it can be told apart from the user's own comments
+const headerPrefix = "# swk:"
+
+// abortMarker is a line that cancels an edit, wherever it is in the file.
+// It must start the line: indented, it may be part of a value.
+const abortMarker = "# swk:abort"
+
+// errorPrefix starts the lines above a reopened edit that say why it
+// couldn't be saved. They are header lines, so saving removes them.
+const errorPrefix = headerPrefix + " ! "
+
+// errEditCanceled is returned when the edited file was emptied or holds
+// the abort marker, which cancels the edit as with git commit
+var errEditCanceled = errors.New("edit canceled")
+
+// editHeader returns the help header for a decoded Secret: what it is, its
+// keys with the size of their values, and how saving and canceling work
+func editHeader(doc *secret.Document) []byte {
+	meta := doc.Metadata()
+	name := meta.Name
+	if meta.Namespace != "" {
+		name = meta.Namespace + "/" + name
+	}
+	kind := doc.Type()
+	if kind == "" {
+		kind = "Opaque"
+	}
+
+	lines := []string{fmt.Sprintf(i18n.T("Secret %s, type %s"), name, kind)}
+	data, stringData := doc.Data(), doc.StringData()
+	width := 0
+	for _, e := range append(data, stringData...) {
+		width = max(width, len(e.Key))
+	}
+	if len(data)+len(stringData) > 0 {
+		lines = append(lines, i18n.T("Keys:"))
+	}
+	for _, e := range data {
+		if doc.IsBinary(e.Key) {
+			size := base64.StdEncoding.DecodedLen(len(e.Value)) - strings.Count(e.Value, "=")
+			lines = append(lines, fmt.Sprintf("  %-*s  "+i18n.T("%d bytes, binary, left base64-encoded"), width, e.Key, size))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  %-*s  "+i18n.T("%d bytes"), width, e.Key, len(e.Value)))
+	}
+	for _, e := range stringData {
+		lines = append(lines, fmt.Sprintf("  %-*s  "+i18n.T("%d bytes, stringData"), width, e.Key, len(e.Value)))
+	}
+	lines = append(lines,
+		i18n.T("Values are shown decoded; data is base64-encoded again when you save."),
+		fmt.Sprintf(i18n.T("To cancel, delete everything or add a line %s, and save: the file is left as it was."), abortMarker),
+		i18n.T("These lines are removed when you save; --no-header leaves them out."),
+	)
+
+	var b bytes.Buffer
+	for _, line := range lines {
+		b.WriteString(headerPrefix + " " + line + "\n")
+	}
+	return b.Bytes()
+}
+
+// stripHeader removes the help header from the start of an edited file,
+// along with any header lines the user left where they were
+func stripHeader(data []byte) []byte {
+	for bytes.HasPrefix(data, []byte(headerPrefix)) {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			return nil
+		}
+		data = data[i+1:]
+	}
+	return data
+}
+
+// canceled reports whether an edit was canceled: an unindented line is the
+// abort marker, or nothing but the header is left. Indented lines are left
+// alone, as they may belong to a block value such as a stored script.
+func canceled(edited []byte) bool {
+	for _, line := range strings.Split(string(edited), "\n") {
+		if strings.TrimRight(line, " \t\r") == abortMarker {
+			return true
+		}
+	}
+	return strings.TrimSpace(string(stripHeader(edited))) == ""
+}
+
+// invalidEdit is an edit that can't be saved as it is, e.g. malformed YAML
+// or a broken Secret, which can be fixed in the editor
+type invalidEdit struct{ err error }
+
+func (e *invalidEdit) Error() string { return e.err.Error() }
+
+func (e *invalidEdit) Unwrap() error { return e.err }
+
+// markInvalid puts the reason an edit couldn't be saved above it, in place
+// of the reason for an earlier attempt, as kubectl edit does. It returns
+// the edit without the reason, to tell whether the next attempt changed it.
+func markInvalid(tmpPath string, cause error) ([]byte, error) {
+	data, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read edited file: %w", err)
+	}
+	data = dropErrorLines(data)
+
+	var b bytes.Buffer
+	lines := append([]string{i18n.T("The edit could not be saved. Fix it and save again, or cancel to leave the file as it was:")}, strings.Split(cause.Error(), "\n")...)
+	for _, line := range lines {
+		b.WriteString(errorPrefix + line + "\n")
+	}
+	b.Write(data)
+	if err := os.WriteFile(tmpPath, b.Bytes(), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return data, nil
+}
+
+// dropErrorLines removes the lines markInvalid added
+func dropErrorLines(data []byte) []byte {
+	for bytes.HasPrefix(data, []byte(errorPrefix)) {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			return nil
+		}
+		data = data[i+1:]
+	}
+	return data
+}
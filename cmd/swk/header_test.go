@@ -0,0 +1,233 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditHeader(t *testing.T) {
+	const manifest = "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\n  namespace: prod\ntype: kubernetes.io/basic-auth\ndata:\n  password: aHVudGVyMg==\nstringData:\n  username: admin\n"
+	tests := []struct {
+		name       string
+		args       []string
+		editor     string
+		wantHeader bool
+		want       string
+		wantStderr string
+	}{
+		{
+			name:       "header shown and removed on save",
+			editor:     "sed 's/hunter2/hunter3/' \"$1\" > \"$1.new\" && mv \"$1.new\" \"$1\"",
+			wantHeader: true,
+			want:       "password: aHVudGVyMw==",
+		},
+		{
+			name:   "no header",
+			args:   []string{"--no-header"},
+			editor: "sed 's/hunter2/hunter3/' \"$1\" > \"$1.new\" && mv \"$1.new\" \"$1\"",
+			want:   "password: aHVudGVyMw==",
+		},
+		{
+			name:       "emptied file cancels",
+			editor:     ": > \"$1\"",
+			wantHeader: true,
+			want:       manifest,
+			wantStderr: "Edit canceled",
+		},
+		{
+			name:       "abort marker cancels",
+			editor:     "sed 's/hunter2/hunter3/' \"$1\" > \"$1.new\" && echo '# swk:abort' >> \"$1.new\" && mv \"$1.new\" \"$1\"",
+			wantHeader: true,
+			want:       manifest,
+			wantStderr: "Edit canceled",
+		},
+		{
+			name:       "abort marker alone cancels",
+			editor:     "echo '# swk: abort' > \"$1\"",
+			wantHeader: true,
+			want:       manifest,
+			wantStderr: "Edit canceled",
+		},
+		{
+			name:       "only the header left cancels",
+			editor:     "grep '^# swk:' \"$1\" > \"$1.new\"; mv \"$1.new\" \"$1\"",
+			wantHeader: true,
+			want:       manifest,
+			wantStderr: "Edit canceled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errOut := &strings.Builder{}
+			stderr = errOut
+			t.Cleanup(func() { stderr = os.Stderr })
+
+			dir := t.TempDir()
+			file := filepath.Join(dir, "secret.yaml")
+			if err := os.WriteFile(file, []byte(manifest), 0600); err != nil {
+				t.Fatal(err)
+			}
+			// The editor keeps a copy of what it was given
+			editor := filepath.Join(dir, "editor.sh")
+			script := "#!/bin/sh\ncp \"$1\" " + filepath.Join(dir, "shown") + "\n" + tt.editor + "\n"
+			if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := run(append(append([]string{"-e", editor}, tt.args...), file)); err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+
+			shown, _ := os.ReadFile(filepath.Join(dir, "shown"))
+			header := "# swk: Secret prod/db, type kubernetes.io/basic-auth\n# swk: Keys:\n# swk:   password  7 bytes\n# swk:   username  5 bytes, stringData\n"
+			if got := strings.HasPrefix(string(shown), header); got != tt.wantHeader {
+				t.Errorf("header shown = %v, want %v:\n%s", got, tt.wantHeader, shown)
+			}
+			data, _ := os.ReadFile(file)
+			if !strings.Contains(string(data), tt.want) || strings.Contains(string(data), "# swk:") {
+				t.Errorf("saved file =\n%s\nwant it to contain %q and no header", data, tt.want)
+			}
+			if !strings.Contains(errOut.String(), tt.wantStderr) {
+				t.Errorf("stderr = %q, want %q", errOut, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestCanceled(t *testing.T) {
+	const body = "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\n"
+	tests := []struct {
+		name   string
+		edited string
+		want   bool
+	}{
+		{"edited", body, false},
+		{"marker", body + "# swk:abort\n", true},
+		{"marker with trailing space", body + "# swk:abort \r\n", true},
+		{"marker first", "# swk:abort\n" + body, true},
+		{"emptied", "", true},
+		{"header only", "# swk: Secret db, type Opaque\n", true},
+		{"marker in a block value", body + "stringData:\n  hook.sh: |\n    #!/bin/sh\n    # swk:abort\n    exit 0\n", false},
+		{"indented marker", body + "  # swk:abort\n", false},
+		{"marker in a value", body + "stringData:\n  note: '# swk:abort'\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canceled([]byte(tt.edited)); got != tt.want {
+				t.Errorf("canceled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripHeader(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"# swk: a\n# swk:\n# own comment\nkind: Secret\n", "# own comment\nkind: Secret\n"},
+		{"kind: Secret\n# swk: moved\n", "kind: Secret\n# swk: moved\n"},
+		{"# swk: only", ""},
+	}
+	for _, tt := range tests {
+		if got := string(stripHeader([]byte(tt.in))); got != tt.want {
+			t.Errorf("stripHeader(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEditBinaryValue(t *testing.T) {
+	stderr = &strings.Builder{}
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "secret.yaml")
+	// //79 decodes to 3 bytes of invalid UTF-8
+	manifest := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\ndata:\n  keystore: //79\n  password: aHVudGVyMg==\n"
+	if err := os.WriteFile(file, []byte(manifest), 0600); err != nil {
+		t.Fatal(err)
+	}
+	editor := filepath.Join(dir, "editor.sh")
+	script := "#!/bin/sh\ncp \"$1\" " + filepath.Join(dir, "shown") + "\nsed 's/hunter2/hunter3/' \"$1\" > \"$1.new\" && mv \"$1.new\" \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"-e", editor, file}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	shown, _ := os.ReadFile(filepath.Join(dir, "shown"))
+	for _, want := range []string{"keystore  3 bytes, binary, left base64-encoded", "keystore: //79 # swk:binary\n"} {
+		if !strings.Contains(string(shown), want) {
+			t.Errorf("shown =\n%s\nwant %q", shown, want)
+		}
+	}
+	data, _ := os.ReadFile(file)
+	if want := strings.Replace(manifest, "aHVudGVyMg==", "aHVudGVyMw==", 1); string(data) != want {
+		t.Errorf("saved file =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestRunReopensInvalidEdit(t *testing.T) {
+	tests := []struct {
+		name     string
+		terminal bool
+		// fix is the second editor run; the first breaks the YAML
+		fix       string
+		wantRuns  int
+		wantValue string
+		wantErr   string
+	}{
+		{"fixed", true, `sed -i 's/\[admin/root/' "$1"`, 2, "root", ""},
+		{"saved unchanged", true, `true`, 2, "admin", "swk recover"},
+		{"canceled", true, `echo '# swk:abort' >> "$1"`, 2, "admin", ""},
+		{"no terminal", false, `true`, 1, "admin", "failed to finalize"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stderr = &strings.Builder{}
+			t.Cleanup(func() { stderr = os.Stderr })
+			t.Setenv("XDG_CACHE_HOME", t.TempDir())
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			withStdin(t, "", tt.terminal)
+
+			dir := t.TempDir()
+			file := filepath.Join(dir, "secret.yaml")
+			if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+				t.Fatal(err)
+			}
+			// The second run records what it was given before fixing it
+			script := filepath.Join(dir, "editor.sh")
+			content := "#!/bin/sh\necho x >> " + dir + "/runs\nif [ $(wc -l < " + dir + "/runs) = 1 ]; then\n" +
+				"  sed -i 's/username: admin/username: [admin/' \"$1\"\nelse\n  cp \"$1\" " + dir + "/reopened\n  " + tt.fix + "\nfi\n"
+			if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := run([]string{"-e", script, file})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("run() failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("run() error = %v, want %q", err, tt.wantErr)
+			}
+
+			runs, _ := os.ReadFile(filepath.Join(dir, "runs"))
+			if got := strings.Count(string(runs), "x"); got != tt.wantRuns {
+				t.Errorf("editor ran %d times, want %d", got, tt.wantRuns)
+			}
+			if reopened, err := os.ReadFile(filepath.Join(dir, "reopened")); err == nil {
+				if !strings.HasPrefix(string(reopened), errorPrefix) || !strings.Contains(string(reopened), "username: [admin") {
+					t.Errorf("reopened file = %q, want the error above the edit", reopened)
+				}
+			}
+			if got := queryFile(t, file, ".data.username | @base64d"); got != tt.wantValue {
+				t.Errorf("username = %q, want %q", got, tt.wantValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/generate"
+)
+
+// importRow is one key of one Secret in a bulk definition
+type importRow struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Type      string `json:"type"`
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	Generate  string `json:"generate"`
+
+	line int
+}
+
+// validName matches Kubernetes object names, which also keeps the
+// generated paths inside the output directory
+var validName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+// runImport handles `swk import DEFS -o DIR`, creating or updating one
+// Secret manifest per name and namespace in a CSV or JSON definition
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("swk import", flag.ContinueOnError)
+	outDir := fs.String("o", ".", "Directory to write manifests to, as NAME.yaml or NAMESPACE/NAME.yaml")
+	format := fs.String("format", "", "Input format: csv or json (default from the file extension)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: swk import DEFS.csv|DEFS.json [-o DIR]")
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(positional[0]), ".")
+	}
+	f, err := os.Open(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var rows []importRow
+	switch *format {
+	case "csv":
+		rows, err = readImportCSV(f)
+	case "json":
+		rows, err = readImportJSON(f)
+	default:
+		return fmt.Errorf("unsupported format %q (supported: csv, json)", *format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", positional[0], err)
+	}
+
+	// Check every row before writing anything
+	groups, err := groupImportRows(rows)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if err := importSecret(*outDir, group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readImportCSV reads rows from a CSV file whose header names the columns
+func readImportCSV(r io.Reader) ([]importRow, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header row")
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "key"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing %q column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	rows := make([]importRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		rows = append(rows, importRow{
+			Name:      field(record, "name"),
+			Namespace: field(record, "namespace"),
+			Type:      field(record, "type"),
+			Key:       field(record, "key"),
+			Value:     field(record, "value"),
+			Generate:  field(record, "generate"),
+			line:      i + 2,
+		})
+	}
+	return rows, nil
+}
+
+// readImportJSON reads rows from a JSON array of objects
+func readImportJSON(r io.Reader) ([]importRow, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var rows []importRow
+	if err := decoder.Decode(&rows); err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].line = i + 1
+	}
+	return rows, nil
+}
+
+// importGroup is the rows for one Secret, in definition order
+type importGroup struct {
+	name, namespace, secretType string
+	rows                        []importRow
+}
+
+// groupImportRows validates rows and groups them by Secret
+func groupImportRows(rows []importRow) ([]*importGroup, error) {
+	var groups []*importGroup
+	byID := map[string]*importGroup{}
+	seen := map[string]bool{}
+
+	for _, row := range rows {
+		where := fmt.Sprintf("row %d", row.line)
+		switch {
+		case !validName.MatchString(row.Name):
+			return nil, fmt.Errorf("%s: invalid name %q", where, row.Name)
+		case row.Namespace != "" && !validName.MatchString(row.Namespace):
+			return nil, fmt.Errorf("%s: invalid namespace %q", where, row.Namespace)
+		case row.Key == "":
+			return nil, fmt.Errorf("%s: missing key", where)
+		case row.Value != "" && row.Generate != "":
+			return nil, fmt.Errorf("%s: value and generate are mutually exclusive", where)
+		}
+		if row.Generate != "" {
+			if _, err := generate.ParseSpec(row.Generate); err != nil {
+				return nil, fmt.Errorf("%s: %w", where, err)
+			}
+		}
+
+		id := row.Namespace + "/" + row.Name
+		if seen[id+"/"+row.Key] {
+			return nil, fmt.Errorf("%s: duplicate key %q for %s", where, row.Key, id)
+		}
+		seen[id+"/"+row.Key] = true
+
+		group := byID[id]
+		if group == nil {
+			group = &importGroup{name: row.Name, namespace: row.Namespace}
+			byID[id] = group
+			groups = append(groups, group)
+		}
+		if group.secretType == "" {
+			group.secretType = row.Type
+		}
+		group.rows = append(group.rows, row)
+	}
+	return groups, nil
+}
+
+// importSecret creates or updates the manifest for one Secret. Literal
+// values always overwrite; generated keys are only generated when the
+// manifest doesn't have them yet, so importing again doesn't rotate them.
+func importSecret(outDir string, group *importGroup) error {
+	path := filepath.Join(outDir, group.namespace, group.name+".yaml")
+
+	data, err := os.ReadFile(path)
+	created := errors.Is(err, fs.ErrNotExist)
+	if created {
+		secretType := group.secretType
+		if secretType == "" {
+			secretType = "Opaque"
+		}
+		data, err = newSecret(group.name, group.namespace, secretType, nil, nil, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	doc, err := parseSecret(data, "")
+	if err != nil || !doc.IsSecret() {
+		return fmt.Errorf("%s exists but is not a Secret manifest", path)
+	}
+	existing := doc.Values()
+
+	for _, row := range group.rows {
+		values := map[string]string{row.Key: row.Value}
+		var spec *generate.Spec
+		if row.Generate != "" {
+			if _, ok := existing[row.Key]; ok {
+				continue
+			}
+			s, _ := generate.ParseSpec(row.Generate)
+			out, err := s.Run()
+			if err != nil {
+				return fmt.Errorf("row %d: key %q: %w", row.line, row.Key, err)
+			}
+			values, spec = out.Keys(row.Key), &s
+		}
+		if data, err = setValues(data, row.Key, values, spec); err != nil {
+			return fmt.Errorf("row %d: %w", row.line, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := writeResult(path, "", data); err != nil {
+		return err
+	}
+
+	verb := "Updated"
+	if created {
+		verb = "Created"
+	}
+	fmt.Fprintf(stderr, "%s %s (%d keys)\n", verb, path, len(group.rows))
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunImport(t *testing.T) {
+	dir := t.TempDir()
+	defs := filepath.Join(dir, "defs.csv")
+	csvDefs := `name,namespace,key,value,generate
+db,prod,username,admin,
+db,prod,password,,passphrase:words=4
+api,,token,"abc,def",
+`
+	if err := os.WriteFile(defs, []byte(csvDefs), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "secrets")
+
+	if err := run([]string{"import", defs, "-o", out}); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+	db := filepath.Join(out, "prod", "db.yaml")
+	if got := queryFile(t, db, ".data.username | @base64d"); got != "admin" {
+		t.Errorf("username = %q, want admin", got)
+	}
+	password := queryFile(t, db, ".data.password | @base64d")
+	if len(strings.Split(password, "-")) != 4 {
+		t.Errorf("password = %q, want a 4-word passphrase", password)
+	}
+	if got := queryFile(t, db, ".metadata.namespace"); got != "prod" {
+		t.Errorf("namespace = %q, want prod", got)
+	}
+	if got := queryFile(t, filepath.Join(out, "api.yaml"), ".data.token | @base64d"); got != "abc,def" {
+		t.Errorf("token = %q, want abc,def", got)
+	}
+
+	// Importing again updates literals but keeps generated values
+	jsonDefs := filepath.Join(dir, "defs.json")
+	if err := os.WriteFile(jsonDefs, []byte(`[
+  {"name": "db", "namespace": "prod", "key": "username", "value": "root"},
+  {"name": "db", "namespace": "prod", "key": "password", "generate": "passphrase:words=4"}
+]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"import", jsonDefs, "-o", out}); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+	if got := queryFile(t, db, ".data.username | @base64d"); got != "root" {
+		t.Errorf("username = %q, want root", got)
+	}
+	if got := queryFile(t, db, ".data.password | @base64d"); got != password {
+		t.Errorf("password = %q, want it unchanged (%q)", got, password)
+	}
+}
+
+func TestRunImportInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"missing column", "defs.csv", "name,value\ndb,x\n", `missing "key" column`},
+		{"path in name", "defs.csv", "name,key,value\n../etc,k,v\n", "row 2: invalid name"},
+		{"value and generator", "defs.csv", "name,key,value,generate\ndb,k,v,uuid\n", "mutually exclusive"},
+		{"duplicate key", "defs.csv", "name,key,value\ndb,k,a\ndb,k,b\n", "row 3: duplicate key"},
+		{"unknown generator", "defs.csv", "name,key,generate\ndb,k,nope\n", "row 2"},
+		{"unknown field", "defs.json", `[{"name": "db", "key": "k", "vlaue": "x"}]`, "vlaue"},
+		{"unknown format", "defs.txt", "", "unsupported format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			defs := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(defs, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := run([]string{"import", defs, "-o", dir})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("run() error = %v, want it to contain %q", err, tt.want)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+				t.Errorf("run() wrote files despite the error")
+			}
+		})
+	}
+}
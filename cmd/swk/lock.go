@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/patch"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+	"gopkg.in/yaml.v3"
+)
+
+// runLock handles `swk lock FILE --key KEY... [--remove]`, recording keys in
+// the locked-keys annotation so later edits can't change them by accident
+func runLock(args []string) error {
+	fs := flag.NewFlagSet("swk lock", flag.ContinueOnError)
+	var keys stringList
+	fs.Var(&keys, "key", "Key to lock (repeatable)")
+	remove := fs.Bool("remove", false, "Unlock the keys instead")
+	list := fs.Bool("list", false, "List the locked keys")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || (len(keys) == 0) == !*list {
+		return fmt.Errorf("usage: swk lock FILE --key KEY... [--remove] | swk lock FILE --list")
+	}
+	filePath := positional[0]
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	doc, err := parseSecret(data, "")
+	if err != nil {
+		return err
+	}
+	if !doc.IsSecret() {
+		return fmt.Errorf("%s is not a Secret", filePath)
+	}
+	b, err := doc.Behaviors()
+	if err != nil {
+		return err
+	}
+
+	if *list {
+		for _, key := range sortedKeys(b.Locked) {
+			fmt.Fprintln(stdout, key)
+		}
+		return nil
+	}
+
+	values := doc.Values()
+	for _, key := range keys {
+		if _, ok := values[key]; !ok && !*remove {
+			return fmt.Errorf("no key %q in %s", key, filePath)
+		}
+		b.Locked[key] = !*remove
+	}
+
+	var annotation any
+	if locked := sortedKeys(b.Locked); len(locked) > 0 {
+		annotation = strings.Join(locked, ",")
+	}
+	patchData, err := yaml.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": map[string]any{secret.LockedKeysAnnotation: annotation}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build patch: %w", err)
+	}
+	result, err := patchManifest(data, patchData, patch.TypeMerge)
+	if err != nil {
+		return err
+	}
+
+	path, err := resolveTarget(filePath)
+	if err != nil {
+		return err
+	}
+	// Removing a lock is the explicit unlock, so it skips the lock check
+	write := saveFile
+	if *remove {
+		write = writeFile
+	}
+	if err := write(path, result); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys set to true, sorted
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for key, ok := range set {
+		if ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
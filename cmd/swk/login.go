@@ -0,0 +1,57 @@
+//go:build !noserve
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/auth"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+)
+
+func init() {
+	commands["login"] = runLogin
+}
+
+// runLogin handles `swk login [--issuer URL --client-id ID]`, signing in
+// with the OIDC device flow and printing the ID token, for editor plugins
+// and tools to send to swk serve as a bearer token
+func runLogin(args []string) error {
+	fs := flag.NewFlagSet("swk login", flag.ContinueOnError)
+	issuer := fs.String("issuer", "", "OIDC issuer URL (default: serve.auth.oidc.issuer from the config)")
+	clientID := fs.String("client-id", "", "OIDC client ID (default: serve.auth.oidc.clientID from the config)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("usage: swk login [--issuer URL --client-id ID]")
+	}
+	if *issuer == "" || *clientID == "" {
+		cfg, err := config.LoadDefault()
+		if err != nil {
+			return err
+		}
+		if *issuer == "" {
+			*issuer = cfg.Serve.Auth.OIDC.Issuer
+		}
+		if *clientID == "" {
+			*clientID = cfg.Serve.Auth.OIDC.ClientID
+		}
+	}
+	if *issuer == "" || *clientID == "" {
+		return fmt.Errorf("no OIDC issuer and client ID: pass --issuer and --client-id, or set serve.auth.oidc in the config")
+	}
+
+	token, err := auth.NewOIDC(*issuer, *clientID, "").DeviceLogin(context.Background(), func(uri, code string) {
+		fmt.Fprintf(stderr, "To sign in, open %s and enter the code %s\n", uri, code)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, token)
+	return nil
+}
@@ -0,0 +1,110 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cache"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+)
+
+func init() {
+	commands["ls"] = runLs
+}
+
+// runLs handles `swk ls`, listing the Secrets in a cluster with their types
+// and keys. Listings are cached briefly so repeated runs stay fast.
+func runLs(args []string) error {
+	var clusterOpts cluster.Options
+	fs := flag.NewFlagSet("swk ls", flag.ContinueOnError)
+	clusterOpts.BindFlags(fs)
+	allNamespaces := fs.Bool("all-namespaces", false, "List Secrets in every namespace")
+	fs.BoolVar(allNamespaces, "A", false, "Shorthand for -all-namespaces")
+	ttl := fs.Duration("cache-ttl", cache.DefaultTTL, "How long a cached listing stays fresh (0 disables the cache)")
+	refresh := fs.Bool("refresh", false, "Ignore the cached listing and fetch a new one")
+	names := fs.Bool("names", false, "Print only secret/NAME, one per line, as shell completion uses")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("usage: swk ls [-n NAMESPACE | -A] [--cache-ttl DURATION] [--refresh] [--names]")
+	}
+
+	client := cluster.New(clusterOpts)
+	secrets, err := listSecrets(client, *allNamespaces, *ttl, *refresh)
+	if err != nil {
+		return err
+	}
+	if *names {
+		for _, s := range secrets {
+			fmt.Fprintln(stdout, "secret/"+s.Name)
+		}
+		return nil
+	}
+
+	return paged(func() error {
+		w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAMESPACE\tNAME\tTYPE\tKEYS")
+		for _, s := range secrets {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Namespace, s.Name, s.Type, strings.Join(s.Keys, ","))
+		}
+		return w.Flush()
+	})
+}
+
+// listSecrets returns the Secret listing from the cache when it is fresh,
+// and fetches and caches it otherwise. The cache is best effort: if it can't
+// be used, the listing is fetched every time.
+func listSecrets(client *cluster.Client, allNamespaces bool, ttl time.Duration, refresh bool) ([]cluster.SecretMeta, error) {
+	var c *cache.Cache
+	var name string
+	if ttl > 0 {
+		if dir, err := cache.DefaultDir(); err == nil {
+			c, _ = cache.Open(dir, ttl)
+		}
+	}
+	if c != nil {
+		var err error
+		if name, err = listingKey(client, allNamespaces); err != nil {
+			c = nil
+		}
+	}
+
+	var secrets []cluster.SecretMeta
+	if c != nil && !refresh && c.Get(name, &secrets) {
+		return secrets, nil
+	}
+
+	secrets, err := client.ListSecrets(context.Background(), allNamespaces)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		_ = c.Put(name, secrets)
+	}
+	return secrets, nil
+}
+
+// listingKey names the cached listing after what it lists: the kubeconfig,
+// and the context, API server, and namespace in use, so switching with
+// kubectl config use-context never shows another cluster's Secrets
+func listingKey(client *cluster.Client, allNamespaces bool) (string, error) {
+	kubeconfig := client.Options().Kubeconfig
+	if kubeconfig == "" {
+		kubeconfig = os.Getenv("KUBECONFIG")
+	}
+	target, err := client.CurrentTarget(context.Background())
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{"secrets", kubeconfig, target.Context, target.Server, target.Namespace, fmt.Sprint(allNamespaces)}, "\x00"), nil
+}
@@ -0,0 +1,88 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunLs(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "calls.log")
+	list := `{"items":[{"metadata":{"name":"db","namespace":"prod"},"type":"Opaque","data":{"password":"c2VjcmV0"}}]}`
+	target := filepath.Join(dir, "target")
+	script := "#!/bin/sh\ncase \"$*\" in *\"config view\"*) cat " + target + "; exit ;; esac\necho \"$@\" >> " + logFile + "\nprintf '%s' '" + list + "'\n"
+	if err := os.WriteFile(filepath.Join(dir, "kubectl"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake kubectl: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("KUBECONFIG", "")
+
+	useContext := func(name, server string) {
+		if err := os.WriteFile(target, []byte(name+"\t"+server+"\t\n"), 0644); err != nil {
+			t.Fatalf("Failed to write current context: %v", err)
+		}
+	}
+	useContext("staging", "https://staging.example.com")
+
+	calls := func() int {
+		data, _ := os.ReadFile(logFile)
+		return strings.Count(string(data), "\n")
+	}
+
+	tests := []struct {
+		name      string
+		context   string
+		server    string
+		args      []string
+		wantCalls int
+	}{
+		{"fetches", "staging", "https://staging.example.com", []string{"-A"}, 1},
+		{"cached", "staging", "https://staging.example.com", []string{"-A"}, 1},
+		{"other namespace is cached separately", "staging", "https://staging.example.com", []string{"-n", "dev"}, 2},
+		{"other context is cached separately", "prod", "https://prod.example.com", []string{"-A"}, 3},
+		{"other server is cached separately", "prod", "https://prod-2.example.com", []string{"-A"}, 4},
+		{"refresh", "prod", "https://prod-2.example.com", []string{"-A", "--refresh"}, 5},
+		{"cache disabled", "prod", "https://prod-2.example.com", []string{"-A", "--cache-ttl", "0"}, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useContext(tt.context, tt.server)
+			out := captureStdout(t)
+			if err := run(append([]string{"ls"}, tt.args...)); err != nil {
+				t.Fatalf("run() failed: %v", err)
+			}
+			if !strings.Contains(out.String(), "prod       db    Opaque  password") {
+				t.Errorf("output = %q", out.String())
+			}
+			if strings.Contains(out.String(), "c2VjcmV0") {
+				t.Error("output contains a value")
+			}
+			if got := calls(); got != tt.wantCalls {
+				t.Errorf("kubectl calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRunLsNames(t *testing.T) {
+	dir := fakeCluster(t)
+	list := `{"items":[{"metadata":{"name":"db","namespace":"prod"},"type":"Opaque","data":{"password":"c2VjcmV0"}},{"metadata":{"name":"tls","namespace":"prod"},"type":"kubernetes.io/tls"}]}`
+	if err := os.WriteFile(filepath.Join(dir, ".secrets"), []byte(list), 0644); err != nil {
+		t.Fatalf("Failed to write secrets: %v", err)
+	}
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	out := captureStdout(t)
+	if err := run([]string{"ls", "-n", "prod", "--names"}); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+	if got, want := out.String(), "secret/db\nsecret/tls\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -1,24 +1,307 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
 	"github.com/davidschrooten/secret-wrapper-k8s/internal/editor"
-	"github.com/davidschrooten/secret-wrapper-k8s/internal/secret"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/output"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/safefile"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/schema"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/tempdir"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
 )
 
+// Standard streams, replaceable in tests
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// isTerminal reports whether stdin is an interactive terminal
+var isTerminal = func() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// stderrIsTerminal reports whether stderr is an interactive terminal, where
+// progress is drawn as a bar
+var stderrIsTerminal = func() bool {
+	fi, err := os.Stderr.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// plain disables box drawing and interactive menus in favor of line-oriented
+// output and yes/no questions, for screen readers and logs. Set by --plain
+// or SWK_PLAIN.
+var plain bool
+
+// followSymlinks allows editing files whose symlinks lead outside the
+// working tree. Set by --follow-symlinks.
+var followSymlinks bool
+
+// allowWatched allows editing files that a controller or the kubelet keeps
+// in sync. Set by --allow-watched.
+var allowWatched bool
+
+// unlockKeys allows changing keys listed in the swk.dev/locked-keys
+// annotation. Set by --unlock.
+var unlockKeys bool
+
+// enforceOwners refuses changes to keys owned by another team instead of
+// warning about them. Set by --enforce-owners.
+var enforceOwners bool
+
+// keepDuplicates, when set by --keep, resolves duplicate keys in Secrets
+// instead of refusing them
+var keepDuplicates secret.Keep
+
+// fileMode, when set by --mode, is applied to every file swk writes.
+// Otherwise existing files keep their mode and new ones honor the umask.
+var fileMode fs.FileMode
+
+// commands maps subcommand names to their handlers. Anything else is
+// treated as the editor wrapper invocation used by kubectl. Commands that
+// a build tag can leave out (nocloud, noserve, nocluster) add themselves in
+// init.
+var commands = map[string]func([]string) error{
+	"audit":       runAudit,
+	"blame":       runBlame,
+	"check":       runCheck,
+	"config":      runConfig,
+	"convert":     runConvert,
+	"decode":      runDecode,
+	"doctor":      runDoctor,
+	"edit":        runEdit,
+	"encode":      runEncode,
+	"explode":     runExplode,
+	"fmt":         runFmt,
+	"export":      runExport,
+	"fake":        runFake,
+	"gen":         runGen,
+	"implode":     runImplode,
+	"import":      runImport,
+	"join":        runJoin,
+	"lock":        runLock,
+	"merge":       runMerge,
+	"new":         runNew,
+	"patch":       runPatch,
+	"query":       runQuery,
+	"recover":     runRecover,
+	"redact":      runRedact,
+	"registry":    runRegistry,
+	"rename-keys": runRenameKeys,
+	"rotate":      runRotate,
+	"run-plan":    runRunPlan,
+	"scaffold":    runScaffold,
+	"shell":       runShell,
+	"split":       runSplit,
+	"stats":       runStats,
+	"schema":      runSchema,
+	"set":         runSet,
+	"test":        runTest,
+	"totp":        runTOTP,
+}
+
+// options holds the parsed command-line options for the editor wrapper
+type options struct {
+	editor   string
+	filePath string
+	// files are all the files given; more than one are edited together
+	files    []string
+	validate string
+	jsonPath string
+	strict   bool
+	compare  bool
+	confirm  bool
+	apply    bool
+	noHeader bool
+	// cluster locates the Secret given as secret/NAME
+	cluster cluster.Options
+}
+
 func main() {
-	if err := run(os.Args[1:]); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	i18n.SetLanguage(i18n.Detect())
+	asPlugin = isPlugin(os.Args[0])
+	err := run(os.Args[1:])
+	recordUsage(os.Args[1:], err)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, i18n.T("Error: %v\n"), err)
 		os.Exit(1)
 	}
 }
 
 // run is the main entry point that can be tested
 func run(args []string) error {
-	editorFlag, filePath, err := parseArgs(args)
+	if err := applyGlobalEnv(os.LookupEnv); err != nil {
+		return err
+	}
+	rest, err := parseGlobalFlags(args)
+	if err != nil {
+		return err
+	}
+	globalArgs, args = args[:len(args)-len(rest)], rest
+	if asPlugin {
+		args = hoistClusterFlags(args)
+	}
+	if len(args) > 0 {
+		if cmd, ok := commands[args[0]]; ok {
+			return cmd(args[1:])
+		}
+	}
+	return runEdit(args)
+}
+
+// globalEnv lists the global flags that SWK_* environment variables set,
+// e.g. SWK_YES for --yes. Flags win over the environment, which wins over
+// the config file. --lang is left out: SWK_LANG is read with the locale.
+var globalEnv = []string{"mode", "profile", "keep", "no-pager", "yes", "plain", "follow-symlinks", "allow-watched", "unlock", "enforce-owners", "diff-tool", "deterministic", "tmpdir", "no-shred"}
+
+// globalEnvName returns the environment variable for a global flag
+func globalEnvName(flag string) string {
+	return config.EnvPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// applyGlobalEnv applies the global flags set in the environment
+func applyGlobalEnv(lookup func(string) (string, bool)) error {
+	for _, name := range globalEnv {
+		env := globalEnvName(name)
+		value, ok := lookup(env)
+		if !ok || value == "" {
+			continue
+		}
+		if err := setGlobalFlag(name, value, true); err != nil {
+			return fmt.Errorf("invalid %s: %w", env, err)
+		}
+	}
+	return nil
+}
+
+// parseGlobalFlags applies the leading --lang, --plain, --follow-symlinks,
+// --allow-watched, --unlock, --enforce-owners, --profile, --yes, --no-pager,
+// --keep, --diff-tool, --deterministic, --tmpdir, --no-shred, and --mode
+// flags, which work for any subcommand, and returns the remaining arguments
+func parseGlobalFlags(args []string) ([]string, error) {
+	for len(args) > 0 {
+		if !strings.HasPrefix(args[0], "-") {
+			return args, nil
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		if !isGlobalFlag(name) {
+			return args, nil
+		}
+
+		if takesValue(name) && !hasValue {
+			if len(args) < 2 {
+				return nil, fmt.Errorf("flag needs an argument: --%s", name)
+			}
+			value, hasValue, args = args[1], true, args[1:]
+		}
+		if err := setGlobalFlag(name, value, hasValue); err != nil {
+			return nil, err
+		}
+		args = args[1:]
+	}
+	return args, nil
+}
+
+// isGlobalFlag reports whether name is a flag parseGlobalFlags handles
+func isGlobalFlag(name string) bool {
+	return name == "lang" || slices.Contains(globalEnv, name)
+}
+
+// takesValue reports whether the global flag name takes a value
+func takesValue(name string) bool {
+	return name == "lang" || name == "mode" || name == "profile" || name == "keep" || name == "diff-tool" || name == "tmpdir"
+}
+
+// setGlobalFlag sets a global flag; boolean flags without a value are true
+func setGlobalFlag(name, value string, hasValue bool) error {
+	on := !hasValue
+	if hasValue {
+		on, _ = strconv.ParseBool(value)
+	}
+	switch name {
+	case "lang":
+		i18n.SetLanguage(value)
+	case "mode":
+		mode, err := strconv.ParseUint(value, 8, 32)
+		if err != nil || mode > 0777 {
+			return fmt.Errorf("invalid --mode %q: want octal permissions like 0600", value)
+		}
+		fileMode = fs.FileMode(mode)
+	case "profile":
+		profileName = value
+	case "keep":
+		if value != string(secret.KeepFirst) && value != string(secret.KeepLast) {
+			return fmt.Errorf("invalid --keep %q: want first or last", value)
+		}
+		keepDuplicates = secret.Keep(value)
+	case "no-pager":
+		noPager = on
+	case "yes":
+		assumeYes = on
+	case "plain":
+		plain = on
+	case "follow-symlinks":
+		followSymlinks = on
+	case "allow-watched":
+		allowWatched = on
+	case "unlock":
+		unlockKeys = on
+	case "enforce-owners":
+		enforceOwners = on
+	case "diff-tool":
+		diffTool = value
+	case "deterministic":
+		deterministic = on
+	case "tmpdir":
+		tempdir.Override = value
+	case "no-shred":
+		tempdir.NoShred = on
+	}
+	return nil
+}
+
+// runEdit wraps an editor session around the given file, fetching it over
+// SSH first if it is given as [USER@]HOST:PATH. A Secret in the cluster,
+// given as secret/NAME, is edited with kubectl edit and swk as its editor.
+func runEdit(args []string) error {
+	opts, err := parseArgs(args)
+	if err != nil {
+		return err
+	}
+	if len(opts.files) > 1 {
+		return editFiles(opts)
+	}
+	if name, ok := secretInCluster(opts.filePath); ok {
+		return editClusterSecret(name, opts)
+	}
+	if opts.cluster != (cluster.Options{}) {
+		return fmt.Errorf("--namespace, --context, and --kubeconfig need a Secret in the cluster, given as secret/NAME")
+	}
+	if remote, ok := parseRemote(opts.filePath); ok {
+		return editRemote(remote, opts)
+	}
+	return editFile(opts)
+}
+
+// editFile wraps an editor session around a local file
+func editFile(opts options) error {
+	// Edit the real file, refusing links that lead out of the working tree
+	filePath, err := resolveTarget(opts.filePath)
 	if err != nil {
 		return err
 	}
@@ -26,124 +309,431 @@ func run(args []string) error {
 	// Read the file to check if it's a Secret
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return fmt.Errorf(i18n.T("failed to read file: %w"), err)
+	}
+
+	// Parse once; the same document is checked and decoded
+	doc, err := parseSecret(data, opts.jsonPath)
+	if duplicateKey(err) {
+		return err
+	}
+	isSecret := err == nil && doc.IsSecret()
+
+	// A Secret embedded at an explicit path must be there, don't silently pass through
+	if opts.jsonPath != "" && !isSecret {
+		return fmt.Errorf(i18n.T("no Secret found at %s"), opts.jsonPath)
 	}
 
 	// Check if this is a Kubernetes Secret
-	if !secret.IsSecret(data) {
+	if !isSecret {
 		// Not a Secret - just pass through to editor
-		editorCmd := editor.SelectEditor(editorFlag)
+		editorCmd := editor.SelectEditor(opts.editor)
 		if err := editor.LaunchEditor(editorCmd, filePath); err != nil {
-			return fmt.Errorf("editor failed: %w", err)
+			return fmt.Errorf(i18n.T("editor failed: %w"), err)
 		}
 		return nil
 	}
 
+	// Production-like profiles make validation stricter by default
+	if _, profile, err := activeProfile(doc.Metadata().Namespace); err != nil {
+		return err
+	} else if profile != nil {
+		if opts.validate == "" && opts.jsonPath == "" {
+			opts.validate = profile.Validate
+		}
+		opts.strict = opts.strict || profile.Strict
+	}
+
 	// It's a Secret - process with decode/encode workflow
-	tmpFile, cleanup, err := processSecretFile(filePath)
+	tmpFile, cleanup, err := writeDecoded(doc, !opts.noHeader)
+	// Warnings may also explain why decoding failed
+	colors := stderrTerminal()
+	for _, w := range doc.Warnings() {
+		fmt.Fprintln(stderr, colors.Paint(fmt.Sprintf(i18n.T("Warning: %s"), w), output.Yellow))
+	}
 	if err != nil {
-		return fmt.Errorf("failed to process secret file: %w", err)
+		return fmt.Errorf(i18n.T("failed to process secret file: %w"), err)
 	}
 	defer cleanup()
 
-	// Select and launch editor
-	editorCmd := editor.SelectEditor(editorFlag)
-	if err := editor.LaunchEditor(editorCmd, tmpFile); err != nil {
-		return fmt.Errorf("editor failed: %w", err)
+	// Select and launch editor, next to the original if comparing
+	editorCmd := editor.SelectEditor(opts.editor)
+	editorArgs := []string{tmpFile}
+	if opts.compare {
+		original, cleanupOriginal, err := writeOriginal(data)
+		if err != nil {
+			return fmt.Errorf(i18n.T("failed to process secret file: %w"), err)
+		}
+		defer cleanupOriginal()
+		editorArgs = editor.CompareArgs(editorCmd, original, tmpFile)
+	}
+	if err := launchEditor(editorCmd, editorArgs, tmpFile, filePath, opts.jsonPath); err != nil {
+		return err
 	}
 
-	// Finalize: encode the edited file and write back to original
-	if err := finalizeSecretFile(filePath, tmpFile); err != nil {
-		return fmt.Errorf("failed to finalize secret file: %w", err)
+	// Finalize: encode the edited file and write back to original. An edit
+	// that can't be saved is reopened on a terminal, with the reason above
+	// it, until it can be or is canceled.
+	var previous []byte
+	for {
+		err := finalizeSecretFile(filePath, tmpFile, opts)
+		if errors.Is(err, errEditCanceled) {
+			fmt.Fprintf(stderr, i18n.T("Edit canceled; %s is unchanged\n"), filePath)
+			return nil
+		}
+		var invalid *invalidEdit
+		if !errors.As(err, &invalid) || !isTerminal() {
+			if err != nil {
+				return fmt.Errorf(i18n.T("failed to finalize secret file: %w"), err)
+			}
+			break
+		}
+
+		edit, markErr := markInvalid(tmpFile, err)
+		if markErr != nil {
+			return fmt.Errorf(i18n.T("failed to finalize secret file: %w"), errors.Join(err, markErr))
+		}
+		if previous != nil && bytes.Equal(edit, previous) {
+			// Saved again without a change: stop, keeping the edit
+			return saveRecovery(tmpFile, filePath, opts.jsonPath, fmt.Errorf(i18n.T("failed to finalize secret file: %w"), err))
+		}
+		previous = edit
+		if err := launchEditor(editorCmd, editorArgs, tmpFile, filePath, opts.jsonPath); err != nil {
+			return err
+		}
 	}
 
+	if opts.apply {
+		return applyEdited(filePath, opts.jsonPath, data)
+	}
 	return nil
 }
 
-// parseArgs parses command-line arguments and returns editor flag and file path
-func parseArgs(args []string) (string, string, error) {
+// launchEditor runs the editor, offering to retry or save the edit for
+// recovery if it fails
+func launchEditor(editorCmd string, editorArgs []string, tmpFile, filePath, jsonPath string) error {
+	for {
+		err := editor.LaunchEditor(editorCmd, editorArgs...)
+		if err == nil {
+			return nil
+		}
+		// Don't throw away what may be a long edit because the editor crashed
+		retry, err := editorFailed(tmpFile, filePath, jsonPath, err)
+		if !retry {
+			return err
+		}
+	}
+}
+
+// parseArgs parses command-line arguments for the editor wrapper
+func parseArgs(args []string) (options, error) {
+	var opts options
 	fs := flag.NewFlagSet("swk", flag.ContinueOnError)
-	editorFlag := fs.String("editor", "", "Editor to use (overrides $EDITOR and $VISUAL)")
+	fs.StringVar(&opts.editor, "editor", "", "Editor to use (overrides $EDITOR and $VISUAL)")
 	fs.String("e", "", "Shorthand for -editor")
+	builtin := fs.Bool("builtin-editor", false, "Edit with the line editor built into swk, for systems without vi or nano")
+	fs.StringVar(&opts.validate, "validate", "", "Validate the result before saving (supported: schema)")
+	fs.StringVar(&opts.jsonPath, "json-path", "", "Path of a Secret embedded in a larger document (e.g. .spec.template)")
+	fs.BoolVar(&opts.compare, "compare", false, "Show the original encoded file next to the decoded one while editing")
+	fs.BoolVar(&opts.confirm, "confirm", false, "Show a diff of the decoded changes and ask before saving them")
+	fs.BoolVar(&opts.strict, "strict", false, "Reject fields a Secret doesn't have, such as datas or stringdata")
+	fs.StringVar(&opts.cluster.Namespace, "namespace", "", "Namespace of a Secret given as secret/NAME")
+	fs.StringVar(&opts.cluster.Namespace, "n", "", "Shorthand for -namespace")
+	fs.StringVar(&opts.cluster.Context, "context", "", "Kubeconfig context of a Secret given as secret/NAME")
+	fs.StringVar(&opts.cluster.Kubeconfig, "kubeconfig", "", "Kubeconfig of a Secret given as secret/NAME")
+	fs.BoolVar(&opts.noHeader, "no-header", false, "Leave out the help header above the decoded Secret")
+	fs.BoolVar(&opts.apply, "apply", false, "Apply the saved file to the cluster, after approval if its profile asks for it")
 
-	if err := fs.Parse(args); err != nil {
-		return "", "", err
+	// Flags may follow the file, as in kubectl swk edit NAME -n NAMESPACE
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return options{}, err
 	}
 
 	// Check for -e flag
 	if e := fs.Lookup("e"); e != nil && e.Value.String() != "" {
-		*editorFlag = e.Value.String()
+		opts.editor = e.Value.String()
+	}
+	if *builtin {
+		opts.editor = editor.Builtin
+	}
+
+	switch opts.validate {
+	case "", "schema":
+	default:
+		return options{}, fmt.Errorf("unsupported -validate mode %q (supported: schema)", opts.validate)
+	}
+	if opts.validate != "" && opts.jsonPath != "" {
+		return options{}, fmt.Errorf("-validate cannot be combined with -json-path")
 	}
 
 	// Get positional argument (file path)
-	if fs.NArg() == 0 {
-		return "", "", fmt.Errorf("usage: swk [-editor EDITOR] FILE")
+	if len(positional) == 0 {
+		return options{}, fmt.Errorf("%s", i18n.T("usage: swk [-editor EDITOR] FILE..."))
 	}
 
-	filePath := fs.Arg(0)
-	return *editorFlag, filePath, nil
+	opts.filePath = positional[0]
+	opts.files = positional
+	return opts, nil
 }
 
-// processSecretFile reads the secret file, decodes base64 values, and writes to a temp file
-// Returns the temp file path and a cleanup function
-func processSecretFile(filePath string) (string, func(), error) {
-	// Read original file
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to read file: %w", err)
+// parseSecret parses a manifest like secret.ParseAt, resolving duplicate
+// keys as --keep says
+func parseSecret(data []byte, path string) (*secret.Document, error) {
+	doc, err := secret.ParseKeep(data, path, keepDuplicates)
+	if duplicateKey(err) {
+		return nil, fmt.Errorf(i18n.T("%w; pass --keep first or --keep last to keep one of the values"), err)
 	}
+	return doc, err
+}
 
-	// Decode base64 values
-	decoded, err := secret.DecodeSecretData(data)
+// duplicateKey reports whether err is about a duplicate key, which, unlike
+// other parse errors, must not make a Secret pass for another document
+func duplicateKey(err error) bool {
+	var dup *secret.DuplicateKeyError
+	return errors.As(err, &dup)
+}
+
+// writeDecoded decodes a parsed Secret and writes it to a temp file, below
+// the help header if header is set
+func writeDecoded(doc *secret.Document, header bool) (string, func(), error) {
+	// Decode base64 values, leaving binary ones encoded
+	if err := doc.DecodeText(); err != nil {
+		return "", nil, fmt.Errorf("failed to decode secret: %w", err)
+	}
+	decoded, err := doc.Bytes()
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to decode secret: %w", err)
 	}
+	if header {
+		decoded = append(editHeader(doc), decoded...)
+	}
 
-	// Create temp file
-	tmpFile, err := os.CreateTemp("", "swk-*.yaml")
+	// Only the user can open the directory, which is kept in memory
+	// where possible; cleanup removes it with the file
+	return tempdir.WriteFile("swk-*", "secret.yaml", decoded)
+}
+
+// writeOriginal writes the unmodified input to a read-only temp file, shown
+// for comparison next to the decoded file
+func writeOriginal(data []byte) (string, func(), error) {
+	tmpPath, cleanup, err := tempdir.WriteFile("swk-original-*", "original.yaml", data)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to create temp file: %w", err)
+		return "", nil, err
 	}
-	tmpPath := tmpFile.Name()
-
-	// Write decoded data to temp file
-	if _, err := tmpFile.Write(decoded); err != nil {
-		_ = tmpFile.Close()
-		_ = os.Remove(tmpPath)
-		return "", nil, fmt.Errorf("failed to write temp file: %w", err)
+	// Editing the original would have no effect, so make that obvious
+	if err := os.Chmod(tmpPath, 0400); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to protect temp file: %w", err)
 	}
+	return tmpPath, cleanup, nil
+}
 
-	if err := tmpFile.Close(); err != nil {
-		_ = os.Remove(tmpPath)
-		return "", nil, fmt.Errorf("failed to close temp file: %w", err)
+// finalizeSecretFile reads the edited temp file, encodes values, and writes back to original
+func finalizeSecretFile(originalPath, tmpPath string, opts options) error {
+	encoded, err := encodeEdited(originalPath, tmpPath, opts)
+	if err != nil && !errors.Is(err, errEditCanceled) {
+		return &invalidEdit{err}
+	}
+	if err != nil || encoded == nil {
+		return err
+	}
+	if opts.confirm {
+		existing, _ := os.ReadFile(originalPath)
+		if err := showEditDiff(existing, encoded, opts.jsonPath); err != nil {
+			return err
+		}
+		if err := confirmSave(fmt.Sprintf(i18n.T("Save %s?"), originalPath)); err != nil {
+			return err
+		}
 	}
 
-	// Cleanup function to remove temp file
-	cleanup := func() {
-		_ = os.Remove(tmpPath)
+	// Write back to original file
+	if err := saveFile(originalPath, encoded); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
 	}
 
-	return tmpPath, cleanup, nil
+	return nil
 }
 
-// finalizeSecretFile reads the edited temp file, encodes values, and writes back to original
-func finalizeSecretFile(originalPath, tmpPath string) error {
+// encodeEdited reads the edited temp file and returns it encoded and
+// checked, or nil if the edit changed nothing
+func encodeEdited(originalPath, tmpPath string, opts options) ([]byte, error) {
 	// Read edited data
 	edited, err := os.ReadFile(tmpPath)
 	if err != nil {
-		return fmt.Errorf("failed to read edited file: %w", err)
+		return nil, fmt.Errorf("failed to read edited file: %w", err)
+	}
+	if canceled(edited) {
+		return nil, errEditCanceled
 	}
+	edited = stripHeader(edited)
 
-	// Encode base64 values
-	encoded, err := secret.EncodeSecretData(edited)
+	// Encode base64 values, validating the encoded document before
+	// marshalling it
+	doc, err := parseSecret(edited, opts.jsonPath)
 	if err != nil {
-		return fmt.Errorf("failed to encode secret: %w", err)
+		return nil, fmt.Errorf("failed to encode secret: %w", err)
+	}
+	if err := doc.Encode(); err != nil {
+		return nil, fmt.Errorf("failed to encode secret: %w", err)
+	}
+	// Values the edit didn't change keep their exact form
+	var unchanged []byte
+	if data, err := os.ReadFile(originalPath); err == nil {
+		if original, err := parseSecret(data, opts.jsonPath); err == nil {
+			doc.KeepUnchanged(original)
+			unchanged, _ = original.Bytes()
+		}
 	}
 
-	// Write back to original file
-	if err := os.WriteFile(originalPath, encoded, 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	if opts.strict {
+		if err := checkUnknownFields(doc); err != nil {
+			return nil, err
+		}
+	}
+	if opts.validate == "schema" {
+		if err := validateSchema(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	encoded, err := doc.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode secret: %w", err)
+	}
+	// An edit that changed nothing leaves the file as it was, byte for
+	// byte, even where yaml would write it differently, e.g. blank lines
+	if unchanged != nil && bytes.Equal(encoded, unchanged) {
+		return nil, nil
+	}
+	return encoded, nil
+}
+
+// resolveTarget resolves a file swk is about to write in place, refusing
+// unsafe links and files that a controller or the kubelet keeps in sync
+func resolveTarget(path string) (string, error) {
+	real, err := safefile.Resolve(path, followSymlinks)
+	if allowWatched {
+		return real, err
+	}
+	// Secret mounts are read-only in a pod, so Resolve fails there before
+	// Watched could give the reason
+	if err := safefile.Projected(path); err != nil {
+		return "", err
+	}
+	if err != nil {
+		return "", err
 	}
 
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return "", err
+	}
+	if err := safefile.Watched(path, real, cfg.Watched); err != nil {
+		return "", err
+	}
+	return real, nil
+}
+
+// saveFile writes a file swk produced, applying --mode if given, after
+// checking key locks and owners, or recording the write during break-glass,
+// and applying the confirmation and backup settings of the active profile
+func saveFile(path string, data []byte) error {
+	staged, err := stageFile(path, data)
+	if err != nil {
+		return err
+	}
+	return staged.Commit()
+}
+
+// stageFile runs the checks of saveFile and writes data next to path,
+// leaving the rename into place to the caller
+func stageFile(path string, data []byte) (*safefile.Staged, error) {
+	data, err := canonicalOutput(data, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkConstraints(path, data); err != nil {
+		return nil, err
+	}
+	if breakGlass == nil {
+		if err := checkChanges(path, data); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyProfile(path, data); err != nil {
+		return nil, err
+	}
+	if breakGlass != nil {
+		stamped, err := breakGlass.record(path, data)
+		if err != nil {
+			return nil, err
+		}
+		data = stamped
+	}
+	return stageWrite(path, data)
+}
+
+// writeFile writes a file, applying --mode if given
+func writeFile(path string, data []byte) error {
+	if fileMode != 0 {
+		return safefile.WriteFileMode(path, data, fileMode)
+	}
+	return safefile.WriteFile(path, data, 0644)
+}
+
+// stageWrite is writeFile, leaving the rename into place to the caller
+func stageWrite(path string, data []byte) (*safefile.Staged, error) {
+	if fileMode != 0 {
+		return safefile.StageMode(path, data, fileMode)
+	}
+	return safefile.Stage(path, data, 0644)
+}
+
+// validateSchema checks an encoded manifest against the OpenAPI schemas
+func validateSchema(doc *secret.Document) error {
+	registry, err := schema.Load()
+	if err != nil {
+		return err
+	}
+
+	errs, err := registry.ValidateNode(doc.Root())
+	if err != nil {
+		return fmt.Errorf("schema validation failed: %w", err)
+	}
+	if len(errs) > 0 {
+		msg := fmt.Sprintf(i18n.T("manifest does not match the %s schema:"), registry.Source)
+		for _, e := range errs {
+			msg += "\n  " + e.Error()
+		}
+		countCheck("schema")
+		return fmt.Errorf("%s", msg)
+	}
+
+	return nil
+}
+
+// checkUnknownFields rejects top-level fields the Secret's schema doesn't
+// define, which the API server would otherwise silently drop
+func checkUnknownFields(doc *secret.Document) error {
+	registry, err := schema.Load()
+	if err != nil {
+		return err
+	}
+
+	version := doc.Version()
+	errs, err := registry.UnknownFields(doc.Secret(), version.APIVersion, version.Kind)
+	if err != nil {
+		return fmt.Errorf("strict check failed: %w", err)
+	}
+	if len(errs) > 0 {
+		countCheck("strict")
+		msg := i18n.T("Secret has fields that Kubernetes would drop:")
+		for _, e := range errs {
+			msg += "\n  " + e.Error()
+		}
+		return fmt.Errorf("%s", msg)
+	}
 	return nil
 }
@@ -1,9 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/tempdir"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
 )
 
 func TestParseArgs(t *testing.T) {
@@ -28,6 +35,13 @@ func TestParseArgs(t *testing.T) {
 			wantFilePath: "/tmp/secret.yaml",
 			wantErr:      false,
 		},
+		{
+			name:         "builtin editor wins over -editor",
+			args:         []string{"-editor", "vim", "--builtin-editor", "/tmp/secret.yaml"},
+			wantEditor:   "builtin",
+			wantFilePath: "/tmp/secret.yaml",
+			wantErr:      false,
+		},
 		{
 			name:         "no editor flag, just file",
 			args:         []string{"/tmp/secret.yaml"},
@@ -49,31 +63,56 @@ func TestParseArgs(t *testing.T) {
 			wantFilePath: "",
 			wantErr:      true,
 		},
+		{
+			name:         "schema validation",
+			args:         []string{"-validate=schema", "/tmp/secret.yaml"},
+			wantEditor:   "",
+			wantFilePath: "/tmp/secret.yaml",
+			wantErr:      false,
+		},
+		{
+			name:         "json path",
+			args:         []string{"-json-path", ".spec.template", "/tmp/cr.yaml"},
+			wantEditor:   "",
+			wantFilePath: "/tmp/cr.yaml",
+			wantErr:      false,
+		},
+		{
+			name:         "json path with schema validation",
+			args:         []string{"-json-path", ".spec.template", "-validate=schema", "/tmp/cr.yaml"},
+			wantEditor:   "",
+			wantFilePath: "",
+			wantErr:      true,
+		},
+		{
+			name:         "unsupported validation mode",
+			args:         []string{"-validate=strict", "/tmp/secret.yaml"},
+			wantEditor:   "",
+			wantFilePath: "",
+			wantErr:      true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotEditor, gotFile, err := parseArgs(tt.args)
+			got, err := parseArgs(tt.args)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("parseArgs() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if err == nil {
-				if gotEditor != tt.wantEditor {
-					t.Errorf("parseArgs() editor = %v, want %v", gotEditor, tt.wantEditor)
+				if got.editor != tt.wantEditor {
+					t.Errorf("parseArgs() editor = %v, want %v", got.editor, tt.wantEditor)
 				}
-				if gotFile != tt.wantFilePath {
-					t.Errorf("parseArgs() file = %v, want %v", gotFile, tt.wantFilePath)
+				if got.filePath != tt.wantFilePath {
+					t.Errorf("parseArgs() file = %v, want %v", got.filePath, tt.wantFilePath)
 				}
 			}
 		})
 	}
 }
 
-func TestProcessSecretFile(t *testing.T) {
-	// Create a temporary directory for test files
-	tmpDir := t.TempDir()
-
+func TestWriteDecoded(t *testing.T) {
 	validSecret := `apiVersion: v1
 kind: Secret
 metadata:
@@ -83,83 +122,70 @@ data:
   password: cGFzc3dvcmQxMjM=
 `
 
-	invalidYAML := `this is not valid yaml: [[[`
-
-	notASecret := `apiVersion: v1
-kind: ConfigMap
-metadata:
-  name: test-config
-data:
-  key: value
-`
-
-	tests := []struct {
-		name        string
-		fileContent string
-		wantErr     bool
-	}{
-		{
-			name:        "valid secret file",
-			fileContent: validSecret,
-			wantErr:     false,
-		},
-		{
-			name:        "invalid YAML",
-			fileContent: invalidYAML,
-			wantErr:     true,
-		},
-		{
-			name:        "not a secret",
-			fileContent: notASecret,
-			wantErr:     true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Create test file
-			testFile := filepath.Join(tmpDir, "test.yaml")
-			if err := os.WriteFile(testFile, []byte(tt.fileContent), 0644); err != nil {
-				t.Fatalf("Failed to create test file: %v", err)
+	for _, header := range []bool{true, false} {
+		t.Run(fmt.Sprintf("header=%v", header), func(t *testing.T) {
+			doc, err := parseSecret([]byte(validSecret), "")
+			if err != nil {
+				t.Fatalf("parseSecret() error = %v", err)
 			}
-			defer func() { _ = os.Remove(testFile) }()
-
-			// Process the file
-			tmpFile, cleanup, err := processSecretFile(testFile)
-			if cleanup != nil {
-				defer cleanup()
+			tmpFile, cleanup, err := writeDecoded(doc, header)
+			if err != nil {
+				t.Fatalf("writeDecoded() error = %v", err)
 			}
 
-			if (err != nil) != tt.wantErr {
-				t.Errorf("processSecretFile() error = %v, wantErr %v", err, tt.wantErr)
-				return
+			content, err := os.ReadFile(tmpFile)
+			if err != nil {
+				t.Fatalf("Failed to read temp file: %v", err)
+			}
+			if !contains(content, []byte("password: password123")) {
+				t.Errorf("writeDecoded() did not decode base64 values:\n%s", content)
+			}
+			if got := contains(content, []byte(abortMarker)); got != header {
+				t.Errorf("header written = %v, want %v:\n%s", got, header, content)
 			}
 
-			if err == nil {
-				// Verify temp file was created
-				if _, err := os.Stat(tmpFile); os.IsNotExist(err) {
-					t.Errorf("processSecretFile() did not create temp file")
-				}
-
-				// Verify the temp file contains decoded data
-				content, err := os.ReadFile(tmpFile)
-				if err != nil {
-					t.Fatalf("Failed to read temp file: %v", err)
-				}
-
-				// Should contain decoded password
-				if !contains(content, []byte("password123")) {
-					t.Error("processSecretFile() did not decode base64 values")
-				}
+			cleanup()
+			if _, err := os.Stat(tmpFile); !os.IsNotExist(err) {
+				t.Error("cleanup should remove the temp file")
 			}
 		})
 	}
+
+	doc, err := parseSecret([]byte("apiVersion: v1\nkind: Secret\ndata:\n  password: '!!!'\n"), "")
+	if err != nil {
+		t.Fatalf("parseSecret() error = %v", err)
+	}
+	if _, _, err := writeDecoded(doc, true); err == nil {
+		t.Error("writeDecoded() should fail on a value that isn't base64")
+	}
 }
 
-func TestProcessSecretFileNonExistent(t *testing.T) {
-	_, _, err := processSecretFile("/nonexistent/file.yaml")
-	if err == nil {
-		t.Error("processSecretFile() should fail with non-existent file")
+func TestStageFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "secret.yaml")
+	data := []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: test-secret\ndata:\n  password: cGFzc3dvcmQxMjM=\n")
+
+	staged, err := stageFile(path, data)
+	if err != nil {
+		t.Fatalf("stageFile() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("stageFile() should leave the file in place until Commit")
+	}
+	if err := staged.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != string(data) {
+		t.Errorf("file = %q, want %q", got, data)
+	}
+
+	staged, err = stageFile(path, []byte("changed"))
+	if err != nil {
+		t.Fatalf("stageFile() error = %v", err)
+	}
+	staged.Discard()
+	if got, _ := os.ReadFile(path); string(got) != string(data) {
+		t.Errorf("file after Discard = %q, want %q", got, data)
 	}
 }
 
@@ -218,7 +244,7 @@ data:
 			}
 			defer func() { _ = os.Remove(tmpFile) }()
 
-			err := finalizeSecretFile(originalFile, tmpFile)
+			err := finalizeSecretFile(originalFile, tmpFile, options{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("finalizeSecretFile() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -293,40 +319,151 @@ func TestRun(t *testing.T) {
 	}
 }
 
-func TestProcessSecretFileWriteError(t *testing.T) {
-	// Test error when writing temp file fails
-	// This is hard to trigger naturally, but we test what we can
+func TestRunLang(t *testing.T) {
+	t.Cleanup(func() { i18n.SetLanguage(i18n.Default) })
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{"separate value", []string{"--lang", "nl"}, "gebruik: swk [-editor EDITOR] BESTAND...", false},
+		{"with equals", []string{"--lang=de_DE.UTF-8"}, "Verwendung: swk [-editor EDITOR] DATEI...", false},
+		{"unknown language", []string{"--lang", "xx"}, "usage: swk [-editor EDITOR] FILE...", false},
+		{"missing value", []string{"--lang"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i18n.SetLanguage(i18n.Default)
+			err := run(tt.args)
+			if err == nil {
+				t.Fatal("run() expected an error")
+			}
+			if !tt.wantErr && err.Error() != tt.want {
+				t.Errorf("run() error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFinalizeSecretFileValidateSchema(t *testing.T) {
 	tmpDir := t.TempDir()
-	validSecret := `apiVersion: v1
+	t.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	originalFile := filepath.Join(tmpDir, "original.yaml")
+	tmpFile := filepath.Join(tmpDir, "temp.yaml")
+	original := `apiVersion: v1
 kind: Secret
 metadata:
   name: test-secret
-type: Opaque
 data:
   password: cGFzc3dvcmQxMjM=
 `
-	testFile := filepath.Join(tmpDir, "test.yaml")
-	if err := os.WriteFile(testFile, []byte(validSecret), 0644); err != nil {
-		t.Fatalf("Failed to create test file: %v", err)
+	// immutable must be a boolean
+	edited := `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-secret
+immutable: "yes"
+data:
+  password: changed
+`
+	if err := os.WriteFile(originalFile, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to create original file: %v", err)
+	}
+	if err := os.WriteFile(tmpFile, []byte(edited), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	err := finalizeSecretFile(originalFile, tmpFile, options{validate: "schema"})
+	if err == nil || !strings.Contains(err.Error(), ".immutable") {
+		t.Fatalf("finalizeSecretFile() error = %v, want schema violation for .immutable", err)
 	}
 
-	// This should succeed normally
-	tmpFile, cleanup, err := processSecretFile(testFile)
+	// The original must be untouched when validation fails
+	content, err := os.ReadFile(originalFile)
 	if err != nil {
-		t.Errorf("processSecretFile() should succeed: %v", err)
+		t.Fatalf("Failed to read original file: %v", err)
+	}
+	if string(content) != original {
+		t.Error("finalizeSecretFile() should not write an invalid manifest")
+	}
+}
+
+func TestFinalizeSecretFileStrict(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", tmpDir)
+
+	edited := `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-secret
+stringdata:
+  password: changed
+`
+	tests := []struct {
+		name    string
+		opts    options
+		wantErr string
+	}{
+		{"strict rejects typos", options{strict: true}, `.stringdata (line 5): unknown field (did you mean "stringData"?)`},
+		{"lenient by default", options{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalFile := filepath.Join(tmpDir, "original.yaml")
+			tmpFile := filepath.Join(tmpDir, "temp.yaml")
+			if err := os.WriteFile(originalFile, []byte("original"), 0644); err != nil {
+				t.Fatalf("Failed to create original file: %v", err)
+			}
+			if err := os.WriteFile(tmpFile, []byte(edited), 0644); err != nil {
+				t.Fatalf("Failed to create temp file: %v", err)
+			}
+
+			err := finalizeSecretFile(originalFile, tmpFile, tt.opts)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("finalizeSecretFile() failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("finalizeSecretFile() error = %v, want %q", err, tt.wantErr)
+			}
+			if content, _ := os.ReadFile(originalFile); string(content) != "original" {
+				t.Error("finalizeSecretFile() should not write a rejected manifest")
+			}
+		})
 	}
-	if cleanup != nil {
-		cleanup()
+}
+
+func TestRunSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"no subcommand", []string{"schema"}, true},
+		{"unknown subcommand", []string{"schema", "bogus"}, true},
+		{"update without cluster", []string{"schema", "update", "-max-retries", "0", "-kubeconfig", "/nonexistent"}, true},
 	}
-	// Verify temp file is cleaned up
-	if _, err := os.Stat(tmpFile); !os.IsNotExist(err) {
-		t.Error("Cleanup should remove temp file")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := run(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
 	}
 }
 
 func TestFinalizeSecretFileReadError(t *testing.T) {
 	// Test error when reading edited file fails
-	err := finalizeSecretFile("/tmp/original.yaml", "/nonexistent/temp.yaml")
+	err := finalizeSecretFile("/tmp/original.yaml", "/nonexistent/temp.yaml", options{})
 	if err == nil {
 		t.Error("finalizeSecretFile() should fail with non-existent temp file")
 	}
@@ -334,15 +471,15 @@ func TestFinalizeSecretFileReadError(t *testing.T) {
 
 func TestParseArgsEditorShorthand(t *testing.T) {
 	// Ensure -e flag properly sets editor
-	editor, file, err := parseArgs([]string{"-e", "emacs", "/tmp/test.yaml"})
+	opts, err := parseArgs([]string{"-e", "emacs", "/tmp/test.yaml"})
 	if err != nil {
 		t.Fatalf("parseArgs() failed: %v", err)
 	}
-	if editor != "emacs" {
-		t.Errorf("parseArgs() editor = %q, want %q", editor, "emacs")
+	if opts.editor != "emacs" {
+		t.Errorf("parseArgs() editor = %q, want %q", opts.editor, "emacs")
 	}
-	if file != "/tmp/test.yaml" {
-		t.Errorf("parseArgs() file = %q, want %q", file, "/tmp/test.yaml")
+	if opts.filePath != "/tmp/test.yaml" {
+		t.Errorf("parseArgs() file = %q, want %q", opts.filePath, "/tmp/test.yaml")
 	}
 }
 
@@ -392,6 +529,92 @@ spec:
 	}
 }
 
+func TestRunCompare(t *testing.T) {
+	tmpDir := t.TempDir()
+	original := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: test\ndata:\n  password: c2VjcmV0\n"
+	testFile := filepath.Join(tmpDir, "secret.yaml")
+	if err := os.WriteFile(testFile, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// The editor records what it was given: the original first, then the
+	// decoded file, which it edits
+	logFile := filepath.Join(tmpDir, "editor.log")
+	script := filepath.Join(tmpDir, "editor.sh")
+	content := "#!/bin/sh\ncat \"$1\" > " + logFile + "\necho --- >> " + logFile + "\ncat \"$2\" >> " + logFile + "\nsed -i 's/secret/changed/' \"$2\"\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatalf("Failed to create editor script: %v", err)
+	}
+
+	if err := run([]string{"--compare", "-e", script, testFile}); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+
+	log, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Failed to read editor log: %v", err)
+	}
+	left, right, _ := strings.Cut(string(log), "---\n")
+	if left != original {
+		t.Errorf("left pane = %q, want the original", left)
+	}
+	if !strings.Contains(right, "password: secret") {
+		t.Errorf("right pane = %q, want the decoded Secret", right)
+	}
+
+	result, _ := os.ReadFile(testFile)
+	if !contains(result, []byte("password: Y2hhbmdlZA==")) {
+		t.Errorf("edit of the decoded file was not saved:\n%s", result)
+	}
+}
+
+func TestRunWithJSONPath(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	resource := `apiVersion: secrets.example.com/v1
+kind: SecretProvider
+metadata:
+  name: db
+spec:
+  template:
+    type: Opaque
+    data:
+      password: cGFzc3dvcmQxMjM=
+`
+
+	testFile := filepath.Join(tmpDir, "provider.yaml")
+	if err := os.WriteFile(testFile, []byte(resource), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// The editor changes the decoded value, which must come back encoded
+	editorScript := filepath.Join(tmpDir, "editor.sh")
+	script := "#!/bin/sh\nsed 's/password123/password456/' \"$1\" > \"$1.tmp\" && mv \"$1.tmp\" \"$1\"\n"
+	if err := os.WriteFile(editorScript, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to create editor script: %v", err)
+	}
+
+	if err := run([]string{"-e", editorScript, "-json-path", ".spec.template", testFile}); err != nil {
+		t.Fatalf("run() with -json-path failed: %v", err)
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	// base64("password456")
+	if !contains(content, []byte("password: cGFzc3dvcmQ0NTY=")) {
+		t.Errorf("run() did not re-encode the embedded Secret:\n%s", content)
+	}
+	if !contains(content, []byte("kind: SecretProvider")) {
+		t.Error("run() should preserve the wrapping resource")
+	}
+
+	if err := run([]string{"-e", "true", "-json-path", ".spec.missing", testFile}); err == nil {
+		t.Error("run() should fail when no Secret exists at -json-path")
+	}
+}
+
 func TestRunWithConfigMap(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -425,3 +648,156 @@ func contains(data []byte, substr []byte) bool {
 	}
 	return false
 }
+
+func TestRunWarnings(t *testing.T) {
+	var errOut bytes.Buffer
+	stderr = &errOut
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	testFile := filepath.Join(t.TempDir(), "secret.yaml")
+	content := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: test\ndata:\n  password: cGFzcwo=\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if err := run([]string{"-e", "true", testFile}); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+	if !strings.Contains(errOut.String(), `Warning: line 6: key "password"`) {
+		t.Errorf("stderr = %q, want a trailing newline warning", errOut.String())
+	}
+}
+
+func TestRunDuplicateKeys(t *testing.T) {
+	t.Cleanup(func() { keepDuplicates = secret.KeepNone })
+
+	testFile := filepath.Join(t.TempDir(), "secret.yaml")
+	content := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: test\ndata:\n  password: Zmlyc3Q=\n  password: bGFzdA==\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	err := run([]string{"-e", "true", testFile})
+	if err == nil || !strings.Contains(err.Error(), "--keep first") {
+		t.Fatalf("run() error = %v, want a duplicate key error", err)
+	}
+	if data, _ := os.ReadFile(testFile); string(data) != content {
+		t.Errorf("file changed after a refused edit:\n%s", data)
+	}
+
+	if err := run([]string{"--keep", "first", "-e", "true", testFile}); err != nil {
+		t.Fatalf("run() with --keep first failed: %v", err)
+	}
+	if got := queryFile(t, testFile, ".data.password | @base64d"); got != "first" {
+		t.Errorf("password = %q, want the first value", got)
+	}
+
+	if _, err := parseGlobalFlags([]string{"--keep", "both"}); err == nil {
+		t.Error("parseGlobalFlags() accepted --keep both")
+	}
+}
+
+func TestRunKeepsStyles(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "secret.yaml")
+	content := "apiVersion: v1\nkind: Secret\nmetadata: {name: test}\ndata: {password: 'c2VjcmV0', username: \"YWRtaW4=\"}\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if err := run([]string{"-e", "true", testFile}); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `data: {password: 'c2VjcmV0', username: "YWRtaW4="}`; !strings.Contains(string(data), want) {
+		t.Errorf("edit without changes restyled the file:\n%s\nwant %s", data, want)
+	}
+}
+
+func TestRunUnchangedIsByteExact(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "secret.yaml")
+	// Blank lines, extra spaces before a comment, 4-space indentation, and
+	// no final newline are all things yaml would write differently
+	content := "---\napiVersion: \"v1\"\nkind: Secret\n\nmetadata:\n    name: test   # primary\ndata:\n    password: c2VjcmV0\ntype: Opaque"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if err := run([]string{"-e", "true", testFile}); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("edit without changes rewrote the file:\n%q\nwant\n%q", data, content)
+	}
+}
+
+func TestGlobalEnv(t *testing.T) {
+	t.Cleanup(func() { assumeYes, profileName, plain = false, "", false })
+	env := map[string]string{"SWK_YES": "1", "SWK_PROFILE": "prod", "SWK_PLAIN": "true"}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	if err := applyGlobalEnv(lookup); err != nil {
+		t.Fatalf("applyGlobalEnv() error = %v", err)
+	}
+	if !assumeYes || profileName != "prod" || !plain {
+		t.Errorf("yes = %v, profile = %q, plain = %v, want them from the environment", assumeYes, profileName, plain)
+	}
+
+	// Flags win over the environment
+	if _, err := parseGlobalFlags([]string{"--profile", "dev", "--plain=false", "ls"}); err != nil {
+		t.Fatal(err)
+	}
+	if profileName != "dev" || plain {
+		t.Errorf("profile = %q, plain = %v, want the flags' values", profileName, plain)
+	}
+
+	env["SWK_KEEP"] = "both"
+	if err := applyGlobalEnv(lookup); err == nil || !strings.Contains(err.Error(), "SWK_KEEP") {
+		t.Errorf("applyGlobalEnv() error = %v, want one naming SWK_KEEP", err)
+	}
+}
+
+func TestRunTmpDir(t *testing.T) {
+	t.Cleanup(func() { tempdir.Override = "" })
+	dir := t.TempDir()
+	private := filepath.Join(dir, "private")
+	if err := os.Mkdir(private, 0755); err != nil {
+		t.Fatal(err)
+	}
+	testFile := filepath.Join(dir, "secret.yaml")
+	if err := os.WriteFile(testFile, []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: test\ndata:\n  password: c2VjcmV0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The editor records where the decoded file is and who may open it
+	logFile := filepath.Join(dir, "editor.log")
+	script := filepath.Join(dir, "editor.sh")
+	content := "#!/bin/sh\necho \"$1\" > " + logFile + "\nstat -c %a \"$(dirname \"$1\")\" \"$1\" >> " + logFile + "\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"--tmpdir", private, "-e", script, testFile}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	log, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(log)), "\n")
+	if len(lines) != 3 || filepath.Dir(filepath.Dir(lines[0])) != private || lines[1] != "700" || lines[2] != "600" {
+		t.Errorf("editor saw %q, want a 0600 file in a 0700 directory below %s", lines, private)
+	}
+	if entries, _ := os.ReadDir(private); len(entries) != 0 {
+		t.Errorf("%s holds %d entries after the edit, want the private directory removed", private, len(entries))
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/merge"
+)
+
+// runMerge handles `swk merge BASE OURS THEIRS`, a key-level three-way merge
+// usable as a git merge driver
+func runMerge(args []string) error {
+	fs := flag.NewFlagSet("swk merge", flag.ContinueOnError)
+	output := fs.String("o", "", "Write the result here instead of OURS (- for stdout)")
+	resolve := fs.String("resolve", "auto", "Conflict handling: auto (prompt on a terminal), prompt, ours, theirs, or fail")
+	editorFlag := fs.String("editor", "", "Editor used to edit conflicting values")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 3 {
+		return fmt.Errorf("usage: swk merge BASE OURS THEIRS [-o OUT] [--resolve auto|prompt|ours|theirs|fail]")
+	}
+
+	var contents [3][]byte
+	for i, path := range positional {
+		if contents[i], err = os.ReadFile(path); err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	result, err := merge.Manifests(contents[0], contents[1], contents[2])
+	if err != nil {
+		return fmt.Errorf("merge failed: %w", err)
+	}
+
+	if len(result.Conflicts) > 0 {
+		mode := *resolve
+		if mode == "auto" {
+			mode = "fail"
+			if isTerminal() {
+				mode = "prompt"
+			}
+		}
+
+		switch mode {
+		case "ours", "theirs":
+			for _, c := range result.Conflicts {
+				if mode == "ours" {
+					result.Resolve(c.Key, c.Ours)
+				} else {
+					result.Resolve(c.Key, c.Theirs)
+				}
+			}
+		case "prompt":
+			r := &resolver{in: bufio.NewReader(stdin), out: stdout, editor: *editorFlag, plain: plain}
+			if err := r.resolve(result); err != nil {
+				return err
+			}
+		case "fail":
+			return conflictError(result.Conflicts)
+		default:
+			return fmt.Errorf("unknown --resolve mode %q", *resolve)
+		}
+	}
+
+	merged, err := result.Bytes()
+	if err != nil {
+		return err
+	}
+
+	return writeResult(positional[1], *output, merged)
+}
+
+// conflictError lists conflicting keys without revealing their values
+func conflictError(conflicts []merge.Conflict) error {
+	msg := fmt.Sprintf("%d conflicting key(s):", len(conflicts))
+	for _, c := range conflicts {
+		msg += "\n  " + c.Key
+	}
+	return fmt.Errorf("%s", msg)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeMergeInputs writes base/ours/theirs Secrets where "password"
+// conflicts and "username" merges cleanly
+func writeMergeInputs(t *testing.T) (string, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	secretWith := func(user, pass string) string {
+		return "apiVersion: v1\nkind: Secret\nmetadata:\n  name: test\ndata:\n" +
+			"  username: " + base64.StdEncoding.EncodeToString([]byte(user)) + "\n" +
+			"  password: " + base64.StdEncoding.EncodeToString([]byte(pass)) + "\n"
+	}
+
+	files := map[string]string{
+		"base.yaml":   secretWith("admin", "base"),
+		"ours.yaml":   secretWith("admin", "ours"),
+		"theirs.yaml": secretWith("root", "theirs"),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return filepath.Join(dir, "base.yaml"), filepath.Join(dir, "ours.yaml"), filepath.Join(dir, "theirs.yaml")
+}
+
+func withStdin(t *testing.T, input string, terminal bool) {
+	t.Helper()
+	oldStdin, oldTerminal := stdin, isTerminal
+	stdin = strings.NewReader(input)
+	isTerminal = func() bool { return terminal }
+	t.Cleanup(func() {
+		stdin, isTerminal = oldStdin, oldTerminal
+	})
+}
+
+func captureStdout(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	stdout = &buf
+	t.Cleanup(func() { stdout = os.Stdout })
+	return &buf
+}
+
+func TestRunMerge(t *testing.T) {
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name     string
+		extra    []string
+		input    string
+		terminal bool
+		want     []string
+		wantErr  string
+	}{
+		{
+			name:    "fails on conflict without a terminal",
+			wantErr: "password",
+		},
+		{
+			name:  "resolve ours",
+			extra: []string{"--resolve", "ours"},
+			want:  []string{"username: " + b64("root"), "password: " + b64("ours")},
+		},
+		{
+			name:  "resolve theirs",
+			extra: []string{"--resolve", "theirs"},
+			want:  []string{"password: " + b64("theirs")},
+		},
+		{
+			name:     "prompt picks right",
+			input:    "x\nr\n",
+			terminal: true,
+			want:     []string{"password: " + b64("theirs")},
+		},
+		{
+			name:     "prompt abort",
+			input:    "a\n",
+			terminal: true,
+			wantErr:  "aborted",
+		},
+		{
+			name:     "prompt eof",
+			input:    "",
+			terminal: true,
+			wantErr:  "aborted",
+		},
+		{
+			name:    "unknown mode",
+			extra:   []string{"--resolve", "coinflip"},
+			wantErr: "unknown --resolve mode",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, ours, theirs := writeMergeInputs(t)
+			withStdin(t, tt.input, tt.terminal)
+			captureStdout(t)
+
+			err := run(append([]string{"merge", base, ours, theirs}, tt.extra...))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("run() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run() failed: %v", err)
+			}
+
+			content, err := os.ReadFile(ours)
+			if err != nil {
+				t.Fatalf("Failed to read result: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("merged file missing %q:\n%s", want, content)
+				}
+			}
+		})
+	}
+}
+
+func TestRunMergeEditValue(t *testing.T) {
+	base, ours, theirs := writeMergeInputs(t)
+	withStdin(t, "e\n", true)
+	out := captureStdout(t)
+
+	editorScript := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(editorScript, []byte("#!/bin/sh\nprintf 'edited\\n' > \"$1\"\n"), 0755); err != nil {
+		t.Fatalf("Failed to create editor script: %v", err)
+	}
+
+	if err := run([]string{"merge", base, ours, theirs, "--editor", editorScript, "-o", "-"}); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+
+	want := "password: " + base64.StdEncoding.EncodeToString([]byte("edited"))
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("run() output missing %q:\n%s", want, out.String())
+	}
+	if !strings.Contains(out.String(), "─ ours ") || !strings.Contains(out.String(), "─ theirs ") {
+		t.Errorf("run() should render both sides:\n%s", out.String())
+	}
+}
+
+func TestRunMergePlain(t *testing.T) {
+	t.Cleanup(func() { plain = false })
+	base, ours, theirs := writeMergeInputs(t)
+	withStdin(t, "no\nmaybe\nyes\n", true)
+	out := captureStdout(t)
+
+	if err := run([]string{"--plain", "merge", base, ours, theirs}); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+
+	content, err := os.ReadFile(ours)
+	if err != nil {
+		t.Fatalf("Failed to read result: %v", err)
+	}
+	if want := "password: " + base64.StdEncoding.EncodeToString([]byte("theirs")); !strings.Contains(string(content), want) {
+		t.Errorf("merged file missing %q:\n%s", want, content)
+	}
+	for _, want := range []string{"Our value:\n  ours\n", "Their value:\n  theirs\n", "Keep their value? (yes/no)", "Please answer yes or no."} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.ContainsAny(out.String(), "┌│└") {
+		t.Errorf("plain output should not draw boxes:\n%s", out.String())
+	}
+}
+
+func TestRunMergeArgs(t *testing.T) {
+	if err := run([]string{"merge", "a", "b"}); err == nil {
+		t.Error("run() should require three files")
+	}
+	if err := run([]string{"merge", "/nonexistent/a", "/nonexistent/b", "/nonexistent/c"}); err == nil {
+		t.Error("run() should fail on missing files")
+	}
+}
+
+func TestSideLines(t *testing.T) {
+	long := strings.Repeat("x", columnWidth+4)
+	got := sideLines(&long)
+	if len(got) != 2 || got[1] != "xxxx" {
+		t.Errorf("sideLines() = %q, want wrapped to two lines", got)
+	}
+	if got := sideLines(nil); got[0] != "(deleted)" {
+		t.Errorf("sideLines(nil) = %q, want (deleted)", got)
+	}
+}
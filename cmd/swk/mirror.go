@@ -0,0 +1,96 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/mirror"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+)
+
+func init() {
+	commands["mirror"] = runMirror
+}
+
+// runMirror handles `swk mirror -o DIR [--selector SEL] [--watch]`,
+// exporting the matching Secrets of a cluster as clean manifests, one file
+// per Secret, and with --watch keeping the directory in step until
+// interrupted
+func runMirror(args []string) error {
+	var clusterOpts cluster.Options
+	fs := flag.NewFlagSet("swk mirror", flag.ContinueOnError)
+	clusterOpts.BindFlags(fs)
+	allNamespaces := fs.Bool("all-namespaces", false, "Mirror Secrets in every namespace")
+	fs.BoolVar(allNamespaces, "A", false, "Shorthand for -all-namespaces")
+	selector := fs.String("selector", secret.ExportLabel+"=true", "Label selector of the Secrets to mirror")
+	dir := fs.String("o", "", "Directory to write the manifests to, as NAMESPACE/NAME.yaml")
+	watch := fs.Bool("watch", false, "Keep mirroring until interrupted")
+	interval := fs.Duration("interval", 30*time.Second, "How often to look for changes with --watch")
+	sops := fs.Bool("sops", false, "Encrypt the values of each manifest with sops, using its .sops.yaml rules")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 || *dir == "" {
+		return fmt.Errorf("usage: swk mirror -o DIR [--selector SELECTOR] [-n NAMESPACE | -A] [--watch [--interval DURATION]] [--sops]")
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	m := &mirror.Mirror{Dir: *dir}
+	if *sops {
+		m.Encrypt = sopsEncrypt
+	}
+	client := cluster.New(clusterOpts)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for {
+		err := mirrorOnce(ctx, client, m, *selector, *allNamespaces)
+		if !*watch {
+			return err
+		}
+		if err != nil {
+			// A watch outlives API hiccups; the next round tries again
+			fmt.Fprintln(stderr, fmt.Sprintf(i18n.T("Warning: %s"), err))
+		}
+
+		timer := time.NewTimer(*interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// mirrorOnce fetches the matching Secrets and syncs the directory with them
+func mirrorOnce(ctx context.Context, client *cluster.Client, m *mirror.Mirror, selector string, allNamespaces bool) error {
+	items, err := client.GetSecrets(ctx, selector, allNamespaces)
+	if err != nil {
+		return err
+	}
+	result, err := m.Sync(items)
+	if err != nil {
+		return err
+	}
+	for _, file := range result.Written {
+		fmt.Fprintf(stderr, i18n.T("Wrote %s\n"), file)
+	}
+	for _, file := range result.Removed {
+		fmt.Fprintf(stderr, i18n.T("Removed %s\n"), file)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunMirror(t *testing.T) {
+	bin := t.TempDir()
+	listFile := filepath.Join(bin, "list.json")
+	script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(bin, "args") + "\ncat " + listFile + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "kubectl"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake kubectl: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	setList := func(items ...string) {
+		list := `{"items":[` + strings.Join(items, ",") + `]}`
+		if err := os.WriteFile(listFile, []byte(list), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	origEncrypt := sopsEncrypt
+	sopsEncrypt = func(data []byte) ([]byte, error) { return append([]byte("sops: true\n"), data...), nil }
+	t.Cleanup(func() { sopsEncrypt = origEncrypt })
+	var errOut strings.Builder
+	stderr = &errOut
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	dir := filepath.Join(t.TempDir(), "backup")
+	db := `{"metadata":{"name":"db","namespace":"prod","uid":"1234"},"type":"Opaque","data":{"password":"c2VjcmV0"}}`
+	setList(db)
+	if err := run([]string{"mirror", "-A", "-o", dir, "--sops"}); err != nil {
+		t.Fatalf("mirror failed: %v", err)
+	}
+	args, _ := os.ReadFile(filepath.Join(bin, "args"))
+	if !strings.Contains(string(args), "--selector swk.dev/export=true --all-namespaces") {
+		t.Errorf("kubectl args = %q, want the default selector", args)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "prod", "db.yaml"))
+	if err != nil || !strings.HasPrefix(string(data), "sops: true\n") || strings.Contains(string(data), "uid") {
+		t.Errorf("prod/db.yaml = %q, %v, want a cleaned, encrypted manifest", data, err)
+	}
+
+	setList()
+	if err := run([]string{"mirror", "-A", "-o", dir}); err != nil {
+		t.Fatalf("mirror failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "prod", "db.yaml")); !os.IsNotExist(err) {
+		t.Errorf("mirror kept the file of a deleted Secret: %v", err)
+	}
+	if !strings.Contains(errOut.String(), "Removed prod/db.yaml") {
+		t.Errorf("stderr = %q, want the removal", errOut.String())
+	}
+
+	if err := run([]string{"mirror"}); err == nil || !strings.Contains(err.Error(), "usage") {
+		t.Errorf("mirror without -o error = %v, want usage", err)
+	}
+}
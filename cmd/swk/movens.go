@@ -0,0 +1,124 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+)
+
+func init() {
+	commands["move-ns"] = runMoveNs
+}
+
+// runMoveNs handles `swk move-ns secret/NAME --from OLD --to NEW
+// [--delete-source]`, copying a Secret to another namespace and checking
+// the copy before the original is deleted
+func runMoveNs(args []string) error {
+	var clusterOpts cluster.Options
+	fs := flag.NewFlagSet("swk move-ns", flag.ContinueOnError)
+	clusterOpts.BindFlags(fs)
+	from := fs.String("from", "", "Namespace the Secret is in (default: --namespace)")
+	to := fs.String("to", "", "Namespace to move the Secret to")
+	deleteSource := fs.Bool("delete-source", false, "Delete the original once the copy is verified")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *from == "" {
+		*from = clusterOpts.Namespace
+	}
+	const usage = "usage: swk move-ns secret/NAME --from NAMESPACE --to NAMESPACE [--delete-source]"
+	if len(positional) != 1 || *from == "" || *to == "" {
+		return fmt.Errorf(usage)
+	}
+	name, ok := secretArg(positional[0])
+	if !ok {
+		return fmt.Errorf(usage)
+	}
+	if *from == *to {
+		return fmt.Errorf("--from and --to are both %s", *from)
+	}
+
+	clusterOpts.Namespace = ""
+	client := cluster.New(clusterOpts)
+	ctx := context.Background()
+
+	source, err := client.GetSecretIn(ctx, *from, name)
+	if err != nil {
+		return err
+	}
+	exists, err := client.SecretExists(ctx, *to, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf(i18n.T("%s/%s already exists; not overwriting it"), *to, name)
+	}
+
+	manifest, err := relocateManifest(source, *to, name)
+	if err != nil {
+		return err
+	}
+	if err := client.Apply(ctx, manifest); err != nil {
+		return err
+	}
+	if err := verifyCopy(ctx, client, source, *to, name); err != nil {
+		return fmt.Errorf(i18n.T("%w; %s/%s was kept"), err, *from, name)
+	}
+	fmt.Fprintf(stderr, i18n.T("Copied %s/%s to %s/%s\n"), *from, name, *to, name)
+
+	if !*deleteSource {
+		return nil
+	}
+	if !assumeYes {
+		if !isTerminal() {
+			return fmt.Errorf(i18n.T("pass --yes to delete %s/%s"), *from, name)
+		}
+		ok, err := ask(bufio.NewReader(stdin), stderr, fmt.Sprintf(i18n.T("Delete %s/%s?"), *from, name))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+	if err := client.DeleteSecret(ctx, *from, name); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, i18n.T("Deleted %s/%s\n"), *from, name)
+	return nil
+}
+
+// verifyCopy fetches the copied Secret and checks it has the type and
+// values of the source
+func verifyCopy(ctx context.Context, client *cluster.Client, source []byte, namespace, name string) error {
+	copied, err := client.GetSecretIn(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	var types []string
+	var values []map[string]string
+	for _, data := range [][]byte{source, copied} {
+		doc, err := parseSecret(data, "")
+		if err != nil {
+			return err
+		}
+		if err := doc.Decode(); err != nil {
+			return err
+		}
+		types = append(types, doc.Type())
+		values = append(values, doc.Values())
+	}
+	if types[0] != types[1] || !maps.Equal(values[0], values[1]) {
+		return fmt.Errorf(i18n.T("the copy in %s doesn't match the original"), namespace)
+	}
+	return nil
+}
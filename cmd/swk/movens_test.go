@@ -0,0 +1,82 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunMoveNs(t *testing.T) {
+	t.Cleanup(func() { assumeYes = false })
+	stderr = &strings.Builder{}
+	t.Cleanup(func() { stderr = os.Stderr })
+	withStdin(t, "", false)
+
+	cluster := fakeCluster(t)
+	source := filepath.Join(cluster, "old", "db")
+	write := func() {
+		manifest := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\n  namespace: old\n  uid: 1234\n  resourceVersion: \"42\"\ntype: Opaque\ndata:\n  password: c2VjcmV0\n"
+		if err := os.MkdirAll(filepath.Dir(source), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(source, []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.RemoveAll(filepath.Join(cluster, "new"))
+	}
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantErr    string
+		wantSource bool
+		wantCopy   bool
+	}{
+		{"copy", []string{"secret/db", "--from", "old", "--to", "new"}, "", true, true},
+		{"delete needs --yes", []string{"secret/db", "--from", "old", "--to", "new", "--delete-source"}, "pass --yes", true, true},
+		{"move", []string{"--yes", "move-ns", "db", "-n", "old", "--to", "new", "--delete-source"}, "", false, true},
+		{"same namespace", []string{"secret/db", "--from", "old", "--to", "old"}, "both old", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			write()
+			args := tt.args
+			if args[0] != "--yes" {
+				args = append([]string{"move-ns"}, args...)
+			}
+			err := run(args)
+			if (err != nil) != (tt.wantErr != "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("move-ns error = %v, want %q", err, tt.wantErr)
+			}
+			if exists(source) != tt.wantSource {
+				t.Errorf("source exists = %v, want %v", !tt.wantSource, tt.wantSource)
+			}
+			if !tt.wantCopy {
+				return
+			}
+			copied, err := os.ReadFile(filepath.Join(cluster, "new", "db"))
+			if err != nil || !strings.Contains(string(copied), "namespace: new") || strings.Contains(string(copied), "uid") {
+				t.Errorf("copy = %q, %v, want db in new without its uid", copied, err)
+			}
+		})
+	}
+
+	write()
+	if err := os.MkdirAll(filepath.Join(cluster, "new"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cluster, "new", "db"), []byte("taken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"move-ns", "secret/db", "--from", "old", "--to", "new"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("move-ns onto an existing Secret error = %v, want a refusal", err)
+	}
+}
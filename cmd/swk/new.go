@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/generate"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+	"gopkg.in/yaml.v3"
+)
+
+// runNew handles `swk new NAME [--from-literal KEY=VALUE] [--generate KEY=SPEC]`.
+// Literal values can use per-cluster variables, e.g. {{ .domain }}.
+func runNew(args []string) error {
+	fs := flag.NewFlagSet("swk new", flag.ContinueOnError)
+	namespace := fs.String("namespace", "", "Namespace of the Secret")
+	fs.StringVar(namespace, "n", "", "Namespace of the Secret (shorthand)")
+	secretType := fs.String("type", "Opaque", "Secret type")
+	var literals, generated stringList
+	fs.Var(&literals, "from-literal", "Data key as KEY=VALUE (repeatable)")
+	fs.Var(&generated, "generate", "Generated data key as KEY=SPEC, e.g. password=passphrase:words=5 (repeatable)")
+	varsFrom := fs.String("vars-from", "", "ConfigMap, as NAMESPACE/NAME, with the variables for {{ .var }} in literal values (default: vars.configMap in the config file)")
+	var vars stringList
+	fs.Var(&vars, "var", "Template variable as KEY=VALUE, overriding the ConfigMap (repeatable)")
+	output := fs.String("o", "-", "Write the manifest to this file (- for stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: swk new NAME [-n NAMESPACE] [--type TYPE] [--from-literal KEY=VALUE]... [--generate KEY=SPEC]... [--vars-from NAMESPACE/NAME] [--var KEY=VALUE]... [-o FILE]")
+		fs.PrintDefaults()
+		printGenerators(fs.Output())
+	}
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: swk new NAME [--from-literal KEY=VALUE]... [--generate KEY=SPEC]...")
+	}
+
+	var keys []string
+	values := make(map[string]string)
+	annotations := make(map[string]string)
+	add := func(key, value string) error {
+		if _, ok := values[key]; ok {
+			return fmt.Errorf("duplicate key %q", key)
+		}
+		keys = append(keys, key)
+		values[key] = value
+		return nil
+	}
+
+	templates, err := newTemplateVars(*varsFrom, vars)
+	if err != nil {
+		return err
+	}
+	for _, literal := range literals {
+		key, value, ok := strings.Cut(literal, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid --from-literal %q, expected KEY=VALUE", literal)
+		}
+		if value, err = templates.render(value); err != nil {
+			return fmt.Errorf("key %q: %w", key, err)
+		}
+		if err := add(key, value); err != nil {
+			return err
+		}
+	}
+
+	for _, g := range generated {
+		key, rawSpec, ok := strings.Cut(g, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid --generate %q, expected KEY=SPEC", g)
+		}
+		spec, err := generate.ParseSpec(rawSpec)
+		if err != nil {
+			return err
+		}
+		out, err := spec.Run()
+		if err != nil {
+			return fmt.Errorf("key %q: %w", key, err)
+		}
+
+		generatedKeys := out.Keys(key)
+		names := make([]string, 0, len(generatedKeys))
+		for k := range generatedKeys {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			if err := add(k, generatedKeys[k]); err != nil {
+				return err
+			}
+		}
+		annotations[generatorAnnotation+key] = spec.Public().String()
+	}
+
+	result, err := newSecret(positional[0], *namespace, *secretType, keys, values, annotations)
+	if err != nil {
+		return err
+	}
+
+	return writeResult(*output, *output, result)
+}
+
+// newSecret renders a Secret manifest with the given plaintext data, in key
+// order
+func newSecret(name, namespace, secretType string, keys []string, values, annotations map[string]string) ([]byte, error) {
+	metadata := mapping("name", name)
+	if namespace != "" {
+		metadata.Content = append(metadata.Content, mapping("namespace", namespace).Content...)
+	}
+	if len(annotations) > 0 {
+		names := make([]string, 0, len(annotations))
+		for k := range annotations {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		a := mapping()
+		for _, k := range names {
+			a.Content = append(a.Content, mapping(k, annotations[k]).Content...)
+		}
+		metadata.Content = append(metadata.Content, scalarNode("annotations"), a)
+	}
+
+	root := mapping("apiVersion", "v1", "kind", "Secret")
+	root.Content = append(root.Content, scalarNode("metadata"), metadata)
+	root.Content = append(root.Content, mapping("type", secretType).Content...)
+	if len(keys) > 0 {
+		data := mapping()
+		for _, k := range keys {
+			data.Content = append(data.Content, mapping(k, values[k]).Content...)
+		}
+		root.Content = append(root.Content, scalarNode("data"), data)
+	}
+
+	manifest, err := marshalNode(root)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := secret.EncodeSecretData(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode secret: %w", err)
+	}
+	return encoded, nil
+}
+
+// marshalNode renders a mapping node as a YAML document
+func marshalNode(root *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}); err != nil {
+		return nil, fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// mapping builds a mapping node from alternating keys and values
+func mapping(pairs ...string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, s := range pairs {
+		node.Content = append(node.Content, scalarNode(s))
+	}
+	return node
+}
+
+func scalarNode(v string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/output"
+)
+
+// noPager writes long output straight to stdout. Set by --no-pager.
+var noPager bool
+
+// stdoutIsTerminal reports whether stdout is an interactive terminal
+var stdoutIsTerminal = func() bool {
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// stdoutTerminal reports whether output on stdout may be colored
+func stdoutTerminal() output.Terminal {
+	return terminalFor(stdoutIsTerminal())
+}
+
+// stderrTerminal reports whether output on stderr may be colored or redrawn
+func stderrTerminal() output.Terminal {
+	return terminalFor(stderrIsTerminal())
+}
+
+// terminalFor applies the environment conventions, and plain mode, which
+// turns off color and cursor control alike
+func terminalFor(tty bool) output.Terminal {
+	if plain {
+		return output.Terminal{}
+	}
+	return output.Detect(tty, os.Getenv)
+}
+
+// paged runs fn with stdout going through the pager, like git does for long
+// output, if stdout is a terminal and a pager is configured
+func paged(fn func() error) error {
+	command := output.PagerCommand(os.LookupEnv)
+	if noPager || command == "" || !stdoutIsTerminal() {
+		return fn()
+	}
+	pager, err := output.StartPager(command, os.Stdout, os.Stderr)
+	if err != nil {
+		return fn()
+	}
+
+	saved := stdout
+	stdout = pager
+	err = fn()
+	stdout = saved
+	// The pager's exit status is the user's business, as with git
+	_ = pager.Close()
+	return err
+}
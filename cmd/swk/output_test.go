@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckColors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	content := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nstringData:\n  password: <CHANGEME>\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { plain = false })
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		plain    bool
+		wantANSI bool
+	}{
+		{"redirected", nil, false, false},
+		{"forced", map[string]string{"CLICOLOR_FORCE": "1"}, false, true},
+		{"forced but NO_COLOR", map[string]string{"CLICOLOR_FORCE": "1", "NO_COLOR": "1"}, false, false},
+		{"forced but plain", map[string]string{"CLICOLOR_FORCE": "1"}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"CLICOLOR_FORCE", "NO_COLOR", "CLICOLOR"} {
+				t.Setenv(name, tt.env[name])
+			}
+			plain = tt.plain
+			out := captureStdout(t)
+
+			if err := run([]string{"check", file}); err == nil {
+				t.Fatal("check succeeded despite a placeholder")
+			}
+			if got := strings.Contains(out.String(), "\x1b[31m"); got != tt.wantANSI {
+				t.Errorf("colored = %v, want %v: %q", got, tt.wantANSI, out)
+			}
+		})
+	}
+}
+
+func TestPaged(t *testing.T) {
+	t.Cleanup(func() { noPager = false })
+	oldTerminal := stdoutIsTerminal
+	stdoutIsTerminal = func() bool { return true }
+	t.Cleanup(func() { stdoutIsTerminal = oldTerminal })
+
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+		t.Fatal(err)
+	}
+	paged := filepath.Join(t.TempDir(), "paged.txt")
+	t.Setenv("SWK_PAGER", "cat > "+paged)
+
+	out := captureStdout(t)
+	if err := run([]string{"export", file}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	got, err := os.ReadFile(paged)
+	if err != nil || !strings.Contains(string(got), "test-secret") || out.Len() != 0 {
+		t.Errorf("paged = %q, %v; stdout = %q; want the export in the pager", got, err, out)
+	}
+
+	_ = os.Remove(paged)
+	if err := run([]string{"--no-pager", "export", file}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if _, err := os.Stat(paged); err == nil || !strings.Contains(out.String(), "test-secret") {
+		t.Errorf("--no-pager still used the pager; stdout = %q", out)
+	}
+}
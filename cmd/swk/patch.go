@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/patch"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+)
+
+// runPatch handles `swk patch FILE --patch PATCH [--type merge|json]`
+func runPatch(args []string) error {
+	fs := flag.NewFlagSet("swk patch", flag.ContinueOnError)
+	patchFlag := fs.String("patch", "", "Patch to apply, with plaintext values for data keys")
+	patchFile := fs.String("patch-file", "", "File containing the patch")
+	patchType := fs.String("type", patch.TypeStrategic, "Patch type: strategic, merge, or json")
+	output := fs.String("o", "", "Write the result here instead of back to FILE (- for stdout)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: swk patch FILE --patch PATCH [--type strategic|merge|json] [-o OUT]")
+	}
+	filePath := positional[0]
+
+	patchData := []byte(*patchFlag)
+	if *patchFile != "" {
+		if *patchFlag != "" {
+			return fmt.Errorf("--patch and --patch-file are mutually exclusive")
+		}
+		if patchData, err = os.ReadFile(*patchFile); err != nil {
+			return fmt.Errorf("failed to read patch file: %w", err)
+		}
+	}
+	if len(patchData) == 0 {
+		return fmt.Errorf("a patch is required (--patch or --patch-file)")
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	result, err := patchManifest(data, patchData, *patchType)
+	if err != nil {
+		return err
+	}
+
+	return writeResult(filePath, *output, result)
+}
+
+// patchManifest applies a patch to a manifest. Secrets are patched in their
+// decoded form, so patch values for data keys are plaintext.
+func patchManifest(data, patchData []byte, patchType string) ([]byte, error) {
+	doc, err := parseSecret(data, "")
+	if err != nil || !doc.IsSecret() {
+		result, err := patch.Apply(data, patchData, patchType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply patch: %w", err)
+		}
+		return result, nil
+	}
+
+	// Binary values stay base64, marked so that encoding passes them through
+	if err := doc.DecodeText(); err != nil {
+		return nil, fmt.Errorf("failed to decode secret: %w", err)
+	}
+	binary := map[string]string{}
+	for _, e := range doc.Data() {
+		if doc.IsBinary(e.Key) {
+			binary[e.Key] = e.Value
+		}
+	}
+	decoded, err := doc.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode secret: %w", err)
+	}
+
+	patched, err := patch.Apply(decoded, patchData, patchType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply patch: %w", err)
+	}
+	if patched, err = unmarkPatched(patched, binary); err != nil {
+		return nil, err
+	}
+
+	encoded, err := secret.EncodeSecretData(patched)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode secret: %w", err)
+	}
+	return encoded, nil
+}
+
+// unmarkPatched drops the binary mark of values the patch replaced, which
+// are plaintext like any other patch value. binary holds the base64 values
+// that were marked.
+func unmarkPatched(patched []byte, binary map[string]string) ([]byte, error) {
+	if len(binary) == 0 {
+		return patched, nil
+	}
+	doc, err := secret.Parse(patched)
+	if err != nil || !doc.IsSecret() {
+		return patched, nil
+	}
+	changed := false
+	for _, e := range doc.Data() {
+		if old, ok := binary[e.Key]; ok && e.Value != old {
+			doc.SetBinary(e.Key, false)
+			changed = true
+		}
+	}
+	if !changed {
+		return patched, nil
+	}
+	return doc.Bytes()
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunPatch(t *testing.T) {
+	original := `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-secret
+data:
+  username: YWRtaW4=
+  password: cGFzc3dvcmQxMjM=
+`
+
+	tests := []struct {
+		name     string
+		args     func(file string) []string
+		contains []string
+		absent   []string
+		wantErr  bool
+	}{
+		{
+			name: "merge patch with plaintext values",
+			args: func(file string) []string {
+				return []string{"patch", file, "--patch", `{"data":{"password":"password456","token":"abc"}}`}
+			},
+			// base64("password456"), base64("abc")
+			contains: []string{"password: cGFzc3dvcmQ0NTY=", "token: YWJj", "username: YWRtaW4="},
+		},
+		{
+			name: "json patch",
+			args: func(file string) []string {
+				return []string{"patch", file, "--type", "json", "--patch", `[{"op":"remove","path":"/data/username"}]`}
+			},
+			contains: []string{"password: cGFzc3dvcmQxMjM="},
+			absent:   []string{"username"},
+		},
+		{
+			name: "missing patch",
+			args: func(file string) []string {
+				return []string{"patch", file}
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing file argument",
+			args: func(file string) []string {
+				return []string{"patch", "--patch", "{}"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "patch and patch file",
+			args: func(file string) []string {
+				return []string{"patch", file, "--patch", "{}", "--patch-file", file}
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid patch type",
+			args: func(file string) []string {
+				return []string{"patch", file, "--type", "nope", "--patch", "{}"}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "secret.yaml")
+			if err := os.WriteFile(testFile, []byte(original), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			err := run(tt.args(testFile))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			content, err := os.ReadFile(testFile)
+			if err != nil {
+				t.Fatalf("Failed to read test file: %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("patched file missing %q:\n%s", want, content)
+				}
+			}
+			for _, unwanted := range tt.absent {
+				if strings.Contains(string(content), unwanted) {
+					t.Errorf("patched file should not contain %q:\n%s", unwanted, content)
+				}
+			}
+		})
+	}
+}
+
+func TestRunPatchStdoutAndNonSecret(t *testing.T) {
+	tmpDir := t.TempDir()
+	configMap := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  key: value
+`
+	testFile := filepath.Join(tmpDir, "cm.yaml")
+	if err := os.WriteFile(testFile, []byte(configMap), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	stdout = &buf
+	defer func() { stdout = os.Stdout }()
+
+	if err := run([]string{"patch", testFile, "--patch", `{"data":{"key":"other"}}`, "-o", "-"}); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "key: other") {
+		t.Errorf("run() should patch non-Secrets verbatim, got:\n%s", buf.String())
+	}
+
+	content, _ := os.ReadFile(testFile)
+	if string(content) != configMap {
+		t.Error("run() with -o - should not modify the input file")
+	}
+}
+
+func TestRunPatchBinary(t *testing.T) {
+	// keystore is binary, so it stays base64 while patching
+	original := `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-secret
+data:
+  keystore: AAECA/7/
+  password: cGFzc3dvcmQxMjM=
+`
+
+	tests := []struct {
+		name     string
+		patch    string
+		contains []string
+	}{
+		{"other key", `{"data":{"password":"password456"}}`, []string{"keystore: AAECA/7/", "password: cGFzc3dvcmQ0NTY="}},
+		{"binary key replaced", `{"data":{"keystore":"abc"}}`, []string{"keystore: YWJj", "password: cGFzc3dvcmQxMjM="}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "secret.yaml")
+			if err := os.WriteFile(file, []byte(original), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := run([]string{"patch", file, "--patch", tt.patch}); err != nil {
+				t.Fatalf("run() failed: %v", err)
+			}
+			content, _ := os.ReadFile(file)
+			for _, want := range tt.contains {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("patched file should contain %q:\n%s", want, content)
+				}
+			}
+			if strings.Contains(string(content), "swk:binary") {
+				t.Errorf("patched file kept the binary marker:\n%s", content)
+			}
+		})
+	}
+}
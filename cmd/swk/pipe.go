@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/safefile"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+)
+
+// runDecode handles `swk decode FILE|- [-o OUT] [--json-path PATH]`,
+// writing the Secret with its values decoded, as swk shows it in the
+// editor, to stdout or OUT:
+//
+//	kubectl get secret app -o yaml | swk decode - | less
+func runDecode(args []string) error {
+	return pipe("decode", args, (*secret.Document).DecodeText, writePlaintext)
+}
+
+// runEncode handles `swk encode FILE|- [-o OUT] [--json-path PATH]`, the
+// reverse of swk decode, for Secrets generated from templates with
+// plaintext values. OUT is written like any manifest swk saves, so locked
+// keys, owners, and constraints are checked.
+func runEncode(args []string) error {
+	return pipe("encode", args, (*secret.Document).Encode, func(output string, result []byte) error {
+		return writeResult("", output, result)
+	})
+}
+
+// pipe reads a Secret from a file, or stdin for "-", transforms it, and
+// writes the result to stdout or the -o file. Nothing is written on errors,
+// so a broken pipeline doesn't pass half a manifest on.
+func pipe(name string, args []string, transform func(*secret.Document) error, write func(output string, result []byte) error) error {
+	fs := flag.NewFlagSet("swk "+name, flag.ContinueOnError)
+	jsonPath := fs.String("json-path", "", "Path of a Secret embedded in a larger document (e.g. .spec.template)")
+	output := fs.String("o", "-", "Write the result to this file (- for stdout)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *output == "" {
+		return fmt.Errorf("usage: swk %s FILE|- [-o OUT] [--json-path PATH]", name)
+	}
+
+	source := positional[0]
+	var data []byte
+	if source == "-" {
+		source = "stdin"
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source, err)
+	}
+
+	doc, err := parseSecret(data, *jsonPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", source, err)
+	}
+	if !doc.IsSecret() {
+		return fmt.Errorf("%s doesn't hold a Secret", source)
+	}
+	if err := transform(doc); err != nil {
+		return fmt.Errorf("failed to %s secret: %w", name, err)
+	}
+	result, err := doc.Bytes()
+	if err != nil {
+		return fmt.Errorf("failed to %s secret: %w", name, err)
+	}
+	return write(*output, result)
+}
+
+// writePlaintext writes decoded values to stdout for "-", or to a file
+// only the user can read unless --mode says otherwise
+func writePlaintext(output string, result []byte) error {
+	result, err := canonicalOutput(result, false)
+	if err != nil {
+		return err
+	}
+	if output == "-" {
+		_, err := stdout.Write(result)
+		return err
+	}
+	path, err := resolveTarget(output)
+	if err != nil {
+		return err
+	}
+	if fileMode != 0 {
+		err = writeFile(path, result)
+	} else {
+		err = safefile.WriteFile(path, result, 0600)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
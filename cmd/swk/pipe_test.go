@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunDecodeEncode(t *testing.T) {
+	out := captureStdout(t)
+	withStdin(t, setTestSecret, false)
+	if err := run([]string{"decode", "-"}); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	decoded := out.String()
+	if !strings.Contains(decoded, "username: admin") {
+		t.Fatalf("decode output = %q, want the decoded username", decoded)
+	}
+
+	out.Reset()
+	withStdin(t, decoded, false)
+	if err := run([]string{"encode", "-"}); err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	if out.String() != setTestSecret {
+		t.Errorf("encode output = %q, want %q", out, setTestSecret)
+	}
+
+	out.Reset()
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"decode", file}); err != nil || out.String() != decoded {
+		t.Errorf("decode of a file = %q, %v; want the same as from stdin", out, err)
+	}
+}
+
+func TestRunEncodeOutput(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain.yaml")
+	if err := os.WriteFile(plain, []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: test-secret\ndata:\n  username: admin\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "secret.yaml")
+	if err := run([]string{"encode", plain, "-o", out}); err != nil {
+		t.Fatalf("encode -o failed: %v", err)
+	}
+	if data, err := os.ReadFile(out); err != nil || string(data) != setTestSecret {
+		t.Errorf("encoded file = %q, %v; want %q", data, err, setTestSecret)
+	}
+
+	decoded := filepath.Join(dir, "decoded.yaml")
+	if err := run([]string{"decode", out, "-o", decoded}); err != nil {
+		t.Fatalf("decode -o failed: %v", err)
+	}
+	info, err := os.Stat(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("decoded file mode = %o, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestRunDecodeErrors(t *testing.T) {
+	captureStdout(t)
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+		want  string
+	}{
+		{"no argument", []string{"decode"}, "", "usage: swk decode"},
+		{"not a Secret", []string{"decode", "-"}, "apiVersion: v1\nkind: ConfigMap\n", "stdin doesn't hold a Secret"},
+		{"invalid base64", []string{"decode", "-"}, "apiVersion: v1\nkind: Secret\ndata:\n  a: '!!'\n", "failed to decode secret"},
+		{"missing file", []string{"encode", "missing.yaml"}, "", "failed to read missing.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withStdin(t, tt.input, false)
+			err := run(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("run(%q) error = %v, want %q", tt.args, err, tt.want)
+			}
+		})
+	}
+}
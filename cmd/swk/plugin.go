@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// pluginName is the binary name that makes swk a kubectl plugin, run as
+// kubectl swk
+const pluginName = "kubectl-swk"
+
+// asPlugin is set when swk runs as kubectl swk. swk edit then takes the
+// name of a Secret in the cluster, as kubectl edit does.
+var asPlugin bool
+
+// globalArgs are the global flags run was given, passed on to swk when
+// kubectl edit runs it as the editor
+var globalArgs []string
+
+// isPlugin reports whether swk was started as the kubectl plugin
+func isPlugin(argv0 string) bool {
+	return strings.TrimSuffix(filepath.Base(argv0), ".exe") == pluginName
+}
+
+// hoistClusterFlags moves kubectl flags given before the command, as in
+// kubectl swk -n prod edit db, to after it, where the command parses them
+func hoistClusterFlags(args []string) []string {
+	var hoisted []string
+	for len(args) > 0 {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		if !strings.HasPrefix(args[0], "-") || (name != "n" && name != "namespace" && name != "context" && name != "kubeconfig") {
+			break
+		}
+		n := 1
+		if !hasValue && len(args) > 1 {
+			n = 2
+		}
+		hoisted, args = append(hoisted, args[:n]...), args[n:]
+	}
+	if len(hoisted) == 0 || len(args) == 0 {
+		return append(args, hoisted...)
+	}
+	return append(append([]string{args[0]}, hoisted...), args[1:]...)
+}
+
+// secretInCluster returns the name of the Secret arg names in the cluster:
+// secret/NAME, or, as a plugin, a bare name that isn't a local file
+func secretInCluster(arg string) (string, bool) {
+	if strings.HasPrefix(arg, "secret/") || strings.HasPrefix(arg, "secrets/") {
+		return secretArg(arg)
+	}
+	if !asPlugin || strings.ContainsAny(arg, `/\.`) {
+		return "", false
+	}
+	if _, err := os.Lstat(arg); err == nil {
+		return "", false
+	}
+	return arg, true
+}
+
+// secretArg accepts secret/NAME, secrets/NAME, or NAME
+func secretArg(arg string) (string, bool) {
+	name := strings.TrimPrefix(strings.TrimPrefix(arg, "secret/"), "secrets/")
+	return name, name != "" && !strings.Contains(name, "/")
+}
+
+// editClusterSecret runs kubectl edit on a Secret, with this swk and its
+// flags as the editor, so kubectl fetches the Secret and applies the edit
+func editClusterSecret(name string, opts options) error {
+	if opts.apply {
+		return fmt.Errorf("--apply can't be used with secret/NAME: kubectl edit applies the change")
+	}
+	if opts.jsonPath != "" {
+		return fmt.Errorf("--json-path can't be used with secret/NAME")
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the swk binary: %w", err)
+	}
+
+	editorArgs := append([]string{self}, globalArgs...)
+	if opts.editor != "" {
+		editorArgs = append(editorArgs, "--editor", opts.editor)
+	}
+	if opts.validate != "" {
+		editorArgs = append(editorArgs, "--validate", opts.validate)
+	}
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{{"--strict", opts.strict}, {"--compare", opts.compare}, {"--no-header", opts.noHeader}} {
+		if f.set {
+			editorArgs = append(editorArgs, f.name)
+		}
+	}
+	quoted := make([]string, len(editorArgs))
+	for i, arg := range editorArgs {
+		quoted[i] = shellQuote(arg)
+	}
+
+	args := []string{"edit", "secret/" + name}
+	if opts.cluster.Namespace != "" {
+		args = append(args, "--namespace", opts.cluster.Namespace)
+	}
+	if opts.cluster.Context != "" {
+		args = append(args, "--context", opts.cluster.Context)
+	}
+	if opts.cluster.Kubeconfig != "" {
+		args = append(args, "--kubeconfig", opts.cluster.Kubeconfig)
+	}
+	cmd := exec.Command("kubectl", args...)
+	cmd.Env = append(os.Environ(), "KUBE_EDITOR="+strings.Join(quoted, " "))
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("kubectl edit failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHoistClusterFlags(t *testing.T) {
+	tests := []struct {
+		args, want []string
+	}{
+		{[]string{"-n", "prod", "edit", "db"}, []string{"edit", "-n", "prod", "db"}},
+		{[]string{"--context=kind", "--kubeconfig", "k", "ls"}, []string{"ls", "--context=kind", "--kubeconfig", "k"}},
+		{[]string{"edit", "db", "-n", "prod"}, []string{"edit", "db", "-n", "prod"}},
+		{[]string{"-n", "prod"}, []string{"-n", "prod"}},
+	}
+	for _, tt := range tests {
+		if got := hoistClusterFlags(tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("hoistClusterFlags(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSecretInCluster(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile("local", nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		arg    string
+		plugin bool
+		want   string
+		ok     bool
+	}{
+		{"secret/db", false, "db", true},
+		{"secrets/db", true, "db", true},
+		{"db", false, "", false},
+		{"db", true, "db", true},
+		{"local", true, "", false},
+		{"db.yaml", true, "", false},
+		{"/tmp/swk-1.yaml", true, "", false},
+	}
+	t.Cleanup(func() { asPlugin = false })
+	for _, tt := range tests {
+		asPlugin = tt.plugin
+		if got, ok := secretInCluster(tt.arg); got != tt.want || ok != tt.ok {
+			t.Errorf("secretInCluster(%q) as plugin %v = %q, %v, want %q, %v", tt.arg, tt.plugin, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestEditClusterSecret(t *testing.T) {
+	dir := t.TempDir()
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(dir, "args") + "\necho \"$KUBE_EDITOR\" > " + filepath.Join(dir, "editor") + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "kubectl"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	asPlugin = true
+	t.Cleanup(func() { asPlugin = false; profileName = "" })
+
+	if err := run([]string{"--profile", "prod", "-n", "prod", "edit", "db", "--context", "kind", "-e", "nano", "--strict"}); err != nil {
+		t.Fatalf("kubectl swk edit error = %v", err)
+	}
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	if got := strings.TrimSpace(string(args)); got != "edit secret/db --namespace prod --context kind" {
+		t.Errorf("kubectl args = %q", got)
+	}
+	editor, _ := os.ReadFile(filepath.Join(dir, "editor"))
+	if got := string(editor); !strings.HasSuffix(got, "'--profile' 'prod' '--editor' 'nano' '--strict'\n") {
+		t.Errorf("KUBE_EDITOR = %q", got)
+	}
+
+	if err := run([]string{"edit", "secret/db", "--apply"}); err == nil {
+		t.Error("edit secret/db --apply succeeded, want an error")
+	}
+	asPlugin = false
+	if err := run([]string{"-n", "prod", filepath.Join(dir, "args")}); err == nil {
+		t.Error("-n with a local file succeeded, want an error")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/safefile"
+)
+
+// profileName selects a config profile instead of matching namespaces. Set
+// by --profile.
+var profileName string
+
+// assumeYes answers the confirmation a profile asks for. Set by --yes.
+var assumeYes bool
+
+// activeProfile returns the config profile for a Secret in namespace, or
+// nil if none applies
+func activeProfile(namespace string) (string, *config.Profile, error) {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return "", nil, err
+	}
+	return cfg.Profile(profileName, namespace)
+}
+
+// manifestNamespace returns the namespace of the Secret in data, or ""
+func manifestNamespace(data []byte) string {
+	doc, err := parseSecret(data, "")
+	if err != nil || !doc.IsSecret() {
+		return ""
+	}
+	return doc.Metadata().Namespace
+}
+
+// applyProfile asks for confirmation and backs up path before data
+// replaces it, if the active profile says so
+func applyProfile(path string, data []byte) error {
+	name, p, err := activeProfile(manifestNamespace(data))
+	if err != nil || p == nil {
+		return err
+	}
+
+	if p.Confirm && !assumeYes {
+		if !isTerminal() {
+			return fmt.Errorf(i18n.T("profile %s asks for confirmation; pass --yes to write %s"), name, path)
+		}
+		if err := confirmDiff(path, data); err != nil {
+			return err
+		}
+		ok, err := ask(bufio.NewReader(stdin), stderr, fmt.Sprintf(i18n.T("Profile %s: write %s?"), name, path))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf(i18n.T("%s not written"), path)
+		}
+	}
+
+	if p.Backup {
+		existing, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		if err := safefile.WriteFileMode(path+".bak", existing, 0600); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	return nil
+}
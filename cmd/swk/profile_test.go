@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+)
+
+func TestRunProfile(t *testing.T) {
+	t.Cleanup(func() { profileName, assumeYes = "", false })
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	cfg := "profiles:\n  prod:\n    namespaces: [prod]\n    confirm: true\n    backup: true\n    mask: true\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvPath, cfgPath)
+
+	dir := t.TempDir()
+	prod, dev := filepath.Join(dir, "prod.yaml"), filepath.Join(dir, "dev.yaml")
+	if err := os.WriteFile(prod, []byte(strings.Replace(setTestSecret, "name: test-secret", "name: db\n  namespace: prod", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dev, []byte(setTestSecret), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	withStdin(t, "", false)
+	if err := run([]string{"set", dev, "password", "x"}); err != nil {
+		t.Errorf("set outside any profile failed: %v", err)
+	}
+	err := run([]string{"set", prod, "password", "x"})
+	if err == nil || !strings.Contains(err.Error(), "profile prod asks for confirmation") {
+		t.Errorf("set in prod error = %v, want a confirmation error", err)
+	}
+	err = run([]string{"--profile", "prod", "set", dev, "password", "y"})
+	if err == nil || !strings.Contains(err.Error(), "profile prod") {
+		t.Errorf("set with --profile prod error = %v, want a confirmation error", err)
+	}
+
+	if err := run([]string{"--yes", "set", prod, "password", "x"}); err != nil {
+		t.Fatalf("set with --yes failed: %v", err)
+	}
+	backup, err := os.ReadFile(prod + ".bak")
+	if err != nil || strings.Contains(string(backup), "password") {
+		t.Errorf("backup = %q, %v, want the file before the change", backup, err)
+	}
+
+	captureStdout(t)
+	if err := run([]string{"export", "--show-values", prod}); err == nil {
+		t.Error("export --show-values of a masked profile succeeded")
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import "github.com/davidschrooten/secret-wrapper-k8s/internal/progress"
+
+// newProgress reports progress on stderr: a bar on a terminal that allows
+// redrawing, periodic lines otherwise or in plain mode. It returns nil, which reports nothing, if
+// disabled.
+func newProgress(label string, total int, disabled bool) *progress.Reporter {
+	if disabled {
+		return nil
+	}
+	return progress.New(stderr, label, total, stderrTerminal().Control)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+)
+
+// ask puts a yes/no question and reads answers until it gets one
+func ask(in *bufio.Reader, out io.Writer, question string) (bool, error) {
+	for {
+		_, _ = fmt.Fprintf(out, "%s %s ", question, i18n.T("(yes/no)"))
+
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			return false, fmt.Errorf(i18n.T("no answer: %w"), err)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes", strings.ToLower(i18n.T("yes")):
+			return true, nil
+		case "n", "no", strings.ToLower(i18n.T("no")):
+			return false, nil
+		}
+		_, _ = fmt.Fprintln(out, i18n.T("Please answer yes or no."))
+	}
+}
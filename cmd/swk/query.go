@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/query"
+)
+
+// runQuery handles `swk query FILE EXPR`
+func runQuery(args []string) error {
+	fs := flag.NewFlagSet("swk query", flag.ContinueOnError)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return fmt.Errorf("usage: swk query FILE EXPR (e.g. '$.data.password | @base64d')")
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	result, err := query.Evaluate(data, positional[1])
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	out, err := query.Format(result)
+	if err != nil {
+		return fmt.Errorf("failed to format result: %w", err)
+	}
+
+	_, err = stdout.Write(out)
+	return err
+}
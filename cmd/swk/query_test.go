@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunQuery(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "secret.yaml")
+	content := `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-secret
+data:
+  password: cGFzc3dvcmQxMjM=
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{"decoded value", []string{"query", testFile, "$.data.password | @base64d"}, "password123\n", false},
+		{"missing expression", []string{"query", testFile}, "", true},
+		{"missing file", []string{"query", "/nonexistent.yaml", ".data"}, "", true},
+		{"bad expression", []string{"query", testFile, ".data | @nope"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			stdout = &buf
+			defer func() { stdout = os.Stdout }()
+
+			err := run(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("run() output = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/recovery"
+)
+
+// editorFailed handles an editor that exited with an error, so the edits in
+// tmpPath aren't lost. It reports whether to launch the editor again; if
+// not, the returned error explains what happened to the edits. Without a
+// terminal to ask on, the edits are saved for recovery.
+func editorFailed(tmpPath, target, jsonPath string, cause error) (bool, error) {
+	failed := fmt.Errorf(i18n.T("editor failed: %w"), cause)
+	if !isTerminal() {
+		return false, saveRecovery(tmpPath, target, jsonPath, failed)
+	}
+
+	in := bufio.NewReader(stdin)
+	for {
+		fmt.Fprintf(stderr, i18n.T("The editor failed (%v).\n[r]etry, [s]ave your edits for recovery, or [a]bort? "), cause)
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			return false, saveRecovery(tmpPath, target, jsonPath, failed)
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "r", "retry":
+			return true, nil
+		case "s", "save":
+			return false, saveRecovery(tmpPath, target, jsonPath, failed)
+		case "a", "abort":
+			return false, failed
+		}
+	}
+}
+
+// saveRecovery stores the edited temp file encrypted and returns cause with
+// a hint how to restore it
+func saveRecovery(tmpPath, target, jsonPath string, cause error) error {
+	data, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return fmt.Errorf("%w; failed to read your edits: %v", cause, err)
+	}
+	store, err := recovery.DefaultStore()
+	if err != nil {
+		return fmt.Errorf("%w; failed to save your edits: %v", cause, err)
+	}
+	e, err := store.Save(recovery.Entry{Source: target, JSONPath: jsonPath}, data)
+	if err != nil {
+		return fmt.Errorf("%w; failed to save your edits: %v", cause, err)
+	}
+	return fmt.Errorf(i18n.T("%w; your edits were saved, restore them with: swk recover %s"), cause, e.ID)
+}
+
+// runRecover handles `swk recover [ID] [-o OUT] [--discard]`, listing saved
+// edits or writing one back to its file
+func runRecover(args []string) error {
+	fs := flag.NewFlagSet("swk recover", flag.ContinueOnError)
+	output := fs.String("o", "", "Write the result here instead of back to the original file (- for stdout)")
+	discard := fs.Bool("discard", false, "Delete the saved edits without restoring them")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return fmt.Errorf("usage: swk recover [ID] [-o OUT] [--discard]")
+	}
+
+	store, err := recovery.DefaultStore()
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		entries, err := store.List()
+		if err != nil {
+			return err
+		}
+		return paged(func() error {
+			for _, e := range entries {
+				fmt.Fprintf(stdout, "%s  %s  %s\n", e.ID, e.Saved.Format(time.DateTime), e.Source)
+			}
+			return nil
+		})
+	}
+
+	id := positional[0]
+	e, data, err := store.Load(id)
+	if err != nil {
+		return err
+	}
+	if *discard {
+		return store.Delete(id)
+	}
+
+	// The saved edits are decoded, so encode them like a finished edit
+	doc, err := parseSecret(stripHeader(data), e.JSONPath)
+	if err != nil {
+		return fmt.Errorf("failed to encode secret: %w", err)
+	}
+	if err := doc.Encode(); err != nil {
+		return fmt.Errorf("failed to encode secret: %w", err)
+	}
+	encoded, err := doc.Bytes()
+	if err != nil {
+		return fmt.Errorf("failed to encode secret: %w", err)
+	}
+
+	if err := writeResult(e.Source, *output, encoded); err != nil {
+		return err
+	}
+	if *output == "-" {
+		return nil
+	}
+	fmt.Fprintf(stderr, "Restored %s\n", e.Source)
+	return store.Delete(id)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestEditorCrashRecovery(t *testing.T) {
+	stderr = io.Discard
+	t.Cleanup(func() { stderr = os.Stderr })
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "secret.yaml")
+	if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	// Edits the file, then crashes
+	editorScript := filepath.Join(dir, "editor.sh")
+	script := "#!/bin/sh\nsed 's/admin/root/' \"$1\" > \"$1.tmp\" && mv \"$1.tmp\" \"$1\"\nexit 1\n"
+	if err := os.WriteFile(editorScript, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// On a terminal the user can abort, which discards the edits
+	withStdin(t, "x\na\n", true)
+	err := run([]string{"-editor", editorScript, file})
+	if err == nil || strings.Contains(err.Error(), "swk recover") {
+		t.Errorf("abort error = %v, want an editor error without recovery", err)
+	}
+
+	// Without a terminal the edits are saved
+	withStdin(t, "", false)
+	err = run([]string{"-editor", editorScript, file})
+	if err == nil {
+		t.Fatal("run() succeeded with a crashing editor")
+	}
+	match := regexp.MustCompile(`swk recover (\S+)`).FindStringSubmatch(err.Error())
+	if match == nil {
+		t.Fatalf("error = %v, want a recovery hint", err)
+	}
+	if got := queryFile(t, file, ".data.username | @base64d"); got != "admin" {
+		t.Errorf("username = %q, want the file untouched", got)
+	}
+
+	out := captureStdout(t)
+	if err := run([]string{"recover"}); err != nil {
+		t.Fatalf("recover failed: %v", err)
+	}
+	if !strings.Contains(out.String(), match[1]+"  ") || !strings.Contains(out.String(), file) {
+		t.Errorf("recover listing = %q, want %s", out, match[1])
+	}
+
+	if err := run([]string{"recover", match[1]}); err != nil {
+		t.Fatalf("recover %s failed: %v", match[1], err)
+	}
+	if got := queryFile(t, file, ".data.username | @base64d"); got != "root" {
+		t.Errorf("username = %q, want the recovered edit", got)
+	}
+	if err := run([]string{"recover", match[1]}); err == nil {
+		t.Error("recovering the same entry twice succeeded")
+	}
+}
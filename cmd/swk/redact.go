@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/redact"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/safefile"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+	"gopkg.in/yaml.v3"
+)
+
+// redactedAnnotations are annotations that hold values too: kubectl's copy
+// of the last applied manifest, and approval tokens
+var redactedAnnotations = map[string]bool{
+	lastAppliedAnnotation:     true,
+	secret.ApprovalAnnotation: true,
+}
+
+// runRedact handles `swk redact FILE|- [-o OUT] [--decoded]`, replacing
+// every value of the Secrets in FILE with a placeholder such as
+// <pem-cert 2048B>, so that a manifest can go into a bug report or design
+// doc and still look like the real thing. Secrets are found anywhere in
+// the input, e.g. in the items of a List or a stream of documents. Data
+// values stay valid base64 unless --decoded shows the placeholders as
+// they are.
+func runRedact(args []string) error {
+	fs := flag.NewFlagSet("swk redact", flag.ContinueOnError)
+	output := fs.String("o", "-", "Write the result to this file (- for stdout)")
+	decoded := fs.Bool("decoded", false, "Write data placeholders in plain text, as swk decode shows values")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *output == "" {
+		return fmt.Errorf("usage: swk redact FILE|- [-o OUT] [--decoded]")
+	}
+
+	source := positional[0]
+	var data []byte
+	if source == "-" {
+		source = "stdin"
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source, err)
+	}
+
+	result, found, err := redactManifests(data, *decoded)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", source, err)
+	}
+	if found == 0 {
+		return fmt.Errorf("%s doesn't hold a Secret", source)
+	}
+	if result, err = canonicalOutput(result, !*decoded); err != nil {
+		return err
+	}
+
+	if *output == "-" {
+		_, err := stdout.Write(result)
+		return err
+	}
+	path, err := resolveTarget(*output)
+	if err != nil {
+		return err
+	}
+	if err := safefile.WriteFile(path, result, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// redactManifests redacts the Secrets in a stream of documents, returning
+// the result and how many Secrets it redacted
+func redactManifests(data []byte, decoded bool) ([]byte, int, error) {
+	return rewriteSecrets(data, func(node *yaml.Node, v secret.Version) error {
+		redactSecret(node, v, decoded)
+		return nil
+	})
+}
+
+// rewriteSecrets calls rewrite on every Secret in a stream of documents,
+// wherever it is, e.g. in the items of a List, and returns the result and
+// how many Secrets it found
+func rewriteSecrets(data []byte, rewrite func(node *yaml.Node, v secret.Version) error) ([]byte, int, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	found := 0
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		n, err := eachSecret(&doc, rewrite)
+		if err != nil {
+			return nil, 0, err
+		}
+		found += n
+		if err := encoder.Encode(&doc); err != nil {
+			return nil, 0, fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	return buf.Bytes(), found, nil
+}
+
+// eachSecret calls fn on the Secrets in and below node, returning how many
+// it found
+func eachSecret(node *yaml.Node, fn func(node *yaml.Node, v secret.Version) error) (int, error) {
+	if node.Kind == yaml.MappingNode {
+		if v, ok := secret.ResolveVersion(scalarField(node, "apiVersion"), scalarField(node, "kind")); ok {
+			return 1, fn(node, v)
+		}
+	}
+	found := 0
+	for _, child := range node.Content {
+		n, err := eachSecret(child, fn)
+		if err != nil {
+			return 0, err
+		}
+		found += n
+	}
+	return found, nil
+}
+
+// redactSecret replaces the values of a Secret with placeholders
+func redactSecret(node *yaml.Node, v secret.Version, decoded bool) {
+	if data := findNode(node, v.DataField); data != nil && data.Kind == yaml.MappingNode {
+		for i := 1; i < len(data.Content); i += 2 {
+			value := data.Content[i]
+			raw, err := base64.StdEncoding.DecodeString(value.Value)
+			if err != nil {
+				// Not valid base64; its kind is still worth telling
+				raw = []byte(value.Value)
+			}
+			placeholder := redact.Placeholder(raw)
+			if !decoded {
+				placeholder = base64.StdEncoding.EncodeToString([]byte(placeholder))
+			}
+			value.Value, value.Style = placeholder, 0
+		}
+	}
+	if stringData := findNode(node, v.StringDataField); stringData != nil && stringData.Kind == yaml.MappingNode {
+		for i := 1; i < len(stringData.Content); i += 2 {
+			value := stringData.Content[i]
+			value.Value, value.Style = redact.Placeholder([]byte(value.Value)), 0
+		}
+	}
+	annotations := findNode(findNode(node, "metadata"), "annotations")
+	if annotations != nil && annotations.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(annotations.Content); i += 2 {
+			if redactedAnnotations[annotations.Content[i].Value] {
+				value := annotations.Content[i+1]
+				value.Value, value.Style = redact.Placeholder([]byte(value.Value)), 0
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunRedact(t *testing.T) {
+	const input = `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: '{"data":{"password":"aHVudGVyMg=="}}'
+data:
+  password: aHVudGVyMg==
+stringData:
+  url: postgres://app:hunter2@db/app
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  level: debug
+`
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"encoded", nil, []string{"password: PHN0cmluZyA3Qj4=", "url: <postgres-url 29B>", "last-applied-configuration: <json 36B>", "level: debug"}},
+		{"decoded", []string{"--decoded"}, []string{"password: <string 7B>", "url: <postgres-url 29B>"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "secret.yaml")
+			if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+				t.Fatal(err)
+			}
+			out := captureStdout(t)
+			if err := run(append([]string{"redact", path}, tt.args...)); err != nil {
+				t.Fatalf("run() failed: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output lacks %q:\n%s", want, out)
+				}
+			}
+			if strings.Contains(out.String(), "hunter2") || strings.Contains(out.String(), "aHVudGVyMg") {
+				t.Errorf("output leaks the value:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestRunRedactNoSecret(t *testing.T) {
+	withStdin(t, "apiVersion: v1\nkind: ConfigMap\n", false)
+	if err := run([]string{"redact", "-"}); err == nil || !strings.Contains(err.Error(), "doesn't hold a Secret") {
+		t.Errorf("run() error = %v, want no Secret", err)
+	}
+}
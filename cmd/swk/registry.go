@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/output"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/registry"
+	"golang.org/x/term"
+)
+
+// readPassword reads a password from the terminal without echoing it
+var readPassword = func() (string, error) {
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(stderr)
+	return string(password), err
+}
+
+// registryClient is the HTTP client for registry pings, replaceable in tests
+var registryClient = &http.Client{}
+
+// runRegistry handles `swk registry login|test`
+func runRegistry(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: swk registry login|test FILE [flags]")
+	}
+
+	switch args[0] {
+	case "login":
+		return runRegistryLogin(args[1:])
+	case "test":
+		return runRegistryTest(args[1:])
+	default:
+		return fmt.Errorf("unknown registry command %q (available: login, test)", args[0])
+	}
+}
+
+// runRegistryLogin handles `swk registry login FILE --registry HOST`,
+// merging credentials from a Docker credential helper or a prompt into a
+// dockerconfigjson Secret
+func runRegistryLogin(args []string) error {
+	fs := flag.NewFlagSet("swk registry login", flag.ContinueOnError)
+	host := fs.String("registry", "", "Registry host, e.g. r.example.com")
+	username := fs.String("username", "", "Username (prompted for if needed)")
+	passwordStdin := fs.Bool("password-stdin", false, "Read the password from stdin")
+	helper := fs.String("helper", "", "Docker credential helper, e.g. ecr-login (default from ~/.docker/config.json)")
+	secretName := fs.String("name", "regcred", "Secret name when FILE does not exist yet")
+	output := fs.String("o", "", "Write the result here instead of back to FILE (- for stdout)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *host == "" {
+		return fmt.Errorf("usage: swk registry login FILE --registry HOST [--username USER] [--password-stdin | --helper NAME]")
+	}
+	filePath := positional[0]
+
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = newSecret(*secretName, "", "kubernetes.io/dockerconfigjson", []string{registry.Key},
+			map[string]string{registry.Key: "{}"}, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	cfg, err := dockerConfig(data)
+	if err != nil {
+		return err
+	}
+
+	user, password, err := registryCredentials(*host, *username, *passwordStdin, *helper)
+	if err != nil {
+		return err
+	}
+	cfg.Set(*host, user, password)
+
+	config, err := cfg.Bytes()
+	if err != nil {
+		return fmt.Errorf("failed to render docker config: %w", err)
+	}
+	result, err := setValues(data, registry.Key, map[string]string{registry.Key: string(config)}, nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stderr, "Stored credentials for %s\n", *host)
+	return writeResult(filePath, *output, result)
+}
+
+// registryCredentials gets credentials from stdin, a credential helper, or
+// an interactive prompt, in that order
+func registryCredentials(host, username string, passwordStdin bool, helper string) (string, string, error) {
+	if passwordStdin {
+		if username == "" {
+			return "", "", fmt.Errorf("--password-stdin requires --username")
+		}
+		password, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read password: %w", err)
+		}
+		return username, strings.TrimSuffix(string(password), "\n"), nil
+	}
+
+	if helper == "" && username == "" {
+		helper = registry.HelperFor(host)
+	}
+	if helper != "" {
+		return registry.FromHelper(context.Background(), helper, host)
+	}
+
+	if !isTerminal() {
+		return "", "", fmt.Errorf("no credentials for %s: use --password-stdin or --helper", host)
+	}
+
+	if username == "" {
+		fmt.Fprint(stderr, i18n.T("Username: "))
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", "", fmt.Errorf("failed to read username: %w", err)
+		}
+		username = strings.TrimSpace(line)
+	}
+	fmt.Fprint(stderr, i18n.T("Password: "))
+	password, err := readPassword()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return username, password, nil
+}
+
+// runRegistryTest handles `swk registry test FILE [--registry HOST]`, trying
+// the stored credentials against each registry
+func runRegistryTest(args []string) error {
+	fs := flag.NewFlagSet("swk registry test", flag.ContinueOnError)
+	host := fs.String("registry", "", "Only test this registry")
+	plainHTTP := fs.Bool("plain-http", false, "Use HTTP instead of HTTPS")
+	var allowRealms stringList
+	fs.Var(&allowRealms, "allow-realm", "Token service host the credentials may be sent to besides the registry's own domain (repeatable)")
+	timeout := fs.Duration("timeout", 10*time.Second, "Timeout per registry")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: swk registry test FILE [--registry HOST] [--allow-realm HOST]...")
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	cfg, err := dockerConfig(data)
+	if err != nil {
+		return err
+	}
+
+	hosts := cfg.Registries()
+	if *host != "" {
+		if _, ok := cfg.Auths[*host]; !ok {
+			return fmt.Errorf("no credentials for %s", *host)
+		}
+		hosts = []string{*host}
+	}
+	if len(hosts) == 0 {
+		return fmt.Errorf("no registries configured")
+	}
+
+	colors := stdoutTerminal()
+	failed := 0
+	for _, h := range hosts {
+		user, password, err := cfg.Auths[h].Credentials()
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+			err = registry.Ping(ctx, registryClient, h, user, password, registry.PingOptions{PlainHTTP: *plainHTTP, AllowRealms: allowRealms})
+			cancel()
+		}
+
+		if err != nil {
+			failed++
+			fmt.Fprintf(stdout, "%s: %s (%v)\n", h, colors.Paint("FAILED", output.Red), err)
+		} else {
+			fmt.Fprintf(stdout, "%s: %s\n", h, colors.Paint("OK", output.Green))
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d registries failed", failed, len(hosts))
+	}
+	return nil
+}
+
+// dockerConfig reads the docker config held by a dockerconfigjson Secret
+func dockerConfig(manifest []byte) (*registry.Config, error) {
+	values, err := secretValues(manifest)
+	if err != nil {
+		return nil, err
+	}
+	return registry.Parse([]byte(values[registry.Key]))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunRegistryLogin(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "regcred.yaml")
+	captureStdout(t)
+
+	// A new Secret is created when FILE does not exist
+	withStdin(t, "s3cret\n", false)
+	if err := run([]string{"registry", "login", file, "--registry", "r.example.com", "--username", "bot", "--password-stdin"}); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if got := queryFile(t, file, ".type"); got != "kubernetes.io/dockerconfigjson" {
+		t.Errorf("type = %q, want kubernetes.io/dockerconfigjson", got)
+	}
+
+	// A credential helper's entry is merged in next to the existing one
+	helper := filepath.Join(tmpDir, "docker-credential-fake")
+	script := "#!/bin/sh\nread host\necho \"{\\\"ServerURL\\\":\\\"$host\\\",\\\"Username\\\":\\\"helper\\\",\\\"Secret\\\":\\\"token\\\"}\"\n"
+	if err := os.WriteFile(helper, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to create helper: %v", err)
+	}
+	t.Setenv("PATH", tmpDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if err := run([]string{"registry", "login", file, "--registry", "other.example.com", "--helper", "fake"}); err != nil {
+		t.Fatalf("login with helper: %v", err)
+	}
+
+	config := queryFile(t, file, `.data[".dockerconfigjson"] | @base64d`)
+	for _, want := range []string{`"r.example.com"`, `"username":"bot"`, `"other.example.com"`, `"password":"token"`} {
+		if !strings.Contains(config, want) {
+			t.Errorf("config %s missing %s", config, want)
+		}
+	}
+
+	withStdin(t, "", false)
+	if err := run([]string{"registry", "login", file, "--registry", "third.example.com", "--username", "x"}); err == nil {
+		t.Error("expected an error without a password source on a non-terminal")
+	}
+}
+
+func TestRunRegistryTest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "bot" || pass != "s3cret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	file := filepath.Join(t.TempDir(), "regcred.yaml")
+	out := captureStdout(t)
+	for _, creds := range [][2]string{{host, "s3cret"}, {"127.0.0.1:1", "s3cret"}} {
+		withStdin(t, creds[1], false)
+		if err := run([]string{"registry", "login", file, "--registry", creds[0], "--username", "bot", "--password-stdin"}); err != nil {
+			t.Fatalf("login: %v", err)
+		}
+	}
+
+	if err := run([]string{"registry", "test", file, "--registry", host, "--plain-http"}); err != nil {
+		t.Errorf("test: %v", err)
+	}
+	if !strings.Contains(out.String(), host+": OK") {
+		t.Errorf("output = %q, want %s: OK", out.String(), host)
+	}
+
+	out.Reset()
+	if err := run([]string{"registry", "test", file, "--plain-http"}); err == nil {
+		t.Error("expected an error for the unreachable registry")
+	}
+	if !strings.Contains(out.String(), "127.0.0.1:1: FAILED") {
+		t.Errorf("output = %q, want a failure line", out.String())
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/tempdir"
+)
+
+// remoteFile is a file on another host, reached with ssh
+type remoteFile struct {
+	Host string // [USER@]HOST, as ssh takes it
+	Path string
+}
+
+func (r remoteFile) String() string {
+	return r.Host + ":" + r.Path
+}
+
+// shellPath returns the path quoted for the remote shell. Like scp, a
+// relative path or one starting with ~/ is in the home directory.
+func (r remoteFile) shellPath() string {
+	return shellQuote(strings.TrimPrefix(r.Path, "~/"))
+}
+
+// parseRemote recognizes [USER@]HOST:PATH, as scp writes it. A file that
+// exists locally, or a slash before the colon, makes it a local path.
+func parseRemote(arg string) (remoteFile, bool) {
+	host, p, ok := strings.Cut(arg, ":")
+	if !ok || len(host) < 2 || p == "" || strings.ContainsAny(host, `/\`) || strings.HasPrefix(host, "-") {
+		return remoteFile{}, false
+	}
+	if _, err := os.Lstat(arg); err == nil {
+		return remoteFile{}, false
+	}
+	return remoteFile{Host: host, Path: p}, true
+}
+
+// editRemote fetches a file over SSH, edits a local copy as usual, and
+// writes the result back, replacing the remote file atomically. If the
+// remote file changed in the meantime, or can't be written, the local copy
+// is kept and its path reported.
+func editRemote(remote remoteFile, opts options) error {
+	original, err := sshRun(remote.Host, "cat -- "+remote.shellPath(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", remote, err)
+	}
+
+	dir, err := tempdir.Dir("swk-remote-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	keep := false
+	defer func() {
+		if !keep {
+			_ = tempdir.Remove(dir)
+		}
+	}()
+	local := filepath.Join(dir, path.Base(remote.Path))
+	if err := os.WriteFile(local, original, 0600); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	opts.filePath = local
+	if err := editFile(opts); err != nil {
+		return err
+	}
+	edited, err := os.ReadFile(local)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if bytes.Equal(edited, original) {
+		fmt.Fprintf(stderr, "No changes; %s left as it was\n", remote)
+		return nil
+	}
+
+	current, err := sshRun(remote.Host, "cat -- "+remote.shellPath(), nil)
+	if err != nil {
+		keep = true
+		return fmt.Errorf("failed to check %s before writing it: %w; your version is in %s", remote, err, local)
+	}
+	if !bytes.Equal(current, original) {
+		keep = true
+		return fmt.Errorf("%s changed while you were editing it; your version is in %s", remote, local)
+	}
+	if _, err := sshRun(remote.Host, remoteWriteScript(remote.shellPath()), edited); err != nil {
+		keep = true
+		return fmt.Errorf("failed to write %s: %w; your version is in %s", remote, err, local)
+	}
+	fmt.Fprintf(stderr, "Wrote %s\n", remote)
+	return nil
+}
+
+// remoteWriteScript returns a POSIX shell script that replaces the file at
+// quotedPath with its standard input: written to a temp file next to it,
+// given the file's permissions or --mode, then renamed over it. Symlinks
+// are refused unless --follow-symlinks is set.
+func remoteWriteScript(quotedPath string) string {
+	var script strings.Builder
+	fmt.Fprintf(&script, "f=%s\n", quotedPath)
+	if followSymlinks {
+		script.WriteString(`[ -L "$f" ] && { f=$(readlink -f -- "$f") || exit 1; }` + "\n")
+	} else {
+		script.WriteString(`[ -L "$f" ] && { echo "$f is a symlink; pass --follow-symlinks to write the file it points to" >&2; exit 1; }` + "\n")
+	}
+	script.WriteString(`t=$(mktemp "$f.swk.XXXXXX") || exit 1` + "\n")
+	script.WriteString(`cat > "$t" || { rm -f "$t"; exit 1; }` + "\n")
+	if fileMode != 0 {
+		fmt.Fprintf(&script, "chmod %o \"$t\" || { rm -f \"$t\"; exit 1; }\n", fileMode)
+	} else {
+		script.WriteString(`m=$(stat -c %a "$f" 2>/dev/null || stat -f %Lp "$f") && chmod "$m" "$t"` + "\n")
+	}
+	// Flushed before the rename, so a crash can't leave a truncated file;
+	// sync FILE needs coreutils 8.24, otherwise everything is synced
+	script.WriteString(`sync "$t" 2>/dev/null || sync` + "\n")
+	script.WriteString(`mv -f "$t" "$f" || { rm -f "$t"; exit 1; }` + "\n")
+	return script.String()
+}
+
+// sshRun runs a shell command on host with ssh, which takes its keys,
+// ports, and jump hosts from ~/.ssh/config as usual
+func sshRun(host, command string, input []byte) ([]byte, error) {
+	cmd := exec.Command("ssh", "--", host, command)
+	cmd.Stdin = bytes.NewReader(input)
+	var errOut bytes.Buffer
+	cmd.Stderr = &errOut
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ssh failed: %w: %s", err, strings.TrimSpace(errOut.String()))
+	}
+	return out, nil
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRemote(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "a:b.yaml")
+	if err := os.WriteFile(local, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		arg    string
+		want   remoteFile
+		remote bool
+	}{
+		{"admin@jump:/etc/app/secret.yaml", remoteFile{"admin@jump", "/etc/app/secret.yaml"}, true},
+		{"vm1:secret.yaml", remoteFile{"vm1", "secret.yaml"}, true},
+		{"/tmp/secret.yaml", remoteFile{}, false},
+		{"./dir:x/secret.yaml", remoteFile{}, false},
+		{`C:\secret.yaml`, remoteFile{}, false},
+		{"-oProxyCommand=x:y", remoteFile{}, false},
+		{"host:", remoteFile{}, false},
+		{local, remoteFile{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRemote(tt.arg)
+		if ok != tt.remote || got != tt.want {
+			t.Errorf("parseRemote(%q) = %v, %v, want %v, %v", tt.arg, got, ok, tt.want, tt.remote)
+		}
+	}
+}
+
+// fakeSSH puts an ssh on PATH that runs the command locally
+func fakeSSH(t *testing.T) {
+	t.Helper()
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "ssh"), []byte("#!/bin/sh\nexec sh -c \"$3\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestEditRemote(t *testing.T) {
+	fakeSSH(t)
+	stderr = &strings.Builder{}
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "secret.yaml")
+	manifest := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app\ndata:\n  password: b2xk\n"
+	if err := os.WriteFile(file, []byte(manifest), 0640); err != nil {
+		t.Fatal(err)
+	}
+	editor := filepath.Join(dir, "editor.sh")
+	script := "#!/bin/sh\nsed -i.bak 's/password: old/password: new/' \"$1\" && rm -f \"$1.bak\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"edit", "-e", editor, "me@vm:" + file}); err != nil {
+		t.Fatalf("swk edit error = %v", err)
+	}
+	data, _ := os.ReadFile(file)
+	if !strings.Contains(string(data), "password: bmV3") {
+		t.Errorf("remote file = %s, want the new password encoded", data)
+	}
+	if info, _ := os.Stat(file); info.Mode().Perm() != 0640 {
+		t.Errorf("remote file mode = %v, want 0640 kept", info.Mode().Perm())
+	}
+	leftovers, _ := filepath.Glob(file + ".swk.*")
+	if len(leftovers) > 0 {
+		t.Errorf("temp files left next to the remote file: %v", leftovers)
+	}
+
+	// A remote file that changes while it is edited isn't overwritten
+	racing := filepath.Join(dir, "racing.sh")
+	script = "#!/bin/sh\necho '  user: eve' >> " + file + "\nsed -i.bak 's/password: new/password: newer/' \"$1\" && rm -f \"$1.bak\"\n"
+	if err := os.WriteFile(racing, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	err := run([]string{"edit", "-e", racing, "vm:" + file})
+	if err == nil || !strings.Contains(err.Error(), "changed while you were editing") {
+		t.Fatalf("swk edit error = %v, want a conflict", err)
+	}
+	if data, _ := os.ReadFile(file); strings.Contains(string(data), "bmV3ZXI=") {
+		t.Errorf("remote file overwritten despite the conflict:\n%s", data)
+	}
+}
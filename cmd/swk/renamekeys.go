@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/usage"
+)
+
+// renameRule renames the keys it matches, reporting whether it did
+type renameRule func(key string) (string, bool)
+
+// renameRules is a repeatable flag adding rules of one kind to a shared
+// list, so rules of different kinds keep the order they were given in
+type renameRules struct {
+	rules *[]renameRule
+	parse func(string) (renameRule, error)
+}
+
+func (r renameRules) String() string { return "" }
+
+func (r renameRules) Set(value string) error {
+	rule, err := r.parse(value)
+	if err != nil {
+		return err
+	}
+	*r.rules = append(*r.rules, rule)
+	return nil
+}
+
+// runRenameKeys handles `swk rename-keys FILE [--prefix OLD=NEW]...
+// [--suffix OLD=NEW]... [--regex s/RE/REPLACEMENT/]... [--update-refs]`,
+// renaming many keys in one pass, e.g. for a change in env var naming. The
+// first rule that matches a key renames it. With --update-refs, the key
+// references in the other manifests in FILE's directory are rewritten too.
+func runRenameKeys(args []string) error {
+	const usageLine = "usage: swk rename-keys FILE [--prefix OLD=NEW]... [--suffix OLD=NEW]... [--regex s/RE/REPLACEMENT/]... [--update-refs] [--dry-run]"
+	var rules []renameRule
+	fs := flag.NewFlagSet("swk rename-keys", flag.ContinueOnError)
+	fs.Var(renameRules{&rules, prefixRule}, "prefix", "Replace the prefix OLD of keys with NEW, as OLD=NEW (repeatable)")
+	fs.Var(renameRules{&rules, suffixRule}, "suffix", "Replace the suffix OLD of keys with NEW, as OLD=NEW (repeatable)")
+	fs.Var(renameRules{&rules, regexRule}, "regex", "Rename keys matching RE, as s/RE/REPLACEMENT/ with $1 for groups (repeatable)")
+	updateRefs := fs.Bool("update-refs", false, "Rewrite secretKeyRef and volume item keys in the other manifests in FILE's directory")
+	dryRun := fs.Bool("dry-run", false, "Only show what would be renamed")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || len(rules) == 0 {
+		return fmt.Errorf(usageLine)
+	}
+	filePath := positional[0]
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	doc, err := parseSecret(data, "")
+	if err != nil {
+		return err
+	}
+	if !doc.IsSecret() {
+		return fmt.Errorf("%s is not a Secret", filePath)
+	}
+	renamed, err := doc.RenameKeys(func(key string) string {
+		for _, rule := range rules {
+			if n, ok := rule(key); ok {
+				return n
+			}
+		}
+		return key
+	}, generatorAnnotation)
+	if err != nil {
+		return err
+	}
+	if len(renamed) == 0 {
+		return fmt.Errorf("no keys in %s match the rules", filePath)
+	}
+
+	names := map[string]string{}
+	for _, r := range renamed {
+		names[r.Old] = r.New
+		fmt.Fprintf(stderr, "%s: %s -> %s\n", filePath, r.Old, r.New)
+	}
+	if !*dryRun {
+		result, err := doc.Bytes()
+		if err != nil {
+			return err
+		}
+		if err := writeResult(filePath, "", result); err != nil {
+			return err
+		}
+	}
+
+	if *updateRefs {
+		meta := doc.Metadata()
+		return renameRefs(filePath, meta.Namespace, meta.Name, names, *dryRun)
+	}
+	return nil
+}
+
+// renameRefs rewrites the references to renamed keys of a Secret in the
+// YAML manifests next to its file
+func renameRefs(secretPath, namespace, name string, names map[string]string, dryRun bool) error {
+	var siblings []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(filepath.Dir(secretPath), pattern))
+		if err != nil {
+			return err
+		}
+		siblings = append(siblings, matches...)
+	}
+
+	for _, sibling := range siblings {
+		if same, err := sameFile(sibling, secretPath); err != nil || same {
+			continue
+		}
+		data, err := os.ReadFile(sibling)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		result, refs, err := usage.RenameKeys(data, namespace, name, names)
+		if err != nil {
+			return fmt.Errorf("%s: %w", sibling, err)
+		}
+		for _, ref := range refs {
+			fmt.Fprintf(stderr, "%s: %s\n", sibling, ref)
+		}
+		if dryRun || bytes.Equal(result, data) {
+			continue
+		}
+		if err := writeResult(sibling, "", result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameFile reports whether two paths name the same file
+func sameFile(a, b string) (bool, error) {
+	ia, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	ib, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(ia, ib), nil
+}
+
+func prefixRule(value string) (renameRule, error) {
+	old, new, ok := strings.Cut(value, "=")
+	if !ok || old == "" {
+		return nil, fmt.Errorf("want OLD=NEW, got %q", value)
+	}
+	return func(key string) (string, bool) {
+		rest, ok := strings.CutPrefix(key, old)
+		return new + rest, ok
+	}, nil
+}
+
+func suffixRule(value string) (renameRule, error) {
+	old, new, ok := strings.Cut(value, "=")
+	if !ok || old == "" {
+		return nil, fmt.Errorf("want OLD=NEW, got %q", value)
+	}
+	return func(key string) (string, bool) {
+		rest, ok := strings.CutSuffix(key, old)
+		return rest + new, ok
+	}, nil
+}
+
+// regexRule parses s/RE/REPLACEMENT/, where any character can take the
+// place of /, as in sed
+func regexRule(value string) (renameRule, error) {
+	if len(value) < 4 || value[0] != 's' {
+		return nil, fmt.Errorf("want s/RE/REPLACEMENT/, got %q", value)
+	}
+	parts := strings.Split(value[2:], value[1:2])
+	if len(parts) != 3 || parts[0] == "" || parts[2] != "" {
+		return nil, fmt.Errorf("want s/RE/REPLACEMENT/, got %q", value)
+	}
+	re, err := regexp.Compile(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	return func(key string) (string, bool) {
+		if !re.MatchString(key) {
+			return key, false
+		}
+		return re.ReplaceAllString(key, parts[1]), true
+	}, nil
+}
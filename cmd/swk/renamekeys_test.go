@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunRenameKeys(t *testing.T) {
+	var errOut strings.Builder
+	stderr = &errOut
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "secret.yaml")
+	secretYAML := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app\ndata:\n  DB_HOST: ZGI=\n  DB_PASS: cHc=\n  API_TOKEN: dG9r\n"
+	deploy := filepath.Join(dir, "deploy.yaml")
+	deployYAML := "kind: Deployment\nmetadata:\n  name: web\nspec:\n  template:\n    spec:\n      containers:\n        - name: app\n          env:\n            - name: DB_HOST\n              valueFrom:\n                secretKeyRef:\n                  name: app\n                  key: DB_HOST\n"
+	for path, content := range map[string]string{file: secretYAML, deploy: deployYAML} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := run([]string{"rename-keys", file, "--prefix", "DB_=DATABASE_", "--regex", "s/^(.*)_TOKEN$/${1}_KEY/", "--dry-run", "--update-refs"}); err != nil {
+		t.Fatalf("rename-keys --dry-run failed: %v", err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != secretYAML {
+		t.Errorf("--dry-run changed the Secret:\n%s", data)
+	}
+	if !strings.Contains(errOut.String(), "API_TOKEN -> API_KEY") || !strings.Contains(errOut.String(), "Deployment/web: env DB_HOST: DB_HOST -> DATABASE_HOST") {
+		t.Errorf("--dry-run output = %q, want the renames and references", errOut.String())
+	}
+
+	if err := run([]string{"rename-keys", file, "--suffix", "_PASS=_PASSWORD", "--prefix", "DB_=DATABASE_", "--update-refs"}); err != nil {
+		t.Fatalf("rename-keys failed: %v", err)
+	}
+	if data, _ := os.ReadFile(file); !strings.Contains(string(data), "  DATABASE_HOST: ZGI=\n  DB_PASSWORD: cHc=\n  API_TOKEN: dG9r\n") {
+		t.Errorf("Secret after rename-keys:\n%s\nwant the first matching rule to rename each key", data)
+	}
+	if data, _ := os.ReadFile(deploy); !strings.Contains(string(data), "key: DATABASE_HOST\n") {
+		t.Errorf("deploy.yaml not updated:\n%s", data)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"rename-keys", file}, "usage: swk rename-keys"},
+		{[]string{"rename-keys", file, "--prefix", "NOPE_=X_"}, "no keys in"},
+		{[]string{"rename-keys", file, "--prefix", "DB_"}, "want OLD=NEW"},
+		{[]string{"rename-keys", file, "--regex", "s/(/x/"}, "invalid regex"},
+	}
+	for _, tt := range tests {
+		if err := run(tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("run(%q) error = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/check"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/output"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/report"
+)
+
+// reportSinks returns the sinks given with --report or, without any, those
+// in the config file
+func reportSinks(specs []string) ([]report.Sink, error) {
+	if len(specs) == 0 {
+		cfg, err := config.LoadDefault()
+		if err != nil {
+			return nil, err
+		}
+		return cfg.ReportSinks()
+	}
+	sinks := make([]report.Sink, 0, len(specs))
+	for _, spec := range specs {
+		s, err := report.ParseSink(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --report %q: %w", spec, err)
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// reportsToStdout reports whether a sink writes to stdout, in place of the
+// usual listing
+func reportsToStdout(sinks []report.Sink) bool {
+	for _, s := range sinks {
+		if s.Dest == "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeReport sends a report to every sink, trying them all even if some
+// fail
+func writeReport(r report.Report, sinks []report.Sink) error {
+	var errs []error
+	for _, s := range sinks {
+		if err := s.Write(context.Background(), r, stdout); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// printFindings lists findings on stdout, errors in red and warnings in
+// yellow, then sends them to the report sinks
+func printFindings(command string, findings []check.Finding, sinks []report.Sink) error {
+	if !reportsToStdout(sinks) {
+		colors := stdoutTerminal()
+		_ = paged(func() error {
+			for _, f := range findings {
+				style := output.Yellow
+				if f.Severity == check.Error {
+					style = output.Red
+				}
+				fmt.Fprintln(stdout, colors.Paint(f.String(), style))
+			}
+			return nil
+		})
+	}
+	return writeReport(report.Report{Command: command, Findings: findings}, sinks)
+}
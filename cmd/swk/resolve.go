@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/editor"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/merge"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/tempdir"
+)
+
+// columnWidth is the content width of each side of the resolver view
+const columnWidth = 36
+
+// resolver walks the user through merge conflicts one key at a time,
+// showing both sides next to each other, or one after the other in plain mode
+type resolver struct {
+	in     *bufio.Reader
+	out    io.Writer
+	editor string
+	plain  bool
+}
+
+func (r *resolver) resolve(result *merge.Result) error {
+	conflicts := result.Unresolved()
+	for i, c := range conflicts {
+		_, _ = fmt.Fprintf(r.out, i18n.T("\nConflict %d/%d: key %q\n"), i+1, len(conflicts), c.Key)
+		choose := r.choose
+		if r.plain {
+			r.renderPlain(c)
+			choose = r.choosePlain
+		} else {
+			r.render(c)
+		}
+
+		value, err := choose(c)
+		if err != nil {
+			return err
+		}
+		result.Resolve(c.Key, value)
+	}
+	return nil
+}
+
+// choose prompts until the user picks a side, edits a value, or aborts
+func (r *resolver) choose(c merge.Conflict) (*string, error) {
+	for {
+		_, _ = fmt.Fprint(r.out, i18n.T("Keep [l]eft (ours), [r]ight (theirs), [e]dit, or [a]bort? "))
+
+		line, err := r.in.ReadString('\n')
+		if err != nil && line == "" {
+			return nil, fmt.Errorf(i18n.T("merge aborted: %w"), err)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "l", "left", "o", "ours":
+			return c.Ours, nil
+		case "r", "right", "t", "theirs":
+			return c.Theirs, nil
+		case "e", "edit":
+			return r.edit(c)
+		case "a", "abort", "q", "quit":
+			return nil, fmt.Errorf("%s", i18n.T("merge aborted"))
+		}
+	}
+}
+
+// choosePlain asks one yes/no question per option instead of a menu
+func (r *resolver) choosePlain(c merge.Conflict) (*string, error) {
+	options := []struct {
+		question string
+		pick     func() (*string, error)
+	}{
+		{i18n.T("Keep our value?"), func() (*string, error) { return c.Ours, nil }},
+		{i18n.T("Keep their value?"), func() (*string, error) { return c.Theirs, nil }},
+		{i18n.T("Edit the value?"), func() (*string, error) { return r.edit(c) }},
+	}
+	for _, option := range options {
+		ok, err := ask(r.in, r.out, option.question)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return option.pick()
+		}
+	}
+	return nil, fmt.Errorf("%s", i18n.T("merge aborted"))
+}
+
+// edit opens the editor on our value (or theirs, if we deleted the key)
+func (r *resolver) edit(c merge.Conflict) (*string, error) {
+	initial := c.Ours
+	if initial == nil {
+		initial = c.Theirs
+	}
+
+	tmpPath, cleanup, err := tempdir.WriteFile("swk-merge-*", "value.txt", []byte(*initial))
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	if err := editor.LaunchEditor(editor.SelectEditor(r.editor), tmpPath); err != nil {
+		return nil, fmt.Errorf("editor failed: %w", err)
+	}
+
+	edited, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read edited value: %w", err)
+	}
+
+	// Most editors add a final newline the original value didn't have
+	value := string(edited)
+	if !strings.HasSuffix(*initial, "\n") {
+		value = strings.TrimSuffix(value, "\n")
+	}
+	return &value, nil
+}
+
+// render draws both sides of a conflict in two boxed columns
+func (r *resolver) render(c merge.Conflict) {
+	left, right := sideLines(c.Ours), sideLines(c.Theirs)
+	rows := len(left)
+	if len(right) > rows {
+		rows = len(right)
+	}
+
+	bar := strings.Repeat("─", columnWidth+2)
+	_, _ = fmt.Fprintf(r.out, "┌%s┬%s┐\n", title(" ours ", columnWidth+2), title(" theirs ", columnWidth+2))
+	for i := 0; i < rows; i++ {
+		_, _ = fmt.Fprintf(r.out, "│ %s │ %s │\n", pad(at(left, i)), pad(at(right, i)))
+	}
+	_, _ = fmt.Fprintf(r.out, "└%s┴%s┘\n", bar, bar)
+}
+
+// renderPlain lists both sides of a conflict one after the other
+func (r *resolver) renderPlain(c merge.Conflict) {
+	for _, side := range []struct {
+		label string
+		value *string
+	}{{i18n.T("Our value:"), c.Ours}, {i18n.T("Their value:"), c.Theirs}} {
+		_, _ = fmt.Fprintln(r.out, side.label)
+		if side.value == nil {
+			_, _ = fmt.Fprintln(r.out, "  "+i18n.T("(deleted)"))
+			continue
+		}
+		for _, line := range strings.Split(*side.value, "\n") {
+			_, _ = fmt.Fprintln(r.out, "  "+line)
+		}
+	}
+}
+
+// sideLines splits a value into display lines wrapped to the column width
+func sideLines(value *string) []string {
+	if value == nil {
+		return []string{"(deleted)"}
+	}
+
+	var lines []string
+	for _, line := range strings.Split(*value, "\n") {
+		for utf8.RuneCountInString(line) > columnWidth {
+			runes := []rune(line)
+			lines = append(lines, string(runes[:columnWidth]))
+			line = string(runes[columnWidth:])
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func title(label string, width int) string {
+	return "─" + label + strings.Repeat("─", width-1-utf8.RuneCountInString(label))
+}
+
+func pad(s string) string {
+	return s + strings.Repeat(" ", columnWidth-utf8.RuneCountInString(s))
+}
+
+func at(lines []string, i int) string {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return ""
+}
@@ -0,0 +1,163 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/audit"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/patch"
+	"gopkg.in/yaml.v3"
+)
+
+// Conflict policies for Secrets that already exist in the cluster
+const (
+	conflictSkip      = "skip"
+	conflictOverwrite = "overwrite"
+	conflictRename    = "rename"
+)
+
+func init() {
+	commands["restore"] = runRestore
+}
+
+// runRestore handles `swk restore DIR [--on-conflict skip|overwrite|rename]
+// [--rename-suffix SUFFIX]`, applying a directory of Secret manifests, such
+// as one written by swk mirror, to a cluster
+func runRestore(args []string) error {
+	var clusterOpts cluster.Options
+	fs := flag.NewFlagSet("swk restore", flag.ContinueOnError)
+	clusterOpts.BindFlags(fs)
+	onConflict := fs.String("on-conflict", conflictSkip, "What to do with Secrets that already exist: skip, overwrite, or rename")
+	suffix := fs.String("rename-suffix", "-restored", "Suffix for the names of renamed Secrets; implies --on-conflict rename")
+	dryRun := fs.Bool("dry-run", false, "Only report what would be restored")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: swk restore DIR [--context CONTEXT] [-n NAMESPACE] [--on-conflict skip|overwrite|rename] [--rename-suffix SUFFIX] [--dry-run]")
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	policy := *onConflict
+	if set["rename-suffix"] && !set["on-conflict"] {
+		policy = conflictRename
+	}
+	switch policy {
+	case conflictSkip, conflictOverwrite, conflictRename:
+	default:
+		return fmt.Errorf("unsupported conflict policy %q (supported: skip, overwrite, rename)", policy)
+	}
+	if policy == conflictRename && *suffix == "" {
+		return fmt.Errorf("--rename-suffix must not be empty")
+	}
+
+	// -n restores everything into one namespace instead of each manifest's own
+	namespace := clusterOpts.Namespace
+	clusterOpts.Namespace = ""
+	client := cluster.New(clusterOpts)
+
+	files, err := audit.Files(positional)
+	if err != nil {
+		return err
+	}
+	restored := 0
+	for _, file := range files {
+		ok, err := restoreFile(client, file, namespace, policy, *suffix, *dryRun)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if ok {
+			restored++
+		}
+	}
+	fmt.Fprintf(stderr, i18n.T("Restored %d of %d Secrets\n"), restored, len(files))
+	return nil
+}
+
+// restoreFile applies one manifest unless the policy says to skip it, and
+// reports whether it was applied
+func restoreFile(client *cluster.Client, file, namespace, policy, suffix string, dryRun bool) (bool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file: %w", err)
+	}
+	if sopsEncrypted(data) {
+		if data, err = sopsDecrypt(data); err != nil {
+			return false, err
+		}
+	}
+	doc, err := parseSecret(data, "")
+	if err != nil {
+		return false, err
+	}
+	if !doc.IsSecret() {
+		return false, fmt.Errorf("not a Secret")
+	}
+	meta := doc.Metadata()
+	if namespace == "" {
+		namespace = meta.Namespace
+	}
+	if namespace == "" {
+		return false, fmt.Errorf("no namespace; pass -n NAMESPACE")
+	}
+
+	ctx := context.Background()
+	target := meta.Name
+	exists, err := client.SecretExists(ctx, namespace, target)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		switch policy {
+		case conflictSkip:
+			fmt.Fprintf(stderr, i18n.T("Skipped %s/%s: it already exists\n"), namespace, target)
+			return false, nil
+		case conflictRename:
+			target += suffix
+		}
+	}
+
+	manifest, err := relocateManifest(data, namespace, target)
+	if err != nil {
+		return false, err
+	}
+
+	if !dryRun {
+		if err := client.Apply(ctx, manifest); err != nil {
+			return false, err
+		}
+	}
+	if target != meta.Name || namespace != meta.Namespace {
+		fmt.Fprintf(stderr, i18n.T("Restored %s/%s as %s/%s\n"), meta.Namespace, meta.Name, namespace, target)
+	} else {
+		fmt.Fprintf(stderr, i18n.T("Restored %s/%s\n"), namespace, target)
+	}
+	return true, nil
+}
+
+// relocateManifest sets the namespace and name of a Secret manifest and
+// drops the server-set fields that would make applying it fail or clash
+// with another Secret, such as the UID and resource version
+func relocateManifest(data []byte, namespace, name string) ([]byte, error) {
+	patchData, err := yaml.Marshal(map[string]any{"metadata": map[string]any{
+		"name":              name,
+		"namespace":         namespace,
+		"uid":               nil,
+		"resourceVersion":   nil,
+		"creationTimestamp": nil,
+		"managedFields":     nil,
+		"ownerReferences":   nil,
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build patch: %w", err)
+	}
+	return patchManifest(data, patchData, patch.TypeMerge)
+}
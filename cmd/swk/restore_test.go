@@ -0,0 +1,79 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/query"
+)
+
+func TestRunRestore(t *testing.T) {
+	bin := t.TempDir()
+	applied := filepath.Join(bin, "applied")
+	// The fake cluster already holds prod/db
+	script := "#!/bin/sh\ncase \"$*\" in\n*\"get secret db --namespace prod \"*) echo secret/db ;;\n*apply*) cat >> " + applied + "; echo --- >> " + applied + " ;;\nesac\n"
+	if err := os.WriteFile(filepath.Join(bin, "kubectl"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake kubectl: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	stderr = &strings.Builder{}
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	dir := t.TempDir()
+	for _, name := range []string{"db", "cache"} {
+		manifest := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: " + name + "\n  namespace: prod\n  resourceVersion: \"42\"\ndata:\n  password: c2VjcmV0\n"
+		if err := os.MkdirAll(filepath.Join(dir, "prod"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "prod", name+".yaml"), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string // names applied
+	}{
+		{"skip", nil, []string{"cache"}},
+		{"overwrite", []string{"--on-conflict", "overwrite"}, []string{"cache", "db"}},
+		{"rename", []string{"--rename-suffix", "-restored"}, []string{"cache", "db-restored"}},
+		{"other namespace", []string{"-n", "drill"}, []string{"cache", "db"}},
+		{"dry run", []string{"--dry-run", "--on-conflict", "overwrite"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.Remove(applied)
+			if err := run(append([]string{"restore", dir}, tt.args...)); err != nil {
+				t.Fatalf("restore failed: %v", err)
+			}
+			data, _ := os.ReadFile(applied)
+			var names []string
+			for _, doc := range strings.Split(string(data), "---\n") {
+				if doc == "" {
+					continue
+				}
+				if strings.Contains(doc, "resourceVersion") {
+					t.Errorf("applied %q with its resource version", doc)
+				}
+				node, err := query.Evaluate([]byte(doc), ".metadata.name")
+				if err != nil {
+					t.Fatalf("applied %q: %v", doc, err)
+				}
+				names = append(names, node.Value)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("applied %v, want %v", names, tt.want)
+			}
+		})
+	}
+
+	if err := run([]string{"restore", dir, "--on-conflict", "merge"}); err == nil {
+		t.Error("restore accepted an unknown conflict policy")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/generate"
+)
+
+// runRotate handles `swk rotate FILE [KEY...] [--generate SPEC]`. Keys are
+// regenerated with the spec recorded when they were generated.
+func runRotate(args []string) error {
+	fs := flag.NewFlagSet("swk rotate", flag.ContinueOnError)
+	generator := fs.String("generate", "", "Generator spec to use instead of the recorded one (single KEY only)")
+	output := fs.String("o", "", "Write the result here instead of back to FILE (- for stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: swk rotate FILE [KEY...] [--generate SPEC] [-o OUT]")
+		fs.PrintDefaults()
+		printGenerators(fs.Output())
+	}
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		return fmt.Errorf("usage: swk rotate FILE [KEY...] [--generate SPEC]")
+	}
+	filePath, keys := positional[0], positional[1:]
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	recorded := make(map[string]string)
+	for name, spec := range annotations(data) {
+		if key, ok := strings.CutPrefix(name, generatorAnnotation); ok {
+			recorded[key] = spec
+		}
+	}
+
+	if len(keys) == 0 {
+		if *generator != "" {
+			return fmt.Errorf("--generate requires a single KEY")
+		}
+		for key := range recorded {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if len(keys) == 0 {
+			return fmt.Errorf("no generated keys to rotate in %s", filePath)
+		}
+	} else if *generator != "" && len(keys) > 1 {
+		return fmt.Errorf("--generate requires a single KEY")
+	}
+
+	for _, key := range keys {
+		rawSpec := *generator
+		if rawSpec == "" {
+			if rawSpec = recorded[key]; rawSpec == "" {
+				return fmt.Errorf("key %q has no recorded generator, pass --generate", key)
+			}
+		}
+
+		spec, err := generate.ParseSpec(rawSpec)
+		if err != nil {
+			return err
+		}
+		out, err := spec.Run()
+		if err != nil {
+			return fmt.Errorf("key %q: %w", key, err)
+		}
+		if data, err = setValues(data, key, out.Keys(key), &spec); err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, "Rotated %s\n", key)
+	}
+
+	return writeResult(filePath, *output, data)
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/plan"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/safefile"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/tempdir"
+)
+
+// savedFile is a manifest as it was before a plan changed it
+type savedFile struct {
+	data    []byte
+	mode    fs.FileMode
+	existed bool
+}
+
+// runRunPlan handles `swk run-plan PLAN [--dry-run]`, running the set,
+// rotate, apply, and notify steps of a plan file in order. Steps go
+// through the same checks as the commands they stand for. If one fails,
+// the files the plan changed are restored, the Secrets it applied get
+// their earlier manifests applied again, and no notifications are sent:
+// those go out only once every other step has succeeded. With --dry-run,
+// the steps run on copies, showing the keys each would change.
+func runRunPlan(args []string) error {
+	fs := flag.NewFlagSet("swk run-plan", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Show what each step would change, without changing anything")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: swk run-plan PLAN [--dry-run]")
+	}
+	p, err := plan.Load(positional[0])
+	if err != nil {
+		return err
+	}
+	if p.Description != "" {
+		fmt.Fprintf(stderr, "Plan: %s\n", p.Description)
+	}
+	if *dryRun {
+		return dryRunPlan(p)
+	}
+	return executePlan(positional[0], p)
+}
+
+// executePlan runs a plan's steps, rolling back on the first failure
+func executePlan(planPath string, p *plan.Plan) error {
+	saved := map[string]savedFile{}
+	var applied []string
+	var notifications []plan.Step
+
+	for i, step := range p.Steps {
+		fmt.Fprintf(stderr, "Step %d/%d: %s\n", i+1, len(p.Steps), step)
+		if step.Kind() == plan.Notify {
+			notifications = append(notifications, step)
+			continue
+		}
+
+		file := step.Target()
+		if _, ok := saved[file]; !ok {
+			f, err := saveState(file)
+			if err != nil {
+				rollback(saved, applied)
+				return fmt.Errorf("step %d (%s): %w", i+1, step, err)
+			}
+			saved[file] = f
+		}
+		before, _ := os.ReadFile(file)
+
+		var err error
+		if step.Kind() == plan.Apply {
+			err = applyEdited(file, "", saved[file].data)
+			if err == nil {
+				applied = append(applied, file)
+			}
+		} else {
+			err = runFileStep(step, file)
+		}
+		if err != nil {
+			rollback(saved, applied)
+			return fmt.Errorf("step %d (%s) failed, so the plan was rolled back: %w", i+1, step, err)
+		}
+		printStepChanges(file, before)
+	}
+
+	for _, step := range notifications {
+		if err := notifyPlan(step, planPath, p); err != nil {
+			fmt.Fprintf(stderr, "Warning: notification to %s failed: %v\n", step.Notify, err)
+		}
+	}
+	return nil
+}
+
+// dryRunPlan runs a plan's file steps on copies of the files in a private
+// directory, and shows what the other steps would do
+func dryRunPlan(p *plan.Plan) error {
+	dir, err := tempdir.Dir("swk-plan-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = tempdir.Remove(dir) }()
+
+	// Nothing real is written, so there's nothing for a profile to confirm
+	defer func(yes bool) { assumeYes = yes }(assumeYes)
+	assumeYes = true
+
+	copies := map[string]string{}
+	for i, step := range p.Steps {
+		fmt.Fprintf(stderr, "Step %d/%d: %s\n", i+1, len(p.Steps), step)
+		switch step.Kind() {
+		case plan.Notify:
+			fmt.Fprintf(stderr, "  would notify %s\n", step.Notify)
+			continue
+		case plan.Apply:
+			fmt.Fprintf(stderr, "  would apply %s\n", step.Apply)
+			continue
+		}
+
+		file := step.Target()
+		copyPath, ok := copies[file]
+		if !ok {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("step %d (%s): failed to read file: %w", i+1, step, err)
+			}
+			copyPath = filepath.Join(dir, fmt.Sprintf("%d-%s", len(copies), filepath.Base(file)))
+			if err := safefile.WriteFile(copyPath, data, 0600); err != nil {
+				return fmt.Errorf("failed to write temp file: %w", err)
+			}
+			copies[file] = copyPath
+		}
+
+		before, _ := os.ReadFile(copyPath)
+		// The commands' own output would claim changes that aren't made
+		quiet := stderr
+		stderr = io.Discard
+		err := runFileStep(step, copyPath)
+		stderr = quiet
+		if err != nil {
+			return fmt.Errorf("step %d (%s) would fail: %w", i+1, step, err)
+		}
+		printStepChanges(copyPath, before)
+	}
+	fmt.Fprintln(stderr, "Dry run; nothing was changed")
+	return nil
+}
+
+// runFileStep runs a set or rotate step on file
+func runFileStep(step plan.Step, file string) error {
+	switch step.Kind() {
+	case plan.Set:
+		args := []string{file, step.Key}
+		if step.Value != nil {
+			args = append(args, *step.Value)
+		} else {
+			args = append(args, "--generate", step.Generate)
+		}
+		return runSet(args)
+	case plan.Rotate:
+		args := append([]string{file}, step.Keys...)
+		if step.Generate != "" {
+			args = append(args, "--generate", step.Generate)
+		}
+		return runRotate(args)
+	}
+	return fmt.Errorf("%s is not a file step", step.Kind())
+}
+
+// printStepChanges lists the keys a step changed in file, never values
+func printStepChanges(file string, before []byte) {
+	after, _ := os.ReadFile(file)
+	diff := keyDiff(manifestValues(before, ""), manifestValues(after, ""))
+	if len(diff) == 0 && !bytes.Equal(before, after) {
+		diff = []string{"metadata only"}
+	}
+	for _, line := range diff {
+		fmt.Fprintf(stderr, "  %s\n", line)
+	}
+}
+
+// saveState records a file before the plan first touches it
+func saveState(path string) (savedFile, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return savedFile{}, nil
+	}
+	if err != nil {
+		return savedFile{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return savedFile{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	return savedFile{data: data, mode: info.Mode().Perm(), existed: true}, nil
+}
+
+// rollback restores the files a failed plan changed, and applies the
+// earlier manifests of the Secrets it applied, latest first
+func rollback(saved map[string]savedFile, applied []string) {
+	for path, f := range saved {
+		current, err := os.ReadFile(path)
+		if f.existed && err == nil && bytes.Equal(current, f.data) {
+			continue
+		}
+		if !f.existed {
+			err = os.Remove(path)
+			if errors.Is(err, os.ErrNotExist) {
+				err = nil
+			}
+		} else {
+			err = safefile.WriteFileMode(path, f.data, f.mode)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "Warning: failed to restore %s: %v\n", path, err)
+		} else {
+			fmt.Fprintf(stderr, "Restored %s\n", path)
+		}
+	}
+
+	for i := len(applied) - 1; i >= 0; i-- {
+		path := applied[i]
+		if !saved[path].existed {
+			continue
+		}
+		if err := applyManifest(context.Background(), saved[path].data); err != nil {
+			fmt.Fprintf(stderr, "Warning: failed to apply %s as it was: %v\n", path, err)
+		} else {
+			fmt.Fprintf(stderr, "Applied %s as it was\n", path)
+		}
+	}
+}
+
+// notifyPlan posts a finished plan to a notify step's webhook
+func notifyPlan(step plan.Step, planPath string, p *plan.Plan) error {
+	var steps []string
+	for _, s := range p.Steps {
+		steps = append(steps, s.String())
+	}
+	body, err := json.Marshal(struct {
+		Event       string   `json:"event"`
+		Plan        string   `json:"plan"`
+		Description string   `json:"description,omitempty"`
+		Message     string   `json:"message,omitempty"`
+		Steps       []string `json:"steps"`
+	}{"plan", planPath, p.Description, step.Message, steps})
+	if err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(step.Notify, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
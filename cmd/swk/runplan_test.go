@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+)
+
+// planTest sets up a directory with a Secret and a plan, a fake cluster
+// recording applied manifests, and a webhook recording notifications
+func planTest(t *testing.T, steps string) (dir string, applied *[]string, notified *[]string) {
+	t.Helper()
+	dir = t.TempDir()
+	t.Setenv(config.EnvPath, filepath.Join(dir, "config.yaml"))
+	if err := os.WriteFile(filepath.Join(dir, "db.yaml"), []byte(setTestSecret), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	notified = &[]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		*notified = append(*notified, body.Message)
+	}))
+	t.Cleanup(ts.Close)
+
+	applied = &[]string{}
+	orig := applyManifest
+	applyManifest = func(_ context.Context, manifest []byte) error {
+		// ZmFpbC1hcHBseQ== is fail-apply
+		if strings.Contains(string(manifest), "ZmFpbC1hcHBseQ==") {
+			return fmt.Errorf("admission webhook denied the request")
+		}
+		*applied = append(*applied, string(manifest))
+		return nil
+	}
+	t.Cleanup(func() { applyManifest = orig })
+	stderr = &strings.Builder{}
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	plan := "description: rotate db\nsteps:\n" + strings.ReplaceAll(steps, "WEBHOOK", ts.URL)
+	if err := os.WriteFile(filepath.Join(dir, "plan.yaml"), []byte(plan), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, applied, notified
+}
+
+func TestRunRunPlan(t *testing.T) {
+	dir, applied, notified := planTest(t, `- set: db.yaml
+  key: password
+  generate: passphrase
+- set: db.yaml
+  key: username
+  value: root
+- apply: db.yaml
+- notify: WEBHOOK
+  message: db credentials rotated
+`)
+
+	if err := runRunPlan([]string{filepath.Join(dir, "plan.yaml")}); err != nil {
+		t.Fatalf("runRunPlan() failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "db.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "password:") || !strings.Contains(string(data), "username: cm9vdA==") {
+		t.Errorf("db.yaml = %s, want password generated and username set", data)
+	}
+	if len(*applied) != 1 || (*applied)[0] != string(data) {
+		t.Errorf("applied %q, want db.yaml as the plan left it", *applied)
+	}
+	if len(*notified) != 1 || (*notified)[0] != "db credentials rotated" {
+		t.Errorf("notified %q, want the message", *notified)
+	}
+	if out := stderr.(*strings.Builder).String(); !strings.Contains(out, "Step 2/4: set") || !strings.Contains(out, "  ~ username") {
+		t.Errorf("output = %q, want each step and the keys it changed", out)
+	}
+}
+
+func TestRunRunPlanRollsBack(t *testing.T) {
+	dir, applied, notified := planTest(t, `- notify: WEBHOOK
+- set: db.yaml
+  key: username
+  value: root
+- apply: db.yaml
+- set: db.yaml
+  key: username
+  value: fail-apply
+- apply: db.yaml
+`)
+
+	err := runRunPlan([]string{filepath.Join(dir, "plan.yaml")})
+	if err == nil || !strings.Contains(err.Error(), "step 5") || !strings.Contains(err.Error(), "rolled back") {
+		t.Fatalf("runRunPlan() error = %v, want step 5 to fail", err)
+	}
+	path := filepath.Join(dir, "db.yaml")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != setTestSecret {
+		t.Errorf("db.yaml = %s, want it restored", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("db.yaml mode = %v, want 0640 kept", info.Mode().Perm())
+	}
+	if len(*applied) != 2 || (*applied)[1] != setTestSecret {
+		t.Errorf("applied %q, want the earlier manifest applied again", *applied)
+	}
+	if len(*notified) != 0 {
+		t.Errorf("notified %q, want nothing for a failed plan", *notified)
+	}
+}
+
+func TestRunRunPlanDryRun(t *testing.T) {
+	dir, applied, notified := planTest(t, `- set: db.yaml
+  key: username
+  value: root
+- rotate: db.yaml
+  keys: [token]
+  generate: passphrase
+- apply: db.yaml
+- notify: WEBHOOK
+`)
+
+	if err := runRunPlan([]string{filepath.Join(dir, "plan.yaml"), "--dry-run"}); err != nil {
+		t.Fatalf("runRunPlan() failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "db.yaml")); string(data) != setTestSecret {
+		t.Errorf("db.yaml = %s, want it unchanged", data)
+	}
+	if len(*applied)+len(*notified) != 0 {
+		t.Errorf("applied %q and notified %q in a dry run", *applied, *notified)
+	}
+	out := stderr.(*strings.Builder).String()
+	for _, want := range []string{"  ~ username", "  + token", "would apply", "would notify", "nothing was changed"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output = %q, want %q", out, want)
+		}
+	}
+	if strings.Contains(out, "Rotated") {
+		t.Errorf("output = %q, want no claims of changes", out)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/check"
+)
+
+// scaffoldTypes maps short type names to Secret types and their required keys
+var scaffoldTypes = map[string]struct {
+	secretType string
+	keys       []string
+}{
+	"opaque":           {"Opaque", nil},
+	"basic-auth":       {"kubernetes.io/basic-auth", []string{"username", "password"}},
+	"tls":              {"kubernetes.io/tls", []string{"tls.crt", "tls.key"}},
+	"ssh-auth":         {"kubernetes.io/ssh-auth", []string{"ssh-privatekey"}},
+	"dockerconfigjson": {"kubernetes.io/dockerconfigjson", []string{".dockerconfigjson"}},
+}
+
+// runScaffold handles `swk scaffold --type TYPE --keys A,B`, writing a Secret
+// whose values are placeholders for a developer to fill in
+func runScaffold(args []string) error {
+	names := make([]string, 0, len(scaffoldTypes))
+	for name := range scaffoldTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fs := flag.NewFlagSet("swk scaffold", flag.ContinueOnError)
+	typeName := fs.String("type", "opaque", "Secret type: "+strings.Join(names, ", "))
+	keys := fs.String("keys", "", "Comma-separated keys to add, e.g. DB_URL,DB_PASS")
+	secretName := fs.String("name", "my-secret", "Secret name")
+	namespace := fs.String("namespace", "", "Namespace of the Secret")
+	fs.StringVar(namespace, "n", "", "Namespace of the Secret (shorthand)")
+	output := fs.String("o", "-", "Write the manifest to this file (- for stdout)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("usage: swk scaffold [--type TYPE] --keys KEY,KEY [-o FILE]")
+	}
+
+	t, ok := scaffoldTypes[*typeName]
+	if !ok {
+		return fmt.Errorf("unknown type %q (available: %s)", *typeName, strings.Join(names, ", "))
+	}
+
+	allKeys := append([]string{}, t.keys...)
+	for _, key := range strings.Split(*keys, ",") {
+		if key = strings.TrimSpace(key); key != "" && !containsKey(allKeys, key) {
+			allKeys = append(allKeys, key)
+		}
+	}
+	if len(allKeys) == 0 {
+		return fmt.Errorf("--keys is required for type %s", *typeName)
+	}
+
+	metadata := mapping("name", *secretName)
+	if *namespace != "" {
+		metadata.Content = append(metadata.Content, mapping("namespace", *namespace).Content...)
+	}
+	stringData := mapping()
+	for _, key := range allKeys {
+		stringData.Content = append(stringData.Content, mapping(key, check.Placeholder).Content...)
+	}
+
+	root := mapping("apiVersion", "v1", "kind", "Secret")
+	root.Content = append(root.Content, scalarNode("metadata"), metadata)
+	root.Content = append(root.Content, mapping("type", t.secretType).Content...)
+	key := scalarNode("stringData")
+	key.HeadComment = "Replace every " + check.Placeholder + " value; `swk check` fails while any remain"
+	root.Content = append(root.Content, key, stringData)
+
+	result, err := marshalNode(root)
+	if err != nil {
+		return err
+	}
+	return writeResult(*output, *output, result)
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunScaffoldAndCheck(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	out := captureStdout(t)
+
+	if err := run([]string{"scaffold", "--type", "basic-auth", "--keys", "DB_URL,password", "-o", file}); err != nil {
+		t.Fatalf("scaffold: %v", err)
+	}
+	if got := queryFile(t, file, ".type"); got != "kubernetes.io/basic-auth" {
+		t.Errorf("type = %q, want kubernetes.io/basic-auth", got)
+	}
+	if got := queryFile(t, file, ".stringData | keys | length"); got != "3" {
+		t.Errorf("got %s keys, want username, password, and DB_URL", got)
+	}
+
+	if err := run([]string{"check", file}); err == nil {
+		t.Error("check should fail while placeholders remain")
+	}
+	if !strings.Contains(out.String(), "[placeholder] my-secret DB_URL") {
+		t.Errorf("check output = %q", out.String())
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	filled := strings.ReplaceAll(string(content), "<CHANGEME>", "value")
+	if err := os.WriteFile(file, []byte(filled), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := run([]string{"check", file}); err != nil {
+		t.Errorf("check after filling in values: %v", err)
+	}
+}
+
+func TestRunScaffoldErrors(t *testing.T) {
+	captureStdout(t)
+	for _, args := range [][]string{
+		{"scaffold"},                   // opaque needs keys
+		{"scaffold", "--type", "nope"}, // unknown type
+		{"scaffold", "extra-argument"}, // no positional arguments
+		{"check"},                      // no files
+		{"check", "/nonexistent.yaml"}, // unreadable file
+	} {
+		if err := run(args); err == nil {
+			t.Errorf("run(%v) expected an error", args)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/schema"
+)
+
+// runSchema handles `swk schema SUBCOMMAND`
+func runSchema(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: swk schema update [flags]")
+	}
+
+	switch args[0] {
+	case "update":
+		return runSchemaUpdate(args[1:])
+	default:
+		return fmt.Errorf("unknown schema subcommand %q (available: update)", args[0])
+	}
+}
+
+// runSchemaUpdate refreshes the cached schemas from the current cluster,
+// or with --from from a schema file, such as one from an offline bundle
+func runSchemaUpdate(args []string) error {
+	var clusterOpts cluster.Options
+	fs := flag.NewFlagSet("swk schema update", flag.ContinueOnError)
+	clusterOpts.BindFlags(fs)
+	from := fs.String("from", "", "Read the schemas from a file, e.g. schemas/v1.json of an offline bundle, instead of the cluster")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var extracted []byte
+	if *from != "" {
+		data, err := os.ReadFile(*from)
+		if err != nil {
+			return fmt.Errorf("failed to read schema: %w", err)
+		}
+		extracted = data
+	} else {
+		client := cluster.New(clusterOpts)
+		openapi, err := client.Run(context.Background(), nil, "get", "--raw", "/openapi/v3/api/v1")
+		if err != nil {
+			return fmt.Errorf("failed to fetch OpenAPI schema: %w", err)
+		}
+		if extracted, err = schema.Extract(openapi); err != nil {
+			return err
+		}
+	}
+
+	path, err := schema.Save(extracted)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(stdout, "Schemas updated: %s\n", path)
+	return nil
+}
@@ -0,0 +1,265 @@
+//go:build !noserve
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/auth"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/server"
+)
+
+func init() {
+	commands["serve"] = runServe
+	capabilities = append(capabilities, capability{Name: "serve", Description: "HTTP edit API, with token, OIDC, and client certificate auth"})
+}
+
+// runServe handles `swk serve [--root DIR] [--listen ADDR] [--read-only]
+// [--audit-log FILE]`, running the edit API that editor plugins talk to
+// until interrupted. Only files under the root can be read or written.
+// On SIGINT or SIGTERM it stops accepting requests and lets saves in flight
+// finish, for up to --drain-timeout.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("swk serve", flag.ContinueOnError)
+	root := fs.String("root", ".", "Directory to serve; requested paths must resolve to files inside it")
+	listen := fs.String("listen", "127.0.0.1:7420", "Address to listen on, or unix:PATH for a socket only you can reach")
+	readOnly := fs.Bool("read-only", false, "Refuse saves and mask values; single values can only be revealed with a token")
+	auditLog := fs.String("audit-log", "", "Append requests and reveal token events to FILE as JSON lines (default: stderr)")
+	rateLimit := fs.Float64("rate-limit", 10, "Requests per second each client may make (0: unlimited)")
+	burst := fs.Int("burst", 20, "Requests a client may make at once before --rate-limit applies")
+	maxBody := fs.Int64("max-body", server.DefaultMaxBody, "Largest request body to accept, in bytes")
+	drainTimeout := fs.Duration("drain-timeout", 30*time.Second, "How long to let requests in flight finish on shutdown")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("usage: swk serve [--root DIR] [--listen ADDR | --listen unix:PATH] [--read-only] [--audit-log FILE]")
+	}
+
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+	if !cfg.Serve.Enabled() && !localListener(*listen) {
+		return fmt.Errorf("refusing to serve on %s without authentication: configure serve.auth, or listen on a loopback address or unix socket", *listen)
+	}
+	tlsConfig, err := serveTLS(&cfg.Serve)
+	if err != nil {
+		return err
+	}
+
+	realRoot, err := serveRoot(*root)
+	if err != nil {
+		return err
+	}
+	api := server.New(realRoot, resolveTarget, saveFile)
+	// Browsers can reach loopback addresses, but not unix sockets
+	api.LoopbackOnly = localListener(*listen) && !strings.HasPrefix(*listen, "unix:")
+	api.ReadOnly = *readOnly
+	api.RateLimit, api.Burst, api.MaxBody = *rateLimit, *burst, *maxBody
+	var audit io.Writer = stderr
+	if *auditLog != "" {
+		f, err := os.OpenFile(*auditLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		audit = f
+	}
+	api.Audit = auditWriter(audit)
+
+	if cfg.Serve.Enabled() {
+		if len(cfg.Serve.Routes) == 0 {
+			return fmt.Errorf("serve.auth is configured but serve.routes allows no routes, so every request would be refused")
+		}
+		authn, policy := serveAuthenticator(&cfg.Serve), auth.Policy{Teams: cfg.Teams, Rules: cfg.Serve.Routes}
+		api.Auth = func(next http.Handler) http.Handler { return auth.Middleware(next, authn, policy) }
+	}
+
+	ln, err := serveListener(*listen)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{
+		Handler:           api.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		MaxHeaderBytes:    64 << 10,
+	}
+	drained := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		drained <- drainServer(srv, api, *drainTimeout)
+	}()
+
+	if *readOnly && !cfg.Serve.Enabled() {
+		fmt.Fprintln(stderr, fmt.Sprintf(i18n.T("Warning: %s"), "reveal tokens are refused without serve.auth"))
+	}
+	if *readOnly {
+		fmt.Fprintf(stderr, "Serving the read-only edit API for %s on %s\n", realRoot, ln.Addr())
+	} else {
+		fmt.Fprintf(stderr, "Serving the edit API for %s on %s\n", realRoot, ln.Addr())
+	}
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+	// Serve returns as soon as shutdown starts; wait for the drain
+	return <-drained
+}
+
+// serveRoot returns the real, absolute path of the directory to serve
+func serveRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	if info, err := os.Stat(real); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	return real, nil
+}
+
+// drainServer stops srv accepting requests and waits up to timeout for
+// those in flight, reporting the saves that didn't finish
+func drainServer(srv *http.Server, api *server.Server, timeout time.Duration) error {
+	if n := len(api.Queue.Status()); n > 0 {
+		fmt.Fprintf(stderr, "Shutting down, waiting for %d save(s) in flight\n", n)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		for _, st := range api.Queue.Status() {
+			fmt.Fprintf(stderr, "Unfinished: %s (%s)\n", st.Target, st.Active)
+		}
+		_ = srv.Close()
+		return fmt.Errorf("requests still running after %s: %w", timeout, err)
+	}
+	return nil
+}
+
+// serveAuthenticator chains the configured authentication methods: client
+// certificates first, then static tokens, then OIDC ID tokens
+func serveAuthenticator(s *config.Serve) auth.Authenticator {
+	var chain auth.Chain
+	if s.Auth.ClientCA != "" {
+		chain = append(chain, auth.ClientCert{})
+	}
+	if len(s.Auth.Tokens) > 0 {
+		chain = append(chain, auth.Tokens(s.Auth.Tokens))
+	}
+	if o := s.Auth.OIDC; o.Issuer != "" {
+		chain = append(chain, auth.NewOIDC(o.Issuer, o.ClientID, o.UsernameClaim))
+	}
+	return chain
+}
+
+// serveTLS returns the TLS config for the server, or nil to serve plain
+// HTTP. With a client CA, verified client certificates authenticate.
+func serveTLS(s *config.Serve) (*tls.Config, error) {
+	if s.TLS.Cert == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(s.TLS.Cert, s.TLS.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	c := &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}
+	if s.Auth.ClientCA != "" {
+		pem, err := os.ReadFile(s.Auth.ClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		c.ClientCAs = x509.NewCertPool()
+		if !c.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in client CA %s", s.Auth.ClientCA)
+		}
+		c.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return c, nil
+}
+
+// localListener reports whether addr can only be reached from this host:
+// a unix socket or a loopback address
+func localListener(addr string) bool {
+	if strings.HasPrefix(addr, "unix:") {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// auditWriter returns an audit hook writing each event to w as a JSON line
+func auditWriter(w io.Writer) func(server.AuditEvent) {
+	var mu sync.Mutex
+	return func(e server.AuditEvent) {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = fmt.Fprintf(w, "%s\n", line)
+	}
+}
+
+// serveListener listens on a TCP address or, for unix:PATH, on a socket
+// created with mode 0600
+func serveListener(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, "unix:")
+	if !ok {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen: %w", err)
+		}
+		return ln, nil
+	}
+
+	// A socket left behind by an earlier run would make Listen fail
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		_ = os.Remove(path)
+	}
+	old := umask(0077)
+	ln, err := net.Listen("unix", path)
+	umask(old)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,9 @@
+//go:build !noserve && !unix
+
+package main
+
+// umask does nothing where there is no file mode creation mask, such as on
+// Windows, where sockets are created with the directory's ACL
+func umask(int) int {
+	return 0
+}
@@ -0,0 +1,120 @@
+//go:build !noserve
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/server"
+)
+
+func TestServeListener(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swk.sock")
+
+	for range 2 {
+		// The second round replaces the socket the first one left behind
+		ln, err := serveListener("unix:" + path)
+		if err != nil {
+			t.Fatalf("serveListener() failed: %v", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("socket missing: %v", err)
+		}
+		if info.Mode().Perm()&0077 != 0 {
+			t.Errorf("socket mode = %v, want it private", info.Mode().Perm())
+		}
+		if l, ok := ln.(interface{ SetUnlinkOnClose(bool) }); ok {
+			l.SetUnlinkOnClose(false)
+		}
+		_ = ln.Close()
+	}
+}
+
+func TestRunServeUsage(t *testing.T) {
+	if err := run([]string{"serve", "extra"}); err == nil {
+		t.Error("run() expected a usage error")
+	}
+}
+
+func TestLocalListener(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1:7420", true},
+		{"[::1]:7420", true},
+		{"localhost:7420", true},
+		{"unix:/run/swk.sock", true},
+		{"0.0.0.0:7420", false},
+		{":7420", false},
+		{"10.0.0.5:7420", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := localListener(tt.addr); got != tt.want {
+				t.Errorf("localListener(%q) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunServeRefusesOpenListener(t *testing.T) {
+	t.Setenv("SWK_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+	err := run([]string{"serve", "--listen", "0.0.0.0:0"})
+	if err == nil || !strings.Contains(err.Error(), "without authentication") {
+		t.Errorf("run() error = %v, want a refusal without authentication", err)
+	}
+}
+
+func TestDrainServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		wantErr bool
+	}{
+		{"finishes", time.Minute, false},
+		{"times out", 10 * time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started, release := make(chan struct{}), make(chan struct{})
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-release
+			}))
+			srv.Start()
+			defer srv.Close()
+			go func() {
+				if resp, err := http.Get(srv.URL); err == nil {
+					_ = resp.Body.Close()
+				}
+			}()
+			<-started
+
+			done := make(chan error, 1)
+			go func() { done <- drainServer(srv.Config, server.New("", nil, nil), tt.timeout) }()
+			var err error
+			if tt.wantErr {
+				err = <-done
+				close(release)
+			} else {
+				// The request finishes while the server drains
+				time.Sleep(10 * time.Millisecond)
+				close(release)
+				err = <-done
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("drainServer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
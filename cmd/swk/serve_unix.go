@@ -0,0 +1,10 @@
+//go:build !noserve && unix
+
+package main
+
+import "syscall"
+
+// umask sets the file mode creation mask, returning the previous one
+func umask(mask int) int {
+	return syscall.Umask(mask)
+}
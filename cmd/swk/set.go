@@ -0,0 +1,153 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/generate"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/patch"
+	"gopkg.in/yaml.v3"
+)
+
+// generatorAnnotation prefixes the annotation recording how a key was
+// generated, so `swk rotate` can generate it again
+const generatorAnnotation = "generator.swk.dev/"
+
+// runSet handles `swk set FILE KEY [VALUE] [--generate SPEC] [--param k=v]`
+func runSet(args []string) error {
+	fs := flag.NewFlagSet("swk set", flag.ContinueOnError)
+	generator := fs.String("generate", "", "Generate the value, e.g. passphrase or hmac:bytes=64 (see swk set --help)")
+	params := generate.Params{}
+	fs.Var(params, "param", "Generator parameter as key=value (repeatable)")
+	output := fs.String("o", "", "Write the result here instead of back to FILE (- for stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: swk set FILE KEY [VALUE] [--generate NAME [--param k=v]...] [-o OUT]")
+		fs.PrintDefaults()
+		printGenerators(fs.Output())
+	}
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 2 || len(positional) > 3 {
+		return fmt.Errorf("usage: swk set FILE KEY [VALUE] [--generate NAME [--param k=v]...]")
+	}
+	filePath, key := positional[0], positional[1]
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var values map[string]string
+	var spec *generate.Spec
+	switch {
+	case *generator != "":
+		if len(positional) == 3 {
+			return fmt.Errorf("VALUE and --generate are mutually exclusive")
+		}
+		s, err := generate.ParseSpec(*generator)
+		if err != nil {
+			return err
+		}
+		for k, v := range params {
+			s.Params[k] = v
+		}
+		out, err := s.Run()
+		if err != nil {
+			return err
+		}
+		values, spec = out.Keys(key), &s
+	case len(params) > 0:
+		return fmt.Errorf("--param requires --generate")
+	case len(positional) == 3:
+		values = map[string]string{key: positional[2]}
+	default:
+		// Reading from stdin keeps the value out of shell history
+		value, err := io.ReadAll(stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read value: %w", err)
+		}
+		values = map[string]string{key: strings.TrimSuffix(string(value), "\n")}
+	}
+
+	result, err := setValues(data, key, values, spec)
+	if err != nil {
+		return err
+	}
+	return writeResult(filePath, *output, result)
+}
+
+// setValues sets data keys in a Secret manifest and records the generator
+// spec for key, or removes a stale one when the value was set by hand
+func setValues(data []byte, key string, values map[string]string, spec *generate.Spec) ([]byte, error) {
+	dataPatch := make(map[string]any, len(values))
+	for k, v := range values {
+		dataPatch[k] = v
+	}
+	p := map[string]any{"data": dataPatch}
+
+	annotation := generatorAnnotation + key
+	if spec != nil {
+		p["metadata"] = map[string]any{"annotations": map[string]any{annotation: spec.Public().String()}}
+	} else if _, ok := annotations(data)[annotation]; ok {
+		p["metadata"] = map[string]any{"annotations": map[string]any{annotation: nil}}
+	}
+
+	patchData, err := yaml.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build patch: %w", err)
+	}
+	return patchManifest(data, patchData, patch.TypeMerge)
+}
+
+// annotations returns the metadata annotations of a manifest
+func annotations(data []byte) map[string]string {
+	var m struct {
+		Metadata struct {
+			Annotations map[string]string `yaml:"annotations"`
+		} `yaml:"metadata"`
+	}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return m.Metadata.Annotations
+}
+
+// writeResult writes a command's result to output, stdout for "-", or back
+// to the input file when output is empty
+func writeResult(filePath, output string, result []byte) error {
+	switch output {
+	case "-":
+		result, err := canonicalOutput(result, true)
+		if err != nil {
+			return err
+		}
+		_, err = stdout.Write(result)
+		return err
+	case "":
+		output = filePath
+	}
+
+	path, err := resolveTarget(output)
+	if err != nil {
+		return err
+	}
+	if err := saveFile(path, result); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// printGenerators lists the available generators for usage output
+func printGenerators(w io.Writer) {
+	fmt.Fprintln(w, "\nGenerators:")
+	for _, name := range generate.Names() {
+		g, _ := generate.Lookup(name)
+		fmt.Fprintf(w, "  %-16s %s\n", name, g.Description())
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/query"
+)
+
+const setTestSecret = `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-secret
+data:
+  username: YWRtaW4=
+`
+
+// queryFile evaluates a query expression against a file
+func queryFile(t *testing.T, path, expr string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	node, err := query.Evaluate(data, expr)
+	if err != nil {
+		return ""
+	}
+	return node.Value
+}
+
+func TestRunSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		check   func(t *testing.T, file string)
+		wantErr bool
+	}{
+		{
+			name: "literal value",
+			args: []string{"password", "hunter2"},
+			check: func(t *testing.T, file string) {
+				if got := queryFile(t, file, ".data.password | @base64d"); got != "hunter2" {
+					t.Errorf("password = %q, want hunter2", got)
+				}
+			},
+		},
+		{
+			name:  "value from stdin",
+			args:  []string{"password"},
+			stdin: "from-stdin\n",
+			check: func(t *testing.T, file string) {
+				if got := queryFile(t, file, ".data.password | @base64d"); got != "from-stdin" {
+					t.Errorf("password = %q, want from-stdin", got)
+				}
+			},
+		},
+		{
+			name: "generated value records spec",
+			args: []string{"token", "--generate", "hmac", "--param", "bytes=8"},
+			check: func(t *testing.T, file string) {
+				if got := queryFile(t, file, ".data.token | @base64d"); len(got) != 16 {
+					t.Errorf("token = %q, want 16 hex characters", got)
+				}
+				if got := queryFile(t, file, `.metadata.annotations["generator.swk.dev/token"]`); got != "hmac:bytes=8" {
+					t.Errorf("annotation = %q, want hmac:bytes=8", got)
+				}
+				if got := queryFile(t, file, ".data.username | @base64d"); got != "admin" {
+					t.Errorf("username = %q, want admin", got)
+				}
+			},
+		},
+		{
+			name: "generated companion keys",
+			args: []string{"auth", "--generate", "htpasswd:user=bob"},
+			check: func(t *testing.T, file string) {
+				if got := queryFile(t, file, ".data.auth | @base64d"); !strings.HasPrefix(got, "bob:$2") {
+					t.Errorf("auth = %q, want a bcrypt htpasswd line", got)
+				}
+				if got := queryFile(t, file, `.data["auth.password"]`); got == "" {
+					t.Error("missing generated password")
+				}
+			},
+		},
+		{name: "value and generator", args: []string{"password", "x", "--generate", "uuid"}, wantErr: true},
+		{name: "param without generator", args: []string{"password", "x", "--param", "a=b"}, wantErr: true},
+		{name: "unknown generator", args: []string{"password", "--generate", "nope"}, wantErr: true},
+		{name: "missing key", args: []string{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "secret.yaml")
+			if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			withStdin(t, tt.stdin, false)
+
+			err := run(append([]string{"set", file}, tt.args...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, file)
+			}
+		})
+	}
+}
+
+func TestRunMode(t *testing.T) {
+	t.Cleanup(func() { fileMode = 0 })
+
+	tests := []struct {
+		name     string
+		mode     []string
+		wantMode os.FileMode
+		wantErr  bool
+	}{
+		{"keeps existing mode", nil, 0644, false},
+		{"tightens", []string{"--mode", "0600"}, 0600, false},
+		{"with equals", []string{"--mode=640"}, 0640, false},
+		{"not octal", []string{"--mode", "rw"}, 0644, true},
+		{"too wide", []string{"--mode", "1777"}, 0644, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileMode = 0
+			file := filepath.Join(t.TempDir(), "secret.yaml")
+			if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			args := append(append([]string{}, tt.mode...), "set", file, "password", "x")
+			if err := run(args); (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if info, _ := os.Stat(file); info.Mode().Perm() != tt.wantMode {
+				t.Errorf("mode = %v, want %v", info.Mode().Perm(), tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestRunWatched(t *testing.T) {
+	t.Cleanup(func() { allowWatched = false })
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("watched:\n  - "+dir+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvPath, cfgPath)
+
+	file := filepath.Join(dir, "secret.yaml")
+	if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	err := run([]string{"set", file, "password", "x"})
+	if err == nil || !strings.Contains(err.Error(), "local controller watches") {
+		t.Fatalf("run() error = %v, want a watched directory error", err)
+	}
+	if got := queryFile(t, file, ".data.password | @base64d"); got == "x" {
+		t.Error("run() wrote a watched file")
+	}
+
+	if err := run([]string{"--allow-watched", "set", file, "password", "x"}); err != nil {
+		t.Fatalf("run() with --allow-watched failed: %v", err)
+	}
+	if got := queryFile(t, file, ".data.password | @base64d"); got != "x" {
+		t.Errorf("password = %q, want x", got)
+	}
+}
+
+func TestRunLock(t *testing.T) {
+	t.Cleanup(func() { unlockKeys = false })
+
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := run([]string{"lock", file, "--key", "username"}); err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+
+	err := run([]string{"set", file, "username", "root"})
+	if err == nil || !strings.Contains(err.Error(), "locked keys username") {
+		t.Fatalf("set error = %v, want a locked key error", err)
+	}
+	if err := run([]string{"set", file, "password", "x"}); err != nil {
+		t.Fatalf("set on an unlocked key failed: %v", err)
+	}
+
+	if err := run([]string{"--unlock", "set", file, "username", "root"}); err != nil {
+		t.Fatalf("set with --unlock failed: %v", err)
+	}
+	if got := queryFile(t, file, ".data.username | @base64d"); got != "root" {
+		t.Errorf("username = %q, want root", got)
+	}
+
+	unlockKeys = false
+	if err := run([]string{"lock", file, "--key", "username", "--remove"}); err != nil {
+		t.Fatalf("lock --remove: %v", err)
+	}
+	if err := run([]string{"set", file, "username", "admin"}); err != nil {
+		t.Fatalf("set after removing the lock failed: %v", err)
+	}
+}
+
+func TestRunNewAndRotate(t *testing.T) {
+	stderr = io.Discard
+	defer func() { stderr = os.Stderr }()
+
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	err := run([]string{"new", "db", "-n", "prod", "--from-literal", "user=admin",
+		"--generate", "password=passphrase:words=3", "-o", file})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	if got := queryFile(t, file, ".metadata.namespace"); got != "prod" {
+		t.Errorf("namespace = %q, want prod", got)
+	}
+	if got := queryFile(t, file, ".data.user | @base64d"); got != "admin" {
+		t.Errorf("user = %q, want admin", got)
+	}
+	before := queryFile(t, file, ".data.password | @base64d")
+	if n := len(strings.Split(before, "-")); n != 3 {
+		t.Fatalf("password = %q, want 3 words", before)
+	}
+
+	// Without keys, every key with a recorded generator is rotated
+	if err := run([]string{"rotate", file}); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	after := queryFile(t, file, ".data.password | @base64d")
+	if after == before || len(strings.Split(after, "-")) != 3 {
+		t.Errorf("password after rotate = %q (before %q)", after, before)
+	}
+
+	if err := run([]string{"rotate", file, "user"}); err == nil {
+		t.Error("expected an error rotating a key without a recorded generator")
+	}
+	if err := run([]string{"rotate", file, "user", "--generate", "uuid"}); err != nil {
+		t.Errorf("rotate with --generate: %v", err)
+	}
+	if got := queryFile(t, file, `.metadata.annotations["generator.swk.dev/user"]`); got != "uuid" {
+		t.Errorf("annotation = %q, want uuid", got)
+	}
+
+	// Setting a value by hand drops the recorded generator
+	if err := run([]string{"set", file, "user", "admin"}); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if got := queryFile(t, file, `.metadata.annotations["generator.swk.dev/user"]`); got != "" {
+		t.Errorf("annotation = %q, want it removed", got)
+	}
+}
+
+func TestRunWatchedPodMount(t *testing.T) {
+	// A Secret mounted in a pod: secret.yaml links to ..data/secret.yaml,
+	// and ..data to the current timestamped directory
+	mount := t.TempDir()
+	stamp := filepath.Join(mount, "..2026_01_02_03_04_05.123")
+	if err := os.Mkdir(stamp, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stamp, "secret.yaml"), []byte(setTestSecret), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Base(stamp), filepath.Join(mount, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(mount, "secret.yaml")
+	if err := os.Symlink(filepath.Join("..data", "secret.yaml"), file); err != nil {
+		t.Fatal(err)
+	}
+
+	// The link leads outside the working tree, which Resolve refuses first
+	err := run([]string{"set", file, "password", "x"})
+	if err == nil || !strings.Contains(err.Error(), "kubelet-projected") {
+		t.Fatalf("run() error = %v, want a projected volume error", err)
+	}
+}
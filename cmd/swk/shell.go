@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/output"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/patch"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/valuediff"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// shellHelp lists the shell commands
+const shellHelp = `Commands:
+  keys          List the keys
+  get KEY       Print a value
+  set KEY [V]   Set a value, prompting without echo if V is left out
+  delete KEY    Remove a key
+  diff [KEY]    List the keys changed since the last save, or show how
+                KEY's value changed
+  save          Write the changes to the file
+  quit          Leave, asking again if there are unsaved changes
+`
+
+// lineReader reads shell input, from a line-editing terminal or a plain
+// stream
+type lineReader interface {
+	ReadLine() (string, error)
+	ReadSecret(prompt string) (string, error)
+}
+
+// runShell handles `swk shell FILE`, a small REPL for quick changes without
+// an editor
+func runShell(args []string) error {
+	fs := flag.NewFlagSet("swk shell", flag.ContinueOnError)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: swk shell FILE")
+	}
+	filePath := positional[0]
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	values, err := secretValues(data)
+	if err != nil {
+		return fmt.Errorf("failed to decode secret: %w", err)
+	}
+
+	in, out, restore, err := shellIO()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	s := &shell{path: filePath, data: data, saved: values, values: copyValues(values), out: out, colors: stdoutTerminal()}
+	return s.run(in)
+}
+
+// shellIO returns line-editing input on a terminal, and plain line input
+// otherwise, along with a function restoring the terminal
+func shellIO() (lineReader, io.Writer, func(), error) {
+	if !isTerminal() || plain {
+		return &plainReader{in: bufio.NewReader(stdin), out: stdout}, stdout, func() {}, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to set up terminal: %w", err)
+	}
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "swk> ")
+	return terminalReader{t}, t, func() { _ = term.Restore(fd, state) }, nil
+}
+
+// terminalReader reads lines with editing and history
+type terminalReader struct{ t *term.Terminal }
+
+func (r terminalReader) ReadLine() (string, error) { return r.t.ReadLine() }
+
+func (r terminalReader) ReadSecret(prompt string) (string, error) {
+	return r.t.ReadPassword(prompt)
+}
+
+// plainReader reads lines from a stream, e.g. a script piped to swk shell
+type plainReader struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func (r *plainReader) ReadLine() (string, error) {
+	fmt.Fprint(r.out, "swk> ")
+	return r.readLine()
+}
+
+func (r *plainReader) ReadSecret(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	return r.readLine()
+}
+
+func (r *plainReader) readLine() (string, error) {
+	line, err := r.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// shell holds the values being edited and the manifest they were read from
+type shell struct {
+	path   string
+	data   []byte
+	saved  map[string]string
+	values map[string]string
+	out    io.Writer
+	colors output.Terminal
+
+	// quitting is set after quit was refused for unsaved changes
+	quitting bool
+}
+
+// run reads and runs commands until quit or end of input
+func (s *shell) run(in lineReader) error {
+	fmt.Fprintf(s.out, "Editing %s; type help for commands\n", s.path)
+	for {
+		line, err := in.ReadLine()
+		if errors.Is(err, io.EOF) {
+			if s.changed() {
+				fmt.Fprintln(s.out, "Unsaved changes discarded")
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		cmd, cmdArgs := fields[0], fields[1:]
+		if cmd != "quit" && cmd != "exit" {
+			s.quitting = false
+		}
+
+		switch cmd {
+		case "quit", "exit":
+			if !s.changed() || s.quitting {
+				return nil
+			}
+			s.quitting = true
+			fmt.Fprintln(s.out, "There are unsaved changes; save first, or quit again to discard them")
+		case "help", "?":
+			fmt.Fprint(s.out, shellHelp)
+		default:
+			if err := s.exec(in, cmd, cmdArgs); err != nil {
+				fmt.Fprintf(s.out, "Error: %v\n", err)
+			}
+		}
+	}
+}
+
+// exec runs a command other than quit and help
+func (s *shell) exec(in lineReader, cmd string, args []string) error {
+	want := map[string]int{"keys": 0, "get": 1, "delete": 1, "save": 0}
+	if n, ok := want[cmd]; ok && len(args) != n {
+		return fmt.Errorf("usage: see help")
+	}
+
+	switch cmd {
+	case "keys":
+		for _, key := range sortedValues(s.values) {
+			fmt.Fprintln(s.out, key)
+		}
+	case "get":
+		value, ok := s.values[args[0]]
+		if !ok {
+			return fmt.Errorf("no key %q", args[0])
+		}
+		fmt.Fprintln(s.out, value)
+	case "set":
+		if len(args) == 0 || len(args) > 2 {
+			return fmt.Errorf("usage: set KEY [VALUE]")
+		}
+		value := ""
+		if len(args) == 2 {
+			value = args[1]
+		} else {
+			v, err := in.ReadSecret(fmt.Sprintf("Value for %s: ", args[0]))
+			if err != nil {
+				return err
+			}
+			value = v
+		}
+		s.values[args[0]] = value
+	case "delete":
+		if _, ok := s.values[args[0]]; !ok {
+			return fmt.Errorf("no key %q", args[0])
+		}
+		delete(s.values, args[0])
+	case "diff":
+		if len(args) > 1 {
+			return fmt.Errorf("usage: diff [KEY]")
+		}
+		if len(args) == 1 {
+			return s.diffValue(args[0])
+		}
+		if diffTool != "" {
+			return writeValueDiff(s.out, s.colors, s.saved, s.values, diffTool)
+		}
+		styles := map[byte]output.Style{'+': output.Green, '-': output.Red, '~': output.Yellow}
+		for _, line := range s.diff() {
+			fmt.Fprintln(s.out, s.colors.Paint(line, styles[line[0]]))
+		}
+	case "save":
+		return s.save()
+	default:
+		return fmt.Errorf("unknown command %q; type help for commands", cmd)
+	}
+	return nil
+}
+
+// diff lists the keys changed since the last save, see keyDiff
+func (s *shell) diff() []string {
+	return keyDiff(s.saved, s.values)
+}
+
+// keyDiff lists added (+), removed (-), and changed (~) keys, without values
+func keyDiff(before, after map[string]string) []string {
+	var lines []string
+	for _, key := range sortedValues(mergeKeys(before, after)) {
+		old, wasSet := before[key]
+		value, isSet := after[key]
+		switch {
+		case !wasSet:
+			lines = append(lines, "+ "+key)
+		case !isSet:
+			lines = append(lines, "- "+key)
+		case old != value:
+			lines = append(lines, "~ "+key)
+		}
+	}
+	return lines
+}
+
+// diffValue shows how the value of key changed since the last save, word
+// by word unless --diff-tool picks another diff
+func (s *shell) diffValue(key string) error {
+	old, wasSet := s.saved[key]
+	value, isSet := s.values[key]
+	if !wasSet && !isSet {
+		return fmt.Errorf("no key %q", key)
+	}
+	before, after := map[string]string{}, map[string]string{}
+	if wasSet {
+		before[key] = old
+	}
+	if isSet {
+		after[key] = value
+	}
+	tool := diffTool
+	if tool == "" {
+		tool = valuediff.Words
+	}
+	return writeValueDiff(s.out, s.colors, before, after, tool)
+}
+
+func (s *shell) changed() bool {
+	return len(s.diff()) > 0
+}
+
+// save writes the changed keys through the usual checks
+func (s *shell) save() error {
+	if !s.changed() {
+		fmt.Fprintln(s.out, "No changes")
+		return nil
+	}
+
+	dataPatch := map[string]any{}
+	for key, old := range s.saved {
+		if _, ok := s.values[key]; !ok {
+			dataPatch[key] = nil
+		} else if s.values[key] != old {
+			dataPatch[key] = s.values[key]
+		}
+	}
+	for key, value := range s.values {
+		if _, ok := s.saved[key]; !ok {
+			dataPatch[key] = value
+		}
+	}
+	patchData, err := yaml.Marshal(map[string]any{"data": dataPatch})
+	if err != nil {
+		return fmt.Errorf("failed to build patch: %w", err)
+	}
+	result, err := patchManifest(s.data, patchData, patch.TypeMerge)
+	if err != nil {
+		return err
+	}
+	if err := writeResult(s.path, "", result); err != nil {
+		return err
+	}
+
+	s.data, s.saved = result, copyValues(s.values)
+	fmt.Fprintf(s.out, "Saved %s\n", s.path)
+	return nil
+}
+
+func copyValues(values map[string]string) map[string]string {
+	c := make(map[string]string, len(values))
+	for k, v := range values {
+		c[k] = v
+	}
+	return c
+}
+
+func mergeKeys(a, b map[string]string) map[string]string {
+	merged := copyValues(a)
+	for k, v := range b {
+		merged[k] = v
+	}
+	return merged
+}
+
+// sortedValues returns the keys of a value map, sorted
+func sortedValues(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunShell(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	script := strings.Join([]string{
+		"keys",
+		"get username",
+		"set password",
+		"hunter2",
+		"set username root",
+		"diff",
+		"quit",
+		"save",
+		"diff",
+		"delete password",
+		"frobnicate",
+		"quit",
+		"quit",
+	}, "\n") + "\n"
+	withStdin(t, script, false)
+	out := captureStdout(t)
+
+	if err := run([]string{"shell", file}); err != nil {
+		t.Fatalf("shell failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"swk> username\n",
+		"swk> admin\n",
+		"Value for password: ",
+		"swk> + password\n~ username\n",
+		"There are unsaved changes",
+		"Saved " + file,
+		`unknown command "frobnicate"`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	if got := queryFile(t, file, ".data.password | @base64d"); got != "hunter2" {
+		t.Errorf("password = %q, want hunter2", got)
+	}
+	if got := queryFile(t, file, ".data.username | @base64d"); got != "root" {
+		t.Errorf("username = %q, want root", got)
+	}
+}
+
+func TestRunShellDiffValue(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"words", nil, "swk> ~ username\n    [-admin-]{+administrator+}\n"},
+		{"chars", []string{"--diff-tool", "chars"}, "swk> ~ username\n    admin{+istrator+}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "secret.yaml")
+			if err := os.WriteFile(file, []byte(setTestSecret), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			t.Cleanup(func() { diffTool = "" })
+			withStdin(t, "set username administrator\ndiff username\ndiff missing\nquit\nquit\n", false)
+			out := captureStdout(t)
+
+			if err := run(append(tt.args, "shell", file)); err != nil {
+				t.Fatalf("shell failed: %v", err)
+			}
+			for _, want := range []string{tt.want, `no key "missing"`} {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/snapshot"
+)
+
+func init() {
+	commands["snapshot"] = runSnapshot
+	commands["rollback"] = runRollback
+	capabilities = append(capabilities, capability{Name: "snapshot", Description: "Encrypted snapshots of cluster Secrets and rollback", Network: true})
+}
+
+// runSnapshot handles `swk snapshot secret/NAME -n NAMESPACE [--list]`,
+// saving the Secret as it is in the cluster as its next numbered snapshot
+func runSnapshot(args []string) error {
+	var clusterOpts cluster.Options
+	fs := flag.NewFlagSet("swk snapshot", flag.ContinueOnError)
+	clusterOpts.BindFlags(fs)
+	list := fs.Bool("list", false, "List the snapshots instead of taking one")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	const usage = "usage: swk snapshot secret/NAME -n NAMESPACE [--context CONTEXT] [--list]"
+	if len(positional) != 1 || clusterOpts.Namespace == "" {
+		return fmt.Errorf(usage)
+	}
+	name, ok := secretArg(positional[0])
+	if !ok {
+		return fmt.Errorf(usage)
+	}
+
+	client := cluster.New(clusterOpts)
+	store, kubeContext, err := openSnapshots(client)
+	if err != nil {
+		return err
+	}
+	if *list {
+		return listSnapshots(store, kubeContext, clusterOpts.Namespace, name)
+	}
+	snap, err := takeSnapshot(client, store, kubeContext, clusterOpts.Namespace, name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, i18n.T("Saved snapshot %d of %s/%s\n"), snap.Version, snap.Namespace, snap.Name)
+	return nil
+}
+
+// runRollback handles `swk rollback secret/NAME -n NAMESPACE --to N`,
+// replacing the Secret in the cluster with a snapshot. The Secret as it was
+// before is snapshotted first, so a rollback can be rolled back too.
+func runRollback(args []string) error {
+	var clusterOpts cluster.Options
+	fs := flag.NewFlagSet("swk rollback", flag.ContinueOnError)
+	clusterOpts.BindFlags(fs)
+	to := fs.Int("to", 0, "Snapshot version to roll back to (default: list the snapshots)")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	const usage = "usage: swk rollback secret/NAME -n NAMESPACE [--context CONTEXT] --to VERSION"
+	if len(positional) != 1 || clusterOpts.Namespace == "" || *to < 0 {
+		return fmt.Errorf(usage)
+	}
+	name, ok := secretArg(positional[0])
+	if !ok {
+		return fmt.Errorf(usage)
+	}
+	namespace := clusterOpts.Namespace
+
+	client := cluster.New(clusterOpts)
+	store, kubeContext, err := openSnapshots(client)
+	if err != nil {
+		return err
+	}
+	if *to == 0 {
+		return listSnapshots(store, kubeContext, namespace, name)
+	}
+	snap, err := store.Load(kubeContext, namespace, name, *to)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	exists, err := client.SecretExists(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := client.Apply(ctx, snap.Manifest); err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, i18n.T("Restored %s/%s from snapshot %d\n"), namespace, name, snap.Version)
+		return nil
+	}
+
+	before, err := takeSnapshot(client, store, kubeContext, namespace, name)
+	if err != nil {
+		return err
+	}
+	if err := client.Replace(ctx, snap.Manifest); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, i18n.T("Rolled back %s/%s to snapshot %d; the state before is snapshot %d\n"), namespace, name, snap.Version, before.Version)
+	return nil
+}
+
+// openSnapshots opens the snapshot store and returns it with the context
+// the client talks to, which snapshots are kept per
+func openSnapshots(client *cluster.Client) (*snapshot.Store, string, error) {
+	kubeContext, err := client.CurrentContext(context.Background())
+	if err != nil {
+		return nil, "", err
+	}
+	store, err := snapshot.DefaultStore()
+	if err != nil {
+		return nil, "", err
+	}
+	return store, kubeContext, nil
+}
+
+// takeSnapshot saves the Secret as it is in the cluster, without the
+// server-set fields that would stop it from being applied again
+func takeSnapshot(client *cluster.Client, store *snapshot.Store, kubeContext, namespace, name string) (snapshot.Snapshot, error) {
+	manifest, err := client.GetSecretIn(context.Background(), namespace, name)
+	if err != nil {
+		return snapshot.Snapshot{}, err
+	}
+	if manifest, err = relocateManifest(manifest, namespace, name); err != nil {
+		return snapshot.Snapshot{}, err
+	}
+	return store.Save(kubeContext, namespace, name, manifest)
+}
+
+// listSnapshots prints the snapshots of a Secret with their keys, never
+// their values
+func listSnapshots(store *snapshot.Store, kubeContext, namespace, name string) error {
+	snaps, err := store.List(kubeContext, namespace, name)
+	if err != nil {
+		return err
+	}
+	if len(snaps) == 0 {
+		return fmt.Errorf(i18n.T("no snapshots of %s/%s in context %s"), namespace, name, kubeContext)
+	}
+	return paged(func() error {
+		w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tTAKEN\tKEYS")
+		for _, snap := range snaps {
+			var keys []string
+			if values, err := secretValues(snap.Manifest); err == nil {
+				for key := range values {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", snap.Version, snap.Taken.Local().Format(time.DateTime), strings.Join(keys, ","))
+		}
+		return w.Flush()
+	})
+}
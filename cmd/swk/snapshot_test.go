@@ -0,0 +1,72 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSnapshotRollback(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	stderr = &strings.Builder{}
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	cluster := fakeCluster(t)
+	current := filepath.Join(cluster, "prod", "db")
+	if err := os.MkdirAll(filepath.Dir(current), 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(password string) {
+		manifest := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\n  namespace: prod\n  resourceVersion: \"42\"\ndata:\n  password: " + password + "\n"
+		if err := os.WriteFile(current, []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("djE=") // v1
+	if err := run([]string{"snapshot", "secret/db", "-n", "prod"}); err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	write("djI=") // v2
+	if err := run([]string{"snapshot", "secret/db", "-n", "prod"}); err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	write("djM=") // v3, edited after the last snapshot
+
+	out := captureStdout(t)
+	if err := run([]string{"rollback", "secret/db", "-n", "prod"}); err != nil {
+		t.Fatalf("rollback without --to failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "VERSION") || strings.Count(out.String(), "password") != 2 {
+		t.Errorf("listing = %q, want two snapshots", out.String())
+	}
+
+	if err := run([]string{"rollback", "secret/db", "-n", "prod", "--to", "1"}); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if got := queryFile(t, current, ".data.password"); got != "djE=" {
+		t.Errorf("password after rollback = %q, want v1", got)
+	}
+	data, _ := os.ReadFile(current)
+	if strings.Contains(string(data), "resourceVersion") {
+		t.Errorf("rolled back with a stale resource version: %q", data)
+	}
+
+	// The state before the rollback became snapshot 3
+	if err := run([]string{"rollback", "secret/db", "-n", "prod", "--to", "3"}); err != nil {
+		t.Fatalf("rollback of the rollback failed: %v", err)
+	}
+	if got := queryFile(t, current, ".data.password"); got != "djM=" {
+		t.Errorf("password after undoing the rollback = %q, want v3", got)
+	}
+
+	if err := run([]string{"rollback", "secret/db", "-n", "prod", "--to", "9"}); err == nil {
+		t.Error("rollback to a missing snapshot succeeded")
+	}
+	if err := run([]string{"rollback", "secret/db", "-n", "dev"}); err == nil || !strings.Contains(err.Error(), "no snapshots") {
+		t.Errorf("rollback in another namespace error = %v, want no snapshots", err)
+	}
+}
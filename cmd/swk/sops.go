@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sopsEncrypt encrypts a manifest's values with sops, replaceable in tests
+var sopsEncrypt = func(data []byte) ([]byte, error) {
+	return runSops(data, "--encrypt", "--input-type", "yaml", "--output-type", "yaml",
+		"--encrypted-regex", "^(data|stringData)$", "/dev/stdin")
+}
+
+// sopsDecrypt decrypts a sops-encrypted manifest, replaceable in tests
+var sopsDecrypt = func(data []byte) ([]byte, error) {
+	return runSops(data, "--decrypt", "--input-type", "yaml", "--output-type", "yaml", "/dev/stdin")
+}
+
+// sopsEncrypted reports whether a manifest was encrypted by sops, which
+// adds a top-level sops key
+func sopsEncrypted(data []byte) bool {
+	var doc struct {
+		Sops any `yaml:"sops"`
+	}
+	return yaml.Unmarshal(data, &doc) == nil && doc.Sops != nil
+}
+
+// runSops runs sops with data on stdin and returns its output
+func runSops(data []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("sops", args...)
+	cmd.Stdin = bytes.NewReader(data)
+	var errOut bytes.Buffer
+	cmd.Stderr = &errOut
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("sops failed: %w: %s", err, strings.TrimSpace(errOut.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+	"gopkg.in/yaml.v3"
+)
+
+// maxSecretSize is the most data the API server stores in one Secret
+const maxSecretSize = 1 << 20
+
+// splitRecord is the SplitAnnotation of a part
+type splitRecord struct {
+	Of    string `json:"of"`
+	Type  string `json:"type,omitempty"`
+	Part  int    `json:"part"`
+	Parts int    `json:"parts"`
+	// Chunks maps keys too large for one part to the number of chunks,
+	// stored as KEY.part1, KEY.part2, and so on
+	Chunks map[string]int `json:"chunks,omitempty"`
+}
+
+// runSplit handles `swk split FILE [--max-size 900KiB] [-o DIR|-]`,
+// spreading a Secret too large for the API server over numbered Secrets,
+// NAME-1, NAME-2, and so on, and printing a projected volume that mounts
+// them together in a Pod. Keys stay whole where they fit; a value larger
+// than a part is cut into chunks, which swk join puts back together.
+func runSplit(args []string) error {
+	const usage = "usage: swk split FILE [--max-size 900KiB] [-o DIR|-]"
+	fs := flag.NewFlagSet("swk split", flag.ContinueOnError)
+	maxSizeFlag := fs.String("max-size", "900KiB", "Most data per Secret, leaving room for metadata below the 1MiB limit")
+	output := fs.String("o", "", "Directory to write NAME-N.yaml to (default: FILE's), or - for a stream on stdout")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf(usage)
+	}
+	maxSize, err := parseSize(*maxSizeFlag)
+	if err != nil {
+		return err
+	}
+	if maxSize > maxSecretSize {
+		return fmt.Errorf("--max-size %s is over the API server's limit of 1MiB", *maxSizeFlag)
+	}
+	filePath := positional[0]
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("%s holds no manifest", filePath)
+	}
+	src := doc.Content[0]
+	sections, err := valueSections(src, false)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	values, err := sourceValues(src, sections)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	name := scalarField(findNode(src, "metadata"), "name")
+	if name == "" {
+		return fmt.Errorf("%s: the Secret has no name", filePath)
+	}
+
+	parts, chunks, err := splitValues(values, maxSize)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	if len(parts) < 2 {
+		return fmt.Errorf("%s fits in one Secret of --max-size %s; nothing to split", filePath, *maxSizeFlag)
+	}
+
+	var manifests [][]byte
+	for i, part := range parts {
+		record := splitRecord{Of: name, Type: scalarField(src, "type"), Part: i + 1, Parts: len(parts), Chunks: chunks}
+		manifest, err := marshalNode(partObject(src, part, record))
+		if err != nil {
+			return err
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	snippet := stdout
+	if *output == "-" {
+		// The parts go to stdout, so the snippet can't
+		snippet = stderr
+		if err := writeResult("", "-", bytes.Join(manifests, []byte("---\n"))); err != nil {
+			return err
+		}
+	} else {
+		dir := *output
+		if dir == "" {
+			dir = filepath.Dir(filePath)
+		}
+		for i, manifest := range manifests {
+			path := filepath.Join(dir, fmt.Sprintf("%s-%d.yaml", name, i+1))
+			if err := writeResult(path, "", manifest); err != nil {
+				return err
+			}
+			fmt.Fprintf(stderr, i18n.T("Wrote %s\n"), path)
+		}
+	}
+	writeProjectedVolume(snippet, name, len(parts), chunks)
+	return nil
+}
+
+// splitValues packs values into parts of at most maxSize bytes of keys and
+// values, in order, cutting values that don't fit in a part of their own
+// into chunks. It returns the parts and the number of chunks per cut key.
+func splitValues(values []keyValue, maxSize int) ([][]keyValue, map[string]int, error) {
+	var items []keyValue
+	chunks := map[string]int{}
+	for _, v := range values {
+		if len(v.Key)+len(v.Value) <= maxSize {
+			items = append(items, v)
+			continue
+		}
+		// Leaves room for the longest suffix a chunk key can get
+		size := maxSize - len(v.Key) - len(".part") - len(strconv.Itoa(len(v.Value)))
+		if size <= 0 {
+			return nil, nil, fmt.Errorf("key %q is too long for --max-size", v.Key)
+		}
+		n := 0
+		for start := 0; start < len(v.Value); start += size {
+			n++
+			items = append(items, keyValue{fmt.Sprintf("%s.part%d", v.Key, n), v.Value[start:min(start+size, len(v.Value))]})
+		}
+		chunks[v.Key] = n
+	}
+
+	var parts [][]keyValue
+	var current []keyValue
+	used := 0
+	for _, item := range items {
+		size := len(item.Key) + len(item.Value)
+		if used+size > maxSize && len(current) > 0 {
+			parts = append(parts, current)
+			current, used = nil, 0
+		}
+		current = append(current, item)
+		used += size
+	}
+	if len(current) > 0 {
+		parts = append(parts, current)
+	}
+	if len(chunks) == 0 {
+		chunks = nil
+	}
+	return parts, chunks, nil
+}
+
+// partObject builds one part: an Opaque Secret named NAME-N with src's
+// metadata, the values of the part, and the SplitAnnotation
+func partObject(src *yaml.Node, values []keyValue, record splitRecord) *yaml.Node {
+	annotation, _ := json.Marshal(record)
+	metadata := cleanMetadata(findNode(src, "metadata"), fmt.Sprintf("%s-%d", record.Of, record.Part))
+	annotations := findNode(metadata, "annotations")
+	if annotations == nil {
+		annotations = mapping()
+		metadata.Content = append(metadata.Content, scalarNode("annotations"), annotations)
+	}
+	annotations.Content = append(annotations.Content, mapping(secret.SplitAnnotation, string(annotation)).Content...)
+
+	root := mapping("apiVersion", "v1", "kind", "Secret")
+	root.Content = append(root.Content, scalarNode("metadata"), metadata)
+	root.Content = append(root.Content, mapping("type", "Opaque").Content...)
+	data := mapping()
+	for _, v := range values {
+		data.Content = append(data.Content, mapping(v.Key, base64.StdEncoding.EncodeToString([]byte(v.Value))).Content...)
+	}
+	root.Content = append(root.Content, scalarNode("data"), data)
+	return root
+}
+
+// writeProjectedVolume prints a Pod volume that mounts the parts of a
+// split Secret in one directory, as the Secret would have been
+func writeProjectedVolume(w io.Writer, name string, parts int, chunks map[string]int) {
+	fmt.Fprintf(w, "# Mounts the parts of %s together, as one Secret would be\n", name)
+	keys := make([]string, 0, len(chunks))
+	for key := range chunks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		n := chunks[key]
+		fmt.Fprintf(w, "# %s is in %d chunks, %s.part1 to %s.part%d; concatenate them in order before use\n", key, n, key, key, n)
+	}
+	fmt.Fprintf(w, "volumes:\n  - name: %s\n    projected:\n      sources:\n", name)
+	for i := 1; i <= parts; i++ {
+		fmt.Fprintf(w, "        - secret:\n            name: %s-%d\n", name, i)
+	}
+}
+
+// runJoin handles `swk join FILE... [-o OUT]`, putting the parts written
+// by swk split back together into the original Secret. The parts may be in
+// separate files or in streams, in any order, but all must be there.
+func runJoin(args []string) error {
+	const usage = "usage: swk join FILE... [-o OUT]"
+	fs := flag.NewFlagSet("swk join", flag.ContinueOnError)
+	output := fs.String("o", "-", "Write the Secret to this file (- for stdout)")
+	files, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf(usage)
+	}
+
+	var parts []*yaml.Node
+	var records []splitRecord
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var doc yaml.Node
+			err := decoder.Decode(&doc)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", file, err)
+			}
+			if len(doc.Content) == 0 {
+				continue
+			}
+			src := doc.Content[0]
+			raw := scalarField(findNode(findNode(src, "metadata"), "annotations"), secret.SplitAnnotation)
+			var record splitRecord
+			if raw == "" || json.Unmarshal([]byte(raw), &record) != nil || record.Of == "" {
+				return fmt.Errorf("%s: not a part written by swk split", file)
+			}
+			parts, records = append(parts, src), append(records, record)
+		}
+	}
+
+	joined, err := joinParts(parts, records)
+	if err != nil {
+		return err
+	}
+	result, err := marshalNode(joined)
+	if err != nil {
+		return err
+	}
+	return writeResult("", *output, result)
+}
+
+// joinParts checks that parts are all the parts of one Secret and builds
+// it from them
+func joinParts(parts []*yaml.Node, records []splitRecord) (*yaml.Node, error) {
+	first := records[0]
+	ordered := make([]*yaml.Node, first.Parts)
+	for i, r := range records {
+		if r.Of != first.Of || r.Parts != first.Parts {
+			return nil, fmt.Errorf("parts of %s and %s can't be joined", first.Of, r.Of)
+		}
+		if r.Part < 1 || r.Part > r.Parts {
+			return nil, fmt.Errorf("%s: invalid part number %d of %d", r.Of, r.Part, r.Parts)
+		}
+		if ordered[r.Part-1] != nil {
+			return nil, fmt.Errorf("%s: part %d is given more than once", r.Of, r.Part)
+		}
+		ordered[r.Part-1] = parts[i]
+	}
+	var missing []string
+	for i, part := range ordered {
+		if part == nil {
+			missing = append(missing, strconv.Itoa(i+1))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%s: missing part %s of %d", first.Of, strings.Join(missing, ", "), first.Parts)
+	}
+
+	var values []keyValue
+	for _, part := range ordered {
+		partValues, err := sourceValues(part, []string{"data"})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", scalarField(findNode(part, "metadata"), "name"), err)
+		}
+		values = append(values, partValues...)
+	}
+	values, err := joinChunks(values, first.Chunks)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", first.Of, err)
+	}
+
+	metadata := cleanMetadata(findNode(ordered[0], "metadata"), first.Of)
+	if annotations := findNode(metadata, "annotations"); annotations != nil {
+		removeKeys(annotations, map[string]bool{secret.SplitAnnotation: true})
+		if len(annotations.Content) == 0 {
+			removeKeys(metadata, map[string]bool{"annotations": true})
+		}
+	}
+	root := mapping("apiVersion", "v1", "kind", "Secret")
+	root.Content = append(root.Content, scalarNode("metadata"), metadata)
+	if first.Type != "" {
+		root.Content = append(root.Content, mapping("type", first.Type).Content...)
+	}
+	data := mapping()
+	for _, v := range values {
+		data.Content = append(data.Content, mapping(v.Key, base64.StdEncoding.EncodeToString([]byte(v.Value))).Content...)
+	}
+	root.Content = append(root.Content, scalarNode("data"), data)
+	return root, nil
+}
+
+// joinChunks puts the chunks of cut keys back together, where the first
+// chunk was
+func joinChunks(values []keyValue, chunks map[string]int) ([]keyValue, error) {
+	byKey := map[string]string{}
+	for _, v := range values {
+		byKey[v.Key] = v.Value
+	}
+	owner := map[string]string{}
+	for key, n := range chunks {
+		for i := 1; i <= n; i++ {
+			owner[fmt.Sprintf("%s.part%d", key, i)] = key
+		}
+	}
+
+	var joined []keyValue
+	for _, v := range values {
+		key, ok := owner[v.Key]
+		if !ok {
+			joined = append(joined, v)
+			continue
+		}
+		if v.Key != key+".part1" {
+			continue
+		}
+		var b strings.Builder
+		for i := 1; i <= chunks[key]; i++ {
+			chunk, ok := byKey[fmt.Sprintf("%s.part%d", key, i)]
+			if !ok {
+				return nil, fmt.Errorf("chunk %d of key %q is missing", i, key)
+			}
+			b.WriteString(chunk)
+		}
+		joined = append(joined, keyValue{key, b.String()})
+	}
+	return joined, nil
+}
+
+// parseSize parses a size such as 900KiB, 1Mi, 500k, or 1000: Ki and Mi
+// are powers of 1024, k and M powers of 1000, and B is optional
+func parseSize(s string) (int, error) {
+	units := []struct {
+		suffix string
+		factor int
+	}{{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"k", 1000}, {"K", 1000}, {"M", 1000 * 1000}, {"", 1}}
+	number := strings.TrimSuffix(s, "B")
+	for _, u := range units {
+		if digits, ok := strings.CutSuffix(number, u.suffix); ok {
+			n, err := strconv.Atoi(digits)
+			if err != nil || n <= 0 {
+				break
+			}
+			return n * u.factor, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid size %q: want e.g. 900KiB, 1Mi, or 500000", s)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSplitJoin(t *testing.T) {
+	b64 := func(n int, c string) string { return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(c, n))) }
+	tests := []struct {
+		name      string
+		data      map[string]string
+		wantParts int
+	}{
+		{"whole keys", map[string]string{"a": b64(400, "a"), "b": b64(400, "b"), "c": b64(400, "c")}, 2},
+		{"chunked key", map[string]string{"bundle": b64(2500, "x"), "small": b64(10, "s")}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			manifest := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: big\n  namespace: ml\n  labels:\n    app: model\ntype: kubernetes.io/tls\ndata:\n"
+			for _, key := range []string{"a", "b", "c", "bundle", "small"} {
+				if v, ok := tt.data[key]; ok {
+					manifest += "  " + key + ": " + v + "\n"
+				}
+			}
+			path := filepath.Join(dir, "big.yaml")
+			if err := os.WriteFile(path, []byte(manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+			stderr = &strings.Builder{}
+			t.Cleanup(func() { stderr = os.Stderr })
+
+			out := captureStdout(t)
+			if err := run([]string{"split", path, "--max-size", "1000"}); err != nil {
+				t.Fatalf("run(split) failed: %v", err)
+			}
+			var parts []string
+			for i := 1; i <= tt.wantParts; i++ {
+				part := filepath.Join(dir, "big-"+string(rune('0'+i))+".yaml")
+				if _, err := os.Stat(part); err != nil {
+					t.Fatalf("part %d missing: %v", i, err)
+				}
+				if !strings.Contains(out.String(), "name: big-"+string(rune('0'+i))) {
+					t.Errorf("projected volume lacks part %d:\n%s", i, out)
+				}
+				parts = append(parts, part)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "big-"+string(rune('0'+tt.wantParts+1))+".yaml")); err == nil {
+				t.Errorf("split wrote more than %d parts", tt.wantParts)
+			}
+
+			// Parts in any order join into the original
+			joined := filepath.Join(dir, "joined.yaml")
+			reversed := append([]string{"join", "-o", joined}, parts[1:]...)
+			if err := run(append(reversed, parts[0])); err != nil {
+				t.Fatalf("run(join) failed: %v", err)
+			}
+			data, _ := os.ReadFile(joined)
+			for key, value := range tt.data {
+				if !strings.Contains(string(data), key+": "+value+"\n") {
+					t.Errorf("joined Secret lacks %s", key)
+				}
+			}
+			for _, want := range []string{"name: big\n", "namespace: ml", "app: model", "type: kubernetes.io/tls"} {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("joined Secret lacks %q:\n%s", want, data)
+				}
+			}
+			if strings.Contains(string(data), "swk.dev/split") {
+				t.Errorf("joined Secret kept the split annotation:\n%s", data)
+			}
+
+			if err := run([]string{"join", parts[0]}); err == nil || !strings.Contains(err.Error(), "missing part") {
+				t.Errorf("run(join) with a part missing error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRunSplitFits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "small.yaml")
+	if err := os.WriteFile(path, []byte(setTestSecret), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"split", path}); err == nil || !strings.Contains(err.Error(), "nothing to split") {
+		t.Errorf("run() error = %v, want nothing to split", err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"900KiB", 900 * 1024, false},
+		{"1Mi", 1 << 20, false},
+		{"500k", 500000, false},
+		{"1000", 1000, false},
+		{"1MB", 1000000, false},
+		{"KiB", 0, true},
+		{"-5", 0, true},
+		{"lots", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseSize(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
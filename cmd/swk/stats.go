@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/approval"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/stats"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+)
+
+// statsPath returns the stats file, replaceable in tests
+var statsPath = stats.DefaultPath
+
+// firedChecks are the validations that fired during this run
+var firedChecks []string
+
+// countCheck records that a validation fired, for swk stats
+func countCheck(name string) {
+	firedChecks = append(firedChecks, name)
+}
+
+// runStats handles `swk stats [--json] [--reset]`, showing the local usage
+// counters
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("swk stats", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print the counters as JSON")
+	reset := fs.Bool("reset", false, "Remove the counters and start over")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("usage: swk stats [--json] [--reset]")
+	}
+	path, err := statsPath()
+	if err != nil {
+		return fmt.Errorf("failed to find stats file: %w", err)
+	}
+	if *reset {
+		return stats.Reset(path)
+	}
+
+	s, err := stats.Load(path)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+	if s.Runs() == 0 {
+		fmt.Fprintf(stdout, "No runs counted yet in %s\n", path)
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "%d runs since %s (%s)\n", s.Runs(), s.Since.Format("2006-01-02"), path)
+	for _, section := range []struct {
+		title  string
+		counts map[string]int
+	}{
+		{"Commands", s.Commands},
+		{"Failures", s.Failures},
+		{"Validations that fired", s.Checks},
+	} {
+		if len(section.counts) == 0 {
+			continue
+		}
+		fmt.Fprintf(stdout, "\n%s:\n", section.title)
+		for _, c := range stats.Sorted(section.counts) {
+			fmt.Fprintf(stdout, "  %6d  %s\n", c.Count, c.Name)
+		}
+	}
+	return nil
+}
+
+// recordUsage counts a run in the stats file, unless the config turns that
+// off. Counting is best effort: it never makes a run fail.
+func recordUsage(args []string, err error) {
+	if cfg, cerr := config.LoadDefault(); cerr != nil || cfg.Stats.Disabled {
+		return
+	}
+	path, perr := statsPath()
+	if perr != nil {
+		return
+	}
+	_ = stats.Add(path, stats.Run{
+		Command: commandName(args),
+		Failure: failureCategory(err),
+		Checks:  firedChecks,
+	}, now())
+}
+
+// commandName returns the command args run, skipping global flags, or edit
+func commandName(args []string) string {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		if !isGlobalFlag(name) {
+			break
+		}
+		if takesValue(name) && !hasValue && len(args) > 1 {
+			args = args[1:]
+		}
+		args = args[1:]
+	}
+	if len(args) > 0 {
+		if _, ok := commands[args[0]]; ok {
+			return args[0]
+		}
+	}
+	return "edit"
+}
+
+// failureCategory sorts the error a run ended with into a category
+func failureCategory(err error) string {
+	var (
+		dup     *secret.DuplicateKeyError
+		command *cluster.CommandError
+		path    *fs.PathError
+	)
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, flag.ErrHelp), strings.HasPrefix(err.Error(), "usage:"),
+		strings.HasPrefix(err.Error(), "flag provided but not defined"), strings.HasPrefix(err.Error(), "invalid value"):
+		return "usage"
+	case errors.As(err, &dup):
+		countCheck("duplicate-keys")
+		return "validation"
+	case len(firedChecks) > 0:
+		return "validation"
+	case errors.As(err, &command):
+		return "cluster"
+	case errors.Is(err, approval.ErrDenied):
+		return "approval"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &path):
+		return "file"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/approval"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+)
+
+func TestCommandName(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "edit"},
+		{[]string{"/tmp/secret.yaml"}, "edit"},
+		{[]string{"check", "a.yaml"}, "check"},
+		{[]string{"--profile", "prod", "fmt", "x"}, "fmt"},
+		{[]string{"--lang=nl", "--yes", "query"}, "query"},
+		{[]string{"--validate=schema", "f.yaml"}, "edit"},
+	}
+	for _, tt := range tests {
+		if got := commandName(tt.args); got != tt.want {
+			t.Errorf("commandName(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestFailureCategory(t *testing.T) {
+	tests := []struct {
+		err    error
+		checks []string
+		want   string
+	}{
+		{nil, nil, ""},
+		{errors.New("usage: swk stats [--json] [--reset]"), nil, "usage"},
+		{errors.New("manifest does not match"), []string{"schema"}, "validation"},
+		{&cluster.CommandError{Args: []string{"get"}, Err: errors.New("exit 1")}, nil, "cluster"},
+		{fmt.Errorf("%w by alice", approval.ErrDenied), nil, "approval"},
+		{fmt.Errorf("failed to read file: %w", &os.PathError{Op: "open", Path: "x", Err: os.ErrNotExist}), nil, "file"},
+		{errors.New("boom"), nil, "other"},
+	}
+	for _, tt := range tests {
+		firedChecks = tt.checks
+		if got := failureCategory(tt.err); got != tt.want {
+			t.Errorf("failureCategory(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+	firedChecks = nil
+}
+
+func TestStats(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SWK_CONFIG", filepath.Join(dir, "config.yaml"))
+	path := filepath.Join(dir, "stats.json")
+	orig := statsPath
+	statsPath = func() (string, error) { return path, nil }
+	t.Cleanup(func() { statsPath = orig })
+
+	recordUsage([]string{"check", "a.yaml"}, nil)
+	firedChecks = []string{"check:base64"}
+	recordUsage([]string{"check", "a.yaml"}, errors.New("1 problem(s) found"))
+	firedChecks = nil
+
+	out := captureStdout(t)
+	if err := run([]string{"stats"}); err != nil {
+		t.Fatalf("swk stats error = %v", err)
+	}
+	for _, want := range []string{"2 runs since", "2  check", "1  validation", "1  check:base64"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("swk stats output missing %q:\n%s", want, out)
+		}
+	}
+
+	if err := run([]string{"stats", "--reset"}); err != nil {
+		t.Fatalf("swk stats --reset error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("stats file still exists after --reset: %v", err)
+	}
+
+	// Disabled in the config, nothing is counted
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("stats:\n  disabled: true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	recordUsage([]string{"ls"}, nil)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("stats file written while disabled: %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+//go:build !nocloud
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cloudsync"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/output"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/patch"
+	"gopkg.in/yaml.v3"
+)
+
+// syncFlags are the flags shared by push and pull
+type syncFlags struct {
+	resume     *bool
+	state      *string
+	maxRetries *int
+}
+
+func bindSyncFlags(fs *flag.FlagSet) syncFlags {
+	return syncFlags{
+		resume:     fs.Bool("resume", false, "Skip the keys an interrupted run already synced"),
+		state:      fs.String("state", "", "Where to record progress (default FILE.swk-sync)"),
+		maxRetries: fs.Int("max-retries", cloudsync.DefaultMaxRetries, "Retries per key for throttled or failed requests"),
+	}
+}
+
+// syncer opens the store and the state for a push or pull of filePath
+func (f syncFlags) syncer(op, storeURL, filePath string, manifest []byte) (*cloudsync.Syncer, error) {
+	store, err := cloudsync.Open(storeURL)
+	if err != nil {
+		return nil, err
+	}
+
+	statePath := *f.state
+	if statePath == "" {
+		statePath = filePath + ".swk-sync"
+	}
+	source := cloudsync.Fingerprint(manifest)
+	state := cloudsync.NewState(statePath, op, store.Name(), source)
+	if *f.resume {
+		if state, err = cloudsync.ResumeState(statePath, op, store.Name(), source); err != nil {
+			return nil, err
+		}
+	}
+
+	s := cloudsync.New(store, state)
+	s.MaxRetries = *f.maxRetries
+	return s, nil
+}
+
+func init() {
+	commands["push"] = runPush
+	commands["pull"] = runPull
+}
+
+// runPush handles `swk push FILE --to STORE`, copying every key of a
+// Secret manifest to an external store
+func runPush(args []string) error {
+	fs := flag.NewFlagSet("swk push", flag.ContinueOnError)
+	to := fs.String("to", "", "Store to push to, e.g. vault://kv/apps/db")
+	sf := bindSyncFlags(fs)
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *to == "" {
+		return fmt.Errorf("usage: swk push FILE --to STORE [--resume] [--state PATH]")
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	values, err := secretValues(data)
+	if err != nil {
+		return err
+	}
+	s, err := sf.syncer("push", *to, positional[0], data)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	results := s.Push(context.Background(), keys, values)
+	return finishSync(s, results)
+}
+
+// runPull handles `swk pull FILE --from STORE`, copying keys from an
+// external store into a Secret manifest
+func runPull(args []string) error {
+	fs := flag.NewFlagSet("swk pull", flag.ContinueOnError)
+	from := fs.String("from", "", "Store to pull from, e.g. vault://kv/apps/db")
+	keyList := fs.String("keys", "", "Comma-separated keys to pull (default: all keys in the store)")
+	sf := bindSyncFlags(fs)
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *from == "" {
+		return fmt.Errorf("usage: swk pull FILE --from STORE [--keys K1,K2] [--resume] [--state PATH]")
+	}
+	filePath := positional[0]
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	s, err := sf.syncer("pull", *from, filePath, data)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var keys []string
+	if *keyList != "" {
+		keys = strings.Split(*keyList, ",")
+	} else if keys, err = s.Keys(ctx); err != nil {
+		return fmt.Errorf("failed to list keys: %w", err)
+	}
+
+	values := map[string]string{}
+	results := s.Pull(ctx, keys, values)
+
+	// Write what arrived, even if some keys failed, so a resumed run only
+	// has to fetch the rest
+	if len(values) > 0 {
+		dataPatch := make(map[string]any, len(values))
+		pulled := make([]string, 0, len(values))
+		for k, v := range values {
+			dataPatch[k] = v
+			pulled = append(pulled, k)
+		}
+		patchData, err := yaml.Marshal(map[string]any{"data": dataPatch})
+		if err != nil {
+			return fmt.Errorf("failed to build patch: %w", err)
+		}
+		result, err := patchManifest(data, patchData, patch.TypeMerge)
+		if err != nil {
+			return err
+		}
+		if err := writeResult(filePath, "", result); err != nil {
+			return err
+		}
+		sort.Strings(pulled)
+		if err := s.State.PulledInto(cloudsync.Fingerprint(result), pulled...); err != nil {
+			return fmt.Errorf("failed to record progress: %w", err)
+		}
+	}
+	return finishSync(s, results)
+}
+
+// finishSync reports the result for every key, and removes the state once
+// every key is done
+func finishSync(s *cloudsync.Syncer, results []cloudsync.Result) error {
+	colors := stdoutTerminal()
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			fmt.Fprintf(stdout, "%s: skipped (synced by an earlier run)\n", r.Key)
+		case r.Err != nil:
+			fmt.Fprintf(stdout, "%s: %s (%v)\n", r.Key, colors.Paint("FAILED", output.Red), r.Err)
+		case r.Attempts > 1:
+			fmt.Fprintf(stdout, "%s: %s after %d attempts\n", r.Key, colors.Paint("OK", output.Green), r.Attempts)
+		default:
+			fmt.Fprintf(stdout, "%s: %s\n", r.Key, colors.Paint("OK", output.Green))
+		}
+	}
+
+	if failed := cloudsync.Failed(results); failed > 0 {
+		return fmt.Errorf("%d of %d keys failed; run again with --resume to retry only those", failed, len(results))
+	}
+	if err := s.State.Remove(); err != nil {
+		return fmt.Errorf("failed to remove sync state: %w", err)
+	}
+	return nil
+}
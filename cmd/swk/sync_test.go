@@ -0,0 +1,95 @@
+//go:build !nocloud
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeVault serves KV v2 secrets from memory
+func fakeVault(t *testing.T) map[string]any {
+	t.Helper()
+	var mu sync.Mutex
+	data := map[string]any{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method != http.MethodGet {
+			var body struct {
+				Data map[string]any `json:"data"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			for k, v := range body.Data {
+				data[k] = v
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": data}})
+	}))
+	t.Cleanup(ts.Close)
+	t.Setenv("VAULT_ADDR", ts.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+	return data
+}
+
+func TestRunPushPull(t *testing.T) {
+	vault := fakeVault(t)
+	out := captureStdout(t)
+	dir := t.TempDir()
+
+	src := filepath.Join(dir, "src.yaml")
+	if err := os.WriteFile(src, []byte(setTestSecret), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"push", src, "--to", "vault://kv/apps/db"}); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if vault["username"] != "admin" {
+		t.Errorf("vault = %v, want username admin", vault)
+	}
+	if _, err := os.Stat(src + ".swk-sync"); !os.IsNotExist(err) {
+		t.Error("a completed push left its state behind")
+	}
+
+	// One key is missing, so the pull fails part way and records progress
+	dst := filepath.Join(dir, "dst.yaml")
+	if err := os.WriteFile(dst, []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pull := []string{"pull", dst, "--from", "vault://kv/apps/db", "--keys", "username,password"}
+	err := run(pull)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 keys failed") {
+		t.Fatalf("pull error = %v, want one failed key", err)
+	}
+	if !strings.Contains(out.String(), "password: FAILED") {
+		t.Errorf("output = %q, want a failure for password", out)
+	}
+	if got := queryFile(t, dst, ".data.username | @base64d"); got != "admin" {
+		t.Errorf("username = %q, want admin", got)
+	}
+
+	// Resuming skips the key that already arrived
+	vault["password"] = "hunter2"
+	out.Reset()
+	if err := run(append(pull, "--resume")); err != nil {
+		t.Fatalf("resumed pull failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "username: skipped") || !strings.Contains(out.String(), "password: OK") {
+		t.Errorf("output = %q, want username skipped and password pulled", out)
+	}
+	if got := queryFile(t, dst, ".data.password | @base64d"); got != "hunter2" {
+		t.Errorf("password = %q, want hunter2", got)
+	}
+	if _, err := os.Stat(dst + ".swk-sync"); !os.IsNotExist(err) {
+		t.Error("a completed pull left its state behind")
+	}
+}
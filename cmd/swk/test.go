@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/generate"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/probe"
+)
+
+// probeAliases are Secret keys commonly used for probe parameters, besides
+// the parameter name itself
+var probeAliases = map[string][]string{
+	"user":          {"username"},
+	"access-key":    {"aws_access_key_id", "access_key_id"},
+	"secret-key":    {"aws_secret_access_key", "secret_access_key"},
+	"session-token": {"aws_session_token"},
+}
+
+// runTest handles `swk test FILE --probe NAME`, authenticating against a
+// service with the Secret's decoded values
+func runTest(args []string) error {
+	fs := flag.NewFlagSet("swk test", flag.ContinueOnError)
+	probeName := fs.String("probe", "", "Service to authenticate against: "+strings.Join(probe.Names(), ", "))
+	params := generate.Params{}
+	fs.Var(params, "param", "Connection parameter as name=value, e.g. host=db.internal (repeatable)")
+	keys := generate.Params{}
+	fs.Var(keys, "key", "Take a parameter from a Secret key as name=KEY, e.g. password=DB_PASS (repeatable)")
+	timeout := fs.Duration("timeout", 10*time.Second, "Connection timeout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: swk test FILE --probe NAME [--param name=value]... [--key name=KEY]...")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "\nProbe parameters (taken from the Secret key of the same name unless given):")
+		for _, name := range probe.Names() {
+			fmt.Fprintf(fs.Output(), "  %-9s %s\n", name, strings.Join(probe.Parameters(name), ", "))
+		}
+	}
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *probeName == "" {
+		return fmt.Errorf("usage: swk test FILE --probe %s [--param name=value]...", strings.Join(probe.Names(), "|"))
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	values, err := secretValues(data)
+	if err != nil {
+		return err
+	}
+
+	resolved := probe.Params{}
+	for _, name := range probe.Parameters(*probeName) {
+		switch {
+		case params[name] != "":
+			resolved[name] = params[name]
+		case keys[name] != "":
+			v, ok := values[keys[name]]
+			if !ok {
+				return fmt.Errorf("key %q not found in Secret", keys[name])
+			}
+			resolved[name] = v
+		default:
+			resolved[name] = lookupKey(values, append([]string{name}, probeAliases[name]...))
+		}
+	}
+
+	switch resolved["sslmode"] {
+	case "prefer":
+		fmt.Fprintln(stderr, fmt.Sprintf(i18n.T("Warning: %s"), "sslmode=prefer doesn't check the server's certificate, so the password may reach an impostor"))
+	case "disable":
+		fmt.Fprintln(stderr, fmt.Sprintf(i18n.T("Warning: %s"), "sslmode=disable sends the login unencrypted"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if err := probe.Run(ctx, *probeName, resolved); err != nil {
+		return fmt.Errorf("%s probe failed: %w", *probeName, err)
+	}
+
+	fmt.Fprintf(stdout, "%s: authentication succeeded\n", *probeName)
+	return nil
+}
+
+// lookupKey finds a Secret value by any of the candidate names, ignoring
+// case and treating - and _ alike
+func lookupKey(values map[string]string, candidates []string) string {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.ReplaceAll(s, "-", "_"))
+	}
+	for _, candidate := range candidates {
+		for key, value := range values {
+			if normalize(key) == normalize(candidate) {
+				return value
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRunTest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "admin" || pass != "password123" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	content := `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-secret
+data:
+  USERNAME: YWRtaW4=
+  api-pass: cGFzc3dvcmQxMjM=
+`
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		// user comes from USERNAME via its alias, password from an explicit key
+		{"keys from secret", []string{"--param", "url=" + server.URL, "--key", "password=api-pass"}, false},
+		{"literal overrides", []string{"--param", "url=" + server.URL, "--param", "password=wrong"}, true},
+		{"missing key", []string{"--param", "url=" + server.URL, "--key", "password=nope"}, true},
+		{"unknown probe", []string{"--probe", "ftp"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := captureStdout(t)
+			args := append([]string{"test", file, "--probe", "http"}, tt.args...)
+			err := run(args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !strings.Contains(out.String(), "authentication succeeded") {
+				t.Errorf("output = %q", out.String())
+			}
+		})
+	}
+}
+
+func TestRunTestSSLModeWarning(t *testing.T) {
+	var errOut strings.Builder
+	stderr = &errOut
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	// Nothing listens there; only the warning matters
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().(*net.TCPAddr)
+	ln.Close()
+
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	content := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nstringData:\n  user: app\n  password: hunter2\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	for _, mode := range []string{"", "verify-full", "prefer"} {
+		errOut.Reset()
+		args := []string{"test", file, "--probe", "postgres", "--param", "host=127.0.0.1", "--param", "port=" + strconv.Itoa(addr.Port), "--param", "sslmode=" + mode}
+		if err := run(args); err == nil {
+			t.Fatalf("run() with sslmode %q succeeded without a server", mode)
+		}
+		if got := strings.Contains(errOut.String(), "doesn't check the server's certificate"); got != (mode == "prefer") {
+			t.Errorf("sslmode %q: stderr = %q", mode, errOut.String())
+		}
+	}
+}
@@ -0,0 +1,111 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/i18n"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	commands["token"] = runToken
+}
+
+// runToken handles `swk token create`
+func runToken(args []string) error {
+	if len(args) == 0 || args[0] != "create" {
+		return fmt.Errorf("usage: swk token create --service-account NAME -n NAMESPACE [--duration 1h] [--audience AUD]... [-o FILE [--name NAME]]")
+	}
+	return runTokenCreate(args[1:])
+}
+
+// runTokenCreate handles `swk token create --service-account NAME -n
+// NAMESPACE [--duration D] [--audience AUD]... [-o FILE [--name NAME]]`,
+// minting a short-lived token with the TokenRequest API instead of the
+// long-lived token Secrets Kubernetes no longer creates. The token is
+// printed, or written with -o into an Opaque Secret manifest labeled for
+// swk delete --expired once the token expires.
+func runTokenCreate(args []string) error {
+	var clusterOpts cluster.Options
+	fs := flag.NewFlagSet("swk token create", flag.ContinueOnError)
+	clusterOpts.BindFlags(fs)
+	serviceAccount := fs.String("service-account", "", "Service account to mint the token for")
+	duration := fs.Duration("duration", time.Hour, "How long the token is valid; the API server may cap it")
+	var audiences stringList
+	fs.Var(&audiences, "audience", "Audience the token is valid for (repeatable; default: the API server)")
+	output := fs.String("o", "", "Write a Secret manifest holding the token here (- for stdout) instead of printing the token")
+	secretName := fs.String("name", "", "Name of the Secret written with -o (default: SERVICE-ACCOUNT-token)")
+	key := fs.String("key", "token", "Key of the token in the Secret written with -o")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	const usage = "usage: swk token create --service-account NAME -n NAMESPACE [--duration 1h] [--audience AUD]... [-o FILE [--name NAME]]"
+	if len(positional) > 0 || *serviceAccount == "" || clusterOpts.Namespace == "" || *key == "" {
+		return fmt.Errorf(usage)
+	}
+	if *duration < 10*time.Minute {
+		return fmt.Errorf("--duration must be at least 10m, the shortest token the API server issues")
+	}
+	namespace := clusterOpts.Namespace
+	clusterOpts.Namespace = ""
+
+	token, err := cluster.New(clusterOpts).CreateToken(context.Background(), namespace, *serviceAccount, *duration, audiences)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, i18n.T("Token for %s/%s expires at %s\n"), namespace, *serviceAccount, token.Expires.Local().Format(time.DateTime))
+
+	if *output == "" {
+		_, err := fmt.Fprintln(stdout, token.Token)
+		return err
+	}
+	name := *secretName
+	if name == "" {
+		name = *serviceAccount + "-token"
+	}
+	result, err := tokenSecret(name, namespace, *serviceAccount, *key, audiences, token)
+	if err != nil {
+		return err
+	}
+	return writeResult(*output, *output, result)
+}
+
+// tokenSecret renders an Opaque Secret holding a token under key, with
+// where it came from in secret.TokenAnnotation and its expiry in
+// secret.DeleteAfterLabel
+func tokenSecret(name, namespace, serviceAccount, key string, audiences []string, token cluster.Token) ([]byte, error) {
+	origin, err := json.Marshal(struct {
+		ServiceAccount string    `json:"serviceAccount"`
+		Namespace      string    `json:"namespace"`
+		Audiences      []string  `json:"audiences,omitempty"`
+		Expires        time.Time `json:"expires"`
+	}{serviceAccount, namespace, audiences, token.Expires.UTC()})
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := newSecret(name, namespace, "Opaque", []string{key}, map[string]string{key: token.Token},
+		map[string]string{secret.TokenAnnotation: string(origin)})
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(manifest, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse secret: %w", err)
+	}
+	root := doc.Content[0]
+	metadata := findNode(root, "metadata")
+	metadata.Content = append(metadata.Content, scalarNode("labels"),
+		mapping(secret.DeleteAfterLabel, token.Expires.UTC().Format(deleteAfterFormat)))
+	return marshalNode(root)
+}
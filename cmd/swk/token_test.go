@@ -0,0 +1,60 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunTokenCreate(t *testing.T) {
+	stderr = &strings.Builder{}
+	t.Cleanup(func() { stderr = os.Stderr })
+	cluster := fakeCluster(t)
+	out := captureStdout(t)
+
+	if err := run([]string{"token", "create", "--service-account", "deployer", "-n", "ci", "--audience", "vault"}); err != nil {
+		t.Fatalf("token create failed: %v", err)
+	}
+	if out.String() != "token-for-ci-deployer\n" {
+		t.Errorf("token create output = %q, want the token", out.String())
+	}
+	calls, _ := os.ReadFile(filepath.Join(cluster, ".tokens"))
+	if want := "create token deployer --namespace ci --duration 1h0m0s -o json --audience vault\n"; !strings.HasSuffix(string(calls), want) {
+		t.Errorf("kubectl calls = %q, want %q", calls, want)
+	}
+
+	file := filepath.Join(t.TempDir(), "token.yaml")
+	if err := run([]string{"token", "create", "--service-account", "deployer", "-n", "ci", "--duration", "2h", "-o", file}); err != nil {
+		t.Fatalf("token create -o failed: %v", err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"name: deployer-token",
+		"namespace: ci",
+		"type: Opaque",
+		"token: dG9rZW4tZm9yLWNpLWRlcGxveWVy",
+		`swk.dev/token: '{"serviceAccount":"deployer","namespace":"ci","expires":"2026-10-16T13:00:00Z"}'`,
+		"swk.dev/delete-after: 20261016T130000Z",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("manifest = %s, want %q in it", data, want)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"token", "create", "-n", "ci"},
+		{"token", "create", "--service-account", "deployer"},
+		{"token", "create", "--service-account", "deployer", "-n", "ci", "--duration", "1m"},
+		{"token", "list"},
+	} {
+		if err := run(args); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+}
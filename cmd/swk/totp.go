@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/totp"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+)
+
+// runTOTP handles `swk totp FILE KEY`, printing the current one-time code
+// for a key listed in the swk.dev/totp-keys annotation, so the seed never
+// has to be copied into a phone app. The code goes to stdout on its own,
+// for piping into a clipboard, and how long it stays valid to stderr.
+func runTOTP(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: swk totp FILE KEY")
+	}
+	filePath, key := args[0], args[1]
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	doc, err := parseSecret(data, "")
+	if err != nil {
+		return err
+	}
+	if !doc.IsSecret() {
+		return fmt.Errorf("%s is not a Secret", filePath)
+	}
+	b, err := doc.Behaviors()
+	if err != nil {
+		return err
+	}
+	if !b.TOTP[key] {
+		return fmt.Errorf("key %q is not a TOTP seed; list it in the %s annotation", key, secret.TOTPKeysAnnotation)
+	}
+
+	if err := doc.Decode(); err != nil {
+		return fmt.Errorf("failed to decode secret: %w", err)
+	}
+	seed, ok := "", false
+	for _, e := range append(doc.Data(), doc.StringData()...) {
+		if e.Key == key {
+			seed, ok = e.Value, true
+		}
+	}
+	if !ok {
+		return fmt.Errorf("no key %q in %s", key, filePath)
+	}
+	k, err := totp.Parse(seed)
+	if err != nil {
+		return fmt.Errorf("key %q: %w", key, err)
+	}
+
+	t := now()
+	fmt.Fprintln(stdout, k.Code(t))
+	fmt.Fprintf(stderr, "valid for %s\n", k.Remaining(t))
+	return nil
+}
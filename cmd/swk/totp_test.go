@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const totpTestSecret = `apiVersion: v1
+kind: Secret
+metadata:
+  name: admin
+  annotations:
+    swk.dev/totp-keys: admin-otp
+data:
+  admin-otp: R0VaREdOQlZHWTNUUU9KUUdFWkRHTkJWR1kzVFFPSlE=
+  password: c2VjcmV0
+`
+
+func TestRunTOTP(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := os.WriteFile(file, []byte(totpTestSecret), 0644); err != nil {
+		t.Fatal(err)
+	}
+	now = func() time.Time { return time.Unix(59, 0) }
+	t.Cleanup(func() { now = time.Now })
+	out := captureStdout(t)
+	var errOut strings.Builder
+	stderr = &errOut
+	t.Cleanup(func() { stderr = os.Stderr })
+
+	if err := run([]string{"totp", file, "admin-otp"}); err != nil {
+		t.Fatalf("totp failed: %v", err)
+	}
+	if out.String() != "287082\n" {
+		t.Errorf("code = %q, want 287082", out)
+	}
+	if errOut.String() != "valid for 1s\n" {
+		t.Errorf("stderr = %q, want the remaining validity", errOut.String())
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"totp", file}, "usage: swk totp"},
+		{[]string{"totp", file, "password"}, `key "password" is not a TOTP seed`},
+	}
+	for _, tt := range tests {
+		if err := run(tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("run(%q) error = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
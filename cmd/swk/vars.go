@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+)
+
+// templateVars holds the variables literal values of swk new are rendered
+// with. They come from a ConfigMap in the cluster, fetched only once a
+// value needs them, and from --var, which wins.
+type templateVars struct {
+	configMap string
+	overrides map[string]string
+	values    map[string]string
+}
+
+// newTemplateVars returns the variables from configMap, as NAMESPACE/NAME,
+// or the ConfigMap named in the config file, and from KEY=VALUE overrides.
+// It returns nil if there are none, so values are left as they are.
+func newTemplateVars(configMap string, overrides []string) (*templateVars, error) {
+	if configMap == "" {
+		cfg, err := config.LoadDefault()
+		if err != nil {
+			return nil, err
+		}
+		configMap = cfg.Vars.ConfigMap
+	}
+	if configMap == "" && len(overrides) == 0 {
+		return nil, nil
+	}
+
+	v := &templateVars{configMap: configMap, overrides: map[string]string{}}
+	for _, o := range overrides {
+		key, value, ok := strings.Cut(o, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --var %q, expected KEY=VALUE", o)
+		}
+		v.overrides[key] = value
+	}
+	return v, nil
+}
+
+// render expands {{ .key }} references in value. Values without any are
+// returned as they are, without contacting the cluster.
+func (v *templateVars) render(value string) (string, error) {
+	if v == nil || !strings.Contains(value, "{{") {
+		return value, nil
+	}
+	if v.values == nil {
+		if err := v.load(); err != nil {
+			return "", err
+		}
+	}
+
+	tmpl, err := template.New("value").Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", value, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, v.values); err != nil {
+		return "", fmt.Errorf("failed to render %q: %w", value, err)
+	}
+	return out.String(), nil
+}
+
+func (v *templateVars) load() error {
+	v.values = map[string]string{}
+	if v.configMap != "" {
+		namespace, name, ok := strings.Cut(v.configMap, "/")
+		if !ok || namespace == "" || name == "" {
+			return fmt.Errorf("invalid --vars-from %q, expected NAMESPACE/NAME", v.configMap)
+		}
+		data, err := cluster.New(cluster.Options{}).GetConfigMapData(context.Background(), namespace, name)
+		if err != nil {
+			return err
+		}
+		for key, value := range data {
+			v.values[key] = value
+		}
+	}
+	for key, value := range v.overrides {
+		v.values[key] = value
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+)
+
+func TestRunNewTemplateVars(t *testing.T) {
+	bin := t.TempDir()
+	logFile := filepath.Join(bin, "calls.log")
+	cm := `{"kind":"ConfigMap","data":{"domain":"staging.example.com","cluster-name":"stg-1"}}`
+	script := "#!/bin/sh\necho \"$@\" >> " + logFile + "\nprintf '%s' '" + cm + "'\n"
+	if err := os.WriteFile(filepath.Join(bin, "kubectl"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake kubectl: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("vars:\n  configMap: kube-system/swk-vars\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvPath, cfgPath)
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{"config map", []string{"--from-literal", "url=https://api.{{ .domain }}"}, "https://api.staging.example.com", ""},
+		{"dashed key", []string{"--from-literal", `url={{ index . "cluster-name" }}.internal`}, "stg-1.internal", ""},
+		{"override", []string{"--from-literal", "url=https://api.{{ .domain }}", "--var", "domain=local"}, "https://api.local", ""},
+		{"missing", []string{"--from-literal", "url={{ .region }}"}, "", "region"},
+		{"plain", []string{"--from-literal", "url=https://example.com"}, "https://example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "secret.yaml")
+			err := run(append([]string{"new", "api", "-o", out}, tt.args...))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("new error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("new failed: %v", err)
+			}
+			if got := queryFile(t, out, ".data.url | @base64d"); got != tt.want {
+				t.Errorf("url = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	data, _ := os.ReadFile(logFile)
+	if calls := strings.Count(string(data), "\n"); calls != 4 {
+		t.Errorf("kubectl calls = %d, want one per templated run", calls)
+	}
+	if !strings.Contains(string(data), "get configmap swk-vars --namespace kube-system -o json") {
+		t.Errorf("kubectl calls = %q, want the vars ConfigMap", data)
+	}
+}
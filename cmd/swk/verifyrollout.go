@@ -0,0 +1,172 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/cluster"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/usage"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	commands["verify-rollout"] = runVerifyRollout
+}
+
+// Rollout states of a container using a key
+const (
+	rolloutCurrent = "current"
+	rolloutStale   = "stale"
+	rolloutUnknown = "unknown"
+)
+
+// runVerifyRollout handles `swk verify-rollout secret/NAME -n NAMESPACE
+// --key KEY [--no-exec]`, checking whether the running containers that get
+// KEY use its current value, since a Secret that is applied isn't
+// necessarily in use: env variables only change when a container restarts,
+// and files in volumes some time after the change. Values are compared
+// locally and never printed.
+func runVerifyRollout(args []string) error {
+	const usageLine = "usage: swk verify-rollout secret/NAME -n NAMESPACE --key KEY [--no-exec]"
+	var clusterOpts cluster.Options
+	fs := flag.NewFlagSet("swk verify-rollout", flag.ContinueOnError)
+	clusterOpts.BindFlags(fs)
+	key := fs.String("key", "", "Key whose value to look for")
+	noExec := fs.Bool("no-exec", false, "Don't exec into containers; judge by when they started instead")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || clusterOpts.Namespace == "" || *key == "" {
+		return fmt.Errorf(usageLine)
+	}
+	name, ok := secretArg(positional[0])
+	if !ok {
+		return fmt.Errorf(usageLine)
+	}
+	namespace := clusterOpts.Namespace
+	client := cluster.New(clusterOpts)
+	ctx := context.Background()
+
+	manifest, err := client.GetSecretIn(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	value, changed, err := rolloutValue(manifest, *key)
+	if err != nil {
+		return fmt.Errorf("%s/%s: %w", namespace, name, err)
+	}
+
+	pods, err := client.Run(ctx, nil, "get", "pods", "--namespace", namespace, "-o", "json")
+	if err != nil {
+		return fmt.Errorf("failed to list pods in %s: %w", namespace, err)
+	}
+	consumers, err := usage.Consumers(pods, name, *key)
+	if err != nil {
+		return err
+	}
+	if len(consumers) == 0 {
+		fmt.Fprintf(stderr, "No running container in %s gets key %q of %s\n", namespace, *key, name)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "POD\tCONTAINER\tVIA\tSTATE\tDETAIL")
+	stale := 0
+	for _, c := range consumers {
+		state, detail := rolloutState(ctx, client, namespace, c, value, changed, *noExec)
+		if state == rolloutStale {
+			stale++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Pod, c.Container, c.Via(), state, detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if stale > 0 {
+		return fmt.Errorf("%d of %d container(s) still use an old value of %q", stale, len(consumers), *key)
+	}
+	return nil
+}
+
+// rolloutValue returns the decoded value of key in a Secret manifest, and
+// when the Secret last changed: the latest time in its managed fields, or
+// its creation if it has none
+func rolloutValue(manifest []byte, key string) (string, time.Time, error) {
+	var s struct {
+		Metadata struct {
+			CreationTimestamp time.Time `yaml:"creationTimestamp"`
+			ManagedFields     []struct {
+				Time time.Time `yaml:"time"`
+			} `yaml:"managedFields"`
+		} `yaml:"metadata"`
+		Data map[string]string `yaml:"data"`
+	}
+	if err := yaml.Unmarshal(manifest, &s); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to parse secret: %w", err)
+	}
+	encoded, ok := s.Data[key]
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("no key %q", key)
+	}
+	value, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("key %q is not valid base64: %w", key, err)
+	}
+
+	changed := s.Metadata.CreationTimestamp
+	for _, f := range s.Metadata.ManagedFields {
+		if f.Time.After(changed) {
+			changed = f.Time
+		}
+	}
+	return string(value), changed, nil
+}
+
+// rolloutState finds out whether a container uses value, reading the env
+// variable or file in the container unless noExec is set. If that isn't
+// possible, e.g. in images without a shell's tools, the container's start
+// is compared to the Secret's last change.
+func rolloutState(ctx context.Context, client *cluster.Client, namespace string, c usage.Consumer, value string, changed time.Time, noExec bool) (string, string) {
+	if !noExec {
+		command := []string{"cat", c.Path}
+		if c.Env != "" {
+			command = []string{"printenv", c.Env}
+		}
+		args := append([]string{"exec", c.Pod, "--namespace", namespace, "--container", c.Container, "--"}, command...)
+		out, err := client.Run(ctx, nil, args...)
+		if err == nil {
+			got := string(out)
+			if c.Env != "" {
+				got = strings.TrimSuffix(got, "\n")
+			}
+			if got == value {
+				return rolloutCurrent, "value matches"
+			}
+			if c.Env != "" || c.SubPath {
+				return rolloutStale, "value differs; restart the pod to pick up the change"
+			}
+			return rolloutStale, "value differs; the kubelet may not have updated the file yet"
+		}
+	}
+
+	// Env variables and subPath files are read once, when the container
+	// starts; other files follow the Secret
+	started := c.StartedAt.Format(time.RFC3339)
+	switch {
+	case !c.StartedAt.Before(changed):
+		return rolloutCurrent, "started " + started + ", after the last change"
+	case c.Env != "" || c.SubPath:
+		return rolloutStale, "started " + started + ", before the last change"
+	default:
+		return rolloutUnknown, "can't read the file"
+	}
+}
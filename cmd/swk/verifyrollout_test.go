@@ -0,0 +1,83 @@
+//go:build !nocluster
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeRolloutCluster installs a kubectl with a Secret whose password is
+// "new", changed at 12:00, and three pods getting it from env: web-1 reads
+// the new value, web-2 the old one, and web-3 has no printenv
+func fakeRolloutCluster(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	pods := `{"items": [` + rolloutPod("web-1", "11:00") + `,` + rolloutPod("web-2", "11:00") + `,` + rolloutPod("web-3", "13:00") + `]}`
+	if err := os.WriteFile(filepath.Join(dir, "pods.json"), []byte(pods), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := `#!/bin/sh
+while [ "${1#--}" != "$1" ]; do shift 2; done
+case "$1 $2" in
+"get secret")
+  printf 'metadata:\n  creationTimestamp: "2026-10-01T00:00:00Z"\n  managedFields:\n    - time: "2026-10-16T12:00:00Z"\ndata:\n  password: bmV3\n' ;;
+"get pods")
+  cat "` + dir + `/pods.json" ;;
+"exec web-1")
+  echo new ;;
+"exec web-2")
+  echo old ;;
+*)
+  echo "exec failed" >&2; exit 1 ;;
+esac
+`
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "kubectl"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func rolloutPod(name, started string) string {
+	return `{"metadata": {"name": "` + name + `"},
+  "spec": {"containers": [{"name": "app", "env": [{"name": "DB_PASSWORD", "valueFrom": {"secretKeyRef": {"name": "db", "key": "password"}}}]}]},
+  "status": {"containerStatuses": [{"name": "app", "state": {"running": {"startedAt": "2026-10-16T` + started + `:00Z"}}}]}}`
+}
+
+func TestRunVerifyRollout(t *testing.T) {
+	fakeRolloutCluster(t)
+	out := captureStdout(t)
+
+	err := run([]string{"verify-rollout", "secret/db", "-n", "prod", "--key", "password"})
+	if err == nil || !strings.Contains(err.Error(), `1 of 3 container(s) still use an old value of "password"`) {
+		t.Errorf("verify-rollout error = %v, want one stale container", err)
+	}
+	for _, want := range []string{
+		"web-1  app        env DB_PASSWORD  current  value matches",
+		"web-2  app        env DB_PASSWORD  stale    value differs; restart the pod",
+		"web-3  app        env DB_PASSWORD  current  started 2026-10-16T13:00:00Z, after the last change",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out.String(), "new") || strings.Contains(out.String(), "old") {
+		t.Errorf("output shows a value:\n%s", out)
+	}
+
+	out.Reset()
+	err = run([]string{"verify-rollout", "secret/db", "-n", "prod", "--key", "password", "--no-exec"})
+	if err == nil || !strings.Contains(err.Error(), "2 of 3") {
+		t.Errorf("verify-rollout --no-exec error = %v, want the pods started before the change stale", err)
+	}
+
+	if err := run([]string{"verify-rollout", "secret/db", "-n", "prod", "--key", "nope"}); err == nil || !strings.Contains(err.Error(), `no key "nope"`) {
+		t.Errorf("verify-rollout of a missing key error = %v", err)
+	}
+	if err := run([]string{"verify-rollout", "secret/db", "--key", "password"}); err == nil || !strings.Contains(err.Error(), "usage:") {
+		t.Errorf("verify-rollout without a namespace error = %v, want usage", err)
+	}
+}
@@ -2,4 +2,11 @@ module github.com/davidschrooten/secret-wrapper-k8s
 
 go 1.25.5
 
-require gopkg.in/yaml.v3 v3.0.1 // indirect
+require (
+	github.com/BurntSushi/toml v1.6.0
+	golang.org/x/crypto v0.54.0
+	golang.org/x/term v0.45.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.47.0 // indirect
@@ -0,0 +1,138 @@
+// Package approval asks a webhook, such as a chat bot or a change-management
+// system, to approve a change and waits for the answer
+package approval
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/retry"
+)
+
+// DefaultTimeout is how long to wait for an answer if the config sets none
+const DefaultTimeout = 15 * time.Minute
+
+// DefaultInterval is how often the webhook is asked for the status
+const DefaultInterval = 5 * time.Second
+
+// Request describes a change waiting for approval. It never holds values:
+// Diff only names the keys that are added (+), removed (-), or changed (~).
+type Request struct {
+	ID        string    `json:"id"`
+	User      string    `json:"user"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Diff      []string  `json:"diff"`
+	Expires   time.Time `json:"expires"`
+}
+
+// Decision is the webhook's answer about a request
+type Decision struct {
+	Status   string `json:"status"` // pending, approved, or denied
+	Token    string `json:"token"`
+	Approver string `json:"approver"`
+	Reason   string `json:"reason"`
+}
+
+// ErrDenied is returned when the change was denied
+var ErrDenied = errors.New("change denied")
+
+// Client posts requests to a webhook and polls WEBHOOK/ID for decisions
+type Client struct {
+	Webhook  string
+	Interval time.Duration
+	HTTP     *http.Client
+
+	sleep func(context.Context, time.Duration) error
+}
+
+// New returns a client for webhook with the default poll interval
+func New(webhook string) *Client {
+	return &Client{
+		Webhook:  strings.TrimSuffix(webhook, "/"),
+		Interval: DefaultInterval,
+		HTTP:     &http.Client{Timeout: 30 * time.Second},
+		sleep:    retry.Sleep,
+	}
+}
+
+// NewID returns a random request ID
+func NewID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Request posts r and waits until it is approved or denied, or ctx ends.
+// Only an approval with a token is a success; polls that fail are retried
+// until then.
+func (c *Client) Request(ctx context.Context, r Request) (*Decision, error) {
+	body, err := json.Marshal(struct {
+		Event string `json:"event"`
+		Request
+	}{"approval", r})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.do(ctx, http.MethodPost, c.Webhook, body); err != nil {
+		return nil, fmt.Errorf("failed to request approval: %w", err)
+	}
+
+	for {
+		if err := c.sleep(ctx, c.Interval); err != nil {
+			return nil, fmt.Errorf("no approval for %s before the timeout", r.ID)
+		}
+		data, err := c.do(ctx, http.MethodGet, c.Webhook+"/"+r.ID, nil)
+		if err != nil {
+			continue
+		}
+		var d Decision
+		if err := json.Unmarshal(data, &d); err != nil {
+			continue
+		}
+		switch d.Status {
+		case "approved":
+			if d.Token == "" {
+				return nil, fmt.Errorf("approval for %s came without a token", r.ID)
+			}
+			return &d, nil
+		case "denied":
+			if d.Reason != "" {
+				return nil, fmt.Errorf("%w by %s: %s", ErrDenied, d.Approver, d.Reason)
+			}
+			return nil, fmt.Errorf("%w by %s", ErrDenied, d.Approver)
+		}
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, url string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return data, nil
+}
@@ -0,0 +1,89 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/retry"
+)
+
+// fakeWebhook records requests and answers each poll with the next of
+// answers, repeating the last one
+func fakeWebhook(t *testing.T, answers ...string) (*Client, *Request) {
+	t.Helper()
+	var got Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			_ = json.NewDecoder(r.Body).Decode(&got)
+			return
+		}
+		if r.URL.Path != "/hook/"+got.ID {
+			http.NotFound(w, r)
+			return
+		}
+		answer := answers[0]
+		if len(answers) > 1 {
+			answers = answers[1:]
+		}
+		if answer == "" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(answer))
+	}))
+	t.Cleanup(ts.Close)
+
+	c := New(ts.URL + "/hook/")
+	c.sleep = func(ctx context.Context, _ time.Duration) error { return ctx.Err() }
+	return c, &got
+}
+
+func TestRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		answers []string
+		want    string // approver, or an error substring
+	}{
+		{"approved after pending", []string{`{"status":"pending"}`, "", `{"status":"approved","token":"t1","approver":"bob"}`}, "bob"},
+		{"denied", []string{`{"status":"denied","approver":"bob","reason":"not today"}`}, "denied by bob: not today"},
+		{"approved without token", []string{`{"status":"approved"}`}, "without a token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, got := fakeWebhook(t, tt.answers...)
+			d, err := c.Request(context.Background(), Request{ID: "r1", Name: "db", Diff: []string{"~ password"}})
+			if err != nil {
+				if !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("Request() error = %v, want %q", err, tt.want)
+				}
+				return
+			}
+			if d.Approver != tt.want || d.Token != "t1" {
+				t.Errorf("Request() = %+v, want approval by %s", d, tt.want)
+			}
+			if got.ID != "r1" || got.Diff[0] != "~ password" {
+				t.Errorf("webhook got %+v", got)
+			}
+		})
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	c, _ := fakeWebhook(t, `{"status":"pending"}`)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c.sleep = retry.Sleep
+	c.Interval = 10 * time.Millisecond
+
+	_, err := c.Request(ctx, Request{ID: "r1"})
+	if err == nil || !strings.Contains(err.Error(), "before the timeout") || errors.Is(err, ErrDenied) {
+		t.Errorf("Request() error = %v, want a timeout", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Package audit checks every Secret manifest in a repository, keeping a
+// state file of file hashes and findings so later runs only check the files
+// that changed
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/check"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/safefile"
+)
+
+// DefaultStatePath is where `swk audit --state` keeps its state by
+// convention, relative to the repository root
+const DefaultStatePath = ".swk/state.json"
+
+// stateVersion changes whenever the state format does, discarding old states
+const stateVersion = 2
+
+// State holds what the last audit found in each file
+type State struct {
+	Version int                  `json:"version"`
+	Rules   string               `json:"rules"`
+	Files   map[string]FileState `json:"files"`
+
+	// seen records the files checked in this run, see Prune
+	seen map[string]bool
+	opts check.Options
+}
+
+// FileState is a file's hash and the findings for that content
+type FileState struct {
+	SHA256   string          `json:"sha256"`
+	Findings []check.Finding `json:"findings,omitempty"`
+}
+
+// NewState returns an empty state for checks with opts
+func NewState(opts check.Options) *State {
+	return &State{Version: stateVersion, Rules: rulesFingerprint(opts), Files: map[string]FileState{}, seen: map[string]bool{}, opts: opts}
+}
+
+// LoadState reads a state file. A missing file, or one written by another
+// version of swk or for other rules or options, such as another weak list,
+// gives an empty state, so everything is checked again.
+func LoadState(path string, opts check.Options) (*State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewState(opts), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit state: %w", err)
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse audit state %s: %w", path, err)
+	}
+	if s.Version != stateVersion || s.Rules != rulesFingerprint(opts) || s.Files == nil {
+		return NewState(opts), nil
+	}
+	s.seen, s.opts = map[string]bool{}, opts
+	return &s, nil
+}
+
+// Save writes the state, creating its directory if needed
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create audit state directory: %w", err)
+	}
+	if err := safefile.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write audit state: %w", err)
+	}
+	return nil
+}
+
+// Check returns the findings for a file, from the state if the file hasn't
+// changed since it was last checked, and reports whether they were
+func (s *State) Check(file string) ([]check.Finding, bool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read file: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	s.seen[file] = true
+
+	if prev, ok := s.Files[file]; ok && prev.SHA256 == hash {
+		return prev.Findings, true, nil
+	}
+	findings, err := check.Manifest(file, data, s.opts)
+	if err != nil {
+		return nil, false, err
+	}
+	s.Files[file] = FileState{SHA256: hash, Findings: findings}
+	return findings, false, nil
+}
+
+// Prune forgets the files that weren't checked in this run, such as deleted
+// ones
+func (s *State) Prune() {
+	for file := range s.Files {
+		if !s.seen[file] {
+			delete(s.Files, file)
+		}
+	}
+}
+
+// Files lists the YAML files under roots, sorted. Files given directly are
+// included whatever their extension; hidden directories such as .git are
+// skipped.
+func Files(roots []string) ([]string, error) {
+	var files []string
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if path == root {
+				if !d.IsDir() {
+					files = append(files, path)
+				}
+				return nil
+			}
+			if strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if ext := filepath.Ext(path); d.Type().IsRegular() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// rulesFingerprint identifies the rule set and its options, so that a
+// state written before rules were added or changed, or with other options,
+// isn't trusted
+func rulesFingerprint(opts check.Options) string {
+	names := []string{fmt.Sprintf("v%d", check.RulesetVersion)}
+	for _, rule := range check.Rules() {
+		names = append(names, rule.Name)
+	}
+	if fp := opts.Fingerprint(); fp != "" {
+		names = append(names, fp)
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/check"
+)
+
+const placeholderSecret = "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\nstringData:\n  password: <CHANGEME>\n"
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.yaml", "sub/b.yml", "notes.txt", ".git/c.yaml", ".hidden.yaml"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := Files([]string{dir, filepath.Join(dir, "notes.txt")})
+	if err != nil {
+		t.Fatalf("Files() failed: %v", err)
+	}
+	var got []string
+	for _, f := range files {
+		rel, _ := filepath.Rel(dir, f)
+		got = append(got, rel)
+	}
+	if want := "a.yaml,notes.txt,sub/b.yml"; strings.Join(got, ",") != want {
+		t.Errorf("Files() = %v, want %s", got, want)
+	}
+}
+
+func TestStateCheck(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "secret.yaml")
+	gone := filepath.Join(dir, "gone.yaml")
+	for _, f := range []string{file, gone} {
+		if err := os.WriteFile(f, []byte(placeholderSecret), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	statePath := filepath.Join(dir, ".swk", "state.json")
+
+	run := func(files ...string) []bool {
+		t.Helper()
+		state, err := LoadState(statePath, check.Options{})
+		if err != nil {
+			t.Fatalf("LoadState() failed: %v", err)
+		}
+		var hits []bool
+		for _, f := range files {
+			findings, hit, err := state.Check(f)
+			if err != nil {
+				t.Fatalf("Check() failed: %v", err)
+			}
+			if len(findings) != 1 || findings[0].Rule != "placeholder" {
+				t.Errorf("Check(%s) = %v, want the placeholder finding", f, findings)
+			}
+			hits = append(hits, hit)
+		}
+		state.Prune()
+		if err := state.Save(statePath); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+		return hits
+	}
+
+	if hits := run(file, gone); hits[0] || hits[1] {
+		t.Errorf("first run hits = %v, want none", hits)
+	}
+	if hits := run(file); !hits[0] {
+		t.Error("unchanged file was checked again")
+	}
+
+	if err := os.WriteFile(file, []byte(placeholderSecret+"  # edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if hits := run(file); hits[0] {
+		t.Error("changed file was taken from the state")
+	}
+
+	state, err := LoadState(statePath, check.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := state.Files[gone]; ok {
+		t.Error("state still lists a file that wasn't checked")
+	}
+
+	weak, err := check.ParseWeakList(strings.NewReader("5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := LoadState(statePath, check.Options{WeakList: weak}); len(state.Files) != 0 {
+		t.Error("state written without a weak list was trusted with one")
+	}
+
+	state.Rules = "other"
+	if err := state.Save(statePath); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := LoadState(statePath, check.Options{}); len(state.Files) != 0 {
+		t.Error("state for other rules was trusted")
+	}
+}
@@ -0,0 +1,205 @@
+// Package auth authenticates requests to swk's HTTP surfaces, by static
+// token, OIDC ID token, or TLS client certificate, and authorizes them per
+// route
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Identity is an authenticated caller
+type Identity struct {
+	User   string
+	Method string // token, oidc, or cert
+}
+
+// ErrNoCredentials means a request carries no credentials an authenticator
+// recognizes, so the next one in a Chain may try
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator identifies the caller of a request
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// Chain tries each authenticator in turn until one recognizes the request
+type Chain []Authenticator
+
+// Authenticate returns the identity from the first authenticator that
+// recognizes the request's credentials
+func (c Chain) Authenticate(r *http.Request) (*Identity, error) {
+	for _, a := range c {
+		id, err := a.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return id, err
+	}
+	return nil, ErrNoCredentials
+}
+
+// Tokens maps user names to the hex SHA-256 of their static bearer token,
+// so the config never holds a usable token
+type Tokens map[string]string
+
+// Authenticate looks up the request's bearer token
+func (t Tokens) Authenticate(r *http.Request) (*Identity, error) {
+	token, ok := bearer(r)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	got := HashToken(token)
+	for user, want := range t {
+		if subtle.ConstantTimeCompare([]byte(got), []byte(strings.ToLower(want))) == 1 {
+			return &Identity{User: user, Method: "token"}, nil
+		}
+	}
+	return nil, ErrNoCredentials
+}
+
+// HashToken returns the form of token that Tokens holds
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ClientCert identifies callers by the common name of a TLS client
+// certificate the server verified
+type ClientCert struct{}
+
+// Authenticate returns the common name of the verified client certificate
+func (ClientCert) Authenticate(r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, ErrNoCredentials
+	}
+	cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if cn == "" {
+		return nil, errors.New("client certificate has no common name")
+	}
+	return &Identity{User: cn, Method: "cert"}, nil
+}
+
+// Rule allows the requests matching Route, such as "POST /v1/reveals" or
+// "/v1/files", to some users and the members of some teams. Route is a
+// path glob, optionally after a method. The user "*" is every
+// authenticated caller.
+type Rule struct {
+	Route string   `yaml:"route"`
+	Users []string `yaml:"users"`
+	Teams []string `yaml:"teams"`
+}
+
+// match reports whether r is a request the rule is about
+func (rule Rule) match(r *http.Request) bool {
+	method, pattern, ok := strings.Cut(rule.Route, " ")
+	if !ok {
+		method, pattern = "", rule.Route
+	}
+	if method != "" && method != r.Method {
+		return false
+	}
+	matched, _ := path.Match(pattern, r.URL.Path)
+	return matched
+}
+
+// Policy authorizes requests. As with owners, the last matching rule wins;
+// routes no rule matches are refused, so a route the config forgets, or
+// one added in a later version, is never open by accident.
+type Policy struct {
+	// Teams maps team names to members
+	Teams map[string][]string
+	Rules []Rule
+}
+
+// Validate checks that every rule has a valid route and known teams
+func (p Policy) Validate() error {
+	for _, rule := range p.Rules {
+		_, pattern, ok := strings.Cut(rule.Route, " ")
+		if !ok {
+			pattern = rule.Route
+		}
+		if _, err := path.Match(pattern, ""); err != nil || !strings.HasPrefix(pattern, "/") {
+			return fmt.Errorf("invalid route %q", rule.Route)
+		}
+		for _, team := range rule.Teams {
+			if _, ok := p.Teams[team]; !ok {
+				return fmt.Errorf("route %q names unknown team %q", rule.Route, team)
+			}
+		}
+	}
+	return nil
+}
+
+// Allow reports whether id may make request r
+func (p Policy) Allow(id *Identity, r *http.Request) bool {
+	var rule *Rule
+	for i := range p.Rules {
+		if p.Rules[i].match(r) {
+			rule = &p.Rules[i]
+		}
+	}
+	if rule == nil {
+		return false
+	}
+	for _, user := range rule.Users {
+		if user == "*" || strings.EqualFold(user, id.User) {
+			return true
+		}
+	}
+	for _, team := range rule.Teams {
+		for _, member := range p.Teams[team] {
+			if strings.EqualFold(member, id.User) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Middleware refuses requests that authn can't identify with 401, and
+// those policy doesn't allow with 403. The identity of allowed requests is
+// in their context.
+func Middleware(next http.Handler, authn Authenticator, policy Policy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := authn.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="swk"`)
+			msg := "authentication required"
+			if !errors.Is(err, ErrNoCredentials) {
+				msg = "authentication failed: " + err.Error()
+			}
+			http.Error(w, msg, http.StatusUnauthorized)
+			return
+		}
+		if !policy.Allow(id, r) {
+			http.Error(w, fmt.Sprintf("%s may not %s %s", id.User, r.Method, r.URL.Path), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+	})
+}
+
+type identityKey struct{}
+
+// FromContext returns the identity Middleware authenticated, or nil
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+// bearer returns the request's bearer token
+func bearer(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	tokens := Tokens{"ci": HashToken("s3cret")}
+
+	tests := []struct {
+		name     string
+		header   string
+		wantUser string
+		wantErr  error
+	}{
+		{"known", "Bearer s3cret", "ci", nil},
+		{"lower-case scheme", "bearer s3cret", "ci", nil},
+		{"unknown", "Bearer nope", "", ErrNoCredentials},
+		{"basic", "Basic s3cret", "", ErrNoCredentials},
+		{"none", "", "", ErrNoCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/files", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			id, err := tokens.Authenticate(r)
+			if err != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && id.User != tt.wantUser {
+				t.Errorf("user = %q, want %q", id.User, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestClientCert(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/files", nil)
+	if _, err := (ClientCert{}).Authenticate(r); err != ErrNoCredentials {
+		t.Errorf("without TLS error = %v, want ErrNoCredentials", err)
+	}
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "editor-bot"}}
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	id, err := (ClientCert{}).Authenticate(r)
+	if err != nil || id.User != "editor-bot" {
+		t.Errorf("Authenticate() = %v, %v, want editor-bot", id, err)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	p := Policy{
+		Teams: map[string][]string{"sre": {"bob@example.com"}},
+		Rules: []Rule{
+			{Route: "/v1/*", Users: []string{"ci"}, Teams: []string{"sre"}},
+			{Route: "POST /v1/reveals", Teams: []string{"sre"}},
+			{Route: "GET /v1/queue", Users: []string{"*"}},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		user   string
+		method string
+		path   string
+		want   bool
+	}{
+		{"listed user", "ci", http.MethodGet, "/v1/files", true},
+		{"team member", "Bob@example.com", http.MethodGet, "/v1/files", true},
+		{"outsider", "eve", http.MethodGet, "/v1/files", false},
+		{"later rule wins", "ci", http.MethodPost, "/v1/reveals", false},
+		{"later rule team", "bob@example.com", http.MethodPost, "/v1/reveals", true},
+		{"other method", "ci", http.MethodGet, "/v1/reveals", true},
+		{"any user", "eve", http.MethodGet, "/v1/queue", true},
+		{"no rule", "ci", http.MethodGet, "/healthz", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if got := p.Allow(&Identity{User: tt.user}, r); got != tt.want {
+				t.Errorf("Allow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"relative", Rule{Route: "v1/files"}},
+		{"bad glob", Rule{Route: "GET /v1/["}},
+		{"unknown team", Rule{Route: "/v1/files", Teams: []string{"ops"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (Policy{Rules: []Rule{tt.rule}}).Validate(); err == nil {
+				t.Error("Validate() should fail")
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var seen *Identity
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+	}), Chain{Tokens{"ci": HashToken("a"), "eve": HashToken("b")}}, Policy{
+		Rules: []Rule{{Route: "/v1/files", Users: []string{"ci"}}},
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"allowed", "Bearer a", http.StatusOK},
+		{"forbidden", "Bearer b", http.StatusForbidden},
+		{"unknown token", "Bearer c", http.StatusUnauthorized},
+		{"no token", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = nil
+			r := httptest.NewRequest(http.MethodGet, "/v1/files", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusOK && (seen == nil || seen.User != "ci") {
+				t.Errorf("identity = %v, want ci", seen)
+			}
+		})
+	}
+}
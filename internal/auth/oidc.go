@@ -0,0 +1,340 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/retry"
+)
+
+// DefaultUsernameClaim is the ID token claim that names the user
+const DefaultUsernameClaim = "email"
+
+// leeway allows for clock skew when checking exp and nbf
+const leeway = time.Minute
+
+// OIDC verifies ID tokens signed by an OpenID Connect provider, such as
+// those swk login gets with the device flow
+type OIDC struct {
+	Issuer        string
+	ClientID      string
+	UsernameClaim string
+	HTTP          *http.Client
+
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      map[string]*rsa.PublicKey
+	fetched   time.Time
+}
+
+// discovery is the part of the provider's metadata swk uses
+type discovery struct {
+	Issuer                      string `json:"issuer"`
+	JWKSURI                     string `json:"jwks_uri"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+}
+
+// NewOIDC returns a verifier for ID tokens issued by issuer for clientID
+func NewOIDC(issuer, clientID, usernameClaim string) *OIDC {
+	if usernameClaim == "" {
+		usernameClaim = DefaultUsernameClaim
+	}
+	return &OIDC{
+		Issuer:        strings.TrimSuffix(issuer, "/"),
+		ClientID:      clientID,
+		UsernameClaim: usernameClaim,
+		HTTP:          &http.Client{Timeout: 30 * time.Second},
+		now:           time.Now,
+		sleep:         retry.Sleep,
+	}
+}
+
+// Authenticate verifies the request's bearer token as an ID token
+func (o *OIDC) Authenticate(r *http.Request) (*Identity, error) {
+	token, ok := bearer(r)
+	if !ok || strings.Count(token, ".") != 2 {
+		return nil, ErrNoCredentials
+	}
+	user, err := o.Verify(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{User: user, Method: "oidc"}, nil
+}
+
+// Verify checks an RS256 ID token's signature, issuer, audience, and
+// lifetime, and returns its username claim
+func (o *OIDC) Verify(ctx context.Context, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed ID token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", err
+	}
+	if header.Alg != "RS256" {
+		return "", fmt.Errorf("unsupported ID token algorithm %q", header.Alg)
+	}
+	key, err := o.key(ctx, header.Kid)
+	if err != nil {
+		return "", err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("malformed ID token signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return "", errors.New("invalid ID token signature")
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", err
+	}
+	if claims["iss"] != o.Issuer {
+		return "", fmt.Errorf("ID token issued by %v, want %s", claims["iss"], o.Issuer)
+	}
+	if !audience(claims["aud"], o.ClientID) {
+		return "", fmt.Errorf("ID token is not meant for %s", o.ClientID)
+	}
+	now := o.now()
+	if exp, ok := claims["exp"].(float64); !ok || now.After(time.Unix(int64(exp), 0).Add(leeway)) {
+		return "", errors.New("ID token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(leeway).Before(time.Unix(int64(nbf), 0)) {
+		return "", errors.New("ID token not valid yet")
+	}
+	user, _ := claims[o.UsernameClaim].(string)
+	if user == "" {
+		return "", fmt.Errorf("ID token has no %s claim", o.UsernameClaim)
+	}
+	return user, nil
+}
+
+// key returns the signing key kid, fetching the provider's keys again if
+// it is unknown, at most once a minute
+func (o *OIDC) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if key, ok := o.keys[kid]; ok {
+		return key, nil
+	}
+	if o.keys != nil && o.now().Sub(o.fetched) < time.Minute {
+		return nil, fmt.Errorf("unknown ID token key %q", kid)
+	}
+
+	d, err := o.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := o.getJSON(ctx, d.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+	o.keys = map[string]*rsa.PublicKey{}
+	o.fetched = o.now()
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) > 4 {
+			continue
+		}
+		o.keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	key, ok := o.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown ID token key %q", kid)
+	}
+	return key, nil
+}
+
+// discover fetches the provider's metadata once; the caller holds o.mu
+func (o *OIDC) discover(ctx context.Context) (*discovery, error) {
+	if o.discovery != nil {
+		return o.discovery, nil
+	}
+	var d discovery
+	if err := o.getJSON(ctx, o.Issuer+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, err
+	}
+	if d.Issuer != o.Issuer {
+		return nil, fmt.Errorf("OIDC provider calls itself %s, want %s", d.Issuer, o.Issuer)
+	}
+	o.discovery = &d
+	return &d, nil
+}
+
+// DeviceLogin signs the user in with the OAuth device flow and returns
+// their ID token. prompt is called with the URL to open and the code to
+// enter there.
+func (o *OIDC) DeviceLogin(ctx context.Context, prompt func(uri, code string)) (string, error) {
+	o.mu.Lock()
+	d, err := o.discover(ctx)
+	o.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	if d.DeviceAuthorizationEndpoint == "" {
+		return "", fmt.Errorf("%s doesn't support the device flow", o.Issuer)
+	}
+
+	var auth struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}
+	form := url.Values{"client_id": {o.ClientID}, "scope": {"openid email profile"}}
+	if _, err := o.postForm(ctx, d.DeviceAuthorizationEndpoint, form, &auth); err != nil {
+		return "", err
+	}
+	uri := auth.VerificationURIComplete
+	if uri == "" {
+		uri = auth.VerificationURI
+	}
+	prompt(uri, auth.UserCode)
+
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	if auth.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(auth.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+	form = url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {auth.DeviceCode},
+		"client_id":   {o.ClientID},
+	}
+	for {
+		if err := o.sleep(ctx, interval); err != nil {
+			return "", errors.New("the device code expired before sign-in finished")
+		}
+		var tok struct {
+			IDToken string `json:"id_token"`
+			Error   string `json:"error"`
+		}
+		if _, err := o.postForm(ctx, d.TokenEndpoint, form, &tok); err != nil && tok.Error == "" {
+			return "", err
+		}
+		switch tok.Error {
+		case "":
+			if tok.IDToken == "" {
+				return "", errors.New("the token response has no ID token")
+			}
+			return tok.IDToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return "", fmt.Errorf("sign-in failed: %s", tok.Error)
+		}
+	}
+}
+
+func (o *OIDC) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	status, err := o.send(req, v)
+	if err == nil && status/100 != 2 {
+		err = fmt.Errorf("%s returned %d", u, status)
+	}
+	return err
+}
+
+// postForm posts form to u and decodes the JSON answer into v, also for
+// error statuses, which carry OAuth errors
+func (o *OIDC) postForm(ctx context.Context, u string, form url.Values, v any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	status, err := o.send(req, v)
+	if err == nil && status/100 != 2 {
+		err = fmt.Errorf("%s returned %d", u, status)
+	}
+	return status, err
+}
+
+func (o *OIDC) send(req *http.Request, v any) (int, error) {
+	req.Header.Set("Accept", "application/json")
+	resp, err := o.HTTP.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(data, v); err != nil && resp.StatusCode/100 == 2 {
+		return 0, fmt.Errorf("failed to parse response from %s: %w", req.URL.Host, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// decodeSegment decodes a base64url JSON part of a JWT
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err == nil {
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		return errors.New("malformed ID token")
+	}
+	return nil
+}
+
+// audience reports whether the aud claim, a string or a list, names client
+func audience(aud any, client string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == client
+	case []any:
+		for _, a := range aud {
+			if a == client {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testProvider is an OIDC provider that signs with key and finishes the
+// device flow on the second poll
+func testProvider(t *testing.T, key *rsa.PrivateKey, idToken *string) *httptest.Server {
+	t.Helper()
+	polls := 0
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"issuer":                        ts.URL,
+				"jwks_uri":                      ts.URL + "/keys",
+				"token_endpoint":                ts.URL + "/token",
+				"device_authorization_endpoint": ts.URL + "/device",
+			})
+		case "/keys":
+			_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		case "/device":
+			_, _ = fmt.Fprint(w, `{"device_code":"dc","user_code":"ABCD-EFGH","verification_uri":"https://example.com/device","interval":1}`)
+		case "/token":
+			if r.FormValue("device_code") != "dc" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprint(w, `{"error":"invalid_grant"}`)
+				return
+			}
+			if polls++; polls < 2 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprint(w, `{"error":"authorization_pending"}`)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"id_token": *idToken})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestOIDCVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := testProvider(t, key, new(string))
+	now := time.Unix(1700000000, 0)
+	valid := func() map[string]any {
+		return map[string]any{"iss": ts.URL, "aud": "swk", "exp": now.Add(time.Hour).Unix(), "email": "alice@example.com"}
+	}
+	with := func(k string, v any) map[string]any {
+		c := valid()
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"valid", signToken(t, key, "k1", valid()), ""},
+		{"audience list", signToken(t, key, "k1", with("aud", []string{"other", "swk"})), ""},
+		{"wrong audience", signToken(t, key, "k1", with("aud", "other")), "not meant for"},
+		{"wrong issuer", signToken(t, key, "k1", with("iss", "https://evil.example.com")), "issued by"},
+		{"expired", signToken(t, key, "k1", with("exp", now.Add(-time.Hour).Unix())), "expired"},
+		{"not yet valid", signToken(t, key, "k1", with("nbf", now.Add(time.Hour).Unix())), "not valid yet"},
+		{"no email", signToken(t, key, "k1", with("email", nil)), "no email claim"},
+		{"wrong key", signToken(t, other, "k1", valid()), "signature"},
+		{"unknown key", signToken(t, key, "k2", valid()), "unknown ID token key"},
+		{"malformed", "a.b.c", "malformed"},
+	}
+
+	o := NewOIDC(ts.URL, "swk", "")
+	o.now = func() time.Time { return now }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := o.Verify(context.Background(), tt.token)
+			if tt.wantErr == "" {
+				if err != nil || user != "alice@example.com" {
+					t.Errorf("Verify() = %q, %v, want alice@example.com", user, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeviceLogin(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idToken := "header.payload.signature"
+	ts := testProvider(t, key, &idToken)
+
+	o := NewOIDC(ts.URL, "swk", "")
+	o.sleep = func(context.Context, time.Duration) error { return nil }
+	var code string
+	got, err := o.DeviceLogin(context.Background(), func(uri, c string) { code = c })
+	if err != nil {
+		t.Fatalf("DeviceLogin() error = %v", err)
+	}
+	if got != idToken || code != "ABCD-EFGH" {
+		t.Errorf("DeviceLogin() = %q with code %q, want the ID token and ABCD-EFGH", got, code)
+	}
+}
@@ -0,0 +1,168 @@
+// Package bundle writes and verifies offline bundles: a tarball with the
+// swk binary and everything it would otherwise fetch, for machines without
+// network access
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SumsName is the checksum file of a bundle, in the format of sha256sum,
+// so it can be checked without swk too
+const SumsName = "SHA256SUMS"
+
+// PolicySample is a starting config with constraints, owners, and a
+// production profile
+//
+//go:embed policy.sample.yaml
+var PolicySample []byte
+
+// File is a file in a bundle
+type File struct {
+	// Name is the path of the file below the bundle's root directory
+	Name string
+	Mode int64
+	Data []byte
+}
+
+// Write writes files as a gzipped tarball below the directory root, with
+// a SHA256SUMS file listing them
+func Write(w io.Writer, root string, files []File) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	// Bundles built from the same files are the same
+	modTime := time.Unix(0, 0)
+
+	var sums bytes.Buffer
+	for _, f := range files {
+		sum := sha256.Sum256(f.Data)
+		fmt.Fprintf(&sums, "%s  %s\n", hex.EncodeToString(sum[:]), f.Name)
+	}
+	all := append(slices.Clone(files), File{Name: SumsName, Mode: 0644, Data: sums.Bytes()})
+
+	dirs := map[string]bool{}
+	for _, f := range all {
+		name := path.Join(root, f.Name)
+		for dir := path.Dir(name); dir != "." && !dirs[dir]; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	slices.Sort(sorted)
+	for _, dir := range sorted {
+		hdr := &tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0755, ModTime: modTime}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+
+	for _, f := range all {
+		hdr := &tar.Header{Typeflag: tar.TypeReg, Name: path.Join(root, f.Name), Mode: f.Mode, Size: int64(len(f.Data)), ModTime: modTime}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+		if _, err := tw.Write(f.Data); err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return gz.Close()
+}
+
+// Verify reads a bundle and checks every file against its SHA256SUMS,
+// returning the names checked. Files missing from the bundle, files the
+// sums don't list, and files that don't match are all errors.
+func Verify(r io.Reader) ([]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	tr := tar.NewReader(gz)
+	actual := map[string]string{}
+	var sums []byte
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		// Names are checked below the root directory
+		_, name, ok := strings.Cut(hdr.Name, "/")
+		if !ok {
+			name = hdr.Name
+		}
+		h := sha256.New()
+		var buf bytes.Buffer
+		out := io.Writer(h)
+		if name == SumsName {
+			out = io.MultiWriter(h, &buf)
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		if name == SumsName {
+			sums = buf.Bytes()
+			continue
+		}
+		actual[name] = hex.EncodeToString(h.Sum(nil))
+	}
+	if sums == nil {
+		return nil, fmt.Errorf("bundle has no %s", SumsName)
+	}
+
+	var problems []string
+	listed := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		sum, name, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok {
+			return nil, fmt.Errorf("malformed %s line: %q", SumsName, scanner.Text())
+		}
+		listed[name] = true
+		got, ok := actual[name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s: missing", name))
+		case got != sum:
+			problems = append(problems, fmt.Sprintf("%s: checksum mismatch", name))
+		}
+	}
+	var names []string
+	for name := range actual {
+		if !listed[name] {
+			problems = append(problems, fmt.Sprintf("%s: not in %s", name, SumsName))
+		}
+		names = append(names, name)
+	}
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return nil, fmt.Errorf("bundle failed verification:\n  %s", strings.Join(problems, "\n  "))
+	}
+	slices.Sort(names)
+	return names, nil
+}
@@ -0,0 +1,134 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/config"
+)
+
+var testFiles = []File{
+	{Name: "bin/swk", Mode: 0755, Data: []byte("binary")},
+	{Name: "schemas/v1.json", Mode: 0644, Data: []byte("{}")},
+}
+
+func TestWriteVerify(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "swk-offline", testFiles); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	names, err := Verify(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+	if want := []string{"bin/swk", "schemas/v1.json"}; !slices.Equal(names, want) {
+		t.Errorf("Verify() = %v, want %v", names, want)
+	}
+
+	var again bytes.Buffer
+	if err := Write(&again, "swk-offline", testFiles); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Error("Write() isn't reproducible")
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(name string, data []byte) (string, []byte, bool)
+		wantErr string
+	}{
+		{"changed file", func(name string, data []byte) (string, []byte, bool) {
+			if strings.HasSuffix(name, "bin/swk") {
+				return name, []byte("evil"), true
+			}
+			return name, data, true
+		}, "bin/swk: checksum mismatch"},
+		{"missing file", func(name string, data []byte) (string, []byte, bool) {
+			return name, data, !strings.HasSuffix(name, "v1.json")
+		}, "schemas/v1.json: missing"},
+		{"unlisted file", func(name string, data []byte) (string, []byte, bool) {
+			if strings.HasSuffix(name, "v1.json") {
+				return name + ".bak", data, true
+			}
+			return name, data, true
+		}, "schemas/v1.json.bak: not in SHA256SUMS"},
+		{"no sums", func(name string, data []byte) (string, []byte, bool) {
+			return name, data, !strings.HasSuffix(name, SumsName)
+		}, "bundle has no SHA256SUMS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, "swk-offline", testFiles); err != nil {
+				t.Fatal(err)
+			}
+			tampered := rewrite(t, buf.Bytes(), tt.modify)
+			if _, err := Verify(bytes.NewReader(tampered)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// rewrite copies a bundle, passing each regular file through modify
+func rewrite(t *testing.T, bundle []byte, modify func(string, []byte) (string, []byte, bool)) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(bundle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var out bytes.Buffer
+	gw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gw)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		var data bytes.Buffer
+		if _, err := data.ReadFrom(tr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			name, content, keep := modify(hdr.Name, data.Bytes())
+			if !keep {
+				continue
+			}
+			hdr.Name, hdr.Size = name, int64(len(content))
+			data.Reset()
+			data.Write(content)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestPolicySample(t *testing.T) {
+	cfg, err := config.Parse(PolicySample, "policy.sample.yaml")
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if len(cfg.Constraints) == 0 || len(cfg.Owners) == 0 || len(cfg.Profiles) == 0 {
+		t.Errorf("Parse() = %+v, want constraints, owners, and profiles", cfg)
+	}
+}
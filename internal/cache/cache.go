@@ -0,0 +1,136 @@
+// Package cache stores small JSON documents on disk, encrypted and bound to a
+// time-to-live. It is meant for cluster metadata, never Secret values.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/sealed"
+)
+
+// DefaultTTL is how long entries stay fresh
+const DefaultTTL = 5 * time.Minute
+
+const keyFile = "cache.key"
+
+// Cache is a directory of encrypted entries. The key is generated on first
+// use and kept next to them, readable only by the user, so the entries are
+// useless if copied elsewhere on their own.
+type Cache struct {
+	Dir string
+	TTL time.Duration
+
+	box *sealed.Box
+	now func() time.Time
+}
+
+// entry is the plaintext of a cache file
+type entry struct {
+	Stored time.Time       `json:"stored"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// DefaultDir returns the cache directory in the user's cache dir
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "swk", "cluster"), nil
+}
+
+// Open opens or creates a cache in dir
+func Open(dir string, ttl time.Duration) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// A missing or damaged key is replaced, which invalidates every
+	// existing entry
+	box, err := sealed.OpenReplacing(filepath.Join(dir, keyFile), "cache")
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{Dir: dir, TTL: ttl, box: box, now: time.Now}, nil
+}
+
+// Get decodes the entry for name into v. It reports false for missing,
+// expired, or unreadable entries.
+func (c *Cache) Get(name string, v any) bool {
+	data, err := os.ReadFile(c.path(name))
+	if err != nil {
+		return false
+	}
+	plaintext, err := c.box.Open(data, []byte(name))
+	if err != nil {
+		return false
+	}
+
+	var e entry
+	if err := json.Unmarshal(plaintext, &e); err != nil {
+		return false
+	}
+	if c.TTL > 0 && c.now().Sub(e.Stored) > c.TTL {
+		return false
+	}
+	return json.Unmarshal(e.Data, v) == nil
+}
+
+// Put stores v as the entry for name
+func (c *Cache) Put(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(entry{Stored: c.now(), Data: data})
+	if err != nil {
+		return err
+	}
+
+	ciphertext, err := c.box.Seal(plaintext, []byte(name))
+	if err != nil {
+		return err
+	}
+
+	// Write and rename so readers never see a partial entry
+	tmp, err := os.CreateTemp(c.Dir, ".entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(ciphertext); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(name)); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the entry for name
+func (c *Cache) Delete(name string) error {
+	if err := os.Remove(c.path(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path names entry files by a hash, so file names don't reveal cluster or
+// namespace names either
+func (c *Cache) path(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".bin")
+}
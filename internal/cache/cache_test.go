@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPutGet(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, time.Minute)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+
+	if err := c.Put("secrets", []string{"db-credentials"}); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+	var got []string
+	if !c.Get("secrets", &got) || len(got) != 1 || got[0] != "db-credentials" {
+		t.Fatalf("Get() = %v", got)
+	}
+	if c.Get("other", &got) {
+		t.Error("Get() should miss for an unknown name")
+	}
+
+	// Entries are encrypted
+	data, err := os.ReadFile(c.path("secrets"))
+	if err != nil {
+		t.Fatalf("Failed to read entry: %v", err)
+	}
+	if bytes.Contains(data, []byte("db-credentials")) {
+		t.Error("cache entry is stored in plaintext")
+	}
+
+	// A reopened cache shares the key
+	reopened, err := Open(dir, time.Minute)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if !reopened.Get("secrets", &got) {
+		t.Error("Get() should hit after reopening")
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	c, err := Open(t.TempDir(), time.Minute)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	if err := c.Put("secrets", 1); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+	var got int
+	now = now.Add(59 * time.Second)
+	if !c.Get("secrets", &got) {
+		t.Error("Get() should hit within the TTL")
+	}
+	now = now.Add(2 * time.Second)
+	if c.Get("secrets", &got) {
+		t.Error("Get() should miss after the TTL")
+	}
+}
+
+func TestInvalidEntries(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, time.Minute)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if err := c.Put("secrets", 1); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+
+	// A new key makes old entries unreadable
+	if err := os.WriteFile(filepath.Join(dir, keyFile), []byte("short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	rekeyed, err := Open(dir, time.Minute)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	var got int
+	if rekeyed.Get("secrets", &got) {
+		t.Error("Get() should miss for entries sealed with another key")
+	}
+
+	if err := os.WriteFile(c.path("junk"), []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if rekeyed.Get("junk", &got) {
+		t.Error("Get() should miss for a truncated entry")
+	}
+
+	if err := rekeyed.Delete("secrets"); err != nil {
+		t.Errorf("Delete() failed: %v", err)
+	}
+	if err := rekeyed.Delete("secrets"); err != nil {
+		t.Errorf("Delete() of a missing entry failed: %v", err)
+	}
+}
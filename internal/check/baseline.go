@@ -0,0 +1,154 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/safefile"
+)
+
+// RulesetVersion changes whenever a rule starts finding different problems
+// than it did, so that baselines and audit states recorded with the old
+// rules can be recognized
+const RulesetVersion = 1
+
+// baselineVersion changes whenever the baseline format does
+const baselineVersion = 1
+
+// Baseline records known findings, so that a repository can adopt strict
+// checks and fail only on new problems while it works through the old
+// ones. Findings are matched by file, Secret, key, and rule, not by line
+// or message, so edits elsewhere in a file don't make them new.
+type Baseline struct {
+	Version int             `json:"version"`
+	Ruleset int             `json:"ruleset"`
+	Entries []BaselineEntry `json:"findings"`
+
+	// remaining counts the entries not matched yet, by entry without Count
+	remaining map[BaselineEntry]int
+	known     int
+}
+
+// BaselineEntry is a known finding, with how often it occurs
+type BaselineEntry struct {
+	File   string `json:"file"`
+	Secret string `json:"secret"`
+	Key    string `json:"key,omitempty"`
+	Rule   string `json:"rule"`
+	Count  int    `json:"count,omitempty"`
+}
+
+// NewBaseline records findings as known
+func NewBaseline(findings []Finding) *Baseline {
+	counts := map[BaselineEntry]int{}
+	for _, f := range findings {
+		counts[baselineEntry(f)]++
+	}
+	b := &Baseline{Version: baselineVersion, Ruleset: RulesetVersion, Entries: []BaselineEntry{}}
+	for e, n := range counts {
+		if n > 1 {
+			e.Count = n
+		}
+		b.Entries = append(b.Entries, e)
+	}
+	sort.Slice(b.Entries, func(i, j int) bool {
+		a, c := b.Entries[i], b.Entries[j]
+		if a.File != c.File {
+			return a.File < c.File
+		}
+		if a.Secret != c.Secret {
+			return a.Secret < c.Secret
+		}
+		if a.Key != c.Key {
+			return a.Key < c.Key
+		}
+		return a.Rule < c.Rule
+	})
+	b.index()
+	return b
+}
+
+// LoadBaseline reads a baseline file written by Save
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	if b.Version != baselineVersion {
+		return nil, fmt.Errorf("baseline %s has version %d, but this swk reads version %d; record it again with --update-baseline", path, b.Version, baselineVersion)
+	}
+	b.index()
+	return &b, nil
+}
+
+// Save writes the baseline, creating its directory if needed
+func (b *Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create baseline directory: %w", err)
+	}
+	if err := safefile.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+// Filter returns the findings the baseline doesn't know. Each entry
+// matches as many findings as it occurred, so a second copy of a known
+// problem is new. A nil baseline knows nothing.
+func (b *Baseline) Filter(findings []Finding) []Finding {
+	if b == nil {
+		return findings
+	}
+	var fresh []Finding
+	for _, f := range findings {
+		e := baselineEntry(f)
+		if b.remaining[e] > 0 {
+			b.remaining[e]--
+			b.known++
+			continue
+		}
+		fresh = append(fresh, f)
+	}
+	return fresh
+}
+
+// Known returns how many findings Filter has matched so far
+func (b *Baseline) Known() int {
+	return b.known
+}
+
+// Fixed returns how many recorded findings Filter hasn't matched: fixed
+// since the baseline was recorded, or in files that weren't checked
+func (b *Baseline) Fixed() int {
+	n := 0
+	for _, left := range b.remaining {
+		n += left
+	}
+	return n
+}
+
+// index counts the entries for Filter
+func (b *Baseline) index() {
+	b.remaining = map[BaselineEntry]int{}
+	for _, e := range b.Entries {
+		n := max(e.Count, 1)
+		e.Count = 0
+		b.remaining[e] += n
+	}
+}
+
+// baselineEntry identifies a finding in a baseline
+func baselineEntry(f Finding) BaselineEntry {
+	return BaselineEntry{File: filepath.ToSlash(f.File), Secret: f.Secret, Key: f.Key, Rule: f.Rule}
+}
@@ -0,0 +1,52 @@
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	placeholder := Finding{File: "db.yaml", Line: 6, Secret: "prod/db", Key: "password", Rule: "placeholder", Severity: Error}
+	known := []Finding{placeholder, placeholder, {File: "api.yaml", Line: 3, Secret: "api", Rule: "breakglass", Severity: Warning}}
+
+	path := filepath.Join(t.TempDir(), ".swk", "baseline.json")
+	if err := NewBaseline(known).Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	b, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatalf("LoadBaseline() error = %v", err)
+	}
+	if b.Ruleset != RulesetVersion || len(b.Entries) != 2 {
+		t.Errorf("LoadBaseline() = %+v", b)
+	}
+
+	// Moved down a line, a third copy of the placeholder, and a new key
+	moved := placeholder
+	moved.Line = 9
+	other := placeholder
+	other.Key = "token"
+	fresh := b.Filter([]Finding{moved, moved, moved, other})
+	if len(fresh) != 2 || fresh[0] != moved || fresh[1] != other {
+		t.Errorf("Filter() = %+v, want the third copy and the new key", fresh)
+	}
+	if b.Known() != 2 || b.Fixed() != 1 {
+		t.Errorf("Known() = %d, Fixed() = %d, want 2 and 1", b.Known(), b.Fixed())
+	}
+
+	var none *Baseline
+	if got := none.Filter(known); len(got) != len(known) {
+		t.Errorf("nil Filter() = %+v, want every finding", got)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 99, "findings": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBaseline(path); err == nil {
+		t.Error("LoadBaseline() of another version should fail")
+	}
+	if _, err := LoadBaseline(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadBaseline() of a missing file should fail")
+	}
+}
@@ -0,0 +1,275 @@
+// Package check lints Secret manifests for problems such as undecodable
+// values and leftover placeholders
+package check
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+	"gopkg.in/yaml.v3"
+)
+
+// Placeholder marks values that still need to be filled in
+const Placeholder = "<CHANGEME>"
+
+// Severity of a finding; errors fail `swk check`, warnings do not
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+// Finding is a problem found in a manifest
+type Finding struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line,omitempty"`
+	Secret   string   `json:"secret"` // namespace/name
+	Key      string   `json:"key,omitempty"`
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// String formats a finding as `file:line: severity [rule] secret key: message`
+func (f Finding) String() string {
+	loc := f.File
+	if f.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	subject := f.Secret
+	if f.Key != "" {
+		subject += " " + f.Key
+	}
+	return fmt.Sprintf("%s: %s [%s] %s: %s", loc, f.Severity, f.Rule, subject, f.Message)
+}
+
+// Value is a data or stringData entry of a Secret
+type Value struct {
+	Key   string
+	Value string // decoded
+	Line  int
+	Raw   string // as written, base64 for data entries
+	Plain bool   // from stringData
+}
+
+// Document is a Secret being checked
+type Document struct {
+	File      string
+	Name      string
+	Namespace string
+	Type      string
+	Line      int
+	Values    []Value
+	Root      *yaml.Node
+
+	findings []Finding
+	weakList *WeakList
+}
+
+// Report records a finding for a key of the document ("" for the whole
+// Secret)
+func (d *Document) Report(rule string, severity Severity, key string, line int, format string, args ...any) {
+	if line == 0 {
+		line = d.Line
+	}
+	id := d.Name
+	if d.Namespace != "" {
+		id = d.Namespace + "/" + d.Name
+	}
+	d.findings = append(d.findings, Finding{
+		File:     d.File,
+		Line:     line,
+		Secret:   id,
+		Key:      key,
+		Rule:     rule,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Rule checks a single aspect of a Secret
+type Rule struct {
+	Name        string
+	Description string
+	Check       func(d *Document)
+}
+
+// rules are run in order on every Secret. The base64, canonical, and
+// duplicate rules are applied while loading, since the other rules work on decoded values.
+var rules = []Rule{
+	{"base64", "Data values that are not valid base64", nil},
+	{"canonical", "Data values whose base64 is not in canonical form", nil},
+	{"duplicate", "Keys given twice in the Secret, its metadata, or its data", nil},
+	{"placeholder", "Values still set to the " + Placeholder + " placeholder", checkPlaceholder},
+	{"breakglass", "Break-glass edits that still need a review", checkBreakGlass},
+	{"weak", "Values whose hashes are on the --weak-list of banned or breached values", checkWeak},
+}
+
+// Rules returns the available rules
+func Rules() []Rule {
+	return append([]Rule{}, rules...)
+}
+
+// Options are the inputs some rules need besides the manifest
+type Options struct {
+	// WeakList is checked by the weak rule, which is skipped without one
+	WeakList *WeakList
+}
+
+// Fingerprint identifies the options, so that findings made with other
+// ones aren't reused
+func (o Options) Fingerprint() string {
+	if o.WeakList == nil {
+		return ""
+	}
+	return "weak-list:" + o.WeakList.Digest()
+}
+
+// Manifest checks every Secret in a (possibly multi-document) manifest.
+// Other resources are skipped.
+func Manifest(file string, data []byte, opts Options) ([]Finding, error) {
+	var findings []Finding
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to parse YAML: %w", file, err)
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+
+		d, ok := load(file, doc.Content[0])
+		if !ok {
+			continue
+		}
+		d.weakList = opts.WeakList
+		for _, rule := range rules {
+			if rule.Check != nil {
+				rule.Check(d)
+			}
+		}
+		findings = append(findings, d.findings...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Line < findings[j].Line })
+	return findings, nil
+}
+
+// HasErrors reports whether any finding is an error
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// load reads a Secret's metadata and values, reporting undecodable and
+// non-canonical values and duplicate keys
+func load(file string, root *yaml.Node) (*Document, bool) {
+	version, ok := secret.ResolveVersion(scalar(root, "apiVersion"), scalar(root, "kind"))
+	if !ok {
+		return nil, false
+	}
+
+	d := &Document{File: file, Type: scalar(root, "type"), Line: root.Line, Root: root}
+	if metadata := field(root, "metadata"); metadata != nil {
+		d.Name = scalar(metadata, "name")
+		d.Namespace = scalar(metadata, "namespace")
+	}
+	for _, dup := range secret.Duplicates(root, version) {
+		where := dup.Mapping
+		if where == "" {
+			where = "the Secret"
+		}
+		d.Report("duplicate", Error, dup.Key, dup.Line, "key is also set on line %d of %s; only one value is kept", dup.FirstLine, where)
+	}
+
+	if data := field(root, version.DataField); data != nil && data.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(data.Content); i += 2 {
+			key, value := data.Content[i], data.Content[i+1]
+			canonical, problems, err := secret.CanonicalBase64(value.Value)
+			if err != nil {
+				d.Report("base64", Error, key.Value, key.Line, "value is not valid base64")
+				continue
+			}
+			if len(problems) > 0 {
+				d.Report("canonical", Warning, key.Value, key.Line, "base64 is not canonical (%s); swk fmt --normalize-base64 rewrites it", strings.Join(problems, ", "))
+			}
+			decoded, _ := base64.StdEncoding.DecodeString(canonical)
+			d.Values = append(d.Values, Value{Key: key.Value, Value: string(decoded), Line: key.Line, Raw: value.Value})
+		}
+	}
+	if stringData := field(root, version.StringDataField); stringData != nil && stringData.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(stringData.Content); i += 2 {
+			key, value := stringData.Content[i], stringData.Content[i+1]
+			d.Values = append(d.Values, Value{Key: key.Value, Value: value.Value, Line: key.Line, Raw: value.Value, Plain: true})
+		}
+	}
+	return d, true
+}
+
+func checkPlaceholder(d *Document) {
+	for _, v := range d.Values {
+		if strings.Contains(v.Value, Placeholder) {
+			d.Report("placeholder", Error, v.Key, v.Line, "replace the %s placeholder with a real value", Placeholder)
+		}
+	}
+}
+
+func checkBreakGlass(d *Document) {
+	metadata := field(d.Root, "metadata")
+	if metadata == nil {
+		return
+	}
+	annotations := field(metadata, "annotations")
+	if annotations == nil {
+		return
+	}
+	record := field(annotations, secret.BreakGlassAnnotation)
+	if record == nil {
+		return
+	}
+
+	var r struct {
+		User   string `json:"user"`
+		Ticket string `json:"ticket"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(record.Value), &r); err != nil {
+		d.Report("breakglass", Warning, "", record.Line, "edited under break-glass; review the change and remove the %s annotation", secret.BreakGlassAnnotation)
+		return
+	}
+	d.Report("breakglass", Warning, "", record.Line, "edited under break-glass by %s for %s (%s); review the change and remove the %s annotation",
+		r.User, r.Ticket, r.Reason, secret.BreakGlassAnnotation)
+}
+
+func field(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalar(node *yaml.Node, key string) string {
+	if v := field(node, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package check
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     []string // rule and key of each finding, in order
+		warnOnly bool
+	}{
+		{
+			name: "clean secret",
+			manifest: `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+data:
+  password: cGFzc3dvcmQxMjM=
+`,
+		},
+		{
+			name: "placeholders in data and stringData",
+			manifest: `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+data:
+  password: PENIQU5HRU1FPg==
+stringData:
+  url: postgres://<CHANGEME>@db
+`,
+			want: []string{"placeholder password", "placeholder url"},
+		},
+		{
+			name: "invalid base64",
+			manifest: `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+data:
+  password: not base64!
+`,
+			want: []string{"base64 password"},
+		},
+		{
+			name: "non-canonical base64",
+			manifest: `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+data:
+  password: cGFzc3dvcmQxMjM
+  token: |
+    cGFzc3dv
+    cmQxMjM=
+stringData:
+  url: postgres://<CHANGEME>@db
+`,
+			want: []string{"canonical password", "canonical token", "placeholder url"},
+		},
+		{
+			name: "duplicate keys",
+			manifest: `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  name: db2
+data:
+  password: cGFzc3dvcmQxMjM=
+  password: cGFzc3dvcmQxMjM=
+`,
+			want: []string{"duplicate name", "duplicate password"},
+		},
+		{
+			name: "other resources are skipped",
+			manifest: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cfg
+data:
+  url: <CHANGEME>
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+stringData:
+  token: <CHANGEME>
+`,
+			want: []string{"placeholder token"},
+		},
+		{
+			name: "break-glass edit",
+			manifest: `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  annotations:
+    swk.dev/break-glass: '{"user":"alice","ticket":"INC-1","reason":"outage"}'
+data:
+  password: cGFzc3dvcmQxMjM=
+`,
+			want:     []string{"breakglass "},
+			warnOnly: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := Manifest("secret.yaml", []byte(tt.manifest), Options{})
+			if err != nil {
+				t.Fatalf("Manifest() error = %v", err)
+			}
+
+			var got []string
+			for _, f := range findings {
+				got = append(got, f.Rule+" "+f.Key)
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("findings = %v, want %v", got, tt.want)
+			}
+			if HasErrors(findings) != (len(tt.want) > 0 && !tt.warnOnly) {
+				t.Errorf("HasErrors() = %v", HasErrors(findings))
+			}
+		})
+	}
+}
+
+func TestFindingString(t *testing.T) {
+	f := Finding{File: "s.yaml", Line: 7, Secret: "prod/db", Key: "password", Rule: "placeholder", Severity: Error, Message: "fix me"}
+	if got, want := f.String(), "s.yaml:7: error [placeholder] prod/db password: fix me"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestManifestInvalidYAML(t *testing.T) {
+	if _, err := Manifest("bad.yaml", []byte("a: ["), Options{}); err == nil {
+		t.Error("expected an error for invalid YAML")
+	}
+}
@@ -0,0 +1,114 @@
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// WeakList is a set of hashes of values that must not be used, such as
+// banned or breached passwords. It holds SHA-1 hashes, as in the Pwned
+// Passwords downloads, and SHA-256 hashes, so the values themselves never
+// have to be shipped.
+type WeakList struct {
+	sha1   map[string]bool
+	sha256 map[string]bool
+	digest string
+}
+
+// LoadWeakList reads a weak list file, see ParseWeakList
+func LoadWeakList(path string) (*WeakList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read weak list: %w", err)
+	}
+	w, err := ParseWeakList(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return w, nil
+}
+
+// ParseWeakList reads one hex SHA-1 or SHA-256 hash per line, in either
+// case. Anything after the hash and a colon or space is ignored, so the
+// Pwned Passwords HASH:COUNT format and sha256sum output both work, and so
+// are blank lines and lines starting with #.
+func ParseWeakList(r io.Reader) (*WeakList, error) {
+	w := &WeakList{sha1: map[string]bool{}, sha256: map[string]bool{}}
+	digest := sha256.New()
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(strings.Fields(line)[0], ":")
+		hash = strings.ToLower(hash)
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("line %d: %q is not a hex hash", n, hash)
+		}
+		switch len(hash) {
+		case 2 * sha1.Size:
+			w.sha1[hash] = true
+		case 2 * sha256.Size:
+			w.sha256[hash] = true
+		default:
+			return nil, fmt.Errorf("line %d: %q is neither a SHA-1 nor a SHA-256 hash", n, hash)
+		}
+		fmt.Fprintln(digest, hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read weak list: %w", err)
+	}
+	w.digest = hex.EncodeToString(digest.Sum(nil))
+	return w, nil
+}
+
+// Len returns how many hashes the list holds
+func (w *WeakList) Len() int {
+	return len(w.sha1) + len(w.sha256)
+}
+
+// Digest identifies the hashes on the list, so that findings made with
+// another list aren't reused
+func (w *WeakList) Digest() string {
+	return w.digest
+}
+
+// Contains reports whether value is on the list. A value with surrounding
+// whitespace, such as a trailing newline left by echo, counts as the value
+// without it.
+func (w *WeakList) Contains(value string) bool {
+	for _, v := range []string{value, strings.TrimSpace(value)} {
+		if len(w.sha1) > 0 {
+			sum := sha1.Sum([]byte(v))
+			if w.sha1[hex.EncodeToString(sum[:])] {
+				return true
+			}
+		}
+		if len(w.sha256) > 0 {
+			sum := sha256.Sum256([]byte(v))
+			if w.sha256[hex.EncodeToString(sum[:])] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func checkWeak(d *Document) {
+	if d.weakList == nil {
+		return
+	}
+	for _, v := range d.Values {
+		if v.Value != "" && d.weakList.Contains(v.Value) {
+			d.Report("weak", Error, v.Key, v.Line, "value is on the weak list of banned or breached values; replace it")
+		}
+	}
+}
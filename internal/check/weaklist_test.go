@@ -0,0 +1,62 @@
+package check
+
+import (
+	"strings"
+	"testing"
+)
+
+// weakList holds "password" as a Pwned Passwords SHA-1 line and "hunter2"
+// as sha256sum output
+const weakList = `# banned values
+5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824
+
+F52FBD32B2B3B86FF88EF6C490628285F482AF15DDCB29541F94BCF526A3F6C7  -
+`
+
+func TestParseWeakList(t *testing.T) {
+	w, err := ParseWeakList(strings.NewReader(weakList))
+	if err != nil {
+		t.Fatalf("ParseWeakList() error = %v", err)
+	}
+	if w.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", w.Len())
+	}
+	for value, want := range map[string]bool{"password": true, "hunter2": true, "hunter2\n": true, "Password": false, "": false} {
+		if got := w.Contains(value); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", value, got, want)
+		}
+	}
+
+	for _, bad := range []string{"not-a-hash\n", "abcd\n"} {
+		if _, err := ParseWeakList(strings.NewReader(bad)); err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("ParseWeakList(%q) error = %v, want one naming line 1", bad, err)
+		}
+	}
+}
+
+func TestManifestWeakList(t *testing.T) {
+	w, err := ParseWeakList(strings.NewReader(weakList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\ndata:\n  password: cGFzc3dvcmQ=\n  token: c3Ryb25n\nstringData:\n  admin: hunter2\n")
+
+	findings, err := Manifest("secret.yaml", manifest, Options{WeakList: w})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.Rule+" "+f.Key)
+		if strings.Contains(f.Message, "hunter2") || strings.Contains(f.Message, "password") {
+			t.Errorf("finding %q reveals the value", f)
+		}
+	}
+	if strings.Join(got, ", ") != "weak password, weak admin" {
+		t.Errorf("findings = %v, want the password and admin values", got)
+	}
+
+	if findings, _ := Manifest("secret.yaml", manifest, Options{}); len(findings) != 0 {
+		t.Errorf("findings without a weak list = %v, want none", findings)
+	}
+}
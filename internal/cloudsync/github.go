@@ -0,0 +1,156 @@
+//go:build !nocloud
+
+package cloudsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+func init() {
+	register("github", func(rest string) (Store, error) { return NewGitHub(rest) })
+}
+
+// GitHub is the Actions secrets of a GitHub repository. Values can be
+// written and listed, but GitHub never hands them back.
+type GitHub struct {
+	API   string // e.g. https://api.github.com
+	Token string
+	Repo  string // owner/name
+
+	Client *http.Client
+
+	// publicKey is the repository key values are sealed with, fetched once
+	publicKey *githubKey
+}
+
+type githubKey struct {
+	ID  string `json:"key_id"`
+	Key string `json:"key"`
+}
+
+// NewGitHub returns a store for the Actions secrets of repo, taking the
+// token from GITHUB_TOKEN or GH_TOKEN and the API from GITHUB_API_URL,
+// which GitHub Enterprise sets
+func NewGitHub(repo string) (*GitHub, error) {
+	g := &GitHub{
+		API:    strings.TrimSuffix(os.Getenv("GITHUB_API_URL"), "/"),
+		Token:  os.Getenv("GITHUB_TOKEN"),
+		Repo:   strings.Trim(repo, "/"),
+		Client: http.DefaultClient,
+	}
+	if g.API == "" {
+		g.API = "https://api.github.com"
+	}
+	if g.Token == "" {
+		g.Token = os.Getenv("GH_TOKEN")
+	}
+	if g.Token == "" {
+		return nil, fmt.Errorf("GITHUB_TOKEN or GH_TOKEN must be set")
+	}
+	if owner, name, ok := strings.Cut(g.Repo, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("github store needs a repository, e.g. github://org/repo")
+	}
+	return g, nil
+}
+
+// Name returns the store as a github:// URL
+func (g *GitHub) Name() string {
+	return "github://" + g.Repo
+}
+
+// Keys lists the names of the repository's secrets
+func (g *GitHub) Keys(ctx context.Context) ([]string, error) {
+	var keys []string
+	for page := 1; ; page++ {
+		body, err := g.do(ctx, http.MethodGet, fmt.Sprintf("actions/secrets?per_page=100&page=%d", page), nil)
+		if err != nil {
+			return nil, err
+		}
+		var resp struct {
+			TotalCount int `json:"total_count"`
+			Secrets    []struct {
+				Name string `json:"name"`
+			} `json:"secrets"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse response from %s: %w", g.Name(), err)
+		}
+		for _, s := range resp.Secrets {
+			keys = append(keys, s.Name)
+		}
+		if len(resp.Secrets) == 0 || len(keys) >= resp.TotalCount {
+			break
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Get always fails: GitHub secrets are write-only
+func (g *GitHub) Get(_ context.Context, key string) (string, error) {
+	return "", fmt.Errorf("%s: GitHub Actions secrets can't be read back, so %q can't be pulled", g.Name(), key)
+}
+
+// Put seals the value with the repository's public key, as the API
+// requires, and creates or updates the secret
+func (g *GitHub) Put(ctx context.Context, key, value string) error {
+	if g.publicKey == nil {
+		body, err := g.do(ctx, http.MethodGet, "actions/secrets/public-key", nil)
+		if err != nil {
+			return err
+		}
+		var k githubKey
+		if err := json.Unmarshal(body, &k); err != nil {
+			return fmt.Errorf("failed to parse public key from %s: %w", g.Name(), err)
+		}
+		g.publicKey = &k
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(g.publicKey.Key)
+	if err != nil || len(raw) != 32 {
+		return fmt.Errorf("%s returned an invalid public key", g.Name())
+	}
+	var recipient [32]byte
+	copy(recipient[:], raw)
+	sealed, err := box.SealAnonymous(nil, []byte(value), &recipient, rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %q: %w", key, err)
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"encrypted_value": base64.StdEncoding.EncodeToString(sealed),
+		"key_id":          g.publicKey.ID,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = g.do(ctx, http.MethodPut, "actions/secrets/"+url.PathEscape(key), body)
+	return err
+}
+
+// do sends a request for a path below the repository
+func (g *GitHub) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, g.API+"/repos/"+g.Repo+"/"+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.Token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return send(g.Client, req, g.Name())
+}
@@ -0,0 +1,110 @@
+//go:build !nocloud
+
+package cloudsync
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+// fakeGitHub serves the Actions secrets API of org/app, decrypting what it
+// is sent so tests can check the values
+func fakeGitHub(t *testing.T) (*GitHub, map[string]string) {
+	t.Helper()
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets := map[string]string{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/repos/org/app/actions/secrets")
+		switch {
+		case r.Method == http.MethodGet && path == "/public-key":
+			_ = json.NewEncoder(w).Encode(map[string]string{"key_id": "k1", "key": base64.StdEncoding.EncodeToString(pub[:])})
+		case r.Method == http.MethodGet && path == "":
+			var list []map[string]string
+			for name := range secrets {
+				list = append(list, map[string]string{"name": name})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"total_count": len(list), "secrets": list})
+		case r.Method == http.MethodPut && strings.HasPrefix(path, "/"):
+			var body struct {
+				EncryptedValue string `json:"encrypted_value"`
+				KeyID          string `json:"key_id"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			sealed, _ := base64.StdEncoding.DecodeString(body.EncryptedValue)
+			plain, ok := box.OpenAnonymous(nil, sealed, pub, priv)
+			if !ok || body.KeyID != "k1" {
+				http.Error(w, `{"message":"bad encryption"}`, http.StatusUnprocessableEntity)
+				return
+			}
+			secrets[path[1:]] = string(plain)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	t.Setenv("GITHUB_API_URL", ts.URL)
+	t.Setenv("GITHUB_TOKEN", "token")
+	g, err := NewGitHub("org/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g, secrets
+}
+
+func TestGitHub(t *testing.T) {
+	g, secrets := fakeGitHub(t)
+	ctx := context.Background()
+
+	for key, value := range map[string]string{"DB_PASSWORD": "hunter2", "API_KEY": "abc"} {
+		if err := g.Put(ctx, key, value); err != nil {
+			t.Fatalf("Put() failed: %v", err)
+		}
+	}
+	if secrets["DB_PASSWORD"] != "hunter2" || secrets["API_KEY"] != "abc" {
+		t.Errorf("secrets = %v, want the sealed values to decrypt", secrets)
+	}
+
+	keys, err := g.Keys(ctx)
+	if err != nil || strings.Join(keys, ",") != "API_KEY,DB_PASSWORD" {
+		t.Errorf("Keys() = %v, %v", keys, err)
+	}
+	if _, err := g.Get(ctx, "API_KEY"); err == nil {
+		t.Error("Get() succeeded on a write-only store")
+	}
+
+	g.Token = "wrong"
+	if err := g.Put(ctx, "API_KEY", "x"); err == nil || IsRetryable(err) {
+		t.Errorf("Put() with a bad token = %v, want a permanent error", err)
+	}
+}
+
+func TestNewGitHub(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "token")
+	for _, repo := range []string{"org", "org/", "org/app/extra"} {
+		if _, err := NewGitHub(repo); err == nil {
+			t.Errorf("NewGitHub(%q) succeeded", repo)
+		}
+	}
+	if _, err := Open("github://org/app"); err != nil {
+		t.Errorf("Open() with GH_TOKEN failed: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+//go:build !nocloud
+
+package cloudsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+func init() {
+	register("gitlab", func(rest string) (Store, error) { return NewGitLab(rest) })
+}
+
+// GitLab is the CI/CD variables of a GitLab project
+type GitLab struct {
+	API     string // e.g. https://gitlab.com/api/v4
+	Token   string
+	Project string // numeric ID or full path, e.g. group/app
+
+	Client *http.Client
+}
+
+// NewGitLab returns a store for the CI/CD variables of project, taking the
+// token from GITLAB_TOKEN and the API from CI_API_V4_URL, which GitLab CI
+// sets, or GITLAB_URL
+func NewGitLab(project string) (*GitLab, error) {
+	g := &GitLab{
+		API:     strings.TrimSuffix(os.Getenv("CI_API_V4_URL"), "/"),
+		Token:   os.Getenv("GITLAB_TOKEN"),
+		Project: strings.Trim(project, "/"),
+		Client:  http.DefaultClient,
+	}
+	if g.API == "" {
+		base := strings.TrimSuffix(os.Getenv("GITLAB_URL"), "/")
+		if base == "" {
+			base = "https://gitlab.com"
+		}
+		g.API = base + "/api/v4"
+	}
+	if g.Token == "" {
+		return nil, fmt.Errorf("GITLAB_TOKEN must be set")
+	}
+	if g.Project == "" {
+		return nil, fmt.Errorf("gitlab store needs a project ID or path, e.g. gitlab://1234")
+	}
+	return g, nil
+}
+
+// Name returns the store as a gitlab:// URL
+func (g *GitLab) Name() string {
+	return "gitlab://" + g.Project
+}
+
+// Keys lists the project's variables
+func (g *GitLab) Keys(ctx context.Context) ([]string, error) {
+	var keys []string
+	for page := 1; ; page++ {
+		body, err := g.do(ctx, http.MethodGet, fmt.Sprintf("variables?per_page=100&page=%d", page), nil)
+		if err != nil {
+			return nil, err
+		}
+		var vars []struct {
+			Key string `json:"key"`
+		}
+		if err := json.Unmarshal(body, &vars); err != nil {
+			return nil, fmt.Errorf("failed to parse response from %s: %w", g.Name(), err)
+		}
+		for _, v := range vars {
+			keys = append(keys, v.Key)
+		}
+		if len(vars) < 100 {
+			break
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Get reads one variable
+func (g *GitLab) Get(ctx context.Context, key string) (string, error) {
+	body, err := g.do(ctx, http.MethodGet, "variables/"+url.PathEscape(key), nil)
+	if isStatus(err, http.StatusNotFound) {
+		return "", fmt.Errorf("%s has no key %q", g.Name(), key)
+	}
+	if err != nil {
+		return "", err
+	}
+	var v struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "", fmt.Errorf("failed to parse response from %s: %w", g.Name(), err)
+	}
+	return v.Value, nil
+}
+
+// Put updates a variable, or creates it if it doesn't exist yet. Values
+// GitLab can mask in job logs are masked.
+func (g *GitLab) Put(ctx context.Context, key, value string) error {
+	body, err := json.Marshal(map[string]any{"key": key, "value": value, "masked": maskable(value), "raw": true})
+	if err != nil {
+		return err
+	}
+	_, err = g.do(ctx, http.MethodPut, "variables/"+url.PathEscape(key), body)
+	if isStatus(err, http.StatusNotFound) {
+		_, err = g.do(ctx, http.MethodPost, "variables", body)
+	}
+	return err
+}
+
+// do sends a request for a path below the project
+func (g *GitLab) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	u := g.API + "/projects/" + url.PathEscape(g.Project) + "/" + path
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return send(g.Client, req, g.Name())
+}
+
+// maskable reports whether GitLab accepts value as a masked variable: a
+// single line of at least 8 characters from a limited alphabet
+func maskable(value string) bool {
+	if len(value) < 8 {
+		return false
+	}
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("+/=@:.~_-", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
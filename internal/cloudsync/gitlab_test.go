@@ -0,0 +1,101 @@
+//go:build !nocloud
+
+package cloudsync
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeGitLab serves the CI/CD variables API of project group/app
+func fakeGitLab(t *testing.T) (*GitLab, map[string]map[string]any) {
+	t.Helper()
+	vars := map[string]map[string]any{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "token" {
+			http.Error(w, `{"message":"401 Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		prefix := "/api/v4/projects/group%2Fapp/variables"
+		if !strings.HasPrefix(r.URL.EscapedPath(), prefix) {
+			http.NotFound(w, r)
+			return
+		}
+		key := strings.TrimPrefix(strings.TrimPrefix(r.URL.EscapedPath(), prefix), "/")
+
+		switch {
+		case r.Method == http.MethodGet && key == "":
+			list := []map[string]any{}
+			for _, v := range vars {
+				list = append(list, v)
+			}
+			_ = json.NewEncoder(w).Encode(list)
+		case r.Method == http.MethodPost && key == "":
+			var v map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&v)
+			vars[v["key"].(string)] = v
+			w.WriteHeader(http.StatusCreated)
+		case vars[key] == nil:
+			http.Error(w, `{"message":"404 Variable Not Found"}`, http.StatusNotFound)
+		case r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(vars[key])
+		case r.Method == http.MethodPut:
+			var v map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&v)
+			vars[key] = v
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	t.Setenv("CI_API_V4_URL", ts.URL+"/api/v4")
+	t.Setenv("GITLAB_TOKEN", "token")
+	g, err := NewGitLab("group/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g, vars
+}
+
+func TestGitLab(t *testing.T) {
+	g, vars := fakeGitLab(t)
+	ctx := context.Background()
+
+	// The first Put creates the variable, the second updates it
+	for _, value := range []string{"short", "long-enough-to-mask"} {
+		if err := g.Put(ctx, "DB_PASSWORD", value); err != nil {
+			t.Fatalf("Put() failed: %v", err)
+		}
+	}
+	if got, err := g.Get(ctx, "DB_PASSWORD"); err != nil || got != "long-enough-to-mask" {
+		t.Errorf("Get() = %q, %v", got, err)
+	}
+	if vars["DB_PASSWORD"]["masked"] != true {
+		t.Errorf("variable = %v, want it masked", vars["DB_PASSWORD"])
+	}
+	if keys, err := g.Keys(ctx); err != nil || strings.Join(keys, ",") != "DB_PASSWORD" {
+		t.Errorf("Keys() = %v, %v", keys, err)
+	}
+	if _, err := g.Get(ctx, "MISSING"); err == nil || IsRetryable(err) {
+		t.Errorf("Get() of a missing key = %v, want a permanent error", err)
+	}
+}
+
+func TestMaskable(t *testing.T) {
+	tests := map[string]bool{
+		"short":            false,
+		"c2VjcmV0c2VjcmV0": true,
+		"has space in it":  false,
+		"line\nbreak12345": false,
+		"user@host:8080.x": true,
+	}
+	for value, want := range tests {
+		if got := maskable(value); got != want {
+			t.Errorf("maskable(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
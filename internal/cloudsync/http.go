@@ -0,0 +1,56 @@
+package cloudsync
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// send sends a request to a store and returns the response body, marking
+// throttling, server errors, and connection failures as retryable
+func send(client *http.Client, req *http.Request, store string) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+		// Connection resets, timeouts, and the like
+		return nil, &RetryableError{Err: err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &RetryableError{Err: err}
+	}
+	if resp.StatusCode >= 300 {
+		err := &StatusError{Store: store, Code: resp.StatusCode, Body: strings.TrimSpace(string(data))}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return nil, &RetryableError{Err: err}
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// StatusError is an unexpected HTTP response from a store
+type StatusError struct {
+	Store string
+	Code  int
+	Body  string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("%s: %s: %s", e.Store, http.StatusText(e.Code), e.Body)
+	}
+	return fmt.Sprintf("%s: %s", e.Store, http.StatusText(e.Code))
+}
+
+// isStatus reports whether err is an HTTP response with the given code
+func isStatus(err error, code int) bool {
+	var status *StatusError
+	return errors.As(err, &status) && status.Code == code
+}
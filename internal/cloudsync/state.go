@@ -0,0 +1,103 @@
+package cloudsync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+)
+
+// State records which keys of a sync are done, so a rerun with --resume
+// skips them. It never holds values, only key names and a fingerprint of
+// the manifest being synced.
+type State struct {
+	Op     string   `json:"op"`
+	Store  string   `json:"store"`
+	Source string   `json:"source"`
+	Done   []string `json:"done"`
+
+	path string
+}
+
+// Fingerprint identifies a version of the manifest a sync started from
+func Fingerprint(manifest []byte) string {
+	sum := sha256.Sum256(manifest)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewState starts a fresh state at path, replacing any earlier one
+func NewState(path, op, store, source string) *State {
+	return &State{Op: op, Store: store, Source: source, path: path}
+}
+
+// ResumeState loads the state of an interrupted sync at path. It fails if
+// there is none, or if it belongs to a different operation, store, or
+// version of the manifest, because skipping keys would then be wrong.
+func ResumeState(path, op, store, source string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("nothing to resume: %s does not exist", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync state: %w", err)
+	}
+
+	s := &State{path: path}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse sync state %s: %w", path, err)
+	}
+	switch {
+	case s.Op != op || s.Store != store:
+		return nil, fmt.Errorf("%s records a %s with %s, not a %s with %s", path, s.Op, s.Store, op, store)
+	case s.Source != source:
+		return nil, fmt.Errorf("the manifest changed since the sync recorded in %s; run without --resume", path)
+	}
+	return s, nil
+}
+
+// IsDone reports whether key finished in an earlier run
+func (s *State) IsDone(key string) bool {
+	return s != nil && slices.Contains(s.Done, key)
+}
+
+// MarkDone records keys as finished and saves the state
+func (s *State) MarkDone(keys ...string) error {
+	if s == nil {
+		return nil
+	}
+	s.Done = append(s.Done, keys...)
+	return s.save()
+}
+
+// PulledInto records keys as finished after they were written to the
+// manifest, whose new fingerprint is source
+func (s *State) PulledInto(source string, keys ...string) error {
+	if s == nil {
+		return nil
+	}
+	s.Source = source
+	return s.MarkDone(keys...)
+}
+
+// Remove deletes the state file once a sync completes
+func (s *State) Remove() error {
+	if s == nil {
+		return nil
+	}
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *State) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, append(data, '\n'), 0600)
+}
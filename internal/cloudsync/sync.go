@@ -0,0 +1,187 @@
+// Package cloudsync pushes Secret values to and pulls them from external
+// secret stores one key at a time, retrying transient failures and
+// recording progress so an interrupted sync can resume where it stopped
+package cloudsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/retry"
+)
+
+// Default retry settings
+const (
+	DefaultMaxRetries = 4
+
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 16 * time.Second
+)
+
+// Store is an external secret store holding one value per key
+type Store interface {
+	// Name identifies the store in state files and reports, e.g. vault://kv/app
+	Name() string
+	Keys(ctx context.Context) ([]string, error)
+	Get(ctx context.Context, key string) (string, error)
+	Put(ctx context.Context, key, value string) error
+}
+
+// openers maps the URL schemes of the stores compiled in to functions that
+// open them. Each store registers itself, so that builds with the nocloud
+// tag leave them all out, along with their dependencies.
+var openers = map[string]func(rest string) (Store, error){}
+
+// register adds a store for URLs with scheme
+func register(scheme string, open func(rest string) (Store, error)) {
+	openers[scheme] = open
+}
+
+// Schemes returns the URL schemes of the stores Open supports, sorted
+func Schemes() []string {
+	schemes := make([]string, 0, len(openers))
+	for scheme := range openers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// Open returns the store for a URL such as vault://kv/apps/db,
+// github://org/repo, or gitlab://PROJECT
+func Open(rawURL string) (Store, error) {
+	scheme, rest, ok := strings.Cut(rawURL, "://")
+	if !ok {
+		return nil, fmt.Errorf("invalid store %q: want a URL such as vault://MOUNT/PATH", rawURL)
+	}
+	open, ok := openers[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported store %q (available: %s)", scheme, strings.Join(Schemes(), ", "))
+	}
+	return open(rest)
+}
+
+// RetryableError marks a failure worth retrying, such as throttling or a
+// server error. Anything else fails the key at once.
+type RetryableError struct {
+	Err error
+}
+
+func (e *RetryableError) Error() string { return e.Err.Error() }
+
+func (e *RetryableError) Unwrap() error { return e.Err }
+
+// IsRetryable reports whether err is worth retrying
+func IsRetryable(err error) bool {
+	var retryable *RetryableError
+	return errors.As(err, &retryable)
+}
+
+// Result is the outcome of syncing one key
+type Result struct {
+	Key      string
+	Attempts int
+	Skipped  bool // done in an earlier, interrupted run
+	Err      error
+}
+
+// Syncer runs pushes and pulls against a store
+type Syncer struct {
+	Store      Store
+	MaxRetries int
+	// State records finished keys; nil disables resuming
+	State *State
+
+	sleep func(context.Context, time.Duration) error
+}
+
+// New returns a syncer with the default retry settings
+func New(store Store, state *State) *Syncer {
+	return &Syncer{Store: store, MaxRetries: DefaultMaxRetries, State: state, sleep: retry.Sleep}
+}
+
+// Push writes each value to the store. Keys that fail don't stop the
+// others; every key gets a Result, in the order of keys.
+func (s *Syncer) Push(ctx context.Context, keys []string, values map[string]string) []Result {
+	return s.each(ctx, keys, true, func(key string) error {
+		return s.Store.Put(ctx, key, values[key])
+	})
+}
+
+// Pull reads each key from the store into values, which must not be nil.
+// Pulled keys only count as done once the caller has written them, so
+// Pull doesn't record them; call State.MarkDone after writing.
+func (s *Syncer) Pull(ctx context.Context, keys []string, values map[string]string) []Result {
+	return s.each(ctx, keys, false, func(key string) error {
+		value, err := s.Store.Get(ctx, key)
+		if err == nil {
+			values[key] = value
+		}
+		return err
+	})
+}
+
+// Keys lists the store's keys, with retries
+func (s *Syncer) Keys(ctx context.Context) ([]string, error) {
+	var keys []string
+	_, err := s.retry(ctx, func() error {
+		var err error
+		keys, err = s.Store.Keys(ctx)
+		return err
+	})
+	return keys, err
+}
+
+// each runs fn for every key not already done, with retries, recording
+// progress after every key if record is set
+func (s *Syncer) each(ctx context.Context, keys []string, record bool, fn func(key string) error) []Result {
+	results := make([]Result, 0, len(keys))
+	for _, key := range keys {
+		if s.State.IsDone(key) {
+			results = append(results, Result{Key: key, Skipped: true})
+			continue
+		}
+
+		r := Result{Key: key}
+		r.Attempts, r.Err = s.retry(ctx, func() error { return fn(key) })
+		if r.Err == nil && record {
+			if err := s.State.MarkDone(key); err != nil {
+				r.Err = fmt.Errorf("synced, but failed to record progress: %w", err)
+			}
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// retry runs fn until it succeeds, fails permanently, or runs out of
+// retries, and returns the number of attempts
+func (s *Syncer) retry(ctx context.Context, fn func() error) (int, error) {
+	var err error
+	for attempt := 0; attempt <= s.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := s.sleep(ctx, retry.Backoff(attempt, baseBackoff, maxBackoff)); err != nil {
+				return attempt, err
+			}
+		}
+		if err = fn(); err == nil || !IsRetryable(err) {
+			return attempt + 1, err
+		}
+	}
+	return s.MaxRetries + 1, fmt.Errorf("giving up after %d attempts: %w", s.MaxRetries+1, err)
+}
+
+// Failed counts the results that failed
+func Failed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if r.Err != nil {
+			n++
+		}
+	}
+	return n
+}
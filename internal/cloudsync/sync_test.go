@@ -0,0 +1,159 @@
+package cloudsync
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeStore fails each key a set number of times before succeeding
+type fakeStore struct {
+	values   map[string]string
+	failures map[string]int
+	fatal    map[string]bool
+	calls    map[string]int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{values: map[string]string{}, failures: map[string]int{}, fatal: map[string]bool{}, calls: map[string]int{}}
+}
+
+func (f *fakeStore) Name() string { return "fake://store" }
+
+func (f *fakeStore) Keys(context.Context) ([]string, error) { return []string{"a", "b"}, nil }
+
+func (f *fakeStore) Get(_ context.Context, key string) (string, error) {
+	if err := f.fail(key); err != nil {
+		return "", err
+	}
+	return f.values[key], nil
+}
+
+func (f *fakeStore) Put(_ context.Context, key, value string) error {
+	if err := f.fail(key); err != nil {
+		return err
+	}
+	f.values[key] = value
+	return nil
+}
+
+func (f *fakeStore) fail(key string) error {
+	f.calls[key]++
+	if f.fatal[key] {
+		return errors.New("permission denied")
+	}
+	if f.failures[key] > 0 {
+		f.failures[key]--
+		return &RetryableError{Err: errors.New("503 Service Unavailable")}
+	}
+	return nil
+}
+
+func newTestSyncer(store Store, state *State) *Syncer {
+	s := New(store, state)
+	s.sleep = func(context.Context, time.Duration) error { return nil }
+	return s
+}
+
+func TestPush(t *testing.T) {
+	store := newFakeStore()
+	store.failures["a"] = 2
+	store.failures["b"] = DefaultMaxRetries + 1
+	store.fatal["c"] = true
+
+	s := newTestSyncer(store, nil)
+	results := s.Push(context.Background(), []string{"a", "b", "c", "d"}, map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"})
+
+	tests := []struct {
+		key      string
+		attempts int
+		wantErr  string
+	}{
+		{"a", 3, ""},
+		{"b", DefaultMaxRetries + 1, "giving up"},
+		{"c", 1, "permission denied"},
+		{"d", 1, ""},
+	}
+	for i, tt := range tests {
+		r := results[i]
+		if r.Key != tt.key || r.Attempts != tt.attempts {
+			t.Errorf("result %d = %+v, want key %s after %d attempts", i, r, tt.key, tt.attempts)
+		}
+		if (r.Err == nil) != (tt.wantErr == "") || (r.Err != nil && !strings.Contains(r.Err.Error(), tt.wantErr)) {
+			t.Errorf("%s: error = %v, want %q", tt.key, r.Err, tt.wantErr)
+		}
+	}
+	if Failed(results) != 2 {
+		t.Errorf("Failed() = %d, want 2", Failed(results))
+	}
+	if store.values["a"] != "1" || store.values["d"] != "4" {
+		t.Errorf("store = %v", store.values)
+	}
+}
+
+func TestPushResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	store := newFakeStore()
+	store.fatal["b"] = true
+	values := map[string]string{"a": "1", "b": "2", "c": "3"}
+	keys := []string{"a", "b", "c"}
+
+	s := newTestSyncer(store, NewState(path, "push", store.Name(), "v1"))
+	if Failed(s.Push(context.Background(), keys, values)) != 1 {
+		t.Fatal("expected b to fail")
+	}
+
+	// A resumed run only retries b
+	store.fatal["b"] = false
+	state, err := ResumeState(path, "push", store.Name(), "v1")
+	if err != nil {
+		t.Fatalf("ResumeState() failed: %v", err)
+	}
+	results := newTestSyncer(store, state).Push(context.Background(), keys, values)
+	if !results[0].Skipped || results[1].Skipped || !results[2].Skipped || Failed(results) != 0 {
+		t.Errorf("resumed results = %+v, want only b synced", results)
+	}
+	if store.calls["a"] != 1 || store.calls["c"] != 1 {
+		t.Errorf("calls = %v, want a and c pushed once", store.calls)
+	}
+}
+
+func TestResumeState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	if err := NewState(path, "push", "vault://kv/a", "v1").MarkDone("x"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		op      string
+		store   string
+		source  string
+		wantErr string
+	}{
+		{"matches", path, "push", "vault://kv/a", "v1", ""},
+		{"missing", path + ".nope", "push", "vault://kv/a", "v1", "nothing to resume"},
+		{"other op", path, "pull", "vault://kv/a", "v1", "not a pull"},
+		{"other store", path, "push", "vault://kv/b", "v1", "not a push with vault://kv/b"},
+		{"changed manifest", path, "push", "vault://kv/a", "v2", "manifest changed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := ResumeState(tt.path, tt.op, tt.store, tt.source)
+			if tt.wantErr == "" {
+				if err != nil || !state.IsDone("x") {
+					t.Errorf("ResumeState() = %+v, %v", state, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ResumeState() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+//go:build !nocloud
+
+package cloudsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+func init() {
+	register("vault", func(rest string) (Store, error) {
+		mount, path, _ := strings.Cut(rest, "/")
+		return NewVault(mount, path)
+	})
+}
+
+// Vault is a HashiCorp Vault KV version 2 secret, holding one field per key
+type Vault struct {
+	Addr      string // e.g. https://vault.example.com:8200
+	Token     string
+	Namespace string // Vault Enterprise namespace, if any
+	Mount     string // KV engine mount, e.g. kv
+	Path      string // secret path below the mount, e.g. apps/db
+
+	Client *http.Client
+}
+
+// NewVault returns a store for mount/path, taking the address, token, and
+// namespace from VAULT_ADDR, VAULT_TOKEN, and VAULT_NAMESPACE
+func NewVault(mount, path string) (*Vault, error) {
+	v := &Vault{
+		Addr:      strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/"),
+		Token:     os.Getenv("VAULT_TOKEN"),
+		Namespace: os.Getenv("VAULT_NAMESPACE"),
+		Mount:     strings.Trim(mount, "/"),
+		Path:      strings.Trim(path, "/"),
+		Client:    http.DefaultClient,
+	}
+	if v.Addr == "" || v.Token == "" {
+		return nil, fmt.Errorf("VAULT_ADDR and VAULT_TOKEN must be set")
+	}
+	if v.Mount == "" || v.Path == "" {
+		return nil, fmt.Errorf("vault store needs a mount and a path, e.g. vault://kv/apps/db")
+	}
+	return v, nil
+}
+
+// Name returns the store as a vault:// URL
+func (v *Vault) Name() string {
+	return "vault://" + v.Mount + "/" + v.Path
+}
+
+// Keys lists the fields of the secret
+func (v *Vault) Keys(ctx context.Context) ([]string, error) {
+	data, err := v.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Get reads one field
+func (v *Vault) Get(ctx context.Context, key string) (string, error) {
+	data, err := v.read(ctx)
+	if err != nil {
+		return "", err
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("%s has no key %q", v.Name(), key)
+	}
+	return value, nil
+}
+
+// Put writes one field, leaving the others alone
+func (v *Vault) Put(ctx context.Context, key, value string) error {
+	body, err := json.Marshal(map[string]any{"data": map[string]string{key: value}})
+	if err != nil {
+		return err
+	}
+	// PATCH merges into the latest version; it fails on a missing secret,
+	// which is then created with POST
+	_, err = v.do(ctx, http.MethodPatch, body)
+	if isStatus(err, http.StatusNotFound) {
+		_, err = v.do(ctx, http.MethodPost, body)
+	}
+	return err
+}
+
+func (v *Vault) read(ctx context.Context) (map[string]string, error) {
+	body, err := v.do(ctx, http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response from %s: %w", v.Name(), err)
+	}
+
+	data := make(map[string]string, len(resp.Data.Data))
+	for k, value := range resp.Data.Data {
+		if s, ok := value.(string); ok {
+			data[k] = s
+		} else {
+			// Secret values are strings; keep anything else as JSON
+			b, _ := json.Marshal(value)
+			data[k] = string(b)
+		}
+	}
+	return data, nil
+}
+
+// do sends a request for the secret's data endpoint
+func (v *Vault) do(ctx context.Context, method string, body []byte) ([]byte, error) {
+	u := v.Addr + "/v1/" + v.Mount + "/data/" + (&url.URL{Path: v.Path}).EscapedPath()
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+	switch method {
+	case http.MethodPatch:
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+	case http.MethodPost:
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return send(v.Client, req, v.Name())
+}
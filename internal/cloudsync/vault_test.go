@@ -0,0 +1,120 @@
+//go:build !nocloud
+
+package cloudsync
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeVault serves a single KV v2 secret, failing the first requests
+// with 503 if asked to
+func fakeVault(t *testing.T, unavailable int) *Vault {
+	t.Helper()
+	var data map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/kv/data/apps/db" {
+			http.NotFound(w, r)
+			return
+		}
+		if unavailable > 0 {
+			unavailable--
+			http.Error(w, "sealed", http.StatusServiceUnavailable)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodPatch:
+			if data == nil {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		if r.Method != http.MethodGet {
+			var body struct {
+				Data map[string]any `json:"data"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if data == nil {
+				data = map[string]any{}
+			}
+			for k, v := range body.Data {
+				data[k] = v
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": data}})
+	}))
+	t.Cleanup(ts.Close)
+
+	t.Setenv("VAULT_ADDR", ts.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+	v, err := NewVault("kv", "apps/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestVault(t *testing.T) {
+	v := fakeVault(t, 0)
+	ctx := context.Background()
+
+	// The first Put creates the secret, the second merges into it
+	if err := v.Put(ctx, "username", "admin"); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+	if err := v.Put(ctx, "password", "hunter2"); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+
+	keys, err := v.Keys(ctx)
+	if err != nil || len(keys) != 2 || keys[0] != "password" || keys[1] != "username" {
+		t.Errorf("Keys() = %v, %v", keys, err)
+	}
+	if got, err := v.Get(ctx, "password"); err != nil || got != "hunter2" {
+		t.Errorf("Get() = %q, %v", got, err)
+	}
+	if _, err := v.Get(ctx, "missing"); err == nil || IsRetryable(err) {
+		t.Errorf("Get() of a missing key = %v, want a permanent error", err)
+	}
+
+	v.Token = "wrong"
+	if err := v.Put(ctx, "password", "x"); err == nil || IsRetryable(err) {
+		t.Errorf("Put() with a bad token = %v, want a permanent error", err)
+	}
+}
+
+func TestVaultRetry(t *testing.T) {
+	v := fakeVault(t, 2)
+	s := newTestSyncer(v, nil)
+
+	results := s.Push(context.Background(), []string{"password"}, map[string]string{"password": "hunter2"})
+	if results[0].Err != nil || results[0].Attempts != 3 {
+		t.Errorf("Push() = %+v, want success after 3 attempts", results[0])
+	}
+}
+
+func TestOpen(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "http://127.0.0.1:8200")
+	t.Setenv("VAULT_TOKEN", "root")
+
+	store, err := Open("vault://kv/apps/db")
+	if err != nil || store.Name() != "vault://kv/apps/db" {
+		t.Errorf("Open() = %v, %v", store, err)
+	}
+	for _, u := range []string{"kv/apps/db", "aws://secret", "vault://kv"} {
+		if _, err := Open(u); err == nil {
+			t.Errorf("Open(%q) expected an error", u)
+		}
+	}
+}
@@ -0,0 +1,518 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/retry"
+)
+
+// Default client settings, chosen to stay well below the API server's
+// default priority-and-fairness limits for a single user
+const (
+	DefaultQPS            = 5.0
+	DefaultBurst          = 10
+	DefaultMaxRetries     = 4
+	DefaultRequestTimeout = 30 * time.Second
+
+	baseBackoff = 250 * time.Millisecond
+	maxBackoff  = 8 * time.Second
+)
+
+// Options configures how the client talks to the cluster
+type Options struct {
+	Kubectl        string // kubectl binary, defaults to "kubectl"
+	Kubeconfig     string
+	Context        string
+	Namespace      string
+	RequestTimeout time.Duration
+	QPS            float64
+	Burst          int
+	MaxRetries     int
+}
+
+// BindFlags registers the common cluster connection flags on a flag set
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Kubeconfig, "kubeconfig", o.Kubeconfig, "Path to the kubeconfig file")
+	fs.StringVar(&o.Context, "context", o.Context, "Kubeconfig context to use")
+	fs.StringVar(&o.Namespace, "namespace", o.Namespace, "Namespace of the Secret")
+	fs.StringVar(&o.Namespace, "n", o.Namespace, "Shorthand for -namespace")
+	fs.DurationVar(&o.RequestTimeout, "request-timeout", DefaultRequestTimeout, "Timeout for a single API request")
+	fs.Float64Var(&o.QPS, "qps", DefaultQPS, "Maximum requests per second sent to the API server")
+	fs.IntVar(&o.Burst, "burst", DefaultBurst, "Maximum burst of requests sent to the API server")
+	fs.IntVar(&o.MaxRetries, "max-retries", DefaultMaxRetries, "Retries for throttled (429) or failed (5xx) requests")
+}
+
+// Client runs kubectl with rate limiting and retries
+type Client struct {
+	opts    Options
+	limiter *limiter
+	sleep   func(context.Context, time.Duration) error
+}
+
+// New creates a client, filling in defaults for unset options
+func New(opts Options) *Client {
+	if opts.Kubectl == "" {
+		opts.Kubectl = "kubectl"
+	}
+	if opts.QPS <= 0 {
+		opts.QPS = DefaultQPS
+	}
+	if opts.Burst <= 0 {
+		opts.Burst = DefaultBurst
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+
+	return &Client{
+		opts:    opts,
+		limiter: newLimiter(opts.QPS, opts.Burst),
+		sleep:   retry.Sleep,
+	}
+}
+
+// Options returns the effective client options
+func (c *Client) Options() Options {
+	return c.opts
+}
+
+// GetSecret fetches a Secret manifest as YAML
+func (c *Client) GetSecret(ctx context.Context, name string) ([]byte, error) {
+	out, err := c.Run(ctx, nil, "get", "secret", name, "-o", "yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %q: %w", name, err)
+	}
+	return out, nil
+}
+
+// GetSecretIn fetches a Secret manifest from namespace as YAML
+func (c *Client) GetSecretIn(ctx context.Context, namespace, name string) ([]byte, error) {
+	out, err := c.Run(ctx, nil, "get", "secret", name, "--namespace", namespace, "-o", "yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %s/%s: %w", namespace, name, err)
+	}
+	return out, nil
+}
+
+// DeleteSecret deletes a Secret from namespace
+func (c *Client) DeleteSecret(ctx context.Context, namespace, name string) error {
+	if _, err := c.Run(ctx, nil, "delete", "secret", name, "--namespace", namespace); err != nil {
+		return fmt.Errorf("failed to delete secret %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// PatchSecret applies a JSON merge patch to a Secret in namespace. The
+// patch holds values, so it goes to kubectl in a file only the user can
+// read rather than on the command line, where ps would show it.
+func (c *Client) PatchSecret(ctx context.Context, namespace, name string, patch []byte) error {
+	f, err := os.CreateTemp("", "swk-patch-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to patch secret %s/%s: %w", namespace, name, err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(patch)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to patch secret %s/%s: %w", namespace, name, err)
+	}
+
+	if _, err := c.Run(ctx, nil, "patch", "secret", name, "--namespace", namespace, "--type", "merge", "--patch-file", f.Name()); err != nil {
+		return fmt.Errorf("failed to patch secret %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// LabelSecret sets a label, given as KEY=VALUE, on a Secret in namespace
+func (c *Client) LabelSecret(ctx context.Context, namespace, name, label string) error {
+	if _, err := c.Run(ctx, nil, "label", "secret", name, "--namespace", namespace, "--overwrite", label); err != nil {
+		return fmt.Errorf("failed to label secret %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// GetConfigMapData fetches the data of a ConfigMap in namespace
+func (c *Client) GetConfigMapData(ctx context.Context, namespace, name string) (map[string]string, error) {
+	out, err := c.Run(ctx, nil, "get", "configmap", name, "--namespace", namespace, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get configmap %s/%s: %w", namespace, name, err)
+	}
+	var cm struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(out, &cm); err != nil {
+		return nil, fmt.Errorf("failed to parse configmap %s/%s: %w", namespace, name, err)
+	}
+	return cm.Data, nil
+}
+
+// SecretExists reports whether a Secret exists in namespace
+func (c *Client) SecretExists(ctx context.Context, namespace, name string) (bool, error) {
+	out, err := c.Run(ctx, nil, "get", "secret", name, "--namespace", namespace, "--ignore-not-found", "-o", "name")
+	if err != nil {
+		return false, fmt.Errorf("failed to look up secret %s/%s: %w", namespace, name, err)
+	}
+	return len(bytes.TrimSpace(out)) > 0, nil
+}
+
+// SecretMeta describes a Secret without its values
+type SecretMeta struct {
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Keys      []string `json:"keys"`
+}
+
+// ListSecrets lists the Secrets in the client's namespace, or in every
+// namespace. Values are dropped as soon as the response is parsed.
+func (c *Client) ListSecrets(ctx context.Context, allNamespaces bool) ([]SecretMeta, error) {
+	args := []string{"get", "secrets", "-o", "json"}
+	if allNamespaces {
+		args = append(args, "--all-namespaces")
+	}
+	out, err := c.Run(ctx, nil, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Type       string                     `json:"type"`
+			Data       map[string]json.RawMessage `json:"data"`
+			StringData map[string]json.RawMessage `json:"stringData"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse secret list: %w", err)
+	}
+
+	secrets := make([]SecretMeta, 0, len(list.Items))
+	for _, item := range list.Items {
+		meta := SecretMeta{Namespace: item.Metadata.Namespace, Name: item.Metadata.Name, Type: item.Type}
+		for key := range item.Data {
+			meta.Keys = append(meta.Keys, key)
+		}
+		for key := range item.StringData {
+			meta.Keys = append(meta.Keys, key)
+		}
+		sort.Strings(meta.Keys)
+		secrets = append(secrets, meta)
+	}
+	return secrets, nil
+}
+
+// GetSecrets fetches the Secrets matching a label selector, in the client's
+// namespace or in every namespace, each as its JSON manifest
+func (c *Client) GetSecrets(ctx context.Context, selector string, allNamespaces bool) ([]json.RawMessage, error) {
+	args := []string{"get", "secrets", "-o", "json"}
+	if selector != "" {
+		args = append(args, "--selector", selector)
+	}
+	if allNamespaces {
+		args = append(args, "--all-namespaces")
+	}
+	out, err := c.Run(ctx, nil, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secrets: %w", err)
+	}
+
+	var list struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse secret list: %w", err)
+	}
+	return list.Items, nil
+}
+
+// Token is a service account token minted with the TokenRequest API
+type Token struct {
+	Token   string
+	Expires time.Time
+}
+
+// CreateToken mints a token for a service account in namespace that expires
+// after duration, valid only for the given audiences if there are any. The
+// API server may shorten the duration, so check Expires.
+func (c *Client) CreateToken(ctx context.Context, namespace, serviceAccount string, duration time.Duration, audiences []string) (Token, error) {
+	args := []string{"create", "token", serviceAccount, "--namespace", namespace, "--duration", duration.String(), "-o", "json"}
+	for _, a := range audiences {
+		args = append(args, "--audience", a)
+	}
+	out, err := c.Run(ctx, nil, args...)
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to create token for %s/%s: %w", namespace, serviceAccount, err)
+	}
+
+	var request struct {
+		Status struct {
+			Token               string    `json:"token"`
+			ExpirationTimestamp time.Time `json:"expirationTimestamp"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(out, &request); err != nil || request.Status.Token == "" {
+		return Token{}, fmt.Errorf("failed to parse token request for %s/%s", namespace, serviceAccount)
+	}
+	return Token{Token: request.Status.Token, Expires: request.Status.ExpirationTimestamp}, nil
+}
+
+// Apply applies a manifest to the cluster
+func (c *Client) Apply(ctx context.Context, manifest []byte) error {
+	if _, err := c.Run(ctx, manifest, "apply", "-f", "-"); err != nil {
+		return fmt.Errorf("failed to apply manifest: %w", err)
+	}
+	return nil
+}
+
+// Replace replaces a Secret with a manifest, dropping keys the manifest
+// doesn't have, which apply would keep
+func (c *Client) Replace(ctx context.Context, manifest []byte) error {
+	if _, err := c.Run(ctx, manifest, "replace", "-f", "-"); err != nil {
+		return fmt.Errorf("failed to replace manifest: %w", err)
+	}
+	return nil
+}
+
+// CurrentContext returns the name of the kubeconfig context the client uses
+func (c *Client) CurrentContext(ctx context.Context) (string, error) {
+	if c.opts.Context != "" {
+		return c.opts.Context, nil
+	}
+	out, err := c.Run(ctx, nil, "config", "current-context")
+	if err != nil {
+		return "", fmt.Errorf("failed to get the current context: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Target is the cluster and namespace a client talks to, as the kubeconfig
+// resolves them
+type Target struct {
+	Context   string
+	Server    string
+	Namespace string
+}
+
+// CurrentTarget returns the kubeconfig context the client uses, the API
+// server it points at, and its namespace, read from the kubeconfig without
+// contacting the cluster
+func (c *Client) CurrentTarget(ctx context.Context) (Target, error) {
+	out, err := c.Run(ctx, nil, "config", "view", "--minify", "-o",
+		`jsonpath={.contexts[0].name}{"\t"}{.clusters[0].cluster.server}{"\t"}{.contexts[0].context.namespace}`)
+	if err != nil {
+		return Target{}, fmt.Errorf("failed to get the current context: %w", err)
+	}
+	fields := strings.SplitN(strings.TrimRight(string(out), "\n"), "\t", 3)
+	fields = append(fields, "", "", "")
+	t := Target{Context: fields[0], Server: fields[1], Namespace: fields[2]}
+	if c.opts.Namespace != "" {
+		t.Namespace = c.opts.Namespace
+	}
+	if t.Namespace == "" {
+		t.Namespace = "default"
+	}
+	return t, nil
+}
+
+// Run executes kubectl with the given arguments, retrying throttled and
+// server-side failures with exponential backoff
+func (c *Client) Run(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	fullArgs := append(c.globalArgs(), args...)
+
+	var lastErr error
+	for attempt := 0; attempt <= c.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := c.sleep(ctx, retry.Backoff(attempt, baseBackoff, maxBackoff)); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		out, err := c.exec(ctx, stdin, fullArgs)
+		if err == nil {
+			return out, nil
+		}
+		lastErr = err
+
+		if !IsRetryable(err) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("giving up after %d attempts: %w", c.opts.MaxRetries+1, lastErr)
+}
+
+// globalArgs returns the connection flags passed to every kubectl call
+func (c *Client) globalArgs() []string {
+	var args []string
+	if c.opts.Kubeconfig != "" {
+		args = append(args, "--kubeconfig", c.opts.Kubeconfig)
+	}
+	if c.opts.Context != "" {
+		args = append(args, "--context", c.opts.Context)
+	}
+	if c.opts.Namespace != "" {
+		args = append(args, "--namespace", c.opts.Namespace)
+	}
+	if c.opts.RequestTimeout > 0 {
+		args = append(args, "--request-timeout", c.opts.RequestTimeout.String())
+	}
+	return args
+}
+
+// exec runs a single kubectl invocation
+func (c *Client) exec(ctx context.Context, stdin []byte, args []string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, c.opts.Kubectl, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, &CommandError{
+			Args:   args,
+			Stderr: strings.TrimSpace(stderr.String()),
+			Err:    err,
+		}
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// CommandError describes a failed kubectl invocation
+type CommandError struct {
+	Args   []string
+	Stderr string
+	Err    error
+}
+
+func (e *CommandError) Error() string {
+	args := strings.Join(redactArgs(e.Args), " ")
+	if e.Stderr != "" {
+		return fmt.Sprintf("kubectl %s: %s", args, e.Stderr)
+	}
+	return fmt.Sprintf("kubectl %s: %v", args, e.Err)
+}
+
+// valueFlags are kubectl flags whose argument may hold Secret values
+var valueFlags = []string{"-p", "--patch", "--from-literal", "--overrides"}
+
+// redactArgs returns args with the arguments of valueFlags masked, so
+// errors never print a value that reached the command line
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i, arg := range redacted {
+		for _, flag := range valueFlags {
+			if arg == flag && i+1 < len(redacted) {
+				redacted[i+1] = "<redacted>"
+			} else if strings.HasPrefix(arg, flag+"=") {
+				redacted[i] = flag + "=<redacted>"
+			}
+		}
+	}
+	return redacted
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// retryablePattern matches kubectl error output for throttling (429) and
+// transient server-side (5xx) failures
+var retryablePattern = regexp.MustCompile(`(?i)\((TooManyRequests|InternalError|ServiceUnavailable|Timeout|ServerTimeout)\)|` +
+	`status code:? (429|50[0-4])|` +
+	`the server is currently unable to handle the request|` +
+	`the server has received too many requests|` +
+	`etcdserver: request timed out|` +
+	`connection reset by peer|` +
+	`TLS handshake timeout`)
+
+// IsRetryable reports whether a kubectl failure is worth retrying
+func IsRetryable(err error) bool {
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	return retryablePattern.MatchString(cmdErr.Stderr)
+}
+
+// limiter is a token bucket allowing qps requests per second with bursts
+type limiter struct {
+	mu     sync.Mutex
+	qps    float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newLimiter(qps float64, burst int) *limiter {
+	return &limiter{
+		qps:    qps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// wait blocks until a token is available
+func (l *limiter) wait(ctx context.Context) error {
+	for {
+		delay := l.reserve()
+		if delay == 0 {
+			return nil
+		}
+		if err := retry.Sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token if one is available, otherwise returns how long to
+// wait before the next one is
+func (l *limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.qps
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	missing := 1 - l.tokens
+	return time.Duration(missing / l.qps * float64(time.Second))
+}
@@ -0,0 +1,432 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeKubectl writes a shell script that fails with the given stderr for the
+// first `failures` invocations and then prints stdout. Every invocation's
+// arguments are appended to the returned log file.
+func fakeKubectl(t *testing.T, failures int, failStderr, stdout string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "calls.log")
+	script := filepath.Join(dir, "kubectl")
+
+	content := `#!/bin/sh
+echo "$@" >> "` + logFile + `"
+count=$(wc -l < "` + logFile + `")
+if [ "$count" -le ` + strconv.Itoa(failures) + ` ]; then
+  echo '` + failStderr + `' >&2
+  exit 1
+fi
+cat > /dev/null
+printf '%s' '` + stdout + `'
+`
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatalf("Failed to write fake kubectl: %v", err)
+	}
+	return script, logFile
+}
+
+func readCalls(t *testing.T, logFile string) []string {
+	t.Helper()
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Failed to read call log: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func newTestClient(kubectl string, opts Options) *Client {
+	opts.Kubectl = kubectl
+	c := New(opts)
+	c.sleep = func(context.Context, time.Duration) error { return nil }
+	return c
+}
+
+func TestRunRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		stderr     string
+		maxRetries int
+		wantErr    bool
+		wantCalls  int
+	}{
+		{
+			name:       "success first try",
+			failures:   0,
+			maxRetries: 3,
+			wantErr:    false,
+			wantCalls:  1,
+		},
+		{
+			name:       "retries on 429",
+			failures:   2,
+			stderr:     "Error from server (TooManyRequests): the server has received too many requests",
+			maxRetries: 3,
+			wantErr:    false,
+			wantCalls:  3,
+		},
+		{
+			name:       "retries on 503",
+			failures:   1,
+			stderr:     "Error from server (ServiceUnavailable): the server is currently unable to handle the request",
+			maxRetries: 3,
+			wantErr:    false,
+			wantCalls:  2,
+		},
+		{
+			name:       "gives up after max retries",
+			failures:   5,
+			stderr:     "Error from server (InternalError): etcdserver: request timed out",
+			maxRetries: 2,
+			wantErr:    true,
+			wantCalls:  3,
+		},
+		{
+			name:       "does not retry not found",
+			failures:   5,
+			stderr:     `Error from server (NotFound): secrets "x" not found`,
+			maxRetries: 3,
+			wantErr:    true,
+			wantCalls:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubectl, logFile := fakeKubectl(t, tt.failures, tt.stderr, "ok")
+			c := newTestClient(kubectl, Options{MaxRetries: tt.maxRetries})
+
+			out, err := c.Run(context.Background(), nil, "get", "secret", "x")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && string(out) != "ok" {
+				t.Errorf("Run() output = %q, want %q", out, "ok")
+			}
+			if calls := readCalls(t, logFile); len(calls) != tt.wantCalls {
+				t.Errorf("Run() made %d calls, want %d", len(calls), tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGlobalArgs(t *testing.T) {
+	kubectl, logFile := fakeKubectl(t, 0, "", "")
+	c := newTestClient(kubectl, Options{
+		Kubeconfig:     "/tmp/kubeconfig",
+		Context:        "staging",
+		Namespace:      "prod",
+		RequestTimeout: 5 * time.Second,
+	})
+
+	if _, err := c.GetSecret(context.Background(), "db"); err != nil {
+		t.Fatalf("GetSecret() failed: %v", err)
+	}
+
+	want := "--kubeconfig /tmp/kubeconfig --context staging --namespace prod --request-timeout 5s get secret db -o yaml"
+	if calls := readCalls(t, logFile); calls[0] != want {
+		t.Errorf("GetSecret() args = %q, want %q", calls[0], want)
+	}
+}
+
+func TestApply(t *testing.T) {
+	kubectl, logFile := fakeKubectl(t, 0, "", "secret/db configured")
+	c := newTestClient(kubectl, Options{})
+
+	if err := c.Apply(context.Background(), []byte("kind: Secret")); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	if calls := readCalls(t, logFile); calls[0] != "apply -f -" {
+		t.Errorf("Apply() args = %q, want %q", calls[0], "apply -f -")
+	}
+}
+
+func TestListSecrets(t *testing.T) {
+	list := `{"items":[{"metadata":{"name":"db","namespace":"prod"},"type":"Opaque","data":{"password":"c2VjcmV0","user":"YWRtaW4="}}]}`
+	kubectl, logFile := fakeKubectl(t, 0, "", list)
+	c := newTestClient(kubectl, Options{})
+
+	secrets, err := c.ListSecrets(context.Background(), true)
+	if err != nil {
+		t.Fatalf("ListSecrets() failed: %v", err)
+	}
+	want := SecretMeta{Namespace: "prod", Name: "db", Type: "Opaque", Keys: []string{"password", "user"}}
+	if len(secrets) != 1 || !reflect.DeepEqual(secrets[0], want) {
+		t.Errorf("ListSecrets() = %+v, want %+v", secrets, want)
+	}
+	if calls := readCalls(t, logFile); calls[0] != "get secrets -o json --all-namespaces" {
+		t.Errorf("ListSecrets() args = %q", calls[0])
+	}
+}
+
+func TestCreateToken(t *testing.T) {
+	request := `{"kind":"TokenRequest","status":{"token":"eyJhbGciOi.x.y","expirationTimestamp":"2026-01-02T04:00:00Z"}}`
+	kubectl, logFile := fakeKubectl(t, 0, "", request)
+	c := newTestClient(kubectl, Options{})
+
+	token, err := c.CreateToken(context.Background(), "ci", "deployer", time.Hour, []string{"vault", "api"})
+	if err != nil {
+		t.Fatalf("CreateToken() failed: %v", err)
+	}
+	want := Token{Token: "eyJhbGciOi.x.y", Expires: time.Date(2026, 1, 2, 4, 0, 0, 0, time.UTC)}
+	if !token.Expires.Equal(want.Expires) || token.Token != want.Token {
+		t.Errorf("CreateToken() = %+v, want %+v", token, want)
+	}
+	if calls := readCalls(t, logFile); calls[0] != "create token deployer --namespace ci --duration 1h0m0s -o json --audience vault --audience api" {
+		t.Errorf("CreateToken() args = %q", calls[0])
+	}
+
+	kubectl, _ = fakeKubectl(t, 0, "", "{}")
+	if _, err := newTestClient(kubectl, Options{}).CreateToken(context.Background(), "ci", "deployer", time.Hour, nil); err == nil {
+		t.Error("CreateToken() without a token in the response should fail")
+	}
+}
+
+func TestCurrentContext(t *testing.T) {
+	kubectl, logFile := fakeKubectl(t, 0, "", "staging\n")
+	if got, err := newTestClient(kubectl, Options{}).CurrentContext(context.Background()); err != nil || got != "staging" {
+		t.Errorf("CurrentContext() = %q, %v, want staging", got, err)
+	}
+	if got, _ := newTestClient(kubectl, Options{Context: "prod"}).CurrentContext(context.Background()); got != "prod" {
+		t.Errorf("CurrentContext() with --context = %q, want prod", got)
+	}
+	if calls := readCalls(t, logFile); len(calls) != 1 || calls[0] != "config current-context" {
+		t.Errorf("calls = %q, want one lookup", calls)
+	}
+}
+
+func TestCurrentTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		opts Options
+		want Target
+	}{
+		{"from kubeconfig", "staging\thttps://staging.example.com:6443\tapps", Options{Context: "staging"}, Target{"staging", "https://staging.example.com:6443", "apps"}},
+		{"default namespace", "staging\thttps://staging.example.com:6443\t", Options{}, Target{"staging", "https://staging.example.com:6443", "default"}},
+		{"namespace flag", "staging\thttps://staging.example.com:6443\tapps", Options{Namespace: "prod"}, Target{"staging", "https://staging.example.com:6443", "prod"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubectl, logFile := fakeKubectl(t, 0, "", tt.out)
+			got, err := newTestClient(kubectl, tt.opts).CurrentTarget(context.Background())
+			if err != nil || got != tt.want {
+				t.Errorf("CurrentTarget() = %+v, %v, want %+v", got, err, tt.want)
+			}
+			if calls := readCalls(t, logFile); len(calls) != 1 || !strings.Contains(calls[0], "config view --minify") {
+				t.Errorf("calls = %q, want one kubeconfig lookup", calls)
+			}
+		})
+	}
+}
+
+func TestGetConfigMapData(t *testing.T) {
+	kubectl, logFile := fakeKubectl(t, 0, "", `{"kind":"ConfigMap","data":{"domain":"example.com"}}`)
+	c := newTestClient(kubectl, Options{})
+
+	data, err := c.GetConfigMapData(context.Background(), "kube-system", "swk-vars")
+	if err != nil || data["domain"] != "example.com" {
+		t.Errorf("GetConfigMapData() = %v, %v", data, err)
+	}
+	if calls := readCalls(t, logFile); calls[0] != "get configmap swk-vars --namespace kube-system -o json" {
+		t.Errorf("GetConfigMapData() args = %q", calls[0])
+	}
+}
+
+func TestNamespacedCalls(t *testing.T) {
+	kubectl, logFile := fakeKubectl(t, 0, "", "")
+	c := newTestClient(kubectl, Options{})
+
+	if _, err := c.GetSecretIn(context.Background(), "prod", "db"); err != nil {
+		t.Fatalf("GetSecretIn() failed: %v", err)
+	}
+	if err := c.DeleteSecret(context.Background(), "prod", "db"); err != nil {
+		t.Fatalf("DeleteSecret() failed: %v", err)
+	}
+	if err := c.LabelSecret(context.Background(), "prod", "db", "team=ops"); err != nil {
+		t.Fatalf("LabelSecret() failed: %v", err)
+	}
+	if err := c.PatchSecret(context.Background(), "prod", "db", []byte(`{"data":{}}`)); err != nil {
+		t.Fatalf("PatchSecret() failed: %v", err)
+	}
+	want := []string{
+		"get secret db --namespace prod -o yaml",
+		"delete secret db --namespace prod",
+		"label secret db --namespace prod --overwrite team=ops",
+		"patch secret db --namespace prod --type merge --patch-file",
+	}
+	calls := readCalls(t, logFile)
+	if len(calls) == len(want) {
+		// The patch file has a random name
+		calls[3], _, _ = strings.Cut(calls[3], " /")
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
+
+func TestPatchSecret(t *testing.T) {
+	dir := t.TempDir()
+	kubectl := filepath.Join(dir, "kubectl")
+	// Keep a copy of the patch file and its mode, which are gone after
+	script := "#!/bin/sh\necho \"$@\" > " + dir + "/args\ncp \"$9\" " + dir + "/patch\nls -l \"$9\" | cut -c1-10 > " + dir + "/mode\n"
+	if err := os.WriteFile(kubectl, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	patch := `{"data":{"password":"c2VjcmV0"}}`
+	if err := newTestClient(kubectl, Options{}).PatchSecret(context.Background(), "prod", "db", []byte(patch)); err != nil {
+		t.Fatalf("PatchSecret() failed: %v", err)
+	}
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	if strings.Contains(string(args), "c2VjcmV0") {
+		t.Errorf("args = %q, want the values kept off the command line", args)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "patch")); string(got) != patch {
+		t.Errorf("patch file = %q, want %q", got, patch)
+	}
+	if mode, _ := os.ReadFile(filepath.Join(dir, "mode")); strings.TrimSpace(string(mode)) != "-rw-------" {
+		t.Errorf("patch file mode = %q, want -rw-------", mode)
+	}
+	if file := strings.Fields(string(args))[8]; fileExists(file) {
+		t.Errorf("patch file %s was left behind", file)
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestCommandErrorRedacts(t *testing.T) {
+	err := &CommandError{
+		Args:   []string{"patch", "secret", "db", "-p", `{"data":{"password":"c2VjcmV0"}}`, "--from-literal=password=hunter2"},
+		Stderr: "Error from server (Forbidden): nope",
+	}
+	want := "kubectl patch secret db -p <redacted> --from-literal=<redacted>: Error from server (Forbidden): nope"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if err.Args[4] == "<redacted>" {
+		t.Error("Error() changed Args")
+	}
+}
+
+func TestSecretExists(t *testing.T) {
+	tests := []struct {
+		name   string
+		stdout string
+		want   bool
+	}{
+		{"exists", "secret/db\n", true},
+		{"missing", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubectl, logFile := fakeKubectl(t, 0, "", tt.stdout)
+			c := newTestClient(kubectl, Options{})
+
+			got, err := c.SecretExists(context.Background(), "prod", "db")
+			if err != nil || got != tt.want {
+				t.Errorf("SecretExists() = %v, %v, want %v", got, err, tt.want)
+			}
+			if calls := readCalls(t, logFile); calls[0] != "get secret db --namespace prod --ignore-not-found -o name" {
+				t.Errorf("SecretExists() args = %q", calls[0])
+			}
+		})
+	}
+}
+
+func TestGetSecrets(t *testing.T) {
+	list := `{"items":[{"metadata":{"name":"db","namespace":"prod"},"data":{"password":"c2VjcmV0"}}]}`
+	kubectl, logFile := fakeKubectl(t, 0, "", list)
+	c := newTestClient(kubectl, Options{})
+
+	items, err := c.GetSecrets(context.Background(), "swk.dev/export=true", false)
+	if err != nil {
+		t.Fatalf("GetSecrets() failed: %v", err)
+	}
+	if len(items) != 1 || !strings.Contains(string(items[0]), `"password":"c2VjcmV0"`) {
+		t.Errorf("GetSecrets() = %s", items)
+	}
+	if calls := readCalls(t, logFile); calls[0] != "get secrets -o json --selector swk.dev/export=true" {
+		t.Errorf("GetSecrets() args = %q", calls[0])
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"plain error", errors.New("boom"), false},
+		{"throttled", &CommandError{Stderr: "Error from server (TooManyRequests): slow down"}, true},
+		{"status code", &CommandError{Stderr: "an error on the server has prevented the request, status code 502"}, true},
+		{"forbidden", &CommandError{Stderr: "Error from server (Forbidden): nope"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newLimiter(2, 2)
+	l.now = func() time.Time { return now }
+
+	// Burst is available immediately
+	for i := 0; i < 2; i++ {
+		if d := l.reserve(); d != 0 {
+			t.Fatalf("reserve() #%d = %v, want 0", i, d)
+		}
+	}
+
+	// Bucket is empty, next token arrives after 1/qps
+	if d := l.reserve(); d != 500*time.Millisecond {
+		t.Errorf("reserve() on empty bucket = %v, want 500ms", d)
+	}
+
+	now = now.Add(time.Second)
+	if d := l.reserve(); d != 0 {
+		t.Errorf("reserve() after refill = %v, want 0", d)
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	var opts Options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts.BindFlags(fs)
+
+	if err := fs.Parse([]string{"-n", "prod", "-context", "ctx", "-request-timeout", "3s", "-qps", "1"}); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if opts.Namespace != "prod" || opts.Context != "ctx" {
+		t.Errorf("BindFlags() namespace/context = %q/%q", opts.Namespace, opts.Context)
+	}
+	if opts.RequestTimeout != 3*time.Second {
+		t.Errorf("BindFlags() request timeout = %v, want 3s", opts.RequestTimeout)
+	}
+	if opts.QPS != 1 || opts.Burst != DefaultBurst {
+		t.Errorf("BindFlags() qps/burst = %v/%v", opts.QPS, opts.Burst)
+	}
+}
@@ -0,0 +1,192 @@
+// Package confformat handles values that are themselves config files, such
+// as Java .properties, INI, XML, or TOML files. A value is expanded into an
+// easier to edit form, and collapsed back so that every line the edit
+// didn't touch keeps its exact original bytes, keeping diffs of the
+// underlying config minimal.
+package confformat
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Format is a config file format
+type Format interface {
+	// Name is the format's name, as given to --format
+	Name() string
+	// Ext is the file extension for exploded values, including the dot
+	Ext() string
+	// Detect reports whether value looks like this format
+	Detect(value string) bool
+	// Validate checks that an edited value is well-formed
+	Validate(value string) error
+	// split breaks a value into logical lines: their exact original text
+	// and their form for editing, and returns the separator that joins the
+	// original text, usually the line ending
+	split(value string) ([]line, string)
+}
+
+// line is one logical line of a value
+type line struct {
+	raw     string
+	display string
+}
+
+var formats = map[string]Format{
+	"ini":        ini{},
+	"properties": properties{},
+	"toml":       tomlFormat{},
+	"xml":        xmlFormat{},
+}
+
+// Lookup returns the format with the given name
+func Lookup(name string) (Format, error) {
+	if f, ok := formats[name]; ok {
+		return f, nil
+	}
+	return nil, fmt.Errorf("unknown format %q (available: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Names returns the available format names, sorted
+func Names() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Detect returns the format a value looks like, or nil. The stricter
+// formats are checked first: valid TOML is often valid INI, and INI without
+// sections is valid properties.
+func Detect(value string) Format {
+	for _, f := range []Format{xmlFormat{}, tomlFormat{}, ini{}, properties{}} {
+		if f.Detect(value) {
+			return f
+		}
+	}
+	return nil
+}
+
+// Expand returns value in its form for editing
+func Expand(f Format, value string) string {
+	lines, _ := f.split(value)
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = l.display
+	}
+	expanded := strings.Join(out, "\n")
+	if strings.HasSuffix(value, "\n") {
+		expanded += "\n"
+	}
+	return expanded
+}
+
+// Collapse turns an edited expansion of original back into a value. Lines
+// that are unchanged, even if moved, get their original text back; new and
+// changed lines are taken as edited. Line endings follow the original, and
+// if it was compact, e.g. XML on a single line, new lines are unindented
+// and joined without line breaks.
+func Collapse(f Format, original, edited string) string {
+	// Queue the original text of every logical line by its display form,
+	// so a repeated line (e.g. a blank one) maps to its occurrences in order
+	unused := map[string][]string{}
+	lines, sep := f.split(original)
+	for _, l := range lines {
+		unused[l.display] = append(unused[l.display], l.raw)
+	}
+
+	edited = strings.ReplaceAll(edited, "\r\n", "\n")
+	if edited == "" {
+		return ""
+	}
+
+	var out []string
+	for _, text := range strings.Split(strings.TrimSuffix(edited, "\n"), "\n") {
+		if raws := unused[text]; len(raws) > 0 {
+			out = append(out, raws[0])
+			unused[text] = raws[1:]
+		} else if sep == "" {
+			out = append(out, strings.TrimLeft(text, " \t"))
+		} else {
+			out = append(out, text)
+		}
+	}
+
+	trailing := ""
+	if strings.HasSuffix(edited, "\n") {
+		trailing = sep
+	}
+	return strings.Join(out, sep) + trailing
+}
+
+// physicalLines splits a value into lines without their endings
+func physicalLines(value string) []string {
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "\n"), "\r")
+	if value == "" {
+		return nil
+	}
+	lines := strings.Split(value, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+	return lines
+}
+
+// lineEnding returns the line ending a value uses
+func lineEnding(value string) string {
+	if strings.Contains(value, "\r\n") {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// ini is the Windows-style INI format: [section] headers, key = value
+// pairs, and ; or # comments
+type ini struct{}
+
+var (
+	iniSection = regexp.MustCompile(`^\s*\[[^\]]+\]\s*$`)
+	keyValue   = regexp.MustCompile(`^\s*[^\s=:#;!][^=:]*?\s*[=:]`)
+)
+
+func (ini) Name() string { return "ini" }
+
+func (ini) Ext() string { return ".ini" }
+
+func (ini) Detect(value string) bool {
+	sections, pairs := 0, 0
+	for _, l := range physicalLines(value) {
+		trimmed := strings.TrimSpace(l)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, ";") || strings.HasPrefix(trimmed, "#"):
+		case iniSection.MatchString(l):
+			sections++
+		case keyValue.MatchString(l):
+			pairs++
+		default:
+			return false
+		}
+	}
+	return sections > 0 && pairs > 0
+}
+
+// Validate accepts any INI file; parsers differ too much to be stricter
+func (ini) Validate(string) error { return nil }
+
+func (ini) split(value string) ([]line, string) {
+	return textLines(value)
+}
+
+// textLines splits a value into lines kept as they are, apart from
+// dropping \r from CRLF endings
+func textLines(value string) ([]line, string) {
+	var lines []line
+	for _, l := range physicalLines(value) {
+		lines = append(lines, line{raw: l, display: l})
+	}
+	return lines, lineEnding(value)
+}
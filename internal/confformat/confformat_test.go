@@ -0,0 +1,171 @@
+package confformat
+
+import (
+	"strings"
+	"testing"
+)
+
+const propertiesValue = "# Database\r\n" +
+	"db.url = jdbc:postgresql://db:5432/app\r\n" +
+	"db.user=caf\\u00e9\r\n" +
+	"db.hosts = a,\\\r\n" +
+	"           b\r\n" +
+	"db.eq = x\\u003dy\r\n"
+
+const iniValue = "; main config\r\n[server]\r\nport = 8080\r\n\r\n[auth]\r\ntoken = abc\r\n"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"properties", propertiesValue, "properties"},
+		{"ini", iniValue, "ini"},
+		{"single pair", "password=x", ""},
+		{"compact xml", `<?xml version="1.0"?><config><a>1</a></config>`, "xml"},
+		{"toml", "title = \"app\"\n\n[db]\nport = 5432\n", "toml"},
+		{"single toml key", "count = 5", ""},
+		{"broken xml", "<a><b></a>", ""},
+		{"prose", "hello world\nthis is text", ""},
+		{"json", `{"a": 1, "b": 2}`, ""},
+		{"pem", "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if f := Detect(tt.value); f != nil {
+				got = f.Name()
+			}
+			if got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	f, _ := Lookup("properties")
+	want := "# Database\n" +
+		"db.url = jdbc:postgresql://db:5432/app\n" +
+		"db.user=café\n" +
+		"db.hosts = a,b\n" +
+		"db.eq = x\\u003dy\n"
+	if got := Expand(f, propertiesValue); got != want {
+		t.Errorf("Expand() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	props, _ := Lookup("properties")
+	ini, _ := Lookup("ini")
+
+	tests := []struct {
+		name     string
+		format   Format
+		original string
+		edit     func(string) string
+		want     string
+	}{
+		{
+			name:     "untouched",
+			format:   props,
+			original: propertiesValue,
+			edit:     func(s string) string { return s },
+			want:     propertiesValue,
+		},
+		{
+			name:     "one line changed",
+			format:   props,
+			original: propertiesValue,
+			edit:     func(s string) string { return strings.Replace(s, "db:5432", "db2:5432", 1) },
+			want:     strings.Replace(propertiesValue, "db:5432", "db2:5432", 1),
+		},
+		{
+			name:     "line added and removed",
+			format:   props,
+			original: propertiesValue,
+			edit: func(s string) string {
+				return strings.Replace(s, "# Database\n", "", 1) + "db.pool=10\n"
+			},
+			want: strings.Replace(propertiesValue, "# Database\r\n", "", 1) + "db.pool=10\r\n",
+		},
+		{
+			name:     "ini keeps crlf",
+			format:   ini,
+			original: iniValue,
+			edit:     func(s string) string { return strings.Replace(s, "8080", "9090", 1) },
+			want:     strings.Replace(iniValue, "8080", "9090", 1),
+		},
+		{
+			name:     "compact xml",
+			format:   xmlFormat{},
+			original: `<?xml version="1.0"?><c><db host='a'/><user>bob &amp; co</user><port>1</port></c>`,
+			edit: func(s string) string {
+				return strings.Replace(s, "<port>1</port>", "<port>2</port>\n  <tls/>", 1) + "\n"
+			},
+			want: `<?xml version="1.0"?><c><db host='a'/><user>bob &amp; co</user><port>2</port><tls/></c>`,
+		},
+		{
+			name:     "indented xml",
+			format:   xmlFormat{},
+			original: "<c>\r\n  <port>1</port>\r\n</c>\r\n",
+			edit:     func(s string) string { return strings.Replace(s, "1", "2", 1) },
+			want:     "<c>\r\n  <port>2</port>\r\n</c>\r\n",
+		},
+		{
+			name:     "no final newline",
+			format:   ini,
+			original: "[a]\nb=1",
+			edit:     func(s string) string { return s },
+			want:     "[a]\nb=1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited := tt.edit(Expand(tt.format, tt.original))
+			if got := Collapse(tt.format, tt.original, edited); got != tt.want {
+				t.Errorf("Collapse() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandXML(t *testing.T) {
+	got := Expand(xmlFormat{}, `<c><db host="a"/><list><item>1</item></list><empty></empty></c>`)
+	want := "<c>\n  <db host=\"a\"/>\n  <list>\n    <item>1</item>\n  </list>\n  <empty></empty>\n</c>"
+	if got != want {
+		t.Errorf("Expand() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		format  string
+		value   string
+		wantErr bool
+	}{
+		{"xml", "<a><b/></a>", false},
+		{"xml", "<a><b></a>", true},
+		{"xml", "<a/><b/>", true},
+		{"xml", "text", true},
+		{"toml", "[db]\nport = 5432\n", false},
+		{"toml", "[db]\nport = \n", true},
+		{"properties", "anything goes", false},
+	}
+
+	for _, tt := range tests {
+		f, _ := Lookup(tt.format)
+		if err := f.Validate(tt.value); (err != nil) != tt.wantErr {
+			t.Errorf("%s Validate(%q) error = %v, wantErr %v", tt.format, tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if _, err := Lookup("yaml"); err == nil {
+		t.Error("Lookup() expected an error for an unknown format")
+	}
+}
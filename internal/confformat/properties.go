@@ -0,0 +1,89 @@
+package confformat
+
+import (
+	"strconv"
+	"strings"
+)
+
+// properties is the Java .properties format. For editing, continuation
+// lines are joined and \uXXXX escapes shown as the characters they stand
+// for.
+type properties struct{}
+
+func (properties) Name() string { return "properties" }
+
+func (properties) Ext() string { return ".properties" }
+
+func (p properties) Detect(value string) bool {
+	pairs := 0
+	lines, _ := p.split(value)
+	for _, l := range lines {
+		trimmed := strings.TrimSpace(l.display)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "!"):
+		case keyValue.MatchString(l.display):
+			pairs++
+		default:
+			return false
+		}
+	}
+	return pairs >= 2
+}
+
+// Validate accepts any properties file, as Java does
+func (properties) Validate(string) error { return nil }
+
+func (properties) split(value string) ([]line, string) {
+	eol := lineEnding(value)
+	var lines []line
+	physical := physicalLines(value)
+	for i := 0; i < len(physical); i++ {
+		raw, display := physical[i], physical[i]
+		// A line ending in an odd number of backslashes continues on the
+		// next one, whose leading whitespace is ignored
+		for continues(display) && i+1 < len(physical) {
+			i++
+			raw += eol + physical[i]
+			display = display[:len(display)-1] + strings.TrimLeft(physical[i], " \t\f")
+		}
+		lines = append(lines, line{raw: raw, display: unescapeUnicode(display)})
+	}
+	return lines, eol
+}
+
+// continues reports whether a properties line ends in an unescaped backslash
+func continues(l string) bool {
+	n := len(l) - len(strings.TrimRight(l, `\`))
+	return n%2 == 1
+}
+
+// unescapeUnicode replaces \uXXXX escapes with their characters, except
+// where that would change the meaning of the line: for characters that are
+// special in properties files, whitespace, and control characters
+func unescapeUnicode(l string) string {
+	if !strings.Contains(l, `\u`) {
+		return l
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(l); i++ {
+		if l[i] != '\\' || i+1 >= len(l) {
+			b.WriteByte(l[i])
+			continue
+		}
+		if l[i+1] != 'u' || i+6 > len(l) {
+			// Some other escape, e.g. \\ or \=, copied as is
+			b.WriteString(l[i : i+2])
+			i++
+			continue
+		}
+		r, err := strconv.ParseUint(l[i+2:i+6], 16, 32)
+		if err != nil || r < 0x20 || r == 0x7f || strings.ContainsRune(" \\=:#!", rune(r)) || (r >= 0xd800 && r <= 0xdfff) {
+			b.WriteString(l[i : i+6])
+		} else {
+			b.WriteRune(rune(r))
+		}
+		i += 5
+	}
+	return b.String()
+}
@@ -0,0 +1,42 @@
+package confformat
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// tomlFormat is TOML. It is edited as it is, line by line.
+type tomlFormat struct{}
+
+func (tomlFormat) Name() string { return "toml" }
+
+func (tomlFormat) Ext() string { return ".toml" }
+
+// Detect accepts valid TOML with a table or at least two keys, so a lone
+// "count = 5" isn't mistaken for a config file
+func (tomlFormat) Detect(value string) bool {
+	var v map[string]any
+	md, err := toml.Decode(value, &v)
+	if err != nil {
+		return false
+	}
+	for _, key := range md.Keys() {
+		if md.Type(key.String()) == "Hash" {
+			return true
+		}
+	}
+	return len(md.Keys()) >= 2
+}
+
+func (tomlFormat) Validate(value string) error {
+	var v map[string]any
+	if _, err := toml.Decode(value, &v); err != nil {
+		return fmt.Errorf("invalid TOML: %w", err)
+	}
+	return nil
+}
+
+func (tomlFormat) split(value string) ([]line, string) {
+	return textLines(value)
+}
@@ -0,0 +1,150 @@
+package confformat
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xmlFormat is XML. Compact XML, all on one line, is indented for editing
+// and joined up again afterwards; XML that already has line breaks is
+// edited as it is.
+type xmlFormat struct{}
+
+// xmlIndent indents nested elements for editing
+const xmlIndent = "  "
+
+func (xmlFormat) Name() string { return "xml" }
+
+func (xmlFormat) Ext() string { return ".xml" }
+
+func (x xmlFormat) Detect(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), "<") && x.Validate(value) == nil
+}
+
+// Validate checks that value is well-formed XML with a single root element
+func (xmlFormat) Validate(value string) error {
+	d := xml.NewDecoder(strings.NewReader(value))
+	depth, roots := 0, 0
+	for {
+		t, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid XML: %w", err)
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots++
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && strings.TrimSpace(string(t)) != "" {
+				return fmt.Errorf("invalid XML: text outside the root element")
+			}
+		}
+	}
+	if roots != 1 {
+		return fmt.Errorf("invalid XML: want one root element, found %d", roots)
+	}
+	return nil
+}
+
+func (xmlFormat) split(value string) ([]line, string) {
+	if strings.Contains(strings.TrimSpace(value), "\n") {
+		return textLines(value)
+	}
+	lines, err := xmlLines(value)
+	if err != nil {
+		return textLines(value)
+	}
+	return lines, ""
+}
+
+// rawToken is an XML token with its exact source text
+type rawToken struct {
+	token xml.Token
+	raw   string
+}
+
+// xmlLines breaks compact XML into indented lines: one per tag, with
+// elements holding only text, like <a>b</a>, kept on one line
+func xmlLines(value string) ([]line, error) {
+	d := xml.NewDecoder(strings.NewReader(value))
+	var tokens []rawToken
+	var offset int64
+	for {
+		t, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		end := d.InputOffset()
+		tokens = append(tokens, rawToken{token: xml.CopyToken(t), raw: value[offset:end]})
+		offset = end
+	}
+
+	var lines []line
+	depth := 0
+	pending := "" // whitespace before the first line
+	add := func(raw string, depth int) {
+		lines = append(lines, line{raw: pending + raw, display: strings.Repeat(xmlIndent, depth) + raw})
+		pending = ""
+	}
+	isEnd := func(i int) bool {
+		if i >= len(tokens) {
+			return false
+		}
+		_, ok := tokens[i].token.(xml.EndElement)
+		return ok
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch token := t.token.(type) {
+		case xml.CharData:
+			if strings.TrimSpace(string(token)) == "" {
+				// Formatting whitespace stays with the line before it
+				if len(lines) > 0 {
+					lines[len(lines)-1].raw += t.raw
+				} else {
+					pending += t.raw
+				}
+				continue
+			}
+			add(t.raw, depth)
+		case xml.StartElement:
+			switch {
+			case isEnd(i + 1):
+				// <a></a> or <a/>
+				add(t.raw+tokens[i+1].raw, depth)
+				i++
+			case isEnd(i + 2):
+				if _, ok := tokens[i+1].token.(xml.CharData); ok {
+					add(t.raw+tokens[i+1].raw+tokens[i+2].raw, depth)
+					i += 2
+					continue
+				}
+				add(t.raw, depth)
+				depth++
+			default:
+				add(t.raw, depth)
+				depth++
+			}
+		case xml.EndElement:
+			depth--
+			add(t.raw, depth)
+		default:
+			add(t.raw, depth)
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,350 @@
+// Package config loads the user's swk configuration file
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/approval"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/auth"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/owners"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/report"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPath names the environment variable that overrides the config path
+const EnvPath = "SWK_CONFIG"
+
+// Config is the contents of the config file. Every field is optional.
+type Config struct {
+	// Watched lists glob patterns for directories that a local controller,
+	// e.g. a GitOps agent or a file-watching operator, reconciles from.
+	// A leading ~/ is the home directory.
+	Watched []string `yaml:"watched"`
+
+	// Teams maps team names to members, by git email or cluster user name
+	Teams map[string][]string `yaml:"teams"`
+	// Owners assigns key patterns to teams; the last matching rule wins
+	Owners []owners.Rule `yaml:"owners"`
+
+	// Constraints limit the values of the keys matching their pattern, in
+	// every Secret swk writes
+	Constraints []secret.Constraint `yaml:"constraints"`
+
+	// Profiles are named sets of stricter defaults, selected with --profile
+	// or by the namespace of the Secret being written
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	BreakGlass struct {
+		// Webhook receives a JSON notification of every break-glass edit
+		Webhook string `yaml:"webhook"`
+	} `yaml:"breakglass"`
+
+	Approval struct {
+		// Webhook receives approval requests for profiles that need them,
+		// and answers polls for their status at WEBHOOK/ID
+		Webhook string `yaml:"webhook"`
+		// Timeout is how long to wait for an answer, e.g. 30m
+		Timeout string `yaml:"timeout"`
+	} `yaml:"approval"`
+
+	Vars struct {
+		// ConfigMap, as NAMESPACE/NAME, holds the template variables for
+		// swk new, such as the cluster's domain
+		ConfigMap string `yaml:"configMap"`
+	} `yaml:"vars"`
+
+	Serve Serve `yaml:"serve"`
+
+	Stats struct {
+		// Disabled stops swk from counting runs in the local stats file
+		Disabled bool `yaml:"disabled"`
+	} `yaml:"stats"`
+
+	// Reports lists where swk check, audit, and sync send what they found,
+	// unless --report is given
+	Reports []ReportSink `yaml:"reports"`
+}
+
+// ReportSink is a destination for reports, see report.NewSink
+type ReportSink struct {
+	// To is - for stdout, a file, an http(s) webhook, or s3://BUCKET/KEY
+	To string `yaml:"to"`
+	// Format is text, json, or sarif; by default it's picked from the
+	// extension of To
+	Format string `yaml:"format"`
+}
+
+// ReportSinks returns the configured report sinks
+func (c *Config) ReportSinks() ([]report.Sink, error) {
+	var sinks []report.Sink
+	for _, r := range c.Reports {
+		s, err := report.NewSink(r.To, r.Format)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// Serve configures authentication and authorization for swk serve
+type Serve struct {
+	Auth struct {
+		// Tokens maps user names to the hex SHA-256 of a static token
+		Tokens map[string]string `yaml:"tokens"`
+		OIDC   struct {
+			Issuer   string `yaml:"issuer"`
+			ClientID string `yaml:"clientID"`
+			// UsernameClaim names the user, email by default
+			UsernameClaim string `yaml:"usernameClaim"`
+		} `yaml:"oidc"`
+		// ClientCA verifies TLS client certificates, whose common name is
+		// the user
+		ClientCA string `yaml:"clientCA"`
+	} `yaml:"auth"`
+	TLS struct {
+		Cert string `yaml:"cert"`
+		Key  string `yaml:"key"`
+	} `yaml:"tls"`
+	// Routes allows routes to some users and teams; the last matching rule
+	// wins, and routes no rule matches are refused
+	Routes []auth.Rule `yaml:"routes"`
+}
+
+// Enabled reports whether any authentication method is configured
+func (s *Serve) Enabled() bool {
+	return len(s.Auth.Tokens) > 0 || s.Auth.OIDC.Issuer != "" || s.Auth.ClientCA != ""
+}
+
+// Profile holds defaults for a kind of environment, such as production
+type Profile struct {
+	// Namespaces lists glob patterns of namespaces that select the profile
+	Namespaces []string `yaml:"namespaces"`
+	// Validate is the default edit validation mode, e.g. schema
+	Validate string `yaml:"validate"`
+	// Strict rejects fields a Secret doesn't have when editing
+	Strict bool `yaml:"strict"`
+	// Mask never shows decoded values in listings such as swk export
+	Mask bool `yaml:"mask"`
+	// Confirm asks before every write
+	Confirm bool `yaml:"confirm"`
+	// Backup copies a file to FILE.bak before overwriting it
+	Backup bool `yaml:"backup"`
+	// Approval makes --apply wait for the approval webhook to approve
+	Approval bool `yaml:"approval"`
+}
+
+// Profile returns the profile called name or, if name is empty, the first
+// profile by name whose namespaces match namespace. It returns "" and nil
+// if no profile applies.
+func (c *Config) Profile(name, namespace string) (string, *Profile, error) {
+	if name != "" {
+		p, ok := c.Profiles[name]
+		if !ok {
+			return "", nil, fmt.Errorf("unknown profile %q", name)
+		}
+		return name, &p, nil
+	}
+	if namespace == "" {
+		return "", nil, nil
+	}
+
+	names := make([]string, 0, len(c.Profiles))
+	for n := range c.Profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		p := c.Profiles[n]
+		for _, pattern := range p.Namespaces {
+			if ok, _ := path.Match(pattern, namespace); ok {
+				return n, &p, nil
+			}
+		}
+	}
+	return "", nil, nil
+}
+
+// ApprovalTimeout returns how long to wait for an approval
+func (c *Config) ApprovalTimeout() time.Duration {
+	if d, err := time.ParseDuration(c.Approval.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return approval.DefaultTimeout
+}
+
+// OwnerRules returns the key ownership rules
+func (c *Config) OwnerRules() owners.Rules {
+	return owners.Rules{Teams: c.Teams, Owners: c.Owners}
+}
+
+// Path returns the config file location: $SWK_CONFIG, or swk/config.yaml in
+// the user's config dir
+func Path() (string, error) {
+	if path := os.Getenv(EnvPath); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "swk", "config.yaml"), nil
+}
+
+// Load reads the config file at path, with the SWK_* environment variables
+// overriding its settings. A missing file is an empty config.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return load(data, path, os.LookupEnv)
+}
+
+// Parse parses and validates config file data read from path, without
+// environment overrides
+func Parse(data []byte, path string) (*Config, error) {
+	return load(data, path, nil)
+}
+
+// load decodes data, applies the environment from lookup if it isn't nil,
+// and validates the result
+func load(data []byte, path string, lookup func(string) (string, bool)) (*Config, error) {
+	cfg := &Config{}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if errs := checkSchema(&root); len(errs) > 0 {
+		lines := make([]string, len(errs))
+		for i, e := range errs {
+			lines[i] = path + ":" + e.Error()
+		}
+		return nil, fmt.Errorf("invalid config:\n  %s", strings.Join(lines, "\n  "))
+	}
+	if len(root.Content) > 0 {
+		if err := root.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+	}
+	if lookup != nil {
+		applied, err := applyEnv(cfg, lookup)
+		if err != nil {
+			return nil, err
+		}
+		// Errors below may come from either
+		if len(applied) > 0 {
+			path += " or " + strings.Join(applied, ", ")
+		}
+	}
+	if err := cfg.validate(path); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validate checks settings the schema can't, naming source in errors
+func (cfg *Config) validate(path string) error {
+	for i, pattern := range cfg.Watched {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid watched pattern %q in %s: %w", pattern, path, err)
+		}
+		cfg.Watched[i] = expandHome(pattern)
+	}
+	if err := cfg.OwnerRules().Validate(); err != nil {
+		return fmt.Errorf("%w in %s", err, path)
+	}
+	for _, c := range cfg.Constraints {
+		if c.Keys == "" {
+			return fmt.Errorf("constraint without a keys pattern in %s", path)
+		}
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("constraint %q in %s: %w", c.Keys, path, err)
+		}
+	}
+	for name, p := range cfg.Profiles {
+		if p.Validate != "" && p.Validate != "schema" {
+			return fmt.Errorf("profile %s: unsupported validate mode %q in %s (supported: schema)", name, p.Validate, path)
+		}
+		for _, pattern := range p.Namespaces {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("profile %s: invalid namespace pattern %q in %s: %w", name, pattern, path, err)
+			}
+		}
+		if p.Approval && cfg.Approval.Webhook == "" {
+			return fmt.Errorf("profile %s needs approval, but no approval webhook is set in %s", name, path)
+		}
+	}
+	if t := cfg.Approval.Timeout; t != "" {
+		if d, err := time.ParseDuration(t); err != nil || d <= 0 {
+			return fmt.Errorf("invalid approval timeout %q in %s: want a duration such as 30m", t, path)
+		}
+	}
+	if cm := cfg.Vars.ConfigMap; cm != "" {
+		if ns, name, ok := strings.Cut(cm, "/"); !ok || ns == "" || name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("invalid vars ConfigMap %q in %s: want NAMESPACE/NAME", cm, path)
+		}
+	}
+	if err := cfg.Serve.validate(cfg.Teams); err != nil {
+		return fmt.Errorf("%w in %s", err, path)
+	}
+	if _, err := cfg.ReportSinks(); err != nil {
+		return fmt.Errorf("%w in %s", err, path)
+	}
+	return nil
+}
+
+// validate checks the serve section for settings that can't work together
+func (s *Serve) validate(teams map[string][]string) error {
+	for user, hash := range s.Auth.Tokens {
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("serve token for %s must be a hex SHA-256, not the token itself", user)
+		}
+	}
+	if o := s.Auth.OIDC; (o.Issuer == "") != (o.ClientID == "") {
+		return errors.New("serve OIDC needs both an issuer and a clientID")
+	}
+	if (s.TLS.Cert == "") != (s.TLS.Key == "") {
+		return errors.New("serve TLS needs both a cert and a key")
+	}
+	if s.Auth.ClientCA != "" && s.TLS.Cert == "" {
+		return errors.New("serve clientCA needs a TLS cert and key")
+	}
+	if len(s.Routes) > 0 && !s.Enabled() {
+		return errors.New("serve routes need an authentication method")
+	}
+	return auth.Policy{Teams: teams, Rules: s.Routes}.Validate()
+}
+
+// LoadDefault loads the config file at Path
+func LoadDefault() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return &Config{}, nil
+	}
+	return Load(path)
+}
+
+// expandHome replaces a leading ~/ with the home directory
+func expandHome(pattern string) string {
+	rest, ok := strings.CutPrefix(pattern, "~/")
+	if !ok {
+		return pattern
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return pattern
+	}
+	return filepath.Join(home, rest)
+}
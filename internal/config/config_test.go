@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	tests := []struct {
+		name        string
+		content     string
+		wantWatched []string
+		wantErr     bool
+	}{
+		{"empty", "", nil, false},
+		{"watched", "watched:\n  - /srv/gitops/*\n", []string{"/srv/gitops/*"}, false},
+		{"home", "watched: [~/flux]\n", []string{filepath.Join(home, "flux")}, false},
+		{"bad pattern", "watched: ['/srv/[']\n", nil, true},
+		{"bad yaml", "watched: {\n", nil, true},
+		{"owners", "teams:\n  ops: [a@example.com]\nowners:\n  - keys: '*'\n    team: ops\n", nil, false},
+		{"bad profile", "profiles:\n  prod:\n    validate: lint\n", nil, true},
+		{"unknown team", "owners:\n  - keys: '*'\n    team: ops\n", nil, true},
+		{"approval", "approval:\n  webhook: https://example.com/hook\n  timeout: 30m\nprofiles:\n  prod:\n    approval: true\n", nil, false},
+		{"approval without webhook", "profiles:\n  prod:\n    approval: true\n", nil, true},
+		{"vars", "vars:\n  configMap: kube-system/swk-vars\n", nil, false},
+		{"bad vars", "vars:\n  configMap: swk-vars\n", nil, true},
+		{"serve auth", "teams:\n  sre: [bob]\nserve:\n  auth:\n    tokens:\n      ci: " + strings.Repeat("ab", 32) + "\n    oidc:\n      issuer: https://id.example.com\n      clientID: swk\n  routes:\n    - route: POST /v1/reveals\n      teams: [sre]\n", nil, false},
+		{"plain serve token", "serve:\n  auth:\n    tokens:\n      ci: s3cret\n", nil, true},
+		{"oidc without client", "serve:\n  auth:\n    oidc:\n      issuer: https://id.example.com\n", nil, true},
+		{"client CA without TLS", "serve:\n  auth:\n    clientCA: ca.pem\n", nil, true},
+		{"routes without auth", "serve:\n  routes:\n    - route: /v1/files\n      users: [ci]\n", nil, true},
+		{"constraints", "constraints:\n  - keys: '*password*'\n    maxLength: 72\n    charset: visible\n", nil, false},
+		{"constraint without keys", "constraints:\n  - maxLength: 72\n", nil, true},
+		{"bad constraint pattern", "constraints:\n  - keys: user\n    pattern: '[a-z'\n", nil, true},
+		{"reports", "reports:\n  - to: '-'\n  - to: s3://ci-reports/swk.sarif\n  - to: https://dash.example.com/hook\n    format: json\n", nil, false},
+		{"bad report format", "reports:\n  - to: out.xml\n    format: xml\n", nil, true},
+		{"report without destination", "reports:\n  - format: json\n", nil, true},
+		{"bad approval timeout", "approval:\n  webhook: https://example.com/hook\n  timeout: soon\n", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(cfg.Watched) != len(tt.wantWatched) {
+				t.Fatalf("Watched = %v, want %v", cfg.Watched, tt.wantWatched)
+			}
+			for i := range cfg.Watched {
+				if cfg.Watched[i] != tt.wantWatched[i] {
+					t.Errorf("Watched[%d] = %q, want %q", i, cfg.Watched[i], tt.wantWatched[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil || cfg == nil || len(cfg.Watched) != 0 {
+		t.Errorf("Load() = %v, %v, want an empty config", cfg, err)
+	}
+}
+
+func TestApprovalTimeout(t *testing.T) {
+	cfg := &Config{}
+	if got := cfg.ApprovalTimeout(); got != 15*time.Minute {
+		t.Errorf("ApprovalTimeout() = %v, want the default", got)
+	}
+	cfg.Approval.Timeout = "30m"
+	if got := cfg.ApprovalTimeout(); got != 30*time.Minute {
+		t.Errorf("ApprovalTimeout() = %v, want 30m", got)
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv(EnvPath, "/etc/swk.yaml")
+	if path, err := Path(); err != nil || path != "/etc/swk.yaml" {
+		t.Errorf("Path() = %q, %v, want /etc/swk.yaml", path, err)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `profiles:
+  prod:
+    namespaces: [prod, "prod-*"]
+    confirm: true
+  staging:
+    namespaces: ["*-staging"]
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	tests := []struct {
+		name, namespace string
+		want            string
+		wantErr         bool
+	}{
+		{"", "prod", "prod", false},
+		{"", "prod-eu", "prod", false},
+		{"", "shop-staging", "staging", false},
+		{"", "dev", "", false},
+		{"", "", "", false},
+		{"staging", "prod", "staging", false},
+		{"qa", "", "", true},
+	}
+	for _, tt := range tests {
+		got, p, err := cfg.Profile(tt.name, tt.namespace)
+		if (err != nil) != tt.wantErr || got != tt.want || (p != nil) != (tt.want != "") {
+			t.Errorf("Profile(%q, %q) = %q, %v, %v, want %q", tt.name, tt.namespace, got, p, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotSet is returned by Get for a known key the file doesn't set
+var ErrNotSet = errors.New("not set")
+
+// Get returns the value at a dotted key, such as approval.webhook, in
+// config file data: scalars as they are, anything else as YAML
+func Get(data []byte, key string) (string, error) {
+	segments, _, err := resolveKey(key)
+	if err != nil {
+		return "", err
+	}
+	root, err := parseRoot(data)
+	if err != nil {
+		return "", err
+	}
+	node := root.Content[0]
+	for _, seg := range segments {
+		node = child(node, seg)
+		if node == nil {
+			return "", fmt.Errorf("%s is %w", key, ErrNotSet)
+		}
+	}
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+	out, err := encode(node)
+	return strings.TrimSuffix(string(out), "\n"), err
+}
+
+// Set sets the value at a dotted key, creating the mappings on the way,
+// and returns the new file data. Comments elsewhere are kept. Values of
+// lists and mappings are given as YAML, e.g. "[a, b]".
+func Set(data []byte, key, value string) ([]byte, error) {
+	segments, t, err := resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	var newNode *yaml.Node
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, not %q", key, value)
+		}
+		newNode = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(b)}
+	case reflect.String:
+		newNode = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	default:
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(value), &doc); err != nil || len(doc.Content) == 0 {
+			return nil, fmt.Errorf("%s needs a YAML value, e.g. [a, b] or {k: v}", key)
+		}
+		newNode = doc.Content[0]
+		blockStyle(newNode)
+		var errs []*SchemaError
+		checkNode(newNode, t, key, &errs)
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("%s: %s", key, errs[0].Msg)
+		}
+	}
+
+	root, err := parseRoot(data)
+	if err != nil {
+		return nil, err
+	}
+	node := root.Content[0]
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		next := child(node, seg)
+		switch {
+		case next != nil && last:
+			newNode.HeadComment, newNode.LineComment = next.HeadComment, next.LineComment
+			*next = *newNode
+		case next != nil:
+			if next.Kind == yaml.ScalarNode && next.Tag == "!!null" {
+				*next = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+			node = next
+		case node.Kind == yaml.SequenceNode:
+			if index, _ := strconv.Atoi(seg); index != len(node.Content) {
+				return nil, fmt.Errorf("%s: index %s is past the end of the list", key, seg)
+			}
+			next = newNode
+			if !last {
+				next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+			node.Content = append(node.Content, next)
+			node = next
+		default:
+			next = newNode
+			if !last {
+				next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				if _, err := strconv.Atoi(segments[i+1]); err == nil {
+					next = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+				}
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: seg}, next)
+			node = next
+		}
+	}
+	return encode(root)
+}
+
+// List returns every value set in config file data as key=value lines,
+// sorted by key
+func List(data []byte) ([]string, error) {
+	root, err := parseRoot(data)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	var walk func(node *yaml.Node, key string)
+	walk = func(node *yaml.Node, key string) {
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				walk(node.Content[i+1], joinKey(key, node.Content[i].Value))
+			}
+		case yaml.SequenceNode:
+			for i, item := range node.Content {
+				walk(item, joinKey(key, strconv.Itoa(i)))
+			}
+		case yaml.ScalarNode:
+			if node.Tag != "!!null" {
+				lines = append(lines, key+"="+node.Value)
+			}
+		}
+	}
+	walk(root.Content[0], "")
+	sort.Strings(lines)
+	return lines, nil
+}
+
+// resolveKey splits a dotted key into its segments and returns the type of
+// its value. A key into a map of plain values takes the rest of the key,
+// dots and all, so tokens.alice.smith names user alice.smith.
+func resolveKey(key string) ([]string, reflect.Type, error) {
+	if key == "" {
+		return nil, nil, errors.New("missing key")
+	}
+	parts := strings.Split(key, ".")
+	var segments []string
+	t := configType
+	for i := 0; i < len(parts); i++ {
+		part := parts[i]
+		switch t.Kind() {
+		case reflect.Struct:
+			found := false
+			for _, f := range fields(t) {
+				if f.name == part {
+					t, found = f.typ, true
+					break
+				}
+			}
+			if !found {
+				return nil, nil, fmt.Errorf("unknown config key %q", key)
+			}
+		case reflect.Map:
+			if k := t.Elem().Kind(); k == reflect.String || k == reflect.Bool {
+				part = strings.Join(parts[i:], ".")
+				i = len(parts)
+			}
+			t = t.Elem()
+		case reflect.Slice:
+			if n, err := strconv.Atoi(part); err != nil || n < 0 {
+				return nil, nil, fmt.Errorf("config key %q: %s is a list, so %q must be an index", key, strings.Join(segments, "."), part)
+			}
+			t = t.Elem()
+		default:
+			return nil, nil, fmt.Errorf("unknown config key %q", key)
+		}
+		if part == "" {
+			return nil, nil, fmt.Errorf("invalid config key %q", key)
+		}
+		segments = append(segments, part)
+	}
+	return segments, t, nil
+}
+
+// parseRoot parses config file data into a document holding a mapping
+func parseRoot(data []byte) (*yaml.Node, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(root.Content) == 0 {
+		root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if root.Content[0].Kind == yaml.ScalarNode && root.Content[0].Tag == "!!null" {
+		root.Content[0] = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	if root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("the config must be a mapping")
+	}
+	return &root, nil
+}
+
+// child returns the value of key in a mapping, or the item at index key
+// in a list
+func child(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(node.Content) {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+// blockStyle writes node and its children in block style, as the rest of
+// the file
+func blockStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle
+	for _, c := range node.Content {
+		blockStyle(c)
+	}
+}
+
+func encode(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const editConfig = `# swk settings
+approval:
+  webhook: https://example.com/hook # the bot
+profiles:
+  prod:
+    namespaces: [prod]
+    strict: true
+serve:
+  auth:
+    tokens:
+      alice.smith: abc
+`
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		key     string
+		want    string
+		wantErr error
+	}{
+		{"approval.webhook", "https://example.com/hook", nil},
+		{"profiles.prod.strict", "true", nil},
+		{"profiles.prod.namespaces", "[prod]", nil},
+		{"profiles.prod.namespaces.0", "prod", nil},
+		{"serve.auth.tokens.alice.smith", "abc", nil},
+		{"approval.timeout", "", ErrNotSet},
+		{"approval.webhok", "", nil},
+		{"profiles.prod.bogus", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := Get([]byte(editConfig), tt.key)
+			if tt.want == "" {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Errorf("Get() = %q, %v, want error %v", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Get() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{"replace", "approval.webhook", "https://example.com/new", []string{"webhook: https://example.com/new # the bot", "# swk settings"}, false},
+		{"new section", "vars.configMap", "kube-system/vars", []string{"vars:\n  configMap: kube-system/vars"}, false},
+		{"bool", "profiles.staging.confirm", "true", []string{"  staging:\n    confirm: true"}, false},
+		{"string that looks like a bool", "approval.timeout", "true", []string{`timeout: "true"`}, false},
+		{"list", "watched", "[/srv/a, /srv/b]", []string{"watched:\n  - /srv/a\n  - /srv/b"}, false},
+		{"append", "profiles.prod.namespaces.1", "prod-*", []string{"    namespaces: [prod, prod-*]"}, false},
+		{"map key with dots", "serve.auth.tokens.bob.jones", "def", []string{"      bob.jones: def"}, false},
+		{"not a bool", "profiles.prod.strict", "maybe", nil, true},
+		{"unknown key", "approval.webhok", "x", nil, true},
+		{"wrong shape", "watched", "{a: b}", nil, true},
+		{"past the end", "profiles.prod.namespaces.5", "x", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Set([]byte(editConfig), tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("Set() =\n%s\nwant it to contain %q", got, want)
+				}
+			}
+			if err == nil {
+				if v, err := Get(got, tt.key); err != nil || (!strings.HasPrefix(tt.value, "[") && v != tt.value) {
+					t.Errorf("Get() after Set() = %q, %v, want %q", v, err, tt.value)
+				}
+			}
+		})
+	}
+}
+
+func TestSetEmpty(t *testing.T) {
+	got, err := Set(nil, "breakglass.webhook", "https://example.com")
+	if err != nil || string(got) != "breakglass:\n  webhook: https://example.com\n" {
+		t.Errorf("Set() = %q, %v", got, err)
+	}
+}
+
+func TestList(t *testing.T) {
+	got, err := List([]byte(editConfig))
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	want := []string{
+		"approval.webhook=https://example.com/hook",
+		"profiles.prod.namespaces.0=prod",
+		"profiles.prod.strict=true",
+		"serve.auth.tokens.alice.smith=abc",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("List() = %q, want %q", got, want)
+	}
+}
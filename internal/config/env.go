@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variables that override settings
+const EnvPrefix = "SWK_"
+
+// EnvName returns the environment variable overriding a dotted key:
+// approval.webhook is SWK_APPROVAL_WEBHOOK and vars.configMap is
+// SWK_VARS_CONFIG_MAP
+func EnvName(key string) string {
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	prev := rune(0)
+	for _, r := range key {
+		switch {
+		case r == '.' || r == '-':
+			b.WriteByte('_')
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			b.WriteByte('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		prev = r
+	}
+	return b.String()
+}
+
+// EnvVar is a setting that can be overridden from the environment
+type EnvVar struct {
+	Name string
+	Key  string
+	// Format describes the value, e.g. "true or false" or "YAML"
+	Format string
+}
+
+// EnvVars lists every environment variable that overrides a setting, by
+// name. Plain values and lists of strings have a variable for themselves;
+// mappings such as profiles and lists such as owners take YAML.
+func EnvVars() []EnvVar {
+	var vars []EnvVar
+	var walk func(t reflect.Type, key string)
+	walk = func(t reflect.Type, key string) {
+		for _, f := range fields(t) {
+			k := joinKey(key, f.name)
+			switch f.typ.Kind() {
+			case reflect.Struct:
+				walk(f.typ, k)
+			case reflect.Bool:
+				vars = append(vars, EnvVar{EnvName(k), k, "true or false"})
+			case reflect.String:
+				vars = append(vars, EnvVar{EnvName(k), k, "string"})
+			case reflect.Slice:
+				if f.typ.Elem().Kind() == reflect.String {
+					vars = append(vars, EnvVar{EnvName(k), k, "comma-separated list or YAML"})
+				} else {
+					vars = append(vars, EnvVar{EnvName(k), k, "YAML"})
+				}
+			default:
+				vars = append(vars, EnvVar{EnvName(k), k, "YAML"})
+			}
+		}
+	}
+	walk(configType, "")
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+// applyEnv overrides the settings in cfg whose variables lookup finds,
+// returning the names of the variables it applied
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) ([]string, error) {
+	var applied []string
+	for _, v := range EnvVars() {
+		value, ok := lookup(v.Name)
+		if !ok {
+			continue
+		}
+		if err := setField(reflect.ValueOf(cfg).Elem(), strings.Split(v.Key, "."), value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", v.Name, err)
+		}
+		applied = append(applied, v.Name)
+	}
+	return applied, nil
+}
+
+// setField sets the field at path below the struct value v from an
+// environment variable's value
+func setField(v reflect.Value, path []string, value string) error {
+	for _, name := range path {
+		found := false
+		for i := range v.NumField() {
+			tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+			if tag == name {
+				v, found = v.Field(i), true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown setting %q", name)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("want true or false, not %q", value)
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.String:
+		v.SetString(value)
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "[") {
+			var items []string
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			v.Set(reflect.ValueOf(items))
+			return nil
+		}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+		return fmt.Errorf("want YAML: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	var errs []*SchemaError
+	checkNode(doc.Content[0], v.Type(), "", &errs)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	target := reflect.New(v.Type())
+	if err := doc.Content[0].Decode(target.Interface()); err != nil {
+		return err
+	}
+	v.Set(target.Elem())
+	return nil
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"approval.webhook":              "SWK_APPROVAL_WEBHOOK",
+		"vars.configMap":                "SWK_VARS_CONFIG_MAP",
+		"serve.auth.clientCA":           "SWK_SERVE_AUTH_CLIENT_CA",
+		"serve.auth.oidc.clientID":      "SWK_SERVE_AUTH_OIDC_CLIENT_ID",
+		"serve.auth.oidc.usernameClaim": "SWK_SERVE_AUTH_OIDC_USERNAME_CLAIM",
+	}
+	for key, want := range tests {
+		if got := EnvName(key); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "approval:\n  webhook: https://file.example.com\n  timeout: 10m\nwatched: [/srv/a]\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		check   func(*Config) bool
+		wantErr string
+	}{
+		{"file only", nil, func(c *Config) bool {
+			return c.Approval.Webhook == "https://file.example.com" && c.Approval.Timeout == "10m"
+		}, ""},
+		{"string", map[string]string{"SWK_APPROVAL_WEBHOOK": "https://env.example.com"}, func(c *Config) bool {
+			return c.Approval.Webhook == "https://env.example.com" && c.Approval.Timeout == "10m"
+		}, ""},
+		{"comma list", map[string]string{"SWK_WATCHED": "/srv/b, /srv/c"}, func(c *Config) bool {
+			return strings.Join(c.Watched, " ") == "/srv/b /srv/c"
+		}, ""},
+		{"yaml list", map[string]string{"SWK_WATCHED": "[/srv/d]"}, func(c *Config) bool {
+			return strings.Join(c.Watched, " ") == "/srv/d"
+		}, ""},
+		{"yaml mapping", map[string]string{"SWK_PROFILES": "{prod: {namespaces: [prod], strict: true}}"}, func(c *Config) bool {
+			return c.Profiles["prod"].Strict
+		}, ""},
+		{"empty clears", map[string]string{"SWK_WATCHED": ""}, func(c *Config) bool {
+			return len(c.Watched) == 0
+		}, ""},
+		{"bad bool", map[string]string{"SWK_PROFILES": "{prod: {strict: maybe}}"}, nil, "invalid SWK_PROFILES"},
+		{"invalid value names the variable", map[string]string{"SWK_APPROVAL_TIMEOUT": "soon"}, nil, "SWK_APPROVAL_TIMEOUT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Load() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("Load() = %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestEnvVars(t *testing.T) {
+	names := map[string]string{}
+	for _, v := range EnvVars() {
+		names[v.Name] = v.Format
+	}
+	for name, format := range map[string]string{
+		"SWK_APPROVAL_WEBHOOK": "string",
+		"SWK_WATCHED":          "comma-separated list or YAML",
+		"SWK_PROFILES":         "YAML",
+		"SWK_OWNERS":           "YAML",
+	} {
+		if names[name] != format {
+			t.Errorf("%s format = %q, want %q", name, names[name], format)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaID identifies the published JSON Schema of the config file
+const SchemaID = "https://github.com/davidschrooten/secret-wrapper-k8s/config.schema.json"
+
+// SchemaError is a config value that doesn't fit the schema
+type SchemaError struct {
+	Line, Column int
+	// Key is the dotted key of the value, e.g. profiles.prod.strict
+	Key string
+	Msg string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", e.Line, e.Column, e.Key, e.Msg)
+}
+
+// configType is the type the config file decodes into
+var configType = reflect.TypeFor[Config]()
+
+// JSONSchema returns the JSON Schema of the config file, for editors and
+// CI to check it against
+func JSONSchema() ([]byte, error) {
+	s := typeSchema(configType)
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["$id"] = SchemaID
+	s["title"] = "swk configuration"
+	return json.MarshalIndent(s, "", "  ")
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int:
+		return map[string]any{"type": "integer"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		props := map[string]any{}
+		for _, f := range fields(t) {
+			props[f.name] = typeSchema(f.typ)
+		}
+		return map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	}
+	return map[string]any{}
+}
+
+type field struct {
+	name string
+	typ  reflect.Type
+}
+
+// fields returns the YAML fields of a struct type, by name
+func fields(t reflect.Type) []field {
+	var out []field
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		out = append(out, field{name, f.Type})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
+
+// checkSchema returns an error for every value in the document root that
+// doesn't fit the config schema, with its line and column
+func checkSchema(root *yaml.Node) []*SchemaError {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return nil
+	}
+	var errs []*SchemaError
+	checkNode(root.Content[0], configType, "", &errs)
+	return errs
+}
+
+func checkNode(node *yaml.Node, t reflect.Type, key string, errs *[]*SchemaError) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	fail := func(n *yaml.Node, k, format string, args ...any) {
+		*errs = append(*errs, &SchemaError{Line: n.Line, Column: n.Column, Key: k, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			fail(node, key, "must be true or false, not %s", describe(node))
+		}
+	case reflect.Int:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			fail(node, key, "must be a whole number, not %s", describe(node))
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			fail(node, key, "must be a string, not %s", describe(node))
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			fail(node, key, "must be a list, not %s", describe(node))
+			return
+		}
+		for i, item := range node.Content {
+			checkNode(item, t.Elem(), fmt.Sprintf("%s.%d", key, i), errs)
+		}
+	case reflect.Map, reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			fail(node, key, "must be a mapping, not %s", describe(node))
+			return
+		}
+		known := map[string]reflect.Type{}
+		if t.Kind() == reflect.Struct {
+			for _, f := range fields(t) {
+				known[f.name] = f.typ
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k := node.Content[i]
+			child := joinKey(key, k.Value)
+			if t.Kind() == reflect.Map {
+				checkNode(node.Content[i+1], t.Elem(), child, errs)
+				continue
+			}
+			ft, ok := known[k.Value]
+			if !ok {
+				fail(k, child, "unknown key (known in %s: %s)", orRoot(key), strings.Join(sortedNames(known), ", "))
+				continue
+			}
+			checkNode(node.Content[i+1], ft, child, errs)
+		}
+	}
+}
+
+// describe names the kind of a node for errors
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return fmt.Sprintf("%q", node.Value)
+}
+
+func joinKey(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func orRoot(key string) string {
+	if key == "" {
+		return "the top level"
+	}
+	return key
+}
+
+func sortedNames(m map[string]reflect.Type) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
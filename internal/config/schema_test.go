@@ -0,0 +1,68 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"valid", "approval:\n  webhook: https://example.com\nprofiles:\n  prod:\n    strict: true\n", nil},
+		{"null section", "approval:\n", nil},
+		{"unknown key", "approval:\n  webhok: https://example.com\n", []string{"config.yaml:2:3: approval.webhok: unknown key (known in approval: timeout, webhook)"}},
+		{"unknown top-level key", "profile: {}\n", []string{"config.yaml:1:1: profile: unknown key (known in the top level:"}},
+		{"not a bool", "profiles:\n  prod:\n    strict: yes please\n", []string{"config.yaml:3:13: profiles.prod.strict: must be true or false"}},
+		{"not a list", "watched: /srv\n", []string{"config.yaml:1:10: watched: must be a list"}},
+		{"not a number", "constraints:\n  - keys: pin\n    maxLength: six\n", []string{"config.yaml:3:16: constraints.0.maxLength: must be a whole number"}},
+		{"list item", "owners:\n  - keys: '*'\n    teem: ops\n", []string{"config.yaml:3:5: owners.0.teem: unknown key"}},
+		{"every error", "watched: /srv\nvars: []\n", []string{"1:10: watched", "2:7: vars: must be a mapping"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.content), "config.yaml")
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Parse() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Parse() should fail")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Parse() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	data, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema() error = %v", err)
+	}
+	var s struct {
+		ID         string `json:"$id"`
+		Properties map[string]struct {
+			Type       string                     `json:"type"`
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatalf("schema is not JSON: %v", err)
+	}
+	if s.ID != SchemaID {
+		t.Errorf("$id = %q, want %q", s.ID, SchemaID)
+	}
+	if s.Properties["watched"].Type != "array" || s.Properties["approval"].Properties["webhook"] == nil {
+		t.Errorf("schema = %s, want watched and approval.webhook", data)
+	}
+}
@@ -0,0 +1,141 @@
+// Package doctor diagnoses the environment swk runs in: the editor, where
+// decoded Secrets are written, and the tools swk calls
+package doctor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/editor"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/tempdir"
+)
+
+// Status is the outcome of a check
+type Status string
+
+// Check outcomes. Warnings are problems for some uses only.
+const (
+	OK   Status = "ok"
+	Warn Status = "warn"
+	Fail Status = "fail"
+)
+
+// Result is the outcome of one check, with a fix for anything not OK
+type Result struct {
+	Name   string
+	Status Status
+	Detail string
+	Fix    string
+}
+
+// LookPath finds executables, replaceable in tests
+var LookPath = exec.LookPath
+
+// Editor checks the editor swk would launch, named by the --editor flag,
+// $EDITOR, or $VISUAL
+func Editor(command string) Result {
+	r := Result{Name: "editor"}
+	if command == editor.Builtin {
+		r.Status, r.Detail = OK, "the line editor built into swk"
+		return r
+	}
+	words, err := editor.Split(command)
+	if err != nil {
+		r.Status, r.Detail = Fail, err.Error()
+		r.Fix = "quote the editor's path and arguments as you would in a shell"
+		return r
+	}
+	if len(words) == 0 {
+		r.Status, r.Detail = Fail, "no editor is set"
+		r.Fix = "export EDITOR=vim"
+		return r
+	}
+	base := filepath.Base(words[0])
+
+	path, err := LookPath(words[0])
+	if err != nil {
+		r.Status, r.Detail = Fail, fmt.Sprintf("%s is not installed or not on PATH", words[0])
+		r.Fix = "install it, export EDITOR=vi, or pass --builtin-editor to use the line editor built into swk"
+		return r
+	}
+	r.Status, r.Detail = OK, fmt.Sprintf("%s (%s)", base, path)
+	if flag := editor.WaitFlag(words); flag != "" {
+		r.Detail += fmt.Sprintf(", started with %s so that swk waits for the file to be closed", flag)
+	}
+	return r
+}
+
+// TempDir checks the directory decoded Secrets are written to while they
+// are edited: it must be private enough, and ideally in memory
+func TempDir(dir string) Result {
+	r := Result{Name: "temp dir"}
+	info, err := os.Stat(dir)
+	if err != nil {
+		r.Status, r.Detail = Fail, fmt.Sprintf("%s: %v", dir, err)
+		r.Fix = "export SWK_TMPDIR to a directory you can write to"
+		return r
+	}
+	if perm := info.Mode(); perm&0002 != 0 && perm&os.ModeSticky == 0 {
+		r.Status = Fail
+		r.Detail = fmt.Sprintf("%s is writable by everyone without the sticky bit, so others can replace the decoded file", dir)
+		r.Fix = fmt.Sprintf("chmod +t %s, or export SWK_TMPDIR to a private directory", dir)
+		return r
+	}
+
+	f, err := os.CreateTemp(dir, "swk-doctor-*")
+	if err != nil {
+		r.Status, r.Detail = Fail, fmt.Sprintf("can't create files in %s: %v", dir, err)
+		r.Fix = "export SWK_TMPDIR to a directory you can write to"
+		return r
+	}
+	name := f.Name()
+	fi, statErr := f.Stat()
+	_ = f.Close()
+	_ = os.Remove(name)
+	if statErr == nil && fi.Mode().Perm()&0077 != 0 {
+		r.Status = Fail
+		r.Detail = fmt.Sprintf("temp files in %s are created with mode %v, readable by others", dir, fi.Mode().Perm())
+		r.Fix = "check the directory's default ACLs, or export SWK_TMPDIR to a private directory"
+		return r
+	}
+
+	fsType, inMemory := tempdir.Filesystem(dir)
+	switch {
+	case inMemory:
+		r.Status, r.Detail = OK, fmt.Sprintf("%s (%s, in memory)", dir, fsType)
+	case fsType == "":
+		r.Status, r.Detail = Warn, fmt.Sprintf("%s: can't tell whether it is in memory, so decoded Secrets may be written to disk", dir)
+		r.Fix = memoryFix()
+	default:
+		r.Status, r.Detail = Warn, fmt.Sprintf("%s is on %s, so decoded Secrets are written to disk while you edit", dir, fsType)
+		r.Fix = memoryFix()
+	}
+	return r
+}
+
+// memoryFix suggests an in-memory directory for SWK_TMPDIR
+func memoryFix() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return "export SWK_TMPDIR=" + dir
+	}
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		return "export SWK_TMPDIR=/dev/shm"
+	}
+	return "point SWK_TMPDIR at a RAM disk"
+}
+
+// Tool checks that an optional executable is on PATH; what names the
+// features that need it
+func Tool(name, what, install string) Result {
+	r := Result{Name: name}
+	path, err := LookPath(name)
+	if err != nil {
+		r.Status, r.Detail = Warn, fmt.Sprintf("not found; needed for %s", what)
+		r.Fix = install
+		return r
+	}
+	r.Status, r.Detail = OK, path
+	return r
+}
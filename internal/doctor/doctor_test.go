@@ -0,0 +1,88 @@
+package doctor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func fakeLookPath(t *testing.T, installed ...string) {
+	t.Helper()
+	orig := LookPath
+	LookPath = func(name string) (string, error) {
+		for _, i := range installed {
+			if name == i || name == "/usr/bin/"+i {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+	t.Cleanup(func() { LookPath = orig })
+}
+
+func TestEditor(t *testing.T) {
+	fakeLookPath(t, "vim", "code")
+
+	tests := []struct {
+		editor  string
+		want    Status
+		wantFix string
+	}{
+		{"vim", OK, ""},
+		{"/usr/bin/vim", OK, ""},
+		{"nano", Fail, "install it"},
+		{"code", OK, ""},
+		{"code --wait", OK, ""},
+		{"'/usr/bin/vim' -u NONE", OK, ""},
+		{"vim 'unterminated", Fail, "quote"},
+		{"", Fail, "export EDITOR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.editor, func(t *testing.T) {
+			r := Editor(tt.editor)
+			if r.Status != tt.want || !strings.Contains(r.Fix, tt.wantFix) {
+				t.Errorf("Editor(%q) = %+v, want %s with a fix containing %q", tt.editor, r, tt.want, tt.wantFix)
+			}
+		})
+	}
+}
+
+func TestTempDir(t *testing.T) {
+	private := t.TempDir()
+	if r := TempDir(private); r.Status == Fail {
+		t.Errorf("TempDir(private) = %+v, want no failure", r)
+	}
+	if r := TempDir(filepath.Join(private, "missing")); r.Status != Fail {
+		t.Errorf("TempDir(missing) = %+v, want a failure", r)
+	}
+
+	open := filepath.Join(private, "open")
+	if err := os.Mkdir(open, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(open, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if r := TempDir(open); r.Status != Fail || !strings.Contains(r.Detail, "sticky") {
+		t.Errorf("TempDir(world-writable) = %+v, want a failure about the sticky bit", r)
+	}
+	if err := os.Chmod(open, 0777|os.ModeSticky); err != nil {
+		t.Fatal(err)
+	}
+	if r := TempDir(open); r.Status == Fail {
+		t.Errorf("TempDir(sticky) = %+v, want no failure", r)
+	}
+}
+
+func TestTool(t *testing.T) {
+	fakeLookPath(t, "sops")
+	if r := Tool("sops", "--sops", "install sops"); r.Status != OK || r.Detail != "/usr/bin/sops" {
+		t.Errorf("Tool(sops) = %+v, want OK", r)
+	}
+	if r := Tool("kubeseal", "Sealed Secrets", "install kubeseal"); r.Status != Warn || r.Fix != "install kubeseal" {
+		t.Errorf("Tool(kubeseal) = %+v, want a warning with the install hint", r)
+	}
+}
@@ -0,0 +1,312 @@
+package editor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Builtin is the editor name that selects the line editor built into swk,
+// for scratch containers and debug pods without vi or nano
+const Builtin = "builtin"
+
+// builtinHelp lists the commands of the built-in editor
+const builtinHelp = `Commands (N and M are line numbers, $ is the last line):
+  p [N[,M]]      Print lines, numbered (default: all)
+  c N[,M]        Change lines: type the new ones, then a line with only .
+  a [N]          Add lines after N (default: the last), ending with .
+  i N            Insert lines before N, ending with .
+  d N[,M]        Delete lines
+  s N/OLD/NEW/   Replace the first OLD on line N with NEW; any character
+                 not in OLD or NEW can take the place of /
+  r              Print the file given for reference, if any
+  w              Save
+  wq             Save and quit
+  q              Quit, refusing while there are unsaved changes
+  q!             Quit without saving
+  h              Show this help
+`
+
+// RunBuiltin edits the last file with the built-in line editor, reading
+// commands from in. Any files before it are shown by the r command, for
+// --compare. On a terminal, lines can be edited and recalled with the
+// arrow keys.
+func RunBuiltin(in io.Reader, out io.Writer, files ...string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("no file to edit")
+	}
+	path := files[len(files)-1]
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	b := &builtin{path: path, refs: files[:len(files)-1], lines: splitLines(string(data))}
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		state, err := term.MakeRaw(int(f.Fd()))
+		if err != nil {
+			return fmt.Errorf("failed to set up terminal: %w", err)
+		}
+		defer func() { _ = term.Restore(int(f.Fd()), state) }()
+		t := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{in, out}, "")
+		b.read, b.out = t.ReadLine, t
+		b.prompt = t.SetPrompt
+	} else {
+		r := bufio.NewReader(in)
+		b.read = func() (string, error) {
+			line, err := r.ReadString('\n')
+			if err != nil && line == "" {
+				return "", err
+			}
+			return strings.TrimRight(line, "\r\n"), nil
+		}
+		b.out = out
+		b.prompt = func(string) {}
+	}
+	return b.run()
+}
+
+// builtin is the state of the built-in editor
+type builtin struct {
+	path  string
+	refs  []string
+	lines []string
+	dirty bool
+
+	read   func() (string, error)
+	out    io.Writer
+	prompt func(string)
+}
+
+// run reads and runs commands until the editor is quit or input ends
+func (b *builtin) run() error {
+	fmt.Fprintf(b.out, "Editing %s (%d lines); type h for help\n", b.path, len(b.lines))
+	for {
+		b.prompt(": ")
+		line, err := b.read()
+		if errors.Is(err, io.EOF) {
+			if b.dirty {
+				return fmt.Errorf("input ended with unsaved changes to %s", b.path)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		quit, err := b.command(strings.TrimSpace(line))
+		if err != nil {
+			fmt.Fprintf(b.out, "? %v\n", err)
+			continue
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// command runs one command, reporting whether it quits the editor
+func (b *builtin) command(line string) (bool, error) {
+	name, arg, _ := strings.Cut(line, " ")
+	if strings.HasPrefix(line, "s") && !strings.HasPrefix(line, "s ") {
+		// s3/old/new/ works without a space, as in ed
+		name, arg = "s", line[1:]
+	}
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "":
+		return false, nil
+	case "h", "help":
+		fmt.Fprint(b.out, builtinHelp)
+	case "p":
+		from, to := 1, len(b.lines)
+		if arg != "" {
+			var err error
+			if from, to, err = b.lineRange(arg); err != nil {
+				return false, err
+			}
+		}
+		for i := from; i <= to; i++ {
+			fmt.Fprintf(b.out, "%4d  %s\n", i, b.lines[i-1])
+		}
+	case "c":
+		from, to, err := b.lineRange(arg)
+		if err != nil {
+			return false, err
+		}
+		added, err := b.readLines()
+		if err != nil {
+			return false, err
+		}
+		b.replace(from-1, to, added)
+	case "a", "i":
+		at := len(b.lines)
+		if arg != "" || name == "i" {
+			n, err := b.lineNumber(arg, name == "a")
+			if err != nil {
+				return false, err
+			}
+			at = n
+			if name == "i" {
+				at = n - 1
+			}
+		}
+		added, err := b.readLines()
+		if err != nil {
+			return false, err
+		}
+		b.replace(at, at, added)
+	case "d":
+		from, to, err := b.lineRange(arg)
+		if err != nil {
+			return false, err
+		}
+		b.replace(from-1, to, nil)
+	case "s":
+		return false, b.substitute(arg)
+	case "r":
+		if len(b.refs) == 0 {
+			return false, fmt.Errorf("no reference file")
+		}
+		for _, ref := range b.refs {
+			data, err := os.ReadFile(ref)
+			if err != nil {
+				return false, fmt.Errorf("failed to read reference: %w", err)
+			}
+			fmt.Fprintf(b.out, "%s:\n", ref)
+			for i, l := range splitLines(string(data)) {
+				fmt.Fprintf(b.out, "%4d  %s\n", i+1, l)
+			}
+		}
+	case "w", "wq":
+		if err := b.save(); err != nil {
+			return false, err
+		}
+		return name == "wq", nil
+	case "q":
+		if b.dirty {
+			return false, fmt.Errorf("unsaved changes; w to save them, or q! to throw them away")
+		}
+		return true, nil
+	case "q!":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command %q; h for help", name)
+	}
+	return false, nil
+}
+
+// readLines reads lines to add until a line with only a dot
+func (b *builtin) readLines() ([]string, error) {
+	b.prompt("")
+	var lines []string
+	for {
+		line, err := b.read()
+		if err != nil {
+			return nil, fmt.Errorf("input ended before the closing .")
+		}
+		if line == "." {
+			return lines, nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+// replace replaces lines[from:to] with added
+func (b *builtin) replace(from, to int, added []string) {
+	b.lines = append(b.lines[:from], append(added, b.lines[to:]...)...)
+	b.dirty = true
+}
+
+// substitute runs s N/OLD/NEW/
+func (b *builtin) substitute(arg string) error {
+	i := strings.IndexFunc(arg, func(r rune) bool { return (r < '0' || r > '9') && r != '$' })
+	if i <= 0 || i == len(arg) {
+		return fmt.Errorf("usage: s N/OLD/NEW/")
+	}
+	n, err := b.lineNumber(arg[:i], false)
+	if err != nil {
+		return err
+	}
+	parts := strings.Split(arg[i+1:], arg[i:i+1])
+	if len(parts) < 2 || parts[0] == "" {
+		return fmt.Errorf("usage: s N/OLD/NEW/")
+	}
+	if !strings.Contains(b.lines[n-1], parts[0]) {
+		return fmt.Errorf("line %d has no %q", n, parts[0])
+	}
+	b.lines[n-1] = strings.Replace(b.lines[n-1], parts[0], parts[1], 1)
+	b.dirty = true
+	fmt.Fprintf(b.out, "%4d  %s\n", n, b.lines[n-1])
+	return nil
+}
+
+// lineRange parses N or N,M
+func (b *builtin) lineRange(arg string) (int, int, error) {
+	first, last, isRange := strings.Cut(arg, ",")
+	from, err := b.lineNumber(first, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		return from, from, nil
+	}
+	to, err := b.lineNumber(last, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	if to < from {
+		return 0, 0, fmt.Errorf("line range %s is backwards", arg)
+	}
+	return from, to, nil
+}
+
+// lineNumber parses a line number, or $ for the last line. Line 0 is only
+// allowed with zero, for adding lines at the top.
+func (b *builtin) lineNumber(arg string, zero bool) (int, error) {
+	arg = strings.TrimSpace(arg)
+	if arg == "$" {
+		return len(b.lines), nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("want a line number, got %q", arg)
+	}
+	if (n < 1 && !(zero && n == 0)) || n > len(b.lines) {
+		return 0, fmt.Errorf("no line %d; the file has %d", n, len(b.lines))
+	}
+	return n, nil
+}
+
+// save writes the lines back to the file, keeping its permissions
+func (b *builtin) save() error {
+	data := strings.Join(b.lines, "\n")
+	if len(b.lines) > 0 {
+		data += "\n"
+	}
+	if err := os.WriteFile(b.path, []byte(data), 0600); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	b.dirty = false
+	fmt.Fprintf(b.out, "Wrote %d lines to %s\n", len(b.lines), b.path)
+	return nil
+}
+
+// splitLines splits text into lines, without the final newline
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,91 @@
+package editor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunBuiltin(t *testing.T) {
+	const original = "apiVersion: v1\nkind: Secret\nstringData:\n  password: old\n  user: admin\n"
+	tests := []struct {
+		name    string
+		script  string
+		want    string
+		wantOut string
+		wantErr bool
+	}{
+		{
+			name:   "substitute and save",
+			script: "s4/old/new/\nwq\n",
+			want:   strings.Replace(original, "old", "new", 1),
+		},
+		{
+			name:   "change, add, and delete",
+			script: "c 5\n  user: root\n.\na $\n  token: abc\n.\nd 1\ni 1\napiVersion: v2\n.\nw\nq\n",
+			want:   "apiVersion: v2\nkind: Secret\nstringData:\n  password: old\n  user: root\n  token: abc\n",
+		},
+		{
+			name:    "quit refused with unsaved changes",
+			script:  "d 1\nq\nq!\n",
+			want:    original,
+			wantOut: "unsaved changes",
+		},
+		{
+			name:    "bad line numbers are reported",
+			script:  "d 9\np 3,2\nx\nq\n",
+			want:    original,
+			wantOut: "no line 9",
+		},
+		{
+			name:    "print",
+			script:  "p 4\nq\n",
+			want:    original,
+			wantOut: "   4    password: old",
+		},
+		{
+			name:    "input ends with unsaved changes",
+			script:  "d 1\n",
+			want:    original,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "secret.yaml")
+			if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			err := RunBuiltin(strings.NewReader(tt.script), &out, path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunBuiltin() error = %v, wantErr %v\n%s", err, tt.wantErr, out.String())
+			}
+			data, _ := os.ReadFile(path)
+			if string(data) != tt.want {
+				t.Errorf("file = %q, want %q", data, tt.want)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("output missing %q:\n%s", tt.wantOut, out.String())
+			}
+		})
+	}
+}
+
+func TestRunBuiltinReference(t *testing.T) {
+	dir := t.TempDir()
+	ref, path := filepath.Join(dir, "ref.yaml"), filepath.Join(dir, "edit.yaml")
+	_ = os.WriteFile(ref, []byte("data:\n  password: b2xk\n"), 0600)
+	_ = os.WriteFile(path, []byte("stringData:\n  password: old\n"), 0600)
+
+	var out bytes.Buffer
+	if err := RunBuiltin(strings.NewReader("r\nq\n"), &out, ref, path); err != nil {
+		t.Fatalf("RunBuiltin() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "password: b2xk") || !strings.Contains(out.String(), "Editing "+path) {
+		t.Errorf("output = %s", out.String())
+	}
+}
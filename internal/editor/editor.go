@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 )
 
 // SelectEditor determines which editor to use based on CLI flag and environment variables
@@ -24,10 +26,67 @@ func SelectEditor(flagValue string) string {
 	return "vi"
 }
 
+// waitFlags are the flags GUI editors need to block until the file is
+// closed, the first being the one swk adds; without one swk reads the file
+// back before anything changed
+var waitFlags = map[string][]string{
+	"atom":          {"--wait", "-w"},
+	"code":          {"--wait", "-w"},
+	"code-insiders": {"--wait", "-w"},
+	"codium":        {"--wait", "-w"},
+	"cursor":        {"--wait", "-w"},
+	"gedit":         {"--wait"},
+	"goland":        {"--wait"},
+	"gvim":          {"-f", "--nofork"},
+	"idea":          {"--wait"},
+	"kate":          {"--block", "-b"},
+	"mate":          {"-w", "--wait"},
+	"mvim":          {"-f", "--nofork"},
+	"pycharm":       {"--wait"},
+	"subl":          {"--wait", "-w"},
+	"webstorm":      {"--wait"},
+	"zed":           {"--wait", "-w"},
+}
+
+// WaitFlag returns the flag a GUI editor, given as its command split into
+// words, needs to block until the file is closed, or "" if it isn't a GUI
+// editor swk knows or already has the flag
+func WaitFlag(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	flags := waitFlags[filepath.Base(words[0])]
+	for _, flag := range flags {
+		if slices.Contains(words[1:], flag) {
+			return ""
+		}
+	}
+	if len(flags) == 0 {
+		return ""
+	}
+	return flags[0]
+}
+
 // LaunchEditor launches the specified editor with the given file path
-// The function waits for the editor to exit and returns any error
+// The function waits for the editor to exit and returns any error.
+// The editor may carry arguments of its own, such as `emacs -nw`, which
+// are split like a shell would, see Split. GUI editors that would return
+// at once are started with their wait flag, see WaitFlag.
 func LaunchEditor(editor string, args ...string) error {
-	cmd := exec.Command(editor, args...)
+	if editor == Builtin {
+		return RunBuiltin(os.Stdin, os.Stdout, args...)
+	}
+	words, err := Split(editor)
+	if err != nil {
+		return fmt.Errorf("invalid editor: %w", err)
+	}
+	if len(words) == 0 {
+		return fmt.Errorf("no editor is set")
+	}
+	if flag := WaitFlag(words); flag != "" {
+		words = append(words, flag)
+	}
+	cmd := exec.Command(words[0], append(words[1:], args...)...)
 
 	// Connect stdin, stdout, stderr to allow interactive editing
 	cmd.Stdin = os.Stdin
@@ -40,3 +99,23 @@ func LaunchEditor(editor string, args ...string) error {
 
 	return nil
 }
+
+// CompareArgs returns the arguments that open two files side by side, the
+// reference on the left and the file to edit on the right. Editors without
+// such a mode get both files, which most open as separate buffers.
+func CompareArgs(editor, reference, file string) []string {
+	name := editor
+	if words, err := Split(editor); err == nil && len(words) > 0 {
+		name = words[0]
+	}
+	switch filepath.Base(name) {
+	case "vim", "nvim", "gvim", "mvim", "vimdiff":
+		return []string{"-d", reference, file}
+	case "vi":
+		return []string{"-O", reference, file}
+	case "code", "code-insiders", "codium":
+		// LaunchEditor adds --wait
+		return []string{"--diff", reference, file}
+	}
+	return []string{reference, file}
+}
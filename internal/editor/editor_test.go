@@ -3,6 +3,8 @@ package editor
 import (
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -153,10 +155,110 @@ func TestCommandExecution(t *testing.T) {
 }
 
 func TestEditorWithSpaces(t *testing.T) {
-	// Test editor command that might have spaces (though we don't split on spaces)
-	// This ensures we pass the editor name as-is to exec.Command
-	err := LaunchEditor("nonexistent editor with spaces", "/tmp/test.yaml")
-	if err == nil {
-		t.Error("LaunchEditor should fail with invalid command")
+	// The editor's own arguments come before the file
+	out := filepath.Join(t.TempDir(), "args")
+	if err := LaunchEditor(`sh -c 'echo "$@" > "$0"' `+out+` --wait`, "/tmp/test.yaml"); err != nil {
+		t.Fatalf("LaunchEditor with arguments failed: %v", err)
+	}
+	if data, err := os.ReadFile(out); err != nil || string(data) != "--wait /tmp/test.yaml\n" {
+		t.Errorf("editor arguments = %q, %v, want --wait /tmp/test.yaml", data, err)
+	}
+
+	for _, editor := range []string{"nonexistent editor with spaces", "vim 'unterminated", "  "} {
+		if err := LaunchEditor(editor, "/tmp/test.yaml"); err == nil {
+			t.Errorf("LaunchEditor(%q) should fail", editor)
+		}
+	}
+}
+
+func TestWaitFlag(t *testing.T) {
+	tests := []struct {
+		editor string
+		want   string
+	}{
+		{"code", "--wait"},
+		{"/usr/local/bin/subl", "--wait"},
+		{"subl -w", ""},
+		{"code --wait", ""},
+		{"kate", "--block"},
+		{"idea", "--wait"},
+		{"gvim --nofork", ""},
+		{"vim", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.editor, func(t *testing.T) {
+			words, _ := Split(tt.editor)
+			if got := WaitFlag(words); got != tt.want {
+				t.Errorf("WaitFlag(%q) = %q, want %q", tt.editor, got, tt.want)
+			}
+		})
+	}
+
+	// The flag comes before the files
+	dir := t.TempDir()
+	script := filepath.Join(dir, "code")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" > \"$(dirname \"$0\")/args\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := LaunchEditor(script, "/tmp/test.yaml"); err != nil {
+		t.Fatalf("LaunchEditor() failed: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "args")); err != nil || string(data) != "--wait /tmp/test.yaml\n" {
+		t.Errorf("editor arguments = %q, %v, want --wait /tmp/test.yaml", data, err)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{"vim", []string{"vim"}, false},
+		{"code --wait", []string{"code", "--wait"}, false},
+		{"  emacs   -nw  ", []string{"emacs", "-nw"}, false},
+		{`"/Applications/Sublime Text.app/Contents/MacOS/subl" -w`, []string{"/Applications/Sublime Text.app/Contents/MacOS/subl", "-w"}, false},
+		{`'/opt/my editor/bin/ed' --flag='a b'`, []string{"/opt/my editor/bin/ed", "--flag=a b"}, false},
+		{`/opt/my\ editor "say \"hi\" \n" ''`, []string{"/opt/my editor", `say "hi" \n`, ""}, false},
+		{"", nil, false},
+		{`vim "unterminated`, nil, true},
+		{"vim 'unterminated", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			got, err := Split(tt.command)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Split() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareArgs(t *testing.T) {
+	tests := []struct {
+		editor string
+		want   []string
+	}{
+		{"vim", []string{"-d", "a", "b"}},
+		{"/usr/bin/nvim", []string{"-d", "a", "b"}},
+		{"vi", []string{"-O", "a", "b"}},
+		{"code", []string{"--diff", "a", "b"}},
+		{"code --wait", []string{"--diff", "a", "b"}},
+		{"'/opt/my vim/vim' -u NONE", []string{"-d", "a", "b"}},
+		{"nano", []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.editor, func(t *testing.T) {
+			if got := CompareArgs(tt.editor, "a", "b"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareArgs(%q) = %v, want %v", tt.editor, got, tt.want)
+			}
+		})
 	}
 }
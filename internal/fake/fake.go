@@ -0,0 +1,327 @@
+// Package fake makes up values shaped like real secret values, so that
+// staging and test Secrets can mirror production ones without holding a
+// single real credential
+package fake
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/generate"
+)
+
+// Host is the name fake certificates, URLs, and keys are made out to
+const Host = "fake.example.com"
+
+const alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// Value makes up a value of the given kind, as redact.Kind names kinds,
+// about length bytes long. Tokens, such as strings, hex, base64, numbers,
+// JWTs, and URLs, match the length exactly where they can; keys and certificates are
+// freshly generated ones of the size closest to it. An empty kind makes an
+// empty value.
+func Value(kind string, length int) ([]byte, error) {
+	base, count := kind, 1
+	if k, n, ok := strings.Cut(kind, " x"); ok {
+		c, err := strconv.Atoi(n)
+		if err != nil || c < 1 {
+			return nil, fmt.Errorf("can't fake values of kind %q", kind)
+		}
+		base, count = k, c
+	}
+
+	switch base {
+	case "":
+		return nil, nil
+	case "pem-cert":
+		var bundle []byte
+		for range count {
+			cert, _, err := certificate(length / count)
+			if err != nil {
+				return nil, err
+			}
+			bundle = append(bundle, cert...)
+		}
+		return bundle, nil
+	case "pem-key":
+		return privateKey(length)
+	case "pem-public-key":
+		return publicKey(length)
+	case "pem-csr":
+		return csr()
+	case "pem":
+		// Mixed blocks are most often a certificate with its key
+		cert, key, err := certificate(length / 2)
+		return append(cert, key...), err
+	case "der":
+		cert, _, err := certificate(length * 4 / 3)
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(cert)
+		return block.Bytes, nil
+	case "ssh-public-key":
+		keyType := "ed25519"
+		if length > 200 {
+			keyType = "rsa"
+		}
+		key, err := generate.NewSSHKey(keyType, "fake@"+Host)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.TrimSuffix(key.PublicKey, []byte("\n")), nil
+	case "jwt":
+		return jwt(length)
+	case "json":
+		return padded(length, `{"fake":"`, `"}`)
+	case "uuid":
+		b, err := randomBytes(16)
+		if err != nil {
+			return nil, err
+		}
+		b[6], b[8] = b[6]&0x0f|0x40, b[8]&0x3f|0x80
+		h := hex.EncodeToString(b)
+		return []byte(h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]), nil
+	case "number":
+		number, err := randomString(length, "0123456789")
+		if err == nil && len(number) > 1 && number[0] == '0' {
+			number[0] = '1'
+		}
+		return number, err
+	case "hex":
+		return randomString(length, "0123456789abcdef")
+	case "base64":
+		b, err := randomBytes(length / 4 * 3)
+		return []byte(base64.StdEncoding.EncodeToString(b)), err
+	case "text":
+		return text(length)
+	case "string":
+		return randomString(length, alphanumeric)
+	case "gzip":
+		return gzipped(length)
+	case "zip":
+		return zipped(length)
+	case "binary":
+		return randomBytes(length)
+	}
+	if scheme, ok := strings.CutSuffix(base, "-url"); ok && scheme != "" {
+		return fakeURL(scheme, length)
+	}
+	return nil, fmt.Errorf("can't fake values of kind %q", kind)
+}
+
+// certificate returns a self-signed certificate of about length bytes as
+// PEM, with its key
+func certificate(length int) ([]byte, []byte, error) {
+	keyType := "ecdsa"
+	if length > 900 {
+		keyType = "rsa"
+	}
+	return generate.NewCertificate(generate.CertRequest{CommonName: Host, SANs: []string{Host}, Days: 365, KeyType: keyType})
+}
+
+// newKey generates the kind of key whose PEM form is closest to length:
+// Ed25519, ECDSA P-256, or RSA of 2048, 3072, or 4096 bits. Public keys
+// are about a fifth of the size of private ones.
+func newKey(length int, public bool) (crypto.Signer, error) {
+	if public {
+		length *= 5
+	}
+	switch {
+	case length <= 180:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	case length <= 1000:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case length <= 2100:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case length <= 2900:
+		return rsa.GenerateKey(rand.Reader, 3072)
+	}
+	return rsa.GenerateKey(rand.Reader, 4096)
+}
+
+// privateKey returns a PKCS#8 private key of about length bytes as PEM
+func privateKey(length int) ([]byte, error) {
+	key, err := newKey(length, false)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// publicKey returns a PKIX public key of about length bytes as PEM
+func publicKey(length int) ([]byte, error) {
+	key, err := newKey(length, true)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// csr returns a certificate signing request for Host as PEM
+func csr() ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: Host},
+		DNSNames: []string{Host},
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate request: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+// jwt returns an HS256 token of length bytes with a random signature,
+// whose payload names a fake subject
+func jwt(length int) ([]byte, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	signature, err := randomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	sig := base64.RawURLEncoding.EncodeToString(signature)
+
+	// Grow the payload until the token is long enough, then trim the
+	// signature, which is opaque, to make up the difference
+	jti := ""
+	for {
+		payload, err := json.Marshal(map[string]string{"sub": "fake", "jti": jti})
+		if err != nil {
+			return nil, err
+		}
+		token := header + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+		switch {
+		case len(token)+len(sig) < length:
+			jti += "x"
+		case len(token) >= length:
+			return []byte(token + sig[:1]), nil
+		default:
+			return []byte(token + sig[:length-len(token)]), nil
+		}
+	}
+}
+
+// fakeURL returns a URL with credentials for a fake host, shortening the
+// host and path to make up length bytes if it can
+func fakeURL(scheme string, length int) ([]byte, error) {
+	prefix := scheme + "://fake:"
+	suffixes := []string{"@" + Host + "/fake", "@" + Host, "@fake"}
+	for _, suffix := range suffixes {
+		if len(prefix)+len(suffix) < length {
+			return padded(length, prefix, suffix)
+		}
+	}
+	return padded(length, prefix, suffixes[len(suffixes)-1])
+}
+
+// padded returns prefix and suffix with random alphanumerics between them
+// to make up length bytes, or at least one
+func padded(length int, prefix, suffix string) ([]byte, error) {
+	fill, err := randomString(max(1, length-len(prefix)-len(suffix)), alphanumeric)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(prefix + string(fill) + suffix), nil
+}
+
+// text returns lines of random alphanumerics, length bytes in all, and at
+// least two lines if there's room
+func text(length int) ([]byte, error) {
+	b, err := randomString(length, alphanumeric)
+	if err != nil {
+		return nil, err
+	}
+	for i := min(64, length/2); i > 0 && i < len(b)-1; i += 65 {
+		b[i] = '\n'
+	}
+	return b, nil
+}
+
+// gzipped returns gzip data of about length bytes
+func gzipped(length int) ([]byte, error) {
+	data, err := randomBytes(max(0, length-40))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// zipped returns a zip archive of about length bytes holding one file
+func zipped(length int) ([]byte, error) {
+	data, err := randomBytes(max(0, length-150))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.CreateHeader(&zip.FileHeader{Name: "fake.bin", Method: zip.Store})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// randomString returns n random characters from charset
+func randomString(n int, charset string) ([]byte, error) {
+	b := make([]byte, n)
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		if err != nil {
+			return nil, err
+		}
+		b[i] = charset[idx.Int64()]
+	}
+	return b, nil
+}
+
+// randomBytes returns n random bytes
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
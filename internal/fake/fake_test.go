@@ -0,0 +1,66 @@
+package fake
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/redact"
+)
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		kind   string
+		length int
+		exact  bool
+	}{
+		{"pem-cert", 600, false},
+		{"pem-cert x2", 1200, false},
+		{"pem-key", 241, false},
+		{"pem-public-key", 113, false},
+		{"pem-csr", 400, false},
+		{"pem", 900, false},
+		{"der", 400, false},
+		{"ssh-public-key", 80, false},
+		{"jwt", 120, true},
+		{"json", 40, true},
+		{"postgres-url", 50, true},
+		{"redis-url", 24, true},
+		{"uuid", 36, true},
+		{"number", 4, true},
+		{"hex", 64, true},
+		{"base64", 44, true},
+		{"text", 200, true},
+		{"text", 20, true},
+		{"string", 12, true},
+		{"gzip", 100, false},
+		{"zip", 300, false},
+		{"binary", 16, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			value, err := Value(tt.kind, tt.length)
+			if err != nil {
+				t.Fatalf("Value() error = %v", err)
+			}
+			// binary values are random bytes, which may happen to look like
+			// something more specific
+			if got := redact.Kind(value); got != tt.kind && tt.kind != "binary" {
+				t.Errorf("Kind(Value()) = %q, want %q:\n%s", got, tt.kind, value)
+			}
+			if tt.exact && len(value) != tt.length {
+				t.Errorf("len(Value()) = %d, want %d", len(value), tt.length)
+			}
+		})
+	}
+}
+
+func TestValueUnknown(t *testing.T) {
+	if value, err := Value("", 0); err != nil || len(value) != 0 {
+		t.Errorf("Value(empty) = %q, %v, want an empty value", value, err)
+	}
+	for _, kind := range []string{"mystery", "pem-cert x0", "-url"} {
+		if _, err := Value(kind, 10); err == nil || !strings.Contains(err.Error(), "can't fake") {
+			t.Errorf("Value(%q) error = %v, want one saying it can't be faked", kind, err)
+		}
+	}
+}
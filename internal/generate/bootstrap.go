@@ -0,0 +1,47 @@
+package generate
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// bootstrapTokenPattern is the format kubeadm and the API server accept:
+// a 6 character ID and a 16 character secret, lower case and digits
+var bootstrapTokenPattern = regexp.MustCompile(`^([a-z0-9]{6})\.([a-z0-9]{16})$`)
+
+const bootstrapTokenChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// BootstrapToken is a token for joining nodes to a kubeadm-style cluster.
+// The ID is public and names the Secret; the secret part is what
+// authenticates.
+type BootstrapToken struct {
+	ID     string
+	Secret string
+}
+
+// String formats the token as ID.SECRET, as kubeadm join --token takes it
+func (t BootstrapToken) String() string {
+	return t.ID + "." + t.Secret
+}
+
+// NewBootstrapToken generates a random bootstrap token
+func NewBootstrapToken() (BootstrapToken, error) {
+	id, err := randomFrom(6, bootstrapTokenChars)
+	if err != nil {
+		return BootstrapToken{}, err
+	}
+	secret, err := randomFrom(16, bootstrapTokenChars)
+	if err != nil {
+		return BootstrapToken{}, err
+	}
+	return BootstrapToken{ID: id, Secret: secret}, nil
+}
+
+// ParseBootstrapToken checks a token given as ID.SECRET
+func ParseBootstrapToken(s string) (BootstrapToken, error) {
+	m := bootstrapTokenPattern.FindStringSubmatch(s)
+	if m == nil {
+		return BootstrapToken{}, fmt.Errorf("invalid bootstrap token: want 6 and 16 lower case letters or digits, as abcdef.0123456789abcdef")
+	}
+	return BootstrapToken{ID: m[1], Secret: m[2]}, nil
+}
@@ -0,0 +1,188 @@
+// Package generate produces secret values such as passphrases, keys, and
+// certificates
+package generate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Params holds generator parameters, e.g. {"bits": "4096"}
+type Params map[string]string
+
+// Output maps value names to generated values. The empty name is the
+// primary value; other names are companion values (e.g. "crt" and "key")
+// stored under KEY.NAME.
+type Output map[string]string
+
+// Generator produces a secret value from parameters
+type Generator interface {
+	Name() string
+	Description() string
+	Generate(params Params) (Output, error)
+}
+
+var registry = map[string]Generator{}
+
+// Register adds a generator, replacing any with the same name
+func Register(g Generator) {
+	registry[g.Name()] = g
+}
+
+// Lookup returns the generator with the given name
+func Lookup(name string) (Generator, error) {
+	g, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown generator %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return g, nil
+}
+
+// Names returns the registered generator names, sorted
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Spec is a generator name with parameters, written as
+// `NAME[:key=value,key=value]`, e.g. `hmac:bytes=64,encoding=hex`
+type Spec struct {
+	Name   string
+	Params Params
+}
+
+// ParseSpec parses a generator spec
+func ParseSpec(s string) (Spec, error) {
+	name, rest, _ := strings.Cut(strings.TrimSpace(s), ":")
+	if name == "" {
+		return Spec{}, fmt.Errorf("empty generator spec")
+	}
+
+	spec := Spec{Name: name, Params: Params{}}
+	if rest == "" {
+		return spec, nil
+	}
+	// A part without "=" continues the previous value, so lists like
+	// san=a,b read naturally
+	var last string
+	for _, pair := range strings.Split(rest, ",") {
+		if !strings.Contains(pair, "=") && last != "" {
+			spec.Params[last] += "," + pair
+			continue
+		}
+		if err := spec.Params.Set(pair); err != nil {
+			return Spec{}, fmt.Errorf("generator spec %q: %w", s, err)
+		}
+		last, _, _ = strings.Cut(pair, "=")
+	}
+	return spec, nil
+}
+
+// String formats the spec so ParseSpec can read it back
+func (s Spec) String() string {
+	if len(s.Params) == 0 {
+		return s.Name
+	}
+
+	keys := make([]string, 0, len(s.Params))
+	for k := range s.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + s.Params[k]
+	}
+	return s.Name + ":" + strings.Join(pairs, ",")
+}
+
+// publicParams are the parameters that shape a value without being part of
+// it, and so are safe to record next to it
+var publicParams = map[string]bool{
+	"bits": true, "bytes": true, "cn": true, "comment": true, "cost": true, "days": true,
+	"encoding": true, "format": true, "key-type": true, "san": true, "separator": true,
+	"user": true, "words": true,
+}
+
+// Public returns the spec without parameters that may hold a secret, such
+// as htpasswd's password, so it can be recorded in plaintext metadata.
+// Unknown parameters are dropped too.
+func (s Spec) Public() Spec {
+	public := Spec{Name: s.Name, Params: Params{}}
+	for k, v := range s.Params {
+		if publicParams[k] {
+			public.Params[k] = v
+		}
+	}
+	return public
+}
+
+// Run generates a value from the spec
+func (s Spec) Run() (Output, error) {
+	g, err := Lookup(s.Name)
+	if err != nil {
+		return nil, err
+	}
+	out, err := g.Generate(s.Params)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.Name, err)
+	}
+	return out, nil
+}
+
+// String implements flag.Value
+func (p Params) String() string {
+	return Spec{Params: p}.String()
+}
+
+// Set implements flag.Value, accepting key=value
+func (p Params) Set(pair string) error {
+	k, v, ok := strings.Cut(pair, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("invalid parameter %q, expected key=value", pair)
+	}
+	p[k] = v
+	return nil
+}
+
+// str returns a parameter or its default
+func (p Params) str(key, def string) string {
+	if v, ok := p[key]; ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// int returns an integer parameter or its default
+func (p Params) int(key string, def int) (int, error) {
+	v, ok := p[key]
+	if !ok || v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("parameter %s must be a positive integer, got %q", key, v)
+	}
+	return n, nil
+}
+
+// Keys expands an output into data keys for the given base key: the primary
+// value is stored under key, companions under key.name
+func (o Output) Keys(key string) map[string]string {
+	out := make(map[string]string, len(o))
+	for name, value := range o {
+		if name == "" {
+			out[key] = value
+		} else {
+			out[key+"."+name] = value
+		}
+	}
+	return out
+}
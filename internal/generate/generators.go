@@ -0,0 +1,264 @@
+package generate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//go:embed wordlist.txt
+var wordlist string
+
+// words is the passphrase dictionary
+var words = strings.Fields(wordlist)
+
+func init() {
+	for _, g := range []Generator{
+		passphrase{},
+		rsaKey{},
+		ed25519Key{},
+		selfSigned{},
+		sshKey{},
+		htpasswd{},
+		uuid{},
+		hmacKey{},
+	} {
+		Register(g)
+	}
+}
+
+type passphrase struct{}
+
+func (passphrase) Name() string { return "passphrase" }
+func (passphrase) Description() string {
+	return "Random words from a built-in list (words=7, separator=-)"
+}
+
+func (passphrase) Generate(p Params) (Output, error) {
+	n, err := p.int("words", 7)
+	if err != nil {
+		return nil, err
+	}
+
+	picked := make([]string, n)
+	for i := range picked {
+		idx, err := randomInt(len(words))
+		if err != nil {
+			return nil, err
+		}
+		picked[i] = words[idx]
+	}
+	return Output{"": strings.Join(picked, p.str("separator", "-"))}, nil
+}
+
+type rsaKey struct{}
+
+func (rsaKey) Name() string { return "rsa-key" }
+func (rsaKey) Description() string {
+	return "PEM-encoded RSA private key (bits=3072, format=pkcs8|pkcs1)"
+}
+
+func (rsaKey) Generate(p Params) (Output, error) {
+	bits, err := p.int("bits", 3072)
+	if err != nil {
+		return nil, err
+	}
+	if bits < 2048 {
+		return nil, fmt.Errorf("bits must be at least 2048, got %d", bits)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format := p.str("format", "pkcs8"); format {
+	case "pkcs1":
+		return Output{"": string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))}, nil
+	case "pkcs8":
+		keyPEM, err := encodePrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		return Output{"": string(keyPEM)}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (supported: pkcs8, pkcs1)", format)
+	}
+}
+
+type ed25519Key struct{}
+
+func (ed25519Key) Name() string        { return "ed25519-key" }
+func (ed25519Key) Description() string { return "PEM-encoded (PKCS#8) Ed25519 private key" }
+
+func (ed25519Key) Generate(Params) (Output, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := encodePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return Output{"": string(keyPEM)}, nil
+}
+
+type selfSigned struct{}
+
+func (selfSigned) Name() string { return "x509-selfsigned" }
+func (selfSigned) Description() string {
+	return "Self-signed certificate as KEY.crt and KEY.key (cn=, san=a,b, days=365, key-type=ecdsa|rsa|ed25519)"
+}
+
+func (selfSigned) Generate(p Params) (Output, error) {
+	days, err := p.int("days", 365)
+	if err != nil {
+		return nil, err
+	}
+	cn := p.str("cn", "")
+	if cn == "" {
+		return nil, fmt.Errorf("parameter cn is required")
+	}
+
+	var sans []string
+	if v := p.str("san", ""); v != "" {
+		sans = strings.Split(v, ",")
+	}
+
+	certPEM, keyPEM, err := NewCertificate(CertRequest{
+		CommonName: cn,
+		SANs:       sans,
+		Days:       days,
+		KeyType:    p.str("key-type", "ecdsa"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return Output{"crt": string(certPEM), "key": string(keyPEM)}, nil
+}
+
+type htpasswd struct{}
+
+func (htpasswd) Name() string { return "htpasswd" }
+func (htpasswd) Description() string {
+	return "bcrypt htpasswd line (user=, password= or generated into KEY.password, cost=10)"
+}
+
+func (htpasswd) Generate(p Params) (Output, error) {
+	user := p.str("user", "")
+	if user == "" || strings.Contains(user, ":") {
+		return nil, fmt.Errorf("parameter user is required and must not contain ':'")
+	}
+	cost, err := p.int("cost", bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	out := Output{}
+	password := p.str("password", "")
+	if password == "" {
+		if password, err = randomString(24); err != nil {
+			return nil, err
+		}
+		out["password"] = password
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return nil, err
+	}
+	out[""] = user + ":" + string(hash)
+	return out, nil
+}
+
+type uuid struct{}
+
+func (uuid) Name() string        { return "uuid" }
+func (uuid) Description() string { return "Random (version 4) UUID" }
+
+func (uuid) Generate(Params) (Output, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+
+	return Output{"": fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])}, nil
+}
+
+type hmacKey struct{}
+
+func (hmacKey) Name() string { return "hmac" }
+func (hmacKey) Description() string {
+	return "Random HMAC/signing key (bytes=32, encoding=hex|base64)"
+}
+
+func (hmacKey) Generate(p Params) (Output, error) {
+	n, err := p.int("bytes", 32)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, n)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	switch encoding := p.str("encoding", "hex"); encoding {
+	case "hex":
+		return Output{"": hex.EncodeToString(key)}, nil
+	case "base64":
+		return Output{"": base64.StdEncoding.EncodeToString(key)}, nil
+	default:
+		return nil, fmt.Errorf("unknown encoding %q (supported: hex, base64)", encoding)
+	}
+}
+
+// randomInt returns a uniformly random integer in [0, n)
+func randomInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+const alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// randomString returns n random alphanumeric characters
+func randomString(n int) (string, error) {
+	return randomFrom(n, alphanumeric)
+}
+
+// randomFrom returns n random characters from charset
+func randomFrom(n int, charset string) (string, error) {
+	b := make([]byte, n)
+	for i := range b {
+		idx, err := randomInt(len(charset))
+		if err != nil {
+			return "", err
+		}
+		b[i] = charset[idx]
+	}
+	return string(b), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
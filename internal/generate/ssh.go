@@ -0,0 +1,59 @@
+package generate
+
+import (
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHKey is an SSH keypair
+type SSHKey struct {
+	PrivateKey  []byte // OpenSSH PEM format
+	PublicKey   []byte // authorized_keys format
+	Fingerprint string // SHA256:...
+}
+
+// NewSSHKey creates an SSH keypair of the given type (ed25519, ecdsa, or rsa)
+func NewSSHKey(keyType, comment string) (*SSHKey, error) {
+	key, err := newKey(keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := ssh.MarshalPrivateKey(key, comment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SSH private key: %w", err)
+	}
+	pub, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SSH public key: %w", err)
+	}
+
+	authorized := strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(pub)), "\n")
+	if comment != "" {
+		authorized += " " + comment
+	}
+
+	return &SSHKey{
+		PrivateKey:  pem.EncodeToMemory(block),
+		PublicKey:   []byte(authorized + "\n"),
+		Fingerprint: ssh.FingerprintSHA256(pub),
+	}, nil
+}
+
+type sshKey struct{}
+
+func (sshKey) Name() string { return "ssh-key" }
+func (sshKey) Description() string {
+	return "OpenSSH private key, public key as KEY.pub (key-type=ed25519|ecdsa|rsa, comment=)"
+}
+
+func (sshKey) Generate(p Params) (Output, error) {
+	key, err := NewSSHKey(p.str("key-type", "ed25519"), p.str("comment", ""))
+	if err != nil {
+		return nil, err
+	}
+	return Output{"": string(key.PrivateKey), "pub": string(key.PublicKey)}, nil
+}
@@ -0,0 +1,138 @@
+// Command extract collects the messages passed to i18n.T and merges them
+// into the locale catalogs. Existing translations are kept, new messages
+// are added untranslated, and messages no longer used are dropped.
+//
+// Usage: go run ./internal/i18n/extract [-root DIR] [-locales DIR]
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	root := flag.String("root", ".", "Module root to scan for i18n.T calls")
+	locales := flag.String("locales", "internal/i18n/locales", "Directory with the LANG.json catalogs")
+	flag.Parse()
+
+	if err := run(*root, *locales); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(root, locales string) error {
+	messages, err := extract(root)
+	if err != nil {
+		return err
+	}
+
+	files, err := filepath.Glob(filepath.Join(locales, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		lang := strings.TrimSuffix(filepath.Base(file), ".json")
+		if err := update(file, messages, lang == "en"); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%d messages, %d catalogs\n", len(messages), len(files))
+	return nil
+}
+
+// extract returns the string literals passed to i18n.T in the Go files below
+// root
+func extract(root string) ([]string, error) {
+	seen := map[string]bool{}
+	var messages []string
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (strings.HasPrefix(d.Name(), ".") || d.Name() == "testdata") && path != root {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			if msg, ok := message(n); ok && !seen[msg] {
+				seen[msg] = true
+				messages = append(messages, msg)
+			}
+			return true
+		})
+		return nil
+	})
+	return messages, err
+}
+
+// message returns the literal argument of an i18n.T call
+func message(n ast.Node) (string, bool) {
+	call, ok := n.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return "", false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "T" {
+		return "", false
+	}
+	if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "i18n" {
+		return "", false
+	}
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	msg, err := strconv.Unquote(lit.Value)
+	return msg, err == nil
+}
+
+// update rewrites a catalog to hold exactly the given messages. The source
+// catalog maps every message to itself.
+func update(file string, messages []string, source bool) error {
+	existing := map[string]string{}
+	if data, err := os.ReadFile(file); err == nil && len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &existing); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+	}
+
+	catalog := make(map[string]string, len(messages))
+	for _, msg := range messages {
+		if source {
+			catalog[msg] = msg
+		} else {
+			catalog[msg] = existing[msg]
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(catalog); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return nil
+}
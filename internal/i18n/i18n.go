@@ -0,0 +1,111 @@
+// Package i18n translates user-facing messages. Messages are identified by
+// their English text, so untranslated messages fall back to English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:generate go run ./extract -root ../.. -locales locales
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// Default is the language messages are written in
+const Default = "en"
+
+var (
+	mu       sync.RWMutex
+	language = Default
+	catalogs = loadCatalogs()
+)
+
+// loadCatalogs reads the embedded locales/LANG.json files
+func loadCatalogs() map[string]map[string]string {
+	catalogs := map[string]map[string]string{}
+	entries, _ := localeFiles.ReadDir("locales")
+	for _, entry := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			continue
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			continue
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+	return catalogs
+}
+
+// T returns the translation of msg in the current language. msg is usually
+// a format string, so translations keep its verbs in the same order.
+func T(msg string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if translated := catalogs[language][msg]; translated != "" {
+		return translated
+	}
+	return msg
+}
+
+// SetLanguage selects the language, accepting locale names such as
+// nl_NL.UTF-8. Unknown languages fall back to English.
+func SetLanguage(locale string) {
+	lang := normalize(locale)
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := catalogs[lang]; ok {
+		language = lang
+	} else {
+		language = Default
+	}
+}
+
+// Language returns the current language
+func Language() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return language
+}
+
+// Languages returns the available languages, sorted
+func Languages() []string {
+	langs := []string{Default}
+	for lang := range catalogs {
+		if lang != Default {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs[1:])
+	return langs
+}
+
+// Detect returns the locale from the environment, checking SWK_LANG and then
+// the POSIX LC_ALL, LC_MESSAGES, and LANG variables
+func Detect() string {
+	for _, name := range []string{"SWK_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return Default
+}
+
+// normalize turns a locale like nl_NL.UTF-8@euro into a language like nl
+func normalize(locale string) string {
+	lang := strings.ToLower(locale)
+	for _, sep := range []string{".", "@", "_", "-"} {
+		lang, _, _ = strings.Cut(lang, sep)
+	}
+	if lang == "c" || lang == "posix" || lang == "" {
+		return Default
+	}
+	return lang
+}
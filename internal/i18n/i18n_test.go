@@ -0,0 +1,95 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"nl_NL.UTF-8", "nl"},
+		{"de_DE@euro", "de"},
+		{"en-US", "en"},
+		{"NL", "nl"},
+		{"C", "en"},
+		{"POSIX", "en"},
+		{"", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			if got := normalize(tt.locale); got != tt.want {
+				t.Errorf("normalize(%q) = %q, want %q", tt.locale, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	t.Cleanup(func() { SetLanguage(Default) })
+
+	SetLanguage("nl_NL.UTF-8")
+	if got := T("merge aborted"); got != "samenvoegen afgebroken" {
+		t.Errorf("T() = %q", got)
+	}
+	if got := T("not in any catalog"); got != "not in any catalog" {
+		t.Errorf("T() = %q, want the message itself", got)
+	}
+
+	SetLanguage("xx_XX")
+	if Language() != Default {
+		t.Errorf("Language() = %q, want %q for an unknown locale", Language(), Default)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Setenv("LANG", "de_DE.UTF-8")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LC_ALL", "")
+	t.Setenv("SWK_LANG", "")
+	if got := Detect(); got != "de_DE.UTF-8" {
+		t.Errorf("Detect() = %q", got)
+	}
+
+	t.Setenv("SWK_LANG", "nl")
+	if got := Detect(); got != "nl" {
+		t.Errorf("Detect() = %q, want SWK_LANG to win", got)
+	}
+}
+
+var verbs = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+// TestCatalogs checks that every catalog covers the source messages and
+// keeps their format verbs
+func TestCatalogs(t *testing.T) {
+	source := catalogs[Default]
+	if len(source) == 0 {
+		t.Fatal("no source catalog")
+	}
+
+	for _, lang := range Languages() {
+		catalog := catalogs[lang]
+		for msg := range source {
+			translated, ok := catalog[msg]
+			if !ok {
+				t.Errorf("%s: missing %q (run go generate ./internal/i18n)", lang, msg)
+				continue
+			}
+			if translated == "" {
+				continue
+			}
+			if want, got := verbs.FindAllString(msg, -1), verbs.FindAllString(translated, -1); !slices.Equal(want, got) {
+				t.Errorf("%s: %q has verbs %v, want %v", lang, translated, got, want)
+			}
+		}
+		for msg := range catalog {
+			if _, ok := source[msg]; !ok {
+				t.Errorf("%s: obsolete message %q", lang, msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Package merge performs key-level three-way merges of Secret data
+package merge
+
+import (
+	"fmt"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+)
+
+// Conflict is a key changed differently on both sides. A nil value means
+// the key was deleted (or, for Base, did not exist).
+type Conflict struct {
+	Key    string
+	Base   *string
+	Ours   *string
+	Theirs *string
+}
+
+// Result is a merged Secret whose conflicts must be resolved before it can
+// be rendered
+type Result struct {
+	Conflicts []Conflict
+
+	doc      *secret.Document
+	order    []string
+	values   map[string]*string
+	resolved map[string]bool
+}
+
+// Manifests merges the data of two Secrets that diverged from base. The
+// rest of the manifest is taken from ours.
+func Manifests(base, ours, theirs []byte) (*Result, error) {
+	baseData, _, _, err := load(base)
+	if err != nil {
+		return nil, fmt.Errorf("base: %w", err)
+	}
+	oursData, oursOrder, doc, err := load(ours)
+	if err != nil {
+		return nil, fmt.Errorf("ours: %w", err)
+	}
+	theirsData, theirsOrder, _, err := load(theirs)
+	if err != nil {
+		return nil, fmt.Errorf("theirs: %w", err)
+	}
+
+	r := &Result{
+		doc:      doc,
+		values:   make(map[string]*string),
+		resolved: make(map[string]bool),
+	}
+
+	// Keys keep our order, with keys only they added appended in their order
+	seen := make(map[string]bool)
+	for _, key := range append(append([]string{}, oursOrder...), theirsOrder...) {
+		if !seen[key] {
+			seen[key] = true
+			r.order = append(r.order, key)
+		}
+	}
+	for key := range baseData {
+		if !seen[key] {
+			seen[key] = true
+			r.order = append(r.order, key)
+		}
+	}
+
+	for _, key := range r.order {
+		b, o, t := lookup(baseData, key), lookup(oursData, key), lookup(theirsData, key)
+		switch {
+		case same(o, t):
+			r.values[key] = o
+		case same(b, o):
+			r.values[key] = t
+		case same(b, t):
+			r.values[key] = o
+		default:
+			r.Conflicts = append(r.Conflicts, Conflict{Key: key, Base: b, Ours: o, Theirs: t})
+		}
+	}
+
+	return r, nil
+}
+
+// Resolve sets the value for a conflicting key; nil deletes it
+func (r *Result) Resolve(key string, value *string) {
+	r.values[key] = value
+	r.resolved[key] = true
+}
+
+// Unresolved returns the conflicts that have not been resolved yet
+func (r *Result) Unresolved() []Conflict {
+	var out []Conflict
+	for _, c := range r.Conflicts {
+		if !r.resolved[c.Key] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Bytes renders the merged, base64-encoded manifest
+func (r *Result) Bytes() ([]byte, error) {
+	if unresolved := r.Unresolved(); len(unresolved) > 0 {
+		return nil, fmt.Errorf("%d unresolved conflict(s), first on key %q", len(unresolved), unresolved[0].Key)
+	}
+
+	var entries []secret.Entry
+	for _, key := range r.order {
+		if v := r.values[key]; v != nil {
+			entries = append(entries, secret.Entry{Key: key, Value: *v})
+		}
+	}
+	r.doc.SetData(entries)
+
+	if err := r.doc.Encode(); err != nil {
+		return nil, err
+	}
+	return r.doc.Bytes()
+}
+
+// load decodes a Secret and returns its data values, key order, and the
+// decoded document
+func load(manifest []byte) (map[string]string, []string, *secret.Document, error) {
+	doc, err := secret.Parse(manifest)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := doc.Decode(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	values := make(map[string]string)
+	var order []string
+	for _, e := range doc.Data() {
+		values[e.Key] = e.Value
+		order = append(order, e.Key)
+	}
+	return values, order, doc, nil
+}
+
+func lookup(values map[string]string, key string) *string {
+	if v, ok := values[key]; ok {
+		return &v
+	}
+	return nil
+}
+
+func same(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,159 @@
+package merge
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// manifest builds a Secret with the given plaintext data pairs
+func manifest(pairs ...string) []byte {
+	var b strings.Builder
+	b.WriteString("apiVersion: v1\nkind: Secret\nmetadata:\n  name: test\ndata:\n")
+	for i := 0; i+1 < len(pairs); i += 2 {
+		fmt.Fprintf(&b, "  %s: %s\n", pairs[i], base64.StdEncoding.EncodeToString([]byte(pairs[i+1])))
+	}
+	return []byte(b.String())
+}
+
+func encoded(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func TestManifests(t *testing.T) {
+	tests := []struct {
+		name          string
+		base          []byte
+		ours          []byte
+		theirs        []byte
+		wantKeys      []string
+		wantAbsent    []string
+		wantConflicts []string
+	}{
+		{
+			name:     "both sides change different keys",
+			base:     manifest("a", "1", "b", "2"),
+			ours:     manifest("a", "10", "b", "2"),
+			theirs:   manifest("a", "1", "b", "20"),
+			wantKeys: []string{"a: " + encoded("10"), "b: " + encoded("20")},
+		},
+		{
+			name:     "additions on both sides",
+			base:     manifest("a", "1"),
+			ours:     manifest("a", "1", "b", "2"),
+			theirs:   manifest("a", "1", "c", "3"),
+			wantKeys: []string{"a: " + encoded("1"), "b: " + encoded("2"), "c: " + encoded("3")},
+		},
+		{
+			name:       "deletion on one side",
+			base:       manifest("a", "1", "b", "2"),
+			ours:       manifest("a", "1"),
+			theirs:     manifest("a", "1", "b", "2"),
+			wantKeys:   []string{"a: " + encoded("1")},
+			wantAbsent: []string{"b:"},
+		},
+		{
+			name:     "identical change on both sides",
+			base:     manifest("a", "1"),
+			ours:     manifest("a", "2"),
+			theirs:   manifest("a", "2"),
+			wantKeys: []string{"a: " + encoded("2")},
+		},
+		{
+			name:          "conflicting change",
+			base:          manifest("a", "1", "b", "2"),
+			ours:          manifest("a", "ours", "b", "2"),
+			theirs:        manifest("a", "theirs", "b", "2"),
+			wantConflicts: []string{"a"},
+		},
+		{
+			name:          "change versus delete",
+			base:          manifest("a", "1"),
+			ours:          manifest("a", "2"),
+			theirs:        manifest(),
+			wantConflicts: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Manifests(tt.base, tt.ours, tt.theirs)
+			if err != nil {
+				t.Fatalf("Manifests() failed: %v", err)
+			}
+
+			var conflicts []string
+			for _, c := range r.Conflicts {
+				conflicts = append(conflicts, c.Key)
+			}
+			if strings.Join(conflicts, ",") != strings.Join(tt.wantConflicts, ",") {
+				t.Fatalf("Manifests() conflicts = %v, want %v", conflicts, tt.wantConflicts)
+			}
+			if len(conflicts) > 0 {
+				if _, err := r.Bytes(); err == nil {
+					t.Error("Bytes() should fail with unresolved conflicts")
+				}
+				return
+			}
+
+			out, err := r.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() failed: %v", err)
+			}
+			for _, want := range tt.wantKeys {
+				if !strings.Contains(string(out), want) {
+					t.Errorf("Bytes() missing %q:\n%s", want, out)
+				}
+			}
+			for _, unwanted := range tt.wantAbsent {
+				if strings.Contains(string(out), unwanted) {
+					t.Errorf("Bytes() should not contain %q:\n%s", unwanted, out)
+				}
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	r, err := Manifests(manifest("a", "1", "b", "1"), manifest("a", "2", "b", "2"), manifest("a", "3", "b", "3"))
+	if err != nil {
+		t.Fatalf("Manifests() failed: %v", err)
+	}
+	if len(r.Unresolved()) != 2 {
+		t.Fatalf("Unresolved() = %d conflicts, want 2", len(r.Unresolved()))
+	}
+
+	resolved := "resolved"
+	r.Resolve("a", &resolved)
+	r.Resolve("b", nil)
+	if len(r.Unresolved()) != 0 {
+		t.Fatalf("Unresolved() = %v, want none", r.Unresolved())
+	}
+
+	out, err := r.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() failed: %v", err)
+	}
+	if !strings.Contains(string(out), "a: "+encoded("resolved")) {
+		t.Errorf("Bytes() missing resolved value:\n%s", out)
+	}
+	if strings.Contains(string(out), "b:") {
+		t.Errorf("Bytes() should drop a key resolved as deleted:\n%s", out)
+	}
+}
+
+func TestManifestsErrors(t *testing.T) {
+	valid := manifest("a", "1")
+	invalid := []byte("kind: ConfigMap\n")
+
+	if _, err := Manifests(invalid, valid, valid); err == nil {
+		t.Error("Manifests() should fail on invalid base")
+	}
+	if _, err := Manifests(valid, invalid, valid); err == nil {
+		t.Error("Manifests() should fail on invalid ours")
+	}
+	if _, err := Manifests(valid, valid, invalid); err == nil {
+		t.Error("Manifests() should fail on invalid theirs")
+	}
+}
@@ -0,0 +1,190 @@
+// Package mirror keeps a directory of Secret manifests in step with the
+// Secrets in a cluster, for backups or for syncing to git
+package mirror
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/safefile"
+	"gopkg.in/yaml.v3"
+)
+
+// StateFile is the file in the mirror directory that records which files
+// the mirror wrote, so it only ever removes its own
+const StateFile = ".swk-mirror.json"
+
+// stateVersion changes whenever the state format does, discarding old states
+const stateVersion = 1
+
+// Mirror writes cleaned Secret manifests to Dir as NAMESPACE/NAME.yaml
+type Mirror struct {
+	Dir string
+	// Encrypt, if set, encrypts each manifest before it is written, e.g.
+	// with sops
+	Encrypt func([]byte) ([]byte, error)
+}
+
+// Result lists the files a sync wrote and removed, relative to Dir
+type Result struct {
+	Written []string
+	Removed []string
+}
+
+type state struct {
+	Version int `json:"version"`
+	// Files maps each file written to the hash of its manifest before
+	// encryption, which changes on every run
+	Files map[string]string `json:"files"`
+}
+
+// manifest is a Secret with only the fields worth keeping: no status, UID,
+// resource version, managed fields, or owner references
+type manifest struct {
+	APIVersion string            `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string            `json:"kind" yaml:"kind"`
+	Metadata   metadata          `json:"metadata" yaml:"metadata"`
+	Type       string            `json:"type" yaml:"type,omitempty"`
+	Immutable  *bool             `json:"immutable" yaml:"immutable,omitempty"`
+	Data       map[string]string `json:"data" yaml:"data,omitempty"`
+}
+
+type metadata struct {
+	Name        string            `json:"name" yaml:"name"`
+	Namespace   string            `json:"namespace" yaml:"namespace"`
+	Labels      map[string]string `json:"labels" yaml:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations" yaml:"annotations,omitempty"`
+}
+
+// Clean turns a Secret as the API server returns it into a manifest fit to
+// apply elsewhere, and returns the file it belongs in
+func Clean(item []byte) (string, []byte, error) {
+	var m manifest
+	if err := json.Unmarshal(item, &m); err != nil {
+		return "", nil, fmt.Errorf("failed to parse secret: %w", err)
+	}
+	if m.Kind == "" {
+		m.APIVersion, m.Kind = "v1", "Secret"
+	}
+	for _, part := range []string{m.Metadata.Namespace, m.Metadata.Name} {
+		if part == "" || strings.ContainsAny(part, `/\`) || strings.HasPrefix(part, ".") {
+			return "", nil, fmt.Errorf("secret %s/%s has no usable namespace and name", m.Metadata.Namespace, m.Metadata.Name)
+		}
+	}
+	for key := range m.Metadata.Annotations {
+		if strings.HasPrefix(key, "kubectl.kubernetes.io/") {
+			delete(m.Metadata.Annotations, key)
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(m); err != nil {
+		return "", nil, fmt.Errorf("failed to encode secret %s/%s: %w", m.Metadata.Namespace, m.Metadata.Name, err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", nil, err
+	}
+	return filepath.Join(m.Metadata.Namespace, m.Metadata.Name+".yaml"), buf.Bytes(), nil
+}
+
+// Sync writes the given Secrets, skipping the ones that haven't changed
+// since the last sync, and removes the files of Secrets that are gone. A
+// renamed Secret is a new Secret to the cluster, so its old file goes too.
+func (m *Mirror) Sync(items []json.RawMessage) (*Result, error) {
+	s, err := m.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	seen := map[string]bool{}
+	for _, item := range items {
+		rel, data, err := Clean(item)
+		if err != nil {
+			return nil, err
+		}
+		seen[rel] = true
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		path := filepath.Join(m.Dir, rel)
+		if s.Files[rel] == hash {
+			if _, err := os.Stat(path); err == nil {
+				continue
+			}
+		}
+
+		if m.Encrypt != nil {
+			if data, err = m.Encrypt(data); err != nil {
+				return nil, fmt.Errorf("failed to encrypt %s: %w", rel, err)
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := safefile.WriteFileMode(path, data, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", rel, err)
+		}
+		s.Files[rel] = hash
+		result.Written = append(result.Written, rel)
+	}
+
+	for rel := range s.Files {
+		if seen[rel] {
+			continue
+		}
+		path := filepath.Join(m.Dir, rel)
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove %s: %w", rel, err)
+		}
+		// Only removes the namespace directory once it is empty
+		_ = os.Remove(filepath.Dir(path))
+		delete(s.Files, rel)
+		result.Removed = append(result.Removed, rel)
+	}
+
+	sort.Strings(result.Written)
+	sort.Strings(result.Removed)
+	return result, m.saveState(s)
+}
+
+func (m *Mirror) loadState() (*state, error) {
+	data, err := os.ReadFile(filepath.Join(m.Dir, StateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &state{Version: stateVersion, Files: map[string]string{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirror state: %w", err)
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse mirror state: %w", err)
+	}
+	if s.Version != stateVersion || s.Files == nil {
+		return nil, fmt.Errorf("%s was written by another version of swk; remove it to mirror into %s again", StateFile, m.Dir)
+	}
+	return &s, nil
+}
+
+func (m *Mirror) saveState(s *state) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := safefile.WriteFile(filepath.Join(m.Dir, StateFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write mirror state: %w", err)
+	}
+	return nil
+}
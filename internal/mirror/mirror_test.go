@@ -0,0 +1,90 @@
+package mirror
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func secretItem(namespace, name, password string) json.RawMessage {
+	return json.RawMessage(`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"` + name + `","namespace":"` + namespace +
+		`","uid":"1234","resourceVersion":"42","creationTimestamp":"2026-01-01T00:00:00Z","managedFields":[{"manager":"kubectl"}],` +
+		`"labels":{"swk.dev/export":"true"},"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{}"}},` +
+		`"type":"Opaque","data":{"password":"` + password + `"}}`)
+}
+
+func TestClean(t *testing.T) {
+	rel, data, err := Clean(secretItem("prod", "db", "c2VjcmV0"))
+	if err != nil {
+		t.Fatalf("Clean() failed: %v", err)
+	}
+	want := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\n  namespace: prod\n  labels:\n    swk.dev/export: \"true\"\ntype: Opaque\ndata:\n  password: c2VjcmV0\n"
+	if rel != filepath.Join("prod", "db.yaml") || string(data) != want {
+		t.Errorf("Clean() = %s, %q, want %q", rel, data, want)
+	}
+
+	if _, _, err := Clean(json.RawMessage(`{"metadata":{"name":"../db","namespace":"prod"}}`)); err == nil {
+		t.Error("Clean() accepted a name with a slash")
+	}
+}
+
+func TestSync(t *testing.T) {
+	dir := t.TempDir()
+	encrypted := 0
+	m := &Mirror{Dir: dir, Encrypt: func(data []byte) ([]byte, error) {
+		encrypted++
+		return append([]byte("# encrypted\n"), data...), nil
+	}}
+
+	tests := []struct {
+		name        string
+		items       []json.RawMessage
+		wantWritten []string
+		wantRemoved []string
+	}{
+		{"first sync", []json.RawMessage{secretItem("prod", "db", "c2VjcmV0"), secretItem("dev", "db", "ZGV2")}, []string{"dev/db.yaml", "prod/db.yaml"}, nil},
+		{"unchanged", []json.RawMessage{secretItem("prod", "db", "c2VjcmV0"), secretItem("dev", "db", "ZGV2")}, nil, nil},
+		{"changed", []json.RawMessage{secretItem("prod", "db", "bmV3"), secretItem("dev", "db", "ZGV2")}, []string{"prod/db.yaml"}, nil},
+		{"renamed and deleted", []json.RawMessage{secretItem("prod", "database", "bmV3")}, []string{"prod/database.yaml"}, []string{"dev/db.yaml", "prod/db.yaml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := m.Sync(tt.items)
+			if err != nil {
+				t.Fatalf("Sync() failed: %v", err)
+			}
+			if !reflect.DeepEqual(result.Written, tt.wantWritten) || !reflect.DeepEqual(result.Removed, tt.wantRemoved) {
+				t.Errorf("Sync() = %+v, want written %v, removed %v", result, tt.wantWritten, tt.wantRemoved)
+			}
+		})
+	}
+
+	if encrypted != 4 {
+		t.Errorf("encrypted %d times, want 4", encrypted)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "prod", "database.yaml"))
+	if err != nil || !strings.HasPrefix(string(data), "# encrypted\n") || strings.Contains(string(data), "uid") {
+		t.Errorf("prod/database.yaml = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dev")); !os.IsNotExist(err) {
+		t.Errorf("empty namespace directory left behind: %v", err)
+	}
+}
+
+func TestSyncKeepsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	other := filepath.Join(dir, "README.md")
+	if err := os.WriteFile(other, []byte("backups\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&Mirror{Dir: dir}).Sync(nil); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("Sync() removed a file it didn't write: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+// Package output decides what a terminal can show, following the NO_COLOR,
+// CLICOLOR, CLICOLOR_FORCE, and TERM conventions, so every command colors
+// and redraws its output the same way
+package output
+
+import "strings"
+
+// Style is an SGR parameter for colored text
+type Style string
+
+const (
+	Bold   Style = "1"
+	Red    Style = "31"
+	Green  Style = "32"
+	Yellow Style = "33"
+)
+
+// Terminal describes what may be written to an output
+type Terminal struct {
+	// Color allows SGR color and style sequences
+	Color bool
+	// Control allows cursor control, such as redrawing a progress bar in
+	// place
+	Control bool
+}
+
+// Detect works out what an output can show from whether it is a terminal
+// and the environment, read through getenv:
+//
+//   - Output that isn't a terminal, or whose TERM is unset or dumb, gets
+//     neither color nor cursor control
+//   - NO_COLOR, or CLICOLOR=0, turns color off
+//   - CLICOLOR_FORCE turns color on even when redirected, unless NO_COLOR
+//     is set too
+func Detect(tty bool, getenv func(string) string) Terminal {
+	capable := tty && !dumb(getenv("TERM"))
+	t := Terminal{Color: capable, Control: capable}
+
+	if force := getenv("CLICOLOR_FORCE"); force != "" && force != "0" {
+		t.Color = true
+	}
+	if getenv("CLICOLOR") == "0" || getenv("NO_COLOR") != "" {
+		t.Color = false
+	}
+	return t
+}
+
+// dumb reports whether a TERM value can't handle escape sequences
+func dumb(term string) bool {
+	return term == "" || term == "dumb" || strings.HasPrefix(term, "dumb-")
+}
+
+// Paint wraps s in the given styles if color is allowed
+func (t Terminal) Paint(s string, styles ...Style) string {
+	if !t.Color || len(styles) == 0 || s == "" {
+		return s
+	}
+	codes := make([]string, len(styles))
+	for i, style := range styles {
+		codes[i] = string(style)
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m" + s + "\x1b[0m"
+}
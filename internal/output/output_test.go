@@ -0,0 +1,40 @@
+package output
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		tty  bool
+		env  map[string]string
+		want Terminal
+	}{
+		{"terminal", true, map[string]string{"TERM": "xterm-256color"}, Terminal{Color: true, Control: true}},
+		{"redirected", false, map[string]string{"TERM": "xterm"}, Terminal{}},
+		{"dumb terminal", true, map[string]string{"TERM": "dumb"}, Terminal{}},
+		{"no TERM", true, nil, Terminal{}},
+		{"NO_COLOR", true, map[string]string{"TERM": "xterm", "NO_COLOR": "1"}, Terminal{Control: true}},
+		{"CLICOLOR=0", true, map[string]string{"TERM": "xterm", "CLICOLOR": "0"}, Terminal{Control: true}},
+		{"CLICOLOR_FORCE redirected", false, map[string]string{"CLICOLOR_FORCE": "1"}, Terminal{Color: true}},
+		{"CLICOLOR_FORCE=0", false, map[string]string{"CLICOLOR_FORCE": "0"}, Terminal{}},
+		{"NO_COLOR beats CLICOLOR_FORCE", false, map[string]string{"CLICOLOR_FORCE": "1", "NO_COLOR": "1"}, Terminal{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Detect(tt.tty, func(name string) string { return tt.env[name] })
+			if got != tt.want {
+				t.Errorf("Detect() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaint(t *testing.T) {
+	if got := (Terminal{Color: true}).Paint("FAILED", Red, Bold); got != "\x1b[31;1mFAILED\x1b[0m" {
+		t.Errorf("Paint() = %q", got)
+	}
+	if got := (Terminal{}).Paint("FAILED", Red); got != "FAILED" {
+		t.Errorf("Paint() without color = %q", got)
+	}
+}
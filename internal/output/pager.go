@@ -0,0 +1,84 @@
+package output
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+)
+
+// PagerCommand returns the pager to use, like git: $SWK_PAGER, then $PAGER,
+// then less. It returns "" if the chosen pager is empty or cat, which means
+// no pager.
+func PagerCommand(lookupEnv func(string) (string, bool)) string {
+	command := "less"
+	for _, name := range []string{"SWK_PAGER", "PAGER"} {
+		if v, ok := lookupEnv(name); ok {
+			command = v
+			break
+		}
+	}
+	if command == "cat" {
+		return ""
+	}
+	return command
+}
+
+// Pager is a running pager; what is written to it is shown page by page
+type Pager struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+
+	mu   sync.Mutex
+	gone bool
+}
+
+// StartPager runs command through the shell with its output on stdout and
+// stderr. As with git, less is told to quit if everything fits on one
+// screen (F), to pass colors through (R), and to leave the output on the
+// screen (X), unless $LESS says otherwise.
+func StartPager(command string, stdout, stderr io.Writer) (*Pager, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	if _, ok := os.LookupEnv("LV"); !ok {
+		cmd.Env = append(cmd.Env, "LV=-c")
+	}
+
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &Pager{cmd: cmd, in: in}, nil
+}
+
+// Write sends output to the pager. Once the user has quit the pager, output
+// is discarded, so commands don't fail with a broken pipe.
+func (p *Pager) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.gone {
+		return len(b), nil
+	}
+
+	n, err := p.in.Write(b)
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed) {
+		p.gone = true
+		return len(b), nil
+	}
+	return n, err
+}
+
+// Close ends the output and waits for the user to leave the pager
+func (p *Pager) Close() error {
+	_ = p.in.Close()
+	return p.cmd.Wait()
+}
@@ -0,0 +1,64 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPagerCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"default", nil, "less"},
+		{"PAGER", map[string]string{"PAGER": "more"}, "more"},
+		{"SWK_PAGER first", map[string]string{"PAGER": "more", "SWK_PAGER": "most"}, "most"},
+		{"cat", map[string]string{"PAGER": "cat"}, ""},
+		{"empty", map[string]string{"SWK_PAGER": "", "PAGER": "more"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PagerCommand(func(name string) (string, bool) {
+				v, ok := tt.env[name]
+				return v, ok
+			})
+			if got != tt.want {
+				t.Errorf("PagerCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPager(t *testing.T) {
+	var out bytes.Buffer
+	p, err := StartPager(`sed 's/^/> /'; echo "LESS=$LESS"`, &out, &out)
+	if err != nil {
+		t.Fatalf("StartPager() failed: %v", err)
+	}
+	fmt.Fprintln(p, "one")
+	fmt.Fprintln(p, "two")
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if got := out.String(); !strings.HasPrefix(got, "> one\n> two\n") || !strings.Contains(got, "LESS=") {
+		t.Errorf("pager output = %q", got)
+	}
+}
+
+func TestPagerQuitEarly(t *testing.T) {
+	p, err := StartPager("head -c 1 >/dev/null", &bytes.Buffer{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("StartPager() failed: %v", err)
+	}
+	line := strings.Repeat("x", 1024) + "\n"
+	for i := 0; i < 1024; i++ {
+		if _, err := fmt.Fprint(p, line); err != nil {
+			t.Fatalf("Write() after the pager quit failed: %v", err)
+		}
+	}
+	_ = p.Close()
+}
@@ -0,0 +1,114 @@
+// Package owners assigns Secret keys to the teams that own them, in the
+// spirit of CODEOWNERS, and checks edits against that assignment
+package owners
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// EnvUser names the environment variable that overrides the detected user
+const EnvUser = "SWK_USER"
+
+// Rule assigns the keys matching a glob pattern, e.g. stripe-*, to a team
+type Rule struct {
+	Keys string `yaml:"keys"`
+	Team string `yaml:"team"`
+}
+
+// Rules maps keys to owning teams. As in CODEOWNERS, the last matching rule
+// wins, so general rules go first.
+type Rules struct {
+	// Teams maps team names to members, identified by git email or cluster
+	// user name
+	Teams  map[string][]string
+	Owners []Rule
+}
+
+// Violation is an edit to a key owned by a team the user isn't in
+type Violation struct {
+	Key  string
+	Team string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("key %q is owned by team %s", v.Key, v.Team)
+}
+
+// Validate checks that every rule has a valid pattern and a known team
+func (r Rules) Validate() error {
+	for _, rule := range r.Owners {
+		if _, err := path.Match(rule.Keys, ""); err != nil || rule.Keys == "" {
+			return fmt.Errorf("invalid owners pattern %q", rule.Keys)
+		}
+		if _, ok := r.Teams[rule.Team]; !ok {
+			return fmt.Errorf("owners rule %q names unknown team %q", rule.Keys, rule.Team)
+		}
+	}
+	return nil
+}
+
+// Owner returns the team owning key, or "" if no rule matches
+func (r Rules) Owner(key string) string {
+	team := ""
+	for _, rule := range r.Owners {
+		if ok, _ := path.Match(rule.Keys, key); ok {
+			team = rule.Team
+		}
+	}
+	return team
+}
+
+// Check returns the keys that user may not edit, in the order given.
+// Unowned keys are open to everyone.
+func (r Rules) Check(user string, keys []string) []Violation {
+	var violations []Violation
+	for _, key := range keys {
+		team := r.Owner(key)
+		if team != "" && !r.member(team, user) {
+			violations = append(violations, Violation{Key: key, Team: team})
+		}
+	}
+	return violations
+}
+
+func (r Rules) member(team, user string) bool {
+	for _, member := range r.Teams[team] {
+		if strings.EqualFold(member, user) {
+			return true
+		}
+	}
+	return false
+}
+
+// output runs a command and returns its trimmed output, replaceable in tests
+var output = func(ctx context.Context, name string, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// CurrentUser identifies the invoking user by $SWK_USER, their git email, or
+// their cluster user name, in that order
+func CurrentUser(ctx context.Context) (string, error) {
+	if user := os.Getenv(EnvUser); user != "" {
+		return user, nil
+	}
+	if user, err := output(ctx, "git", "config", "user.email"); err == nil && user != "" {
+		return user, nil
+	}
+	user, err := output(ctx, "kubectl", "auth", "whoami", "-o", "jsonpath={.status.userInfo.username}")
+	if err == nil && user != "" {
+		return user, nil
+	}
+	return "", fmt.Errorf("can't tell who you are: set %s, git config user.email, or a kubectl context", EnvUser)
+}
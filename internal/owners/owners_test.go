@@ -0,0 +1,93 @@
+package owners
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testRules = Rules{
+	Teams: map[string][]string{
+		"platform": {"alice@example.com"},
+		"payments": {"bob@example.com", "Alice@Example.com"},
+	},
+	Owners: []Rule{
+		{Keys: "*", Team: "platform"},
+		{Keys: "stripe-*", Team: "payments"},
+	},
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		user string
+		keys []string
+		want string
+	}{
+		{"member of both", "alice@example.com", []string{"db-password", "stripe-key"}, ""},
+		{"last rule wins", "bob@example.com", []string{"stripe-key"}, ""},
+		{"other team", "bob@example.com", []string{"db-password", "stripe-key"}, "db-password:platform"},
+		{"unknown user", "eve@example.com", []string{"stripe-key"}, "stripe-key:payments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range testRules.Check(tt.user, tt.keys) {
+				got = append(got, v.Key+":"+v.Team)
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("Check() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := testRules.Validate(); err != nil {
+		t.Errorf("Validate() failed: %v", err)
+	}
+	for _, rule := range []Rule{{Keys: "[", Team: "platform"}, {Keys: "a", Team: "nobody"}} {
+		rules := Rules{Teams: testRules.Teams, Owners: []Rule{rule}}
+		if err := rules.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want an error", rule)
+		}
+	}
+}
+
+func TestCurrentUser(t *testing.T) {
+	defer func(orig func(context.Context, string, ...string) (string, error)) { output = orig }(output)
+
+	answers := map[string]string{}
+	output = func(_ context.Context, name string, _ ...string) (string, error) {
+		if user, ok := answers[name]; ok {
+			return user, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	t.Setenv(EnvUser, "")
+	if _, err := CurrentUser(context.Background()); err == nil {
+		t.Error("CurrentUser() succeeded without any identity")
+	}
+
+	answers["kubectl"] = "system:admin"
+	answers["git"] = "alice@example.com"
+	for _, tt := range []struct{ env, want string }{
+		{"", "alice@example.com"},
+		{"ci-bot", "ci-bot"},
+	} {
+		t.Setenv(EnvUser, tt.env)
+		got, err := CurrentUser(context.Background())
+		if err != nil || got != tt.want {
+			t.Errorf("CurrentUser() = %q, %v, want %q", got, err, tt.want)
+		}
+	}
+
+	delete(answers, "git")
+	t.Setenv(EnvUser, "")
+	if got, _ := CurrentUser(context.Background()); got != "system:admin" {
+		t.Errorf("CurrentUser() = %q, want the cluster user", got)
+	}
+}
@@ -0,0 +1,323 @@
+// Package patch applies JSON merge patches (RFC 7386) and JSON patches
+// (RFC 6902) to YAML documents without losing key order
+package patch
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Patch types, named as in `kubectl patch --type`
+const (
+	TypeMerge     = "merge"
+	TypeStrategic = "strategic"
+	TypeJSON      = "json"
+)
+
+// Apply patches a YAML document. Strategic merge patches are applied as
+// merge patches, which is equivalent for resources without merge-keyed
+// lists such as Secrets.
+func Apply(document, patch []byte, patchType string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(document, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("invalid YAML document")
+	}
+
+	switch patchType {
+	case TypeMerge, TypeStrategic, "":
+		var p yaml.Node
+		if err := yaml.Unmarshal(patch, &p); err != nil {
+			return nil, fmt.Errorf("failed to parse patch: %w", err)
+		}
+		if p.Kind != yaml.DocumentNode || len(p.Content) == 0 {
+			return nil, fmt.Errorf("empty patch")
+		}
+		doc.Content[0] = Merge(doc.Content[0], restyle(p.Content[0]))
+	case TypeJSON:
+		var ops []Operation
+		if err := yaml.Unmarshal(patch, &ops); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON patch: %w", err)
+		}
+		root, err := ApplyOperations(doc.Content[0], ops)
+		if err != nil {
+			return nil, err
+		}
+		doc.Content[0] = root
+	default:
+		return nil, fmt.Errorf("unsupported patch type %q (supported: merge, strategic, json)", patchType)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Merge applies an RFC 7386 merge patch: mappings are merged recursively,
+// null deletes a key, and anything else replaces the target value, keeping
+// its comments unless the patch brings its own
+func Merge(target, patch *yaml.Node) *yaml.Node {
+	if patch.Kind != yaml.MappingNode {
+		if target != nil && patch.HeadComment == "" && patch.LineComment == "" && patch.FootComment == "" {
+			patch.HeadComment, patch.LineComment, patch.FootComment = target.HeadComment, target.LineComment, target.FootComment
+		}
+		return patch
+	}
+	if target == nil || target.Kind != yaml.MappingNode {
+		target = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+
+	for i := 0; i+1 < len(patch.Content); i += 2 {
+		key, value := patch.Content[i], patch.Content[i+1]
+		idx := indexOf(target, key.Value)
+
+		if isNull(value) {
+			if idx >= 0 {
+				target.Content = append(target.Content[:idx], target.Content[idx+2:]...)
+			}
+			continue
+		}
+
+		if idx >= 0 {
+			target.Content[idx+1] = Merge(target.Content[idx+1], value)
+		} else {
+			target.Content = append(target.Content, key, Merge(nil, value))
+		}
+	}
+
+	return target
+}
+
+// Operation is a single RFC 6902 JSON patch operation
+type Operation struct {
+	Op    string    `yaml:"op"`
+	Path  string    `yaml:"path"`
+	From  string    `yaml:"from"`
+	Value yaml.Node `yaml:"value"`
+}
+
+// ApplyOperations applies JSON patch operations in order, failing on the
+// first one that cannot be applied
+func ApplyOperations(root *yaml.Node, ops []Operation) (*yaml.Node, error) {
+	for i, op := range ops {
+		var err error
+		if op.Value.Kind == 0 && (op.Op == "add" || op.Op == "replace" || op.Op == "test") {
+			return nil, fmt.Errorf("operation %d (%s %s): missing value", i, op.Op, op.Path)
+		}
+
+		switch op.Op {
+		case "add":
+			root, err = add(root, op.Path, restyle(copyNode(&op.Value)))
+		case "remove":
+			root, _, err = remove(root, op.Path)
+		case "replace":
+			if root, _, err = remove(root, op.Path); err == nil {
+				root, err = add(root, op.Path, restyle(copyNode(&op.Value)))
+			}
+		case "move":
+			var moved *yaml.Node
+			if root, moved, err = remove(root, op.From); err == nil {
+				root, err = add(root, op.Path, moved)
+			}
+		case "copy":
+			var src *yaml.Node
+			if src, err = get(root, op.From); err == nil {
+				root, err = add(root, op.Path, copyNode(src))
+			}
+		case "test":
+			var got *yaml.Node
+			if got, err = get(root, op.Path); err == nil && !equal(got, &op.Value) {
+				err = fmt.Errorf("value at %q does not match", op.Path)
+			}
+		default:
+			err = fmt.Errorf("unknown op %q", op.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return root, nil
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, tok := range tokens {
+		tok = strings.ReplaceAll(tok, "~1", "/")
+		tokens[i] = strings.ReplaceAll(tok, "~0", "~")
+	}
+	return tokens, nil
+}
+
+// parent resolves all but the last token of a pointer
+func parent(root *yaml.Node, pointer string) (*yaml.Node, string, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(tokens) == 0 {
+		return nil, "", nil
+	}
+
+	node := root
+	for _, tok := range tokens[:len(tokens)-1] {
+		if node, err = child(node, tok); err != nil {
+			return nil, "", err
+		}
+	}
+	return node, tokens[len(tokens)-1], nil
+}
+
+func get(root *yaml.Node, pointer string) (*yaml.Node, error) {
+	p, last, err := parent(root, pointer)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return root, nil
+	}
+	return child(p, last)
+}
+
+func child(node *yaml.Node, tok string) (*yaml.Node, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		if idx := indexOf(node, tok); idx >= 0 {
+			return node.Content[idx+1], nil
+		}
+		return nil, fmt.Errorf("key %q not found", tok)
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(tok)
+		if err != nil || i < 0 || i >= len(node.Content) {
+			return nil, fmt.Errorf("invalid index %q", tok)
+		}
+		return node.Content[i], nil
+	}
+	return nil, fmt.Errorf("cannot descend into scalar at %q", tok)
+}
+
+func add(root *yaml.Node, pointer string, value *yaml.Node) (*yaml.Node, error) {
+	p, last, err := parent(root, pointer)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return value, nil
+	}
+
+	switch p.Kind {
+	case yaml.MappingNode:
+		if idx := indexOf(p, last); idx >= 0 {
+			p.Content[idx+1] = value
+		} else {
+			p.Content = append(p.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: last}, value)
+		}
+	case yaml.SequenceNode:
+		i := len(p.Content)
+		if last != "-" {
+			if i, err = strconv.Atoi(last); err != nil || i < 0 || i > len(p.Content) {
+				return nil, fmt.Errorf("invalid index %q", last)
+			}
+		}
+		p.Content = append(p.Content[:i], append([]*yaml.Node{value}, p.Content[i:]...)...)
+	default:
+		return nil, fmt.Errorf("cannot add to scalar")
+	}
+	return root, nil
+}
+
+func remove(root *yaml.Node, pointer string) (*yaml.Node, *yaml.Node, error) {
+	p, last, err := parent(root, pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p == nil {
+		return nil, nil, fmt.Errorf("cannot remove the document root")
+	}
+
+	switch p.Kind {
+	case yaml.MappingNode:
+		idx := indexOf(p, last)
+		if idx < 0 {
+			return nil, nil, fmt.Errorf("key %q not found", last)
+		}
+		removed := p.Content[idx+1]
+		p.Content = append(p.Content[:idx], p.Content[idx+2:]...)
+		return root, removed, nil
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i >= len(p.Content) {
+			return nil, nil, fmt.Errorf("invalid index %q", last)
+		}
+		removed := p.Content[i]
+		p.Content = append(p.Content[:i], p.Content[i+1:]...)
+		return root, removed, nil
+	}
+	return nil, nil, fmt.Errorf("cannot remove from scalar")
+}
+
+// indexOf returns the index of key in a mapping's content, or -1
+func indexOf(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+func copyNode(node *yaml.Node) *yaml.Node {
+	c := *node
+	c.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		c.Content[i] = copyNode(child)
+	}
+	return &c
+}
+
+// restyle clears the quoting style patch values carry over from JSON, so
+// they are written in the same plain style as the rest of the document
+func restyle(node *yaml.Node) *yaml.Node {
+	node.Style = 0
+	for _, child := range node.Content {
+		restyle(child)
+	}
+	return node
+}
+
+// equal compares two nodes by value, ignoring style and position
+func equal(a, b *yaml.Node) bool {
+	var va, vb any
+	if err := a.Decode(&va); err != nil {
+		return false
+	}
+	if err := b.Decode(&vb); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,208 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+)
+
+const secret = `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-secret
+  labels:
+    app: web
+data:
+  username: admin
+  password: old
+`
+
+func TestApplyMerge(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "replace and add keys",
+			patch: `{"data":{"password":"new","token":"abc"}}`,
+			want: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-secret
+  labels:
+    app: web
+data:
+  username: admin
+  password: new
+  token: abc
+`,
+		},
+		{
+			name:  "null deletes",
+			patch: `{"data":{"username":null},"metadata":{"labels":null}}`,
+			want: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-secret
+data:
+  password: old
+`,
+		},
+		{
+			name:  "new nested mapping drops nulls",
+			patch: `{"stringData":{"a":"1","b":null}}`,
+			want: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-secret
+  labels:
+    app: web
+data:
+  username: admin
+  password: old
+stringData:
+  a: "1"
+`,
+		},
+		{
+			name:    "invalid patch",
+			patch:   `{"data": [`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(secret), []byte(tt.patch), TypeMerge)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && string(got) != tt.want {
+				t.Errorf("Apply() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		contains []string
+		absent   []string
+		wantErr  string
+	}{
+		{
+			name:     "add and replace",
+			patch:    `[{"op":"add","path":"/data/token","value":"abc"},{"op":"replace","path":"/data/password","value":"new"}]`,
+			contains: []string{"token: abc", "password: new"},
+			absent:   []string{"password: old"},
+		},
+		{
+			name:     "remove",
+			patch:    `[{"op":"remove","path":"/metadata/labels/app"}]`,
+			contains: []string{"labels: {}"},
+		},
+		{
+			name:     "move and copy",
+			patch:    `[{"op":"move","from":"/data/username","path":"/data/user"},{"op":"copy","from":"/data/user","path":"/metadata/labels/user"}]`,
+			contains: []string{"user: admin"},
+			absent:   []string{"username"},
+		},
+		{
+			name:     "escaped pointer",
+			patch:    `[{"op":"add","path":"/metadata/labels/example.com~1team","value":"ops"}]`,
+			contains: []string{"example.com/team: ops"},
+		},
+		{
+			name:     "test passes",
+			patch:    `[{"op":"test","path":"/data/username","value":"admin"}]`,
+			contains: []string{"username: admin"},
+		},
+		{
+			name:    "test fails",
+			patch:   `[{"op":"test","path":"/data/username","value":"root"}]`,
+			wantErr: "does not match",
+		},
+		{
+			name:    "remove missing",
+			patch:   `[{"op":"remove","path":"/data/nope"}]`,
+			wantErr: "not found",
+		},
+		{
+			name:    "unknown op",
+			patch:   `[{"op":"frobnicate","path":"/data"}]`,
+			wantErr: "unknown op",
+		},
+		{
+			name:    "missing value",
+			patch:   `[{"op":"add","path":"/data/x"}]`,
+			wantErr: "missing value",
+		},
+		{
+			name:    "bad pointer",
+			patch:   `[{"op":"add","path":"data/x","value":"1"}]`,
+			wantErr: "invalid JSON pointer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(secret), []byte(tt.patch), TypeJSON)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Apply() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() failed: %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("Apply() result missing %q:\n%s", want, got)
+				}
+			}
+			for _, unwanted := range tt.absent {
+				if strings.Contains(string(got), unwanted) {
+					t.Errorf("Apply() result should not contain %q:\n%s", unwanted, got)
+				}
+			}
+		})
+	}
+}
+
+func TestApplySequences(t *testing.T) {
+	doc := "items:\n- a\n- c\n"
+	got, err := Apply([]byte(doc), []byte(`[{"op":"add","path":"/items/1","value":"b"},{"op":"add","path":"/items/-","value":"d"},{"op":"remove","path":"/items/0"}]`), TypeJSON)
+	if err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	if want := "items:\n  - b\n  - c\n  - d\n"; string(got) != want {
+		t.Errorf("Apply() = %q, want %q", got, want)
+	}
+}
+
+func TestApplyMergeKeepsComments(t *testing.T) {
+	doc := "data:\n  # rotated monthly\n  password: old # from vault\n"
+	got, err := Apply([]byte(doc), []byte(`{"data":{"password":"new"}}`), TypeMerge)
+	if err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	if want := "data:\n  # rotated monthly\n  password: new # from vault\n"; string(got) != want {
+		t.Errorf("Apply() = %q, want %q", got, want)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	if _, err := Apply([]byte(secret), []byte(`{}`), "yolo"); err == nil {
+		t.Error("Apply() should reject unknown patch types")
+	}
+	if _, err := Apply([]byte("invalid: yaml: [[["), []byte(`{}`), TypeMerge); err == nil {
+		t.Error("Apply() should reject invalid documents")
+	}
+	if _, err := Apply([]byte(secret), []byte(""), TypeMerge); err == nil {
+		t.Error("Apply() should reject empty patches")
+	}
+}
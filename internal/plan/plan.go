@@ -0,0 +1,181 @@
+// Package plan reads plan files: reviewed, repeatable runbooks of changes
+// to Secret manifests, run with swk run-plan
+package plan
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of steps
+const (
+	Set    = "set"
+	Rotate = "rotate"
+	Apply  = "apply"
+	Notify = "notify"
+)
+
+// Plan is a description and the steps to run, in order
+type Plan struct {
+	Description string `yaml:"description"`
+	Steps       []Step `yaml:"steps"`
+}
+
+// Step is one operation. Exactly one of Set, Rotate, Apply, and Notify is
+// set, to the manifest to change or apply, or the webhook to notify.
+type Step struct {
+	Set    string `yaml:"set"`
+	Rotate string `yaml:"rotate"`
+	Apply  string `yaml:"apply"`
+	Notify string `yaml:"notify"`
+
+	// Key is the key to set, to Value or a value from Generate
+	Key   string  `yaml:"key"`
+	Value *string `yaml:"value"`
+	// Generate is a generator spec, e.g. passphrase or hmac:bytes=64, for
+	// set or for rotating a single key
+	Generate string `yaml:"generate"`
+	// Keys are the keys to rotate; all keys with a recorded generator if
+	// empty
+	Keys []string `yaml:"keys"`
+	// Message is sent with a notification
+	Message string `yaml:"message"`
+}
+
+// Kind returns the kind of the step, e.g. Set
+func (s Step) Kind() string {
+	switch {
+	case s.Set != "":
+		return Set
+	case s.Rotate != "":
+		return Rotate
+	case s.Apply != "":
+		return Apply
+	case s.Notify != "":
+		return Notify
+	}
+	return ""
+}
+
+// Target returns the manifest a step changes or applies, or the webhook it
+// notifies
+func (s Step) Target() string {
+	return s.Set + s.Rotate + s.Apply + s.Notify
+}
+
+// String describes the step without its value, e.g. "set db.yaml password"
+func (s Step) String() string {
+	parts := []string{s.Kind(), s.Target()}
+	switch s.Kind() {
+	case Set:
+		parts = append(parts, s.Key)
+	case Rotate:
+		parts = append(parts, s.Keys...)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Load reads a plan file. Manifest paths in its steps are relative to the
+// directory of the plan.
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	for i := range p.Steps {
+		s := &p.Steps[i]
+		for _, file := range []*string{&s.Set, &s.Rotate, &s.Apply} {
+			if *file != "" && !filepath.IsAbs(*file) {
+				*file = filepath.Join(dir, *file)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Parse parses and checks a plan. Unknown fields are errors, so that a
+// typo doesn't silently change what a reviewed plan does.
+func Parse(data []byte) (*Plan, error) {
+	var p Plan
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	if len(p.Steps) == 0 {
+		return nil, fmt.Errorf("plan has no steps")
+	}
+	for i, s := range p.Steps {
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return &p, nil
+}
+
+func (s Step) validate() error {
+	kinds := 0
+	for _, target := range []string{s.Set, s.Rotate, s.Apply, s.Notify} {
+		if target != "" {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("want exactly one of set, rotate, apply, and notify")
+	}
+
+	// Fields that the kind doesn't use are mistakes, not options
+	used := map[string]bool{
+		"key":      s.Key != "",
+		"value":    s.Value != nil,
+		"generate": s.Generate != "",
+		"keys":     len(s.Keys) > 0,
+		"message":  s.Message != "",
+	}
+	allowed := map[string][]string{
+		Set:    {"key", "value", "generate"},
+		Rotate: {"keys", "generate"},
+		Apply:  {},
+		Notify: {"message"},
+	}[s.Kind()]
+	for _, field := range []string{"key", "value", "generate", "keys", "message"} {
+		if used[field] && !slices.Contains(allowed, field) {
+			return fmt.Errorf("%s doesn't take %s", s.Kind(), field)
+		}
+	}
+
+	switch s.Kind() {
+	case Set:
+		if s.Key == "" {
+			return fmt.Errorf("set needs a key")
+		}
+		if (s.Value == nil) == (s.Generate == "") {
+			return fmt.Errorf("set needs either a value or generate")
+		}
+	case Rotate:
+		if s.Generate != "" && len(s.Keys) != 1 {
+			return fmt.Errorf("rotate takes generate only with a single key")
+		}
+	case Notify:
+		u, err := url.Parse(s.Notify)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("notify needs an http or https URL, got %q", s.Notify)
+		}
+	}
+	return nil
+}
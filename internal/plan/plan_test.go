@@ -0,0 +1,68 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"valid", "steps:\n- set: db.yaml\n  key: password\n  generate: passphrase\n- rotate: db.yaml\n- apply: db.yaml\n- notify: https://hooks.example.com/swk\n  message: done\n", ""},
+		{"empty value", "steps:\n- set: db.yaml\n  key: password\n  value: ''\n", ""},
+		{"no steps", "description: nothing\n", "no steps"},
+		{"unknown field", "steps:\n- set: db.yaml\n  key: password\n  valeu: x\n", "valeu"},
+		{"two kinds", "steps:\n- set: db.yaml\n  apply: db.yaml\n", "exactly one"},
+		{"no kind", "steps:\n- key: password\n", "exactly one"},
+		{"set without key", "steps:\n- set: db.yaml\n  value: x\n", "needs a key"},
+		{"set without value", "steps:\n- set: db.yaml\n  key: password\n", "either a value or generate"},
+		{"set with both", "steps:\n- set: db.yaml\n  key: password\n  value: x\n  generate: passphrase\n", "either a value or generate"},
+		{"rotate generate needs one key", "steps:\n- rotate: db.yaml\n  generate: passphrase\n", "single key"},
+		{"field of another kind", "steps:\n- apply: db.yaml\n  key: password\n", "apply doesn't take key"},
+		{"notify needs URL", "steps:\n- notify: hooks.example.com\n", "http or https URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Parse() failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plan.yaml")
+	input := "steps:\n- set: secrets/db.yaml\n  key: password\n  value: x\n- apply: /abs/db.yaml\n- notify: https://hooks.example.com/swk\n"
+	if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	want := []string{
+		"set " + filepath.Join(dir, "secrets/db.yaml") + " password",
+		"apply /abs/db.yaml",
+		"notify https://hooks.example.com/swk",
+	}
+	for i, s := range p.Steps {
+		if s.String() != want[i] {
+			t.Errorf("step %d = %q, want %q", i+1, s, want[i])
+		}
+	}
+}
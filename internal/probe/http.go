@@ -0,0 +1,43 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// httpClient is used by the http and s3 probes, replaceable in tests
+var httpClient = &http.Client{}
+
+// probeHTTP requests a URL with basic auth or a bearer token and fails on
+// 401 and 403 responses
+func probeHTTP(ctx context.Context, p Params) error {
+	if err := p.require("url"); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p["url"], nil)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	switch {
+	case p["token"] != "":
+		req.Header.Set("Authorization", "Bearer "+p["token"])
+	case p["user"] != "" || p["password"] != "":
+		req.SetBasicAuth(p["user"], p["password"])
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("authentication failed: %s", resp.Status)
+	}
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("server error: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+)
+
+// MySQL capability flags
+const (
+	myClientLongPassword     = 0x00000001
+	myClientConnectWithDB    = 0x00000008
+	myClientProtocol41       = 0x00000200
+	myClientSSL              = 0x00000800
+	myClientSecureConnection = 0x00008000
+	myClientPluginAuth       = 0x00080000
+)
+
+// mysqlConn frames MySQL packets and tracks their sequence numbers
+type mysqlConn struct {
+	conn net.Conn
+	seq  byte
+}
+
+func (c *mysqlConn) read() ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return nil, fmt.Errorf("failed to read from server: %w", err)
+	}
+	n := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	c.seq = header[3] + 1
+
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.conn, payload); err != nil {
+		return nil, fmt.Errorf("failed to read from server: %w", err)
+	}
+	return payload, nil
+}
+
+func (c *mysqlConn) write(payload []byte) error {
+	n := len(payload)
+	packet := append([]byte{byte(n), byte(n >> 8), byte(n >> 16), c.seq}, payload...)
+	c.seq++
+	if _, err := c.conn.Write(packet); err != nil {
+		return fmt.Errorf("failed to write to server: %w", err)
+	}
+	return nil
+}
+
+// probeMySQL logs in with the connection handshake, supporting the
+// mysql_native_password and caching_sha2_password plugins
+func probeMySQL(ctx context.Context, p Params) error {
+	if err := p.require("user", "password"); err != nil {
+		return err
+	}
+
+	conn, err := dial(ctx, p, "3306")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	c := &mysqlConn{conn: conn}
+	greeting, err := c.read()
+	if err != nil {
+		return err
+	}
+	if len(greeting) > 0 && greeting[0] == 0xff {
+		return fmt.Errorf("server refused connection: %s", mysqlError(greeting))
+	}
+	scramble, plugin, serverCaps, err := parseMySQLGreeting(greeting)
+	if err != nil {
+		return err
+	}
+
+	caps := uint32(myClientLongPassword | myClientProtocol41 | myClientSecureConnection | myClientPluginAuth)
+	if p["database"] != "" {
+		caps |= myClientConnectWithDB
+	}
+
+	// Use TLS when offered, so caching_sha2_password full authentication
+	// does not need an RSA key exchange
+	mode, err := p.sslMode()
+	if err != nil {
+		return err
+	}
+	secure := false
+	if serverCaps&myClientSSL != 0 && mode != "disable" {
+		config, err := p.tlsConfig(mode)
+		if err != nil {
+			return err
+		}
+		caps |= myClientSSL
+		if err := c.write(mysqlCapsHeader(caps)); err != nil {
+			return err
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			return fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		c.conn, secure = tlsConn, true
+	} else if mode == "require" || mode == "verify-full" {
+		return fmt.Errorf("server does not support TLS (sslmode=%s)", mode)
+	}
+
+	resp := mysqlCapsHeader(caps)
+	resp = append(append(resp, p["user"]...), 0)
+	authData := mysqlScramble(plugin, p["password"], scramble)
+	resp = append(append(resp, byte(len(authData))), authData...)
+	if p["database"] != "" {
+		resp = append(append(resp, p["database"]...), 0)
+	}
+	resp = append(append(resp, plugin...), 0)
+	if err := c.write(resp); err != nil {
+		return err
+	}
+
+	for {
+		packet, err := c.read()
+		if err != nil {
+			return err
+		}
+		if len(packet) == 0 {
+			return fmt.Errorf("empty packet from server")
+		}
+
+		switch packet[0] {
+		case 0x00:
+			c.seq = 0
+			c.write([]byte{0x01}) // COM_QUIT
+			return nil
+		case 0xff:
+			return fmt.Errorf("authentication failed: %s", mysqlError(packet))
+		case 0xfe:
+			// Auth switch: plugin name, then new scramble
+			name, data, _ := bytes.Cut(packet[1:], []byte{0})
+			plugin, scramble = string(name), bytes.TrimSuffix(data, []byte{0})
+			if err := c.write(mysqlScramble(plugin, p["password"], scramble)); err != nil {
+				return err
+			}
+		case 0x01:
+			if plugin != "caching_sha2_password" || len(packet) < 2 {
+				return fmt.Errorf("unexpected auth data from server")
+			}
+			switch packet[1] {
+			case 3: // fast auth succeeded, OK follows
+			case 4: // full authentication
+				if err := mysqlFullAuth(c, p["password"], scramble, secure); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unexpected caching_sha2_password state %d", packet[1])
+			}
+		default:
+			return fmt.Errorf("unexpected packet 0x%02x during authentication", packet[0])
+		}
+	}
+}
+
+// mysqlFullAuth sends the password in the clear over TLS, or encrypted with
+// the server's RSA public key otherwise
+func mysqlFullAuth(c *mysqlConn, password string, scramble []byte, secure bool) error {
+	plain := append([]byte(password), 0)
+	if secure {
+		return c.write(plain)
+	}
+
+	if err := c.write([]byte{0x02}); err != nil { // request public key
+		return err
+	}
+	packet, err := c.read()
+	if err != nil {
+		return err
+	}
+	if len(packet) < 2 || packet[0] != 0x01 {
+		return fmt.Errorf("server did not send its public key")
+	}
+	block, _ := pem.Decode(packet[1:])
+	if block == nil {
+		return fmt.Errorf("invalid server public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid server public key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("server public key is not RSA")
+	}
+
+	for i := range plain {
+		plain[i] ^= scramble[i%len(scramble)]
+	}
+	encrypted, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, rsaKey, plain, nil)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt password: %w", err)
+	}
+	return c.write(encrypted)
+}
+
+// mysqlCapsHeader builds the fixed prefix shared by SSLRequest and
+// HandshakeResponse41
+func mysqlCapsHeader(caps uint32) []byte {
+	header := binary.LittleEndian.AppendUint32(nil, caps)
+	header = binary.LittleEndian.AppendUint32(header, 1<<24) // max packet size
+	header = append(header, 45)                              // utf8mb4_general_ci
+	return append(header, make([]byte, 23)...)
+}
+
+// parseMySQLGreeting reads the scramble, auth plugin, and capabilities from
+// a protocol 10 handshake
+func parseMySQLGreeting(b []byte) ([]byte, string, uint32, error) {
+	if len(b) < 1 || b[0] != 10 {
+		return nil, "", 0, fmt.Errorf("unsupported MySQL protocol")
+	}
+	end := bytes.IndexByte(b[1:], 0)
+	if end < 0 {
+		return nil, "", 0, fmt.Errorf("malformed handshake")
+	}
+	pos := 1 + end + 1 + 4 // server version, connection id
+	if len(b) < pos+8+1+2+1+2+2+1+10 {
+		return nil, "", 0, fmt.Errorf("malformed handshake")
+	}
+
+	scramble := append([]byte{}, b[pos:pos+8]...)
+	pos += 8 + 1
+	caps := uint32(binary.LittleEndian.Uint16(b[pos:]))
+	pos += 2 + 1 + 2
+	caps |= uint32(binary.LittleEndian.Uint16(b[pos:])) << 16
+	pos += 2
+	authLen := int(b[pos])
+	pos += 1 + 10
+
+	n := max(13, authLen-8)
+	if len(b) < pos+n {
+		return nil, "", 0, fmt.Errorf("malformed handshake")
+	}
+	scramble = append(scramble, bytes.TrimSuffix(b[pos:pos+n], []byte{0})...)
+	pos += n
+
+	plugin := "mysql_native_password"
+	if pos < len(b) {
+		if name, _, _ := bytes.Cut(b[pos:], []byte{0}); len(name) > 0 {
+			plugin = string(name)
+		}
+	}
+	return scramble, plugin, caps, nil
+}
+
+// mysqlScramble computes the auth response for a plugin
+func mysqlScramble(plugin, password string, scramble []byte) []byte {
+	if password == "" {
+		return nil
+	}
+
+	switch plugin {
+	case "caching_sha2_password":
+		// XOR(SHA256(pw), SHA256(SHA256(SHA256(pw)), scramble))
+		h1 := sha256.Sum256([]byte(password))
+		h2 := sha256.Sum256(h1[:])
+		h3 := sha256.Sum256(append(h2[:], scramble...))
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:]
+	case "mysql_clear_password":
+		return append([]byte(password), 0)
+	default:
+		// mysql_native_password: XOR(SHA1(pw), SHA1(scramble, SHA1(SHA1(pw))))
+		h1 := sha1.Sum([]byte(password))
+		h2 := sha1.Sum(h1[:])
+		h3 := sha1.Sum(append(append([]byte{}, scramble...), h2[:]...))
+		for i := range h1 {
+			h1[i] ^= h3[i]
+		}
+		return h1[:]
+	}
+}
+
+// mysqlError extracts the message of an ERR packet
+func mysqlError(packet []byte) string {
+	if len(packet) < 3 {
+		return "unknown error"
+	}
+	msg := packet[3:]
+	if len(msg) > 6 && msg[0] == '#' {
+		msg = msg[6:]
+	}
+	return string(msg)
+}
@@ -0,0 +1,284 @@
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Postgres protocol constants
+const (
+	pgProtocolVersion = 196608 // 3.0
+	pgSSLRequest      = 80877103
+
+	pgAuthOK           = 0
+	pgAuthCleartext    = 3
+	pgAuthMD5          = 5
+	pgAuthSASL         = 10
+	pgAuthSASLContinue = 11
+	pgAuthSASLFinal    = 12
+)
+
+// probePostgres logs in with the startup handshake, supporting cleartext,
+// MD5, and SCRAM-SHA-256 authentication
+func probePostgres(ctx context.Context, p Params) error {
+	if err := p.require("user", "password"); err != nil {
+		return err
+	}
+
+	conn, err := dial(ctx, p, "5432")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if conn, err = pgNegotiateTLS(conn, p); err != nil {
+		return err
+	}
+
+	startup := []byte{0, 0, 0, 0}
+	startup = binary.BigEndian.AppendUint32(startup, pgProtocolVersion)
+	for _, kv := range [][2]string{{"user", p["user"]}, {"database", p.get("database", p["user"])}} {
+		startup = append(append(append(startup, kv[0]...), 0), append([]byte(kv[1]), 0)...)
+	}
+	startup = append(startup, 0)
+	binary.BigEndian.PutUint32(startup, uint32(len(startup)))
+	if _, err := conn.Write(startup); err != nil {
+		return fmt.Errorf("failed to send startup message: %w", err)
+	}
+
+	r := bufio.NewReader(conn)
+	var scram *scramClient
+	for {
+		typ, body, err := pgRead(r)
+		if err != nil {
+			return err
+		}
+
+		switch typ {
+		case 'E':
+			return fmt.Errorf("authentication failed: %s", pgError(body))
+		case 'R':
+		default:
+			return fmt.Errorf("unexpected message %q during authentication", typ)
+		}
+
+		if len(body) < 4 {
+			return fmt.Errorf("malformed authentication message")
+		}
+		code, data := binary.BigEndian.Uint32(body), body[4:]
+
+		switch code {
+		case pgAuthOK:
+			conn.Write([]byte{'X', 0, 0, 0, 4})
+			return nil
+		case pgAuthCleartext:
+			err = pgWrite(conn, 'p', append([]byte(p["password"]), 0))
+		case pgAuthMD5:
+			if len(data) < 4 {
+				return fmt.Errorf("malformed MD5 salt")
+			}
+			inner := md5.Sum([]byte(p["password"] + p["user"]))
+			outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), data[:4]...))
+			err = pgWrite(conn, 'p', append([]byte("md5"+hex.EncodeToString(outer[:])), 0))
+		case pgAuthSASL:
+			if !bytes.Contains(data, []byte("SCRAM-SHA-256\x00")) {
+				return fmt.Errorf("unsupported SASL mechanisms %q", data)
+			}
+			if scram, err = newSCRAMClient(p["password"]); err != nil {
+				return err
+			}
+			first := scram.clientFirst()
+			msg := append([]byte("SCRAM-SHA-256\x00"), binary.BigEndian.AppendUint32(nil, uint32(len(first)))...)
+			err = pgWrite(conn, 'p', append(msg, first...))
+		case pgAuthSASLContinue:
+			if scram == nil {
+				return fmt.Errorf("unexpected SASL continue message")
+			}
+			var final string
+			if final, err = scram.clientFinal(string(data)); err != nil {
+				return err
+			}
+			err = pgWrite(conn, 'p', []byte(final))
+		case pgAuthSASLFinal:
+			if scram == nil {
+				return fmt.Errorf("unexpected SASL final message")
+			}
+			err = scram.verifyServer(string(data))
+		default:
+			return fmt.Errorf("unsupported authentication method %d", code)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// pgNegotiateTLS upgrades the connection according to the sslmode parameter
+// (disable, prefer, require, or verify-full)
+func pgNegotiateTLS(conn net.Conn, p Params) (net.Conn, error) {
+	mode, err := p.sslMode()
+	if err != nil || mode == "disable" {
+		return conn, err
+	}
+	config, err := p.tlsConfig(mode)
+	if err != nil {
+		return nil, err
+	}
+
+	req := binary.BigEndian.AppendUint32([]byte{0, 0, 0, 8}, pgSSLRequest)
+	if _, err := conn.Write(req); err != nil {
+		return nil, fmt.Errorf("failed to request TLS: %w", err)
+	}
+	answer := make([]byte, 1)
+	if _, err := io.ReadFull(conn, answer); err != nil {
+		return nil, fmt.Errorf("failed to request TLS: %w", err)
+	}
+
+	if answer[0] != 'S' {
+		if mode == "prefer" {
+			return conn, nil
+		}
+		return nil, fmt.Errorf("server does not support TLS (sslmode=%s)", mode)
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// pgRead reads one backend message
+func pgRead(r *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, fmt.Errorf("failed to read from server: %w", err)
+	}
+	n := binary.BigEndian.Uint32(header[1:])
+	if n < 4 || n > 1<<20 {
+		return 0, nil, fmt.Errorf("invalid message length %d", n)
+	}
+	body := make([]byte, n-4)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, fmt.Errorf("failed to read from server: %w", err)
+	}
+	return header[0], body, nil
+}
+
+// pgWrite sends one frontend message
+func pgWrite(w io.Writer, typ byte, body []byte) error {
+	msg := append([]byte{typ}, binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))...)
+	if _, err := w.Write(append(msg, body...)); err != nil {
+		return fmt.Errorf("failed to write to server: %w", err)
+	}
+	return nil
+}
+
+// pgError extracts the message field of an ErrorResponse
+func pgError(body []byte) string {
+	for _, field := range bytes.Split(body, []byte{0}) {
+		if len(field) > 1 && field[0] == 'M' {
+			return string(field[1:])
+		}
+	}
+	return "unknown error"
+}
+
+// scramClient implements the client side of SCRAM-SHA-256 (RFC 7677)
+type scramClient struct {
+	password        string
+	nonce           string
+	clientFirstBare string
+	serverSignature []byte
+}
+
+func newSCRAMClient(password string) (*scramClient, error) {
+	nonce := make([]byte, 18)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &scramClient{password: password, nonce: base64.StdEncoding.EncodeToString(nonce)}, nil
+}
+
+func (s *scramClient) clientFirst() string {
+	// Postgres ignores the SCRAM username in favour of the startup user
+	s.clientFirstBare = "n=,r=" + s.nonce
+	return "n,," + s.clientFirstBare
+}
+
+func (s *scramClient) clientFinal(serverFirst string) (string, error) {
+	attrs := scramAttributes(serverFirst)
+	salt, err := base64.StdEncoding.DecodeString(attrs["s"])
+	if err != nil {
+		return "", fmt.Errorf("invalid SCRAM salt: %w", err)
+	}
+	iterations, err := strconv.Atoi(attrs["i"])
+	if err != nil || iterations <= 0 {
+		return "", fmt.Errorf("invalid SCRAM iteration count %q", attrs["i"])
+	}
+	if !strings.HasPrefix(attrs["r"], s.nonce) {
+		return "", fmt.Errorf("SCRAM server nonce does not extend client nonce")
+	}
+
+	salted, err := pbkdf2.Key(sha256.New, s.password, salt, iterations, sha256.Size)
+	if err != nil {
+		return "", err
+	}
+	clientKey := hmacSHA256(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+
+	withoutProof := "c=biws,r=" + attrs["r"]
+	authMessage := s.clientFirstBare + "," + serverFirst + "," + withoutProof
+
+	proof := hmacSHA256(storedKey[:], authMessage)
+	for i := range proof {
+		proof[i] ^= clientKey[i]
+	}
+	s.serverSignature = hmacSHA256(hmacSHA256(salted, "Server Key"), authMessage)
+
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+func (s *scramClient) verifyServer(serverFinal string) error {
+	attrs := scramAttributes(serverFinal)
+	if e := attrs["e"]; e != "" {
+		return fmt.Errorf("authentication failed: %s", e)
+	}
+	got, err := base64.StdEncoding.DecodeString(attrs["v"])
+	if err != nil || !hmac.Equal(got, s.serverSignature) {
+		return fmt.Errorf("server signature mismatch")
+	}
+	return nil
+}
+
+// scramAttributes parses "k=v,k=v" SCRAM messages
+func scramAttributes(msg string) map[string]string {
+	attrs := make(map[string]string)
+	for _, part := range strings.Split(msg, ",") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			attrs[k] = v
+		}
+	}
+	return attrs
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
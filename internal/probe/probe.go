@@ -0,0 +1,142 @@
+// Package probe checks credentials by authenticating against the services
+// they belong to
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Params holds connection parameters such as host, user, and password
+type Params map[string]string
+
+// Prober authenticates against a service and returns nil if the
+// credentials are accepted
+type Prober func(ctx context.Context, p Params) error
+
+// probes are the available probers, by name
+var probes = map[string]Prober{
+	"http":     probeHTTP,
+	"mysql":    probeMySQL,
+	"postgres": probePostgres,
+	"redis":    probeRedis,
+	"s3":       probeS3,
+}
+
+// parameters lists the parameters each probe reads, for usage output and
+// for picking values out of a Secret
+var parameters = map[string][]string{
+	"http":     {"url", "user", "password", "token"},
+	"mysql":    {"host", "port", "user", "password", "database", "sslmode", "sslrootcert"},
+	"postgres": {"host", "port", "user", "password", "database", "sslmode", "sslrootcert"},
+	"redis":    {"host", "port", "user", "password", "sslmode", "sslrootcert"},
+	"s3":       {"endpoint", "region", "bucket", "access-key", "secret-key", "session-token"},
+}
+
+// Names returns the available probe names, sorted
+func Names() []string {
+	names := make([]string, 0, len(probes))
+	for name := range probes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parameters returns the parameters a probe reads
+func Parameters(name string) []string {
+	return parameters[name]
+}
+
+// Run authenticates with the named probe
+func Run(ctx context.Context, name string, p Params) error {
+	probe, ok := probes[name]
+	if !ok {
+		return fmt.Errorf("unknown probe %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return probe(ctx, p)
+}
+
+// get returns a parameter or its default
+func (p Params) get(key, def string) string {
+	if v := p[key]; v != "" {
+		return v
+	}
+	return def
+}
+
+// require returns a parameter, failing if it is not set
+func (p Params) require(keys ...string) error {
+	var missing []string
+	for _, key := range keys {
+		if p[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing parameter(s): %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// DefaultSSLMode is the sslmode of the database and Redis probes when none
+// is given: TLS with a verified certificate, so a password is never sent to
+// a server that merely claims to be the right one
+const DefaultSSLMode = "verify-full"
+
+// sslMode returns the sslmode parameter: disable, prefer (TLS when the
+// server offers it, without checking its certificate), or require and
+// verify-full (TLS with a verified certificate)
+func (p Params) sslMode() (string, error) {
+	switch mode := p.get("sslmode", DefaultSSLMode); mode {
+	case "disable", "prefer", "require", "verify-full":
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid sslmode %q (use disable, prefer, require, or verify-full)", mode)
+	}
+}
+
+// tlsConfig returns the TLS settings for mode. Only prefer skips checking
+// the certificate; sslrootcert names a PEM file of CAs to check it against
+// in place of the system ones.
+func (p Params) tlsConfig(mode string) (*tls.Config, error) {
+	config := &tls.Config{ServerName: p["host"], InsecureSkipVerify: mode == "prefer"}
+	if file := p["sslrootcert"]; file != "" && !config.InsecureSkipVerify {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sslrootcert: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in sslrootcert %s", file)
+		}
+	}
+	return config, nil
+}
+
+// dial opens a TCP connection to host:port, bounded by the context deadline
+func dial(ctx context.Context, p Params, defaultPort string) (net.Conn, error) {
+	if err := p.require("host"); err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(p["host"], p.get("port", defaultPort)))
+	if err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	conn.SetDeadline(deadline)
+	return conn, nil
+}
@@ -0,0 +1,425 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serve accepts a single connection on a local port and hands it to fn
+func serve(t *testing.T, fn func(conn net.Conn)) Params {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		fn(conn)
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return Params{"host": host, "port": port}
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestRunUnknown(t *testing.T) {
+	if err := Run(context.Background(), "ftp", Params{}); err == nil {
+		t.Error("expected an error for an unknown probe")
+	}
+}
+
+// redisAuth plays the server side of a Redis AUTH for user app with
+// password right
+func redisAuth(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	var args []string
+	line, _ := r.ReadString('\n') // *N
+	for range line[1] - '0' {
+		r.ReadString('\n') // $len
+		arg, _ := r.ReadString('\n')
+		args = append(args, strings.TrimSpace(arg))
+	}
+	if strings.Join(args, " ") == "AUTH app right" {
+		conn.Write([]byte("+OK\r\n"))
+	} else {
+		conn.Write([]byte("-WRONGPASS invalid username-password pair\r\n"))
+	}
+}
+
+func TestRedis(t *testing.T) {
+	for _, tt := range []struct {
+		password string
+		wantErr  bool
+	}{{"right", false}, {"wrong", true}} {
+		t.Run(tt.password, func(t *testing.T) {
+			p := serve(t, redisAuth)
+			p["user"], p["password"], p["sslmode"] = "app", tt.password, "disable"
+
+			if err := Run(testContext(t), "redis", p); (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedisSSLMode(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+	rootCert := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+	if err := os.WriteFile(rootCert, ca, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		params  Params
+		wantErr string
+	}{
+		{"default verifies the certificate", Params{}, "certificate"},
+		{"verify-full with a root", Params{"sslmode": "verify-full", "sslrootcert": rootCert}, ""},
+		{"require verifies the certificate", Params{"sslmode": "require"}, "certificate"},
+		{"prefer skips the check", Params{"sslmode": "prefer"}, ""},
+		{"unknown mode", Params{"sslmode": "allow"}, "invalid sslmode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := serve(t, func(conn net.Conn) {
+				tlsConn := tls.Server(conn, tlsServer.TLS)
+				if tlsConn.Handshake() != nil {
+					return
+				}
+				redisAuth(tlsConn)
+			})
+			p["user"], p["password"] = "app", "right"
+			for k, v := range tt.params {
+				p[k] = v
+			}
+
+			err := Run(testContext(t), "redis", p)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Run() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	if err := Run(testContext(t), "http", Params{"url": server.URL, "token": "good"}); err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+	if err := Run(testContext(t), "http", Params{"url": server.URL, "token": "bad"}); err == nil {
+		t.Error("expected an authentication error")
+	}
+	if err := Run(testContext(t), "http", Params{}); err == nil {
+		t.Error("expected an error for a missing url")
+	}
+}
+
+// pgServer plays the server side of a Postgres login for one user
+type pgServer struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (s *pgServer) send(typ byte, body []byte) {
+	s.conn.Write(append(append([]byte{typ}, binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))...), body...))
+}
+
+func (s *pgServer) receive() []byte {
+	header := make([]byte, 5)
+	io.ReadFull(s.r, header)
+	body := make([]byte, binary.BigEndian.Uint32(header[1:])-4)
+	io.ReadFull(s.r, body)
+	return body
+}
+
+func (s *pgServer) auth(code uint32, data []byte) {
+	s.send('R', append(binary.BigEndian.AppendUint32(nil, code), data...))
+}
+
+func (s *pgServer) fail() {
+	s.send('E', []byte("SFATAL\x00Mpassword authentication failed\x00\x00"))
+}
+
+func (s *pgServer) startup() {
+	// Decline TLS, then read the startup message
+	header := make([]byte, 8)
+	io.ReadFull(s.r, header)
+	s.conn.Write([]byte{'N'})
+	io.ReadFull(s.r, header[:4])
+	io.ReadFull(s.r, make([]byte, binary.BigEndian.Uint32(header[:4])-4))
+}
+
+func TestPostgresSSLMode(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+	rootCert := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+	if err := os.WriteFile(rootCert, ca, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		params  Params
+		offer   bool
+		wantErr string
+	}{
+		{"default verifies the certificate", Params{}, true, "certificate"},
+		{"default requires TLS", Params{}, false, "does not support TLS"},
+		{"verify-full with a root", Params{"sslmode": "verify-full", "sslrootcert": rootCert}, true, ""},
+		{"require verifies the certificate", Params{"sslmode": "require"}, true, "certificate"},
+		{"require with a root", Params{"sslmode": "require", "sslrootcert": rootCert}, true, ""},
+		{"prefer skips the check", Params{"sslmode": "prefer"}, true, ""},
+		{"prefer without TLS", Params{"sslmode": "prefer"}, false, ""},
+		{"disable", Params{"sslmode": "disable"}, false, ""},
+		{"unknown mode", Params{"sslmode": "allow"}, false, "invalid sslmode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := serve(t, func(conn net.Conn) {
+				r := bufio.NewReader(conn)
+				header := make([]byte, 8)
+				io.ReadFull(r, header)
+				if binary.BigEndian.Uint32(header[4:]) == pgSSLRequest {
+					if !tt.offer {
+						conn.Write([]byte{'N'})
+					} else {
+						conn.Write([]byte{'S'})
+						tlsConn := tls.Server(conn, tlsServer.TLS)
+						if tlsConn.Handshake() != nil {
+							return
+						}
+						conn, r = tlsConn, bufio.NewReader(tlsConn)
+					}
+					io.ReadFull(r, header[:4])
+					io.ReadFull(r, make([]byte, binary.BigEndian.Uint32(header[:4])-4))
+				} else {
+					io.ReadFull(r, make([]byte, binary.BigEndian.Uint32(header[:4])-8))
+				}
+				s := &pgServer{conn: conn, r: r}
+				s.auth(pgAuthCleartext, nil)
+				s.receive()
+				s.auth(pgAuthOK, nil)
+			})
+			p["user"], p["password"] = "app", "right"
+			for k, v := range tt.params {
+				p[k] = v
+			}
+
+			err := Run(testContext(t), "postgres", p)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Run() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPostgresMD5(t *testing.T) {
+	for _, tt := range []struct {
+		password string
+		wantErr  bool
+	}{{"right", false}, {"wrong", true}} {
+		t.Run(tt.password, func(t *testing.T) {
+			p := serve(t, func(conn net.Conn) {
+				s := &pgServer{conn: conn, r: bufio.NewReader(conn)}
+				s.startup()
+				salt := []byte{1, 2, 3, 4}
+				s.auth(pgAuthMD5, salt)
+
+				inner := md5.Sum([]byte("right" + "app"))
+				outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+				if string(s.receive()) == "md5"+hex.EncodeToString(outer[:])+"\x00" {
+					s.auth(pgAuthOK, nil)
+				} else {
+					s.fail()
+				}
+			})
+			p["user"], p["password"], p["sslmode"] = "app", tt.password, "prefer"
+
+			if err := Run(testContext(t), "postgres", p); (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPostgresSCRAM(t *testing.T) {
+	for _, tt := range []struct {
+		password string
+		wantErr  bool
+	}{{"right", false}, {"wrong", true}} {
+		t.Run(tt.password, func(t *testing.T) {
+			p := serve(t, func(conn net.Conn) {
+				s := &pgServer{conn: conn, r: bufio.NewReader(conn)}
+				s.startup()
+				s.auth(pgAuthSASL, []byte("SCRAM-SHA-256\x00\x00"))
+
+				initial := s.receive()
+				clientFirst := string(initial[len("SCRAM-SHA-256\x00")+4:])
+				clientFirstBare := strings.TrimPrefix(clientFirst, "n,,")
+				nonce := scramAttributes(clientFirstBare)["r"] + "server"
+				salt := []byte("saltsalt")
+				serverFirst := "r=" + nonce + ",s=" + base64.StdEncoding.EncodeToString(salt) + ",i=4096"
+				s.auth(pgAuthSASLContinue, []byte(serverFirst))
+
+				clientFinal := string(s.receive())
+				withoutProof, proof, _ := strings.Cut(clientFinal, ",p=")
+				authMessage := clientFirstBare + "," + serverFirst + "," + withoutProof
+
+				salted, _ := pbkdf2.Key(sha256.New, "right", salt, 4096, sha256.Size)
+				clientKey := hmacSHA256(salted, "Client Key")
+				storedKey := sha256.Sum256(clientKey)
+				want := hmacSHA256(storedKey[:], authMessage)
+				for i := range want {
+					want[i] ^= clientKey[i]
+				}
+				if proof != base64.StdEncoding.EncodeToString(want) {
+					s.fail()
+					return
+				}
+				signature := hmacSHA256(hmacSHA256(salted, "Server Key"), authMessage)
+				s.auth(pgAuthSASLFinal, []byte("v="+base64.StdEncoding.EncodeToString(signature)))
+				s.auth(pgAuthOK, nil)
+			})
+			p["user"], p["password"], p["sslmode"] = "app", tt.password, "prefer"
+
+			if err := Run(testContext(t), "postgres", p); (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMySQLNativePassword(t *testing.T) {
+	scramble := []byte("abcdefghijklmnopqrst")
+
+	for _, tt := range []struct {
+		password string
+		wantErr  bool
+	}{{"right", false}, {"wrong", true}} {
+		t.Run(tt.password, func(t *testing.T) {
+			p := serve(t, func(conn net.Conn) {
+				c := &mysqlConn{conn: conn}
+
+				greeting := append([]byte{10}, "8.0.0\x00"...)
+				greeting = append(greeting, 1, 0, 0, 0)
+				greeting = append(greeting, scramble[:8]...)
+				greeting = append(greeting, 0)
+				greeting = binary.LittleEndian.AppendUint16(greeting, uint16(myClientProtocol41|myClientSecureConnection))
+				greeting = append(greeting, 45, 2, 0)
+				greeting = binary.LittleEndian.AppendUint16(greeting, uint16(myClientPluginAuth>>16))
+				greeting = append(greeting, 21)
+				greeting = append(greeting, make([]byte, 10)...)
+				greeting = append(append(greeting, scramble[8:]...), 0)
+				greeting = append(greeting, "mysql_native_password\x00"...)
+				c.write(greeting)
+
+				resp, _ := c.read()
+				user, rest, _ := strings.Cut(string(resp[32:]), "\x00")
+				auth := rest[1 : 1+int(rest[0])]
+
+				h1 := sha1.Sum([]byte("right"))
+				h2 := sha1.Sum(h1[:])
+				h3 := sha1.Sum(append(append([]byte{}, scramble...), h2[:]...))
+				for i := range h1 {
+					h1[i] ^= h3[i]
+				}
+				if user == "app" && auth == string(h1[:]) {
+					c.write([]byte{0x00, 0, 0, 2, 0, 0, 0})
+				} else {
+					c.write(append([]byte{0xff, 0x15, 0x04}, "#28000Access denied"...))
+				}
+			})
+			p["user"], p["password"], p["sslmode"] = "app", tt.password, "prefer"
+
+			if err := Run(testContext(t), "mysql", p); (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestS3(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=GOOD/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		params  Params
+		wantErr bool
+	}{
+		{"list buckets", Params{"access-key": "GOOD", "secret-key": "x"}, false},
+		{"head bucket", Params{"access-key": "GOOD", "secret-key": "x", "bucket": "data"}, false},
+		{"missing bucket", Params{"access-key": "GOOD", "secret-key": "x", "bucket": "missing"}, true},
+		{"bad key", Params{"access-key": "BAD", "secret-key": "x"}, true},
+		{"missing secret key", Params{"access-key": "GOOD"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params["endpoint"] = server.URL
+			if err := Run(testContext(t), "s3", tt.params); (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
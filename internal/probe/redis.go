@@ -0,0 +1,70 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// probeRedis sends AUTH (with a username for Redis 6 ACLs) and expects +OK
+func probeRedis(ctx context.Context, p Params) error {
+	if err := p.require("password"); err != nil {
+		return err
+	}
+
+	conn, err := dial(ctx, p, "6379")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if conn, err = redisTLS(conn, p); err != nil {
+		return err
+	}
+
+	args := []string{"AUTH", p["password"]}
+	if user := p["user"]; user != "" {
+		args = []string{"AUTH", user, p["password"]}
+	}
+
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := conn.Write([]byte(cmd.String())); err != nil {
+		return fmt.Errorf("failed to send AUTH: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read reply: %w", err)
+	}
+	reply = strings.TrimSpace(reply)
+	if reply != "+OK" {
+		return fmt.Errorf("authentication failed: %s", strings.TrimPrefix(reply, "-"))
+	}
+	return nil
+}
+
+// redisTLS wraps the connection in TLS according to the sslmode parameter.
+// Redis can't offer TLS on a plain connection, so prefer means TLS without
+// checking the certificate rather than falling back to no TLS.
+func redisTLS(conn net.Conn, p Params) (net.Conn, error) {
+	mode, err := p.sslMode()
+	if err != nil || mode == "disable" {
+		return conn, err
+	}
+	config, err := p.tlsConfig(mode)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	return tlsConn, nil
+}
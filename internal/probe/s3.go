@@ -0,0 +1,59 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/sigv4"
+)
+
+// now is the signing clock, replaceable in tests
+var now = time.Now
+
+// probeS3 sends a SigV4-signed HeadBucket (or ListBuckets without a bucket)
+// request to S3 or an S3-compatible endpoint
+func probeS3(ctx context.Context, p Params) error {
+	if err := p.require("access-key", "secret-key"); err != nil {
+		return err
+	}
+
+	region := p.get("region", "us-east-1")
+	endpoint := strings.TrimSuffix(p.get("endpoint", "https://s3."+region+".amazonaws.com"), "/")
+	method, path := http.MethodGet, "/"
+	if bucket := p["bucket"]; bucket != "" {
+		method, path = http.MethodHead, "/"+url.PathEscape(bucket)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint+path, nil)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	req.Header.Set("X-Amz-Content-Sha256", sigv4.EmptySHA256)
+	if token := p["session-token"]; token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+	sigv4.Sign(req, p["access-key"], p["secret-key"], region, "s3", now())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusMovedPermanently:
+		return fmt.Errorf("bucket is in region %s, not %s", resp.Header.Get("X-Amz-Bucket-Region"), region)
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("bucket %q not found", p["bucket"])
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("authentication failed: %s", resp.Status)
+	default:
+		return fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+}
@@ -0,0 +1,141 @@
+// Package progress reports how far a long-running batch operation has come,
+// as a redrawn bar on a terminal or as periodic lines in logs
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for how often progress is shown
+const (
+	// DefaultDelay is how long a run may take before progress is shown, so
+	// quick runs stay silent
+	DefaultDelay = time.Second
+	// DefaultInterval is the time between line updates when not on a terminal
+	DefaultInterval = 10 * time.Second
+
+	redrawInterval = 100 * time.Millisecond
+	barWidth       = 30
+)
+
+// Reporter tracks the number of completed items out of a known total. A nil
+// Reporter is valid and reports nothing.
+type Reporter struct {
+	Delay    time.Duration
+	Interval time.Duration
+
+	w     io.Writer
+	label string
+	total int
+	tty   bool
+	now   func() time.Time
+
+	mu       sync.Mutex
+	done     int
+	reported int
+	start    time.Time
+	last     time.Time
+	started  bool
+}
+
+// New creates a reporter for total items. On a terminal (tty) it redraws a
+// bar in place; otherwise it writes a line every Interval.
+func New(w io.Writer, label string, total int, tty bool) *Reporter {
+	return newReporter(w, label, total, tty, time.Now)
+}
+
+func newReporter(w io.Writer, label string, total int, tty bool, now func() time.Time) *Reporter {
+	return &Reporter{
+		Delay:    DefaultDelay,
+		Interval: DefaultInterval,
+		w:        w,
+		label:    label,
+		total:    total,
+		tty:      tty,
+		now:      now,
+		start:    now(),
+	}
+}
+
+// Add marks n more items as completed
+func (r *Reporter) Add(n int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.done += n
+	now := r.now()
+	if now.Sub(r.start) < r.Delay {
+		return
+	}
+	interval := r.Interval
+	if r.tty {
+		interval = redrawInterval
+	}
+	if r.started && now.Sub(r.last) < interval {
+		return
+	}
+	r.report(now)
+}
+
+// Increment marks one more item as completed
+func (r *Reporter) Increment() {
+	r.Add(1)
+}
+
+// Done writes the final state if progress was shown at all
+func (r *Reporter) Done() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.started {
+		return
+	}
+	if r.done != r.reported {
+		r.report(r.now())
+	}
+	if r.tty {
+		fmt.Fprintln(r.w)
+	}
+}
+
+// report writes the current state; callers hold the lock
+func (r *Reporter) report(now time.Time) {
+	r.started, r.last, r.reported = true, now, r.done
+
+	percent := 0
+	if r.total > 0 {
+		percent = r.done * 100 / r.total
+	}
+	status := fmt.Sprintf("%d/%d (%d%%)", r.done, r.total, percent)
+	if eta := r.eta(now); eta > 0 {
+		status += ", ETA " + eta.String()
+	}
+
+	if !r.tty {
+		fmt.Fprintf(r.w, "%s: %s\n", r.label, status)
+		return
+	}
+	filled := barWidth * percent / 100
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+	// Clear the rest of the line in case the previous status was longer
+	fmt.Fprintf(r.w, "\r%s [%s] %s\x1b[K", r.label, bar, status)
+}
+
+// eta estimates the time left from the average time per item so far
+func (r *Reporter) eta(now time.Time) time.Duration {
+	if r.done == 0 || r.done >= r.total {
+		return 0
+	}
+	perItem := now.Sub(r.start) / time.Duration(r.done)
+	return (perItem * time.Duration(r.total-r.done)).Round(time.Second)
+}
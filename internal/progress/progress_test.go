@@ -0,0 +1,70 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clock is a fake time source advanced by the test
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func TestLines(t *testing.T) {
+	var buf bytes.Buffer
+	c := &clock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	r := newReporter(&buf, "check", 4, false, c.now)
+
+	// Within the delay nothing is shown
+	r.Increment()
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected output before the delay: %q", buf.String())
+	}
+
+	c.t = c.t.Add(2 * time.Second)
+	r.Increment()
+	c.t = c.t.Add(time.Second)
+	r.Increment() // within the interval
+	c.t = c.t.Add(DefaultInterval)
+	r.Increment()
+	r.Done()
+
+	want := "check: 2/4 (50%), ETA 2s\ncheck: 4/4 (100%)\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestBar(t *testing.T) {
+	var buf bytes.Buffer
+	c := &clock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	r := newReporter(&buf, "scan", 10, true, c.now)
+
+	c.t = c.t.Add(5 * time.Second)
+	r.Add(5)
+	r.Done()
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "\rscan [===============               ] 5/10 (50%), ETA 5s") {
+		t.Errorf("output = %q", out)
+	}
+	if !strings.HasSuffix(out, "\n") {
+		t.Errorf("Done() should end the line: %q", out)
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(&buf, "check", 3, true)
+	r.Add(3)
+	r.Done()
+	if buf.Len() != 0 {
+		t.Errorf("fast runs should be silent, got %q", buf.String())
+	}
+
+	var none *Reporter
+	none.Increment()
+	none.Done()
+}
@@ -0,0 +1,167 @@
+// Package query evaluates small jq-like expressions against YAML manifests
+package query
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+// function transforms the current value of a pipeline
+type function func(*yaml.Node) (*yaml.Node, error)
+
+// functions are the pipeline stages available after `|`
+var functions = map[string]function{
+	"@base64d": mapScalars(decodeBase64),
+	"@base64":  mapScalars(encodeBase64),
+	"keys":     keys,
+	"length":   length,
+}
+
+// Functions returns the names of the available pipeline functions
+func Functions() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Evaluate runs an expression like `$.data.password | @base64d` against a
+// YAML document. Each `|`-separated stage is either a path (relative to the
+// current value) or a function name.
+func Evaluate(input []byte, expr string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(input, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("invalid YAML document")
+	}
+
+	current := doc.Content[0]
+	for _, stage := range strings.Split(expr, "|") {
+		stage = strings.TrimSpace(stage)
+		if stage == "" {
+			return nil, fmt.Errorf("empty stage in expression %q", expr)
+		}
+
+		if fn, ok := functions[stage]; ok {
+			next, err := fn(current)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", stage, err)
+			}
+			current = next
+			continue
+		}
+
+		if !strings.HasPrefix(stage, "$") && !strings.HasPrefix(stage, ".") && !strings.HasPrefix(stage, "[") {
+			return nil, fmt.Errorf("unknown function %q (available: %s)", stage, strings.Join(Functions(), ", "))
+		}
+
+		next, err := yamlpath.Resolve(current, stage)
+		if err != nil {
+			return nil, err
+		}
+		current = next
+	}
+
+	return current, nil
+}
+
+// Format renders a result for printing: scalars as their raw value, anything
+// else as YAML
+func Format(node *yaml.Node) ([]byte, error) {
+	if node.Kind == yaml.ScalarNode {
+		if strings.HasSuffix(node.Value, "\n") {
+			return []byte(node.Value), nil
+		}
+		return []byte(node.Value + "\n"), nil
+	}
+	return yaml.Marshal(node)
+}
+
+// mapScalars lifts a string transformation to scalars, and to every scalar
+// value of a mapping or sequence
+func mapScalars(fn func(string) (string, error)) function {
+	return func(node *yaml.Node) (*yaml.Node, error) {
+		switch node.Kind {
+		case yaml.ScalarNode:
+			v, err := fn(node.Value)
+			if err != nil {
+				return nil, err
+			}
+			return scalar(v), nil
+		case yaml.MappingNode:
+			out := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				v, err := mapScalars(fn)(node.Content[i+1])
+				if err != nil {
+					return nil, fmt.Errorf("key %q: %w", node.Content[i].Value, err)
+				}
+				out.Content = append(out.Content, node.Content[i], v)
+			}
+			return out, nil
+		case yaml.SequenceNode:
+			out := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for i, item := range node.Content {
+				v, err := mapScalars(fn)(item)
+				if err != nil {
+					return nil, fmt.Errorf("index %d: %w", i, err)
+				}
+				out.Content = append(out.Content, v)
+			}
+			return out, nil
+		}
+		return nil, fmt.Errorf("unsupported value")
+	}
+}
+
+func decodeBase64(s string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64: %w", err)
+	}
+	return string(decoded), nil
+}
+
+func encodeBase64(s string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(s)), nil
+}
+
+// keys returns the keys of a mapping, in document order
+func keys(node *yaml.Node) (*yaml.Node, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping")
+	}
+
+	out := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		out.Content = append(out.Content, scalar(node.Content[i].Value))
+	}
+	return out, nil
+}
+
+// length returns the number of entries of a collection or bytes of a scalar
+func length(node *yaml.Node) (*yaml.Node, error) {
+	var n int
+	switch node.Kind {
+	case yaml.MappingNode:
+		n = len(node.Content) / 2
+	case yaml.SequenceNode:
+		n = len(node.Content)
+	case yaml.ScalarNode:
+		n = len(node.Value)
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(n)}, nil
+}
+
+func scalar(v string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+}
@@ -0,0 +1,81 @@
+package query
+
+import (
+	"testing"
+)
+
+const manifest = `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-secret
+data:
+  username: YWRtaW4=
+  password: cGFzc3dvcmQxMjM=
+`
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{"raw value", "$.data.password", "cGFzc3dvcmQxMjM=\n", false},
+		{"decoded value", "$.data.password | @base64d", "password123\n", false},
+		{"without dollar", ".metadata.name", "test-secret\n", false},
+		{"encode", ".metadata.name | @base64", "dGVzdC1zZWNyZXQ=\n", false},
+		{"decode mapping", ".data | @base64d", "username: admin\npassword: password123\n", false},
+		{"keys", ".data | keys", "- username\n- password\n", false},
+		{"length of mapping", ".data | length", "2\n", false},
+		{"length of scalar", ".data.password | @base64d | length", "11\n", false},
+		{"path after function stage", ".data | @base64d | .username", "admin\n", false},
+		{"missing path", ".data.nope", "", true},
+		{"unknown function", ".data | @nope", "", true},
+		{"empty stage", ".data || keys", "", true},
+		{"keys of scalar", ".kind | keys", "", true},
+		{"invalid base64", ".kind | @base64d", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := Evaluate([]byte(manifest), tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Evaluate(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			got, err := Format(node)
+			if err != nil {
+				t.Fatalf("Format() failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Evaluate(%q) = %q, want %q", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateInvalidInput(t *testing.T) {
+	if _, err := Evaluate([]byte("invalid: yaml: [[["), ".a"); err == nil {
+		t.Error("Evaluate() should fail on invalid YAML")
+	}
+	if _, err := Evaluate([]byte(""), ".a"); err == nil {
+		t.Error("Evaluate() should fail on empty input")
+	}
+}
+
+func TestFormatMultilineScalar(t *testing.T) {
+	node, err := Evaluate([]byte("data:\n  cert: bGluZTEKbGluZTIK\n"), ".data.cert | @base64d")
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	got, err := Format(node)
+	if err != nil {
+		t.Fatalf("Format() failed: %v", err)
+	}
+	if string(got) != "line1\nline2\n" {
+		t.Errorf("Format() = %q, should not add a second trailing newline", got)
+	}
+}
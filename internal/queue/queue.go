@@ -0,0 +1,95 @@
+// Package queue serializes jobs that touch the same target, such as a file
+// or a cluster object, while jobs on different targets run concurrently
+package queue
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Queue holds a lock per target. Waiters are served in arrival order.
+type Queue struct {
+	mu      sync.Mutex
+	targets map[string]*target
+	now     func() time.Time
+}
+
+type target struct {
+	lock    chan struct{}
+	waiting int
+	active  string
+	since   time.Time
+}
+
+// Status describes a target that has a job running or waiting
+type Status struct {
+	Target  string    `json:"target"`
+	Active  string    `json:"active,omitempty"`
+	Since   time.Time `json:"since,omitzero"`
+	Waiting int       `json:"waiting"`
+}
+
+// New returns an empty queue
+func New() *Queue {
+	return &Queue{targets: map[string]*target{}, now: time.Now}
+}
+
+// Do runs fn once every earlier job for key has finished. job describes the
+// work for Status. If ctx ends while waiting, fn doesn't run and Do returns
+// the context's error.
+func (q *Queue) Do(ctx context.Context, key, job string, fn func() error) error {
+	q.mu.Lock()
+	t := q.targets[key]
+	if t == nil {
+		t = &target{lock: make(chan struct{}, 1)}
+		q.targets[key] = t
+	}
+	t.waiting++
+	q.mu.Unlock()
+
+	select {
+	case t.lock <- struct{}{}:
+	case <-ctx.Done():
+		q.mu.Lock()
+		t.waiting--
+		q.prune(key, t)
+		q.mu.Unlock()
+		return ctx.Err()
+	}
+
+	q.mu.Lock()
+	t.waiting--
+	t.active, t.since = job, q.now()
+	q.mu.Unlock()
+
+	defer func() {
+		q.mu.Lock()
+		t.active, t.since = "", time.Time{}
+		<-t.lock
+		q.prune(key, t)
+		q.mu.Unlock()
+	}()
+	return fn()
+}
+
+// prune forgets an idle target. q.mu must be held.
+func (q *Queue) prune(key string, t *target) {
+	if t.waiting == 0 && len(t.lock) == 0 {
+		delete(q.targets, key)
+	}
+}
+
+// Status lists the busy targets, sorted by key
+func (q *Queue) Status() []Status {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	status := make([]Status, 0, len(q.targets))
+	for key, t := range q.targets {
+		status = append(status, Status{Target: key, Active: t.active, Since: t.since, Waiting: t.waiting})
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Target < status[j].Target })
+	return status
+}
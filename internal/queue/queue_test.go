@@ -0,0 +1,114 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDoSerializes(t *testing.T) {
+	q := New()
+	var wg sync.WaitGroup
+	running, overlapped := 0, false
+	var mu sync.Mutex
+
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = q.Do(context.Background(), "file:a", "save", func() error {
+				mu.Lock()
+				running++
+				overlapped = overlapped || running > 1
+				mu.Unlock()
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	if overlapped {
+		t.Error("jobs for the same target overlapped")
+	}
+	if status := q.Status(); len(status) != 0 {
+		t.Errorf("Status() = %v, want no busy targets", status)
+	}
+}
+
+func TestDoConcurrentTargets(t *testing.T) {
+	q := New()
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	go func() {
+		_ = q.Do(context.Background(), "file:a", "save", func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	// Another target isn't blocked by a.
+	if err := q.Do(context.Background(), "file:b", "save", func() error { return nil }); err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+	close(release)
+}
+
+func TestStatus(t *testing.T) {
+	q := New()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	q.now = func() time.Time { return now }
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_ = q.Do(context.Background(), "file:a", "save by vim", func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waited := make(chan error)
+	go func() {
+		waited <- q.Do(ctx, "file:a", "save by code", func() error { return nil })
+	}()
+	for q.Status()[0].Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	got := q.Status()
+	want := Status{Target: "file:a", Active: "save by vim", Since: now, Waiting: 1}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("Status() = %+v, want [%+v]", got, want)
+	}
+
+	// A cancelled waiter gives up without running
+	cancel()
+	if err := <-waited; !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() error = %v, want context.Canceled", err)
+	}
+	close(release)
+}
+
+func TestDoError(t *testing.T) {
+	q := New()
+	want := errors.New("boom")
+	if err := q.Do(context.Background(), "file:a", "save", func() error { return want }); err != want {
+		t.Errorf("Do() error = %v, want %v", err, want)
+	}
+	// The lock is released after a failure
+	if err := q.Do(context.Background(), "file:a", "save", func() error { return nil }); err != nil {
+		t.Errorf("Do() error = %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+// Package recovery keeps encrypted copies of edits that could not be saved,
+// e.g. because the editor crashed, so they can be restored later
+package recovery
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/sealed"
+)
+
+// ext is the file extension of recovery entries
+const ext = ".swk-recovery"
+
+// Store is a directory of encrypted recovery entries. The key lives
+// elsewhere, readable only by the user, so a copy of the directory alone
+// reveals nothing.
+type Store struct {
+	Dir string
+
+	box *sealed.Box
+	now func() time.Time
+}
+
+// Entry describes a recovered edit
+type Entry struct {
+	ID     string `json:"-"`
+	Source string `json:"source"`
+	// JSONPath locates a Secret embedded in a larger document, if any
+	JSONPath string    `json:"jsonPath,omitempty"`
+	Saved    time.Time `json:"saved"`
+}
+
+// entryFile is the plaintext of an entry file
+type entryFile struct {
+	Entry
+	Data []byte `json:"data"`
+}
+
+// DefaultStore opens the store in the user's cache dir, with its key in the
+// user's config dir
+func DefaultStore() (*Store, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return Open(filepath.Join(cacheDir, "swk", "recovery"), filepath.Join(configDir, "swk", "recovery.key"))
+}
+
+// Open opens or creates a store in dir, creating the key at keyPath if needed
+func Open(dir, keyPath string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create recovery directory: %w", err)
+	}
+	// A damaged key is an error: replacing it would make every existing
+	// entry unreadable
+	box, err := sealed.Open(keyPath, "recovery")
+	if err != nil {
+		return nil, err
+	}
+	return &Store{Dir: dir, box: box, now: time.Now}, nil
+}
+
+// Save stores data, described by e, and returns e with its ID and time set
+func (s *Store) Save(e Entry, data []byte) (Entry, error) {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return Entry{}, err
+	}
+	e.Saved = s.now()
+	e.ID = e.Saved.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+
+	plaintext, err := json.Marshal(entryFile{Entry: e, Data: data})
+	if err != nil {
+		return Entry{}, err
+	}
+	ciphertext, err := s.box.Seal(plaintext, []byte(e.ID))
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if err := os.WriteFile(s.path(e.ID), ciphertext, 0600); err != nil {
+		return Entry{}, fmt.Errorf("failed to write recovery entry: %w", err)
+	}
+	return e, nil
+}
+
+// Load returns an entry and the data saved in it
+func (s *Store) Load(id string) (Entry, []byte, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return Entry{}, nil, fmt.Errorf("invalid recovery entry %q", id)
+	}
+	ciphertext, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Entry{}, nil, fmt.Errorf("no recovery entry %q", id)
+	}
+	if err != nil {
+		return Entry{}, nil, fmt.Errorf("failed to read recovery entry: %w", err)
+	}
+	plaintext, err := s.box.Open(ciphertext, []byte(id))
+	if err != nil {
+		return Entry{}, nil, fmt.Errorf("recovery entry %s %w", id, err)
+	}
+	var e entryFile
+	if err := json.Unmarshal(plaintext, &e); err != nil {
+		return Entry{}, nil, fmt.Errorf("recovery entry %s is damaged", id)
+	}
+	e.ID = id
+	return e.Entry, e.Data, nil
+}
+
+// List returns the readable entries, oldest first
+func (s *Store) List() ([]Entry, error) {
+	files, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recovery directory: %w", err)
+	}
+
+	var entries []Entry
+	for _, f := range files {
+		id, ok := strings.CutSuffix(f.Name(), ext)
+		if !ok {
+			continue
+		}
+		if e, _, err := s.Load(id); err == nil {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Saved.Before(entries[j].Saved) })
+	return entries, nil
+}
+
+// Delete removes an entry
+func (s *Store) Delete(id string) error {
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.Dir, id+ext)
+}
@@ -0,0 +1,79 @@
+package recovery
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	dir, keyPath := t.TempDir(), filepath.Join(t.TempDir(), "swk", "recovery.key")
+	s, err := Open(dir, keyPath)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	base := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return base }
+
+	first, err := s.Save(Entry{Source: "/tmp/db.yaml"}, []byte("password: hunter2\n"))
+	if err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	s.now = func() time.Time { return base.Add(time.Minute) }
+	if _, err := s.Save(Entry{Source: "/tmp/api.yaml"}, []byte("token: x\n")); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, first.ID+ext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "hunter2") {
+		t.Error("recovery entry is stored in plain text")
+	}
+
+	// A reopened store reads the same key
+	s, err = Open(dir, keyPath)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	entries, err := s.List()
+	if err != nil || len(entries) != 2 || entries[0].Source != "/tmp/db.yaml" {
+		t.Fatalf("List() = %+v, %v", entries, err)
+	}
+	e, data, err := s.Load(first.ID)
+	if err != nil || string(data) != "password: hunter2\n" || !e.Saved.Equal(base) {
+		t.Errorf("Load() = %+v, %q, %v", e, data, err)
+	}
+
+	if err := s.Delete(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Load(first.ID); err == nil {
+		t.Error("Load() of a deleted entry succeeded")
+	}
+	if _, _, err := s.Load("../key"); err == nil {
+		t.Error("Load() accepted a path")
+	}
+
+	// Another key can't read the entries
+	other, err := Open(dir, filepath.Join(t.TempDir(), "other.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := other.List(); len(entries) != 0 {
+		t.Errorf("List() with another key = %+v, want none", entries)
+	}
+}
+
+func TestDamagedKey(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "recovery.key")
+	if err := os.WriteFile(keyPath, []byte("short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(t.TempDir(), keyPath); err == nil {
+		t.Error("Open() with a damaged key succeeded")
+	}
+}
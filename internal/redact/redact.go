@@ -0,0 +1,147 @@
+// Package redact replaces secret values with placeholders that say what
+// kind of value was there and how long it was, but nothing of its content
+package redact
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	pemBegin = regexp.MustCompile(`-----BEGIN ([A-Z0-9 ]+)-----`)
+	uuidRe   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexRe    = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	digitsRe = regexp.MustCompile(`^[0-9]+$`)
+	base64Re = regexp.MustCompile(`^[A-Za-z0-9+/]{16,}={0,2}$`)
+)
+
+// Placeholder returns what a value is replaced with, e.g. <pem-cert 2048B>:
+// the kind of value, see Kind, and its length in bytes. The same value
+// always gets the same placeholder, but the placeholder holds nothing
+// derived from the value's content, not even a hash.
+func Placeholder(value []byte) string {
+	if len(value) == 0 {
+		return "<empty>"
+	}
+	return fmt.Sprintf("<%s %dB>", Kind(value), len(value))
+}
+
+// ParsePlaceholder reads a placeholder written by Placeholder back into
+// the kind and length of the value it replaced. ok is false for anything
+// else; <empty> has an empty kind.
+func ParsePlaceholder(s string) (kind string, length int, ok bool) {
+	inner, found := strings.CutPrefix(strings.TrimSpace(s), "<")
+	if inner, found = strings.CutSuffix(inner, ">"); !found {
+		return "", 0, false
+	}
+	if inner == "empty" {
+		return "", 0, true
+	}
+	i := strings.LastIndex(inner, " ")
+	size, found := strings.CutSuffix(inner[i+1:], "B")
+	if i <= 0 || !found {
+		return "", 0, false
+	}
+	length, err := strconv.Atoi(size)
+	if err != nil || length <= 0 {
+		return "", 0, false
+	}
+	return inner[:i], length, true
+}
+
+// Kind tells what kind of value a secret holds: pem-cert (with the count
+// for bundles, e.g. pem-cert x3), pem-key, ssh-public-key, jwt, json, a URL
+// by scheme, e.g. postgres-url, uuid, hex, number, base64, text for
+// multiline values, string, or for binary values gzip, zip, der, or binary
+func Kind(value []byte) string {
+	if !utf8.Valid(value) {
+		switch {
+		case bytes.HasPrefix(value, []byte{0x1f, 0x8b}):
+			return "gzip"
+		case bytes.HasPrefix(value, []byte("PK\x03\x04")):
+			return "zip"
+		case len(value) > 1 && value[0] == 0x30 && value[1]&0x80 != 0:
+			// An ASN.1 sequence with a long length, e.g. a certificate,
+			// key, or PKCS#12 keystore
+			return "der"
+		}
+		return "binary"
+	}
+
+	s := strings.TrimSpace(string(value))
+	if blocks := pemBegin.FindAllStringSubmatch(s, -1); len(blocks) > 0 {
+		kind := pemKind(blocks[0][1])
+		for _, b := range blocks[1:] {
+			if pemKind(b[1]) != kind {
+				return "pem"
+			}
+		}
+		if len(blocks) > 1 {
+			kind += fmt.Sprintf(" x%d", len(blocks))
+		}
+		return kind
+	}
+	switch {
+	case strings.HasPrefix(s, "ssh-") || strings.HasPrefix(s, "ecdsa-sha2-"):
+		return "ssh-public-key"
+	case isJWT(s):
+		return "jwt"
+	case (strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[")) && json.Valid([]byte(s)):
+		return "json"
+	}
+	if u, err := url.Parse(s); err == nil && u.Scheme != "" && u.Host != "" && !strings.ContainsAny(s, " \n") {
+		return strings.ToLower(u.Scheme) + "-url"
+	}
+	switch {
+	case uuidRe.MatchString(s):
+		return "uuid"
+	case digitsRe.MatchString(s):
+		return "number"
+	case hexRe.MatchString(s):
+		return "hex"
+	case base64Re.MatchString(s) && len(s)%4 == 0:
+		return "base64"
+	case strings.Contains(s, "\n"):
+		return "text"
+	}
+	return "string"
+}
+
+// pemKind names a PEM block type
+func pemKind(blockType string) string {
+	switch {
+	case blockType == "CERTIFICATE":
+		return "pem-cert"
+	case strings.HasSuffix(blockType, "PRIVATE KEY"):
+		return "pem-key"
+	case strings.HasSuffix(blockType, "PUBLIC KEY"):
+		return "pem-public-key"
+	case blockType == "CERTIFICATE REQUEST":
+		return "pem-csr"
+	}
+	return "pem"
+}
+
+// isJWT reports whether s is a JSON Web Token: three base64url parts, the
+// first a JSON header naming its algorithm
+func isJWT(s string) bool {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	return json.Unmarshal(header, &h) == nil && h.Alg != ""
+}
@@ -0,0 +1,348 @@
+// Package registry manages Docker registry credentials stored in
+// kubernetes.io/dockerconfigjson Secrets
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Key is the data key of a kubernetes.io/dockerconfigjson Secret
+const Key = ".dockerconfigjson"
+
+// Auth is a registry entry in a Docker config file
+type Auth struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+// Credentials returns the username and password, from the auth field if the
+// explicit fields are empty
+func (a Auth) Credentials() (string, string, error) {
+	if a.Username != "" || a.Auth == "" {
+		return a.Username, a.Password, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid auth field: %w", err)
+	}
+	user, pass, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", "", fmt.Errorf("invalid auth field, expected user:password")
+	}
+	return user, pass, nil
+}
+
+// Config is a Docker config file. Fields other than auths are kept as is.
+type Config struct {
+	Auths map[string]Auth
+
+	other map[string]json.RawMessage
+}
+
+// Parse reads a Docker config; empty input is an empty config
+func Parse(data []byte) (*Config, error) {
+	c := &Config{Auths: map[string]Auth{}, other: map[string]json.RawMessage{}}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return c, nil
+	}
+
+	if err := json.Unmarshal(data, &c.other); err != nil {
+		return nil, fmt.Errorf("failed to parse docker config: %w", err)
+	}
+	if raw, ok := c.other["auths"]; ok {
+		if err := json.Unmarshal(raw, &c.Auths); err != nil {
+			return nil, fmt.Errorf("failed to parse docker config auths: %w", err)
+		}
+		delete(c.other, "auths")
+	}
+	return c, nil
+}
+
+// Set stores credentials for a registry, replacing any existing entry
+func (c *Config) Set(registry, username, password string) {
+	c.Auths[registry] = Auth{
+		Username: username,
+		Password: password,
+		Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+	}
+}
+
+// Registries returns the configured registries, sorted
+func (c *Config) Registries() []string {
+	names := make([]string, 0, len(c.Auths))
+	for name := range c.Auths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Bytes renders the config as JSON
+func (c *Config) Bytes() ([]byte, error) {
+	out := make(map[string]any, len(c.other)+1)
+	for k, v := range c.other {
+		out[k] = v
+	}
+	out["auths"] = c.Auths
+	return json.Marshal(out)
+}
+
+// HelperFor returns the credential helper the local Docker config uses for
+// a registry, or "" if there is none
+func HelperFor(registry string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		dir = filepath.Join(home, ".docker")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return ""
+	}
+	var cfg struct {
+		CredsStore  string            `json:"credsStore"`
+		CredHelpers map[string]string `json:"credHelpers"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return ""
+	}
+	if helper, ok := cfg.CredHelpers[registry]; ok {
+		return helper
+	}
+	return cfg.CredsStore
+}
+
+// FromHelper gets credentials for a registry from a Docker credential
+// helper, e.g. "ecr-login" runs docker-credential-ecr-login
+func FromHelper(ctx context.Context, helper, registry string) (string, string, error) {
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(registry)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(string(out))
+		}
+		return "", "", fmt.Errorf("credential helper %s failed: %v: %s", helper, err, msg)
+	}
+
+	var creds struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return "", "", fmt.Errorf("credential helper %s returned invalid output: %w", helper, err)
+	}
+	return creds.Username, creds.Secret, nil
+}
+
+// PingOptions configures Ping
+type PingOptions struct {
+	// PlainHTTP talks to the registry over HTTP instead of HTTPS, which
+	// also allows a token realm over HTTP on the registry's own host
+	PlainHTTP bool
+	// AllowRealms are hosts Ping may send the credentials to for a token
+	// besides the registry and its sibling hosts
+	AllowRealms []string
+}
+
+// Ping checks credentials against a registry's /v2/ endpoint. It only
+// succeeds if the registry checked them: /v2/ must first refuse an
+// anonymous request, then accept the credentials, directly or through the
+// bearer token flow. The credentials are only sent to a token realm over
+// HTTPS on the registry's host, a host in its parent domain such as
+// auth.docker.io for registry-1.docker.io, or a host in AllowRealms.
+func Ping(ctx context.Context, client *http.Client, registry, username, password string, opts PingOptions) error {
+	scheme := "https"
+	if opts.PlainHTTP {
+		scheme = "http"
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://"), "/")
+	host = strings.TrimSuffix(host, "/v1")
+	if host == "index.docker.io" || host == "docker.io" {
+		host = "registry-1.docker.io"
+	}
+	endpoint := scheme + "://" + host + "/v2/"
+
+	resp, err := get(ctx, client, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("registry unreachable: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return fmt.Errorf("the registry allows anonymous access to /v2/, so the credentials can't be checked")
+	case resp.StatusCode != http.StatusUnauthorized:
+		return fmt.Errorf("unexpected response from registry: %s", resp.Status)
+	}
+
+	authScheme, params := parseChallenge(resp.Header.Get("WWW-Authenticate"))
+	var authorize func(*http.Request)
+	switch {
+	case strings.EqualFold(authScheme, "basic"):
+		authorize = func(req *http.Request) { req.SetBasicAuth(username, password) }
+	case strings.EqualFold(authScheme, "bearer") && params["realm"] != "":
+		realm, err := checkRealm(params["realm"], host, opts)
+		if err != nil {
+			return err
+		}
+		token, err := fetchToken(ctx, client, realm, params, username, password)
+		if err != nil {
+			return err
+		}
+		authorize = func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+	default:
+		return fmt.Errorf("unsupported authentication challenge %q", authScheme)
+	}
+
+	if resp, err = get(ctx, client, endpoint, authorize); err != nil {
+		return fmt.Errorf("registry unreachable: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("authentication failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// get requests u, letting authorize add credentials, and closes the body
+func get(ctx context.Context, client *http.Client, u string, authorize func(*http.Request)) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if authorize != nil {
+		authorize(req)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// checkRealm parses a token realm, refusing ones Ping shouldn't send the
+// credentials to
+func checkRealm(realm, registryHost string, opts PingOptions) (*url.URL, error) {
+	u, err := url.Parse(realm)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid token realm %q", realm)
+	}
+	realmHost := u.Hostname()
+	registryName := registryHost
+	if h, _, err := net.SplitHostPort(registryHost); err == nil {
+		registryName = h
+	}
+
+	for _, allowed := range opts.AllowRealms {
+		if strings.EqualFold(allowed, u.Host) || strings.EqualFold(allowed, realmHost) {
+			return u, nil
+		}
+	}
+	switch {
+	case u.Scheme == "http" && opts.PlainHTTP && strings.EqualFold(u.Host, registryHost):
+		return u, nil
+	case u.Scheme != "https":
+		return nil, fmt.Errorf("refusing to send credentials to token realm %s over %s; pass --allow-realm %s to allow it", realm, u.Scheme, u.Host)
+	case !siblingHost(realmHost, registryName):
+		return nil, fmt.Errorf("refusing to send credentials to token realm %s on another host; pass --allow-realm %s to allow it", realm, u.Host)
+	}
+	return u, nil
+}
+
+// siblingHost reports whether host is registry, or shares its parent
+// domain when that is more than a top-level domain
+func siblingHost(host, registry string) bool {
+	if strings.EqualFold(host, registry) {
+		return true
+	}
+	_, parent, ok := strings.Cut(registry, ".")
+	if !ok || !strings.Contains(parent, ".") || net.ParseIP(registry) != nil {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(parent))
+}
+
+// fetchToken requests a bearer token from the realm of a challenge
+func fetchToken(ctx context.Context, client *http.Client, realm *url.URL, params map[string]string, username, password string) (string, error) {
+	u := *realm
+	q := u.Query()
+	if service := params["service"]; service != "" {
+		q.Set("service", service)
+	}
+	if scope := params["scope"]; scope != "" {
+		q.Set("scope", scope)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(username, password)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token service unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("authentication failed: %s", resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+	if body.Token == "" {
+		body.Token = body.AccessToken
+	}
+	if body.Token == "" {
+		return "", fmt.Errorf("the token service returned no token")
+	}
+	return body.Token, nil
+}
+
+// parseChallenge splits a WWW-Authenticate header like
+// `Bearer realm="https://auth",service="registry"`
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key != "" {
+			params[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+	return scheme, params
+}
@@ -0,0 +1,157 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	cfg, err := Parse([]byte(`{"auths":{"old.example.com":{"auth":"dXNlcjpwYXNz"}},"credsStore":"desktop"}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	user, pass, err := cfg.Auths["old.example.com"].Credentials()
+	if err != nil || user != "user" || pass != "pass" {
+		t.Errorf("Credentials() = %q, %q, %v", user, pass, err)
+	}
+
+	cfg.Set("r.example.com", "bot", "s3cret")
+	out, err := cfg.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	for _, want := range []string{`"credsStore":"desktop"`, `"old.example.com"`, `"auth":"Ym90OnMzY3JldA=="`} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Bytes() = %s, missing %s", out, want)
+		}
+	}
+
+	if got := cfg.Registries(); len(got) != 2 || got[0] != "old.example.com" {
+		t.Errorf("Registries() = %v", got)
+	}
+
+	if _, err := Parse([]byte("not json")); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a/b:pull"`)
+	if scheme != "Bearer" {
+		t.Errorf("scheme = %q, want Bearer", scheme)
+	}
+	want := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:a/b:pull",
+	}
+	for k, v := range want {
+		if params[k] != v {
+			t.Errorf("params[%s] = %q, want %q", k, params[k], v)
+		}
+	}
+}
+
+// registryServer serves /v2/ the way a registry does: anonymous when
+// challenge is empty, otherwise refusing requests without basic or bearer
+// credentials for user "ok"/"ok". A Bearer challenge points at realm, taken
+// as a path on the server itself when it starts with a slash.
+func registryServer(t *testing.T, challenge, realm string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, basic := r.BasicAuth()
+		switch r.URL.Path {
+		case "/v2/":
+			switch {
+			case challenge == "":
+			case challenge == "Basic" && basic && user == "ok" && pass == "ok":
+			case challenge == "Bearer" && r.Header.Get("Authorization") == "Bearer issued":
+			default:
+				if challenge == "Basic" {
+					w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+				} else {
+					u := realm
+					if strings.HasPrefix(u, "/") {
+						u = "http://" + r.Host + u
+					}
+					w.Header().Set("WWW-Authenticate", `Bearer realm="`+u+`",service="test"`)
+				}
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		case "/token":
+			if r.URL.Query().Get("service") != "test" || user != "ok" || pass != "ok" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"token":"issued"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name       string
+		challenge  string
+		realm      string
+		user, pass string
+		wantErr    string
+	}{
+		{name: "basic auth", challenge: "Basic", user: "ok", pass: "ok"},
+		{name: "basic auth rejected", challenge: "Basic", user: "ok", pass: "bad", wantErr: "authentication failed"},
+		{name: "token flow", challenge: "Bearer", realm: "/token", user: "ok", pass: "ok"},
+		{name: "token flow rejected", challenge: "Bearer", realm: "/token", user: "ok", pass: "bad", wantErr: "authentication failed"},
+		{name: "anonymous registry", user: "ok", pass: "ok", wantErr: "anonymous access"},
+		{name: "realm on another host", challenge: "Bearer", realm: "https://auth.example.com/token", user: "ok", pass: "ok", wantErr: "on another host"},
+		{name: "realm over http", challenge: "Bearer", realm: "http://auth.example.com/token", user: "ok", pass: "ok", wantErr: "over http"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := registryServer(t, tt.challenge, tt.realm)
+			err := Ping(context.Background(), server.Client(), strings.TrimPrefix(server.URL, "http://"), tt.user, tt.pass, PingOptions{PlainHTTP: true})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Ping() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Ping() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckRealm(t *testing.T) {
+	tests := []struct {
+		realm    string
+		registry string
+		opts     PingOptions
+		wantErr  bool
+	}{
+		{"https://registry.example.com/token", "registry.example.com", PingOptions{}, false},
+		{"https://auth.docker.io/token", "registry-1.docker.io", PingOptions{}, false},
+		{"https://ghcr.io/token", "ghcr.io", PingOptions{}, false},
+		{"https://auth.example.com/token", "registry.example.com:5000", PingOptions{}, false},
+		{"https://evil.io/token", "ghcr.io", PingOptions{}, true},
+		{"https://auth.example.org/token", "registry.example.com", PingOptions{}, true},
+		{"https://auth.example.org/token", "registry.example.com", PingOptions{AllowRealms: []string{"auth.example.org"}}, false},
+		{"http://registry.example.com/token", "registry.example.com", PingOptions{}, true},
+		{"http://registry.example.com/token", "registry.example.com", PingOptions{PlainHTTP: true}, false},
+		{"http://auth.example.com/token", "registry.example.com", PingOptions{PlainHTTP: true}, true},
+		{"https://10.0.0.2/token", "10.0.0.1", PingOptions{}, true},
+		{"/token", "registry.example.com", PingOptions{}, true},
+	}
+
+	for _, tt := range tests {
+		_, err := checkRealm(tt.realm, tt.registry, tt.opts)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkRealm(%q, %q) error = %v, wantErr %v", tt.realm, tt.registry, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Package report writes what swk check, audit, and sync found to several
+// sinks at once, such as stdout, a file, a webhook, and S3, each in its own
+// format, so one CI run can feed people and dashboards alike
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/check"
+)
+
+// Format is how a report is written
+type Format string
+
+// Report formats
+const (
+	Text  Format = "text"
+	JSON  Format = "json"
+	SARIF Format = "sarif"
+)
+
+// Formats lists the report formats
+var Formats = []Format{Text, JSON, SARIF}
+
+// ParseFormat checks a format name
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown report format %q (supported: text, json, sarif)", name)
+}
+
+// formatFor picks the format for a destination by its extension: json for
+// .json, sarif for .sarif, and text otherwise
+func formatFor(dest string) Format {
+	switch strings.ToLower(filepath.Ext(dest)) {
+	case ".json":
+		return JSON
+	case ".sarif":
+		return SARIF
+	}
+	return Text
+}
+
+// Report is what a command found: problems for check and audit, keys that
+// would change for sync
+type Report struct {
+	Command  string          `json:"command"`
+	Findings []check.Finding `json:"findings,omitempty"`
+	Changes  []Change        `json:"changes,omitempty"`
+}
+
+// Change is a key that differs between two clusters
+type Change struct {
+	Secret string `json:"secret"` // namespace/name
+	Key    string `json:"key"`
+	Op     string `json:"op"` // added, changed, or removed
+}
+
+// String formats a change as `secret: + key`, as swk sync lists them
+func (c Change) String() string {
+	mark := "~"
+	switch c.Op {
+	case "added":
+		mark = "+"
+	case "removed":
+		mark = "-"
+	}
+	return c.Secret + ": " + mark + " " + c.Key
+}
+
+// Render writes a report in the given format
+func Render(r Report, format Format) ([]byte, error) {
+	switch format {
+	case Text:
+		var b strings.Builder
+		for _, f := range r.Findings {
+			b.WriteString(f.String() + "\n")
+		}
+		for _, c := range r.Changes {
+			b.WriteString(c.String() + "\n")
+		}
+		return []byte(b.String()), nil
+	case JSON:
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case SARIF:
+		return sarif(r)
+	}
+	return nil, fmt.Errorf("unknown report format %q", format)
+}
+
+// sarif writes a report as SARIF 2.1.0, which code scanning dashboards
+// read. Changes are notes under the rule sync.
+func sarif(r Report) ([]byte, error) {
+	descriptions := map[string]string{"owner": "Keys changed that belong to another team", "sync": "Keys that differ between two clusters"}
+	for _, rule := range check.Rules() {
+		descriptions[rule.Name] = rule.Description
+	}
+
+	used := map[string]bool{}
+	results := []any{}
+	for _, f := range r.Findings {
+		text := f.Secret + ": " + f.Message
+		if f.Key != "" {
+			text = f.Secret + " " + f.Key + ": " + f.Message
+		}
+		location := map[string]any{"artifactLocation": map[string]any{"uri": filepath.ToSlash(f.File)}}
+		if f.Line > 0 {
+			location["region"] = map[string]any{"startLine": f.Line}
+		}
+		results = append(results, map[string]any{
+			"ruleId":    f.Rule,
+			"level":     string(f.Severity),
+			"message":   map[string]any{"text": text},
+			"locations": []any{map[string]any{"physicalLocation": location}},
+		})
+		used[f.Rule] = true
+	}
+	for _, c := range r.Changes {
+		results = append(results, map[string]any{
+			"ruleId":  "sync",
+			"level":   "note",
+			"message": map[string]any{"text": c.String()},
+		})
+		used["sync"] = true
+	}
+
+	ids := make([]string, 0, len(used))
+	for id := range used {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	rules := []any{}
+	for _, id := range ids {
+		rules = append(rules, map[string]any{"id": id, "shortDescription": map[string]any{"text": descriptions[id]}})
+	}
+
+	doc := map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []any{map[string]any{
+			"tool": map[string]any{"driver": map[string]any{
+				"name":           "swk " + r.Command,
+				"informationUri": "https://github.com/davidschrooten/secret-wrapper-k8s",
+				"rules":          rules,
+			}},
+			"results": results,
+		}},
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
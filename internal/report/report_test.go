@@ -0,0 +1,102 @@
+package report
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/check"
+)
+
+var testReport = Report{
+	Command: "check",
+	Findings: []check.Finding{
+		{File: "db.yaml", Line: 7, Secret: "prod/db", Key: "password", Rule: "placeholder", Severity: check.Error, Message: "value is a placeholder"},
+	},
+	Changes: []Change{{Secret: "prod/db", Key: "user", Op: "added"}},
+}
+
+func TestRender(t *testing.T) {
+	text, err := Render(testReport, Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "db.yaml:7: error [placeholder] prod/db password: value is a placeholder\nprod/db: + user\n"; string(text) != want {
+		t.Errorf("Render(text) = %q, want %q", text, want)
+	}
+
+	data, err := Render(testReport, JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Report
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Render(json) isn't JSON: %v", err)
+	}
+	if len(got.Findings) != 1 || got.Findings[0].Line != 7 || got.Changes[0].Op != "added" {
+		t.Errorf("Render(json) = %s", data)
+	}
+
+	data, err = Render(testReport, SARIF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID string `json:"ruleId"`
+				Level  string `json:"level"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Render(sarif) isn't JSON: %v", err)
+	}
+	if doc.Version != "2.1.0" || len(doc.Runs) != 1 || len(doc.Runs[0].Results) != 2 || len(doc.Runs[0].Tool.Driver.Rules) != 2 {
+		t.Fatalf("Render(sarif) = %s", data)
+	}
+	if r := doc.Runs[0].Results; r[0].Level != "error" || r[1].RuleID != "sync" || r[1].Level != "note" {
+		t.Errorf("Render(sarif) results = %+v", r)
+	}
+}
+
+func TestParseSink(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Sink
+		wantErr string
+	}{
+		{"-", Sink{"-", Text}, ""},
+		{"out.json", Sink{"out.json", JSON}, ""},
+		{"results.SARIF", Sink{"results.SARIF", SARIF}, ""},
+		{"sarif=-", Sink{"-", SARIF}, ""},
+		{"json=https://dash.example.com/hook?a=b", Sink{"https://dash.example.com/hook?a=b", JSON}, ""},
+		{"https://dash.example.com/hook?a=b", Sink{"https://dash.example.com/hook?a=b", Text}, ""},
+		{"s3://reports/ci/run=1.json", Sink{"s3://reports/ci/run=1.json", JSON}, ""},
+		{"xml=out.xml", Sink{}, "unknown report format"},
+		{"json=", Sink{}, "no destination"},
+		{"s3:///key.json", Sink{}, "no bucket"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseSink(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseSink() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseSink() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
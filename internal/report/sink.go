@@ -0,0 +1,176 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/safefile"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/sigv4"
+)
+
+// Client sends reports to webhooks and S3; tests replace it
+var Client = &http.Client{Timeout: 30 * time.Second}
+
+// now is replaced in tests
+var now = time.Now
+
+// Sink is where a report goes: - for stdout, a file, an http(s) webhook,
+// or an s3://BUCKET/KEY URL
+type Sink struct {
+	Dest   string
+	Format Format
+}
+
+// ParseSink reads a sink given as [FORMAT=]DEST, e.g. sarif=out.sarif or
+// json=https://dashboard.example.com/hook. Without a format, it's picked
+// from the extension of DEST.
+func ParseSink(spec string) (Sink, error) {
+	if name, dest, ok := strings.Cut(spec, "="); ok && !strings.Contains(name, "/") && !strings.Contains(name, ":") {
+		return NewSink(dest, name)
+	}
+	return NewSink(spec, "")
+}
+
+// NewSink checks a sink's destination and format, picking the format from
+// the extension of dest when format is empty
+func NewSink(dest, format string) (Sink, error) {
+	if dest == "" {
+		return Sink{}, fmt.Errorf("report sink has no destination")
+	}
+	s := Sink{Dest: dest, Format: formatFor(dest)}
+	if format != "" {
+		f, err := ParseFormat(format)
+		if err != nil {
+			return Sink{}, err
+		}
+		s.Format = f
+	}
+	if bucket, _, _ := strings.Cut(strings.TrimPrefix(dest, "s3://"), "/"); strings.HasPrefix(dest, "s3://") && bucket == "" {
+		return Sink{}, fmt.Errorf("report sink %s has no bucket", dest)
+	}
+	return s, nil
+}
+
+// String formats a sink as FORMAT=DEST
+func (s Sink) String() string {
+	return string(s.Format) + "=" + s.Dest
+}
+
+// Write renders a report in the sink's format and sends it to its
+// destination, with stdout used for -
+func (s Sink) Write(ctx context.Context, r Report, stdout io.Writer) error {
+	data, err := Render(r, s.Format)
+	if err != nil {
+		return err
+	}
+	switch {
+	case s.Dest == "-":
+		_, err = stdout.Write(data)
+		return err
+	case strings.HasPrefix(s.Dest, "http://"), strings.HasPrefix(s.Dest, "https://"):
+		return s.post(ctx, data)
+	case strings.HasPrefix(s.Dest, "s3://"):
+		return s.put(ctx, data)
+	}
+	if err := safefile.WriteFile(s.Dest, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report to %s: %w", s.Dest, err)
+	}
+	return nil
+}
+
+// contentType is the media type of the sink's format
+func (s Sink) contentType() string {
+	switch s.Format {
+	case JSON:
+		return "application/json"
+	case SARIF:
+		return "application/sarif+json"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// post sends a report to a webhook
+func (s Sink) post(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Dest, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", s.contentType())
+	return send(req, redactURL(s.Dest))
+}
+
+// put uploads a report to S3 or an S3-compatible store, with credentials,
+// region, and endpoint from the usual AWS environment variables
+func (s Sink) put(ctx context.Context, data []byte) error {
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return fmt.Errorf("failed to send report to %s: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set", s.Dest)
+	}
+	region := firstEnv("us-east-1", "AWS_REGION", "AWS_DEFAULT_REGION")
+	endpoint := strings.TrimSuffix(firstEnv("https://s3."+region+".amazonaws.com", "AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"), "/")
+
+	// Path-style addressing works for buckets with dots and for most
+	// S3-compatible stores
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(s.Dest, "s3://"), "/")
+	target := endpoint + "/" + url.PathEscape(bucket) + "/" + (&url.URL{Path: key}).EscapedPath()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", s.contentType())
+	req.Header.Set("X-Amz-Content-Sha256", sigv4.PayloadHash(data))
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+	sigv4.Sign(req, accessKey, secretKey, region, "s3", now())
+	return send(req, s.Dest)
+}
+
+// send makes a request, failing on anything but a 2xx response
+func send(req *http.Request, dest string) error {
+	resp, err := Client.Do(req)
+	if err != nil {
+		// The error would repeat the full URL
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to send report to %s: %w", dest, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to send report to %s: %s: %s", dest, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// firstEnv returns the first of the environment variables that is set, or
+// fallback
+func firstEnv(fallback string, names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return fallback
+}
+
+// redactURL hides a webhook's query and credentials, which often hold a
+// token, in error messages
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "webhook"
+	}
+	u.User, u.RawQuery = nil, ""
+	return u.String()
+}
@@ -0,0 +1,87 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSinkWrite(t *testing.T) {
+	var got struct {
+		method, path, contentType, auth, token string
+		body                                   []byte
+	}
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.method, got.path = r.Method, r.URL.Path
+		got.contentType, got.auth = r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		got.token = r.Header.Get("X-Amz-Security-Token")
+		got.body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	old := now
+	now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	t.Cleanup(func() { now = old })
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "session")
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+
+	t.Run("stdout", func(t *testing.T) {
+		var stdout bytes.Buffer
+		if err := (Sink{"-", Text}).Write(context.Background(), testReport, &stdout); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(stdout.String(), "prod/db: + user") {
+			t.Errorf("stdout = %q", stdout.String())
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "out.json")
+		if err := (Sink{path, JSON}).Write(context.Background(), testReport, io.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if data, err := os.ReadFile(path); err != nil || !strings.Contains(string(data), `"command": "check"`) {
+			t.Errorf("file = %s, %v", data, err)
+		}
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		if err := (Sink{server.URL + "/hook", SARIF}).Write(context.Background(), testReport, io.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if got.method != http.MethodPost || got.path != "/hook" || got.contentType != "application/sarif+json" || !bytes.Contains(got.body, []byte(`"2.1.0"`)) {
+			t.Errorf("request = %s %s %s %s", got.method, got.path, got.contentType, got.body)
+		}
+	})
+
+	t.Run("s3", func(t *testing.T) {
+		if err := (Sink{"s3://reports/ci/run 1.json", JSON}).Write(context.Background(), testReport, io.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if got.method != http.MethodPut || got.path != "/reports/ci/run 1.json" || got.token != "session" {
+			t.Errorf("request = %s %s, token %q", got.method, got.path, got.token)
+		}
+		if !strings.HasPrefix(got.auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20260102/eu-west-1/s3/aws4_request") {
+			t.Errorf("Authorization = %s", got.auth)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		status = http.StatusForbidden
+		err := (Sink{server.URL + "/hook?token=hunter2", JSON}).Write(context.Background(), testReport, io.Discard)
+		if err == nil || !strings.Contains(err.Error(), "403") || strings.Contains(err.Error(), "hunter2") {
+			t.Errorf("Write() error = %v, want a 403 without the token", err)
+		}
+	})
+}
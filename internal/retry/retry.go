@@ -0,0 +1,34 @@
+// Package retry holds the backoff and waiting shared by the clients that
+// retry throttled or failed requests, such as kubectl calls and syncs to
+// external stores
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Backoff returns the delay before the given retry attempt, counting from
+// 1: base, doubling each time up to limit, with up to 50% jitter
+func Backoff(attempt int, base, limit time.Duration) time.Duration {
+	d := base << (attempt - 1)
+	if d > limit || d <= 0 {
+		d = limit
+	}
+	jitter := time.Duration(rand.Int63n(int64(d) / 2))
+	return d/2 + jitter
+}
+
+// Sleep waits for d or until the context is cancelled
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,34 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	base, limit := 250*time.Millisecond, 8*time.Second
+	for attempt := 1; attempt < 70; attempt++ {
+		want := base
+		for i := 1; i < attempt && want < limit; i++ {
+			want *= 2
+		}
+		want = min(want, limit)
+		d := Backoff(attempt, base, limit)
+		if d < want/2 || d > want {
+			t.Errorf("Backoff(%d) = %v, want within [%v, %v]", attempt, d, want/2, want)
+		}
+	}
+}
+
+func TestSleep(t *testing.T) {
+	if err := Sleep(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("Sleep() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Sleep(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Sleep() with a cancelled context error = %v, want context.Canceled", err)
+	}
+}
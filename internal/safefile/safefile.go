@@ -0,0 +1,331 @@
+// Package safefile resolves and writes the files swk edits in place, so
+// that symlinks can't redirect a write somewhere unexpected
+package safefile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Resolve returns the real path of a file that is about to be edited in
+// place, following symlinks. A file inside the working tree, or a symlink
+// anywhere, must resolve to a path inside the working tree unless
+// followOutside is set, so a link in a repository can't point an edit at,
+// say, ~/.kube/config. Files that can't be written, e.g. because a symlink
+// leads into a read-only mount, are reported before any editing happens.
+func Resolve(path string, followOutside bool) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Lstat(abs)
+	if errors.Is(err, fs.ErrNotExist) {
+		// A new file: only its directory can be a link
+		dir, err := filepath.EvalSymlinks(filepath.Dir(abs))
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		real := filepath.Join(dir, filepath.Base(abs))
+		return real, checkInside(path, abs, real, false, followOutside)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	isLink := info.Mode()&fs.ModeSymlink != 0
+	if err := checkInside(path, abs, real, isLink, followOutside); err != nil {
+		return "", err
+	}
+
+	target, err := os.Stat(real)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	if !target.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a regular file", path)
+	}
+	if err := checkWritable(path, real, isLink); err != nil {
+		return "", err
+	}
+	return real, nil
+}
+
+// checkInside enforces that links resolve within the working tree
+func checkInside(path, abs, real string, isLink, followOutside bool) error {
+	if followOutside || real == abs {
+		return nil
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	realWd, err := filepath.EvalSymlinks(wd)
+	if err != nil {
+		return err
+	}
+	if !isLink && !within(abs, wd) {
+		// Outside the tree and not itself a link, e.g. a temp file from
+		// kubectl edit in a symlinked temp directory
+		return nil
+	}
+	if within(real, realWd) {
+		return nil
+	}
+	return fmt.Errorf("%s resolves to %s, outside the working tree; use --follow-symlinks to edit it anyway", path, real)
+}
+
+// checkWritable fails early for files that can't be written back
+func checkWritable(path, real string, isLink bool) error {
+	f, err := os.OpenFile(real, os.O_WRONLY, 0)
+	if err == nil {
+		return f.Close()
+	}
+
+	via := ""
+	if isLink {
+		via = fmt.Sprintf(" (%s links to %s)", path, real)
+	}
+	if errors.Is(err, syscall.EROFS) {
+		return fmt.Errorf("%s is on a read-only file system%s; edit the source it is mounted from instead", path, via)
+	}
+	return fmt.Errorf("%s is not writable%s: %w", path, via, err)
+}
+
+// within reports whether path is dir or below it
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// WriteFile replaces the file at path, normally a path returned by Resolve,
+// by writing a temp file next to it and renaming it into place, so readers
+// never see a partial file. The temp file and then the directory are
+// synced, so a crash leaves either the old file or the new one, never a
+// truncated one. An existing file keeps its mode; a new one is created
+// with perm less the umask. If the directory doesn't allow creating the
+// temp file, the file is written in place instead.
+func WriteFile(path string, data []byte, perm fs.FileMode) error {
+	return writeFile(path, data, perm, false)
+}
+
+// WriteFileMode is WriteFile, but sets mode exactly on both new and existing
+// files, e.g. to tighten a manifest to 0600 as part of a save
+func WriteFileMode(path string, data []byte, mode fs.FileMode) error {
+	return writeFile(path, data, mode, true)
+}
+
+func writeFile(path string, data []byte, perm fs.FileMode, explicit bool) error {
+	s, err := stage(path, data, perm, explicit)
+	if err != nil {
+		return err
+	}
+	return s.Commit()
+}
+
+// Staged is a file written next to its target but not yet renamed into
+// place, so that several files can be replaced together or not at all
+type Staged struct {
+	path    string
+	tmpPath string // empty if the file is to be written in place
+	data    []byte
+	perm    fs.FileMode
+	keep    fs.FileMode
+}
+
+// Stage writes data to a temp file next to path, as WriteFile does, but
+// leaves path as it is until Commit
+func Stage(path string, data []byte, perm fs.FileMode) (*Staged, error) {
+	return stage(path, data, perm, false)
+}
+
+// StageMode is Stage, but sets mode exactly, as WriteFileMode does
+func StageMode(path string, data []byte, mode fs.FileMode) (*Staged, error) {
+	return stage(path, data, mode, true)
+}
+
+func stage(path string, data []byte, perm fs.FileMode, explicit bool) (*Staged, error) {
+	// keep is the mode the file ends up with: the existing file's, or perm
+	// less the umask for a new one
+	keep := perm &^ umask
+	if explicit {
+		keep = perm
+	} else if info, err := os.Stat(path); err == nil {
+		keep = info.Mode().Perm()
+	}
+	s := &Staged{path: path, data: data, perm: perm, keep: keep}
+
+	// The temp file only allows its owner in until the data is written, so
+	// that a private file is never readable by others, however briefly
+	created := keep & 0600
+	tmp, tmpPath, err := createTemp(filepath.Dir(path), "."+filepath.Base(path)+".swk-", created)
+	if errors.Is(err, fs.ErrPermission) {
+		// Written in place on Commit, the best such a directory allows
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+	if keep != created {
+		if err := os.Chmod(tmpPath, keep); err != nil {
+			_ = os.Remove(tmpPath)
+			return nil, err
+		}
+	}
+	s.tmpPath = tmpPath
+	return s, nil
+}
+
+// Path returns the file the staged file replaces
+func (s *Staged) Path() string {
+	return s.path
+}
+
+// Commit renames the staged file into place and syncs its directory, so
+// the rename survives a crash
+func (s *Staged) Commit() error {
+	if s.tmpPath == "" {
+		return writeInPlace(s.path, s.data, s.perm, s.keep)
+	}
+	if err := os.Rename(s.tmpPath, s.path); err != nil {
+		_ = os.Remove(s.tmpPath)
+		return err
+	}
+	return syncDir(filepath.Dir(s.path))
+}
+
+// Discard removes the staged file, leaving its target as it is
+func (s *Staged) Discard() {
+	if s.tmpPath != "" {
+		_ = os.Remove(s.tmpPath)
+	}
+}
+
+// CommitAll renames staged files into place. Once the files are written, a
+// rename rarely fails; if one does, the rest are discarded and the files
+// already replaced get their earlier contents back, so that either all of
+// them change or none do.
+func CommitAll(staged []*Staged) error {
+	type previous struct {
+		data    []byte
+		mode    fs.FileMode
+		existed bool
+	}
+	before := make([]previous, len(staged))
+	for i, s := range staged {
+		info, err := os.Stat(s.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		data, readErr := os.ReadFile(s.path)
+		if err != nil || readErr != nil {
+			for _, s := range staged {
+				s.Discard()
+			}
+			return fmt.Errorf("failed to read %s: %w", s.path, errors.Join(err, readErr))
+		}
+		before[i] = previous{data, info.Mode().Perm(), true}
+	}
+
+	for i, s := range staged {
+		err := s.Commit()
+		if err == nil {
+			continue
+		}
+		for _, rest := range staged[i+1:] {
+			rest.Discard()
+		}
+		for j := i - 1; j >= 0; j-- {
+			var undo error
+			if before[j].existed {
+				undo = WriteFileMode(staged[j].path, before[j].data, before[j].mode)
+			} else {
+				undo = os.Remove(staged[j].path)
+			}
+			if undo != nil {
+				err = fmt.Errorf("%w; failed to restore %s: %v", err, staged[j].path, undo)
+			}
+		}
+		return fmt.Errorf("failed to write %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// createTemp creates a new file with a random suffix, like os.CreateTemp,
+// but with perm (less the umask) instead of 0600
+func createTemp(dir, prefix string, perm fs.FileMode) (*os.File, string, error) {
+	for range 10 {
+		path := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 36))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return f, path, err
+	}
+	return nil, "", fmt.Errorf("failed to create temp file in %s", dir)
+}
+
+// syncDir flushes a directory's entries to disk. Filesystems that can't
+// sync directories are left to flush them when they do.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) && !errors.Is(err, errors.ErrUnsupported) {
+		return fmt.Errorf("failed to sync %s: %w", dir, err)
+	}
+	return nil
+}
+
+// writeInPlace overwrites path directly, for directories swk can't create
+// files in. It is the one write that a crash can leave partial, so the
+// data is synced before it returns.
+func writeInPlace(path string, data []byte, perm, keep fs.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if keep != 0 {
+		return os.Chmod(path, keep)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package safefile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tree := t.TempDir()
+	outside := t.TempDir()
+	t.Chdir(tree)
+
+	write := func(path string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := func(target, name string) {
+		t.Helper()
+		if err := os.Symlink(target, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(filepath.Join(tree, "secret.yaml"))
+	write(filepath.Join(outside, "config"))
+	if err := os.Mkdir(filepath.Join(tree, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	link(filepath.Join(tree, "secret.yaml"), filepath.Join(tree, "sub", "inside.yaml"))
+	link(filepath.Join(outside, "config"), filepath.Join(tree, "escape.yaml"))
+	link(outside, filepath.Join(tree, "linked-dir"))
+
+	realTree, _ := filepath.EvalSymlinks(tree)
+	realOutside, _ := filepath.EvalSymlinks(outside)
+
+	tests := []struct {
+		name          string
+		path          string
+		followOutside bool
+		want          string
+		wantErr       string
+	}{
+		{"plain file", "secret.yaml", false, filepath.Join(realTree, "secret.yaml"), ""},
+		{"link inside the tree", "sub/inside.yaml", false, filepath.Join(realTree, "secret.yaml"), ""},
+		{"link out of the tree", "escape.yaml", false, "", "outside the working tree"},
+		{"link out of the tree allowed", "escape.yaml", true, filepath.Join(realOutside, "config"), ""},
+		{"linked directory", "linked-dir/config", false, "", "outside the working tree"},
+		{"new file", "new.yaml", false, filepath.Join(realTree, "new.yaml"), ""},
+		{"new file in linked directory", "linked-dir/new.yaml", false, "", "outside the working tree"},
+		{"file outside the tree", filepath.Join(outside, "config"), false, filepath.Join(realOutside, "config"), ""},
+		{"directory", "sub", false, "", "not a regular file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(tt.path, tt.followOutside)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveNotWritable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write read-only files")
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile("locked.yaml", []byte("data"), 0444); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Resolve("locked.yaml", false); err == nil || !strings.Contains(err.Error(), "not writable") {
+		t.Errorf("Resolve() error = %v, want not writable", err)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.yaml")
+	if err := os.WriteFile(target, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.yaml")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	real, err := Resolve(link, true)
+	if err != nil {
+		t.Fatalf("Resolve() failed: %v", err)
+	}
+	if err := WriteFile(real, []byte("new"), 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+
+	// The link is left alone and the target replaced, keeping its mode
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Error("WriteFile() replaced the symlink")
+	}
+	content, _ := os.ReadFile(target)
+	if string(content) != "new" {
+		t.Errorf("target = %q, want %q", content, "new")
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("WriteFile() left %d files behind, want 2", len(entries))
+	}
+
+	created := filepath.Join(dir, "created.yaml")
+	if err := WriteFile(created, []byte("x"), 0640); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	if info, _ := os.Stat(created); info.Mode().Perm() != 0640 {
+		t.Errorf("new file mode = %v, want 0640", info.Mode().Perm())
+	}
+}
+
+func TestWriteFileInPlace(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can create files in any directory")
+	}
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.yaml")
+	if err := os.WriteFile(target, []byte("a longer old value"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(dir, 0755) })
+
+	// No temp file can be created, so the file is overwritten and truncated
+	if err := WriteFile(target, []byte("new"), 0644); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "new" {
+		t.Errorf("target = %q, want %q", content, "new")
+	}
+}
+
+func TestWriteFileUmask(t *testing.T) {
+	old := umask
+	umask = 027
+	t.Cleanup(func() { umask = old })
+
+	path := filepath.Join(t.TempDir(), "new.yaml")
+	if err := WriteFile(path, []byte("x"), 0666); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+}
+
+func TestWriteFileMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.yaml")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileMode(path, []byte("new"), 0600); err != nil {
+		t.Fatalf("WriteFileMode() failed: %v", err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	content, _ := os.ReadFile(path)
+	if string(content) != "new" {
+		t.Errorf("content = %q, want %q", content, "new")
+	}
+}
+
+func TestCommitAll(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")
+	for _, path := range []string{a, b} {
+		if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stageAll := func(paths ...string) []*Staged {
+		var staged []*Staged
+		for _, path := range paths {
+			s, err := Stage(path, []byte("new"), 0644)
+			if err != nil {
+				t.Fatalf("Stage() failed: %v", err)
+			}
+			staged = append(staged, s)
+		}
+		return staged
+	}
+
+	staged := stageAll(a, b)
+	if content, _ := os.ReadFile(a); string(content) != "old" {
+		t.Errorf("a = %q before CommitAll(), want it unchanged", content)
+	}
+	if err := CommitAll(staged); err != nil {
+		t.Fatalf("CommitAll() failed: %v", err)
+	}
+	for _, path := range []string{a, b} {
+		if content, _ := os.ReadFile(path); string(content) != "new" {
+			t.Errorf("%s = %q, want %q", filepath.Base(path), content, "new")
+		}
+	}
+
+	// A rename that fails halfway puts back the files already replaced
+	other := filepath.Join(t.TempDir(), "c.yaml")
+	if err := os.WriteFile(a, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	staged = stageAll(a, other)
+	if err := os.RemoveAll(filepath.Dir(other)); err != nil {
+		t.Fatal(err)
+	}
+	if err := CommitAll(staged); err == nil {
+		t.Fatal("CommitAll() succeeded, want an error")
+	}
+	if content, _ := os.ReadFile(a); string(content) != "old" {
+		t.Errorf("a = %q, want it restored", content)
+	}
+	if info, _ := os.Stat(a); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("CommitAll() left %d files behind, want 2", len(entries))
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package safefile
+
+import "io/fs"
+
+// umask is zero where there is no file mode creation mask, such as on
+// Windows
+var umask fs.FileMode
@@ -0,0 +1,19 @@
+//go:build unix
+
+package safefile
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// umask is the file mode creation mask new files get their mode from
+var umask = readUmask()
+
+// readUmask returns the file mode creation mask. Reading it means setting
+// it, so it's only done once, while the package is initialized.
+func readUmask() fs.FileMode {
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return fs.FileMode(mask)
+}
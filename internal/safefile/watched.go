@@ -0,0 +1,93 @@
+package safefile
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// projectedVolumes are the kubelet's directory names for Secret volumes
+var projectedVolumes = []string{"kubernetes.io~secret", "kubernetes.io~projected"}
+
+// Watched fails for files that something else keeps in sync, so editing
+// them directly never sticks: files in a kubelet-projected Secret volume,
+// and files below a directory matching one of patterns, which name the
+// directories local controllers watch. real is the path from Resolve and
+// path is what the user gave.
+func Watched(path, real string, patterns []string) error {
+	if projected(path) || projected(real) {
+		return projectedError(path)
+	}
+
+	for dir := real; ; dir = filepath.Dir(dir) {
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, dir); ok {
+				return fmt.Errorf("%s is in %s, which a local controller watches (pattern %q), so it would act on every save or revert the edit; edit the source it syncs from, or use --allow-watched", path, dir, pattern)
+			}
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return nil
+		}
+	}
+}
+
+// Projected fails for a file in a kubelet-projected Secret volume. Unlike
+// Watched it needs no resolved path, so it can run before Resolve, which
+// fails on the read-only mount of a pod without saying why.
+func Projected(path string) error {
+	if projected(path) {
+		return projectedError(path)
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil && projected(real) {
+		return projectedError(path)
+	}
+	return nil
+}
+
+func projectedError(path string) error {
+	return fmt.Errorf("%s is in a kubelet-projected Secret volume, which the kubelet rewrites from the API server; edit the Secret with kubectl edit instead", path)
+}
+
+// projected reports whether path lies in a kubelet volume, either by the
+// kubelet's own path on a node or by the layout of the kubelet's atomic
+// writer inside a pod: each key is a link to ..data/KEY, where ..data is a
+// link to the current ..TIMESTAMP directory
+func projected(path string) bool {
+	if path == "" {
+		return false
+	}
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		for _, volume := range projectedVolumes {
+			if part == volume {
+				return true
+			}
+		}
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	// The file as mounted, with ..data next to it
+	if isLink(filepath.Join(filepath.Dir(abs), "..data")) {
+		return true
+	}
+	// The file the links lead to, below the directory ..data points at
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		if target, err := os.Readlink(filepath.Join(parent, "..data")); err == nil && filepath.Base(target) == filepath.Base(dir) {
+			return true
+		}
+	}
+}
+
+// isLink reports whether path is a symlink
+func isLink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&fs.ModeSymlink != 0
+}
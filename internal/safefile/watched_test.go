@@ -0,0 +1,107 @@
+package safefile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWatched(t *testing.T) {
+	// A pod's Secret mount, as laid out by the kubelet's atomic writer
+	mount := t.TempDir()
+	stamp := filepath.Join(mount, "..2026_01_02_03_04_05.123")
+	if err := os.Mkdir(stamp, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stamp, "password"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Base(stamp), filepath.Join(mount, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..data", "password"), filepath.Join(mount, "password")); err != nil {
+		t.Fatal(err)
+	}
+	// A key mounted below a directory of its own, as items with a path do
+	if err := os.MkdirAll(filepath.Join(stamp, "db"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stamp, "db", "password"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A plain directory next to the links, and one with a ..data file that
+	// isn't the kubelet's link
+	other := filepath.Join(mount, "other")
+	if err := os.Mkdir(other, 0755); err != nil {
+		t.Fatal(err)
+	}
+	lookalike := t.TempDir()
+	if err := os.WriteFile(filepath.Join(lookalike, "..data"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		real     string
+		patterns []string
+		want     string
+	}{
+		{"plain file", "/home/me/repo/secret.yaml", nil, ""},
+		{"node secret volume", "/var/lib/kubelet/pods/abc/volumes/kubernetes.io~secret/creds/password", nil, "kubelet-projected"},
+		{"node projected volume", "/var/lib/kubelet/pods/abc/volumes/kubernetes.io~projected/all/token", nil, "kubelet-projected"},
+		{"pod mount", filepath.Join(stamp, "password"), nil, "kubelet-projected"},
+		{"pod mount below a directory", filepath.Join(stamp, "db", "password"), nil, "kubelet-projected"},
+		{"next to a pod mount", filepath.Join(other, "secret.yaml"), nil, ""},
+		{"..data that isn't a link", filepath.Join(lookalike, "secret.yaml"), nil, ""},
+		{"watched directory", "/srv/gitops/app/secret.yaml", []string{"/srv/gitops/*"}, `/srv/gitops/app, which a local controller watches (pattern "/srv/gitops/*")`},
+		{"watched file", "/srv/drop/secret.yaml", []string{"/srv/drop/*.yaml"}, "--allow-watched"},
+		{"not watched", "/srv/other/secret.yaml", []string{"/srv/gitops/*"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Watched("secret.yaml", tt.real, tt.patterns)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Watched() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Watched() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjected(t *testing.T) {
+	mount := t.TempDir()
+	stamp := filepath.Join(mount, "..2026_01_02_03_04_05.123")
+	if err := os.Mkdir(stamp, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stamp, "password"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Base(stamp), filepath.Join(mount, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..data", "password"), filepath.Join(mount, "password")); err != nil {
+		t.Fatal(err)
+	}
+	// A link to the mounted file from elsewhere
+	link := filepath.Join(t.TempDir(), "password")
+	if err := os.Symlink(filepath.Join(mount, "password"), link); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{filepath.Join(mount, "password"), link} {
+		if err := Projected(path); err == nil || !strings.Contains(err.Error(), "kubelet-projected") {
+			t.Errorf("Projected(%s) error = %v, want a projected volume error", path, err)
+		}
+	}
+	if err := Projected(filepath.Join(t.TempDir(), "secret.yaml")); err != nil {
+		t.Errorf("Projected() of a plain file error = %v", err)
+	}
+}
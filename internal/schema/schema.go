@@ -0,0 +1,287 @@
+package schema
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/safefile"
+)
+
+// bundled is the OpenAPI v3 subset for core/v1 Secrets and related types,
+// used when no schema has been fetched from a cluster
+//
+//go:embed schemas/v1.json
+var bundled []byte
+
+// rootKinds are the core/v1 kinds whose schemas are kept when extracting
+// from a cluster's OpenAPI document
+var rootKinds = []string{"Secret", "ConfigMap"}
+
+// Schema is the subset of an OpenAPI v3 schema object swk understands
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	GroupVersionKind     []GroupVersionKind `json:"x-kubernetes-group-version-kind,omitempty"`
+}
+
+// GroupVersionKind identifies the Kubernetes type a schema describes
+type GroupVersionKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// APIVersion returns the apiVersion string for the group and version
+func (g GroupVersionKind) APIVersion() string {
+	if g.Group == "" {
+		return g.Version
+	}
+	return g.Group + "/" + g.Version
+}
+
+// document is the top-level layout of an OpenAPI v3 document
+type document struct {
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// Registry holds a set of schemas and resolves them by apiVersion and kind
+type Registry struct {
+	// Source describes where the schemas were loaded from
+	Source  string
+	schemas map[string]*Schema
+}
+
+// Parse builds a registry from an OpenAPI v3 document
+func Parse(data []byte) (*Registry, error) {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	if len(doc.Components.Schemas) == 0 {
+		return nil, errors.New("OpenAPI document contains no schemas")
+	}
+	return &Registry{schemas: doc.Components.Schemas}, nil
+}
+
+// Bundled returns the registry compiled into the binary
+func Bundled() *Registry {
+	r, err := Parse(bundled)
+	if err != nil {
+		panic(fmt.Sprintf("bundled schema is invalid: %v", err))
+	}
+	r.Source = "bundled"
+	return r
+}
+
+// Load returns the schemas last fetched with `swk schema update`, falling
+// back to the bundled schemas when none have been fetched
+func Load() (*Registry, error) {
+	path, err := CachePath()
+	if err != nil {
+		return Bundled(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Bundled(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached schema: %w", err)
+	}
+
+	r, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("cached schema %s: %w", path, err)
+	}
+	r.Source = path
+	return r, nil
+}
+
+// Document returns the schema document Load uses: the one last fetched
+// with `swk schema update`, or the bundled one
+func Document() ([]byte, error) {
+	if path, err := CachePath(); err == nil {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read cached schema: %w", err)
+		}
+	}
+	return bundled, nil
+}
+
+// CachePath returns where refreshed schemas are stored
+func CachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "swk", "schemas", "v1.json"), nil
+}
+
+// Save writes an extracted schema document to the cache path
+func Save(data []byte) (string, error) {
+	if _, err := Parse(data); err != nil {
+		return "", err
+	}
+
+	path, err := CachePath()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine cache directory: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := safefile.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write schema: %w", err)
+	}
+	return path, nil
+}
+
+// Lookup finds the schema for the given apiVersion and kind
+func (r *Registry) Lookup(apiVersion, kind string) *Schema {
+	for _, name := range r.Names() {
+		s := r.schemas[name]
+		for _, gvk := range s.GroupVersionKind {
+			if gvk.Kind == kind && gvk.APIVersion() == apiVersion {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// Names returns the names of all schemas in the registry, sorted
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.schemas))
+	for name := range r.schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolve follows $ref and single-element allOf wrappers to the schema they
+// point at
+func (r *Registry) resolve(s *Schema) *Schema {
+	for i := 0; s != nil && i < 32; i++ {
+		switch {
+		case s.Ref != "":
+			s = r.schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+		case len(s.AllOf) == 1 && s.Type == "" && s.Properties == nil:
+			s = s.AllOf[0]
+		default:
+			return s
+		}
+	}
+	return s
+}
+
+// Extract reduces a cluster's /openapi/v3/api/v1 document to the Secret and
+// ConfigMap schemas plus every schema they reference
+func Extract(openapi []byte) ([]byte, error) {
+	var doc struct {
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(openapi, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+
+	all := doc.Components.Schemas
+	keep := make(map[string]json.RawMessage)
+	var queue []string
+
+	for name, raw := range all {
+		var s Schema
+		if err := json.Unmarshal(raw, &s); err != nil {
+			continue
+		}
+		for _, gvk := range s.GroupVersionKind {
+			if gvk.Group == "" && gvk.Version == "v1" && contains(rootKinds, gvk.Kind) {
+				queue = append(queue, name)
+			}
+		}
+	}
+	if len(queue) == 0 {
+		return nil, errors.New("OpenAPI document does not describe core/v1 Secrets")
+	}
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, done := keep[name]; done {
+			continue
+		}
+		raw, ok := all[name]
+		if !ok {
+			return nil, fmt.Errorf("OpenAPI document references missing schema %q", name)
+		}
+		keep[name] = raw
+		queue = append(queue, refs(raw)...)
+	}
+
+	var out struct {
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	out.Components.Schemas = keep
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// refs returns the schema names referenced anywhere inside a raw schema
+func refs(raw json.RawMessage) []string {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil
+	}
+
+	var names []string
+	var walk func(any)
+	walk = func(v any) {
+		switch t := v.(type) {
+		case map[string]any:
+			for k, child := range t {
+				if ref, ok := child.(string); ok && k == "$ref" {
+					names = append(names, strings.TrimPrefix(ref, "#/components/schemas/"))
+					continue
+				}
+				walk(child)
+			}
+		case []any:
+			for _, child := range t {
+				walk(child)
+			}
+		}
+	}
+	walk(v)
+	return names
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBundledLookup(t *testing.T) {
+	r := Bundled()
+
+	tests := []struct {
+		apiVersion string
+		kind       string
+		wantNil    bool
+	}{
+		{"v1", "Secret", false},
+		{"v1", "ConfigMap", false},
+		{"v2", "Secret", true},
+		{"v1", "Deployment", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.apiVersion+"/"+tt.kind, func(t *testing.T) {
+			if got := r.Lookup(tt.apiVersion, tt.kind); (got == nil) != tt.wantNil {
+				t.Errorf("Lookup(%q, %q) nil = %v, wantNil %v", tt.apiVersion, tt.kind, got == nil, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse([]byte("not json")); err == nil {
+		t.Error("Parse() should fail on invalid JSON")
+	}
+	if _, err := Parse([]byte(`{"components":{"schemas":{}}}`)); err == nil {
+		t.Error("Parse() should fail on empty schema set")
+	}
+}
+
+func TestLoadFallsBackToBundled(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	r, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if r.Source != "bundled" {
+		t.Errorf("Load() source = %q, want bundled", r.Source)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	path, err := Save(bundled)
+	if err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	r, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if r.Source != path {
+		t.Errorf("Load() source = %q, want %q", r.Source, path)
+	}
+	if r.Lookup("v1", "Secret") == nil {
+		t.Error("Load() registry is missing the Secret schema")
+	}
+}
+
+func TestSaveRejectsInvalid(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	if _, err := Save([]byte("{}")); err == nil {
+		t.Error("Save() should reject a document without schemas")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	openapi := `{
+  "components": {
+    "schemas": {
+      "io.k8s.api.core.v1.Secret": {
+        "type": "object",
+        "properties": {
+          "metadata": {"allOf": [{"$ref": "#/components/schemas/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"}]}
+        },
+        "x-kubernetes-group-version-kind": [{"group": "", "kind": "Secret", "version": "v1"}]
+      },
+      "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {"type": "object"},
+      "io.k8s.api.core.v1.Pod": {
+        "type": "object",
+        "x-kubernetes-group-version-kind": [{"group": "", "kind": "Pod", "version": "v1"}]
+      }
+    }
+  }
+}`
+
+	out, err := Extract([]byte(openapi))
+	if err != nil {
+		t.Fatalf("Extract() failed: %v", err)
+	}
+
+	var doc document
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("Extract() produced invalid JSON: %v", err)
+	}
+
+	names := make([]string, 0)
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	if len(names) != 2 {
+		t.Errorf("Extract() kept %v, want Secret and ObjectMeta", names)
+	}
+	if _, ok := doc.Components.Schemas["io.k8s.api.core.v1.Pod"]; ok {
+		t.Error("Extract() should drop unrelated schemas")
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"invalid json", "nope", "failed to parse"},
+		{"no secret", `{"components":{"schemas":{"a":{"type":"object"}}}}`, "does not describe"},
+		{
+			"dangling ref",
+			`{"components":{"schemas":{"s":{"$ref":"#/components/schemas/missing","x-kubernetes-group-version-kind":[{"group":"","kind":"Secret","version":"v1"}]}}}}`,
+			"missing schema",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Extract([]byte(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Extract() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package schema
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError describes a single schema violation
+type FieldError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (e FieldError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s (line %d): %s", e.Path, e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidateManifest validates a YAML manifest against the schema registered
+// for its apiVersion and kind
+func (r *Registry) ValidateManifest(data []byte) ([]FieldError, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("invalid YAML document")
+	}
+	return r.ValidateNode(doc.Content[0])
+}
+
+// ValidateNode validates a parsed manifest mapping node
+func (r *Registry) ValidateNode(root *yaml.Node) ([]FieldError, error) {
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected YAML mapping")
+	}
+
+	apiVersion, kind := scalarField(root, "apiVersion"), scalarField(root, "kind")
+	s := r.Lookup(apiVersion, kind)
+	if s == nil {
+		return nil, fmt.Errorf("no schema for %s %s in %s schemas", apiVersion, kind, r.Source)
+	}
+
+	v := &validator{registry: r}
+	v.validate(root, s, "")
+	return v.errs, nil
+}
+
+type validator struct {
+	registry *Registry
+	errs     []FieldError
+}
+
+func (v *validator) fail(node *yaml.Node, path, format string, args ...any) {
+	if path == "" {
+		path = "."
+	}
+	v.errs = append(v.errs, FieldError{Path: path, Line: node.Line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validate(node *yaml.Node, s *Schema, path string) {
+	s = v.registry.resolve(s)
+	if s == nil {
+		return
+	}
+
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	// Explicit nulls are accepted for any field, as the API server does
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		v.validateObject(node, s, path)
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			v.fail(node, path, "expected array, got %s", describe(node))
+			return
+		}
+		for i, item := range node.Content {
+			v.validate(item, s.Items, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case "string":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!str" {
+			v.fail(node, path, "expected string, got %s", describe(node))
+			return
+		}
+		v.validateFormat(node, s.Format, path)
+	case "integer":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			v.fail(node, path, "expected integer, got %s", describe(node))
+		}
+	case "boolean":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			v.fail(node, path, "expected boolean, got %s", describe(node))
+		}
+	}
+}
+
+func (v *validator) validateObject(node *yaml.Node, s *Schema, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.fail(node, path, "expected object, got %s", describe(node))
+		return
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		seen[key] = true
+		childPath := path + "." + key
+
+		if prop, ok := s.Properties[key]; ok {
+			v.validate(value, prop, childPath)
+		} else if s.AdditionalProperties != nil {
+			v.validate(value, s.AdditionalProperties, childPath)
+		}
+	}
+
+	for _, name := range s.Required {
+		if !seen[name] {
+			v.fail(node, path, "missing required field %q", name)
+		}
+	}
+}
+
+func (v *validator) validateFormat(node *yaml.Node, format, path string) {
+	switch format {
+	case "byte":
+		if _, err := base64.StdEncoding.DecodeString(node.Value); err != nil {
+			v.fail(node, path, "invalid base64 value")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, node.Value); err != nil {
+			v.fail(node, path, "invalid RFC 3339 timestamp %q", node.Value)
+		}
+	}
+}
+
+// describe names a node's YAML type for error messages
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	case yaml.ScalarNode:
+		switch node.Tag {
+		case "!!int", "!!float":
+			return "number"
+		case "!!bool":
+			return "boolean"
+		case "!!str":
+			return "string"
+		}
+		return node.Tag
+	}
+	return "unknown"
+}
+
+// scalarField returns the value of a scalar field in a mapping, or ""
+func scalarField(node *yaml.Node, key string) string {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key && node.Content[i+1].Kind == yaml.ScalarNode {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+// UnknownFields reports top-level fields of a manifest that the schema for
+// apiVersion and kind doesn't define, suggesting the field that was probably
+// meant. The API server drops such fields, so a typo like `datas:` or
+// `stringdata:` silently loses its values.
+func (r *Registry) UnknownFields(root *yaml.Node, apiVersion, kind string) ([]FieldError, error) {
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected YAML mapping")
+	}
+	s := r.resolve(r.Lookup(apiVersion, kind))
+	if s == nil {
+		return nil, fmt.Errorf("no schema for %s %s in %s schemas", apiVersion, kind, r.Source)
+	}
+
+	known := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		known = append(known, name)
+	}
+	sort.Strings(known)
+
+	var errs []FieldError
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i]
+		if _, ok := s.Properties[key.Value]; ok {
+			continue
+		}
+		msg := "unknown field"
+		if match := closest(key.Value, known); match != "" {
+			msg += fmt.Sprintf(" (did you mean %q?)", match)
+		}
+		errs = append(errs, FieldError{Path: "." + key.Value, Line: key.Line, Message: msg})
+	}
+	return errs, nil
+}
+
+// closest returns the candidate nearest to name, ignoring case, if it is
+// close enough to be a likely typo
+func closest(name string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, c := range candidates {
+		if d := distance(strings.ToLower(name), strings.ToLower(c)); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein edit distance between two strings
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr := make([]int, len(b)+1)
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev = curr
+	}
+	return prev[len(b)]
+}
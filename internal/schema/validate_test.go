@@ -0,0 +1,163 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidateManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantErrs []string
+		wantErr  bool
+	}{
+		{
+			name: "valid secret",
+			input: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+  labels:
+    app: web
+  creationTimestamp: "2024-01-01T00:00:00Z"
+type: Opaque
+immutable: false
+data:
+  password: cGFzc3dvcmQxMjM=
+stringData:
+  token: plain
+`,
+		},
+		{
+			name: "invalid base64",
+			input: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+data:
+  password: not base64!
+`,
+			wantErrs: []string{".data.password (line 6): invalid base64 value"},
+		},
+		{
+			name: "wrong types",
+			input: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+  labels: [a, b]
+immutable: "yes"
+stringData:
+  port: 5432
+`,
+			wantErrs: []string{
+				".metadata.labels (line 5): expected object, got array",
+				".immutable (line 6): expected boolean, got string",
+				".stringData.port (line 8): expected string, got number",
+			},
+		},
+		{
+			name: "missing required owner reference fields",
+			input: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+  ownerReferences:
+  - kind: Deployment
+    name: web
+`,
+			wantErrs: []string{
+				`.metadata.ownerReferences[0] (line 6): missing required field "apiVersion"`,
+				`.metadata.ownerReferences[0] (line 6): missing required field "uid"`,
+			},
+		},
+		{
+			name:    "unknown kind",
+			input:   "apiVersion: v1\nkind: Widget\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid yaml",
+			input:   "invalid: yaml: [[[",
+			wantErr: true,
+		},
+		{
+			name:    "not a mapping",
+			input:   "- a\n- b\n",
+			wantErr: true,
+		},
+	}
+
+	r := Bundled()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := r.ValidateManifest([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.wantErrs, "\n") {
+				t.Errorf("ValidateManifest() errors =\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.wantErrs, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantErrs []string
+	}{
+		{
+			name:  "known fields",
+			input: "apiVersion: v1\nkind: Secret\nmetadata:\n  name: test\ntype: Opaque\ndata: {}\nstringData: {}\nimmutable: true\n",
+		},
+		{
+			name:  "typos",
+			input: "apiVersion: v1\nkind: Secret\nmetadata:\n  name: test\ndatas:\n  a: Yg==\nstringdata:\n  b: c\n",
+			wantErrs: []string{
+				`.datas (line 5): unknown field (did you mean "data"?)`,
+				`.stringdata (line 7): unknown field (did you mean "stringData"?)`,
+			},
+		},
+		{
+			name:     "no suggestion",
+			input:    "apiVersion: v1\nkind: Secret\nmetadata:\n  name: test\nspec:\n  x: y\n",
+			wantErrs: []string{".spec (line 5): unknown field"},
+		},
+	}
+
+	r := Bundled()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.input), &doc); err != nil {
+				t.Fatal(err)
+			}
+			errs, err := r.UnknownFields(doc.Content[0], "v1", "Secret")
+			if err != nil {
+				t.Fatalf("UnknownFields() failed: %v", err)
+			}
+
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.wantErrs, "\n") {
+				t.Errorf("UnknownFields() errors =\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.wantErrs, "\n"))
+			}
+		})
+	}
+
+	if _, err := r.UnknownFields(&yaml.Node{Kind: yaml.MappingNode}, "v1", "Bogus"); err == nil {
+		t.Error("UnknownFields() should fail without a schema")
+	}
+}
@@ -0,0 +1,112 @@
+// Package sealed encrypts the files of swk's on-disk stores with AES-GCM,
+// under a key kept in a file of its own that only the user can read, so a
+// copy of a store alone reveals nothing
+package sealed
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// keySize is the size of an AES-256 key
+const keySize = 32
+
+// The errors of Open read as the end of a sentence naming what was opened,
+// e.g. "snapshot 3 of prod/db is damaged"
+var (
+	// ErrDamaged is returned for data too short to have been sealed
+	ErrDamaged = errors.New("is damaged")
+	// ErrWrongKey is returned for data that fails to authenticate, because
+	// it was sealed with another key, for other additional data, or changed
+	ErrWrongKey = errors.New("can't be decrypted with this key")
+)
+
+// Box seals and opens data with one key
+type Box struct {
+	aead cipher.AEAD
+}
+
+// Open loads the key at keyPath, creating it if it doesn't exist. A damaged
+// key is an error, as replacing it would make everything sealed with it
+// unreadable. name says what the key is for in errors, e.g. "snapshot".
+func Open(keyPath, name string) (*Box, error) {
+	return open(keyPath, name, false)
+}
+
+// OpenReplacing is Open, but replaces a damaged key with a new one, for
+// stores whose contents are cheap to rebuild, such as caches
+func OpenReplacing(keyPath, name string) (*Box, error) {
+	return open(keyPath, name, true)
+}
+
+func open(keyPath, name string, replace bool) (*Box, error) {
+	key, err := loadKey(keyPath, name, replace)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// loadKey reads a key, creating it if it doesn't exist, and replacing a
+// damaged one if replace is set
+func loadKey(path, name string, replace bool) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err == nil && len(key) == keySize {
+		return key, nil
+	}
+	if err == nil && !replace {
+		return nil, fmt.Errorf("%s key %s is damaged", name, path)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s key: %w", name, err)
+	}
+
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to write %s key: %w", name, err)
+	}
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write %s key: %w", name, err)
+	}
+	return key, nil
+}
+
+// Seal encrypts plaintext, bound to additionalData, such as the name it is
+// stored under, so that it doesn't open anywhere else. The random nonce is
+// put in front.
+func (b *Box) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return b.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts data returned by Seal with the same additional data
+func (b *Box) Open(sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < b.aead.NonceSize() {
+		return nil, ErrDamaged
+	}
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plaintext, nil
+}
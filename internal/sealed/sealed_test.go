@@ -0,0 +1,78 @@
+package sealed
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBox(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "swk", "test.key")
+	box, err := Open(keyPath, "test")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if info, err := os.Stat(keyPath); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("key file = %v, %v, want mode 0600", info, err)
+	}
+
+	data, err := box.Seal([]byte("hunter2"), []byte("entry"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Error("Seal() left the plaintext readable")
+	}
+
+	// The key is reused, not replaced
+	again, err := Open(keyPath, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Open(filepath.Join(t.TempDir(), "other.key"), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		box     *Box
+		data    []byte
+		ad      string
+		wantErr error
+	}{
+		{"same key", again, data, "entry", nil},
+		{"other additional data", again, data, "moved", ErrWrongKey},
+		{"other key", other, data, "entry", ErrWrongKey},
+		{"truncated", again, data[:4], "entry", ErrDamaged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.box.Open(tt.data, []byte(tt.ad))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Open() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && string(got) != "hunter2" {
+				t.Errorf("Open() = %q, want %q", got, "hunter2")
+			}
+		})
+	}
+}
+
+func TestDamagedKey(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "test.key")
+	if err := os.WriteFile(keyPath, []byte("short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(keyPath, "test"); err == nil {
+		t.Error("Open() with a damaged key succeeded")
+	}
+	if _, err := OpenReplacing(keyPath, "test"); err != nil {
+		t.Errorf("OpenReplacing() error = %v", err)
+	}
+	if key, _ := os.ReadFile(keyPath); len(key) != keySize {
+		t.Errorf("OpenReplacing() left a key of %d bytes, want %d", len(key), keySize)
+	}
+}
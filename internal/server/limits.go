@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/auth"
+)
+
+// DefaultMaxBody caps request bodies if MaxBody is unset
+const DefaultMaxBody = 4 << 20
+
+// idleClient is how long a client's rate limit is remembered after its
+// bucket filled up again
+const idleClient = 10 * time.Minute
+
+// bucket is a client's token bucket
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter allows each client qps requests per second, with bursts
+type limiter struct {
+	mu      sync.Mutex
+	qps     float64
+	burst   float64
+	clients map[string]*bucket
+	now     func() time.Time
+}
+
+func newLimiter(qps float64, burst int, now func() time.Time) *limiter {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(qps)))
+	}
+	return &limiter{qps: qps, burst: float64(burst), clients: map[string]*bucket{}, now: now}
+}
+
+// allow takes a token from client's bucket if one is available, otherwise
+// returns how long until one is
+func (l *limiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b := l.clients[client]
+	if b == nil {
+		l.prune(now)
+		b = &bucket{tokens: l.burst, last: now}
+		l.clients[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.qps)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.qps * float64(time.Second))
+}
+
+// prune forgets clients that have been idle for a while; l.mu must be held
+func (l *limiter) prune(now time.Time) {
+	for client, b := range l.clients {
+		if now.Sub(b.last) > idleClient {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// limit caps request bodies at MaxBody and, if RateLimit is set, refuses
+// requests beyond a client's rate with 429. Clients are told apart by
+// address, as the limit applies before authentication.
+func (s *Server) limit(next http.Handler) http.Handler {
+	var l *limiter
+	if s.RateLimit > 0 {
+		l = newLimiter(s.RateLimit, s.Burst, s.now)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l != nil {
+			if ok, wait := l.allow(clientAddr(r)); !ok {
+				w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "too many requests", http.StatusTooManyRequests)
+				return
+			}
+		}
+		if r.ContentLength > s.maxBody() {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBody())
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) maxBody() int64 {
+	if s.MaxBody > 0 {
+		return s.MaxBody
+	}
+	return DefaultMaxBody
+}
+
+// clientAddr returns the host a request came from
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requestInfo collects what the audit log records about a request
+type requestInfo struct {
+	user string
+}
+
+type requestInfoKey struct{}
+
+// statusWriter remembers the status a handler sent
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// logRequests records every request to Audit, once it is answered. Bodies,
+// headers, and reveal tokens are never logged.
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := &requestInfo{}
+		sw := &statusWriter{ResponseWriter: w}
+		start := s.now()
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)))
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		s.Audit(AuditEvent{
+			Time:     start.UTC(),
+			Event:    "request",
+			Method:   r.Method,
+			Path:     redactPath(r.URL),
+			Status:   sw.status,
+			Duration: s.now().Sub(start).Round(time.Millisecond).String(),
+			By:       info.user,
+			Remote:   r.RemoteAddr,
+		})
+	})
+}
+
+// recordIdentity notes the authenticated caller for the audit log
+func recordIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, _ := r.Context().Value(requestInfoKey{}).(*requestInfo)
+		if id := auth.FromContext(r.Context()); info != nil && id != nil {
+			info.user = id.User
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// redactPath returns the URL path with reveal tokens replaced by a short
+// hash, and the path query parameter, which is all the API takes
+func redactPath(u *url.URL) string {
+	p := u.Path
+	if token, ok := strings.CutPrefix(p, "/v1/reveals/"); ok && token != "" {
+		sum := sha256.Sum256([]byte(token))
+		p = "/v1/reveals/" + hex.EncodeToString(sum[:4])
+	}
+	if file := u.Query().Get("path"); file != "" {
+		p += "?path=" + url.QueryEscape(file)
+	}
+	return p
+}
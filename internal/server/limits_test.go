@@ -0,0 +1,107 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/auth"
+)
+
+// newLimitedServer returns a server configured by configure, with a clock
+// the test moves
+func newLimitedServer(t *testing.T, configure func(*Server)) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "secret.yaml"), []byte(testSecret), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := New(dir, func(path string) (string, error) { return path, nil },
+		func(path string, data []byte) error { return os.WriteFile(path, data, 0600) })
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var elapsed atomic.Int64
+	s.now = func() time.Time { return start.Add(time.Duration(elapsed.Load())) }
+	configure(s)
+
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return ts, &elapsed
+}
+
+func TestRateLimit(t *testing.T) {
+	ts, elapsed := newLimitedServer(t, func(s *Server) {
+		s.RateLimit = 1
+		s.Burst = 2
+	})
+	u := ts.URL + "/v1/queue"
+
+	for i := range 2 {
+		if resp := do(t, http.MethodGet, u, "", ""); resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d status = %d, want 200", i, resp.StatusCode)
+		}
+	}
+	resp := do(t, http.MethodGet, u, "", "")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "1" {
+		t.Errorf("over the limit = %d, Retry-After %q, want 429 after 1s", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+
+	elapsed.Add(int64(time.Second))
+	if resp := do(t, http.MethodGet, u, "", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("after a second status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestMaxBody(t *testing.T) {
+	ts, _ := newLimitedServer(t, func(s *Server) { s.MaxBody = 64 })
+	u := ts.URL + "/v1/files?path=secret.yaml"
+
+	if resp := do(t, http.MethodPut, u, strings.Repeat("x", 65), ""); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("save status = %d, want 413", resp.StatusCode)
+	}
+	if resp := do(t, http.MethodPost, ts.URL+"/v1/reveals", `{"path":"`+strings.Repeat("x", 64)+`"}`, ""); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("reveal status = %d, want 413", resp.StatusCode)
+	}
+}
+
+func TestLogRequests(t *testing.T) {
+	var mu sync.Mutex
+	var events []AuditEvent
+	ts, _ := newLimitedServer(t, func(s *Server) {
+		s.Audit = func(e AuditEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, e)
+		}
+		s.Auth = func(next http.Handler) http.Handler {
+			return auth.Middleware(next, auth.Tokens{"ci": auth.HashToken("t0ken")}, auth.Policy{Rules: anyUser})
+		}
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/files?path="+url.QueryEscape("secret.yaml")+"&x=1", nil)
+	req.Header.Set("Authorization", "Bearer t0ken")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	do(t, http.MethodGet, ts.URL+"/v1/reveals/s3cret-token", "", "")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 {
+		t.Fatalf("events = %+v, want 2", events)
+	}
+	if e := events[0]; e.Event != "request" || e.Path != "/v1/files?path=secret.yaml" || e.Status != http.StatusOK || e.By != "ci" {
+		t.Errorf("first event = %+v, want an OK request by ci", e)
+	}
+	if e := events[1]; strings.Contains(e.Path, "s3cret") || e.Status != http.StatusUnauthorized || e.By != "" {
+		t.Errorf("second event = %+v, want an unauthorized request with the token redacted", e)
+	}
+}
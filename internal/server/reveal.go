@@ -0,0 +1,239 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/auth"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+	"gopkg.in/yaml.v3"
+)
+
+// Reveal token lifetimes: the default, and the longest a client may ask for
+const (
+	DefaultRevealTTL = 5 * time.Minute
+	MaxRevealTTL     = time.Hour
+)
+
+// maskedValue replaces values in read-only mode
+const maskedValue = "********"
+
+// AuditEvent records a request, or a reveal token being issued or redeemed.
+// It never holds values, and Token is a short hash of the token, so the log
+// can't be used to redeem it.
+type AuditEvent struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"` // request, reveal-issued, reveal-redeemed, reveal-refused, or reveal-expired
+	Token string    `json:"token,omitempty"`
+	// Method, Status, and Duration describe requests
+	Method   string `json:"method,omitempty"`
+	Path     string `json:"path"`
+	Status   int    `json:"status,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	Key      string `json:"key,omitempty"`
+	User     string `json:"user,omitempty"`
+	// By is the authenticated caller, if the server requires authentication
+	By     string `json:"by,omitempty"`
+	Remote string `json:"remote"`
+}
+
+// reveal is an issued, not yet redeemed token
+type reveal struct {
+	path    string
+	key     string
+	user    string
+	expires time.Time
+}
+
+// revealRequest is the body of POST /v1/reveals
+type revealRequest struct {
+	Path string `json:"path"`
+	Key  string `json:"key"`
+	// User is the person the value will be shown to
+	User string `json:"user"`
+	TTL  string `json:"ttl"`
+}
+
+// errNoIdentity refuses reveal requests on a server that doesn't
+// authenticate callers, where anyone who can reach it could reveal values
+var errNoIdentity = errors.New("reveal tokens need authenticated callers; configure serve.auth")
+
+// postReveal issues a token that reveals one key once
+func (s *Server) postReveal(w http.ResponseWriter, r *http.Request) {
+	if auth.FromContext(r.Context()) == nil {
+		http.Error(w, errNoIdentity.Error(), http.StatusForbidden)
+		return
+	}
+	var req revealRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Key == "" || req.User == "" {
+		http.Error(w, "key and user are required", http.StatusBadRequest)
+		return
+	}
+	ttl := DefaultRevealTTL
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 || d > MaxRevealTTL {
+			http.Error(w, fmt.Sprintf("invalid ttl %q: want a duration up to %s", req.TTL, MaxRevealTTL), http.StatusBadRequest)
+			return
+		}
+		ttl = d
+	}
+	path, err := s.resolvePath(req.Path)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// Fail now rather than when the token is used, without sending the value
+	if _, err := revealValue(path, req.Key); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	token := hex.EncodeToString(b)
+	expires := s.now().Add(ttl)
+
+	s.mu.Lock()
+	s.prune()
+	s.reveals[token] = reveal{path: path, key: req.Key, user: req.User, expires: expires}
+	s.mu.Unlock()
+	s.audit("reveal-issued", token, path, req.Key, req.User, r)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"token":   token,
+		"url":     "/v1/reveals/" + token,
+		"expires": expires.UTC(),
+	})
+}
+
+// getReveal redeems a token, returning the value it reveals to the user it
+// was issued for. A token works once, so a link that leaks after use
+// reveals nothing, and a link that leaks before reveals nothing to anyone
+// else.
+func (s *Server) getReveal(w http.ResponseWriter, r *http.Request) {
+	id := auth.FromContext(r.Context())
+	if id == nil {
+		http.Error(w, errNoIdentity.Error(), http.StatusForbidden)
+		return
+	}
+	token := r.PathValue("token")
+	s.mu.Lock()
+	rv, ok := s.reveals[token]
+	if ok && rv.user != id.User {
+		s.mu.Unlock()
+		s.audit("reveal-refused", token, rv.path, rv.key, rv.user, r)
+		http.Error(w, fmt.Sprintf("the token is for %s", rv.user), http.StatusForbidden)
+		return
+	}
+	delete(s.reveals, token)
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown or used token", http.StatusNotFound)
+		return
+	}
+	if !s.now().Before(rv.expires) {
+		s.audit("reveal-expired", token, rv.path, rv.key, rv.user, r)
+		http.Error(w, "token expired", http.StatusGone)
+		return
+	}
+
+	value, err := revealValue(rv.path, rv.key)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	s.audit("reveal-redeemed", token, rv.path, rv.key, rv.user, r)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = io.WriteString(w, value)
+}
+
+// prune forgets expired tokens; the caller holds s.mu
+func (s *Server) prune() {
+	now := s.now()
+	for token, rv := range s.reveals {
+		if !now.Before(rv.expires) {
+			delete(s.reveals, token)
+		}
+	}
+}
+
+func (s *Server) audit(event, token, path, key, user string, r *http.Request) {
+	if s.Audit == nil {
+		return
+	}
+	sum := sha256.Sum256([]byte(token))
+	by := ""
+	if id := auth.FromContext(r.Context()); id != nil {
+		by = id.User
+	}
+	s.Audit(AuditEvent{
+		Time:   s.now().UTC(),
+		Event:  event,
+		Token:  hex.EncodeToString(sum[:4]),
+		Path:   path,
+		Key:    key,
+		User:   user,
+		By:     by,
+		Remote: r.RemoteAddr,
+	})
+}
+
+// revealValue returns the decoded value of key in the Secret at path
+func revealValue(path, key string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	doc, err := secret.Parse(data)
+	if err == nil && !doc.IsSecret() {
+		err = errors.New("not a Secret")
+	}
+	if err == nil {
+		err = doc.Decode()
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	for _, e := range append(doc.Data(), doc.StringData()...) {
+		if e.Key == key {
+			return e.Value, nil
+		}
+	}
+	return "", fmt.Errorf("%s has no key %q: %w", path, key, os.ErrNotExist)
+}
+
+// maskValues replaces every value in the Secret's data and stringData
+func maskValues(doc *secret.Document) {
+	for _, field := range []string{doc.Version().DataField, doc.Version().StringDataField} {
+		target := doc.Secret()
+		for i := 0; i+1 < len(target.Content); i += 2 {
+			if target.Content[i].Value != field || target.Content[i+1].Kind != yaml.MappingNode {
+				continue
+			}
+			section := target.Content[i+1]
+			for j := 1; j < len(section.Content); j += 2 {
+				section.Content[j].Value = maskedValue
+				section.Content[j].Style = 0
+			}
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/auth"
+)
+
+// anyUser allows every route of the API to every authenticated caller
+var anyUser = []auth.Rule{{Route: "/v1/*", Users: []string{"*"}}, {Route: "/v1/reveals/*", Users: []string{"*"}}}
+
+// newRevealServer returns a read-only server whose clock the test moves,
+// and the audit events it records. Callers authenticate with a bearer
+// token that is their user name.
+func newRevealServer(t *testing.T) (*httptest.Server, *atomic.Int64, func() []AuditEvent) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "secret.yaml"), []byte(testSecret), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(dir, func(path string) (string, error) { return path, nil },
+		func(path string, data []byte) error { return os.WriteFile(path, data, 0600) })
+	s.ReadOnly = true
+	users := auth.Tokens{}
+	for _, user := range []string{"ops", "alice", "bob"} {
+		users[user] = auth.HashToken(user)
+	}
+	s.Auth = func(next http.Handler) http.Handler { return auth.Middleware(next, users, auth.Policy{Rules: anyUser}) }
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var elapsed atomic.Int64
+	s.now = func() time.Time { return start.Add(time.Duration(elapsed.Load())) }
+	var mu sync.Mutex
+	var events []AuditEvent
+	s.Audit = func(e AuditEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return ts, &elapsed, func() []AuditEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]AuditEvent(nil), events...)
+	}
+}
+
+// doAs sends a request as user
+func doAs(t *testing.T, user, method, u, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, u, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user != "" {
+		req.Header.Set("Authorization", "Bearer "+user)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func issueReveal(t *testing.T, ts *httptest.Server, body string) (int, string) {
+	t.Helper()
+	resp := doAs(t, "ops", http.MethodPost, ts.URL+"/v1/reveals", body)
+	if resp.StatusCode != http.StatusCreated {
+		return resp.StatusCode, ""
+	}
+	var issued struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&issued); err != nil {
+		t.Fatalf("failed to decode reveal: %v", err)
+	}
+	return resp.StatusCode, issued.URL
+}
+
+func TestReadOnly(t *testing.T) {
+	ts, _, _ := newRevealServer(t)
+	u := ts.URL + "/v1/files?path=secret.yaml"
+
+	resp := doAs(t, "ops", http.MethodGet, u, "")
+	body, _ := io.ReadAll(resp.Body)
+	if strings.Contains(string(body), "hunter2") || !strings.Contains(string(body), maskedValue) {
+		t.Errorf("body = %q, want the password masked", body)
+	}
+	if resp := doAs(t, "ops", http.MethodPut, u, string(body)); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("save status = %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestPostReveal(t *testing.T) {
+	ts, _, _ := newRevealServer(t)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"issued", `{"path":"secret.yaml","key":"password","user":"alice"}`, http.StatusCreated},
+		{"with ttl", `{"path":"secret.yaml","key":"password","user":"alice","ttl":"30s"}`, http.StatusCreated},
+		{"ttl too long", `{"path":"secret.yaml","key":"password","user":"alice","ttl":"2h"}`, http.StatusBadRequest},
+		{"no user", `{"path":"secret.yaml","key":"password"}`, http.StatusBadRequest},
+		{"missing key", `{"path":"secret.yaml","key":"token","user":"alice"}`, http.StatusNotFound},
+		{"missing file", `{"path":"nope.yaml","key":"password","user":"alice"}`, http.StatusNotFound},
+		{"invalid json", `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, _ := issueReveal(t, ts, tt.body); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestGetReveal(t *testing.T) {
+	ts, elapsed, events := newRevealServer(t)
+	const req = `{"path":"secret.yaml","key":"password","user":"alice","ttl":"1m"}`
+
+	_, u := issueReveal(t, ts, req)
+	// Only the user the token is for may redeem it
+	if resp := doAs(t, "bob", http.MethodGet, ts.URL+u, ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("reveal by bob status = %d, want 403", resp.StatusCode)
+	}
+	resp := doAs(t, "alice", http.MethodGet, ts.URL+u, "")
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "hunter2" {
+		t.Fatalf("reveal = %d %q, want 200 hunter2", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", got)
+	}
+
+	// Tokens work once
+	if resp := doAs(t, "alice", http.MethodGet, ts.URL+u, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("second reveal status = %d, want 404", resp.StatusCode)
+	}
+
+	_, u = issueReveal(t, ts, req)
+	elapsed.Add(int64(time.Minute))
+	if resp := doAs(t, "alice", http.MethodGet, ts.URL+u, ""); resp.StatusCode != http.StatusGone {
+		t.Errorf("expired reveal status = %d, want 410", resp.StatusCode)
+	}
+
+	var got []string
+	for _, e := range events() {
+		if e.Event == "request" {
+			if strings.Contains(e.Path, strings.TrimPrefix(u, "/v1/reveals/")) {
+				t.Errorf("request event = %+v, want the token redacted", e)
+			}
+			continue
+		}
+		if e.User != "alice" || e.Key != "password" || strings.Contains(u, e.Token) {
+			t.Errorf("event = %+v, want alice's password without the token", e)
+		}
+		got = append(got, e.Event)
+	}
+	want := "reveal-issued reveal-refused reveal-redeemed reveal-issued reveal-expired"
+	if strings.Join(got, " ") != want {
+		t.Errorf("events = %v, want %s", got, want)
+	}
+}
+
+func TestRevealNeedsAuth(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "secret.yaml"), []byte(testSecret), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := New(dir, func(path string) (string, error) { return path, nil }, nil)
+	s.ReadOnly = true
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+
+	resp := do(t, http.MethodPost, ts.URL+"/v1/reveals", `{"path":"secret.yaml","key":"password","user":"alice"}`, "")
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("reveal without serve.auth status = %d, want 403", resp.StatusCode)
+	}
+	if resp := do(t, http.MethodGet, ts.URL+"/v1/reveals/abc", "", ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("redeem without serve.auth status = %d, want 403", resp.StatusCode)
+	}
+}
@@ -0,0 +1,286 @@
+// Package server exposes decode and save operations over HTTP for editor
+// plugins, serializing saves to the same file through a queue
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/queue"
+	"github.com/davidschrooten/secret-wrapper-k8s/pkg/secret"
+)
+
+// Server serves the edit API:
+//
+//	GET /v1/files?path=P    decoded Secret, with an ETag of the file on disk
+//	PUT /v1/files?path=P    encode the body and write it to P; send the ETag
+//	                        from GET as If-Match to refuse stale saves
+//	GET /v1/queue           targets with a save running or waiting
+//	POST /v1/reveals        issue a token revealing one key once; the body
+//	                        is {"path", "key", "user", "ttl"}
+//	GET /v1/reveals/TOKEN   the value, if the token is unused and current
+//
+// Paths are relative to Root, and must resolve to a file inside it.
+type Server struct {
+	Queue *queue.Queue
+	// Root is the real, absolute path of the directory served
+	Root string
+	// Resolve maps a requested path to the file to edit, refusing unsafe ones
+	Resolve func(path string) (string, error)
+	// Write saves an encoded manifest
+	Write func(path string, data []byte) error
+	// LoopbackOnly refuses requests whose Host isn't a loopback name, so a
+	// web page can't reach a server on 127.0.0.1 by rebinding its own
+	// domain to that address
+	LoopbackOnly bool
+	// ReadOnly refuses saves and masks the values of files, so single
+	// values can only be had through reveal tokens
+	ReadOnly bool
+	// Audit, if set, records every request, and every reveal token issued
+	// and redeemed
+	Audit func(AuditEvent)
+	// Auth, if set, wraps the API to authenticate and authorize requests
+	Auth func(http.Handler) http.Handler
+	// RateLimit, if set, is the number of requests per second each client
+	// may make, with bursts of up to Burst
+	RateLimit float64
+	Burst     int
+	// MaxBody caps request bodies, DefaultMaxBody if unset
+	MaxBody int64
+
+	mu      sync.Mutex
+	reveals map[string]reveal
+	now     func() time.Time
+}
+
+// New returns a server for the files under root with an empty queue
+func New(root string, resolve func(string) (string, error), write func(string, []byte) error) *Server {
+	return &Server{Queue: queue.New(), Root: root, Resolve: resolve, Write: write, reveals: map[string]reveal{}, now: time.Now}
+}
+
+// Handler returns the HTTP handler for the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/files", s.getFile)
+	mux.HandleFunc("PUT /v1/files", s.putFile)
+	mux.HandleFunc("GET /v1/queue", s.getQueue)
+	mux.HandleFunc("POST /v1/reveals", s.postReveal)
+	mux.HandleFunc("GET /v1/reveals/{token}", s.getReveal)
+
+	h := recordIdentity(mux)
+	if s.Auth != nil {
+		h = s.Auth(h)
+	}
+	h = s.limit(h)
+	if s.LoopbackOnly {
+		h = checkHost(h)
+	}
+	if s.Audit != nil {
+		h = s.logRequests(h)
+	}
+	return h
+}
+
+func (s *Server) getFile(w http.ResponseWriter, r *http.Request) {
+	path, err := s.resolve(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	doc, err := secret.Parse(data)
+	if err == nil && !doc.IsSecret() {
+		err = errors.New("not a Secret")
+	}
+	if err == nil {
+		err = doc.Decode()
+	}
+	if err == nil && s.ReadOnly {
+		maskValues(doc)
+	}
+	var decoded []byte
+	if err == nil {
+		decoded, err = doc.Bytes()
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode %s: %v", path, err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("ETag", etag(data))
+	_, _ = w.Write(decoded)
+}
+
+func (s *Server) putFile(w http.ResponseWriter, r *http.Request) {
+	if s.ReadOnly {
+		http.Error(w, "the server is read-only", http.StatusMethodNotAllowed)
+		return
+	}
+	path, err := s.resolve(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	doc, err := secret.Parse(body)
+	if err == nil && !doc.IsSecret() {
+		err = errors.New("not a Secret")
+	}
+	if err == nil {
+		err = doc.Encode()
+	}
+	var encoded []byte
+	if err == nil {
+		encoded, err = doc.Bytes()
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// The If-Match check and the write happen under the file's lock, so two
+	// clients saving the same version can't both win
+	var status int
+	err = s.Queue.Do(r.Context(), "file:"+path, "save from "+r.RemoteAddr, func() error {
+		current, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if match := r.Header.Get("If-Match"); match != "" && match != etag(current) {
+			status = http.StatusPreconditionFailed
+			return fmt.Errorf("%s changed since it was read", path)
+		}
+		return s.Write(path, encoded)
+	})
+	if err != nil {
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("ETag", etag(encoded))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getQueue(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.Queue.Status())
+}
+
+// resolve returns the file a request is about
+func (s *Server) resolve(r *http.Request) (string, error) {
+	return s.resolvePath(r.URL.Query().Get("path"))
+}
+
+// resolvePath returns the file a requested path refers to, which must be
+// inside the root whether or not it's reached through a symlink
+func (s *Server) resolvePath(path string) (string, error) {
+	if path == "" {
+		return "", badRequest("missing path parameter")
+	}
+	if s.Root == "" {
+		return "", forbidden("no directory is served")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.Root, path)
+	}
+	real, err := s.Resolve(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	if err != nil {
+		return "", badRequest(err.Error())
+	}
+	if !within(real, s.Root) {
+		return "", forbidden(fmt.Sprintf("%s is outside the served directory", path))
+	}
+	return real, nil
+}
+
+// within reports whether path is dir or below it
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkHost refuses requests for any host but a loopback one, see
+// LoopbackOnly
+func checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !loopbackHost(r.Host) {
+			http.Error(w, fmt.Sprintf("host %q is not allowed", r.Host), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loopbackHost reports whether a Host header names this machine:
+// localhost or a loopback address, with or without a port
+func loopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if strings.EqualFold(strings.TrimSuffix(host, "."), "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// badRequest marks an error caused by the request itself
+type badRequest string
+
+func (e badRequest) Error() string { return string(e) }
+
+// forbidden marks a request for something the server won't serve
+type forbidden string
+
+func (e forbidden) Error() string { return string(e) }
+
+// writeError reports err with a matching status code
+func writeError(w http.ResponseWriter, err error) {
+	var bad badRequest
+	var denied forbidden
+	switch {
+	case errors.As(err, &bad):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.As(err, &denied):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// etag identifies a version of a file
+func etag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
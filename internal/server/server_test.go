@@ -0,0 +1,234 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/queue"
+)
+
+const testSecret = `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+data:
+  password: aHVudGVyMg==
+`
+
+func newTestServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "secret.yaml"), []byte(testSecret), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cm.yaml"), []byte("apiVersion: v1\nkind: ConfigMap\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Like safefile.Resolve, follow symlinks wherever they lead
+	resolve := func(path string) (string, error) {
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			return real, nil
+		}
+		return path, nil
+	}
+	s := New(dir, resolve, func(path string, data []byte) error { return os.WriteFile(path, data, 0600) })
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return ts, dir
+}
+
+func do(t *testing.T, method, u, body, ifMatch string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, u, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestGetFile(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"decoded", "path=secret.yaml", http.StatusOK, "password: hunter2"},
+		{"not a secret", "path=cm.yaml", http.StatusUnprocessableEntity, "not a Secret"},
+		{"missing file", "path=nope.yaml", http.StatusNotFound, ""},
+		{"missing path", "", http.StatusBadRequest, "missing path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := do(t, http.MethodGet, ts.URL+"/v1/files?"+tt.query, "", "")
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", resp.StatusCode, tt.wantStatus, body)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestOutsideRoot(t *testing.T) {
+	ts, dir := newTestServer(t)
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.yaml"), []byte(testSecret), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.yaml"), filepath.Join(dir, "link.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"absolute", http.MethodGet, filepath.Join(outside, "secret.yaml")},
+		{"dot dot", http.MethodGet, "../" + filepath.Base(outside) + "/secret.yaml"},
+		{"symlink", http.MethodGet, "link.yaml"},
+		{"new file", http.MethodPut, filepath.Join(outside, "new.yaml")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := do(t, tt.method, ts.URL+"/v1/files?path="+url.QueryEscape(tt.path), testSecret, "")
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusForbidden || strings.Contains(string(body), "hunter2") {
+				t.Errorf("status = %d, body = %q, want 403", resp.StatusCode, body)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.yaml")); err == nil {
+		t.Error("a save outside the root created the file")
+	}
+}
+
+func TestLoopbackOnly(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "secret.yaml"), []byte(testSecret), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := New(dir, func(path string) (string, error) { return path, nil }, nil)
+	s.LoopbackOnly = true
+
+	tests := []struct {
+		host string
+		want int
+	}{
+		{"127.0.0.1:7420", http.StatusOK},
+		{"localhost:7420", http.StatusOK},
+		{"[::1]:7420", http.StatusOK},
+		{"localhost", http.StatusOK},
+		{"rebound.example.com:7420", http.StatusForbidden},
+		{"127.0.0.1.nip.io", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/v1/files?path=secret.yaml", nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("Host %s: status = %d, want %d", tt.host, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestPutFile(t *testing.T) {
+	ts, dir := newTestServer(t)
+	u := ts.URL + "/v1/files?path=" + url.QueryEscape("secret.yaml")
+
+	resp := do(t, http.MethodGet, u, "", "")
+	decoded, _ := io.ReadAll(resp.Body)
+	tag := resp.Header.Get("ETag")
+	edited := strings.Replace(string(decoded), "hunter2", "swordfish", 1)
+
+	if resp := do(t, http.MethodPut, u, edited, tag); resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("status = %d, want 204 (%s)", resp.StatusCode, body)
+	}
+	written, _ := os.ReadFile(filepath.Join(dir, "secret.yaml"))
+	if !strings.Contains(string(written), "c3dvcmRmaXNo") {
+		t.Errorf("file = %q, want the encoded new password", written)
+	}
+
+	// The file changed, so a save based on the first read is stale
+	if resp := do(t, http.MethodPut, u, edited, tag); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("stale save status = %d, want 412", resp.StatusCode)
+	}
+	if resp := do(t, http.MethodPut, u, "kind: ConfigMap\n", ""); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("non-Secret save status = %d, want 422", resp.StatusCode)
+	}
+}
+
+func TestPutFileConcurrent(t *testing.T) {
+	ts, _ := newTestServer(t)
+	u := ts.URL + "/v1/files?path=secret.yaml"
+
+	resp := do(t, http.MethodGet, u, "", "")
+	decoded, _ := io.ReadAll(resp.Body)
+	tag := resp.Header.Get("ETag")
+
+	// Clients saving the same version race; exactly one may win
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	codes := map[int]int{}
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := strings.Replace(string(decoded), "hunter2", strings.Repeat("x", i+1), 1)
+			req, _ := http.NewRequest(http.MethodPut, u, strings.NewReader(body))
+			req.Header.Set("If-Match", tag)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_ = resp.Body.Close()
+			mu.Lock()
+			codes[resp.StatusCode]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if codes[http.StatusNoContent] != 1 || codes[http.StatusPreconditionFailed] != 9 {
+		t.Errorf("status codes = %v, want one 204 and nine 412", codes)
+	}
+}
+
+func TestGetQueue(t *testing.T) {
+	ts, _ := newTestServer(t)
+	resp := do(t, http.MethodGet, ts.URL+"/v1/queue", "", "")
+
+	var status []queue.Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode queue status: %v", err)
+	}
+	if len(status) != 0 {
+		t.Errorf("queue = %v, want empty", status)
+	}
+}
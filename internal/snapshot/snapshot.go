@@ -0,0 +1,179 @@
+// Package snapshot keeps numbered, encrypted copies of cluster Secrets, so
+// an edit can be undone without an etcd backup
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/sealed"
+)
+
+// ext is the file extension of snapshots
+const ext = ".swk-snapshot"
+
+// Store is a directory of encrypted snapshots, one subdirectory per Secret.
+// Like recovery entries, the key lives elsewhere, readable only by the user.
+type Store struct {
+	Dir string
+
+	box *sealed.Box
+	now func() time.Time
+}
+
+// Snapshot is a Secret manifest as it was at one point in time
+type Snapshot struct {
+	Context   string    `json:"context"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	Taken     time.Time `json:"taken"`
+	Manifest  []byte    `json:"manifest"`
+}
+
+// DefaultStore opens the store in the user's config dir, which, unlike the
+// cache dir, isn't cleaned up behind the user's back
+func DefaultStore() (*Store, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return Open(filepath.Join(configDir, "swk", "snapshots"), filepath.Join(configDir, "swk", "snapshot.key"))
+}
+
+// Open opens or creates a store in dir, creating the key at keyPath if needed
+func Open(dir, keyPath string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	box, err := sealed.Open(keyPath, "snapshot")
+	if err != nil {
+		return nil, err
+	}
+	return &Store{Dir: dir, box: box, now: time.Now}, nil
+}
+
+// Save stores manifest as the next version of the Secret and returns the
+// snapshot with its version and time set
+func (s *Store) Save(context, namespace, name string, manifest []byte) (Snapshot, error) {
+	versions, err := s.versions(context, namespace, name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snap := Snapshot{Context: context, Namespace: namespace, Name: name, Version: 1, Taken: s.now(), Manifest: manifest}
+	if len(versions) > 0 {
+		snap.Version = versions[len(versions)-1] + 1
+	}
+
+	plaintext, err := json.Marshal(snap)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	path := s.path(context, namespace, name, snap.Version)
+	ciphertext, err := s.box.Seal(plaintext, s.additionalData(path))
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	// O_EXCL, so two saves racing for a version can't overwrite each other
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if _, err := f.Write(ciphertext); err != nil {
+		_ = f.Close()
+		return Snapshot{}, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+// Load returns one version of a Secret
+func (s *Store) Load(context, namespace, name string, version int) (Snapshot, error) {
+	path := s.path(context, namespace, name, version)
+	ciphertext, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Snapshot{}, fmt.Errorf("no snapshot %d of %s/%s", version, namespace, name)
+	}
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	plaintext, err := s.box.Open(ciphertext, s.additionalData(path))
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("snapshot %d of %s/%s %w", version, namespace, name, err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(plaintext, &snap); err != nil {
+		return Snapshot{}, fmt.Errorf("snapshot %d of %s/%s is damaged", version, namespace, name)
+	}
+	return snap, nil
+}
+
+// List returns the readable snapshots of a Secret, oldest first
+func (s *Store) List(context, namespace, name string) ([]Snapshot, error) {
+	versions, err := s.versions(context, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	var snaps []Snapshot
+	for _, v := range versions {
+		if snap, err := s.Load(context, namespace, name, v); err == nil {
+			snaps = append(snaps, snap)
+		}
+	}
+	return snaps, nil
+}
+
+// versions lists the version numbers stored for a Secret, in order
+func (s *Store) versions(context, namespace, name string) ([]int, error) {
+	files, err := os.ReadDir(s.secretDir(context, namespace, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+	var versions []int
+	for _, f := range files {
+		if v, err := strconv.Atoi(strings.TrimSuffix(f.Name(), ext)); err == nil && strings.HasSuffix(f.Name(), ext) {
+			versions = append(versions, v)
+		}
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+// secretDir names a Secret's directory by a hash, so directory names don't
+// reveal cluster, namespace, or Secret names
+func (s *Store) secretDir(context, namespace, name string) string {
+	sum := sha256.Sum256([]byte(context + "\x00" + namespace + "\x00" + name))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:16]))
+}
+
+func (s *Store) path(context, namespace, name string, version int) string {
+	return filepath.Join(s.secretDir(context, namespace, name), strconv.Itoa(version)+ext)
+}
+
+// additionalData binds a snapshot to its Secret and version, so a file
+// copied to another one's place doesn't decrypt
+func (s *Store) additionalData(path string) []byte {
+	rel, err := filepath.Rel(s.Dir, path)
+	if err != nil {
+		rel = path
+	}
+	return []byte(filepath.ToSlash(rel))
+}
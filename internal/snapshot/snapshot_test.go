@@ -0,0 +1,69 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(filepath.Join(dir, "snapshots"), filepath.Join(dir, "snapshot.key"))
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+
+	for i, manifest := range []string{"password: v1", "password: v2"} {
+		snap, err := s.Save("prod-cluster", "payments", "db", []byte(manifest))
+		if err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+		if snap.Version != i+1 {
+			t.Errorf("Save() version = %d, want %d", snap.Version, i+1)
+		}
+	}
+	if _, err := s.Save("prod-cluster", "payments", "cache", []byte("other")); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	snaps, err := s.List("prod-cluster", "payments", "db")
+	if err != nil || len(snaps) != 2 {
+		t.Fatalf("List() = %v, %v, want two snapshots", snaps, err)
+	}
+	snap, err := s.Load("prod-cluster", "payments", "db", 1)
+	if err != nil || string(snap.Manifest) != "password: v1" || snap.Name != "db" {
+		t.Errorf("Load(1) = %+v, %v", snap, err)
+	}
+	if _, err := s.Load("prod-cluster", "payments", "db", 3); err == nil || !strings.Contains(err.Error(), "no snapshot 3") {
+		t.Errorf("Load(3) error = %v, want a missing snapshot", err)
+	}
+	if snaps, _ := s.List("staging", "payments", "db"); len(snaps) != 0 {
+		t.Errorf("List() in another context = %v, want none", snaps)
+	}
+
+	// Nothing in the store names the Secret or holds its values in the clear
+	err = filepath.Walk(s.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, _ := os.ReadFile(path)
+		if strings.Contains(path, "payments") || strings.Contains(string(data), "password") {
+			t.Errorf("%s reveals the Secret", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A snapshot moved to another version's place doesn't decrypt
+	first := s.path("prod-cluster", "payments", "db", 1)
+	second := s.path("prod-cluster", "payments", "db", 2)
+	if err := os.Rename(first, second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Load("prod-cluster", "payments", "db", 2); err == nil {
+		t.Error("Load() accepted a snapshot moved to another version")
+	}
+}
@@ -0,0 +1,135 @@
+// Package stats keeps local usage counters: commands run, failures by
+// category, and validations that fired. They are only ever written to a
+// file on this machine.
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/safefile"
+)
+
+// Stats are the counters since Since
+type Stats struct {
+	Since    time.Time      `json:"since"`
+	Commands map[string]int `json:"commands"`
+	// Failures counts failed runs by category, e.g. validation or cluster
+	Failures map[string]int `json:"failures"`
+	// Checks counts validations that fired, e.g. schema or check:base64
+	Checks map[string]int `json:"checks"`
+}
+
+// Run is what one invocation of swk adds to the counters
+type Run struct {
+	Command string
+	// Failure is the category of the error the run ended with, if any
+	Failure string
+	Checks  []string
+}
+
+// Count is a name and how often it was counted
+type Count struct {
+	Name  string
+	Count int
+}
+
+// DefaultPath returns swk/stats.json in the user's config dir
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "swk", "stats.json"), nil
+}
+
+// Load reads the counters at path. A missing file has none.
+func Load(path string) (*Stats, error) {
+	s := &Stats{}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read stats: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, s); err != nil {
+			return nil, fmt.Errorf("failed to parse stats %s: %w", path, err)
+		}
+	}
+	if s.Commands == nil {
+		s.Commands = map[string]int{}
+	}
+	if s.Failures == nil {
+		s.Failures = map[string]int{}
+	}
+	if s.Checks == nil {
+		s.Checks = map[string]int{}
+	}
+	return s, nil
+}
+
+// Add adds a run to the counters at path. Runs that finish at the same
+// time may lose a count; the file is replaced atomically, so it's never
+// corrupted.
+func Add(path string, r Run, now time.Time) error {
+	s, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if s.Since.IsZero() {
+		s.Since = now.UTC().Truncate(time.Second)
+	}
+	s.Commands[r.Command]++
+	if r.Failure != "" {
+		s.Failures[r.Failure]++
+	}
+	for _, c := range r.Checks {
+		s.Checks[c]++
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create stats directory: %w", err)
+	}
+	return safefile.WriteFileMode(path, append(data, '\n'), 0600)
+}
+
+// Reset removes the counters at path
+func Reset(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to reset stats: %w", err)
+	}
+	return nil
+}
+
+// Runs returns the number of runs counted
+func (s *Stats) Runs() int {
+	n := 0
+	for _, c := range s.Commands {
+		n += c
+	}
+	return n
+}
+
+// Sorted returns counters from most to least frequent, then by name
+func Sorted(counts map[string]int) []Count {
+	out := make([]Count, 0, len(counts))
+	for name, n := range counts {
+		out = append(out, Count{name, n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAdd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swk", "stats.json")
+	first := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	runs := []Run{
+		{Command: "edit"},
+		{Command: "edit", Failure: "validation", Checks: []string{"schema"}},
+		{Command: "check", Failure: "validation", Checks: []string{"check:base64", "check:base64"}},
+	}
+	for i, r := range runs {
+		if err := Add(path, r, first.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !s.Since.Equal(first) || s.Runs() != 3 {
+		t.Errorf("since = %v, runs = %d, want %v and 3", s.Since, s.Runs(), first)
+	}
+	if s.Commands["edit"] != 2 || s.Failures["validation"] != 2 || s.Checks["check:base64"] != 2 || s.Checks["schema"] != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("stats file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	if err := Reset(path); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if s, err := Load(path); err != nil || s.Runs() != 0 {
+		t.Errorf("after Reset() = %+v, %v, want no runs", s, err)
+	}
+}
+
+func TestSorted(t *testing.T) {
+	got := Sorted(map[string]int{"b": 2, "a": 2, "c": 5})
+	want := []Count{{"c", 5}, {"a", 2}, {"b", 2}}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Sorted() = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package tempdir
+
+import "syscall"
+
+// Filesystem returns the type of the filesystem holding dir, and whether
+// it keeps files in memory
+func Filesystem(dir string) (string, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return "", false
+	}
+	var name []byte
+	for _, c := range st.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	return string(name), string(name) == "tmpfs"
+}
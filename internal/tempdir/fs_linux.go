@@ -0,0 +1,29 @@
+package tempdir
+
+import "syscall"
+
+// Filesystem magic numbers from statfs(2)
+var fsNames = map[uint32]string{
+	0x01021994: "tmpfs",
+	0x858458f6: "ramfs",
+	0xef53:     "ext4",
+	0x58465342: "xfs",
+	0x9123683e: "btrfs",
+	0x2fc12fc1: "zfs",
+	0x794c7630: "overlayfs",
+	0x6969:     "nfs",
+}
+
+// Filesystem returns the type of the filesystem holding dir, and whether
+// it keeps files in memory
+func Filesystem(dir string) (string, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return "", false
+	}
+	name, ok := fsNames[uint32(st.Type)]
+	if !ok {
+		name = "an unknown filesystem"
+	}
+	return name, name == "tmpfs" || name == "ramfs"
+}
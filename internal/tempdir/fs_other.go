@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package tempdir
+
+// Filesystem can't tell the filesystem type on this platform
+func Filesystem(string) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,136 @@
+// Package tempdir creates the private directories that decoded Secrets are
+// written to while they are edited, preferring ones kept in memory
+package tempdir
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// NoShred, set by --no-shred, makes Remove delete files without
+// overwriting them first, for filesystems where that fails or is slow
+var NoShred bool
+
+// Override is the directory given with --tmpdir or SWK_TMPDIR. When set,
+// private directories are created in it and nowhere else.
+var Override string
+
+// Parent returns the directory private directories are created in: the
+// override, $XDG_RUNTIME_DIR if it is private to the user, /dev/shm if it
+// is in memory, or else the system temp dir
+func Parent() string {
+	return candidates()[0]
+}
+
+// candidates lists the directories to try, most preferred first
+func candidates() []string {
+	if Override != "" {
+		return []string{Override}
+	}
+	var dirs []string
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() && info.Mode().Perm()&0077 == 0 {
+			dirs = append(dirs, dir)
+		}
+	}
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		if _, inMemory := Filesystem("/dev/shm"); inMemory {
+			dirs = append(dirs, "/dev/shm")
+		}
+	}
+	return append(dirs, os.TempDir())
+}
+
+// Dir creates a new directory only the user can open, named after pattern
+// as os.MkdirTemp names it. A preferred parent that can't be written to is
+// passed over for the next one, except for the override.
+func Dir(pattern string) (string, error) {
+	var err error
+	for _, parent := range candidates() {
+		var dir string
+		// MkdirTemp creates the directory with mode 0700
+		if dir, err = os.MkdirTemp(parent, pattern); err == nil {
+			return dir, nil
+		}
+	}
+	return "", err
+}
+
+// WriteFile writes data to a file called name, mode 0600, in a new
+// private directory named after pattern. cleanup removes the directory
+// with everything in it, see Remove.
+func WriteFile(pattern, name string, data []byte) (string, func(), error) {
+	dir, err := Dir(pattern)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	cleanup := func() { _ = Remove(dir) }
+
+	path := filepath.Join(dir, name)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return path, cleanup, nil
+}
+
+// Remove deletes a directory made by Dir with everything in it. Unless
+// NoShred is set, every file is first overwritten with zeros and synced,
+// so the plaintext is less likely to be recovered from the disk. That
+// only helps for the blocks a file still has: on copy-on-write or
+// journaling filesystems, and for files an editor replaced rather than
+// wrote to, older copies may survive.
+func Remove(dir string) error {
+	if !NoShred {
+		_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d.Type().IsRegular() {
+				_ = shred(path)
+			}
+			return nil
+		})
+	}
+	return os.RemoveAll(dir)
+}
+
+// shred overwrites the file at path with zeros and syncs it
+func shred(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0200 == 0 {
+		// Read-only copies, such as the original next to an edit
+		if err := os.Chmod(path, 0600); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	zeros := make([]byte, 32*1024)
+	for left := info.Size(); left > 0; {
+		n := int64(len(zeros))
+		if left < n {
+			n = left
+		}
+		if _, err := f.Write(zeros[:n]); err != nil {
+			return err
+		}
+		left -= n
+	}
+	return f.Sync()
+}
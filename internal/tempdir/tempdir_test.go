@@ -0,0 +1,132 @@
+package tempdir
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	setOverride(t, t.TempDir())
+
+	path, cleanup, err := WriteFile("swk-*", "secret.yaml", []byte("password: hunter2\n"))
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	dir := filepath.Dir(path)
+	if filepath.Dir(dir) != Override || filepath.Base(path) != "secret.yaml" {
+		t.Errorf("WriteFile() path = %s, want OVERRIDE/swk-*/secret.yaml", path)
+	}
+	for _, c := range []struct {
+		path string
+		want os.FileMode
+	}{{dir, 0700}, {path, 0600}} {
+		info, err := os.Stat(c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != c.want {
+			t.Errorf("mode of %s = %v, want %v", c.path, got, c.want)
+		}
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "password: hunter2\n" {
+		t.Errorf("ReadFile() = %q, %v", data, err)
+	}
+
+	cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("cleanup() left %s behind", dir)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	tests := []struct {
+		name    string
+		noShred bool
+		want    string
+	}{
+		{"shred", false, strings.Repeat("\x00", 18)},
+		{"no shred", true, "password: hunter2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setOverride(t, t.TempDir())
+			NoShred = tt.noShred
+			t.Cleanup(func() { NoShred = false })
+
+			path, cleanup, err := WriteFile("swk-*", "secret.yaml", []byte("password: hunter2\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// A second link to the file shows what became of its contents,
+			// read-only like the original shown by --compare
+			link := filepath.Join(Override, "link")
+			if err := os.Link(path, link); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(path, 0400); err != nil {
+				t.Fatal(err)
+			}
+
+			cleanup()
+			if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+				t.Errorf("cleanup() left %s behind", filepath.Dir(path))
+			}
+			if data, err := os.ReadFile(link); err != nil || string(data) != tt.want {
+				t.Errorf("contents after cleanup = %q, %v, want %q", data, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParent(t *testing.T) {
+	setOverride(t, "")
+	runtime := t.TempDir()
+	if err := os.Chmod(runtime, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		override string
+		runtime  os.FileMode
+		want     string
+	}{
+		{"override", "/override", 0700, "/override"},
+		{"private runtime dir", "", 0700, runtime},
+		{"shared runtime dir", "", 0755, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Override = tt.override
+			t.Setenv("XDG_RUNTIME_DIR", runtime)
+			if err := os.Chmod(runtime, tt.runtime); err != nil {
+				t.Fatal(err)
+			}
+			got := Parent()
+			if tt.want == "" {
+				if got == runtime {
+					t.Errorf("Parent() = %s, want a shared runtime dir passed over", got)
+				}
+			} else if got != tt.want {
+				t.Errorf("Parent() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDirOverrideMissing(t *testing.T) {
+	setOverride(t, filepath.Join(t.TempDir(), "missing"))
+	if dir, err := Dir("swk-*"); err == nil {
+		t.Errorf("Dir() = %s, want an error for a missing override", dir)
+	}
+}
+
+// setOverride sets Override for the duration of the test
+func setOverride(t *testing.T, dir string) {
+	t.Helper()
+	old := Override
+	Override = dir
+	t.Cleanup(func() { Override = old })
+}
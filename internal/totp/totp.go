@@ -0,0 +1,123 @@
+// Package totp computes time-based one-time passwords (RFC 6238) from the
+// seeds authenticator apps are set up with
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key is a TOTP seed with its parameters
+type Key struct {
+	Secret    []byte
+	Digits    int
+	Period    time.Duration
+	Algorithm string // SHA1, SHA256, or SHA512
+}
+
+var algorithms = map[string]func() hash.Hash{
+	"SHA1":   sha1.New,
+	"SHA256": sha256.New,
+	"SHA512": sha512.New,
+}
+
+// Parse reads a seed as base32, the form sites show next to their QR codes,
+// or as an otpauth://totp/ URI, the form the QR codes hold. Base32 seeds
+// use the usual 6 digits, 30 seconds, and SHA1.
+func Parse(seed string) (Key, error) {
+	seed = strings.TrimSpace(seed)
+	key := Key{Digits: 6, Period: 30 * time.Second, Algorithm: "SHA1"}
+	if !strings.HasPrefix(seed, "otpauth://") {
+		secret, err := decodeBase32(seed)
+		if err != nil {
+			return Key{}, err
+		}
+		key.Secret = secret
+		return key, nil
+	}
+
+	u, err := url.Parse(seed)
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid otpauth URI: %w", err)
+	}
+	if u.Host != "totp" {
+		return Key{}, fmt.Errorf("otpauth URI is for %q, not totp", u.Host)
+	}
+	q := u.Query()
+	if key.Secret, err = decodeBase32(q.Get("secret")); err != nil {
+		return Key{}, err
+	}
+	if d := q.Get("digits"); d != "" {
+		if key.Digits, err = strconv.Atoi(d); err != nil || key.Digits < 6 || key.Digits > 10 {
+			return Key{}, fmt.Errorf("invalid digits %q in otpauth URI: want 6 to 10", d)
+		}
+	}
+	if p := q.Get("period"); p != "" {
+		seconds, err := strconv.Atoi(p)
+		if err != nil || seconds <= 0 {
+			return Key{}, fmt.Errorf("invalid period %q in otpauth URI", p)
+		}
+		key.Period = time.Duration(seconds) * time.Second
+	}
+	if a := strings.ToUpper(q.Get("algorithm")); a != "" {
+		if _, ok := algorithms[a]; !ok {
+			return Key{}, fmt.Errorf("unsupported algorithm %q in otpauth URI (supported: SHA1, SHA256, SHA512)", a)
+		}
+		key.Algorithm = a
+	}
+	return key, nil
+}
+
+// Code returns the code that is valid at t
+func (k Key) Code(t time.Time) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(t.Unix()/int64(k.Period/time.Second)))
+	mac := hmac.New(algorithms[k.Algorithm], k.Secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := uint64(binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff)
+	mod := uint64(1)
+	for range k.Digits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", k.Digits, value%mod)
+}
+
+// Remaining returns how long the code valid at t stays valid
+func (k Key) Remaining(t time.Time) time.Duration {
+	period := int64(k.Period / time.Second)
+	return time.Duration(period-t.Unix()%period) * time.Second
+}
+
+// decodeBase32 decodes a seed, ignoring case, spaces, dashes, and padding,
+// which sites format seeds with in different ways
+func decodeBase32(seed string) ([]byte, error) {
+	clean := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '=':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(seed))
+	if clean == "" {
+		return nil, fmt.Errorf("empty TOTP seed")
+	}
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(clean)
+	if err != nil {
+		return nil, fmt.Errorf("TOTP seed is not valid base32: %w", err)
+	}
+	return secret, nil
+}
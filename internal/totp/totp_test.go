@@ -0,0 +1,68 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// Seeds from RFC 6238 appendix B, in base32
+const (
+	seedSHA1   = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	seedSHA256 = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA"
+	seedSHA512 = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNA"
+)
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name string
+		seed string
+		unix int64
+		want string
+	}{
+		{"SHA1", "otpauth://totp/x?secret=" + seedSHA1 + "&digits=8", 59, "94287082"},
+		{"SHA1 later", "otpauth://totp/x?secret=" + seedSHA1 + "&digits=8", 1111111109, "07081804"},
+		{"SHA256", "otpauth://totp/x?secret=" + seedSHA256 + "&digits=8&algorithm=SHA256", 1234567890, "91819424"},
+		{"SHA512", "otpauth://totp/x?secret=" + seedSHA512 + "&digits=8&algorithm=sha512", 20000000000, "47863826"},
+		{"base32 defaults", seedSHA1, 59, "287082"},
+		{"formatted base32", "gezd gnbv gy3t qojq gezd gnbv gy3t qojq", 59, "287082"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := Parse(tt.seed)
+			if err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			if got := key.Code(time.Unix(tt.unix, 0)); got != tt.want {
+				t.Errorf("Code() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, seed := range []string{
+		"",
+		"not base32!",
+		"otpauth://hotp/x?secret=" + seedSHA1,
+		"otpauth://totp/x?secret=" + seedSHA1 + "&digits=4",
+		"otpauth://totp/x?secret=" + seedSHA1 + "&period=0",
+		"otpauth://totp/x?secret=" + seedSHA1 + "&algorithm=MD5",
+	} {
+		if _, err := Parse(seed); err == nil {
+			t.Errorf("Parse(%q) should fail", seed)
+		}
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	key, err := Parse(seedSHA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for unix, want := range map[int64]time.Duration{0: 30 * time.Second, 59: time.Second, 61: 29 * time.Second} {
+		if got := key.Remaining(time.Unix(unix, 0)); got != want {
+			t.Errorf("Remaining(%d) = %s, want %s", unix, got, want)
+		}
+	}
+}
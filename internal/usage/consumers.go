@@ -0,0 +1,140 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+)
+
+// Consumer is a container that gets a key of a Secret, from an env
+// variable or a file in a volume
+type Consumer struct {
+	Pod       string
+	Container string
+	// Env is the variable holding the value, or "" for a file
+	Env string
+	// Path is the file holding the value, or "" for an env variable
+	Path string
+	// SubPath is set for files mounted with subPath, which the kubelet
+	// never updates
+	SubPath bool
+	// StartedAt is when the container last started, zero if it isn't
+	// running
+	StartedAt time.Time
+}
+
+// Via describes how the container gets the value, e.g. "env DB_PASSWORD"
+func (c Consumer) Via() string {
+	if c.Env != "" {
+		return "env " + c.Env
+	}
+	return "file " + c.Path
+}
+
+type pod struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec   podSpec `json:"spec"`
+	Status struct {
+		ContainerStatuses []struct {
+			Name  string `json:"name"`
+			State struct {
+				Running *struct {
+					StartedAt time.Time `json:"startedAt"`
+				} `json:"running"`
+			} `json:"state"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+type keyPath struct {
+	Key  string `json:"key"`
+	Path string `json:"path"`
+}
+
+// Consumers returns the running containers that get key of the Secret
+// called name, from a kubectl list of pods such as the output of `kubectl
+// get pods -o json`
+func Consumers(list []byte, name, key string) ([]Consumer, error) {
+	var pods struct {
+		Items []pod `json:"items"`
+	}
+	if err := json.Unmarshal(list, &pods); err != nil {
+		return nil, fmt.Errorf("failed to parse pod list: %w", err)
+	}
+
+	var consumers []Consumer
+	for _, p := range pods.Items {
+		started := map[string]time.Time{}
+		for _, s := range p.Status.ContainerStatuses {
+			if s.State.Running != nil {
+				started[s.Name] = s.State.Running.StartedAt
+			}
+		}
+		files := p.Spec.keyFiles(name, key)
+		for _, c := range p.Spec.Containers {
+			at, running := started[c.Name]
+			if !running {
+				continue
+			}
+			add := func(cons Consumer) {
+				cons.Pod, cons.Container, cons.StartedAt = p.Metadata.Name, c.Name, at
+				consumers = append(consumers, cons)
+			}
+			for _, e := range c.Env {
+				if ref := e.ValueFrom; ref != nil && ref.SecretKeyRef != nil && ref.SecretKeyRef.Name == name && ref.SecretKeyRef.Key == key {
+					add(Consumer{Env: e.Name})
+				}
+			}
+			for _, e := range c.EnvFrom {
+				if e.SecretRef != nil && e.SecretRef.Name == name {
+					add(Consumer{Env: e.Prefix + key})
+				}
+			}
+			for _, m := range c.VolumeMounts {
+				file, ok := files[m.Name]
+				if !ok {
+					continue
+				}
+				if m.SubPath == "" {
+					add(Consumer{Path: path.Join(m.MountPath, file)})
+				} else if m.SubPath == file {
+					add(Consumer{Path: m.MountPath, SubPath: true})
+				}
+			}
+		}
+	}
+	return consumers, nil
+}
+
+// keyFiles maps the volumes holding key of the Secret called name to the
+// path of its file within the volume
+func (s *podSpec) keyFiles(name, key string) map[string]string {
+	files := map[string]string{}
+	find := func(volume string, items []keyPath) {
+		if len(items) == 0 {
+			files[volume] = key
+			return
+		}
+		for _, item := range items {
+			if item.Key == key {
+				files[volume] = item.Path
+			}
+		}
+	}
+	for _, v := range s.Volumes {
+		if v.Secret != nil && v.Secret.SecretName == name {
+			find(v.Name, v.Secret.Items)
+		}
+		if v.Projected != nil {
+			for _, src := range v.Projected.Sources {
+				if src.Secret != nil && src.Secret.Name == name {
+					find(v.Name, src.Secret.Items)
+				}
+			}
+		}
+	}
+	return files
+}
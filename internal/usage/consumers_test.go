@@ -0,0 +1,59 @@
+package usage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConsumers(t *testing.T) {
+	list := `{"items": [
+  {"metadata": {"name": "web-1"},
+   "spec": {
+     "containers": [
+       {"name": "app",
+        "env": [
+          {"name": "DB_PASSWORD", "valueFrom": {"secretKeyRef": {"name": "db", "key": "password"}}},
+          {"name": "DB_USER", "valueFrom": {"secretKeyRef": {"name": "db", "key": "user"}}}
+        ],
+        "envFrom": [{"prefix": "PG_", "secretRef": {"name": "db"}}],
+        "volumeMounts": [
+          {"name": "creds", "mountPath": "/etc/creds"},
+          {"name": "creds", "mountPath": "/app/db-pass", "subPath": "pass"}
+        ]},
+       {"name": "sidecar",
+        "volumeMounts": [{"name": "all", "mountPath": "/secrets"}]},
+       {"name": "stopped",
+        "env": [{"name": "P", "valueFrom": {"secretKeyRef": {"name": "db", "key": "password"}}}]}
+     ],
+     "volumes": [
+       {"name": "creds", "secret": {"secretName": "db", "items": [{"key": "password", "path": "pass"}]}},
+       {"name": "all", "projected": {"sources": [{"secret": {"name": "db"}}]}}
+     ]},
+   "status": {"containerStatuses": [
+     {"name": "app", "state": {"running": {"startedAt": "2026-10-16T10:00:00Z"}}},
+     {"name": "sidecar", "state": {"running": {"startedAt": "2026-10-16T11:00:00Z"}}},
+     {"name": "stopped", "state": {"waiting": {}}}
+   ]}}
+]}`
+
+	consumers, err := Consumers([]byte(list), "db", "password")
+	if err != nil {
+		t.Fatalf("Consumers() failed: %v", err)
+	}
+	started := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	want := []Consumer{
+		{Pod: "web-1", Container: "app", Env: "DB_PASSWORD", StartedAt: started},
+		{Pod: "web-1", Container: "app", Env: "PG_password", StartedAt: started},
+		{Pod: "web-1", Container: "app", Path: "/etc/creds/pass", StartedAt: started},
+		{Pod: "web-1", Container: "app", Path: "/app/db-pass", SubPath: true, StartedAt: started},
+		{Pod: "web-1", Container: "sidecar", Path: "/secrets/password", StartedAt: started.Add(time.Hour)},
+	}
+	if len(consumers) != len(want) {
+		t.Fatalf("Consumers() = %+v, want %+v", consumers, want)
+	}
+	for i := range want {
+		if consumers[i] != want[i] {
+			t.Errorf("consumer %d = %+v, want %+v", i, consumers[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package usage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// edit replaces the key old, written at line and column, with new
+type edit struct {
+	line, column int
+	old, new     string
+}
+
+// RenameKeys rewrites the references to keys of the Secret namespace/name
+// in a manifest, which may hold several documents: secretKeyRef in env, and
+// the items of secret and projected volumes. Only the key names change;
+// everything else in the manifest is kept byte for byte. Documents in
+// another namespace are left alone; ones without a namespace are assumed to
+// share the Secret's. envFrom needs no rewriting, as its variables take
+// their names from the keys.
+func RenameKeys(manifest []byte, namespace, name string, renames map[string]string) ([]byte, []Reference, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(manifest))
+	var edits []edit
+	var refs []Reference
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		root := doc.Content[0]
+		metadata := field(root, "metadata")
+		if ns := scalar(metadata, "namespace"); ns != "" && namespace != "" && ns != namespace {
+			continue
+		}
+		r := &renamer{name: name, renames: renames}
+		r.walk(root, "")
+		edits = append(edits, r.edits...)
+		for _, via := range r.via {
+			refs = append(refs, Reference{Kind: scalar(root, "kind"), Name: scalar(metadata, "name"), Via: via})
+		}
+	}
+	if len(edits) == 0 {
+		return manifest, nil, nil
+	}
+
+	result, err := applyEdits(manifest, edits)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, refs, nil
+}
+
+// renamer collects the edits to the key references in one document
+type renamer struct {
+	name    string
+	renames map[string]string
+	edits   []edit
+	via     []string
+}
+
+// walk looks for references below node; via describes the env variable or
+// volume node belongs to
+func (r *renamer) walk(node *yaml.Node, via string) {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			r.walk(item, via)
+		}
+	case yaml.MappingNode:
+		if n := scalar(node, "name"); n != "" {
+			if field(node, "valueFrom") != nil {
+				via = "env " + n
+			} else if field(node, "secret") != nil || field(node, "projected") != nil {
+				via = "volume " + n
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			switch k.Value {
+			case "secretKeyRef":
+				if scalar(v, "name") == r.name {
+					r.rename(field(v, "key"), via)
+				}
+			case "secret":
+				if scalar(v, "secretName") == r.name || scalar(v, "name") == r.name {
+					if items := field(v, "items"); items != nil && items.Kind == yaml.SequenceNode {
+						for _, item := range items.Content {
+							r.rename(field(item, "key"), via)
+						}
+					}
+				}
+			}
+			r.walk(v, via)
+		}
+	}
+}
+
+// rename records an edit if key is a scalar naming a renamed key
+func (r *renamer) rename(key *yaml.Node, via string) {
+	if key == nil || key.Kind != yaml.ScalarNode {
+		return
+	}
+	n, ok := r.renames[key.Value]
+	if !ok {
+		return
+	}
+	column := key.Column
+	if key.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		column++
+	}
+	r.edits = append(r.edits, edit{line: key.Line, column: column, old: key.Value, new: n})
+	r.via = append(r.via, fmt.Sprintf("%s: %s -> %s", via, key.Value, n))
+}
+
+// applyEdits replaces the keys in data at their positions, right to left
+// within a line so earlier positions stay valid
+func applyEdits(data []byte, edits []edit) ([]byte, error) {
+	lines := strings.SplitAfter(string(data), "\n")
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].line != edits[j].line {
+			return edits[i].line < edits[j].line
+		}
+		return edits[i].column > edits[j].column
+	})
+	for _, e := range edits {
+		line := lines[e.line-1]
+		offset := 0
+		for range e.column - 1 {
+			_, size := utf8.DecodeRuneInString(line[offset:])
+			offset += size
+		}
+		if !strings.HasPrefix(line[offset:], e.old) {
+			return nil, fmt.Errorf("can't rewrite key %q at line %d: it isn't written as a plain or quoted name", e.old, e.line)
+		}
+		lines[e.line-1] = line[:offset] + e.new + line[offset+len(e.old):]
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// field returns the value of key in a mapping node, or nil
+func field(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalar returns the scalar value of key in a mapping node, or ""
+func scalar(node *yaml.Node, key string) string {
+	if v := field(node, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
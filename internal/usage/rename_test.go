@@ -0,0 +1,79 @@
+package usage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenameKeys(t *testing.T) {
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          env:
+            - name: DATABASE_HOST   # renamed variable, same key
+              valueFrom:
+                secretKeyRef: {name: app, key: DB_HOST}
+            - name: OTHER
+              valueFrom:
+                secretKeyRef:
+                  name: other
+                  key: DB_HOST
+          envFrom:
+            - secretRef:
+                name: app
+      volumes:
+        - name: creds
+          secret:
+            secretName: app
+            items:
+              - key: "DB_PASSWORD"
+                path: password
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: elsewhere
+  namespace: other
+spec:
+  containers:
+    - name: app
+      env:
+        - name: X
+          valueFrom:
+            secretKeyRef: {name: app, key: DB_HOST}
+`
+	renames := map[string]string{"DB_HOST": "DATABASE_HOST", "DB_PASSWORD": "DATABASE_PASSWORD"}
+	out, refs, err := RenameKeys([]byte(manifest), "prod", "app", renames)
+	if err != nil {
+		t.Fatalf("RenameKeys() failed: %v", err)
+	}
+
+	want := strings.Replace(manifest, "{name: app, key: DB_HOST}", "{name: app, key: DATABASE_HOST}", 1)
+	want = strings.Replace(want, `key: "DB_PASSWORD"`, `key: "DATABASE_PASSWORD"`, 1)
+	if string(out) != want {
+		t.Errorf("RenameKeys() =\n%s\nwant\n%s", out, want)
+	}
+
+	var got []string
+	for _, r := range refs {
+		got = append(got, r.String())
+	}
+	wantRefs := []string{
+		"Deployment/web: env DATABASE_HOST: DB_HOST -> DATABASE_HOST",
+		"Deployment/web: volume creds: DB_PASSWORD -> DATABASE_PASSWORD",
+	}
+	if strings.Join(got, "\n") != strings.Join(wantRefs, "\n") {
+		t.Errorf("references = %q, want %q", got, wantRefs)
+	}
+
+	unchanged := "kind: ConfigMap\ndata:\n  key: DB_HOST\n"
+	if out, refs, err := RenameKeys([]byte(unchanged), "", "app", renames); err != nil || string(out) != unchanged || len(refs) != 0 {
+		t.Errorf("RenameKeys() of an unrelated manifest = %q, %v, %v", out, refs, err)
+	}
+}
@@ -0,0 +1,214 @@
+// Package usage finds the workloads and other objects in a namespace that
+// reference a Secret, so it isn't deleted from under them
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Kinds are the resource kinds that can reference a Secret, as passed to
+// kubectl get
+const Kinds = "pods,deployments,statefulsets,daemonsets,replicasets,jobs,cronjobs,serviceaccounts,ingresses"
+
+// Reference is an object using a Secret, and how
+type Reference struct {
+	Kind string
+	Name string
+	Via  string // e.g. "env DB_PASSWORD" or "volume creds"
+}
+
+// String formats a reference as "Deployment/web: env DB_PASSWORD"
+func (r Reference) String() string {
+	return fmt.Sprintf("%s/%s: %s", r.Kind, r.Name, r.Via)
+}
+
+type object struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec json.RawMessage `json:"spec"`
+
+	// ServiceAccounts keep these at the top level
+	Secrets          []named `json:"secrets"`
+	ImagePullSecrets []named `json:"imagePullSecrets"`
+}
+
+type named struct {
+	Name string `json:"name"`
+}
+
+type podSpec struct {
+	Volumes []struct {
+		Name   string `json:"name"`
+		Secret *struct {
+			SecretName string    `json:"secretName"`
+			Items      []keyPath `json:"items"`
+		} `json:"secret"`
+		Projected *struct {
+			Sources []struct {
+				Secret *struct {
+					Name  string    `json:"name"`
+					Items []keyPath `json:"items"`
+				} `json:"secret"`
+			} `json:"sources"`
+		} `json:"projected"`
+	} `json:"volumes"`
+	Containers          []container `json:"containers"`
+	InitContainers      []container `json:"initContainers"`
+	EphemeralContainers []container `json:"ephemeralContainers"`
+	ImagePullSecrets    []named     `json:"imagePullSecrets"`
+}
+
+type container struct {
+	Name string `json:"name"`
+	Env  []struct {
+		Name      string `json:"name"`
+		ValueFrom *struct {
+			SecretKeyRef *struct {
+				Name string `json:"name"`
+				Key  string `json:"key"`
+			} `json:"secretKeyRef"`
+		} `json:"valueFrom"`
+	} `json:"env"`
+	EnvFrom []struct {
+		Prefix    string `json:"prefix"`
+		SecretRef *named `json:"secretRef"`
+	} `json:"envFrom"`
+	VolumeMounts []struct {
+		Name      string `json:"name"`
+		MountPath string `json:"mountPath"`
+		SubPath   string `json:"subPath"`
+	} `json:"volumeMounts"`
+}
+
+// Find returns the references to the Secret called name in a kubectl list
+// of objects, such as the output of `kubectl get KINDS -o json`
+func Find(list []byte, name string) ([]Reference, error) {
+	var objects struct {
+		Items []object `json:"items"`
+	}
+	if err := json.Unmarshal(list, &objects); err != nil {
+		return nil, fmt.Errorf("failed to parse object list: %w", err)
+	}
+
+	var refs []Reference
+	for _, o := range objects.Items {
+		add := func(via string) {
+			refs = append(refs, Reference{Kind: o.Kind, Name: o.Metadata.Name, Via: via})
+		}
+		switch o.Kind {
+		case "ServiceAccount":
+			for _, s := range o.Secrets {
+				if s.Name == name {
+					add("secrets")
+				}
+			}
+			for _, s := range o.ImagePullSecrets {
+				if s.Name == name {
+					add("imagePullSecrets")
+				}
+			}
+		case "Ingress":
+			var spec struct {
+				TLS []struct {
+					SecretName string `json:"secretName"`
+				} `json:"tls"`
+			}
+			if err := json.Unmarshal(o.Spec, &spec); err != nil {
+				return nil, fmt.Errorf("failed to parse %s/%s: %w", o.Kind, o.Metadata.Name, err)
+			}
+			for _, t := range spec.TLS {
+				if t.SecretName == name {
+					add("tls")
+				}
+			}
+		default:
+			spec, err := templateSpec(o)
+			if err != nil {
+				return nil, err
+			}
+			if spec != nil {
+				for _, via := range spec.uses(name) {
+					add(via)
+				}
+			}
+		}
+	}
+	return refs, nil
+}
+
+// templateSpec returns the pod spec of a pod or of the pods a workload
+// creates, or nil for other kinds
+func templateSpec(o object) (*podSpec, error) {
+	var spec podSpec
+	var err error
+	switch o.Kind {
+	case "Pod":
+		err = json.Unmarshal(o.Spec, &spec)
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job":
+		var s struct {
+			Template struct {
+				Spec podSpec `json:"spec"`
+			} `json:"template"`
+		}
+		err = json.Unmarshal(o.Spec, &s)
+		spec = s.Template.Spec
+	case "CronJob":
+		var s struct {
+			JobTemplate struct {
+				Spec struct {
+					Template struct {
+						Spec podSpec `json:"spec"`
+					} `json:"template"`
+				} `json:"spec"`
+			} `json:"jobTemplate"`
+		}
+		err = json.Unmarshal(o.Spec, &s)
+		spec = s.JobTemplate.Spec.Template.Spec
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s/%s: %w", o.Kind, o.Metadata.Name, err)
+	}
+	return &spec, nil
+}
+
+// uses lists how a pod spec uses the Secret called name
+func (s *podSpec) uses(name string) []string {
+	var via []string
+	for _, v := range s.Volumes {
+		if v.Secret != nil && v.Secret.SecretName == name {
+			via = append(via, "volume "+v.Name)
+		}
+		if v.Projected != nil {
+			for _, src := range v.Projected.Sources {
+				if src.Secret != nil && src.Secret.Name == name {
+					via = append(via, "volume "+v.Name)
+				}
+			}
+		}
+	}
+	for _, containers := range [][]container{s.InitContainers, s.Containers, s.EphemeralContainers} {
+		for _, c := range containers {
+			for _, e := range c.Env {
+				if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil && e.ValueFrom.SecretKeyRef.Name == name {
+					via = append(via, "env "+e.Name+" in "+c.Name)
+				}
+			}
+			for _, e := range c.EnvFrom {
+				if e.SecretRef != nil && e.SecretRef.Name == name {
+					via = append(via, "envFrom in "+c.Name)
+				}
+			}
+		}
+	}
+	for _, s := range s.ImagePullSecrets {
+		if s.Name == name {
+			via = append(via, "imagePullSecrets")
+		}
+	}
+	return via
+}
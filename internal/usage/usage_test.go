@@ -0,0 +1,49 @@
+package usage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	list := `{"items":[
+  {"kind":"Deployment","metadata":{"name":"web"},"spec":{"template":{"spec":{
+    "containers":[{"name":"app","env":[{"name":"DB_PASSWORD","valueFrom":{"secretKeyRef":{"name":"db","key":"password"}}},{"name":"PLAIN","value":"x"}]}],
+    "volumes":[{"name":"creds","secret":{"secretName":"db"}},{"name":"other","secret":{"secretName":"tls"}}]}}}},
+  {"kind":"CronJob","metadata":{"name":"backup"},"spec":{"jobTemplate":{"spec":{"template":{"spec":{
+    "initContainers":[{"name":"init","envFrom":[{"secretRef":{"name":"db"}}]}],
+    "volumes":[{"name":"all","projected":{"sources":[{"secret":{"name":"db"}}]}}]}}}}}},
+  {"kind":"Pod","metadata":{"name":"debug"},"spec":{"imagePullSecrets":[{"name":"db"}]}},
+  {"kind":"ServiceAccount","metadata":{"name":"builder"},"secrets":[{"name":"db"}]},
+  {"kind":"Ingress","metadata":{"name":"site"},"spec":{"tls":[{"secretName":"db"}]}},
+  {"kind":"ConfigMap","metadata":{"name":"db"},"data":{"db":"db"}}
+]}`
+
+	refs, err := Find([]byte(list), "db")
+	if err != nil {
+		t.Fatalf("Find() failed: %v", err)
+	}
+	var got []string
+	for _, r := range refs {
+		got = append(got, r.String())
+	}
+	want := []string{
+		"Deployment/web: volume creds",
+		"Deployment/web: env DB_PASSWORD in app",
+		"CronJob/backup: volume all",
+		"CronJob/backup: envFrom in init",
+		"Pod/debug: imagePullSecrets",
+		"ServiceAccount/builder: secrets",
+		"Ingress/site: tls",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() = %q, want %q", got, want)
+	}
+
+	if refs, err := Find([]byte(list), "unused"); err != nil || len(refs) != 0 {
+		t.Errorf("Find(unused) = %v, %v, want no references", refs, err)
+	}
+	if _, err := Find([]byte("{"), "db"); err == nil {
+		t.Error("Find() accepted invalid JSON")
+	}
+}
@@ -0,0 +1,348 @@
+// Package valuediff shows how decoded values changed: word by word or
+// character by character within each value, or by handing them to an
+// external diff tool such as delta or difftastic
+package valuediff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/output"
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/tempdir"
+	"gopkg.in/yaml.v3"
+)
+
+// Built-in algorithms; any other tool name is an external command
+const (
+	Words = "words"
+	Chars = "chars"
+)
+
+// maxCells bounds the comparison table; values differing in more than
+// that are shown as replaced whole
+const maxCells = 4 << 20
+
+// Op is what happened to a part of a value
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// Segment is a run of a value that was kept, deleted, or inserted
+type Segment struct {
+	Op   Op
+	Text string
+}
+
+// Builtin reports whether tool is one of the built-in algorithms
+func Builtin(tool string) bool {
+	return tool == Words || tool == Chars
+}
+
+// Diff compares two values split into words, or into characters with
+// Chars. Words are runs of letters and digits, runs of spaces, and any
+// other character on its own, so a changed digit in a PEM line or a URL
+// doesn't mark the whole line.
+func Diff(old, new, algorithm string) []Segment {
+	split := splitWords
+	if algorithm == Chars {
+		split = splitChars
+	}
+	a, b := split(old), split(new)
+
+	// Most edits touch a small part of a value; the common ends need no
+	// table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var segs []Segment
+	segs = appendSeg(segs, Equal, a[:prefix]...)
+	segs = append(segs, lcs(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	segs = appendSeg(segs, Equal, a[len(a)-suffix:]...)
+	return merge(segs)
+}
+
+// lcs diffs two token lists through their longest common subsequence
+func lcs(a, b []string) []Segment {
+	if len(a)*len(b) > maxCells {
+		return appendSeg(appendSeg(nil, Delete, a...), Insert, b...)
+	}
+	// lengths[i][j] is the LCS length of a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var segs []Segment
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			segs = appendSeg(segs, Equal, a[i])
+			i, j = i+1, j+1
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			segs = appendSeg(segs, Delete, a[i])
+			i++
+		default:
+			segs = appendSeg(segs, Insert, b[j])
+			j++
+		}
+	}
+	segs = appendSeg(segs, Delete, a[i:]...)
+	return appendSeg(segs, Insert, b[j:]...)
+}
+
+func appendSeg(segs []Segment, op Op, tokens ...string) []Segment {
+	for _, t := range tokens {
+		segs = append(segs, Segment{op, t})
+	}
+	return segs
+}
+
+// merge joins neighbouring segments, putting deletions before insertions
+// within each changed run
+func merge(segs []Segment) []Segment {
+	var out []Segment
+	var del, ins strings.Builder
+	flush := func() {
+		if del.Len() > 0 {
+			out = append(out, Segment{Delete, del.String()})
+		}
+		if ins.Len() > 0 {
+			out = append(out, Segment{Insert, ins.String()})
+		}
+		del.Reset()
+		ins.Reset()
+	}
+	for _, s := range segs {
+		switch s.Op {
+		case Delete:
+			del.WriteString(s.Text)
+		case Insert:
+			ins.WriteString(s.Text)
+		default:
+			flush()
+			if n := len(out); n > 0 && out[n-1].Op == Equal {
+				out[n-1].Text += s.Text
+			} else {
+				out = append(out, s)
+			}
+		}
+	}
+	flush()
+	return out
+}
+
+func splitChars(s string) []string {
+	var tokens []string
+	for _, r := range s {
+		tokens = append(tokens, string(r))
+	}
+	return tokens
+}
+
+func splitWords(s string) []string {
+	class := func(r rune) int {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return 1
+		case r == ' ' || r == '\t':
+			return 2
+		}
+		return 0
+	}
+	var tokens []string
+	start, prev := 0, -1
+	for i, r := range s {
+		c := class(r)
+		if i > start && (c == 0 || c != prev) {
+			tokens = append(tokens, s[start:i])
+			start = i
+		}
+		prev = c
+	}
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
+}
+
+// Format writes segments as git's word diff does: in red and green if
+// colors allow, and as [-deleted-]{+inserted+} otherwise
+func Format(segs []Segment, colors output.Terminal) string {
+	var b strings.Builder
+	for _, s := range segs {
+		switch {
+		case s.Op == Equal:
+			b.WriteString(s.Text)
+		case colors.Color && s.Op == Delete:
+			b.WriteString(colors.Paint(s.Text, output.Red))
+		case colors.Color:
+			b.WriteString(colors.Paint(s.Text, output.Green))
+		case s.Op == Delete:
+			b.WriteString("[-" + s.Text + "-]")
+		default:
+			b.WriteString("{+" + s.Text + "+}")
+		}
+	}
+	return b.String()
+}
+
+// Write lists the keys added (+), removed (-), and changed (~) between two
+// sets of decoded values, each followed by its value diff, indented
+func Write(w io.Writer, before, after map[string]string, algorithm string, colors output.Terminal) {
+	keys := map[string]bool{}
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		old, wasSet := before[key]
+		value, isSet := after[key]
+		mark := "~"
+		switch {
+		case !wasSet:
+			mark = "+"
+		case !isSet:
+			mark = "-"
+		case old == value:
+			continue
+		}
+		fmt.Fprintf(w, "%s %s\n", mark, key)
+		diff := Format(Diff(old, value, algorithm), colors)
+		for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+}
+
+// Lines writes a line diff of two texts, such as decoded manifests: lines
+// removed are marked - and lines added +, in red and green if colors
+// allow, with up to context unchanged lines around each change and "..."
+// for the lines left out. Equal texts write nothing.
+func Lines(w io.Writer, old, new string, context int, colors output.Terminal) {
+	a := strings.Split(strings.TrimSuffix(old, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(new, "\n"), "\n")
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var segs []Segment
+	segs = appendSeg(segs, Equal, a[:prefix]...)
+	segs = append(segs, lcs(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	segs = appendSeg(segs, Equal, a[len(a)-suffix:]...)
+
+	// Unchanged lines are shown if a change is at most context lines away
+	shown := make([]bool, len(segs))
+	for i, s := range segs {
+		if s.Op == Equal {
+			continue
+		}
+		for j := max(0, i-context); j <= min(len(segs)-1, i+context); j++ {
+			shown[j] = true
+		}
+	}
+	if !slices.Contains(shown, true) {
+		return
+	}
+	skipped := false
+	for i, s := range segs {
+		if !shown[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			fmt.Fprintln(w, colors.Paint("...", output.Bold))
+			skipped = false
+		}
+		switch s.Op {
+		case Delete:
+			fmt.Fprintln(w, colors.Paint("- "+s.Text, output.Red))
+		case Insert:
+			fmt.Fprintln(w, colors.Paint("+ "+s.Text, output.Green))
+		default:
+			fmt.Fprintln(w, "  "+s.Text)
+		}
+	}
+	if skipped {
+		fmt.Fprintln(w, colors.Paint("...", output.Bold))
+	}
+}
+
+// External runs tool through the shell on two files holding the decoded
+// values before and after, as YAML with one key per entry, e.g. delta or
+// difft. The files are private to the user and removed afterwards. Diff
+// tools exit with 1 when the files differ, which isn't an error.
+func External(tool string, before, after map[string]string, stdout, stderr io.Writer) error {
+	dir, err := tempdir.Dir("swk-diff-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = tempdir.Remove(dir) }()
+
+	var files []string
+	for _, f := range []struct {
+		name   string
+		values map[string]string
+	}{{"before.yaml", before}, {"after.yaml", after}} {
+		data, err := yaml.Marshal(f.values)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return fmt.Errorf("failed to write temp file: %w", err)
+		}
+		files = append(files, path)
+	}
+
+	cmd := exec.Command("sh", "-c", tool+` "$@"`, "swk", files[0], files[1])
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	err = cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == 1 {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("diff tool %s failed: %w", tool, err)
+	}
+	return nil
+}
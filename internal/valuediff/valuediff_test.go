@@ -0,0 +1,95 @@
+package valuediff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/output"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		old, new  string
+		algorithm string
+		want      string
+	}{
+		{"same", "secret", "secret", Words, "secret"},
+		{"word changed", "postgres://app@db-1:5432/app", "postgres://app@db-2:5432/app", Words, "postgres://app@db-[-1-]{+2+}:5432/app"},
+		{"words changed", "user=admin mode=ro", "user=root mode=rw", Words, "user=[-admin-]{+root+} mode=[-ro-]{+rw+}"},
+		{"whole word", "passw0rdA", "passw0rdB", Words, "[-passw0rdA-]{+passw0rdB+}"},
+		{"chars", "passw0rdA", "passw0rdB", Chars, "passw0rd[-A-]{+B+}"},
+		{"added", "", "new", Words, "{+new+}"},
+		{"removed", "old", "", Words, "[-old-]"},
+		{"lines", "-----BEGIN-----\nMIIBabc\nMIIBdef\n-----END-----\n", "-----BEGIN-----\nMIIBabc\nMIIBxyz\n-----END-----\n", Words, "-----BEGIN-----\nMIIBabc\n[-MIIBdef-]{+MIIBxyz+}\n-----END-----\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Format(Diff(tt.old, tt.new, tt.algorithm), output.Terminal{})
+			if got != tt.want {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatColor(t *testing.T) {
+	got := Format(Diff("a b", "a c", Words), output.Terminal{Color: true})
+	if want := "a \x1b[31mb\x1b[0m\x1b[32mc\x1b[0m"; got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var out bytes.Buffer
+	before := map[string]string{"kept": "x", "host": "db-1", "gone": "old"}
+	after := map[string]string{"kept": "x", "host": "db-2", "cert": "line 1\nline 2\n"}
+	Write(&out, before, after, Chars, output.Terminal{})
+
+	want := "+ cert\n    {+line 1\n    line 2\n    +}\n- gone\n    [-old-]\n~ host\n    db-[-1-]{+2+}\n"
+	if out.String() != want {
+		t.Errorf("Write() = %q, want %q", out.String(), want)
+	}
+}
+
+func TestExternal(t *testing.T) {
+	var out, errOut bytes.Buffer
+	before := map[string]string{"password": "old"}
+	after := map[string]string{"password": "new"}
+	if err := External("diff -u", before, after, &out, &errOut); err != nil {
+		t.Fatalf("External() failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "-password: old") || !strings.Contains(out.String(), "+password: new") {
+		t.Errorf("External() output = %q, want the decoded values", out.String())
+	}
+
+	if err := External("false; exit 2", before, after, &out, &errOut); err == nil {
+		t.Error("External() succeeded for a failing tool")
+	}
+}
+
+func TestLines(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\n"
+	tests := []struct {
+		name    string
+		new     string
+		context int
+		want    string
+	}{
+		{"changed", "a\nb\nc\nD\ne\nf\ng\n", 1, "...\n  c\n- d\n+ D\n  e\n...\n"},
+		{"added at end", old + "h\n", 2, "...\n  f\n  g\n+ h\n"},
+		{"removed at start", "b\nc\nd\ne\nf\ng\n", 1, "- a\n  b\n...\n"},
+		{"same", old, 3, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			Lines(&out, old, tt.new, tt.context, output.Terminal{})
+			if out.String() != tt.want {
+				t.Errorf("Lines() = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Package yamlpath resolves simple JSONPath-like expressions against YAML nodes
+package yamlpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Resolve finds the node at a simple JSONPath-like expression such as
+// `.spec.template` or `$.items[0].secret`. An empty path is the root.
+func Resolve(root *yaml.Node, path string) (*yaml.Node, error) {
+	segments, err := Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	node := root
+	for _, seg := range segments {
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+
+		if seg.Index >= 0 {
+			if node.Kind != yaml.SequenceNode {
+				return nil, fmt.Errorf("path %q: %s is not a list", path, seg)
+			}
+			if seg.Index >= len(node.Content) {
+				return nil, fmt.Errorf("path %q: index %d out of range", path, seg.Index)
+			}
+			node = node.Content[seg.Index]
+			continue
+		}
+
+		child := Field(node, seg.Key)
+		if child == nil {
+			return nil, fmt.Errorf("path %q: field %q not found", path, seg.Key)
+		}
+		node = child
+	}
+
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node, nil
+}
+
+// Segment is either a mapping key or a sequence index (Key is unused)
+type Segment struct {
+	Key   string
+	Index int
+}
+
+func (s Segment) String() string {
+	if s.Index >= 0 {
+		return fmt.Sprintf("[%d]", s.Index)
+	}
+	return s.Key
+}
+
+// Parse splits a path like `$.spec.items[0]["odd.key"]` into segments
+func Parse(path string) ([]Segment, error) {
+	p := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	var segments []Segment
+
+	for len(p) > 0 {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid path %q: empty field name", path)
+			}
+			segments = append(segments, Segment{Key: p[:end], Index: -1})
+			p = p[end:]
+		case '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unclosed bracket", path)
+			}
+			inner := p[1:end]
+			p = p[end+1:]
+
+			if unquoted, err := strconv.Unquote(strings.ReplaceAll(inner, "'", `"`)); err == nil {
+				segments = append(segments, Segment{Key: unquoted, Index: -1})
+				continue
+			}
+			i, err := strconv.Atoi(inner)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid path %q: bad index %q", path, inner)
+			}
+			segments = append(segments, Segment{Index: i})
+		default:
+			return nil, fmt.Errorf("invalid path %q: expected '.' or '['", path)
+		}
+	}
+
+	return segments, nil
+}
+
+// Field returns the value of key in a mapping node, or nil
+func Field(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
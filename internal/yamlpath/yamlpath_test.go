@@ -0,0 +1,56 @@
+package yamlpath
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestResolve(t *testing.T) {
+	input := `spec:
+  template:
+    data:
+      key: dmFsdWU=
+  items:
+  - name: first
+  - name: second
+  "odd.key": odd
+`
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(input), &doc); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	root := doc.Content[0]
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"root", "", "", false},
+		{"nested field", ".spec.template.data.key", "dmFsdWU=", false},
+		{"dollar prefix", "$.spec.template.data.key", "dmFsdWU=", false},
+		{"index", ".spec.items[1].name", "second", false},
+		{"quoted key", `.spec["odd.key"]`, "odd", false},
+		{"single quoted key", `.spec['odd.key']`, "odd", false},
+		{"missing field", ".spec.nope", "", true},
+		{"index out of range", ".spec.items[5]", "", true},
+		{"index on mapping", ".spec[0]", "", true},
+		{"empty segment", ".spec..template", "", true},
+		{"unclosed bracket", ".spec.items[0", "", true},
+		{"no leading dot", "spec", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(root, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if err == nil && got.Value != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.path, got.Value, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package secret
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Base64Issue is a data value whose base64 isn't in canonical form
+type Base64Issue struct {
+	Key      string
+	Line     int
+	Problems []string
+}
+
+func (i Base64Issue) String() string {
+	return fmt.Sprintf("line %d: key %q: %s", i.Line, i.Key, strings.Join(i.Problems, ", "))
+}
+
+// CanonicalBase64 returns value as canonical base64: the standard alphabet,
+// padded, on a single line. It accepts what other tools produce, such as
+// URL-safe characters, missing padding, and wrapped lines, and also returns
+// what was wrong with value, or nil if it was canonical already.
+func CanonicalBase64(value string) (string, []string, error) {
+	var problems []string
+	s := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, value)
+	if s != value {
+		problems = append(problems, "embedded whitespace")
+	}
+
+	if strings.ContainsAny(s, "-_") {
+		if strings.ContainsAny(s, "+/") {
+			problems = append(problems, "mixed standard and URL-safe alphabets")
+		} else {
+			problems = append(problems, "URL-safe alphabet")
+		}
+		s = strings.NewReplacer("-", "+", "_", "/").Replace(s)
+	}
+
+	trimmed := strings.TrimRight(s, "=")
+	padded := trimmed + strings.Repeat("=", (4-len(trimmed)%4)%4)
+	if padded != s {
+		problems = append(problems, "wrong padding")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(padded)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid base64: %w", err)
+	}
+	canonical := base64.StdEncoding.EncodeToString(decoded)
+	if canonical != padded {
+		problems = append(problems, "stray bits after the last byte")
+	}
+	return canonical, problems, nil
+}
+
+// Base64Issues returns the data values whose base64 isn't canonical, see
+// CanonicalBase64. Keys listed in the skip-keys annotation are included,
+// since they are base64 in the manifest too.
+func (d *Document) Base64Issues() ([]Base64Issue, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	var issues []Base64Issue
+	for _, e := range d.Data() {
+		_, problems, err := CanonicalBase64(e.Value)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", e.Key, err)
+		}
+		if len(problems) > 0 {
+			issues = append(issues, Base64Issue{Key: e.Key, Line: e.Line, Problems: problems})
+		}
+	}
+	return issues, nil
+}
+
+// NormalizeBase64 rewrites the data values found by Base64Issues in
+// canonical form, leaving the decoded values as they are
+func (d *Document) NormalizeBase64() ([]Base64Issue, error) {
+	issues, err := d.Base64Issues()
+	if err != nil || len(issues) == 0 {
+		return issues, err
+	}
+	return issues, transformData(d.target, d.version, func(_, value string) (string, error) {
+		canonical, _, err := CanonicalBase64(value)
+		return canonical, err
+	})
+}
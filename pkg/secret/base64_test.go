@@ -0,0 +1,72 @@
+package secret
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonicalBase64(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		want     string
+		problems string
+		wantErr  bool
+	}{
+		{"canonical", "c2VjcmV0", "c2VjcmV0", "", false},
+		{"missing padding", "c2VjcmV0MQ", "c2VjcmV0MQ==", "wrong padding", false},
+		{"extra padding", "c2VjcmV0===", "c2VjcmV0", "wrong padding", false},
+		{"wrapped", "c2Vj\ncmV0\n", "c2VjcmV0", "embedded whitespace", false},
+		{"URL-safe", "-_8=", "+/8=", "URL-safe alphabet", false},
+		{"mixed", "+_8=", "+/8=", "mixed standard and URL-safe alphabets", false},
+		{"stray bits", "YR==", "YQ==", "stray bits after the last byte", false},
+		{"invalid", "not base64!", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, problems, err := CanonicalBase64(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CanonicalBase64() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || strings.Join(problems, ", ") != tt.problems {
+				t.Errorf("CanonicalBase64() = %q, %v, want %q, %s", got, problems, tt.want, tt.problems)
+			}
+		})
+	}
+}
+
+func TestNormalizeBase64(t *testing.T) {
+	input := `apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+data:
+  clean: c2VjcmV0
+  short: c2VjcmV0MQ
+  wrapped: |
+    c2Vj
+    cmV0
+`
+	doc, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	issues, err := doc.NormalizeBase64()
+	if err != nil {
+		t.Fatalf("NormalizeBase64() failed: %v", err)
+	}
+	if len(issues) != 2 || issues[0].String() != `line 7: key "short": wrong padding` || issues[1].Key != "wrapped" {
+		t.Errorf("issues = %v", issues)
+	}
+
+	output, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() failed: %v", err)
+	}
+	for _, want := range []string{"clean: c2VjcmV0\n", "short: c2VjcmV0MQ==\n", "wrapped: c2VjcmV0\n"} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Annotations that change how individual keys are handled. They live in the
+// manifest itself, so the behavior travels with the file.
+const (
+	// SkipKeysAnnotation lists keys, comma-separated, that are left base64
+	// encoded while editing, e.g. binary keystores or certificates
+	SkipKeysAnnotation = "swk.dev/skip-keys"
+	// CodecAnnotationPrefix is followed by a key and names the codec used to
+	// present that key's value, e.g. swk.dev/codec.config.json: json-pretty
+	CodecAnnotationPrefix = "swk.dev/codec."
+	// LockedKeysAnnotation lists keys, comma-separated, whose values swk
+	// refuses to change unless explicitly unlocked, e.g. signing keys
+	LockedKeysAnnotation = "swk.dev/locked-keys"
+	// TOTPKeysAnnotation lists keys, comma-separated, that hold TOTP seeds,
+	// for which swk totp shows the current code
+	TOTPKeysAnnotation = "swk.dev/totp-keys"
+	// BreakGlassAnnotation records an emergency edit that bypassed locks and
+	// owners, as JSON with the user, reason, ticket, and changed keys
+	BreakGlassAnnotation = "swk.dev/break-glass"
+	// ApprovalAnnotation records the approval a change was applied with,
+	// as JSON with the request ID, approver, and token
+	ApprovalAnnotation = "swk.dev/approval"
+	// SplitAnnotation marks a part of a Secret that swk split spread over
+	// several, as JSON with the original name and type, the part number,
+	// the number of parts, and the keys chunked across parts
+	SplitAnnotation = "swk.dev/split"
+	// TokenAnnotation records the service account a token written by swk
+	// token create was minted for, as JSON with the service account, its
+	// namespace, the audiences, and the expiry
+	TokenAnnotation = "swk.dev/token"
+)
+
+// Labels swk reads and sets on Secrets in a cluster
+const (
+	// ExportLabel, set to "true", marks Secrets for swk mirror
+	ExportLabel = "swk.dev/export"
+	// DeleteAfterLabel holds the time, as 20060102T150405Z, after which
+	// swk delete --expired deletes the Secret
+	DeleteAfterLabel = "swk.dev/delete-after"
+)
+
+// Codec converts a decoded value into the form it is edited in and back
+type Codec struct {
+	Decode func(string) (string, error)
+	Encode func(string) (string, error)
+}
+
+var codecs = map[string]Codec{
+	"json-pretty": {Decode: prettyJSON, Encode: compactJSON},
+}
+
+// Codecs returns the names of the available codecs
+func Codecs() []string {
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Behaviors are the per-key behaviors configured in a Secret's annotations
+type Behaviors struct {
+	Skip   map[string]bool
+	Locked map[string]bool
+	TOTP   map[string]bool
+	Codecs map[string]Codec
+}
+
+// Behaviors reads the per-key behaviors from the Secret's annotations. An
+// unknown codec is an error rather than being ignored, since it would
+// otherwise change how the value is saved.
+func (d *Document) Behaviors() (Behaviors, error) {
+	b := Behaviors{Skip: map[string]bool{}, Locked: map[string]bool{}, TOTP: map[string]bool{}, Codecs: map[string]Codec{}}
+	for name, value := range d.Metadata().Annotations {
+		switch {
+		case name == SkipKeysAnnotation:
+			b.Skip = keySet(value)
+		case name == LockedKeysAnnotation:
+			b.Locked = keySet(value)
+		case name == TOTPKeysAnnotation:
+			b.TOTP = keySet(value)
+		case strings.HasPrefix(name, CodecAnnotationPrefix):
+			key := strings.ReplaceAll(strings.TrimPrefix(name, CodecAnnotationPrefix), `\.`, ".")
+			codec, ok := codecs[value]
+			if !ok {
+				return Behaviors{}, fmt.Errorf("annotation %s: unknown codec %q (available: %s)", name, value, strings.Join(Codecs(), ", "))
+			}
+			b.Codecs[key] = codec
+		}
+	}
+	return b, nil
+}
+
+// LockViolations returns the keys locked in before that after changes,
+// sorted. A locked key is changed if ChangedKeys reports it or if after no
+// longer locks it.
+func LockViolations(before, after *Document) ([]string, error) {
+	b, err := before.Behaviors()
+	if err != nil {
+		return nil, err
+	}
+	if len(b.Locked) == 0 {
+		return nil, nil
+	}
+	a, err := after.Behaviors()
+	if err != nil {
+		return nil, err
+	}
+
+	changed := map[string]bool{}
+	for _, key := range ChangedKeys(before, after) {
+		changed[key] = true
+	}
+
+	var violations []string
+	for key := range b.Locked {
+		if !a.Locked[key] || changed[key] {
+			violations = append(violations, key)
+		}
+	}
+	sort.Strings(violations)
+	return violations, nil
+}
+
+// keySet parses a comma-separated list of keys
+func keySet(value string) map[string]bool {
+	keys := map[string]bool{}
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// prettyJSON indents a JSON value for editing
+func prettyJSON(value string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(value), "", "  "); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+	return buf.String() + "\n", nil
+}
+
+// compactJSON strips the indentation added by prettyJSON
+func compactJSON(value string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(value)); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,148 @@
+package secret
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBehaviors(t *testing.T) {
+	input := `apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+  annotations:
+    swk.dev/skip-keys: "keystore.jks, tls.key"
+    swk.dev/codec.config.json: json-pretty
+data:
+  config.json: eyJhIjoxLCJiIjpbMSwyXX0=
+  keystore.jks: AAECAw==
+  password: c2VjcmV0
+`
+
+	decoded, err := DecodeSecretData([]byte(input))
+	if err != nil {
+		t.Fatalf("DecodeSecretData() failed: %v", err)
+	}
+	for _, want := range []string{
+		"config.json: |\n    {\n      \"a\": 1,\n      \"b\": [\n        1,\n        2\n      ]\n    }\n",
+		"keystore.jks: AAECAw==",
+		"password: secret",
+	} {
+		if !strings.Contains(string(decoded), want) {
+			t.Errorf("decoded output missing %q:\n%s", want, decoded)
+		}
+	}
+
+	encoded, err := EncodeSecretData(decoded)
+	if err != nil {
+		t.Fatalf("EncodeSecretData() failed: %v", err)
+	}
+	if string(encoded) != input {
+		t.Errorf("round trip changed the manifest:\n%s\nwant:\n%s", encoded, input)
+	}
+}
+
+func TestBehaviorErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		encode  bool
+		wantErr string
+	}{
+		{
+			name:    "unknown codec",
+			input:   "apiVersion: v1\nkind: Secret\nmetadata:\n  annotations:\n    swk.dev/codec.a: rot13\ndata:\n  a: Yg==\n",
+			wantErr: `unknown codec "rot13"`,
+		},
+		{
+			name:    "value is not JSON",
+			input:   "apiVersion: v1\nkind: Secret\nmetadata:\n  annotations:\n    swk.dev/codec.a: json-pretty\ndata:\n  a: Yg==\n",
+			wantErr: "invalid JSON",
+		},
+		{
+			name:    "edited value is not JSON",
+			input:   "apiVersion: v1\nkind: Secret\nmetadata:\n  annotations:\n    swk.dev/codec.a: json-pretty\ndata:\n  a: '{\"a\": '\n",
+			encode:  true,
+			wantErr: `key "a": invalid JSON`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transform := DecodeSecretData
+			if tt.encode {
+				transform = EncodeSecretData
+			}
+			_, err := transform([]byte(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLockViolations(t *testing.T) {
+	const before = `apiVersion: v1
+kind: Secret
+metadata:
+  annotations:
+    swk.dev/locked-keys: master-key
+data:
+  master-key: c2VjcmV0
+  password: b2xk
+`
+	tests := []struct {
+		name  string
+		after string
+		want  string
+	}{
+		{
+			name:  "other key changed",
+			after: strings.Replace(before, "b2xk", "bmV3", 1),
+		},
+		{
+			name:  "re-encoded only",
+			after: strings.Replace(before, "c2VjcmV0", "|\n    c2Vj\n    cmV0", 1),
+		},
+		{
+			name:  "locked key changed",
+			after: strings.Replace(before, "c2VjcmV0", "b3RoZXI=", 1),
+			want:  "master-key",
+		},
+		{
+			name:  "locked key removed",
+			after: strings.Replace(before, "  master-key: c2VjcmV0\n", "", 1),
+			want:  "master-key",
+		},
+		{
+			name:  "lock dropped",
+			after: strings.Replace(before, "master-key\n", "password\n", 1),
+			want:  "master-key",
+		},
+		{
+			name:  "overridden by stringData",
+			after: before + "stringData:\n  master-key: other\n",
+			want:  "master-key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := Parse([]byte(before))
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, err := Parse([]byte(tt.after))
+			if err != nil {
+				t.Fatal(err)
+			}
+			changed, err := LockViolations(b, a)
+			if err != nil {
+				t.Fatalf("LockViolations() failed: %v", err)
+			}
+			if got := strings.Join(changed, ","); got != tt.want {
+				t.Errorf("LockViolations() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package secret
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// largeSecret builds an encoded Secret with n keys, every tenth one holding
+// a multi-line value like a certificate
+func largeSecret(n int) []byte {
+	var b strings.Builder
+	b.WriteString("apiVersion: v1\nkind: Secret\nmetadata:\n  name: large\n  namespace: default\ntype: Opaque\ndata:\n")
+	for i := range n {
+		value := fmt.Sprintf("value-%d-%s", i, strings.Repeat("x", 32))
+		if i%10 == 0 {
+			value = strings.Repeat("-----BEGIN LINE-----\n", 20)
+		}
+		fmt.Fprintf(&b, "  key-%d: %s\n", i, base64.StdEncoding.EncodeToString([]byte(value)))
+	}
+	return []byte(b.String())
+}
+
+// editSeparately is what a decode/encode round trip costs with the
+// standalone functions: every call parses the document again
+func editSeparately(input []byte) error {
+	if !IsSecret(input) {
+		return fmt.Errorf("not a Secret")
+	}
+	decoded, err := DecodeSecretData(input)
+	if err != nil {
+		return err
+	}
+	_, err = EncodeSecretData(decoded)
+	return err
+}
+
+// editDocument does the same round trip on a single parsed Document, as the
+// editor wrapper does
+func editDocument(input []byte) error {
+	d, err := Parse(input)
+	if err != nil {
+		return err
+	}
+	if !d.IsSecret() {
+		return fmt.Errorf("not a Secret")
+	}
+	if err := d.Decode(); err != nil {
+		return err
+	}
+	decoded, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+
+	edited, err := Parse(decoded)
+	if err != nil {
+		return err
+	}
+	if err := edited.Encode(); err != nil {
+		return err
+	}
+	_, err = edited.Bytes()
+	return err
+}
+
+func BenchmarkIsSecret(b *testing.B) {
+	input := largeSecret(1000)
+	b.SetBytes(int64(len(input)))
+	for b.Loop() {
+		IsSecret(input)
+	}
+}
+
+func BenchmarkDecodeSecretData(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		input := largeSecret(n)
+		b.Run(fmt.Sprintf("keys=%d", n), func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			for b.Loop() {
+				if _, err := DecodeSecretData(input); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEncodeSecretData(b *testing.B) {
+	decoded, err := DecodeSecretData(largeSecret(1000))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(decoded)))
+	for b.Loop() {
+		if _, err := EncodeSecretData(decoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRoundTrip(b *testing.B) {
+	input := largeSecret(1000)
+	for _, bench := range []struct {
+		name string
+		fn   func([]byte) error
+	}{{"separate", editSeparately}, {"document", editDocument}} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := bench.fn(input); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestPerformanceBudget keeps the editor round trip on a shared Document
+// cheaper than separate calls, and bounds allocations per key so that a
+// change that parses or copies the document once more per key shows up
+// as a test failure rather than a slow release
+func TestPerformanceBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping performance budget in short mode")
+	}
+	const keys = 200
+	input := largeSecret(keys)
+
+	separate := testing.AllocsPerRun(5, func() { _ = editSeparately(input) })
+	document := testing.AllocsPerRun(5, func() { _ = editDocument(input) })
+	if document >= separate {
+		t.Errorf("Document round trip allocates %.0f times, separate calls %.0f; want fewer", document, separate)
+	}
+
+	const budgetPerKey = 50
+	if perKey := document / keys; perKey > budgetPerKey {
+		t.Errorf("Document round trip allocates %.0f times per key, budget is %d", perKey, budgetPerKey)
+	}
+}
@@ -0,0 +1,74 @@
+package secret
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BinaryMarker is the comment after a value that DecodeText left base64
+// encoded because it isn't text. Encode passes marked values through
+// unchanged, so adding the marker to a value is also how to give one as
+// base64 while editing.
+const BinaryMarker = "swk:binary"
+
+// IsBinary reports whether the data value of key is marked with
+// BinaryMarker, and so still base64 after DecodeText
+func (d *Document) IsBinary(key string) bool {
+	marked := false
+	d.eachValue(func(k string, value *yaml.Node) {
+		if k == key {
+			marked = binaryMarked(value)
+		}
+	})
+	return marked
+}
+
+// SetBinary marks or unmarks the data value of key with BinaryMarker, e.g.
+// to unmark a value that was replaced with plaintext after DecodeText
+func (d *Document) SetBinary(key string, binary bool) {
+	d.eachValue(func(k string, value *yaml.Node) {
+		if k != key {
+			return
+		}
+		if binary && !binaryMarked(value) {
+			markBinary(value)
+		} else if !binary {
+			unmarkBinary(value)
+		}
+	})
+}
+
+// markBinary puts BinaryMarker in front of a value's line comment
+func markBinary(value *yaml.Node) {
+	if text := commentText(value.LineComment); text != "" {
+		value.LineComment = "# " + BinaryMarker + " " + text
+		return
+	}
+	value.LineComment = "# " + BinaryMarker
+}
+
+// unmarkBinary removes BinaryMarker from a value's line comment, reporting
+// whether it was there
+func unmarkBinary(value *yaml.Node) bool {
+	if !binaryMarked(value) {
+		return false
+	}
+	if rest := strings.TrimSpace(strings.TrimPrefix(commentText(value.LineComment), BinaryMarker)); rest != "" {
+		value.LineComment = "# " + rest
+	} else {
+		value.LineComment = ""
+	}
+	return true
+}
+
+// binaryMarked reports whether a value's line comment has BinaryMarker
+func binaryMarked(value *yaml.Node) bool {
+	rest, ok := strings.CutPrefix(commentText(value.LineComment), BinaryMarker)
+	return ok && (rest == "" || rest[0] == ' ')
+}
+
+// commentText returns a comment without its # and surrounding spaces
+func commentText(comment string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(comment), "#"))
+}
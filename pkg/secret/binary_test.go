@@ -0,0 +1,78 @@
+package secret
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeTextBinary(t *testing.T) {
+	// //79 is invalid UTF-8 and AAECAw== control characters
+	input := "apiVersion: v1\nkind: Secret\ndata:\n  keystore: //79 # from the vendor\n  raw: AAECAw==\n  password: aHVudGVyMg==\n"
+	decoded, warnings, err := DecodeWithWarnings([]byte(input), "")
+	if err != nil {
+		t.Fatalf("DecodeWithWarnings() failed: %v", err)
+	}
+	want := "  keystore: //79 # swk:binary from the vendor\n  raw: AAECAw== # swk:binary\n  password: hunter2\n"
+	if !strings.HasSuffix(string(decoded), want) {
+		t.Errorf("decoded = %q, want suffix %q", decoded, want)
+	}
+	if len(warnings) != 2 || warnings[0].Kind != WarnBinary || warnings[1].Kind != WarnBinary {
+		t.Errorf("warnings = %v, want both binary values", warnings)
+	}
+
+	encoded, err := EncodeSecretData(decoded)
+	if err != nil {
+		t.Fatalf("EncodeSecretData() failed: %v", err)
+	}
+	if string(encoded) != input {
+		t.Errorf("round trip = %q, want %q", encoded, input)
+	}
+
+	d, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.DecodeText(); err != nil {
+		t.Fatalf("DecodeText() failed: %v", err)
+	}
+	if !d.IsBinary("raw") || d.IsBinary("password") {
+		t.Errorf("IsBinary() = %v, %v, want only raw", d.IsBinary("raw"), d.IsBinary("password"))
+	}
+
+	// Decode, for callers that want the bytes, decodes binary values too
+	if d, err = Parse([]byte(input)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Decode(); err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if got := d.Values()["raw"]; got != "\x00\x01\x02\x03" {
+		t.Errorf("raw = %q, want the bytes", got)
+	}
+}
+
+func TestEncodeBinaryMarker(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"marked base64 passes through", "AAECAw== # swk:binary", "AAECAw==", false},
+		{"other comments stay", "AAECAw== #swk:binary keep me", "AAECAw== # keep me", false},
+		{"marker must be a word", "x # swk:binaryish", "eA== # swk:binaryish", false},
+		{"marked value must be base64", "not base64! # swk:binary", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := EncodeSecretData([]byte("apiVersion: v1\nkind: Secret\ndata:\n  k: " + tt.value + "\n"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EncodeSecretData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !strings.HasSuffix(string(out), "  k: "+tt.want+"\n") {
+				t.Errorf("EncodeSecretData() = %q, want value %q", out, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Canonical rewrites a YAML manifest, or a stream of them, in one fixed
+// form, so that manifests with the same content have the same bytes:
+// mapping keys sorted, as kubectl sorts them, block style throughout,
+// scalars plain unless their value needs quotes, multiline strings as
+// literal blocks, two-space indentation, and no document marker before a
+// single document. Comments stay with the nodes they belong to.
+func Canonical(input []byte) ([]byte, error) {
+	return canonical(input, false)
+}
+
+// CanonicalEncoded is Canonical for encoded manifests: the data values of
+// every Secret in the stream are also rewritten in canonical base64, see
+// CanonicalBase64. Values that aren't base64 are left as they are.
+func CanonicalEncoded(input []byte) ([]byte, error) {
+	return canonical(input, true)
+}
+
+func canonical(input []byte, encoded bool) ([]byte, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(input))
+	var docs []*yaml.Node
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		canonicalize(&doc)
+		if encoded && len(doc.Content) > 0 {
+			canonicalData(doc.Content[0])
+		}
+		docs = append(docs, &doc)
+	}
+	if len(docs) == 0 {
+		return input, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, doc := range docs {
+		if err := encoder.Encode(doc); err != nil {
+			return nil, fmt.Errorf("failed to marshal YAML: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalize sorts the mappings below node and resets its styles
+func canonicalize(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		// An explicit tag is content, how the value is quoted isn't; the
+		// encoder adds quotes where a plain value would read differently
+		style := node.Style & yaml.TaggedStyle
+		if node.Tag == "!!str" && containsNewline(node.Value) {
+			style |= yaml.LiteralStyle
+		}
+		node.Style = style
+		return
+	case yaml.MappingNode:
+		pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+		}
+		sort.SliceStable(pairs, func(i, j int) bool { return pairs[i][0].Value < pairs[j][0].Value })
+		node.Content = node.Content[:0]
+		for _, p := range pairs {
+			node.Content = append(node.Content, p[0], p[1])
+		}
+	}
+	node.Style &= yaml.TaggedStyle
+	for _, child := range node.Content {
+		canonicalize(child)
+	}
+}
+
+// canonicalData rewrites the data values of a Secret in canonical base64
+func canonicalData(root *yaml.Node) {
+	version, ok := detectVersion(root)
+	if !ok {
+		return
+	}
+	data := findField(root, version.DataField)
+	if data == nil || data.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 1; i < len(data.Content); i += 2 {
+		if value, _, err := CanonicalBase64(data.Content[i].Value); err == nil {
+			data.Content[i].Value, data.Content[i].Style = value, 0
+		}
+	}
+}
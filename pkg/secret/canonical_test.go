@@ -0,0 +1,76 @@
+package secret
+
+import "testing"
+
+func TestCanonical(t *testing.T) {
+	want := `apiVersion: v1
+data:
+  password: c2VjcmV0
+  username: YWRtaW4=
+kind: Secret
+metadata:
+  labels:
+    app: web
+  name: db
+stringData:
+  config: |
+    a: 1
+    b: 2
+  enabled: "true"
+type: Opaque
+`
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"canonical", want},
+		{"reordered and quoted", `---
+kind: Secret
+apiVersion: "v1"
+metadata: {name: db, labels: {app: 'web'}}
+type: Opaque
+data:
+    username: "YWRtaW4="
+    password: c2VjcmV0
+stringData:
+    enabled: 'true'
+    config: "a: 1\nb: 2\n"
+`},
+		{"JSON", `{"type": "Opaque", "stringData": {"enabled": "true", "config": "a: 1\nb: 2\n"}, "metadata": {"name": "db", "labels": {"app": "web"}}, "kind": "Secret", "data": {"username": "YWRtaW4=", "password": "c2VjcmV0"}, "apiVersion": "v1"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Canonical([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Canonical() failed: %v", err)
+			}
+			if string(got) != want {
+				t.Errorf("Canonical() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestCanonicalStream(t *testing.T) {
+	got, err := Canonical([]byte("b: 1\na: 2\n---\n# kept\nd: [x, y]\nc: !!binary aGk=\n"))
+	if err != nil {
+		t.Fatalf("Canonical() failed: %v", err)
+	}
+	want := "a: 2\nb: 1\n---\nc: !!binary aGk=\n# kept\nd:\n  - x\n  - y\n"
+	if string(got) != want {
+		t.Errorf("Canonical() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCanonicalEncoded(t *testing.T) {
+	input := "kind: Secret\napiVersion: v1\ndata:\n  a: |\n    YWJj\n    ZA\n  b: not base64!\n---\nkind: ConfigMap\napiVersion: v1\ndata:\n  a: YWJjZA\n"
+	got, err := CanonicalEncoded([]byte(input))
+	if err != nil {
+		t.Fatalf("CanonicalEncoded() failed: %v", err)
+	}
+	want := "apiVersion: v1\ndata:\n  a: YWJjZA==\n  b: not base64!\nkind: Secret\n---\napiVersion: v1\ndata:\n  a: YWJjZA\nkind: ConfigMap\n"
+	if string(got) != want {
+		t.Errorf("CanonicalEncoded() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,44 @@
+package secret
+
+import "sort"
+
+// ChangedKeys returns the keys whose value differs between before and
+// after, including added and removed keys, sorted. Values are compared as
+// they would be applied: decoded data overridden by stringData, so
+// re-encoding a value or moving it between data and stringData alone isn't
+// a change.
+func ChangedKeys(before, after *Document) []string {
+	old, new := appliedValues(before), appliedValues(after)
+
+	var changed []string
+	for key, value := range old {
+		if v, ok := new[key]; !ok || v != value {
+			changed = append(changed, key)
+		}
+	}
+	for key := range new {
+		if _, ok := old[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// appliedValues returns each key's value as the API server would store it.
+// Values that aren't valid base64 are kept as written, so any edit to them
+// still counts as a change.
+func appliedValues(d *Document) map[string]string {
+	values := map[string]string{}
+	for _, e := range d.Data() {
+		if decoded, err := decodeBase64(e.Value); err == nil {
+			values[e.Key] = decoded
+		} else {
+			values[e.Key] = "\x00" + e.Value
+		}
+	}
+	for _, e := range d.StringData() {
+		values[e.Key] = e.Value
+	}
+	return values
+}
@@ -0,0 +1,190 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ConstraintAnnotationPrefix is followed by a key and holds constraints on
+// its value as JSON, e.g. swk.dev/constraint.password: {"maxLength": 72}
+const ConstraintAnnotationPrefix = "swk.dev/constraint."
+
+// Constraint limits the values a key may hold, to catch values that would
+// break the application at runtime before they are saved
+type Constraint struct {
+	// Keys is a glob pattern of the keys the constraint applies to. Only
+	// the config uses it; an annotation names its key.
+	Keys string `yaml:"keys" json:"-"`
+	// MinLength and MaxLength bound the length of the value in bytes
+	MinLength int `yaml:"minLength" json:"minLength,omitempty"`
+	MaxLength int `yaml:"maxLength" json:"maxLength,omitempty"`
+	// Pattern is a regular expression the value must match; anchor it with
+	// ^ and $ to match the whole value
+	Pattern string `yaml:"pattern" json:"pattern,omitempty"`
+	// Charset names the characters the value may contain, see Charsets
+	Charset string `yaml:"charset" json:"charset,omitempty"`
+}
+
+// charset is a named set of characters a value may be limited to
+type charset struct {
+	description string
+	allows      func(rune) bool
+}
+
+var charsets = map[string]charset{
+	"alphanumeric": {"letters and digits", func(r rune) bool { return isAlnum(r) }},
+	"base64":       {"base64", func(r rune) bool { return isAlnum(r) || r == '+' || r == '/' || r == '=' }},
+	"base64url":    {"URL-safe base64", func(r rune) bool { return isAlnum(r) || r == '-' || r == '_' || r == '=' }},
+	"hex":          {"hexadecimal digits", func(r rune) bool { return isDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F') }},
+	"printable":    {"printable ASCII, including spaces", func(r rune) bool { return r >= ' ' && r <= '~' }},
+	"visible":      {"printable ASCII without spaces", func(r rune) bool { return r > ' ' && r <= '~' }},
+}
+
+// Charsets returns the names of the available charsets
+func Charsets() []string {
+	names := make([]string, 0, len(charsets))
+	for name := range charsets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that the keys pattern, lengths, regular expression, and
+// charset make sense
+func (c Constraint) Validate() error {
+	if _, err := path.Match(c.Keys, ""); err != nil {
+		return fmt.Errorf("invalid keys pattern %q", c.Keys)
+	}
+	if c.MinLength < 0 || c.MaxLength < 0 {
+		return fmt.Errorf("lengths can't be negative")
+	}
+	if c.MaxLength > 0 && c.MinLength > c.MaxLength {
+		return fmt.Errorf("minLength %d is more than maxLength %d", c.MinLength, c.MaxLength)
+	}
+	if _, err := regexp.Compile(c.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	if _, ok := charsets[c.Charset]; c.Charset != "" && !ok {
+		return fmt.Errorf("unknown charset %q (available: %s)", c.Charset, strings.Join(Charsets(), ", "))
+	}
+	return nil
+}
+
+// Check returns every way value breaks the constraint, which must be valid
+func (c Constraint) Check(value string) []string {
+	var problems []string
+	if c.MaxLength > 0 && len(value) > c.MaxLength {
+		problems = append(problems, fmt.Sprintf("is %d bytes, more than the maximum of %d", len(value), c.MaxLength))
+	}
+	if len(value) < c.MinLength {
+		problems = append(problems, fmt.Sprintf("is %d bytes, less than the minimum of %d", len(value), c.MinLength))
+	}
+	if cs, ok := charsets[c.Charset]; ok {
+		pos := 0
+		for _, r := range value {
+			pos++
+			if !cs.allows(r) {
+				problems = append(problems, fmt.Sprintf("has %s at position %d, outside charset %s (%s)", describeRune(r), pos, c.Charset, cs.description))
+				break
+			}
+		}
+	}
+	if c.Pattern != "" && !regexp.MustCompile(c.Pattern).MatchString(value) {
+		problems = append(problems, fmt.Sprintf("doesn't match pattern %s", c.Pattern))
+	}
+	return problems
+}
+
+// Constraints reads the constraints in the Secret's annotations, by key
+func (d *Document) Constraints() (map[string]Constraint, error) {
+	constraints := map[string]Constraint{}
+	for name, value := range d.Metadata().Annotations {
+		if !strings.HasPrefix(name, ConstraintAnnotationPrefix) {
+			continue
+		}
+		key := strings.ReplaceAll(strings.TrimPrefix(name, ConstraintAnnotationPrefix), `\.`, ".")
+		var c Constraint
+		dec := json.NewDecoder(strings.NewReader(value))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&c); err != nil {
+			return nil, fmt.Errorf("annotation %s: invalid constraint: %w", name, err)
+		}
+		if err := c.Validate(); err != nil {
+			return nil, fmt.Errorf("annotation %s: %w", name, err)
+		}
+		constraints[key] = c
+	}
+	return constraints, nil
+}
+
+// ConstraintViolations checks the values of keys, as the API server would
+// store them, against the constraints in the Secret's annotations and the
+// rules whose pattern matches them. Every rule that matches applies. Each
+// violation reads as `key "user" has a space at position 4, ...`, in the
+// order of keys.
+func ConstraintViolations(d *Document, rules []Constraint, keys []string) ([]string, error) {
+	annotated, err := d.Constraints()
+	if err != nil {
+		return nil, err
+	}
+	values := appliedValues(d)
+
+	var violations []string
+	for _, key := range keys {
+		value, ok := values[key]
+		if !ok || strings.HasPrefix(value, "\x00") {
+			// Removed, or not valid base64, which encoding reports
+			continue
+		}
+		var applied []Constraint
+		for _, rule := range rules {
+			if ok, _ := path.Match(rule.Keys, key); ok {
+				applied = append(applied, rule)
+			}
+		}
+		if c, ok := annotated[key]; ok {
+			applied = append(applied, c)
+		}
+		for _, c := range applied {
+			for _, problem := range c.Check(value) {
+				violations = append(violations, fmt.Sprintf("key %q %s", key, problem))
+			}
+		}
+	}
+	return violations, nil
+}
+
+// describeRune names a character for a message, spelling out the ones that
+// are hard to see
+func describeRune(r rune) string {
+	switch r {
+	case ' ':
+		return "a space"
+	case '\t':
+		return "a tab"
+	case '\n':
+		return "a newline"
+	case '\r':
+		return "a carriage return"
+	case utf8.RuneError:
+		return "an invalid UTF-8 byte"
+	}
+	if r < ' ' || r == 0x7f {
+		return fmt.Sprintf("control character %U", r)
+	}
+	return fmt.Sprintf("%q", r)
+}
+
+func isAlnum(r rune) bool {
+	return isDigit(r) || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
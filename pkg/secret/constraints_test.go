@@ -0,0 +1,112 @@
+package secret
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConstraintCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint Constraint
+		value      string
+		want       []string
+	}{
+		{"fits", Constraint{MaxLength: 72, Charset: "visible", Pattern: "^[a-z]+$"}, "admin", nil},
+		{"too long", Constraint{MaxLength: 4}, "admin", []string{"is 5 bytes, more than the maximum of 4"}},
+		{"too short", Constraint{MinLength: 8}, "admin", []string{"is 5 bytes, less than the minimum of 8"}},
+		{"bytes, not characters", Constraint{MaxLength: 4}, "héé", []string{"is 5 bytes"}},
+		{"space", Constraint{Charset: "visible"}, "ad min", []string{"has a space at position 3, outside charset visible"}},
+		{"newline", Constraint{Charset: "printable"}, "admin\n", []string{"has a newline at position 6"}},
+		{"non-ASCII", Constraint{Charset: "alphanumeric"}, "héllo", []string{`has 'é' at position 2`}},
+		{"pattern", Constraint{Pattern: "^[a-z]+$"}, "Admin", []string{"doesn't match pattern ^[a-z]+$"}},
+		{"several", Constraint{MaxLength: 2, Charset: "hex"}, "xyz", []string{"more than the maximum of 2", "has 'x' at position 1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.constraint.Check(tt.value)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Check(%q) = %q, want %d problems", tt.value, got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("Check(%q)[%d] = %q, want it to contain %q", tt.value, i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestConstraintValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint Constraint
+		wantErr    bool
+	}{
+		{"empty", Constraint{}, false},
+		{"full", Constraint{Keys: "db-*", MinLength: 8, MaxLength: 72, Pattern: "^\\S+$", Charset: "printable"}, false},
+		{"bad keys", Constraint{Keys: "[db"}, true},
+		{"negative", Constraint{MaxLength: -1}, true},
+		{"min over max", Constraint{MinLength: 10, MaxLength: 5}, true},
+		{"bad pattern", Constraint{Pattern: "(a"}, true},
+		{"unknown charset", Constraint{Charset: "latin1"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.constraint.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConstraintViolations(t *testing.T) {
+	input := `apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+  annotations:
+    swk.dev/constraint.username: '{"charset": "visible"}'
+    swk.dev/constraint.api\.key: '{"minLength": 10}'
+data:
+  username: am9obiBkb2U=
+  password: c2VjcmV0
+  api.key: c2hvcnQ=
+stringData:
+  db-password: hunter2
+`
+	doc, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	rules := []Constraint{{Keys: "*password", MinLength: 8}}
+
+	got, err := ConstraintViolations(doc, rules, []string{"api.key", "db-password", "password", "username"})
+	if err != nil {
+		t.Fatalf("ConstraintViolations() failed: %v", err)
+	}
+	want := []string{
+		`key "api.key" is 5 bytes, less than the minimum of 10`,
+		`key "db-password" is 7 bytes, less than the minimum of 8`,
+		`key "password" is 6 bytes, less than the minimum of 8`,
+		`key "username" has a space at position 5, outside charset visible (printable ASCII without spaces)`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ConstraintViolations() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	got, err = ConstraintViolations(doc, rules, []string{"username"})
+	if err != nil || len(got) != 1 {
+		t.Errorf("ConstraintViolations() for username only = %q, %v; want one violation", got, err)
+	}
+
+	bad, err := Parse([]byte(strings.Replace(input, `{"charset": "visible"}`, `{"maxLen": 3}`, 1)))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if _, err := ConstraintViolations(bad, nil, []string{"username"}); err == nil || !strings.Contains(err.Error(), "swk.dev/constraint.username") {
+		t.Errorf("ConstraintViolations() with an unknown field error = %v, want it to name the annotation", err)
+	}
+}
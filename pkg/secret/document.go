@@ -0,0 +1,365 @@
+package secret
+
+import (
+	"fmt"
+
+	"github.com/davidschrooten/secret-wrapper-k8s/internal/yamlpath"
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a manifest parsed once, so that checking, transforming,
+// validating, and marshalling it all share one yaml.Node tree instead of
+// each parsing the input again
+type Document struct {
+	doc     yaml.Node
+	target  *yaml.Node // the Secret, nil if there is none
+	version Version
+	err     error // why the document isn't a Secret
+	layout  layout
+
+	warnings []Warning
+}
+
+// Parse parses a manifest whose root is a Secret
+func Parse(input []byte) (*Document, error) {
+	return ParseAt(input, "")
+}
+
+// ParseAt parses a manifest and locates the Secret at path (see
+// DecodeSecretDataAt). Only invalid YAML, which includes duplicate keys in
+// the Secret (see DuplicateKeyError), is an error; use IsSecret to find out
+// whether a Secret was found.
+func ParseAt(input []byte, path string) (*Document, error) {
+	return ParseKeep(input, path, KeepNone)
+}
+
+// IsSecret reports whether the document holds a Secret at its path
+func (d *Document) IsSecret() bool {
+	return d.err == nil
+}
+
+// Version returns the API version of the Secret
+func (d *Document) Version() Version {
+	return d.version
+}
+
+// Root returns the top-level node of the whole manifest, or nil for an
+// empty document
+func (d *Document) Root() *yaml.Node {
+	if len(d.doc.Content) == 0 {
+		return nil
+	}
+	return d.doc.Content[0]
+}
+
+// Entry is a key and its value in a Secret's data or stringData
+type Entry struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// Metadata holds the commonly used fields of a Secret's metadata
+type Metadata struct {
+	Name        string
+	Namespace   string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// Secret returns the Secret's mapping node: the root, or the node at the
+// path given to ParseAt. It is nil if the document isn't a Secret.
+func (d *Document) Secret() *yaml.Node {
+	return d.target
+}
+
+// Type returns the Secret's type, e.g. Opaque
+func (d *Document) Type() string {
+	if d.target == nil {
+		return ""
+	}
+	return fieldValue(d.target, "type")
+}
+
+// Metadata returns the Secret's name, namespace, labels, and annotations
+func (d *Document) Metadata() Metadata {
+	if d.target == nil {
+		return Metadata{}
+	}
+	metadata := findField(d.target, "metadata")
+	if metadata == nil {
+		return Metadata{}
+	}
+	return Metadata{
+		Name:        fieldValue(metadata, "name"),
+		Namespace:   fieldValue(metadata, "namespace"),
+		Labels:      stringMap(findField(metadata, "labels")),
+		Annotations: stringMap(findField(metadata, "annotations")),
+	}
+}
+
+// Data returns the entries of the data section in document order. Values are
+// as they currently are in the document: base64 until Decode is called.
+func (d *Document) Data() []Entry {
+	return d.entries(d.version.DataField)
+}
+
+// StringData returns the entries of the stringData section in document order
+func (d *Document) StringData() []Entry {
+	return d.entries(d.version.StringDataField)
+}
+
+// Values returns the data section as a map, see Data
+func (d *Document) Values() map[string]string {
+	values := make(map[string]string)
+	for _, e := range d.Data() {
+		values[e.Key] = e.Value
+	}
+	return values
+}
+
+// SetData replaces the data section with entries, adding the section if
+// the Secret has none. Values must be in the document's current form. The
+// section keeps its style, e.g. flow, and keys that were there already keep
+// their comments, and their style too if their value is unchanged.
+func (d *Document) SetData(entries []Entry) {
+	if d.target == nil {
+		return
+	}
+
+	data := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	old := findField(d.target, d.version.DataField)
+	if old != nil && old.Kind == yaml.MappingNode {
+		data.Style = old.Style
+	} else {
+		old = nil
+	}
+	for _, e := range entries {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: e.Key}
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: e.Value}
+		if old != nil {
+			for i := 0; i+1 < len(old.Content); i += 2 {
+				if k, v := old.Content[i], old.Content[i+1]; k.Value == e.Key && v.Kind == yaml.ScalarNode {
+					key = k
+					if v.Value == e.Value {
+						value = v
+					} else {
+						value.Style = rewrittenStyle(v.Style, e.Value)
+						copyComments(value, v)
+					}
+					break
+				}
+			}
+		}
+		if value.Style == 0 && containsNewline(e.Value) {
+			value.Style = yaml.LiteralStyle
+		}
+		data.Content = append(data.Content, key, value)
+	}
+
+	for i := 0; i+1 < len(d.target.Content); i += 2 {
+		if d.target.Content[i].Value == d.version.DataField {
+			d.target.Content[i+1] = data
+			return
+		}
+	}
+	if len(entries) > 0 {
+		d.target.Content = append(d.target.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: d.version.DataField}, data)
+	}
+}
+
+func (d *Document) entries(field string) []Entry {
+	if d.target == nil {
+		return nil
+	}
+	section := findField(d.target, field)
+	if section == nil || section.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var entries []Entry
+	for i := 0; i+1 < len(section.Content); i += 2 {
+		key, value := section.Content[i], section.Content[i+1]
+		entries = append(entries, Entry{Key: key.Value, Value: value.Value, Line: key.Line})
+	}
+	return entries
+}
+
+// stringMap reads a mapping of scalars, such as labels or annotations
+func stringMap(node *yaml.Node) map[string]string {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	m := make(map[string]string, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		m[node.Content[i].Value] = node.Content[i+1].Value
+	}
+	return m
+}
+
+// Decode decodes the base64 values of the Secret in place, applying the
+// per-key behaviors from its annotations (see Behaviors), and records
+// warnings about the values (see Warnings)
+func (d *Document) Decode() error {
+	return d.decode(false)
+}
+
+// DecodeText decodes like Decode, but leaves values that aren't text, e.g.
+// keystores or gzip blobs, base64 encoded and marked with a BinaryMarker
+// comment, so that the result can be edited and encoded again
+func (d *Document) DecodeText() error {
+	return d.decode(true)
+}
+
+func (d *Document) decode(text bool) error {
+	if d.err != nil {
+		return d.err
+	}
+	b, err := d.Behaviors()
+	if err != nil {
+		return err
+	}
+	d.warnings = d.inspect(b, true)
+	binaryKeys := map[string]bool{}
+	err = transformData(d.target, d.version, func(key, value string) (string, error) {
+		if b.Skip[key] {
+			return value, nil
+		}
+		decoded, err := decodeBase64(value)
+		if err != nil {
+			return "", err
+		}
+		if text && binary(decoded) {
+			binaryKeys[key] = true
+			return value, nil
+		}
+		if codec, ok := b.Codecs[key]; ok {
+			return codec.Decode(decoded)
+		}
+		return decoded, nil
+	})
+	if err != nil {
+		return err
+	}
+	d.eachValue(func(key string, value *yaml.Node) {
+		if binaryKeys[key] {
+			markBinary(value)
+		}
+	})
+	return nil
+}
+
+// Encode encodes the plaintext values of the Secret in place, see Decode.
+// Values marked with a BinaryMarker are already base64 and are only
+// checked; the marker is removed.
+func (d *Document) Encode() error {
+	if d.err != nil {
+		return d.err
+	}
+	b, err := d.Behaviors()
+	if err != nil {
+		return err
+	}
+	d.warnings = d.inspect(b, false)
+	binaryKeys := map[string]bool{}
+	d.eachValue(func(key string, value *yaml.Node) {
+		if unmarkBinary(value) {
+			binaryKeys[key] = true
+		}
+	})
+	return transformData(d.target, d.version, func(key, value string) (string, error) {
+		if b.Skip[key] {
+			return value, nil
+		}
+		if binaryKeys[key] {
+			if _, err := decodeBase64(value); err != nil {
+				return "", fmt.Errorf("marked %s, but %w", BinaryMarker, err)
+			}
+			return value, nil
+		}
+		if codec, ok := b.Codecs[key]; ok {
+			var err error
+			if value, err = codec.Encode(value); err != nil {
+				return "", err
+			}
+		}
+		return encodeBase64(value)
+	})
+}
+
+// eachValue calls fn with every key and value node in the data section
+func (d *Document) eachValue(fn func(key string, value *yaml.Node)) {
+	if d.target == nil {
+		return
+	}
+	data := findField(d.target, d.version.DataField)
+	if data == nil || data.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(data.Content); i += 2 {
+		fn(data.Content[i].Value, data.Content[i+1])
+	}
+}
+
+// KeepUnchanged restores the data values of original that d, after Encode,
+// encodes to the same bytes, so that values an edit didn't change keep their
+// exact form, e.g. wrapped, quoted, or otherwise non-canonical base64. d
+// also takes on original's indentation, document marker, and final newline.
+func (d *Document) KeepUnchanged(original *Document) {
+	if original == nil {
+		return
+	}
+	d.layout = original.layout
+	if d.target == nil || original.target == nil {
+		return
+	}
+	data := findField(d.target, d.version.DataField)
+	old := findField(original.target, original.version.DataField)
+	if data == nil || old == nil || data.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(data.Content); i += 2 {
+		value, prev := data.Content[i+1], findField(old, data.Content[i].Value)
+		if prev == nil || prev.Kind != yaml.ScalarNode || value.Kind != yaml.ScalarNode {
+			continue
+		}
+		if canonical, _, err := CanonicalBase64(prev.Value); err == nil && canonical == value.Value {
+			value.Value, value.Style = prev.Value, prev.Style
+		}
+	}
+}
+
+// Bytes marshals the whole manifest, in the layout it was parsed from
+func (d *Document) Bytes() ([]byte, error) {
+	output, err := marshalWithIndent(&d.doc, d.layout.indent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	return d.layout.apply(output), nil
+}
+
+// locate finds the Secret at path within a parsed document
+func locate(doc *yaml.Node, path string) (*yaml.Node, Version, error) {
+	if path == "" {
+		version, err := validateSecret(doc)
+		if err != nil {
+			return nil, Version{}, err
+		}
+		return doc.Content[0], version, nil
+	}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, Version{}, fmt.Errorf("invalid YAML document")
+	}
+	target, err := yamlpath.Resolve(doc.Content[0], path)
+	if err != nil {
+		return nil, Version{}, err
+	}
+	version, err := embeddedVersion(target)
+	if err != nil {
+		return nil, Version{}, err
+	}
+	return target, version, nil
+}
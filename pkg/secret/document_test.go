@@ -0,0 +1,220 @@
+package secret
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const documentInput = `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  namespace: prod
+  labels:
+    app: api
+  annotations:
+    owner: team-a
+type: Opaque
+data:
+  username: YWRtaW4=
+  password: c2VjcmV0
+stringData:
+  host: db.internal
+`
+
+func TestDocumentAccessors(t *testing.T) {
+	d, err := Parse([]byte(documentInput))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if !d.IsSecret() {
+		t.Fatal("IsSecret() = false")
+	}
+
+	wantMeta := Metadata{
+		Name:        "db",
+		Namespace:   "prod",
+		Labels:      map[string]string{"app": "api"},
+		Annotations: map[string]string{"owner": "team-a"},
+	}
+	if got := d.Metadata(); !reflect.DeepEqual(got, wantMeta) {
+		t.Errorf("Metadata() = %+v, want %+v", got, wantMeta)
+	}
+	if d.Type() != "Opaque" {
+		t.Errorf("Type() = %q", d.Type())
+	}
+
+	// Values stay encoded until Decode
+	if got := d.Values()["password"]; got != "c2VjcmV0" {
+		t.Errorf("Values() before Decode = %q", got)
+	}
+	if err := d.Decode(); err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	wantData := []Entry{{"username", "admin", 12}, {"password", "secret", 13}}
+	if got := d.Data(); !reflect.DeepEqual(got, wantData) {
+		t.Errorf("Data() = %+v, want %+v", got, wantData)
+	}
+	wantStringData := []Entry{{"host", "db.internal", 15}}
+	if got := d.StringData(); !reflect.DeepEqual(got, wantStringData) {
+		t.Errorf("StringData() = %+v, want %+v", got, wantStringData)
+	}
+}
+
+func TestDocumentNotSecret(t *testing.T) {
+	d, err := Parse([]byte("apiVersion: v1\nkind: ConfigMap\ndata:\n  a: b\n"))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if d.IsSecret() {
+		t.Error("IsSecret() = true for a ConfigMap")
+	}
+	if err := d.Decode(); err == nil {
+		t.Error("Decode() should fail for a ConfigMap")
+	}
+	if d.Data() != nil || d.Metadata().Name != "" {
+		t.Error("accessors should be empty for a ConfigMap")
+	}
+
+	if _, err := Parse(nil); err == nil {
+		t.Error("Parse() should fail for empty input")
+	}
+	if _, err := Parse([]byte("a: [")); err == nil {
+		t.Error("Parse() should fail for invalid YAML")
+	}
+}
+
+func TestDocumentSetData(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		entries []Entry
+		want    string
+	}{
+		{
+			name:    "replaces data",
+			input:   documentInput,
+			entries: []Entry{{Key: "token", Value: "abc"}},
+			want:    "data:\n  token: YWJj\nstringData:\n  host: db.internal\n",
+		},
+		{
+			name:    "adds data",
+			input:   "apiVersion: v1\nkind: Secret\nmetadata:\n  name: empty\n",
+			entries: []Entry{{Key: "token", Value: "abc"}},
+			want:    "data:\n  token: YWJj\n",
+		},
+		{
+			name:    "keeps styles",
+			input:   "apiVersion: v1\nkind: Secret\ndata: {token: 'eHl6', old: eA==}\n",
+			entries: []Entry{{Key: "token", Value: "abc"}},
+			want:    "data: {token: 'YWJj'}\n",
+		},
+		{
+			name:    "keeps comments",
+			input:   "apiVersion: v1\nkind: Secret\ndata:\n  # rotated monthly\n  token: eHl6 # from vault\n",
+			entries: []Entry{{Key: "token", Value: "abc"}},
+			want:    "data:\n  # rotated monthly\n  token: YWJj # from vault\n",
+		},
+		{
+			name:  "no empty section",
+			input: "apiVersion: v1\nkind: Secret\nmetadata:\n  name: empty\n",
+			want:  "name: empty\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Parse([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			d.SetData(tt.entries)
+			if err := d.Encode(); err != nil {
+				t.Fatalf("Encode() failed: %v", err)
+			}
+			out, err := d.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() failed: %v", err)
+			}
+			if !strings.HasSuffix(string(out), tt.want) {
+				t.Errorf("Bytes() = %q, want suffix %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentStyles(t *testing.T) {
+	input := `apiVersion: v1
+kind: Secret
+metadata: {name: app, labels: {tier: db}}
+data: {a: 'eA==', b: "eQ==", c: eg==}
+stringData:
+  note: >
+    kept as
+    it is
+`
+	d, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if err := d.Decode(); err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if err := d.Encode(); err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	out, err := d.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() failed: %v", err)
+	}
+	for _, want := range []string{
+		"metadata: {name: app, labels: {tier: db}}\n",
+		`data: {a: 'eA==', b: "eQ==", c: eg==}` + "\n",
+		"note: >\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("round trip lost %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDocumentKeepUnchanged(t *testing.T) {
+	original := "apiVersion: v1\nkind: Secret\ndata:\n  wrapped: |\n    c2Vj\n    cmV0\n  short: c2VjcmV0MQ\n  changed: c2VjcmV0\n"
+	edited := "apiVersion: v1\nkind: Secret\ndata:\n  wrapped: secret\n  short: secret1\n  changed: other\n"
+
+	before, err := Parse([]byte(original))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	d, err := Parse([]byte(edited))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if err := d.Encode(); err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	d.KeepUnchanged(before)
+
+	want := "data:\n  wrapped: |\n    c2Vj\n    cmV0\n  short: c2VjcmV0MQ\n  changed: b3RoZXI=\n"
+	if out, _ := d.Bytes(); !strings.HasSuffix(string(out), want) {
+		t.Errorf("Bytes() = %q, want suffix %q", out, want)
+	}
+}
+
+func TestDocumentEmbedded(t *testing.T) {
+	input := "kind: Wrapper\nspec:\n  template:\n    metadata:\n      name: inner\n    data:\n      key: dmFsdWU=\n"
+	d, err := ParseAt([]byte(input), ".spec.template")
+	if err != nil {
+		t.Fatalf("ParseAt() failed: %v", err)
+	}
+	if err := d.Decode(); err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if d.Metadata().Name != "inner" || d.Values()["key"] != "value" {
+		t.Errorf("Metadata() = %+v, Values() = %v", d.Metadata(), d.Values())
+	}
+	if d.Root() == nil || fieldValue(d.Root(), "kind") != "Wrapper" {
+		t.Error("Root() should be the wrapping resource")
+	}
+}
@@ -0,0 +1,128 @@
+package secret
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Keep says which value of a duplicated key to keep
+type Keep string
+
+const (
+	// KeepNone makes duplicate keys an error
+	KeepNone Keep = ""
+	// KeepFirst keeps the value given first
+	KeepFirst Keep = "first"
+	// KeepLast keeps the value given last, as yaml.v3 does when decoding
+	// into a map
+	KeepLast Keep = "last"
+)
+
+// DuplicateKeyError is a key given twice in one mapping of a Secret.
+// yaml.v3 silently keeps one of the values, but in a Secret a duplicate is
+// almost always a mistake.
+type DuplicateKeyError struct {
+	Mapping   string // e.g. data or metadata.annotations, "" for the Secret itself
+	Key       string
+	Line      int
+	FirstLine int
+}
+
+func (e *DuplicateKeyError) Error() string {
+	where := "the Secret"
+	if e.Mapping != "" {
+		where = e.Mapping
+	}
+	return fmt.Sprintf("line %d: duplicate key %q in %s, first set on line %d", e.Line, e.Key, where, e.FirstLine)
+}
+
+// ParseKeep parses like ParseAt, but resolves duplicate keys in the Secret,
+// its metadata, and its data as keep says instead of failing
+func ParseKeep(input []byte, path string, keep Keep) (*Document, error) {
+	if len(input) == 0 {
+		return nil, fmt.Errorf("empty input")
+	}
+
+	d := &Document{layout: detectLayout(input)}
+	if err := yaml.Unmarshal(input, &d.doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	d.target, d.version, d.err = locate(&d.doc, path)
+	if d.err == nil {
+		if duplicates := dedupe(d.target, d.version, keep); len(duplicates) > 0 {
+			return nil, &duplicates[0]
+		}
+	}
+	return d, nil
+}
+
+// Duplicates lists the duplicate keys in a Secret node, its metadata, and
+// its data, without changing it
+func Duplicates(node *yaml.Node, version Version) []DuplicateKeyError {
+	return dedupe(node, version, KeepNone)
+}
+
+// dedupe resolves the duplicate keys in the mappings of a Secret, or with
+// KeepNone only lists them. The Secret itself goes first, so that a
+// duplicated data field is resolved before its keys are looked at.
+func dedupe(node *yaml.Node, version Version, keep Keep) []DuplicateKeyError {
+	duplicates := dedupeMapping(node, "", keep)
+
+	metadata := findField(node, "metadata")
+	mappings := []struct {
+		name string
+		node *yaml.Node
+	}{
+		{"metadata", metadata},
+		{"metadata.labels", nil},
+		{"metadata.annotations", nil},
+		{version.DataField, findField(node, version.DataField)},
+		{version.StringDataField, findField(node, version.StringDataField)},
+	}
+	if metadata != nil {
+		mappings[1].node = findField(metadata, "labels")
+		mappings[2].node = findField(metadata, "annotations")
+	}
+	for _, m := range mappings {
+		if m.node != nil && m.node.Kind == yaml.MappingNode {
+			duplicates = append(duplicates, dedupeMapping(m.node, m.name, keep)...)
+		}
+	}
+	return duplicates
+}
+
+// dedupeMapping resolves the duplicate keys of a mapping, keeping each key
+// where it first appeared
+func dedupeMapping(node *yaml.Node, name string, keep Keep) []DuplicateKeyError {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var duplicates []DuplicateKeyError
+	index := map[string]int{}
+	var content []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		j, seen := index[key.Value]
+		if !seen {
+			index[key.Value] = len(content)
+			content = append(content, key, value)
+			continue
+		}
+
+		switch keep {
+		case KeepFirst:
+		case KeepLast:
+			// The kept value comes with the comments written next to it
+			copyComments(key, content[j])
+			content[j], content[j+1] = key, value
+		default:
+			duplicates = append(duplicates, DuplicateKeyError{Mapping: name, Key: key.Value, Line: key.Line, FirstLine: content[j].Line})
+		}
+	}
+	if keep != KeepNone {
+		node.Content = content
+	}
+	return duplicates
+}
@@ -0,0 +1,93 @@
+package secret
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseKeep(t *testing.T) {
+	input := `apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+  annotations:
+    owner: a
+    owner: b
+data:
+  password: Zmlyc3Q=
+  username: YWRtaW4=
+  password: bGFzdA==
+`
+	tests := []struct {
+		keep     Keep
+		password string
+		owner    string
+	}{
+		{KeepFirst, "first", "a"},
+		{KeepLast, "last", "b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.keep), func(t *testing.T) {
+			d, err := ParseKeep([]byte(input), "", tt.keep)
+			if err != nil {
+				t.Fatalf("ParseKeep() failed: %v", err)
+			}
+			if err := d.Decode(); err != nil {
+				t.Fatalf("Decode() failed: %v", err)
+			}
+			var keys []string
+			for _, e := range d.Data() {
+				keys = append(keys, e.Key)
+			}
+			if strings.Join(keys, ",") != "password,username" {
+				t.Errorf("keys = %v, want each key once, in first position", keys)
+			}
+			if got := d.Values()["password"]; got != tt.password {
+				t.Errorf("password = %q, want %q", got, tt.password)
+			}
+			if got := d.Metadata().Annotations["owner"]; got != tt.owner {
+				t.Errorf("owner = %q, want %q", got, tt.owner)
+			}
+		})
+	}
+
+	_, err := Parse([]byte(input))
+	var dup *DuplicateKeyError
+	if !errors.As(err, &dup) {
+		t.Fatalf("Parse() error = %v, want a DuplicateKeyError", err)
+	}
+	if dup.Error() != `line 7: duplicate key "owner" in metadata.annotations, first set on line 6` {
+		t.Errorf("Error() = %q", dup.Error())
+	}
+}
+
+func TestParseDuplicateSection(t *testing.T) {
+	input := "apiVersion: v1\nkind: Secret\ndata:\n  a: eA==\ndata:\n  a: eQ==\n  a: eg==\n"
+	if _, err := Parse([]byte(input)); err == nil || !strings.Contains(err.Error(), `"data" in the Secret`) {
+		t.Errorf("Parse() error = %v, want the duplicated data field", err)
+	}
+	d, err := ParseKeep([]byte(input), "", KeepLast)
+	if err != nil {
+		t.Fatalf("ParseKeep() failed: %v", err)
+	}
+	if got := d.Values()["a"]; got != "eg==" {
+		t.Errorf("a = %q, want the last value of the last data field", got)
+	}
+}
+
+func TestParseKeepLastComments(t *testing.T) {
+	input := "apiVersion: v1\nkind: Secret\ndata:\n  a: eA== # old\n  b: eQ==\n  # rotated\n  a: eg== # new\n"
+	d, err := ParseKeep([]byte(input), "", KeepLast)
+	if err != nil {
+		t.Fatalf("ParseKeep() failed: %v", err)
+	}
+	out, err := d.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() failed: %v", err)
+	}
+	if want := "data:\n  # rotated\n  a: eg== # new\n  b: eQ==\n"; !strings.HasSuffix(string(out), want) {
+		t.Errorf("Bytes() = %q, want suffix %q", out, want)
+	}
+}
@@ -0,0 +1,52 @@
+package secret
+
+import (
+	"bytes"
+)
+
+// layout is how a manifest was written in ways yaml.Node doesn't record,
+// so that marshalling it again changes no more than it has to
+type layout struct {
+	indent    int  // spaces per nesting level
+	marker    bool // starts with a "---" line
+	noNewline bool // doesn't end with a newline
+}
+
+// detectLayout finds the layout of a manifest. The indentation is that of
+// the least indented nested line, which is one level deep; files without
+// any, or with an indentation yaml can't write, get 2 spaces.
+func detectLayout(input []byte) layout {
+	l := layout{
+		indent:    2,
+		marker:    bytes.HasPrefix(input, []byte("---\n")) || bytes.HasPrefix(input, []byte("---\r\n")),
+		noNewline: len(input) > 0 && input[len(input)-1] != '\n',
+	}
+
+	least := 0
+	for _, line := range bytes.Split(input, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " ")
+		n := len(line) - len(trimmed)
+		if n == 0 || len(bytes.TrimSpace(trimmed)) == 0 || trimmed[0] == '#' {
+			continue
+		}
+		if least == 0 || n < least {
+			least = n
+		}
+	}
+	if least >= 2 && least <= 9 {
+		l.indent = least
+	}
+	return l
+}
+
+// apply restores the document marker and missing final newline of the
+// layout on marshalled output
+func (l layout) apply(output []byte) []byte {
+	if l.marker && !bytes.HasPrefix(output, []byte("---\n")) {
+		output = append([]byte("---\n"), output...)
+	}
+	if l.noNewline {
+		output = bytes.TrimSuffix(output, []byte("\n"))
+	}
+	return output
+}
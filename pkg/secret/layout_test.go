@@ -0,0 +1,67 @@
+package secret
+
+import (
+	"testing"
+)
+
+func TestDetectLayout(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  layout
+	}{
+		{"two spaces", "a:\n  b: c\n  d:\n    e: f\n", layout{indent: 2}},
+		{"four spaces", "a:\n    b: c\n    # comment\n", layout{indent: 4}},
+		{"comments don't count", "a:\n      # comment\n    b: c\n", layout{indent: 4}},
+		{"flat", "a: b\n", layout{indent: 2}},
+		{"compact sequence", "a:\n- b\n", layout{indent: 2}},
+		{"marker and no newline", "---\na:\n  b: c", layout{indent: 2, marker: true, noNewline: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectLayout([]byte(tt.input)); got != tt.want {
+				t.Errorf("detectLayout() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBytesKeepsLayout(t *testing.T) {
+	input := "---\napiVersion: v1\nkind: Secret\nmetadata:\n    name: db\ndata:\n    password: aHVudGVyMg=="
+	d, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if err := d.Decode(); err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	decoded, err := d.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() failed: %v", err)
+	}
+	if want := "---\napiVersion: v1\nkind: Secret\nmetadata:\n    name: db\ndata:\n    password: hunter2"; string(decoded) != want {
+		t.Errorf("Bytes() = %q, want %q", decoded, want)
+	}
+
+	// An editor adding a final newline doesn't change the encoded file's
+	edited, err := Parse(append(decoded, '\n'))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if err := edited.Encode(); err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	original, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	edited.KeepUnchanged(original)
+	encoded, err := edited.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() failed: %v", err)
+	}
+	if string(encoded) != input {
+		t.Errorf("Bytes() = %q, want %q", encoded, input)
+	}
+}
@@ -0,0 +1,105 @@
+package secret
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// validKey matches the keys the API server accepts in data and stringData
+var validKey = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// Renamed is a key renamed by RenameKeys
+type Renamed struct {
+	Old, New string
+}
+
+// listAnnotations are the per-key annotations holding comma-separated keys
+var listAnnotations = []string{SkipKeysAnnotation, LockedKeysAnnotation, TOTPKeysAnnotation}
+
+// keyAnnotationPrefixes are followed by the key they configure
+var keyAnnotationPrefixes = []string{CodecAnnotationPrefix, ConstraintAnnotationPrefix}
+
+// RenameKeys renames the data and stringData keys for which rename returns
+// another name, in document order. Keys keep their place, values, and
+// comments, and the per-key annotations follow them, including those that
+// start with one of prefixes and end in the key. A new name the API
+// server wouldn't accept, or one that is already taken, is an error, and
+// then nothing is renamed.
+func (d *Document) RenameKeys(rename func(string) string, prefixes ...string) ([]Renamed, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	var renamed []Renamed
+	names := map[string]string{}
+	taken := map[string]string{}
+	var keyNodes []*yaml.Node
+	for _, field := range []string{d.version.DataField, d.version.StringDataField} {
+		section := findField(d.target, field)
+		if section == nil || section.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(section.Content); i += 2 {
+			key := section.Content[i]
+			name := key.Value
+			if n := rename(key.Value); n != key.Value {
+				if !validKey.MatchString(n) {
+					return nil, fmt.Errorf("can't rename %q to %q: keys may only hold letters, digits, -, _, and .", key.Value, n)
+				}
+				name = n
+				names[key.Value] = n
+				renamed = append(renamed, Renamed{Old: key.Value, New: n})
+				keyNodes = append(keyNodes, key)
+			}
+			// A key may be in both data and stringData, as one key
+			if other, ok := taken[field+"\x00"+name]; ok {
+				return nil, fmt.Errorf("keys %q and %q would both be called %q", other, key.Value, name)
+			}
+			taken[field+"\x00"+name] = key.Value
+		}
+	}
+	for _, key := range keyNodes {
+		key.Value = names[key.Value]
+	}
+	d.renameAnnotations(names, append(prefixes, keyAnnotationPrefixes...))
+	return renamed, nil
+}
+
+// renameAnnotations renames keys in the per-key annotations
+func (d *Document) renameAnnotations(names map[string]string, prefixes []string) {
+	annotations := findField(d.target, "metadata")
+	if annotations != nil {
+		annotations = findField(annotations, "annotations")
+	}
+	if annotations == nil || annotations.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(annotations.Content); i += 2 {
+		name, value := annotations.Content[i], annotations.Content[i+1]
+		for _, list := range listAnnotations {
+			if name.Value != list {
+				continue
+			}
+			keys := strings.Split(value.Value, ",")
+			for j, key := range keys {
+				if n, ok := names[strings.TrimSpace(key)]; ok {
+					keys[j] = strings.Replace(key, strings.TrimSpace(key), n, 1)
+				}
+			}
+			value.Value = strings.Join(keys, ",")
+		}
+		for _, prefix := range prefixes {
+			key, ok := strings.CutPrefix(name.Value, prefix)
+			if !ok {
+				continue
+			}
+			if n, ok := names[strings.ReplaceAll(key, `\.`, ".")]; ok {
+				name.Value = prefix + n
+			}
+		}
+	}
+}
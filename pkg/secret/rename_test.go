@@ -0,0 +1,95 @@
+package secret
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenameKeys(t *testing.T) {
+	input := `apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+  annotations:
+    swk.dev/locked-keys: "DB_PASSWORD, API_KEY"
+    swk.dev/codec.DB_CONFIG: json-pretty
+    generator.example/DB_PASSWORD: password:32
+data:
+  DB_HOST: ZGI= # primary
+  DB_PASSWORD: c2VjcmV0
+  API_KEY: a2V5
+stringData:
+  DB_CONFIG: '{}'
+`
+	doc, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	renamed, err := doc.RenameKeys(func(key string) string {
+		if rest, ok := strings.CutPrefix(key, "DB_"); ok {
+			return "DATABASE_" + rest
+		}
+		return key
+	}, "generator.example/")
+	if err != nil {
+		t.Fatalf("RenameKeys() failed: %v", err)
+	}
+	if len(renamed) != 3 || renamed[0] != (Renamed{"DB_HOST", "DATABASE_HOST"}) {
+		t.Errorf("RenameKeys() = %v, want DB_HOST, DB_PASSWORD, and DB_CONFIG renamed", renamed)
+	}
+
+	out, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`swk.dev/locked-keys: "DATABASE_PASSWORD, API_KEY"`,
+		"swk.dev/codec.DATABASE_CONFIG: json-pretty",
+		"generator.example/DATABASE_PASSWORD: password:32",
+		"DATABASE_HOST: ZGI= # primary\n  DATABASE_PASSWORD: c2VjcmV0\n  API_KEY: a2V5",
+		"DATABASE_CONFIG: '{}'",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenameKeysErrors(t *testing.T) {
+	input := "apiVersion: v1\nkind: Secret\ndata:\n  a: YQ==\n  b: Yg==\n"
+	tests := []struct {
+		name   string
+		rename func(string) string
+		want   string
+	}{
+		{"taken", func(k string) string { return "b" }, `keys "a" and "b" would both be called "b"`},
+		{"same new name", func(k string) string { return "c" }, `would both be called "c"`},
+		{"invalid", func(k string) string { return k + "/x" }, "keys may only hold"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := doc.RenameKeys(tt.rename); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("RenameKeys() error = %v, want %q", err, tt.want)
+			}
+			if keys := doc.Values(); keys["a"] == "" || keys["b"] == "" {
+				t.Errorf("keys renamed despite the error: %v", keys)
+			}
+		})
+	}
+
+	doc, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	swap := map[string]string{"a": "b", "b": "a"}
+	if _, err := doc.RenameKeys(func(k string) string { return swap[k] }); err != nil {
+		t.Errorf("swapping keys failed: %v", err)
+	}
+	if v := doc.Values(); v["a"] != "Yg==" || v["b"] != "YQ==" {
+		t.Errorf("values after swap = %v", v)
+	}
+}
@@ -0,0 +1,241 @@
+package secret
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IsSecret checks if the given YAML is a Kubernetes Secret resource
+func IsSecret(input []byte) bool {
+	return IsSecretAt(input, "")
+}
+
+// IsSecretAt checks if the node at path (see DecodeSecretDataAt) is a Secret
+func IsSecretAt(input []byte, path string) bool {
+	d, err := ParseAt(input, path)
+	return err == nil && d.IsSecret()
+}
+
+// DecodeSecretData takes a Kubernetes Secret YAML and decodes all base64 values in the data section.
+// Use Document to decode and inspect or change the result without parsing it again.
+func DecodeSecretData(input []byte) ([]byte, error) {
+	return DecodeSecretDataAt(input, "")
+}
+
+// DecodeSecretDataAt decodes the Secret found at path within a larger
+// document, e.g. `.spec.template` of a custom resource wrapping a Secret.
+// An empty path means the document itself is the Secret. Binary values stay
+// base64 encoded, see DecodeText.
+func DecodeSecretDataAt(input []byte, path string) ([]byte, error) {
+	return transformDocument(input, path, (*Document).DecodeText)
+}
+
+// EncodeSecretData takes a Kubernetes Secret YAML with plaintext data and encodes values to base64
+func EncodeSecretData(input []byte) ([]byte, error) {
+	return EncodeSecretDataAt(input, "")
+}
+
+// EncodeSecretDataAt encodes the Secret found at path, see DecodeSecretDataAt
+func EncodeSecretDataAt(input []byte, path string) ([]byte, error) {
+	return transformDocument(input, path, (*Document).Encode)
+}
+
+// transformDocument parses input, decodes or encodes the Secret at path, and
+// marshals the whole document back
+func transformDocument(input []byte, path string, transform func(*Document) error) ([]byte, error) {
+	d, err := ParseAt(input, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := transform(d); err != nil {
+		return nil, err
+	}
+	return d.Bytes()
+}
+
+// validateSecret checks if the YAML is a valid Kubernetes Secret and returns its version
+func validateSecret(doc *yaml.Node) (Version, error) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return Version{}, fmt.Errorf("invalid YAML document")
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return Version{}, fmt.Errorf("expected YAML mapping")
+	}
+
+	// Find and validate "kind" field
+	kind := findField(root, "kind")
+	if kind == nil || kind.Value != "Secret" {
+		return Version{}, fmt.Errorf("not a Secret resource")
+	}
+
+	version, ok := detectVersion(root)
+	if !ok {
+		return Version{}, fmt.Errorf("unsupported Secret apiVersion %q", fieldValue(root, "apiVersion"))
+	}
+
+	return version, nil
+}
+
+// detectVersion resolves the version matching a manifest's apiVersion and
+// kind, see ResolveVersion
+func detectVersion(root *yaml.Node) (Version, bool) {
+	kind := fieldValue(root, "kind")
+	if kind == "" {
+		return Version{}, false
+	}
+	return ResolveVersion(fieldValue(root, "apiVersion"), kind)
+}
+
+// embeddedVersion determines the version of a Secret embedded in another
+// resource. Embedded specs often omit apiVersion and kind, in which case
+// they are treated as v1.
+func embeddedVersion(node *yaml.Node) (Version, error) {
+	if node.Kind != yaml.MappingNode {
+		return Version{}, fmt.Errorf("expected YAML mapping at Secret path")
+	}
+
+	kind := fieldValue(node, "kind")
+	if kind == "" {
+		return V1, nil
+	}
+	if kind != "Secret" {
+		return Version{}, fmt.Errorf("not a Secret resource")
+	}
+
+	version, ok := detectVersion(node)
+	if !ok {
+		return Version{}, fmt.Errorf("unsupported Secret apiVersion %q", fieldValue(node, "apiVersion"))
+	}
+	return version, nil
+}
+
+// fieldValue returns the scalar value of a field in a mapping node, or ""
+func fieldValue(node *yaml.Node, key string) string {
+	if field := findField(node, key); field != nil && field.Kind == yaml.ScalarNode {
+		return field.Value
+	}
+	return ""
+}
+
+// findField finds a field in a YAML mapping node
+func findField(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// transformData applies a transformation function to all values in the version's data section
+func transformData(root *yaml.Node, version Version, transform func(key, value string) (string, error)) error {
+	dataNode := findField(root, version.DataField)
+
+	if dataNode == nil || dataNode.Kind != yaml.MappingNode {
+		// No data section or empty, nothing to transform
+		return nil
+	}
+
+	// Transform each value in the data section
+	for i := 1; i < len(dataNode.Content); i += 2 {
+		valueNode := dataNode.Content[i]
+
+		// Handle scalar values
+		if valueNode.Kind == yaml.ScalarNode {
+			transformed, err := transform(dataNode.Content[i-1].Value, valueNode.Value)
+			if err != nil {
+				return fmt.Errorf("failed to transform key %q: %w", dataNode.Content[i-1].Value, err)
+			}
+			if transformed == valueNode.Value {
+				// Untouched values keep their style, e.g. skipped keys
+				continue
+			}
+			valueNode.Value = transformed
+			valueNode.Style = rewrittenStyle(valueNode.Style, transformed)
+		}
+	}
+
+	return nil
+}
+
+// rewrittenStyle picks the style of a rewritten value: literal for multiline
+// strings, which the encoder turns into double quotes inside flow mappings,
+// and otherwise the quotes the value had, if any. Block styles of the old
+// value, such as wrapped base64, don't suit the new one.
+func rewrittenStyle(old yaml.Style, value string) yaml.Style {
+	if containsNewline(value) {
+		return yaml.LiteralStyle
+	}
+	return old & (yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle)
+}
+
+// copyComments gives dst the comments of src that it has none of its own
+// for, so that a node replacing another keeps what was written about it
+func copyComments(dst, src *yaml.Node) {
+	if dst.HeadComment == "" {
+		dst.HeadComment = src.HeadComment
+	}
+	if dst.LineComment == "" {
+		dst.LineComment = src.LineComment
+	}
+	if dst.FootComment == "" {
+		dst.FootComment = src.FootComment
+	}
+}
+
+// decodeBase64 decodes a base64 string
+func decodeBase64(encoded string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64: %w", err)
+	}
+	return string(decoded), nil
+}
+
+// encodeBase64 encodes a string to base64
+func encodeBase64(plain string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(plain)), nil
+}
+
+// containsNewline checks if a string contains newline characters
+func containsNewline(s string) bool {
+	for _, c := range s {
+		if c == '\n' {
+			return true
+		}
+	}
+	return false
+}
+
+// marshalWithIndent marshals YAML indented by spaces per level
+func marshalWithIndent(node *yaml.Node, spaces int) ([]byte, error) {
+	var buf []byte
+	encoder := yaml.NewEncoder(&bytesWriter{buf: &buf})
+	encoder.SetIndent(spaces)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// bytesWriter implements io.Writer to accumulate bytes
+type bytesWriter struct {
+	buf *[]byte
+}
+
+func (w *bytesWriter) Write(p []byte) (n int, err error) {
+	*w.buf = append(*w.buf, p...)
+	return len(p), nil
+}
@@ -2,6 +2,7 @@ package secret
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -546,7 +547,7 @@ metadata:
 					t.Fatalf("Failed to unmarshal: %v", err)
 				}
 			}
-			err := validateSecret(&doc)
+			_, err := validateSecret(&doc)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validateSecret() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -701,3 +702,104 @@ data:
 		t.Error("Round trip failed: expected base64 encoded password")
 	}
 }
+
+func TestRoundTripComments(t *testing.T) {
+	original := `# rotated 2024-01-01
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db # primary
+data:
+  # the password
+  password: aHVudGVyMg== # rotated
+  cert: bGluZTEKbGluZTIK
+  # end of data
+type: Opaque
+# trailing
+`
+
+	decoded, err := DecodeSecretData([]byte(original))
+	if err != nil {
+		t.Fatalf("DecodeSecretData() failed: %v", err)
+	}
+	// Edit a value and its comment, and add comments of our own
+	edited := strings.NewReplacer(
+		"hunter2 # rotated", "hunter3 # rotated again",
+		"  cert: |", "  # cert head\n  cert: | # cert line",
+		"type: Opaque", "type: Opaque # opaque",
+	).Replace(string(decoded)) + "# added\n"
+
+	encoded, err := EncodeSecretData([]byte(edited))
+	if err != nil {
+		t.Fatalf("EncodeSecretData() failed: %v", err)
+	}
+	want := `# rotated 2024-01-01
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db # primary
+data:
+  # the password
+  password: aHVudGVyMw== # rotated again
+  # cert head
+  cert: bGluZTEKbGluZTIK # cert line
+  # end of data
+type: Opaque # opaque
+# trailing
+# added
+`
+	if string(encoded) != want {
+		t.Errorf("round trip = %q, want %q", encoded, want)
+	}
+}
+
+func TestDecodeSecretDataAt(t *testing.T) {
+	input := `apiVersion: example.com/v1
+kind: Wrapper
+spec:
+  template:
+    type: Opaque
+    data:
+      password: cGFzc3dvcmQxMjM=
+  other:
+    kind: ConfigMap
+    data:
+      key: value
+`
+
+	if !IsSecretAt([]byte(input), ".spec.template") {
+		t.Error("IsSecretAt() should accept an embedded Secret without kind")
+	}
+	if IsSecretAt([]byte(input), ".spec.other") {
+		t.Error("IsSecretAt() should reject an embedded ConfigMap")
+	}
+	if IsSecretAt([]byte(input), ".spec.missing") {
+		t.Error("IsSecretAt() should reject a missing path")
+	}
+
+	decoded, err := DecodeSecretDataAt([]byte(input), ".spec.template")
+	if err != nil {
+		t.Fatalf("DecodeSecretDataAt() failed: %v", err)
+	}
+	if !strings.Contains(string(decoded), "password: password123") {
+		t.Errorf("DecodeSecretDataAt() did not decode embedded data:\n%s", decoded)
+	}
+	if !strings.Contains(string(decoded), "kind: Wrapper") {
+		t.Errorf("DecodeSecretDataAt() lost the wrapping resource:\n%s", decoded)
+	}
+
+	encoded, err := EncodeSecretDataAt(decoded, ".spec.template")
+	if err != nil {
+		t.Fatalf("EncodeSecretDataAt() failed: %v", err)
+	}
+	if !strings.Contains(string(encoded), "password: cGFzc3dvcmQxMjM=") {
+		t.Errorf("EncodeSecretDataAt() did not re-encode embedded data:\n%s", encoded)
+	}
+
+	if _, err := DecodeSecretDataAt([]byte(input), ".spec.other"); err == nil {
+		t.Error("DecodeSecretDataAt() should reject a non-Secret at the path")
+	}
+	if _, err := DecodeSecretDataAt([]byte(input), ".spec.template.type"); err == nil {
+		t.Error("DecodeSecretDataAt() should reject a scalar at the path")
+	}
+}
@@ -0,0 +1,75 @@
+package secret
+
+import "sync"
+
+// Version describes how a Secret API version lays out its fields
+type Version struct {
+	APIVersion      string
+	Kind            string
+	DataField       string // field holding base64-encoded values
+	StringDataField string // field holding plaintext values
+
+	// Assumed is set when the apiVersion isn't registered and the Secret is
+	// read with V1's fields, see ResolveVersion
+	Assumed bool
+}
+
+// V1 is the core/v1 Secret, the only version Kubernetes serves today
+var V1 = Version{
+	APIVersion:      "v1",
+	Kind:            "Secret",
+	DataField:       "data",
+	StringDataField: "stringData",
+}
+
+var (
+	versionsMu sync.RWMutex
+	versions   = map[versionKey]Version{
+		{V1.APIVersion, V1.Kind}: V1,
+	}
+)
+
+type versionKey struct {
+	apiVersion string
+	kind       string
+}
+
+// RegisterVersion adds or replaces a Secret API version, so aggregated or
+// future apiVersions are recognized with the right field names
+func RegisterVersion(v Version) {
+	versionsMu.Lock()
+	defer versionsMu.Unlock()
+	versions[versionKey{v.APIVersion, v.Kind}] = v
+}
+
+// LookupVersion finds the registered version for an apiVersion and kind.
+// Manifests without an apiVersion are treated as v1.
+func LookupVersion(apiVersion, kind string) (Version, bool) {
+	if apiVersion == "" {
+		apiVersion = V1.APIVersion
+	}
+
+	versionsMu.RLock()
+	defer versionsMu.RUnlock()
+	v, ok := versions[versionKey{apiVersion, kind}]
+	return v, ok
+}
+
+// ResolveVersion finds the version for an apiVersion and kind like
+// LookupVersion, but reads a Secret of an unregistered apiVersion with V1's
+// fields, marked as Assumed, as swk did before versions were registered
+func ResolveVersion(apiVersion, kind string) (Version, bool) {
+	if v, ok := LookupVersion(apiVersion, kind); ok {
+		return v, true
+	}
+	if kind != V1.Kind {
+		return Version{}, false
+	}
+	return Version{
+		APIVersion:      apiVersion,
+		Kind:            kind,
+		DataField:       V1.DataField,
+		StringDataField: V1.StringDataField,
+		Assumed:         true,
+	}, true
+}
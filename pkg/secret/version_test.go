@@ -0,0 +1,113 @@
+package secret
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookupVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiVersion string
+		kind       string
+		want       Version
+		wantOK     bool
+	}{
+		{"v1 secret", "v1", "Secret", V1, true},
+		{"missing apiVersion defaults to v1", "", "Secret", V1, true},
+		{"unknown apiVersion", "v2", "Secret", Version{}, false},
+		{"not a secret", "v1", "ConfigMap", Version{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := LookupVersion(tt.apiVersion, tt.kind)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("LookupVersion(%q, %q) = %+v, %v, want %+v, %v", tt.apiVersion, tt.kind, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRegisterVersion(t *testing.T) {
+	custom := Version{
+		APIVersion:      "secrets.example.com/v1alpha1",
+		Kind:            "Secret",
+		DataField:       "payload",
+		StringDataField: "plainPayload",
+	}
+	RegisterVersion(custom)
+
+	input := `apiVersion: secrets.example.com/v1alpha1
+kind: Secret
+metadata:
+  name: test
+payload:
+  password: cGFzc3dvcmQxMjM=
+data:
+  untouched: cGFzc3dvcmQxMjM=
+`
+
+	if !IsSecret([]byte(input)) {
+		t.Fatal("IsSecret() should recognize a registered apiVersion")
+	}
+
+	decoded, err := DecodeSecretData([]byte(input))
+	if err != nil {
+		t.Fatalf("DecodeSecretData() failed: %v", err)
+	}
+	if !strings.Contains(string(decoded), "password: password123") {
+		t.Errorf("DecodeSecretData() did not decode the registered data field:\n%s", decoded)
+	}
+	if !strings.Contains(string(decoded), "untouched: cGFzc3dvcmQxMjM=") {
+		t.Errorf("DecodeSecretData() decoded a field the version does not declare:\n%s", decoded)
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiVersion string
+		kind       string
+		want       Version
+		wantOK     bool
+	}{
+		{"registered", "v1", "Secret", V1, true},
+		{"unregistered read as v1", "v2", "Secret", Version{APIVersion: "v2", Kind: "Secret", DataField: "data", StringDataField: "stringData", Assumed: true}, true},
+		{"not a secret", "v1", "ConfigMap", Version{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ResolveVersion(tt.apiVersion, tt.kind)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("ResolveVersion(%q, %q) = %+v, %v, want %+v, %v", tt.apiVersion, tt.kind, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestUnregisteredVersion(t *testing.T) {
+	input := `apiVersion: v9
+kind: Secret
+metadata:
+  name: test
+data:
+  password: cGFzc3dvcmQxMjM=
+`
+
+	if !IsSecret([]byte(input)) {
+		t.Error("IsSecret() should recognize a Secret of an unregistered apiVersion")
+	}
+
+	decoded, warnings, err := DecodeWithWarnings([]byte(input), "")
+	if err != nil {
+		t.Fatalf("DecodeWithWarnings() error = %v", err)
+	}
+	if !strings.Contains(string(decoded), "password: password123") {
+		t.Errorf("DecodeWithWarnings() did not decode the data field:\n%s", decoded)
+	}
+	if len(warnings) != 1 || warnings[0].Kind != WarnUnregisteredVersion || warnings[0].String() != `line 1: Secret apiVersion "v9" is not registered, so it is read as v1` {
+		t.Errorf("DecodeWithWarnings() warnings = %v, want one about the apiVersion", warnings)
+	}
+}
@@ -0,0 +1,131 @@
+package secret
+
+import (
+	"encoding/base64"
+	"fmt"
+	"unicode/utf8"
+)
+
+// WarningKind identifies what a Warning is about
+type WarningKind string
+
+const (
+	// WarnTrailingNewline is a decoded value ending in a newline, usually
+	// left by `echo` without -n when the value was created
+	WarnTrailingNewline WarningKind = "trailing-newline"
+	// WarnBinary is a decoded value that isn't text, which an editor would
+	// mangle, so DecodeText leaves it encoded
+	WarnBinary WarningKind = "binary"
+	// WarnNonCanonical is base64 that decodes fine but isn't in canonical
+	// form, e.g. wrapped lines, so tools re-encoding it cause spurious diffs
+	WarnNonCanonical WarningKind = "non-canonical-base64"
+	// WarnDuplicateKey is a key given twice, in which case the API server
+	// keeps only one of the values
+	WarnDuplicateKey WarningKind = "duplicate-key"
+	// WarnUnregisteredVersion is a Secret whose apiVersion isn't
+	// registered, read with v1's fields (see ResolveVersion)
+	WarnUnregisteredVersion WarningKind = "unregistered-version"
+)
+
+// Warning is something about a Secret that doesn't stop it from being
+// transformed but that a user may want to know
+type Warning struct {
+	Kind    WarningKind
+	Key     string
+	Line    int
+	Message string
+}
+
+func (w Warning) String() string {
+	if w.Key == "" {
+		if w.Line > 0 {
+			return fmt.Sprintf("line %d: %s", w.Line, w.Message)
+		}
+		return w.Message
+	}
+	if w.Line > 0 {
+		return fmt.Sprintf("line %d: key %q: %s", w.Line, w.Key, w.Message)
+	}
+	return fmt.Sprintf("key %q: %s", w.Key, w.Message)
+}
+
+// Warnings returns the warnings found by the last Decode or Encode
+func (d *Document) Warnings() []Warning {
+	return append([]Warning(nil), d.warnings...)
+}
+
+// DecodeWithWarnings decodes the Secret at path like DecodeSecretDataAt and
+// also returns the warnings found on the way, even if decoding fails, since
+// they may explain why
+func DecodeWithWarnings(input []byte, path string) ([]byte, []Warning, error) {
+	d, err := ParseAt(input, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := d.DecodeText(); err != nil {
+		return nil, d.Warnings(), err
+	}
+	output, err := d.Bytes()
+	if err != nil {
+		return nil, d.Warnings(), err
+	}
+	return output, d.Warnings(), nil
+}
+
+// inspect finds the warnings for the Secret's current values. encoded says
+// whether the data values are still base64, so they can be checked too.
+func (d *Document) inspect(b Behaviors, encoded bool) []Warning {
+	var warnings []Warning
+	seen := map[string]int{}
+	warn := func(kind WarningKind, e Entry, format string, args ...any) {
+		warnings = append(warnings, Warning{Kind: kind, Key: e.Key, Line: e.Line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if d.version.Assumed {
+		warn(WarnUnregisteredVersion, Entry{Line: d.target.Line}, "Secret apiVersion %q is not registered, so it is read as v1", d.version.APIVersion)
+	}
+
+	for _, e := range append(d.Data(), d.StringData()...) {
+		if line, ok := seen[e.Key]; ok {
+			warn(WarnDuplicateKey, e, "also set on line %d; only one value is kept", line)
+		}
+		seen[e.Key] = e.Line
+	}
+
+	if !encoded {
+		return warnings
+	}
+	for _, e := range d.Data() {
+		if b.Skip[e.Key] {
+			continue
+		}
+		decoded, err := decodeBase64(e.Value)
+		if err != nil {
+			// Decode reports this as an error
+			continue
+		}
+		if base64.StdEncoding.EncodeToString([]byte(decoded)) != e.Value {
+			warn(WarnNonCanonical, e, "base64 is not in canonical form, so other tools may encode it differently")
+		}
+		if binary(decoded) {
+			warn(WarnBinary, e, "value is binary, so it stays base64 encoded; list it in %s to silence this", SkipKeysAnnotation)
+		} else if decoded != "" && decoded[len(decoded)-1] == '\n' {
+			warn(WarnTrailingNewline, e, "value ends with a newline")
+		}
+	}
+	return warnings
+}
+
+// binary reports whether a value isn't text: invalid UTF-8, or control
+// characters other than tabs and line breaks
+func binary(value string) bool {
+	if !utf8.ValidString(value) {
+		return true
+	}
+	for _, r := range value {
+		if (r < 0x20 && r != '\t' && r != '\n' && r != '\r') || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
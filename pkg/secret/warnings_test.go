@@ -0,0 +1,73 @@
+package secret
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeWithWarnings(t *testing.T) {
+	input := `apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+  annotations:
+    swk.dev/skip-keys: keystore
+data:
+  clean: c2VjcmV0
+  newline: c2VjcmV0Cg==
+  binary: AAEC
+  wrapped: |
+    c2Vj
+    cmV0
+  keystore: //79
+stringData:
+  clean: other
+`
+	_, warnings, err := DecodeWithWarnings([]byte(input), "")
+	if err != nil {
+		t.Fatalf("DecodeWithWarnings() failed: %v", err)
+	}
+
+	var got []string
+	for _, w := range warnings {
+		got = append(got, string(w.Kind)+" "+w.Key)
+	}
+	want := []string{
+		"duplicate-key clean",
+		"trailing-newline newline",
+		"binary binary",
+		"non-canonical-base64 wrapped",
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("warnings = %v, want %v", got, want)
+	}
+	if s := warnings[0].String(); s != `line 16: key "clean": also set on line 8; only one value is kept` {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestDecodeWarningsOnError(t *testing.T) {
+	// "{\n" ends with a newline and isn't the JSON its codec wants
+	input := "apiVersion: v1\nkind: Secret\nmetadata:\n  annotations:\n    swk.dev/codec.config: json-pretty\ndata:\n  config: ewo=\n"
+	_, warnings, err := DecodeWithWarnings([]byte(input), "")
+	if err == nil {
+		t.Fatal("DecodeWithWarnings() succeeded with invalid JSON")
+	}
+	if len(warnings) != 1 || warnings[0].Kind != WarnTrailingNewline {
+		t.Errorf("warnings = %v, want the warning found before the error", warnings)
+	}
+}
+
+func TestEncodeWarnings(t *testing.T) {
+	d, err := Parse([]byte("apiVersion: v1\nkind: Secret\ndata:\n  a: x\n  b: \"z\\n\"\nstringData:\n  a: y\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Encode(); err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	warnings := d.Warnings()
+	if len(warnings) != 1 || warnings[0].Kind != WarnDuplicateKey {
+		t.Errorf("Warnings() = %v, want only the duplicate key", warnings)
+	}
+}